@@ -0,0 +1,91 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/kelseyhightower/envconfig"
+	"go.uber.org/zap"
+	"knative.dev/eventing/pkg/kncloudevents"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/signals"
+
+	"knative.dev/eventing-couchdb/broker/pkg/database"
+	"knative.dev/eventing-couchdb/broker/pkg/filter"
+)
+
+type envConfig struct {
+	Port            int    `envconfig:"PORT" default:"8080"`
+	LoggingConfig   string `envconfig:"K_LOGGING_CONFIG"`
+	CredentialsPath string `envconfig:"COUCHDB_CREDENTIALS" required:"true"`
+	Database        string `envconfig:"COUCHDB_DATABASE" required:"true"`
+	Ingress         string `envconfig:"COUCHDB_BROKER_INGRESS" required:"true"`
+	Triggers        string `envconfig:"COUCHDB_TRIGGERS" default:"[]"`
+}
+
+func main() {
+	ctx := signals.NewContext()
+
+	var env envConfig
+	if err := envconfig.Process("", &env); err != nil {
+		log.Fatal("Error processing the environment: ", err)
+	}
+	logger, _ := logging.NewLogger(env.LoggingConfig, "info")
+	defer logger.Sync()
+
+	var triggers []filter.Trigger
+	if err := json.Unmarshal([]byte(env.Triggers), &triggers); err != nil {
+		logger.Fatalw("Invalid triggers", zap.Error(err))
+	}
+	ingressURI, err := apis.ParseURL(env.Ingress)
+	if err != nil {
+		logger.Fatalw("Invalid ingress", zap.String("ingress", env.Ingress), zap.Error(err))
+	}
+
+	db, err := database.Open(ctx, env.CredentialsPath, env.Database)
+	if err != nil {
+		logger.Fatalw("Error opening the database", zap.String("database", env.Database), zap.Error(err))
+	}
+	sender, err := kncloudevents.NewHTTPMessageSenderWithTarget("")
+	if err != nil {
+		logger.Fatalw("Error creating the sender", zap.Error(err))
+	}
+	f := filter.NewFilter(logger, db, sender, ingressURI, triggers)
+
+	// The filter receives no event, it only answers its readiness probe.
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	server := &http.Server{Addr: ":" + strconv.Itoa(env.Port), Handler: mux}
+	go func() {
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			logger.Fatalw("Error serving the readiness probe", zap.Error(err))
+		}
+	}()
+
+	logger.Infow("Delivering the events of the broker", zap.String("database", env.Database),
+		zap.Int("triggers", len(triggers)))
+	// Start returns once the checkpoints of the triggers are saved.
+	f.Start(ctx)
+	server.Close()
+}
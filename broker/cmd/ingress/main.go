@@ -0,0 +1,78 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+	"go.uber.org/zap"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/signals"
+
+	"knative.dev/eventing-couchdb/broker/pkg/database"
+	"knative.dev/eventing-couchdb/broker/pkg/ingress"
+)
+
+// shutdownTimeout bounds the time given to the events being persisted once
+// the ingress is asked to stop.
+const shutdownTimeout = 30 * time.Second
+
+type envConfig struct {
+	Port            int    `envconfig:"PORT" default:"8080"`
+	LoggingConfig   string `envconfig:"K_LOGGING_CONFIG"`
+	CredentialsPath string `envconfig:"COUCHDB_CREDENTIALS" required:"true"`
+	Database        string `envconfig:"COUCHDB_DATABASE" required:"true"`
+}
+
+func main() {
+	ctx := signals.NewContext()
+
+	var env envConfig
+	if err := envconfig.Process("", &env); err != nil {
+		log.Fatal("Error processing the environment: ", err)
+	}
+	logger, _ := logging.NewLogger(env.LoggingConfig, "info")
+	defer logger.Sync()
+
+	db, err := database.Open(ctx, env.CredentialsPath, env.Database)
+	if err != nil {
+		logger.Fatalw("Error opening the database", zap.String("database", env.Database), zap.Error(err))
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.Handle("/", ingress.NewIngress(logger, db))
+	server := &http.Server{Addr: ":" + strconv.Itoa(env.Port), Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
+	logger.Infow("Persisting the events of the broker", zap.String("database", env.Database), zap.Int("port", env.Port))
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		logger.Fatalw("Error serving the events", zap.Error(err))
+	}
+}
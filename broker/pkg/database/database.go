@@ -0,0 +1,65 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package database opens the CouchDB database the events of a broker are
+// persisted in, shared by its ingress and its filter.
+package database
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	_ "github.com/go-kivik/couchdb/v3" // The CouchDB driver
+	"github.com/go-kivik/kivik/v3"
+)
+
+// Document is the document an event is persisted as. The event is nested,
+// since CouchDB reserves the top-level members starting with an underscore,
+// which CloudEvents would read back as extension attributes.
+type Document struct {
+	Event cloudevents.Event `json:"event"`
+}
+
+// Open connects to CouchDB with the url of the credentials mounted at
+// credentialsPath, and opens the database, creating it when missing.
+func Open(ctx context.Context, credentialsPath, name string) (*kivik.DB, error) {
+	url, err := ioutil.ReadFile(credentialsPath + "/url")
+	if err != nil {
+		return nil, fmt.Errorf("missing url key in secret: %w", err)
+	}
+	client, err := kivik.New("couch", string(url))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create the CouchDB client: %w", err)
+	}
+	exists, err := client.DBExists(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to check the database %q: %w", name, err)
+	}
+	// The ingress and the filter start together, either may create it.
+	if !exists {
+		if err := client.CreateDB(ctx, name); err != nil && kivik.StatusCode(err) != http.StatusPreconditionFailed {
+			return nil, fmt.Errorf("unable to create the database %q: %w", name, err)
+		}
+	}
+	db := client.DB(ctx, name)
+	if err := db.Err(); err != nil {
+		return nil, fmt.Errorf("unable to open the database %q: %w", name, err)
+	}
+	return db, nil
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package filter delivers the events persisted by the ingress of a CouchDB
+// broker to its triggers, by tailing the _changes feed of its database.
+package filter
+
+import (
+	"context"
+	"sync"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/binding/spec"
+	"github.com/cloudevents/sdk-go/v2/types"
+	"github.com/go-kivik/kivik/v3"
+	"go.uber.org/zap"
+	eventingv1 "knative.dev/eventing/pkg/apis/eventing/v1"
+	"knative.dev/eventing/pkg/kncloudevents"
+	"knative.dev/pkg/apis"
+)
+
+// Filter delivers the events persisted in the CouchDB database of a broker to
+// the triggers whose filter they match, and sends the replies of the triggers
+// back to the ingress of the broker.
+type Filter struct {
+	logger *zap.SugaredLogger
+	db     *kivik.DB
+	sender *kncloudevents.HTTPMessageSender
+
+	ingressURI *apis.URL
+	triggers   []Trigger
+}
+
+// NewFilter returns the Filter delivering the events of a broker persisted in
+// db to its triggers with sender, and sending their replies to ingressURI.
+func NewFilter(logger *zap.SugaredLogger, db *kivik.DB, sender *kncloudevents.HTTPMessageSender, ingressURI *apis.URL, triggers []Trigger) *Filter {
+	return &Filter{
+		logger:     logger,
+		db:         db,
+		sender:     sender,
+		ingressURI: ingressURI,
+		triggers:   triggers,
+	}
+}
+
+// Start delivers the persisted events to every trigger until ctx is done.
+// Each trigger tails the _changes feed on its own, so that a slow or failing
+// subscriber does not hold back the others.
+func (f *Filter) Start(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, trigger := range f.triggers {
+		wg.Add(1)
+		go func(trigger Trigger) {
+			defer wg.Done()
+			f.fanOut(ctx, trigger)
+		}(trigger)
+	}
+	wg.Wait()
+}
+
+// matches is whether the event has every attribute of the filter, the
+// attributes with an empty value matching any, even a missing one.
+func matches(attributes eventingv1.TriggerFilterAttributes, event cloudevents.Event) bool {
+	for name, want := range attributes {
+		if want == eventingv1.TriggerAnyFilter {
+			continue
+		}
+		if got, ok := attribute(event, name); !ok || got != want {
+			return false
+		}
+	}
+	return true
+}
+
+// attribute returns the canonical string of the context attribute or of the
+// extension of the event, and whether the event has it.
+func attribute(event cloudevents.Event, name string) (string, bool) {
+	var value interface{}
+	if version := spec.VS.Version(event.SpecVersion()); version != nil && version.Attribute(name) != nil {
+		value = version.Attribute(name).Get(event.Context)
+	} else {
+		value = event.Extensions()[name]
+	}
+	if value == nil {
+		return "", false
+	}
+	s, err := types.Format(value)
+	return s, err == nil
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filter
+
+import (
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	eventingv1 "knative.dev/eventing/pkg/apis/eventing/v1"
+)
+
+func newEvent(id string) cloudevents.Event {
+	event := cloudevents.NewEvent()
+	event.SetID(id)
+	event.SetSource("/orders")
+	event.SetType("order.created")
+	event.SetData(cloudevents.ApplicationJSON, map[string]interface{}{"total": 3})
+	return event
+}
+
+func TestMatches(t *testing.T) {
+	event := newEvent("abc")
+	event.SetTime(time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC))
+	event.SetExtension("region", "eu")
+
+	testCases := map[string]struct {
+		attributes eventingv1.TriggerFilterAttributes
+		want       bool
+	}{
+		"no filter": {
+			want: true,
+		},
+		"type": {
+			attributes: eventingv1.TriggerFilterAttributes{"type": "order.created"},
+			want:       true,
+		},
+		"type and source": {
+			attributes: eventingv1.TriggerFilterAttributes{"type": "order.created", "source": "/orders"},
+			want:       true,
+		},
+		"other type": {
+			attributes: eventingv1.TriggerFilterAttributes{"type": "order.cancelled", "source": "/orders"},
+		},
+		"time": {
+			attributes: eventingv1.TriggerFilterAttributes{"time": "2019-10-01T12:00:00Z"},
+			want:       true,
+		},
+		"extension": {
+			attributes: eventingv1.TriggerFilterAttributes{"region": "eu"},
+			want:       true,
+		},
+		"other extension": {
+			attributes: eventingv1.TriggerFilterAttributes{"region": "us"},
+		},
+		"missing attribute": {
+			attributes: eventingv1.TriggerFilterAttributes{"subject": "1234"},
+		},
+		"any value of a missing attribute": {
+			attributes: eventingv1.TriggerFilterAttributes{"subject": eventingv1.TriggerAnyFilter},
+			want:       true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			if got := matches(tc.attributes, event); got != tc.want {
+				t.Errorf("matches() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
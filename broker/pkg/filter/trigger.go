@@ -0,0 +1,278 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/binding"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+	"github.com/go-kivik/kivik/v3"
+	"go.uber.org/zap"
+	"knative.dev/eventing/pkg/kncloudevents"
+
+	"knative.dev/eventing-couchdb/broker/pkg/database"
+	"knative.dev/eventing-couchdb/broker/pkg/reconciler/resources"
+)
+
+const (
+	// checkpointSaveInterval is how often, at most, the checkpoint of a
+	// trigger is saved while the events are delivered.
+	checkpointSaveInterval = 5 * time.Second
+
+	// reconnectDelay is the time waited before reading the _changes feed
+	// again once it failed.
+	reconnectDelay = 5 * time.Second
+
+	// heartbeat keeps the continuous _changes feed open while no event is
+	// sent to the broker.
+	heartbeat = 30 * time.Second
+)
+
+// Trigger is a trigger of the broker.
+type Trigger = resources.Trigger
+
+// savedCheckpoint is the sequence of the _changes feed up to which the events
+// were delivered to a trigger, saved in a local document, which is not
+// replicated nor reported by the changes feed.
+type savedCheckpoint struct {
+	Rev      string `json:"_rev,omitempty"`
+	Sequence string `json:"sequence"`
+}
+
+// triggerDispatch delivers the persisted events to a trigger.
+type triggerDispatch struct {
+	Trigger
+	logger *zap.SugaredLogger
+	retry  kncloudevents.RetryConfig
+
+	// checkpointID is the local document of the checkpoint, rev its current
+	// revision, saved the sequence it holds and last when it was last
+	// written.
+	checkpointID string
+	rev          string
+	saved        string
+	last         time.Time
+}
+
+// checkpointID returns the local document of the checkpoint of the trigger.
+func checkpointID(trigger Trigger) string {
+	return "_local/knative-trigger-" + string(trigger.UID)
+}
+
+// fanOut delivers the events to the trigger, from its checkpoint, until ctx
+// is done.
+func (f *Filter) fanOut(ctx context.Context, trigger Trigger) {
+	t := &triggerDispatch{
+		Trigger:      trigger,
+		logger:       f.logger.With(zap.String("trigger", string(trigger.UID))),
+		retry:        kncloudevents.NoRetries(),
+		checkpointID: checkpointID(trigger),
+	}
+	if trigger.Delivery != nil {
+		retry, err := kncloudevents.RetryConfigFromDeliverySpec(*trigger.Delivery)
+		if err != nil {
+			t.logger.Errorw("Invalid delivery, delivering without retries", zap.Error(err))
+		} else {
+			t.retry = retry
+		}
+	}
+
+	since, err := f.resume(ctx, t)
+	for err != nil {
+		t.logger.Warnw("Unable to resume from the checkpoint", zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectDelay):
+		}
+		since, err = f.resume(ctx, t)
+	}
+
+	for {
+		since, err = f.dispatchChanges(ctx, t, since)
+		// Do not lose the deliveries since the last save.
+		f.saveCheckpoint(t, since, time.Now(), true)
+		if ctx.Err() != nil {
+			return
+		}
+		t.logger.Warnw("The _changes feed stopped, reading it again", zap.String("since", since), zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// resume returns the sequence the trigger reads the _changes feed from: its
+// checkpoint, or else the current sequence of the database, saved as its
+// checkpoint so that the new triggers receive the events sent once they were
+// created, and not before.
+func (f *Filter) resume(ctx context.Context, t *triggerDispatch) (string, error) {
+	var saved savedCheckpoint
+	err := f.db.Get(ctx, t.checkpointID).ScanDoc(&saved)
+	switch {
+	case kivik.StatusCode(err) == http.StatusNotFound:
+	case err != nil:
+		return "", err
+	default:
+		t.rev = saved.Rev
+		t.saved = saved.Sequence
+		t.logger.Infow("Resuming from the checkpoint", zap.String("since", saved.Sequence))
+		return saved.Sequence, nil
+	}
+
+	stats, err := f.db.Stats(ctx)
+	if err != nil {
+		return "", err
+	}
+	f.saveCheckpoint(t, stats.UpdateSeq, time.Now(), true)
+	t.logger.Infow("Delivering the events from now on", zap.String("since", stats.UpdateSeq))
+	return stats.UpdateSeq, nil
+}
+
+// dispatchChanges delivers the events of the continuous _changes feed
+// matching the filter of the trigger, from since, and returns the sequence up
+// to which they were delivered once the feed stops.
+func (f *Filter) dispatchChanges(ctx context.Context, t *triggerDispatch, since string) (string, error) {
+	changes, err := f.db.Changes(ctx, kivik.Options{
+		"feed":         "continuous",
+		"since":        since,
+		"include_docs": true,
+		"heartbeat":    int(heartbeat / time.Millisecond),
+	})
+	if err != nil {
+		return since, err
+	}
+	defer changes.Close()
+
+	for changes.Next() {
+		if !changes.Deleted() && !strings.HasPrefix(changes.ID(), "_design/") {
+			var doc database.Document
+			if err := changes.ScanDoc(&doc); err != nil {
+				t.logger.Errorw("Skipping an invalid event", zap.String("id", changes.ID()), zap.Error(err))
+			} else if matches(t.Attributes, doc.Event) {
+				f.deliver(ctx, t, doc.Event)
+			}
+			if ctx.Err() != nil {
+				// The delivery was interrupted, deliver the event again
+				// once restarted.
+				return since, ctx.Err()
+			}
+		}
+		since = changes.Seq()
+		f.saveCheckpoint(t, since, time.Now(), false)
+	}
+	return since, changes.Err()
+}
+
+// deliver sends the event to the subscriber of the trigger, and its reply to
+// the ingress of the broker. The events that could not be delivered, once
+// retried, are sent to the dead letter sink if any, and dropped otherwise, so
+// that they do not hold back the next ones.
+func (f *Filter) deliver(ctx context.Context, t *triggerDispatch, event cloudevents.Event) {
+	logger := t.logger.With(zap.String("event", event.ID()))
+	err := f.send(ctx, t, event)
+	if err == nil {
+		logger.Debug("Delivered the event")
+		return
+	}
+	if t.DeadLetterSinkURI == nil {
+		logger.Errorw("Dropping the event that could not be delivered", zap.Error(err))
+		return
+	}
+	logger.Warnw("Sending the event that could not be delivered to the dead letter sink", zap.Error(err))
+	if _, err := f.sendTo(ctx, t.DeadLetterSinkURI.String(), binding.ToMessage(&event), &t.retry); err != nil {
+		logger.Errorw("Dropping the event that could not be dead lettered", zap.Error(err))
+	}
+}
+
+// send sends the event to the subscriber of the trigger, and its reply, if
+// any, to the ingress of the broker.
+func (f *Filter) send(ctx context.Context, t *triggerDispatch, event cloudevents.Event) error {
+	resp, err := f.sendTo(ctx, t.SubscriberURI.String(), binding.ToMessage(&event), &t.retry)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	reply := cehttp.NewMessageFromHttpResponse(resp)
+	if reply.ReadEncoding() == binding.EncodingUnknown {
+		return nil
+	}
+	if _, err := f.sendTo(ctx, f.ingressURI.String(), reply, &t.retry); err != nil {
+		return fmt.Errorf("unable to send the reply: %w", err)
+	}
+	return nil
+}
+
+// sendTo sends the message to the target with retries, and returns the
+// accepted response, whose body is left to the caller when it wants it.
+func (f *Filter) sendTo(ctx context.Context, target string, message binding.Message, retry *kncloudevents.RetryConfig) (*http.Response, error) {
+	req, err := f.sender.NewCloudEventRequestWithTarget(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	if err := cehttp.WriteRequest(ctx, message, req); err != nil {
+		return nil, err
+	}
+	resp, err := f.sender.SendWithRetries(req, retry)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s responded %d %s", target, resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	return resp, nil
+}
+
+// saveCheckpoint writes the checkpoint of the trigger when it advanced, at
+// most every checkpointSaveInterval unless forced. A checkpoint that cannot
+// be saved only makes a restarted filter deliver some events again.
+func (f *Filter) saveCheckpoint(t *triggerDispatch, seq string, now time.Time, force bool) {
+	if seq == "" || seq == t.saved {
+		return
+	}
+	if !force && now.Sub(t.last) < checkpointSaveInterval {
+		return
+	}
+	t.last = now
+
+	rev, err := f.db.Put(context.Background(), t.checkpointID, &savedCheckpoint{
+		Rev:      t.rev,
+		Sequence: seq,
+	})
+	if err != nil {
+		t.logger.Warnw("Unable to save the checkpoint", zap.Error(err))
+		if kivik.StatusCode(err) == http.StatusConflict {
+			// A filter rolling out saved it meanwhile, catch up with its
+			// revision.
+			if _, rev, err := f.db.GetMeta(context.Background(), t.checkpointID); err == nil {
+				t.rev = rev
+			}
+		}
+		return
+	}
+	t.rev = rev
+	t.saved = seq
+}
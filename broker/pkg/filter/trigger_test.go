@@ -0,0 +1,231 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/binding"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+	"github.com/go-kivik/kivik/v3"
+	"github.com/go-kivik/kivik/v3/driver"
+	"github.com/go-kivik/kivikmock/v3"
+	"go.uber.org/zap"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+	eventingv1 "knative.dev/eventing/pkg/apis/eventing/v1"
+	"knative.dev/eventing/pkg/kncloudevents"
+	"knative.dev/pkg/apis"
+
+	"knative.dev/eventing-couchdb/broker/pkg/database"
+)
+
+// recorder is an HTTP endpoint recording the IDs of the events it receives,
+// responding with status, and replying with reply when set.
+type recorder struct {
+	status int
+	reply  string
+
+	mu  sync.Mutex
+	ids []string
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	event, err := binding.ToEvent(req.Context(), cehttp.NewMessageFromHttpRequest(req))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.mu.Lock()
+	r.ids = append(r.ids, event.ID())
+	r.mu.Unlock()
+	if r.reply != "" {
+		reply := newEvent(r.reply)
+		if err := cehttp.WriteResponseWriter(req.Context(), binding.ToMessage(&reply), r.status, w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	w.WriteHeader(r.status)
+}
+
+func (r *recorder) received() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.ids...)
+}
+
+func newFilter(t *testing.T, db *kivik.DB, ingress string) *Filter {
+	t.Helper()
+	sender, err := kncloudevents.NewHTTPMessageSenderWithTarget("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ingressURI, err := apis.ParseURL(ingress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Filter{
+		logger:     zap.NewNop().Sugar(),
+		db:         db,
+		sender:     sender,
+		ingressURI: ingressURI,
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	retry := int32(2)
+	linear := eventingduckv1.BackoffPolicyLinear
+	delay := "PT0.001S"
+	delivery := &eventingduckv1.DeliverySpec{Retry: &retry, BackoffPolicy: &linear, BackoffDelay: &delay}
+
+	testCases := map[string]struct {
+		subscriber     *recorder
+		deadLetterSink bool
+		delivery       *eventingduckv1.DeliverySpec
+
+		wantSubscriber     int
+		wantReplies        []string
+		wantDeadLetterSink []string
+	}{
+		"delivered": {
+			subscriber:     &recorder{status: http.StatusAccepted},
+			wantSubscriber: 1,
+		},
+		"reply sent to the ingress": {
+			subscriber:     &recorder{status: http.StatusOK, reply: "reply-1"},
+			wantSubscriber: 1,
+			wantReplies:    []string{"reply-1"},
+		},
+		"retried then dead lettered": {
+			subscriber:         &recorder{status: http.StatusServiceUnavailable},
+			deadLetterSink:     true,
+			delivery:           delivery,
+			wantSubscriber:     3,
+			wantDeadLetterSink: []string{"abc"},
+		},
+		"dropped": {
+			subscriber:     &recorder{status: http.StatusServiceUnavailable},
+			wantSubscriber: 1,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			td := &triggerDispatch{
+				logger: zap.NewNop().Sugar(),
+				retry:  kncloudevents.NoRetries(),
+			}
+			if tc.delivery != nil {
+				retry, err := kncloudevents.RetryConfigFromDeliverySpec(*tc.delivery)
+				if err != nil {
+					t.Fatal(err)
+				}
+				td.retry = retry
+			}
+			subscriber := httptest.NewServer(tc.subscriber)
+			defer subscriber.Close()
+			td.SubscriberURI, _ = apis.ParseURL(subscriber.URL)
+			replies := &recorder{status: http.StatusAccepted}
+			ingress := httptest.NewServer(replies)
+			defer ingress.Close()
+			deadLetters := &recorder{status: http.StatusAccepted}
+			if tc.deadLetterSink {
+				server := httptest.NewServer(deadLetters)
+				defer server.Close()
+				td.DeadLetterSinkURI, _ = apis.ParseURL(server.URL)
+			}
+
+			newFilter(t, nil, ingress.URL).deliver(context.Background(), td, newEvent("abc"))
+
+			if got := len(tc.subscriber.received()); got != tc.wantSubscriber {
+				t.Errorf("subscriber received %d events, want %d", got, tc.wantSubscriber)
+			}
+			if got := replies.received(); strings.Join(got, ",") != strings.Join(tc.wantReplies, ",") {
+				t.Errorf("replies = %v, want %v", got, tc.wantReplies)
+			}
+			if got := deadLetters.received(); strings.Join(got, ",") != strings.Join(tc.wantDeadLetterSink, ",") {
+				t.Errorf("dead letters = %v, want %v", got, tc.wantDeadLetterSink)
+			}
+		})
+	}
+}
+
+func TestDispatchChanges(t *testing.T) {
+	doc := func(id, eventType string) json.RawMessage {
+		event := newEvent(id)
+		event.SetType(eventType)
+		b, err := json.Marshal(&database.Document{Event: event})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	client, mock := kivikmock.NewT(t)
+	db := mock.NewDB()
+	mock.ExpectDB().WithName("broker").WillReturn(db)
+	db.ExpectChanges().WillReturn(kivikmock.NewChanges().
+		AddChange(&driver.Change{ID: "1", Seq: "2-b", Changes: driver.ChangedRevs{"1-a"}, Doc: doc("event-1", "order.created")}).
+		AddChange(&driver.Change{ID: "_design/views", Seq: "3-c", Changes: driver.ChangedRevs{"1-a"}, Doc: json.RawMessage(`{}`)}).
+		AddChange(&driver.Change{ID: "2", Seq: "4-d", Changes: driver.ChangedRevs{"1-a"}, Doc: doc("event-2", "order.cancelled")}).
+		AddChange(&driver.Change{ID: "3", Seq: "5-e", Changes: driver.ChangedRevs{"2-a"}, Deleted: true}).
+		AddChange(&driver.Change{ID: "4", Seq: "6-f", Changes: driver.ChangedRevs{"1-a"}, Doc: doc("event-4", "order.created")}))
+	// The checkpoint is saved once per checkpointSaveInterval.
+	db.ExpectPut().WithDocID("_local/knative-trigger-1234").WithDoc(&savedCheckpoint{Rev: "0-1", Sequence: "2-b"}).WillReturn("0-2")
+
+	subscriber := &recorder{status: http.StatusAccepted}
+	server := httptest.NewServer(subscriber)
+	defer server.Close()
+
+	f := newFilter(t, client.DB(context.Background(), "broker"), "http://ingress.ns.svc.cluster.local")
+	td := &triggerDispatch{
+		logger:       zap.NewNop().Sugar(),
+		retry:        kncloudevents.NoRetries(),
+		checkpointID: "_local/knative-trigger-1234",
+		rev:          "0-1",
+		saved:        "1-a",
+	}
+	td.Attributes = eventingv1.TriggerFilterAttributes{"type": "order.created"}
+	td.SubscriberURI, _ = apis.ParseURL(server.URL)
+
+	since, err := f.dispatchChanges(context.Background(), td, "1-a")
+	if err != nil {
+		t.Fatal("dispatchChanges() =", err)
+	}
+	if since != "6-f" {
+		t.Errorf("since = %q, want 6-f", since)
+	}
+	if got := strings.Join(subscriber.received(), ","); got != "event-1,event-4" {
+		t.Errorf("subscriber received %s, want event-1,event-4", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	// The last sequence is saved once the feed stops.
+	db.ExpectPut().WithDocID("_local/knative-trigger-1234").WithDoc(&savedCheckpoint{Rev: "0-2", Sequence: "6-f"}).WillReturn("0-3")
+	f.saveCheckpoint(td, since, time.Now(), true)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
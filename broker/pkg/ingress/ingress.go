@@ -0,0 +1,74 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ingress persists the CloudEvents sent to a CouchDB broker as
+// documents of its database, for the filter to deliver them to the triggers.
+package ingress
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/cloudevents/sdk-go/v2/binding"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+	"github.com/go-kivik/kivik/v3"
+	"go.uber.org/zap"
+
+	"knative.dev/eventing-couchdb/broker/pkg/database"
+	"knative.dev/eventing-couchdb/pkg/component"
+)
+
+// Ingress persists the CloudEvents sent to a broker as documents of its
+// CouchDB database.
+type Ingress struct {
+	logger *zap.SugaredLogger
+	db     *kivik.DB
+}
+
+var _ http.Handler = (*Ingress)(nil)
+
+// NewIngress returns the Ingress persisting the events of a broker in db.
+func NewIngress(logger *zap.SugaredLogger, db *kivik.DB) *Ingress {
+	return &Ingress{logger: logger, db: db}
+}
+
+// ServeHTTP persists the event of the request. It responds 202 Accepted once
+// the event is written, for the filter to deliver it from the _changes feed,
+// 400 Bad Request for an event that cannot be written, and 502 Bad Gateway
+// when CouchDB fails, for the sender to retry the event.
+func (i *Ingress) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	event, err := binding.ToEvent(req.Context(), cehttp.NewMessageFromHttpRequest(req))
+	if err != nil {
+		http.Error(w, fmt.Sprint("invalid CloudEvent: ", err), http.StatusBadRequest)
+		return
+	}
+
+	logger := i.logger.With(zap.String("event", event.ID()))
+	id, _, err := i.db.CreateDoc(req.Context(), &database.Document{Event: *event})
+	if err != nil {
+		status := component.WriteStatus(err)
+		logger.Warnw("Unable to persist the event", zap.Int("status", status), zap.Error(err))
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Debugw("Persisted the event", zap.String("id", id))
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/binding"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+	"github.com/go-kivik/kivik/v3"
+	"github.com/go-kivik/kivikmock/v3"
+	"go.uber.org/zap"
+)
+
+func newEvent(id string) cloudevents.Event {
+	event := cloudevents.NewEvent()
+	event.SetID(id)
+	event.SetSource("/orders")
+	event.SetType("order.created")
+	event.SetData(cloudevents.ApplicationJSON, map[string]interface{}{"total": 3})
+	return event
+}
+
+func TestIngressServeHTTP(t *testing.T) {
+	testCases := map[string]struct {
+		method     string
+		body       string
+		expect     func(db *kivikmock.DB)
+		wantStatus int
+	}{
+		"persisted": {
+			expect: func(db *kivikmock.DB) {
+				db.ExpectCreateDoc().WithDoc(map[string]interface{}{
+					"event": map[string]interface{}{
+						"specversion":     "1.0",
+						"id":              "abc",
+						"source":          "/orders",
+						"type":            "order.created",
+						"datacontenttype": "application/json",
+						"data":            map[string]interface{}{"total": 3},
+					},
+				}).WillReturn("1234", "1-a")
+			},
+			wantStatus: http.StatusAccepted,
+		},
+		"not a POST": {
+			method:     http.MethodGet,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		"not an event": {
+			body:       "{}",
+			wantStatus: http.StatusBadRequest,
+		},
+		"rejected by CouchDB": {
+			expect: func(db *kivikmock.DB) {
+				db.ExpectCreateDoc().WillReturnError(&kivik.Error{HTTPStatus: http.StatusRequestEntityTooLarge})
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		"CouchDB unavailable": {
+			expect: func(db *kivikmock.DB) {
+				db.ExpectCreateDoc().WillReturnError(&kivik.Error{HTTPStatus: http.StatusServiceUnavailable})
+			},
+			wantStatus: http.StatusBadGateway,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			client, mock := kivikmock.NewT(t)
+			db := mock.NewDB()
+			mock.ExpectDB().WithName("broker").WillReturn(db)
+			if tc.expect != nil {
+				tc.expect(db)
+			}
+			i := &Ingress{
+				logger: zap.NewNop().Sugar(),
+				db:     client.DB(context.Background(), "broker"),
+			}
+
+			method := tc.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			req := httptest.NewRequest(method, "/", strings.NewReader(tc.body))
+			if tc.body == "" {
+				event := newEvent("abc")
+				if err := cehttp.WriteRequest(context.Background(), binding.ToMessage(&event), req); err != nil {
+					t.Fatal(err)
+				}
+			}
+			w := httptest.NewRecorder()
+			i.ServeHTTP(w, req)
+			if w.Code != tc.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tc.wantStatus, w.Body.String())
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	appsv1listers "k8s.io/client-go/listers/apps/v1"
+	eventingv1 "knative.dev/eventing/pkg/apis/eventing/v1"
+	brokerreconciler "knative.dev/eventing/pkg/client/injection/reconciler/eventing/v1/broker"
+	eventinglisters "knative.dev/eventing/pkg/client/listers/eventing/v1"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/network"
+	pkgreconciler "knative.dev/pkg/reconciler"
+
+	"knative.dev/eventing-couchdb/broker/pkg/reconciler/resources"
+	"knative.dev/eventing-couchdb/pkg/component"
+)
+
+const (
+	// Name of the corev1.Events emitted from the reconciliation process
+	couchdbBrokerDeploymentCreated = "CouchDbBrokerDeploymentCreated"
+	couchdbBrokerDeploymentUpdated = "CouchDbBrokerDeploymentUpdated"
+	couchdbBrokerServiceCreated    = "CouchDbBrokerServiceCreated"
+	couchdbBrokerServiceUpdated    = "CouchDbBrokerServiceUpdated"
+
+	// ingressImageEnvVar and filterImageEnvVar are the environment variables
+	// of the controller holding the images of the ingresses and of the
+	// filters.
+	ingressImageEnvVar = "COUCHDB_BROKER_INGRESS_IMAGE"
+	filterImageEnvVar  = "COUCHDB_BROKER_FILTER_IMAGE"
+)
+
+// brokerCondSet is the condition set of the CouchDB brokers, which persist
+// their events in CouchDB rather than in a trigger channel.
+var brokerCondSet = apis.NewLivingConditionSet(
+	eventingv1.BrokerConditionIngress,
+	eventingv1.BrokerConditionFilter,
+	eventingv1.BrokerConditionAddressable,
+)
+
+// Reconciler reconciles the ingress and the filter of the CouchDB brokers.
+type Reconciler struct {
+	ingressImage string
+	filterImage  string
+
+	kubeClientSet    kubernetes.Interface
+	deploymentLister appsv1listers.DeploymentLister
+	triggerLister    eventinglisters.TriggerLister
+}
+
+// Check that our Reconciler implements Interface
+var _ brokerreconciler.Interface = (*Reconciler)(nil)
+
+// ReconcileKind implements Interface.ReconcileKind.
+func (r *Reconciler) ReconcileKind(ctx context.Context, b *eventingv1.Broker) pkgreconciler.Event {
+	b.Status.InitializeConditions()
+
+	config, err := r.readConfig(ctx, b)
+	if err != nil {
+		b.Status.MarkIngressFailed("InvalidConfig", "Unable to read the config: %v", err)
+		b.Status.MarkFilterFailed("InvalidConfig", "Unable to read the config: %v", err)
+		return err
+	}
+
+	ingressArgs := &resources.IngressArgs{
+		Image:  r.ingressImage,
+		Broker: b,
+		Config: config,
+		Labels: resources.Labels(b.Name, resources.IngressRole),
+	}
+	ingress, err := r.reconcileDeployment(ctx, b, resources.MakeIngress(ingressArgs))
+	if err != nil {
+		b.Status.MarkIngressFailed("DeploymentFailed", "Unable to reconcile the ingress: %v", err)
+		return err
+	}
+	if deploymentAvailable(ingress) {
+		brokerCondSet.Manage(&b.Status).MarkTrue(eventingv1.BrokerConditionIngress)
+	} else {
+		b.Status.MarkIngressFailed("DeploymentUnavailable", "The ingress %q is unavailable.", ingress.Name)
+	}
+
+	svc, err := r.reconcileService(ctx, b, resources.MakeIngressService(ingressArgs))
+	if err != nil {
+		b.Status.SetAddress(nil)
+		return err
+	}
+	address := &apis.URL{
+		Scheme: "http",
+		Host:   network.GetServiceHostname(svc.Name, svc.Namespace),
+	}
+	b.Status.SetAddress(address)
+
+	triggers, err := r.listTriggers(b)
+	if err != nil {
+		b.Status.MarkFilterFailed("TriggersFailed", "Unable to list the triggers: %v", err)
+		return err
+	}
+	expected, err := resources.MakeFilter(&resources.FilterArgs{
+		Image:      r.filterImage,
+		Broker:     b,
+		Config:     config,
+		Labels:     resources.Labels(b.Name, resources.FilterRole),
+		IngressURI: address,
+		Triggers:   triggers,
+	})
+	if err != nil {
+		b.Status.MarkFilterFailed("DeploymentFailed", "Unable to make the filter: %v", err)
+		return err
+	}
+	filter, err := r.reconcileDeployment(ctx, b, expected)
+	if err != nil {
+		b.Status.MarkFilterFailed("DeploymentFailed", "Unable to reconcile the filter: %v", err)
+		return err
+	}
+	if deploymentAvailable(filter) {
+		brokerCondSet.Manage(&b.Status).MarkTrue(eventingv1.BrokerConditionFilter)
+	} else {
+		b.Status.MarkFilterFailed("DeploymentUnavailable", "The filter %q is unavailable.", filter.Name)
+	}
+	return nil
+}
+
+// readConfig returns the configuration of the broker, from the ConfigMap its
+// spec.config references. The ConfigMaps are not watched, their changes are
+// applied on the next resync.
+func (r *Reconciler) readConfig(ctx context.Context, b *eventingv1.Broker) (*resources.Config, error) {
+	ref := b.Spec.Config
+	if ref == nil {
+		return nil, fmt.Errorf("missing spec.config")
+	}
+	if ref.Kind != "ConfigMap" {
+		return nil, fmt.Errorf("spec.config references a %s, not a ConfigMap", ref.Kind)
+	}
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = b.Namespace
+	}
+	cm, err := r.kubeClientSet.CoreV1().ConfigMaps(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return resources.ConfigFromConfigMap(cm)
+}
+
+// listTriggers returns the triggers of the broker whose subscriber is
+// resolved, with their delivery, or else the one of the broker, and its dead
+// letter sink resolved by the trigger reconciler. They are sorted by name, so
+// that the filter only rolls out when they change.
+func (r *Reconciler) listTriggers(b *eventingv1.Broker) ([]resources.Trigger, error) {
+	all, err := r.triggerLister.Triggers(b.Namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Name < all[j].Name
+	})
+
+	var triggers []resources.Trigger
+	for _, t := range all {
+		if t.Spec.Broker != b.Name || t.DeletionTimestamp != nil || t.Status.SubscriberURI == nil {
+			continue
+		}
+		trigger := resources.Trigger{
+			UID:               t.UID,
+			SubscriberURI:     t.Status.SubscriberURI,
+			DeadLetterSinkURI: t.Status.DeadLetterSinkURI,
+		}
+		if t.Spec.Filter != nil {
+			trigger.Attributes = t.Spec.Filter.Attributes
+		}
+		delivery := t.Spec.Delivery
+		if delivery == nil {
+			delivery = b.Spec.Delivery
+		}
+		if delivery != nil {
+			trigger.Delivery = delivery.DeepCopy()
+			trigger.Delivery.DeadLetterSink = nil
+		}
+		triggers = append(triggers, trigger)
+	}
+	return triggers, nil
+}
+
+// deploymentAvailable is whether the Deployment reports the Available
+// condition.
+func deploymentAvailable(d *appsv1.Deployment) bool {
+	for _, c := range d.Status.Conditions {
+		if c.Type == appsv1.DeploymentAvailable {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// reconcileDeployment creates a Deployment of the broker, or updates it when
+// its pod template changed.
+func (r *Reconciler) reconcileDeployment(ctx context.Context, b *eventingv1.Broker, expected *appsv1.Deployment) (*appsv1.Deployment, error) {
+	d, err := r.deploymentLister.Deployments(b.Namespace).Get(expected.Name)
+	if apierrors.IsNotFound(err) {
+		d, err = r.kubeClientSet.AppsV1().Deployments(b.Namespace).Create(ctx, expected, metav1.CreateOptions{})
+		if err != nil {
+			return nil, err
+		}
+		controller.GetEventRecorder(ctx).Eventf(b, corev1.EventTypeNormal, couchdbBrokerDeploymentCreated, "Deployment %q created", d.Name)
+		return d, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error getting the deployment %q: %w", expected.Name, err)
+	} else if !metav1.IsControlledBy(d, b) {
+		return nil, fmt.Errorf("deployment %q is not owned by Broker %q", d.Name, b.Name)
+	}
+
+	if !component.PodSpecChanged(d.Spec.Template.Spec, expected.Spec.Template.Spec) &&
+		equality.Semantic.DeepEqual(d.Spec.Template.Labels, expected.Spec.Template.Labels) {
+		logging.FromContext(ctx).Debugw("Reusing the existing deployment", zap.String("deployment", d.Name))
+		return d, nil
+	}
+	d = d.DeepCopy()
+	d.Spec.Template.Spec = expected.Spec.Template.Spec
+	d.Spec.Template.Labels = expected.Spec.Template.Labels
+	if d, err = r.kubeClientSet.AppsV1().Deployments(b.Namespace).Update(ctx, d, metav1.UpdateOptions{}); err != nil {
+		return nil, err
+	}
+	controller.GetEventRecorder(ctx).Eventf(b, corev1.EventTypeNormal, couchdbBrokerDeploymentUpdated, "Deployment %q updated", d.Name)
+	return d, nil
+}
+
+// reconcileService creates the Service of the ingress, or updates it when its
+// selector or ports changed.
+func (r *Reconciler) reconcileService(ctx context.Context, b *eventingv1.Broker, expected *corev1.Service) (*corev1.Service, error) {
+	// The Services are not watched, only the reconciler changes them.
+	svc, err := r.kubeClientSet.CoreV1().Services(b.Namespace).Get(ctx, expected.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		svc, err = r.kubeClientSet.CoreV1().Services(b.Namespace).Create(ctx, expected, metav1.CreateOptions{})
+		if err != nil {
+			return nil, err
+		}
+		controller.GetEventRecorder(ctx).Event(b, corev1.EventTypeNormal, couchdbBrokerServiceCreated, "Service created")
+		return svc, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error getting the service: %w", err)
+	} else if !metav1.IsControlledBy(svc, b) {
+		return nil, fmt.Errorf("service %q is not owned by Broker %q", svc.Name, b.Name)
+	}
+
+	if !component.ServiceChanged(svc, expected) {
+		return svc, nil
+	}
+	svc = svc.DeepCopy()
+	svc.Spec.Selector = expected.Spec.Selector
+	svc.Spec.Ports = expected.Spec.Ports
+	if svc, err = r.kubeClientSet.CoreV1().Services(b.Namespace).Update(ctx, svc, metav1.UpdateOptions{}); err != nil {
+		return nil, err
+	}
+	controller.GetEventRecorder(ctx).Event(b, corev1.EventTypeNormal, couchdbBrokerServiceUpdated, "Service updated")
+	return svc, nil
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+	eventingv1 "knative.dev/eventing/pkg/apis/eventing/v1"
+	eventinglisters "knative.dev/eventing/pkg/client/listers/eventing/v1"
+	"knative.dev/pkg/apis"
+
+	"knative.dev/eventing-couchdb/broker/pkg/reconciler/resources"
+	"knative.dev/eventing-couchdb/pkg/component"
+)
+
+func TestPodSpecChanged(t *testing.T) {
+	b := &eventingv1.Broker{ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "ns", UID: "1234"}}
+	args := &resources.FilterArgs{
+		Image:      "filter",
+		Broker:     b,
+		Config:     &resources.Config{Credentials: "couchdb"},
+		Labels:     resources.Labels(b.Name, resources.FilterRole),
+		IngressURI: apis.HTTP("ingress"),
+	}
+	expected, err := resources.MakeFilter(args)
+	if err != nil {
+		t.Fatal("MakeFilter() =", err)
+	}
+
+	// The API server defaults some fields of the pod spec.
+	defaulted := *expected.Spec.Template.Spec.DeepCopy()
+	defaulted.RestartPolicy = corev1.RestartPolicyAlways
+	defaulted.Containers[0].TerminationMessagePath = "/dev/termination-log"
+	if component.PodSpecChanged(defaulted, expected.Spec.Template.Spec) {
+		t.Error("PodSpecChanged() = true for the defaulted pod spec")
+	}
+
+	args.Triggers = []resources.Trigger{{UID: "5678", SubscriberURI: apis.HTTP("subscriber")}}
+	changed, err := resources.MakeFilter(args)
+	if err != nil {
+		t.Fatal("MakeFilter() =", err)
+	}
+	if !component.PodSpecChanged(defaulted, changed.Spec.Template.Spec) {
+		t.Error("PodSpecChanged() = false after a trigger was added")
+	}
+}
+
+func TestListTriggers(t *testing.T) {
+	retry := int32(3)
+	brokerRetry := int32(1)
+	subscriber := apis.HTTP("subscriber.ns.svc.cluster.local")
+	dls := apis.HTTP("dls.ns.svc.cluster.local")
+
+	b := &eventingv1.Broker{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "ns"},
+		Spec: eventingv1.BrokerSpec{
+			Delivery: &eventingduckv1.DeliverySpec{Retry: &brokerRetry},
+		},
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, trigger := range []*eventingv1.Trigger{{
+		ObjectMeta: metav1.ObjectMeta{Name: "shipping", Namespace: "ns", UID: "2"},
+		Spec: eventingv1.TriggerSpec{
+			Broker:   "default",
+			Filter:   &eventingv1.TriggerFilter{Attributes: eventingv1.TriggerFilterAttributes{"type": "order.created"}},
+			Delivery: &eventingduckv1.DeliverySpec{Retry: &retry},
+		},
+		Status: eventingv1.TriggerStatus{SubscriberURI: subscriber, DeadLetterSinkURI: dls},
+	}, {
+		ObjectMeta: metav1.ObjectMeta{Name: "invoicing", Namespace: "ns", UID: "1"},
+		Spec:       eventingv1.TriggerSpec{Broker: "default"},
+		Status:     eventingv1.TriggerStatus{SubscriberURI: subscriber},
+	}, {
+		ObjectMeta: metav1.ObjectMeta{Name: "unresolved", Namespace: "ns", UID: "3"},
+		Spec:       eventingv1.TriggerSpec{Broker: "default"},
+	}, {
+		ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "ns", UID: "4"},
+		Spec:       eventingv1.TriggerSpec{Broker: "other"},
+		Status:     eventingv1.TriggerStatus{SubscriberURI: subscriber},
+	}} {
+		indexer.Add(trigger)
+	}
+
+	r := &Reconciler{triggerLister: eventinglisters.NewTriggerLister(indexer)}
+	got, err := r.listTriggers(b)
+	if err != nil {
+		t.Fatal("listTriggers() =", err)
+	}
+	want := []resources.Trigger{{
+		UID:           "1",
+		SubscriberURI: subscriber,
+		Delivery:      &eventingduckv1.DeliverySpec{Retry: &brokerRetry},
+	}, {
+		UID:               "2",
+		Attributes:        eventingv1.TriggerFilterAttributes{"type": "order.created"},
+		SubscriberURI:     subscriber,
+		DeadLetterSinkURI: dls,
+		Delivery:          &eventingduckv1.DeliverySpec{Retry: &retry},
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error("Unexpected triggers (-want, +got) =", diff)
+	}
+}
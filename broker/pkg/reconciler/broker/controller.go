@@ -0,0 +1,96 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"context"
+	"os"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/cache"
+	eventingv1 "knative.dev/eventing/pkg/apis/eventing/v1"
+	brokerinformer "knative.dev/eventing/pkg/client/injection/informers/eventing/v1/broker"
+	triggerinformer "knative.dev/eventing/pkg/client/injection/informers/eventing/v1/trigger"
+	brokerreconciler "knative.dev/eventing/pkg/client/injection/reconciler/eventing/v1/broker"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
+	deploymentinformer "knative.dev/pkg/client/injection/kube/informers/apps/v1/deployment"
+	"knative.dev/pkg/configmap"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+	pkgreconciler "knative.dev/pkg/reconciler"
+
+	"knative.dev/eventing-couchdb/broker/pkg/reconciler/resources"
+)
+
+func init() {
+	eventingv1.AddToScheme(scheme.Scheme)
+}
+
+// NewController initializes the controller and is called by the generated code
+// Registers event handlers to enqueue events
+func NewController(
+	ctx context.Context,
+	cmw configmap.Watcher,
+) *controller.Impl {
+	deploymentInformer := deploymentinformer.Get(ctx)
+	brokerInformer := brokerinformer.Get(ctx)
+	triggerInformer := triggerinformer.Get(ctx)
+
+	eventingv1.RegisterAlternateBrokerConditionSet(brokerCondSet)
+
+	ingressImage, defined := os.LookupEnv(ingressImageEnvVar)
+	if !defined {
+		logging.FromContext(ctx).Errorf("required environment variable %q not defined", ingressImageEnvVar)
+		return nil
+	}
+	filterImage, defined := os.LookupEnv(filterImageEnvVar)
+	if !defined {
+		logging.FromContext(ctx).Errorf("required environment variable %q not defined", filterImageEnvVar)
+		return nil
+	}
+
+	r := &Reconciler{
+		ingressImage:     ingressImage,
+		filterImage:      filterImage,
+		kubeClientSet:    kubeclient.Get(ctx),
+		deploymentLister: deploymentInformer.Lister(),
+		triggerLister:    triggerInformer.Lister(),
+	}
+	impl := brokerreconciler.NewImpl(ctx, r, resources.BrokerClass)
+
+	logging.FromContext(ctx).Info("Setting up event handlers")
+	brokerInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: pkgreconciler.AnnotationFilterFunc(brokerreconciler.ClassAnnotationKey, resources.BrokerClass, false),
+		Handler:    controller.HandleAll(impl.Enqueue),
+	})
+
+	deploymentInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: controller.FilterControllerGK(eventingv1.Kind("Broker")),
+		Handler:    controller.HandleAll(impl.EnqueueControllerOf),
+	})
+
+	// The filter rolls out whenever the triggers of the broker change. The
+	// brokers of the other classes are skipped by the generated reconciler.
+	triggerInformer.Informer().AddEventHandler(controller.HandleAll(func(obj interface{}) {
+		if t, ok := obj.(*eventingv1.Trigger); ok {
+			impl.EnqueueKey(types.NamespacedName{Namespace: t.Namespace, Name: t.Spec.Broker})
+		}
+	}))
+
+	return impl
+}
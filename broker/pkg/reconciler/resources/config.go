@@ -0,0 +1,70 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	eventingv1 "knative.dev/eventing/pkg/apis/eventing/v1"
+)
+
+const (
+	// BrokerClass is the eventing.knative.dev/broker.class of the brokers
+	// persisting their events in CouchDB.
+	BrokerClass = "CouchDB"
+
+	// CredentialsKey is the key of the broker ConfigMap holding the name of
+	// the credentials Secret, in the namespace of the broker.
+	CredentialsKey = "credentials"
+
+	// ServiceAccountNameKey is the key of the broker ConfigMap holding the
+	// service account the ingress and the filter run as.
+	ServiceAccountNameKey = "serviceAccountName"
+)
+
+// Config is the configuration of a broker, read from the ConfigMap its
+// spec.config references.
+type Config struct {
+	// Credentials is the Secret whose url holds the URL of CouchDB.
+	Credentials string
+
+	// ServiceAccountName is the service account of the ingress and the
+	// filter, if any.
+	ServiceAccountName string
+}
+
+// ConfigFromConfigMap returns the configuration of the broker held by the
+// ConfigMap.
+func ConfigFromConfigMap(cm *corev1.ConfigMap) (*Config, error) {
+	config := &Config{
+		Credentials:        cm.Data[CredentialsKey],
+		ServiceAccountName: cm.Data[ServiceAccountNameKey],
+	}
+	if config.Credentials == "" {
+		return nil, fmt.Errorf("missing %q key in ConfigMap %s/%s", CredentialsKey, cm.Namespace, cm.Name)
+	}
+	return config, nil
+}
+
+// DatabaseName returns the database the events of the broker are persisted
+// in.
+func DatabaseName(broker *eventingv1.Broker) string {
+	return strings.ToLower(strings.ReplaceAll(fmt.Sprintf("broker-%s-%s", broker.Namespace, broker.Name), ".", "-"))
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	eventingv1 "knative.dev/eventing/pkg/apis/eventing/v1"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/kmeta"
+)
+
+// DispatcherPort is the port the ingress receives the events on, and the
+// filter answers its readiness probe on.
+const DispatcherPort = 8080
+
+// CredentialsVolumeName is the volume of the credentials of the broker.
+const CredentialsVolumeName = "couchdb-credentials"
+
+// IngressArgs are the arguments needed to create the ingress of a broker.
+// Every field is required.
+type IngressArgs struct {
+	Image  string
+	Broker *eventingv1.Broker
+	Config *Config
+	Labels map[string]string
+}
+
+// FilterArgs are the arguments needed to create the filter of a broker.
+// Every field is required, but Triggers.
+type FilterArgs struct {
+	Image  string
+	Broker *eventingv1.Broker
+	Config *Config
+	Labels map[string]string
+
+	// IngressURI is where the replies of the triggers are sent.
+	IngressURI *apis.URL
+	Triggers   []Trigger
+}
+
+// IngressName returns the name of the Deployment and the Service of the
+// ingress of the broker.
+func IngressName(broker *eventingv1.Broker) string {
+	return kmeta.ChildName(fmt.Sprintf("couchdbbroker-%s-ingress-", broker.Name), string(broker.UID))
+}
+
+// FilterName returns the name of the Deployment of the filter of the broker.
+func FilterName(broker *eventingv1.Broker) string {
+	return kmeta.ChildName(fmt.Sprintf("couchdbbroker-%s-filter-", broker.Name), string(broker.UID))
+}
+
+func makeObjectMeta(broker *eventingv1.Broker, name string, labels map[string]string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: broker.Namespace,
+		Name:      name,
+		Labels:    labels,
+		OwnerReferences: []metav1.OwnerReference{
+			*kmeta.NewControllerRef(broker),
+		},
+	}
+}
+
+// makeDeployment returns a Deployment of the broker running a single
+// container of the image in the role, with the credentials of the broker and
+// env.
+func makeDeployment(broker *eventingv1.Broker, config *Config, role, name, image string, labels map[string]string, env []corev1.EnvVar) *appsv1.Deployment {
+	replicas := int32(1)
+	return &appsv1.Deployment{
+		ObjectMeta: makeObjectMeta(broker, name, labels),
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: config.ServiceAccountName,
+					Containers: []corev1.Container{{
+						Name:  role,
+						Image: image,
+						Ports: []corev1.ContainerPort{{
+							Name:          "http",
+							ContainerPort: DispatcherPort,
+						}},
+						Env: append([]corev1.EnvVar{{
+							Name:  "PORT",
+							Value: fmt.Sprint(DispatcherPort),
+						}, {
+							Name:  "K_LOGGING_CONFIG",
+							Value: "",
+						}, {
+							Name:  "COUCHDB_CREDENTIALS",
+							Value: "/etc/couchdb-credentials",
+						}, {
+							Name:  "COUCHDB_DATABASE",
+							Value: DatabaseName(broker),
+						}}, env...),
+						VolumeMounts: []corev1.VolumeMount{{
+							Name:      CredentialsVolumeName,
+							MountPath: "/etc/couchdb-credentials",
+							ReadOnly:  true,
+						}},
+						ReadinessProbe: &corev1.Probe{
+							Handler: corev1.Handler{
+								HTTPGet: &corev1.HTTPGetAction{
+									Path: "/healthz",
+									Port: intstr.FromInt(DispatcherPort),
+								},
+							},
+						},
+					}},
+					Volumes: []corev1.Volume{{
+						Name: CredentialsVolumeName,
+						VolumeSource: corev1.VolumeSource{
+							Secret: &corev1.SecretVolumeSource{
+								SecretName: config.Credentials,
+							},
+						},
+					}},
+				},
+			},
+		},
+	}
+}
+
+// MakeIngress generates (but does not insert into K8s) the ingress
+// Deployment of the broker, which persists the events sent to the broker.
+func MakeIngress(args *IngressArgs) *appsv1.Deployment {
+	return makeDeployment(args.Broker, args.Config, IngressRole, IngressName(args.Broker), args.Image, args.Labels, nil)
+}
+
+// MakeFilter generates (but does not insert into K8s) the filter Deployment
+// of the broker. The triggers are part of its environment, so that the
+// filter rolls out whenever they change.
+func MakeFilter(args *FilterArgs) (*appsv1.Deployment, error) {
+	triggers, err := json.Marshal(args.Triggers)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode the triggers: %w", err)
+	}
+	// A single replica tails the _changes feed, for every trigger to receive
+	// each event once. The rollouts briefly run two of them, which may
+	// deliver some events twice.
+	return makeDeployment(args.Broker, args.Config, FilterRole, FilterName(args.Broker), args.Image, args.Labels, []corev1.EnvVar{{
+		Name:  "COUCHDB_BROKER_INGRESS",
+		Value: args.IngressURI.String(),
+	}, {
+		Name:  TriggersEnvKey,
+		Value: string(triggers),
+	}}), nil
+}
+
+// MakeIngressService generates (but does not insert into K8s) the Service
+// the events are sent to, in front of the ingress.
+func MakeIngressService(args *IngressArgs) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: makeObjectMeta(args.Broker, IngressName(args.Broker), args.Labels),
+		Spec: corev1.ServiceSpec{
+			Selector: args.Labels,
+			Ports: []corev1.ServicePort{{
+				Name:       "http",
+				Port:       80,
+				TargetPort: intstr.FromInt(DispatcherPort),
+			}},
+		},
+	}
+}
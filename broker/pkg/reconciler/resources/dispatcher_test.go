@@ -0,0 +1,157 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	eventingv1 "knative.dev/eventing/pkg/apis/eventing/v1"
+	"knative.dev/pkg/apis"
+)
+
+func newBroker() *eventingv1.Broker {
+	return &eventingv1.Broker{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "default",
+			Namespace: "ns",
+			UID:       "1234",
+		},
+	}
+}
+
+func env(d *appsv1.Deployment) map[string]string {
+	env := map[string]string{}
+	for _, e := range d.Spec.Template.Spec.Containers[0].Env {
+		env[e.Name] = e.Value
+	}
+	return env
+}
+
+func TestMakeIngress(t *testing.T) {
+	b := newBroker()
+	config := &Config{Credentials: "couchdb", ServiceAccountName: "broker"}
+	d := MakeIngress(&IngressArgs{Image: "ingress", Broker: b, Config: config, Labels: Labels(b.Name, IngressRole)})
+
+	if d.Name != IngressName(b) || d.Namespace != "ns" {
+		t.Errorf("ingress = %s/%s, want ns/%s", d.Namespace, d.Name, IngressName(b))
+	}
+	if !metav1.IsControlledBy(d, b) {
+		t.Error("the ingress is not controlled by the broker")
+	}
+	spec := d.Spec.Template.Spec
+	if spec.ServiceAccountName != "broker" {
+		t.Errorf("serviceAccountName = %q, want broker", spec.ServiceAccountName)
+	}
+	if got := spec.Volumes[0].Secret.SecretName; got != "couchdb" {
+		t.Errorf("credentials secret = %q, want couchdb", got)
+	}
+	want := map[string]string{
+		"PORT":                "8080",
+		"K_LOGGING_CONFIG":    "",
+		"COUCHDB_CREDENTIALS": "/etc/couchdb-credentials",
+		"COUCHDB_DATABASE":    "broker-ns-default",
+	}
+	if diff := cmp.Diff(want, env(d)); diff != "" {
+		t.Error("Unexpected env (-want, +got) =", diff)
+	}
+}
+
+func TestMakeFilter(t *testing.T) {
+	b := newBroker()
+	triggers := []Trigger{{
+		UID:           "5678",
+		Attributes:    map[string]string{"type": "order.created"},
+		SubscriberURI: apis.HTTP("subscriber.ns.svc.cluster.local"),
+	}}
+	d, err := MakeFilter(&FilterArgs{
+		Image:      "filter",
+		Broker:     b,
+		Config:     &Config{Credentials: "couchdb"},
+		Labels:     Labels(b.Name, FilterRole),
+		IngressURI: apis.HTTP("ingress.ns.svc.cluster.local"),
+		Triggers:   triggers,
+	})
+	if err != nil {
+		t.Fatal("MakeFilter() =", err)
+	}
+
+	if d.Name != FilterName(b) || d.Name == IngressName(b) {
+		t.Errorf("filter = %s, want %s", d.Name, FilterName(b))
+	}
+	want := map[string]string{
+		"PORT":                   "8080",
+		"K_LOGGING_CONFIG":       "",
+		"COUCHDB_CREDENTIALS":    "/etc/couchdb-credentials",
+		"COUCHDB_DATABASE":       "broker-ns-default",
+		"COUCHDB_BROKER_INGRESS": "http://ingress.ns.svc.cluster.local",
+		"COUCHDB_TRIGGERS":       `[{"uid":"5678","attributes":{"type":"order.created"},"subscriberUri":"http://subscriber.ns.svc.cluster.local"}]`,
+	}
+	if diff := cmp.Diff(want, env(d)); diff != "" {
+		t.Error("Unexpected env (-want, +got) =", diff)
+	}
+
+	got, err := TriggersOf(d)
+	if err != nil {
+		t.Fatal("TriggersOf() =", err)
+	}
+	if diff := cmp.Diff(triggers, got); diff != "" {
+		t.Error("Unexpected triggers (-want, +got) =", diff)
+	}
+}
+
+func TestMakeIngressService(t *testing.T) {
+	b := newBroker()
+	labels := Labels(b.Name, IngressRole)
+	svc := MakeIngressService(&IngressArgs{Image: "ingress", Broker: b, Config: &Config{Credentials: "couchdb"}, Labels: labels})
+
+	if svc.Name != IngressName(b) {
+		t.Errorf("service = %s, want %s", svc.Name, IngressName(b))
+	}
+	if !metav1.IsControlledBy(svc, b) {
+		t.Error("the service is not controlled by the broker")
+	}
+	if diff := cmp.Diff(labels, svc.Spec.Selector); diff != "" {
+		t.Error("Unexpected selector (-want, +got) =", diff)
+	}
+	if got := svc.Spec.Ports[0].TargetPort; got != intstr.FromInt(DispatcherPort) {
+		t.Errorf("targetPort = %v, want %d", got, DispatcherPort)
+	}
+}
+
+func TestConfigFromConfigMap(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "couchdb-broker"},
+		Data:       map[string]string{"credentials": "couchdb", "serviceAccountName": "broker"},
+	}
+	config, err := ConfigFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("ConfigFromConfigMap() =", err)
+	}
+	if diff := cmp.Diff(&Config{Credentials: "couchdb", ServiceAccountName: "broker"}, config); diff != "" {
+		t.Error("Unexpected config (-want, +got) =", diff)
+	}
+
+	delete(cm.Data, "credentials")
+	if _, err := ConfigFromConfigMap(cm); err == nil {
+		t.Error("ConfigFromConfigMap() = nil, want an error without credentials")
+	}
+}
@@ -0,0 +1,40 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+const (
+	// controllerAgentName is the string used by this controller to identify
+	// itself when creating events.
+	controllerAgentName = "couchdb-broker-controller"
+
+	// IngressRole is the role of the Deployment persisting the events sent
+	// to the broker.
+	IngressRole = "ingress"
+
+	// FilterRole is the role of the Deployment delivering the persisted
+	// events to the triggers.
+	FilterRole = "filter"
+)
+
+// Labels returns the labels of the Deployment of the broker with the role.
+func Labels(name, role string) map[string]string {
+	return map[string]string{
+		"eventing.knative.dev/broker":     name,
+		"eventing.knative.dev/brokerRole": role,
+		"app.kubernetes.io/managed-by":    controllerAgentName,
+	}
+}
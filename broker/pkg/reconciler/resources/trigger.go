@@ -0,0 +1,74 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/types"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+	eventingv1 "knative.dev/eventing/pkg/apis/eventing/v1"
+	"knative.dev/pkg/apis"
+)
+
+// TriggersEnvKey is the environment variable of the filter holding the
+// triggers of the broker, as a JSON array of Trigger.
+const TriggersEnvKey = "COUCHDB_TRIGGERS"
+
+// Trigger is a trigger of the broker as the filter delivers to it, its
+// subscriber and dead letter sink resolved to URIs.
+type Trigger struct {
+	// UID identifies the trigger, and its checkpoint in the database.
+	UID types.UID `json:"uid"`
+
+	// Attributes are the attributes the events must match exactly to be
+	// delivered, the empty values matching any.
+	// +optional
+	Attributes eventingv1.TriggerFilterAttributes `json:"attributes,omitempty"`
+
+	// SubscriberURI is where the events are sent.
+	SubscriberURI *apis.URL `json:"subscriberUri"`
+
+	// DeadLetterSinkURI is where the events that could not be delivered are
+	// sent, if any.
+	// +optional
+	DeadLetterSinkURI *apis.URL `json:"deadLetterSinkUri,omitempty"`
+
+	// Delivery holds the retries of the trigger, or else the ones of the
+	// broker, whose dead letter sink is resolved into DeadLetterSinkURI.
+	// +optional
+	Delivery *eventingduckv1.DeliverySpec `json:"delivery,omitempty"`
+}
+
+// TriggersOf returns the triggers the filter Deployment delivers to.
+func TriggersOf(d *appsv1.Deployment) ([]Trigger, error) {
+	for _, c := range d.Spec.Template.Spec.Containers {
+		for _, e := range c.Env {
+			if e.Name != TriggersEnvKey {
+				continue
+			}
+			var triggers []Trigger
+			if err := json.Unmarshal([]byte(e.Value), &triggers); err != nil {
+				return nil, fmt.Errorf("invalid %s of %s/%s: %w", TriggersEnvKey, d.Namespace, d.Name, err)
+			}
+			return triggers, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	eventingv1 "knative.dev/eventing/pkg/apis/eventing/v1"
+	brokerinformer "knative.dev/eventing/pkg/client/injection/informers/eventing/v1/broker"
+	triggerinformer "knative.dev/eventing/pkg/client/injection/informers/eventing/v1/trigger"
+	brokerreconciler "knative.dev/eventing/pkg/client/injection/reconciler/eventing/v1/broker"
+	triggerreconciler "knative.dev/eventing/pkg/client/injection/reconciler/eventing/v1/trigger"
+	eventinglisters "knative.dev/eventing/pkg/client/listers/eventing/v1"
+	deploymentinformer "knative.dev/pkg/client/injection/kube/informers/apps/v1/deployment"
+	"knative.dev/pkg/configmap"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/kmeta"
+	"knative.dev/pkg/logging"
+	pkgreconciler "knative.dev/pkg/reconciler"
+	"knative.dev/pkg/resolver"
+
+	"knative.dev/eventing-couchdb/broker/pkg/reconciler/resources"
+)
+
+// NewController initializes the controller and is called by the generated code
+// Registers event handlers to enqueue events
+func NewController(
+	ctx context.Context,
+	cmw configmap.Watcher,
+) *controller.Impl {
+	deploymentInformer := deploymentinformer.Get(ctx)
+	brokerInformer := brokerinformer.Get(ctx)
+	triggerInformer := triggerinformer.Get(ctx)
+
+	r := &Reconciler{
+		brokerLister:     brokerInformer.Lister(),
+		deploymentLister: deploymentInformer.Lister(),
+	}
+	impl := triggerreconciler.NewImpl(ctx, r)
+	r.sinkResolver = resolver.NewURIResolver(ctx, impl.EnqueueKey)
+
+	logging.FromContext(ctx).Info("Setting up event handlers")
+	triggerInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: couchdbTrigger(brokerInformer.Lister()),
+		Handler:    controller.HandleAll(impl.Enqueue),
+	})
+
+	// The triggers follow the status of their broker, and whether its filter
+	// delivers to them.
+	enqueueTriggers := triggersOf(triggerInformer.Lister(), impl)
+	brokerInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: pkgreconciler.AnnotationFilterFunc(brokerreconciler.ClassAnnotationKey, resources.BrokerClass, false),
+		Handler: controller.HandleAll(func(obj interface{}) {
+			if b, ok := obj.(*eventingv1.Broker); ok {
+				enqueueTriggers(b.Namespace, b.Name)
+			}
+		}),
+	})
+	deploymentInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: controller.FilterControllerGK(eventingv1.Kind("Broker")),
+		Handler: controller.HandleAll(func(obj interface{}) {
+			if m, err := kmeta.DeletionHandlingAccessor(obj); err == nil {
+				if owner := metav1.GetControllerOf(m); owner != nil {
+					enqueueTriggers(m.GetNamespace(), owner.Name)
+				}
+			}
+		}),
+	})
+
+	return impl
+}
+
+// couchdbTrigger returns a filter of the triggers of the CouchDB brokers.
+// The triggers of the brokers not created yet are enqueued once they are.
+func couchdbTrigger(brokerLister eventinglisters.BrokerLister) func(obj interface{}) bool {
+	return func(obj interface{}) bool {
+		t, ok := obj.(*eventingv1.Trigger)
+		if !ok {
+			return false
+		}
+		b, err := brokerLister.Brokers(t.Namespace).Get(t.Spec.Broker)
+		if err != nil {
+			return false
+		}
+		return b.GetAnnotations()[brokerreconciler.ClassAnnotationKey] == resources.BrokerClass
+	}
+}
+
+// triggersOf returns a function enqueuing the triggers of a broker.
+func triggersOf(triggerLister eventinglisters.TriggerLister, impl *controller.Impl) func(namespace, broker string) {
+	return func(namespace, broker string) {
+		triggers, err := triggerLister.Triggers(namespace).List(labels.Everything())
+		if err != nil {
+			return
+		}
+		for _, t := range triggers {
+			if t.Spec.Broker == broker {
+				impl.Enqueue(t)
+			}
+		}
+	}
+}
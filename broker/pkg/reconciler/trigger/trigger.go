@@ -0,0 +1,142 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	appsv1listers "k8s.io/client-go/listers/apps/v1"
+	eventingv1 "knative.dev/eventing/pkg/apis/eventing/v1"
+	brokerreconciler "knative.dev/eventing/pkg/client/injection/reconciler/eventing/v1/broker"
+	triggerreconciler "knative.dev/eventing/pkg/client/injection/reconciler/eventing/v1/trigger"
+	eventinglisters "knative.dev/eventing/pkg/client/listers/eventing/v1"
+	"knative.dev/pkg/apis"
+	pkgreconciler "knative.dev/pkg/reconciler"
+	"knative.dev/pkg/resolver"
+
+	"knative.dev/eventing-couchdb/broker/pkg/reconciler/resources"
+	"knative.dev/eventing-couchdb/pkg/component"
+)
+
+// Reconciler resolves the subscribers and the dead letter sinks of the
+// triggers of the CouchDB brokers, for the broker reconciler to hand them to
+// the filter, and reports whether the filter delivers to them.
+type Reconciler struct {
+	brokerLister     eventinglisters.BrokerLister
+	deploymentLister appsv1listers.DeploymentLister
+	sinkResolver     *resolver.URIResolver
+}
+
+// Check that our Reconciler implements Interface
+var _ triggerreconciler.Interface = (*Reconciler)(nil)
+
+// ReconcileKind implements Interface.ReconcileKind.
+func (r *Reconciler) ReconcileKind(ctx context.Context, t *eventingv1.Trigger) pkgreconciler.Event {
+	b, err := r.brokerLister.Brokers(t.Namespace).Get(t.Spec.Broker)
+	if apierrors.IsNotFound(err) {
+		t.Status.InitializeConditions()
+		t.Status.MarkBrokerFailed("BrokerDoesNotExist", "Broker %q does not exist", t.Spec.Broker)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if b.GetAnnotations()[brokerreconciler.ClassAnnotationKey] != resources.BrokerClass {
+		// The trigger of a broker of another class.
+		return nil
+	}
+
+	t.Status.InitializeConditions()
+	t.Status.PropagateBrokerCondition(b.Status.GetTopLevelCondition())
+	t.Status.MarkDependencySucceeded()
+
+	subscriber := t.Spec.Subscriber.DeepCopy()
+	if subscriber.Ref != nil && subscriber.Ref.Namespace == "" {
+		subscriber.Ref.Namespace = t.Namespace
+	}
+	uri, err := r.sinkResolver.URIFromDestinationV1(ctx, *subscriber, t)
+	if err != nil {
+		t.Status.SubscriberURI = nil
+		t.Status.MarkSubscriberResolvedFailed("Unable to get the Subscriber's URI", "%v", err)
+		return err
+	}
+	t.Status.SubscriberURI = uri
+	t.Status.MarkSubscriberResolvedSucceeded()
+
+	delivery := t.Spec.Delivery
+	if delivery == nil {
+		delivery = b.Spec.Delivery
+	}
+	if delivery == nil || delivery.DeadLetterSink == nil {
+		t.Status.DeadLetterSinkURI = nil
+		t.Status.MarkDeadLetterSinkNotConfigured()
+	} else {
+		dls := delivery.DeadLetterSink.DeepCopy()
+		if dls.Ref != nil && dls.Ref.Namespace == "" {
+			dls.Ref.Namespace = t.Namespace
+		}
+		uri, err := r.sinkResolver.URIFromDestinationV1(ctx, *dls, t)
+		if err != nil {
+			t.Status.DeadLetterSinkURI = nil
+			t.Status.MarkDeadLetterSinkResolvedFailed("Unable to get the DeadLetterSink's URI", "%v", err)
+			return err
+		}
+		t.Status.DeadLetterSinkURI = uri
+		t.Status.MarkDeadLetterSinkResolvedSucceeded()
+	}
+
+	return r.propagateSubscription(t, b)
+}
+
+// propagateSubscription marks the trigger subscribed once the filter of the
+// broker delivering to its resolved subscriber rolled out.
+func (r *Reconciler) propagateSubscription(t *eventingv1.Trigger, b *eventingv1.Broker) error {
+	filter, err := r.deploymentLister.Deployments(b.Namespace).Get(resources.FilterName(b))
+	if apierrors.IsNotFound(err) {
+		t.Status.MarkSubscribedUnknown("FilterNotFound", "The filter of the broker is not created yet.")
+		return nil
+	} else if err != nil {
+		return err
+	}
+	triggers, err := resources.TriggersOf(filter)
+	if err != nil {
+		t.Status.MarkNotSubscribed("FilterInvalid", "%v", err)
+		return err
+	}
+	if !delivers(triggers, t) || !component.RolledOut(filter) {
+		t.Status.MarkSubscribedUnknown("FilterNotUpdated", "The filter does not deliver to the trigger yet.")
+		return nil
+	}
+	t.Status.PropagateSubscriptionCondition(&apis.Condition{
+		Type:   apis.ConditionReady,
+		Status: corev1.ConditionTrue,
+	})
+	return nil
+}
+
+// delivers is whether the triggers of the filter hold the resolved
+// subscriber and dead letter sink of the trigger.
+func delivers(triggers []resources.Trigger, t *eventingv1.Trigger) bool {
+	for _, trigger := range triggers {
+		if trigger.UID == t.UID {
+			return trigger.SubscriberURI.String() == t.Status.SubscriberURI.String() &&
+				trigger.DeadLetterSinkURI.String() == t.Status.DeadLetterSinkURI.String()
+		}
+	}
+	return false
+}
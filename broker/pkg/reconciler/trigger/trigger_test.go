@@ -0,0 +1,62 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	eventingv1 "knative.dev/eventing/pkg/apis/eventing/v1"
+	"knative.dev/pkg/apis"
+
+	"knative.dev/eventing-couchdb/broker/pkg/reconciler/resources"
+)
+
+func TestDelivers(t *testing.T) {
+	subscriber := apis.HTTP("subscriber.ns.svc.cluster.local")
+	dls := apis.HTTP("dls.ns.svc.cluster.local")
+	trigger := &eventingv1.Trigger{
+		ObjectMeta: metav1.ObjectMeta{Name: "invoicing", Namespace: "ns", UID: "1234"},
+		Status:     eventingv1.TriggerStatus{SubscriberURI: subscriber, DeadLetterSinkURI: dls},
+	}
+
+	testCases := map[string]struct {
+		triggers []resources.Trigger
+		want     bool
+	}{
+		"delivered": {
+			triggers: []resources.Trigger{{UID: "5678", SubscriberURI: subscriber}, {UID: "1234", SubscriberURI: subscriber, DeadLetterSinkURI: dls}},
+			want:     true,
+		},
+		"missing": {
+			triggers: []resources.Trigger{{UID: "5678", SubscriberURI: subscriber}},
+		},
+		"other subscriber": {
+			triggers: []resources.Trigger{{UID: "1234", SubscriberURI: apis.HTTP("other.ns.svc.cluster.local"), DeadLetterSinkURI: dls}},
+		},
+		"no dead letter sink": {
+			triggers: []resources.Trigger{{UID: "1234", SubscriberURI: subscriber}},
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			if got := delivers(tc.triggers, trigger); got != tc.want {
+				t.Errorf("delivers() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
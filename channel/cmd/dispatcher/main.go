@@ -0,0 +1,124 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	_ "github.com/go-kivik/couchdb/v3" // The CouchDB driver
+	"github.com/go-kivik/kivik/v3"
+	"github.com/kelseyhightower/envconfig"
+	"go.uber.org/zap"
+	"knative.dev/eventing/pkg/kncloudevents"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/signals"
+
+	"knative.dev/eventing-couchdb/channel/pkg/dispatcher"
+)
+
+// shutdownTimeout bounds the time given to the events being persisted once
+// the dispatcher is asked to stop.
+const shutdownTimeout = 30 * time.Second
+
+// healthPath is the path the dispatcher answers its readiness probe on.
+const healthPath = "/healthz"
+
+type envConfig struct {
+	Port            int    `envconfig:"PORT" default:"8080"`
+	LoggingConfig   string `envconfig:"K_LOGGING_CONFIG"`
+	CredentialsPath string `envconfig:"COUCHDB_CREDENTIALS" required:"true"`
+	Database        string `envconfig:"COUCHDB_DATABASE" required:"true"`
+	Subscribers     string `envconfig:"COUCHDB_SUBSCRIBERS" default:"[]"`
+}
+
+func main() {
+	ctx := signals.NewContext()
+
+	var env envConfig
+	if err := envconfig.Process("", &env); err != nil {
+		log.Fatal("Error processing the environment: ", err)
+	}
+	logger, _ := logging.NewLogger(env.LoggingConfig, "info")
+	defer logger.Sync()
+
+	var subscribers []dispatcher.Subscriber
+	if err := json.Unmarshal([]byte(env.Subscribers), &subscribers); err != nil {
+		logger.Fatalw("Invalid subscribers", zap.Error(err))
+	}
+
+	url, err := ioutil.ReadFile(env.CredentialsPath + "/url")
+	if err != nil {
+		logger.Fatalw("Missing url key in secret", zap.Error(err))
+	}
+	client, err := kivik.New("couch", string(url))
+	if err != nil {
+		logger.Fatalw("Error creating the CouchDB client", zap.Error(err))
+	}
+	// The database of the channel is created along with its dispatcher.
+	exists, err := client.DBExists(ctx, env.Database)
+	if err != nil {
+		logger.Fatalw("Error checking the database", zap.String("database", env.Database), zap.Error(err))
+	}
+	if !exists {
+		if err := client.CreateDB(ctx, env.Database); err != nil && kivik.StatusCode(err) != http.StatusPreconditionFailed {
+			logger.Fatalw("Error creating the database", zap.String("database", env.Database), zap.Error(err))
+		}
+		logger.Infow("Created the database", zap.String("database", env.Database))
+	}
+	db := client.DB(ctx, env.Database)
+	if err := db.Err(); err != nil {
+		logger.Fatalw("Error opening the database", zap.String("database", env.Database), zap.Error(err))
+	}
+
+	sender, err := kncloudevents.NewHTTPMessageSenderWithTarget("")
+	if err != nil {
+		logger.Fatalw("Error creating the sender", zap.Error(err))
+	}
+	d := dispatcher.NewDispatcher(logger, db, sender, subscribers)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(healthPath, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.Handle("/", d)
+	server := &http.Server{Addr: ":" + strconv.Itoa(env.Port), Handler: mux}
+
+	dispatched := make(chan struct{})
+	go func() {
+		defer close(dispatched)
+		d.Start(ctx)
+	}()
+	go func() {
+		<-ctx.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
+	logger.Infow("Dispatching the events of the channel", zap.String("database", env.Database),
+		zap.Int("subscribers", len(subscribers)), zap.Int("port", env.Port))
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		logger.Fatalw("Error serving the events", zap.Error(err))
+	}
+	// Save the checkpoints of the subscribers before exiting.
+	<-dispatched
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+
+	"knative.dev/eventing/pkg/apis/messaging"
+)
+
+// SetDefaults implements apis.Defaultable.
+func (c *CouchDbChannel) SetDefaults(ctx context.Context) {
+	// Record the version of the Subscribable duck the channel implements,
+	// for the Subscriptions to write their subscribers in its shape.
+	if c.Annotations == nil {
+		c.Annotations = make(map[string]string)
+	}
+	if _, ok := c.Annotations[messaging.SubscribableDuckVersionAnnotation]; !ok {
+		c.Annotations[messaging.SubscribableDuckVersionAnnotation] = "v1"
+	}
+
+	// Spell out the database the events are persisted into. The channels
+	// created with a generateName have no name yet, and the controller falls
+	// back to DatabaseName for them.
+	if c.Spec.Database == "" && c.Name != "" {
+		c.Spec.Database = c.DatabaseName()
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/eventing/pkg/apis/messaging"
+)
+
+func TestCouchDbChannelSetDefaults(t *testing.T) {
+	testCases := map[string]struct {
+		channel      CouchDbChannel
+		wantDatabase string
+	}{
+		"named": {
+			channel:      CouchDbChannel{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "orders.v1"}},
+			wantDatabase: "channel-ns-orders-v1",
+		},
+		"database set": {
+			channel: CouchDbChannel{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "orders"},
+				Spec:       CouchDbChannelSpec{Database: "orders"},
+			},
+			wantDatabase: "orders",
+		},
+		"generated name": {
+			channel: CouchDbChannel{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", GenerateName: "orders-"}},
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			c := tc.channel
+			c.SetDefaults(context.Background())
+			if got := c.Spec.Database; got != tc.wantDatabase {
+				t.Errorf("Database = %q, want %q", got, tc.wantDatabase)
+			}
+			if got := c.Annotations[messaging.SubscribableDuckVersionAnnotation]; got != "v1" {
+				t.Errorf("Subscribable duck version = %q, want v1", got)
+			}
+		})
+	}
+}
+
+func TestCouchDbChannelSetDefaultsKeepsDuckVersion(t *testing.T) {
+	c := &CouchDbChannel{ObjectMeta: metav1.ObjectMeta{
+		Name:        "orders",
+		Annotations: map[string]string{messaging.SubscribableDuckVersionAnnotation: "v1beta1"},
+	}}
+	c.SetDefaults(context.Background())
+	if got := c.Annotations[messaging.SubscribableDuckVersionAnnotation]; got != "v1beta1" {
+		t.Errorf("Subscribable duck version = %q, want v1beta1", got)
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/eventing/pkg/apis/duck"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+)
+
+const (
+	// CouchDbChannelConditionReady has status True when the CouchDbChannel is ready to receive events.
+	CouchDbChannelConditionReady = apis.ConditionReady
+
+	// CouchDbChannelConditionDeployed has status True when the dispatcher of
+	// the CouchDbChannel is available.
+	CouchDbChannelConditionDeployed apis.ConditionType = "Deployed"
+
+	// CouchDbChannelConditionAddressable has status True when the
+	// CouchDbChannel has an address to send the events to.
+	CouchDbChannelConditionAddressable apis.ConditionType = "Addressable"
+)
+
+var CouchDbChannelCondSet = apis.NewLivingConditionSet(
+	CouchDbChannelConditionDeployed,
+	CouchDbChannelConditionAddressable,
+)
+
+// GetConditionSet retrieves the condition set for this resource. Implements the KRShaped interface.
+func (*CouchDbChannel) GetConditionSet() apis.ConditionSet {
+	return CouchDbChannelCondSet
+}
+
+// GetCondition returns the condition currently associated with the given type, or nil.
+func (s *CouchDbChannelStatus) GetCondition(t apis.ConditionType) *apis.Condition {
+	return CouchDbChannelCondSet.Manage(s).GetCondition(t)
+}
+
+// IsReady returns true if the resource is ready overall.
+func (s *CouchDbChannelStatus) IsReady() bool {
+	return CouchDbChannelCondSet.Manage(s).IsHappy()
+}
+
+// InitializeConditions sets relevant unset conditions to Unknown state.
+func (s *CouchDbChannelStatus) InitializeConditions() {
+	CouchDbChannelCondSet.Manage(s).InitializeConditions()
+}
+
+// MarkAddress sets the address of the CouchDbChannel, and the condition that
+// it is addressable when the address is not empty.
+func (s *CouchDbChannelStatus) MarkAddress(url *apis.URL) {
+	if url.IsEmpty() {
+		s.Address = nil
+		CouchDbChannelCondSet.Manage(s).MarkFalse(CouchDbChannelConditionAddressable, "AddressEmpty", "The address is empty.")
+		return
+	}
+	s.Address = &duckv1.Addressable{URL: url}
+	CouchDbChannelCondSet.Manage(s).MarkTrue(CouchDbChannelConditionAddressable)
+}
+
+// MarkNoAddress sets the condition that the CouchDbChannel has no address.
+func (s *CouchDbChannelStatus) MarkNoAddress(reason, messageFormat string, messageA ...interface{}) {
+	s.Address = nil
+	CouchDbChannelCondSet.Manage(s).MarkFalse(CouchDbChannelConditionAddressable, reason, messageFormat, messageA...)
+}
+
+// PropagateDeploymentAvailability uses the availability of the dispatcher
+// Deployment to determine the CouchDbChannelConditionDeployed condition.
+func (s *CouchDbChannelStatus) PropagateDeploymentAvailability(d *appsv1.Deployment) {
+	if duck.DeploymentIsAvailable(&d.Status, false) {
+		CouchDbChannelCondSet.Manage(s).MarkTrue(CouchDbChannelConditionDeployed)
+	} else {
+		CouchDbChannelCondSet.Manage(s).MarkFalse(CouchDbChannelConditionDeployed, "DeploymentUnavailable", "The Deployment '%s' is unavailable.", d.Name)
+	}
+}
+
+// MarkNoDeployment sets the condition that the dispatcher could not be
+// deployed.
+func (s *CouchDbChannelStatus) MarkNoDeployment(reason, messageFormat string, messageA ...interface{}) {
+	CouchDbChannelCondSet.Manage(s).MarkFalse(CouchDbChannelConditionDeployed, reason, messageFormat, messageA...)
+}
+
+// MarkSubscribers sets the status of the subscribers of the channel, which
+// are ready once a dispatcher that knows about them is available.
+func (s *CouchDbChannelStatus) MarkSubscribers(subscribers []eventingduckv1.SubscriberSpec, ready bool) {
+	s.Subscribers = make([]eventingduckv1.SubscriberStatus, 0, len(subscribers))
+	for _, sub := range subscribers {
+		status := eventingduckv1.SubscriberStatus{
+			UID:                sub.UID,
+			ObservedGeneration: sub.Generation,
+			Ready:              corev1.ConditionTrue,
+		}
+		if !ready {
+			status.Ready = corev1.ConditionUnknown
+			status.Message = "The dispatcher is not available yet."
+		}
+		s.Subscribers = append(s.Subscribers, status)
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/apis/duck"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"knative.dev/pkg/kmeta"
+)
+
+// +genclient
+// +genreconciler
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CouchDbChannel is a Channel persisting the CloudEvents it receives as
+// documents into a CouchDB database, and fanning them out to its subscribers
+// from the _changes feed of the database.
+// +k8s:openapi-gen=true
+type CouchDbChannel struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CouchDbChannelSpec   `json:"spec,omitempty"`
+	Status CouchDbChannelStatus `json:"status,omitempty"`
+}
+
+// Check that CouchDbChannel is a runtime.Object.
+var _ runtime.Object = (*CouchDbChannel)(nil)
+
+// Check that CouchDbChannel can be validated and can be defaulted.
+var _ apis.Validatable = (*CouchDbChannel)(nil)
+var _ apis.Defaultable = (*CouchDbChannel)(nil)
+
+// Check that we can create OwnerReferences to a CouchDbChannel.
+var _ kmeta.OwnerRefable = (*CouchDbChannel)(nil)
+
+// Check that the type conforms to the duck Knative Resource shape.
+var _ duckv1.KRShaped = (*CouchDbChannel)(nil)
+
+// Check that CouchDbChannel implements the Conditions, Addressable and
+// Channelable duck types.
+var _ = duck.VerifyType(&CouchDbChannel{}, &duckv1.Conditions{})
+var _ = duck.VerifyType(&CouchDbChannel{}, &duckv1.Addressable{})
+var _ = duck.VerifyType(&CouchDbChannel{}, &eventingduckv1.Channelable{})
+
+// CouchDbChannelSpec defines the desired state of CouchDbChannel
+type CouchDbChannelSpec struct {
+	// Channel conforms to the Channelable duck type: the subscribers and the
+	// delivery options of the channel.
+	eventingduckv1.ChannelableSpec `json:",inline"`
+
+	// ServiceAccountName holds the name of the Kubernetes service account
+	// as which the underlying K8s resources should be run. If unspecified
+	// this will default to the "default" service account for the namespace
+	// in which the CouchDbChannel exists.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// CouchDbCredentials is the Secret whose url key holds the url of
+	// CouchDB, with the credentials to create the database, write into it
+	// and read its _changes feed.
+	CouchDbCredentials corev1.ObjectReference `json:"credentials"`
+
+	// Database is the database the events are persisted into. The
+	// dispatcher creates it when missing. Defaults to
+	// channel-<namespace>-<name>.
+	// +optional
+	Database string `json:"database,omitempty"`
+}
+
+// CouchDbChannelStatus defines the observed state of CouchDbChannel
+type CouchDbChannelStatus struct {
+	// Channel conforms to the Channelable duck type: the address, the status
+	// of the subscribers and the dead letter sink of the channel.
+	eventingduckv1.ChannelableStatus `json:",inline"`
+}
+
+// DatabaseName returns the database of the channel, defaulting to one named
+// after its namespace and name.
+func (c *CouchDbChannel) DatabaseName() string {
+	if c.Spec.Database != "" {
+		return c.Spec.Database
+	}
+	// CouchDB rejects dots and uppercase letters in database names.
+	return strings.ToLower(strings.ReplaceAll("channel-"+c.Namespace+"-"+c.Name, ".", "-"))
+}
+
+// GetGroupVersionKind returns the GroupVersionKind.
+func (*CouchDbChannel) GetGroupVersionKind() schema.GroupVersionKind {
+	return SchemeGroupVersion.WithKind("CouchDbChannel")
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CouchDbChannelList contains a list of CouchDbChannel
+type CouchDbChannelList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CouchDbChannel `json:"items"`
+}
+
+// GetStatus retrieves the duck status for this resource. Implements the KRShaped interface.
+func (c *CouchDbChannel) GetStatus() *duckv1.Status {
+	return &c.Status.Status
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"knative.dev/pkg/apis"
+)
+
+// databaseNameRegexp matches the names CouchDB accepts for databases.
+var databaseNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9_$()+/-]*$`)
+
+// Validate implements apis.Validatable.
+func (c *CouchDbChannel) Validate(ctx context.Context) *apis.FieldError {
+	errs := c.Spec.Validate(apis.WithinParent(ctx, c.ObjectMeta)).ViaField("spec")
+
+	// The database of a channel holds its events, which would be lost or
+	// mixed with the ones of another channel if it changed.
+	if original, ok := apis.GetBaseline(ctx).(*CouchDbChannel); ok {
+		if original.DatabaseName() != c.DatabaseName() {
+			errs = errs.Also(&apis.FieldError{
+				Message: "Immutable fields changed (-old +new)",
+				Paths:   []string{"spec.database"},
+				Details: fmt.Sprintf("-%s +%s", original.DatabaseName(), c.DatabaseName()),
+			})
+		}
+	}
+	return errs
+}
+
+// Validate checks the spec of the channel once defaulted.
+func (cs *CouchDbChannelSpec) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+
+	if cs.ServiceAccountName != "" {
+		if msgs := validation.IsDNS1123Subdomain(cs.ServiceAccountName); len(msgs) > 0 {
+			fe := apis.ErrInvalidValue(cs.ServiceAccountName, "serviceAccountName")
+			fe.Details = strings.Join(msgs, ", ")
+			errs = errs.Also(fe)
+		}
+	}
+
+	if cs.CouchDbCredentials.Name == "" {
+		errs = errs.Also(apis.ErrMissingField("credentials.name"))
+	}
+
+	if cs.Database != "" && !databaseNameRegexp.MatchString(cs.Database) {
+		fe := apis.ErrInvalidValue(cs.Database, "database")
+		fe.Details = "database names start with a lowercase letter, followed by lowercase letters, digits and any of _$()+-/"
+		errs = errs.Also(fe)
+	}
+
+	for i, sub := range cs.Subscribers {
+		if sub.SubscriberURI == nil && sub.ReplyURI == nil {
+			fe := apis.ErrMissingField("replyUri", "subscriberUri")
+			fe.Details = "expected at least one of, got none"
+			errs = errs.Also(fe.ViaFieldIndex("subscribers", i))
+		}
+	}
+
+	return errs.Also(cs.Delivery.Validate(ctx).ViaField("delivery"))
+}
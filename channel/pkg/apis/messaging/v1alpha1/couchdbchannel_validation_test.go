@@ -0,0 +1,131 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+	"knative.dev/pkg/apis"
+)
+
+func TestCouchDbChannelValidate(t *testing.T) {
+	valid := func() CouchDbChannelSpec {
+		return CouchDbChannelSpec{
+			CouchDbCredentials: corev1.ObjectReference{Name: "couchdb"},
+			Database:           "channel-ns-orders",
+		}
+	}
+	subscriber := apis.HTTP("subscriber.ns.svc.cluster.local")
+
+	testCases := map[string]struct {
+		spec    func(*CouchDbChannelSpec)
+		wantErr *apis.FieldError
+	}{
+		"valid": {
+			spec: func(*CouchDbChannelSpec) {},
+		},
+		"without database": {
+			spec: func(s *CouchDbChannelSpec) {
+				s.Database = ""
+			},
+		},
+		"with subscribers": {
+			spec: func(s *CouchDbChannelSpec) {
+				s.Subscribers = []eventingduckv1.SubscriberSpec{{UID: "1", SubscriberURI: subscriber}, {UID: "2", ReplyURI: subscriber}}
+			},
+		},
+		"missing credentials": {
+			spec: func(s *CouchDbChannelSpec) {
+				s.CouchDbCredentials = corev1.ObjectReference{}
+			},
+			wantErr: apis.ErrMissingField("spec.credentials.name"),
+		},
+		"invalid database": {
+			spec: func(s *CouchDbChannelSpec) {
+				s.Database = "Orders"
+			},
+			wantErr: &apis.FieldError{
+				Message: `invalid value: Orders`,
+				Paths:   []string{"spec.database"},
+				Details: "database names start with a lowercase letter, followed by lowercase letters, digits and any of _$()+-/",
+			},
+		},
+		"subscriber without URIs": {
+			spec: func(s *CouchDbChannelSpec) {
+				s.Subscribers = []eventingduckv1.SubscriberSpec{{UID: "1", SubscriberURI: subscriber}, {UID: "2"}}
+			},
+			wantErr: &apis.FieldError{
+				Message: "missing field(s)",
+				Paths:   []string{"spec.subscribers[1].replyUri", "spec.subscribers[1].subscriberUri"},
+				Details: "expected at least one of, got none",
+			},
+		},
+		"invalid delivery": {
+			spec: func(s *CouchDbChannelSpec) {
+				retry := int32(-1)
+				s.Delivery = &eventingduckv1.DeliverySpec{Retry: &retry}
+			},
+			wantErr: apis.ErrInvalidValue(int32(-1), "spec.delivery.retry"),
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			spec := valid()
+			tc.spec(&spec)
+			channel := &CouchDbChannel{Spec: spec}
+			got := channel.Validate(context.Background())
+			if got.Error() != tc.wantErr.Error() {
+				t.Errorf("Validate() = %v, want %v", got, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestCouchDbChannelValidateDatabaseImmutable(t *testing.T) {
+	original := &CouchDbChannel{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "orders"},
+		Spec: CouchDbChannelSpec{
+			CouchDbCredentials: corev1.ObjectReference{Name: "couchdb"},
+		},
+	}
+	testCases := map[string]struct {
+		database string
+		wantErr  bool
+	}{
+		"defaulted": {
+			database: "channel-ns-orders",
+		},
+		"changed": {
+			database: "orders",
+			wantErr:  true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			updated := original.DeepCopy()
+			updated.Spec.Database = tc.database
+			ctx := apis.WithinUpdate(context.Background(), original)
+			if err := updated.Validate(ctx); (err != nil) != tc.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dispatcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/binding"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+	"github.com/go-kivik/kivik/v3"
+	"go.uber.org/zap"
+	"knative.dev/eventing/pkg/kncloudevents"
+
+	"knative.dev/eventing-couchdb/pkg/component"
+)
+
+// eventDocument is the document an event is persisted as. The event is
+// nested, since CouchDB reserves the top-level members starting with an
+// underscore, which CloudEvents would read back as extension attributes.
+type eventDocument struct {
+	Event cloudevents.Event `json:"event"`
+}
+
+// Dispatcher persists the CloudEvents sent to a channel as documents of a
+// CouchDB database, and fans them out to the subscribers of the channel by
+// tailing the _changes feed of the database, so that the events survive the
+// restarts of the dispatcher and of the subscribers.
+type Dispatcher struct {
+	logger *zap.SugaredLogger
+	db     *kivik.DB
+	sender *kncloudevents.HTTPMessageSender
+
+	subscribers []Subscriber
+}
+
+var _ http.Handler = (*Dispatcher)(nil)
+
+// NewDispatcher returns the Dispatcher persisting the events of a channel in
+// db, and sending them to its subscribers with sender.
+func NewDispatcher(logger *zap.SugaredLogger, db *kivik.DB, sender *kncloudevents.HTTPMessageSender, subscribers []Subscriber) *Dispatcher {
+	return &Dispatcher{
+		logger:      logger,
+		db:          db,
+		sender:      sender,
+		subscribers: subscribers,
+	}
+}
+
+// ServeHTTP persists the event of the request. It responds 202 Accepted once
+// the event is written, for the subscribers to receive it from the _changes
+// feed, 400 Bad Request for an event that cannot be written, and 502 Bad
+// Gateway when CouchDB fails, for the sender to retry the event.
+func (d *Dispatcher) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	event, err := binding.ToEvent(req.Context(), cehttp.NewMessageFromHttpRequest(req))
+	if err != nil {
+		http.Error(w, fmt.Sprint("invalid CloudEvent: ", err), http.StatusBadRequest)
+		return
+	}
+
+	logger := d.logger.With(zap.String("event", event.ID()))
+	id, _, err := d.db.CreateDoc(req.Context(), &eventDocument{Event: *event})
+	if err != nil {
+		status := component.WriteStatus(err)
+		logger.Warnw("Unable to persist the event", zap.Int("status", status), zap.Error(err))
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Debugw("Persisted the event", zap.String("id", id))
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Start fans the persisted events out to every subscriber until ctx is done.
+// Each subscriber tails the _changes feed on its own, so that a slow or
+// failing subscriber does not hold back the others.
+func (d *Dispatcher) Start(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, sub := range d.subscribers {
+		wg.Add(1)
+		go func(sub Subscriber) {
+			defer wg.Done()
+			d.fanOut(ctx, sub)
+		}(sub)
+	}
+	wg.Wait()
+}
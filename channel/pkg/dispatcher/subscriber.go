@@ -0,0 +1,290 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dispatcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/binding"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+	"github.com/go-kivik/kivik/v3"
+	"go.uber.org/zap"
+	"knative.dev/eventing/pkg/kncloudevents"
+
+	"knative.dev/eventing-couchdb/channel/pkg/reconciler/resources"
+)
+
+const (
+	// checkpointSaveInterval is how often, at most, the checkpoint of a
+	// subscriber is saved while the events are delivered.
+	checkpointSaveInterval = 5 * time.Second
+
+	// reconnectDelay is the time waited before reading the _changes feed
+	// again once it failed.
+	reconnectDelay = 5 * time.Second
+
+	// heartbeat keeps the continuous _changes feed open while no event is
+	// sent to the channel.
+	heartbeat = 30 * time.Second
+)
+
+// Subscriber is a subscriber of the channel.
+type Subscriber = resources.Subscriber
+
+// savedCheckpoint is the sequence of the _changes feed up to which the events
+// were delivered to a subscriber, saved in a local document, which is not
+// replicated nor reported by the changes feed.
+type savedCheckpoint struct {
+	Rev      string `json:"_rev,omitempty"`
+	Sequence string `json:"sequence"`
+}
+
+// subscriberDispatch delivers the persisted events to a subscriber.
+type subscriberDispatch struct {
+	Subscriber
+	logger *zap.SugaredLogger
+	retry  kncloudevents.RetryConfig
+
+	// checkpointID is the local document of the checkpoint, rev its current
+	// revision, saved the sequence it holds and last when it was last
+	// written.
+	checkpointID string
+	rev          string
+	saved        string
+	last         time.Time
+}
+
+// checkpointID returns the local document of the checkpoint of the
+// subscriber.
+func checkpointID(sub Subscriber) string {
+	return "_local/knative-subscriber-" + string(sub.UID)
+}
+
+// fanOut delivers the events to the subscriber, from its checkpoint, until
+// ctx is done.
+func (d *Dispatcher) fanOut(ctx context.Context, sub Subscriber) {
+	s := &subscriberDispatch{
+		Subscriber:   sub,
+		logger:       d.logger.With(zap.String("subscriber", string(sub.UID))),
+		retry:        kncloudevents.NoRetries(),
+		checkpointID: checkpointID(sub),
+	}
+	if sub.Delivery != nil {
+		retry, err := kncloudevents.RetryConfigFromDeliverySpec(*sub.Delivery)
+		if err != nil {
+			s.logger.Errorw("Invalid delivery, delivering without retries", zap.Error(err))
+		} else {
+			s.retry = retry
+		}
+	}
+
+	since, err := d.resume(ctx, s)
+	for err != nil {
+		s.logger.Warnw("Unable to resume from the checkpoint", zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectDelay):
+		}
+		since, err = d.resume(ctx, s)
+	}
+
+	for {
+		since, err = d.dispatchChanges(ctx, s, since)
+		// Do not lose the deliveries since the last save.
+		d.saveCheckpoint(s, since, time.Now(), true)
+		if ctx.Err() != nil {
+			return
+		}
+		s.logger.Warnw("The _changes feed stopped, reading it again", zap.String("since", since), zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// resume returns the sequence the subscriber reads the _changes feed from:
+// its checkpoint, or else the current sequence of the database, saved as its
+// checkpoint so that the new subscribers receive the events sent once they
+// subscribed, and not before.
+func (d *Dispatcher) resume(ctx context.Context, s *subscriberDispatch) (string, error) {
+	var saved savedCheckpoint
+	err := d.db.Get(ctx, s.checkpointID).ScanDoc(&saved)
+	switch {
+	case kivik.StatusCode(err) == http.StatusNotFound:
+	case err != nil:
+		return "", err
+	default:
+		s.rev = saved.Rev
+		s.saved = saved.Sequence
+		s.logger.Infow("Resuming from the checkpoint", zap.String("since", saved.Sequence))
+		return saved.Sequence, nil
+	}
+
+	stats, err := d.db.Stats(ctx)
+	if err != nil {
+		return "", err
+	}
+	d.saveCheckpoint(s, stats.UpdateSeq, time.Now(), true)
+	s.logger.Infow("Delivering the events from now on", zap.String("since", stats.UpdateSeq))
+	return stats.UpdateSeq, nil
+}
+
+// dispatchChanges delivers the events of the continuous _changes feed, from
+// since, and returns the sequence up to which they were delivered once the
+// feed stops.
+func (d *Dispatcher) dispatchChanges(ctx context.Context, s *subscriberDispatch, since string) (string, error) {
+	changes, err := d.db.Changes(ctx, kivik.Options{
+		"feed":         "continuous",
+		"since":        since,
+		"include_docs": true,
+		"heartbeat":    int(heartbeat / time.Millisecond),
+	})
+	if err != nil {
+		return since, err
+	}
+	defer changes.Close()
+
+	for changes.Next() {
+		if !changes.Deleted() && !strings.HasPrefix(changes.ID(), "_design/") {
+			var doc eventDocument
+			if err := changes.ScanDoc(&doc); err != nil {
+				s.logger.Errorw("Skipping an invalid event", zap.String("id", changes.ID()), zap.Error(err))
+			} else {
+				d.deliver(ctx, s, doc.Event)
+			}
+			if ctx.Err() != nil {
+				// The delivery was interrupted, deliver the event again
+				// once restarted.
+				return since, ctx.Err()
+			}
+		}
+		since = changes.Seq()
+		d.saveCheckpoint(s, since, time.Now(), false)
+	}
+	return since, changes.Err()
+}
+
+// deliver sends the event to the subscriber, and its reply to the reply URI.
+// The events that could not be delivered, once retried, are sent to the dead
+// letter sink if any, and dropped otherwise, so that they do not hold back
+// the next ones.
+func (d *Dispatcher) deliver(ctx context.Context, s *subscriberDispatch, event cloudevents.Event) {
+	logger := s.logger.With(zap.String("event", event.ID()))
+	err := d.send(ctx, s, event)
+	if err == nil {
+		logger.Debug("Delivered the event")
+		return
+	}
+	if s.DeadLetterSinkURI == nil {
+		logger.Errorw("Dropping the event that could not be delivered", zap.Error(err))
+		return
+	}
+	logger.Warnw("Sending the event that could not be delivered to the dead letter sink", zap.Error(err))
+	if _, err := d.sendTo(ctx, s.DeadLetterSinkURI.String(), binding.ToMessage(&event), &s.retry); err != nil {
+		logger.Errorw("Dropping the event that could not be dead lettered", zap.Error(err))
+	}
+}
+
+// send sends the event to the subscriber, and its reply, if any, to the reply
+// URI. Without a subscriber URI, the event is sent to the reply URI.
+func (d *Dispatcher) send(ctx context.Context, s *subscriberDispatch, event cloudevents.Event) error {
+	if s.SubscriberURI == nil {
+		if s.ReplyURI == nil {
+			return nil
+		}
+		_, err := d.sendTo(ctx, s.ReplyURI.String(), binding.ToMessage(&event), &s.retry)
+		return err
+	}
+
+	resp, err := d.sendTo(ctx, s.SubscriberURI.String(), binding.ToMessage(&event), &s.retry)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	reply := cehttp.NewMessageFromHttpResponse(resp)
+	if reply.ReadEncoding() == binding.EncodingUnknown {
+		return nil
+	}
+	if s.ReplyURI == nil {
+		s.logger.Debugw("Dropping the reply without a reply URI", zap.String("event", event.ID()))
+		return nil
+	}
+	if _, err := d.sendTo(ctx, s.ReplyURI.String(), reply, &s.retry); err != nil {
+		return fmt.Errorf("unable to send the reply: %w", err)
+	}
+	return nil
+}
+
+// sendTo sends the message to the target with retries, and returns the
+// accepted response, whose body is left to the caller when it wants it.
+func (d *Dispatcher) sendTo(ctx context.Context, target string, message binding.Message, retry *kncloudevents.RetryConfig) (*http.Response, error) {
+	req, err := d.sender.NewCloudEventRequestWithTarget(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	if err := cehttp.WriteRequest(ctx, message, req); err != nil {
+		return nil, err
+	}
+	resp, err := d.sender.SendWithRetries(req, retry)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s responded %d %s", target, resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	return resp, nil
+}
+
+// saveCheckpoint writes the checkpoint of the subscriber when it advanced, at
+// most every checkpointSaveInterval unless forced. A checkpoint that cannot
+// be saved only makes a restarted dispatcher deliver some events again.
+func (d *Dispatcher) saveCheckpoint(s *subscriberDispatch, seq string, now time.Time, force bool) {
+	if seq == "" || seq == s.saved {
+		return
+	}
+	if !force && now.Sub(s.last) < checkpointSaveInterval {
+		return
+	}
+	s.last = now
+
+	rev, err := d.db.Put(context.Background(), s.checkpointID, &savedCheckpoint{
+		Rev:      s.rev,
+		Sequence: seq,
+	})
+	if err != nil {
+		s.logger.Warnw("Unable to save the checkpoint", zap.Error(err))
+		if kivik.StatusCode(err) == http.StatusConflict {
+			// A dispatcher rolling out saved it meanwhile, catch up with
+			// its revision.
+			if _, rev, err := d.db.GetMeta(context.Background(), s.checkpointID); err == nil {
+				s.rev = rev
+			}
+		}
+		return
+	}
+	s.rev = rev
+	s.saved = seq
+}
@@ -0,0 +1,283 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dispatcher
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/binding"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+	"github.com/go-kivik/kivik/v3"
+	"github.com/go-kivik/kivik/v3/driver"
+	"github.com/go-kivik/kivikmock/v3"
+	"go.uber.org/zap"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+	"knative.dev/eventing/pkg/kncloudevents"
+	"knative.dev/pkg/apis"
+)
+
+// recorder is an HTTP endpoint recording the IDs of the events it receives,
+// responding with status, and replying with reply when set.
+type recorder struct {
+	status int
+	reply  string
+
+	mu  sync.Mutex
+	ids []string
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	event, err := binding.ToEvent(req.Context(), cehttp.NewMessageFromHttpRequest(req))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.mu.Lock()
+	r.ids = append(r.ids, event.ID())
+	r.mu.Unlock()
+	if r.reply != "" {
+		reply := newEvent(r.reply)
+		if err := cehttp.WriteResponseWriter(req.Context(), binding.ToMessage(&reply), r.status, w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	w.WriteHeader(r.status)
+}
+
+func (r *recorder) received() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.ids...)
+}
+
+func newDispatcher(t *testing.T, db *kivik.DB) *Dispatcher {
+	t.Helper()
+	sender, err := kncloudevents.NewHTTPMessageSenderWithTarget("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Dispatcher{
+		logger: zap.NewNop().Sugar(),
+		db:     db,
+		sender: sender,
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	retry := int32(2)
+	linear := eventingduckv1.BackoffPolicyLinear
+	delay := "PT0.001S"
+	delivery := &eventingduckv1.DeliverySpec{Retry: &retry, BackoffPolicy: &linear, BackoffDelay: &delay}
+
+	testCases := map[string]struct {
+		subscriber     *recorder
+		reply          bool
+		deadLetterSink bool
+		delivery       *eventingduckv1.DeliverySpec
+
+		wantSubscriber     int
+		wantReplies        []string
+		wantDeadLetterSink []string
+	}{
+		"delivered": {
+			subscriber:     &recorder{status: http.StatusAccepted},
+			wantSubscriber: 1,
+		},
+		"reply": {
+			subscriber:     &recorder{status: http.StatusOK, reply: "reply-1"},
+			reply:          true,
+			wantSubscriber: 1,
+			wantReplies:    []string{"reply-1"},
+		},
+		"reply without reply URI": {
+			subscriber:     &recorder{status: http.StatusOK, reply: "reply-1"},
+			wantSubscriber: 1,
+		},
+		"reply URI only": {
+			reply:       true,
+			wantReplies: []string{"abc"},
+		},
+		"retried then dead lettered": {
+			subscriber:         &recorder{status: http.StatusServiceUnavailable},
+			deadLetterSink:     true,
+			delivery:           delivery,
+			wantSubscriber:     3,
+			wantDeadLetterSink: []string{"abc"},
+		},
+		"dropped": {
+			subscriber:     &recorder{status: http.StatusServiceUnavailable},
+			wantSubscriber: 1,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			s := &subscriberDispatch{
+				logger: zap.NewNop().Sugar(),
+				retry:  kncloudevents.NoRetries(),
+			}
+			if tc.delivery != nil {
+				retry, err := kncloudevents.RetryConfigFromDeliverySpec(*tc.delivery)
+				if err != nil {
+					t.Fatal(err)
+				}
+				s.retry = retry
+			}
+			if tc.subscriber != nil {
+				server := httptest.NewServer(tc.subscriber)
+				defer server.Close()
+				s.SubscriberURI, _ = apis.ParseURL(server.URL)
+			}
+			replies := &recorder{status: http.StatusAccepted}
+			if tc.reply {
+				server := httptest.NewServer(replies)
+				defer server.Close()
+				s.ReplyURI, _ = apis.ParseURL(server.URL)
+			}
+			deadLetters := &recorder{status: http.StatusAccepted}
+			if tc.deadLetterSink {
+				server := httptest.NewServer(deadLetters)
+				defer server.Close()
+				s.DeadLetterSinkURI, _ = apis.ParseURL(server.URL)
+			}
+
+			newDispatcher(t, nil).deliver(context.Background(), s, newEvent("abc"))
+
+			if tc.subscriber != nil {
+				if got := len(tc.subscriber.received()); got != tc.wantSubscriber {
+					t.Errorf("subscriber received %d events, want %d", got, tc.wantSubscriber)
+				}
+			}
+			if got := replies.received(); strings.Join(got, ",") != strings.Join(tc.wantReplies, ",") {
+				t.Errorf("replies = %v, want %v", got, tc.wantReplies)
+			}
+			if got := deadLetters.received(); strings.Join(got, ",") != strings.Join(tc.wantDeadLetterSink, ",") {
+				t.Errorf("dead letters = %v, want %v", got, tc.wantDeadLetterSink)
+			}
+		})
+	}
+}
+
+func TestResume(t *testing.T) {
+	testCases := map[string]struct {
+		saved     string
+		wantSince string
+	}{
+		"checkpoint": {
+			saved:     `{"_id":"_local/knative-subscriber-1234","_rev":"0-4","sequence":"7-g"}`,
+			wantSince: "7-g",
+		},
+		"new subscriber": {
+			wantSince: "9-i",
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			client, mock := kivikmock.NewT(t)
+			db := mock.NewDB()
+			mock.ExpectDB().WithName("channel").WillReturn(db)
+			get := db.ExpectGet().WithDocID("_local/knative-subscriber-1234")
+			if tc.saved == "" {
+				get.WillReturnError(&kivik.Error{HTTPStatus: http.StatusNotFound})
+				db.ExpectStats().WillReturn(&driver.DBStats{Name: "channel", UpdateSeq: "9-i"})
+				db.ExpectPut().WithDocID("_local/knative-subscriber-1234").WithDoc(&savedCheckpoint{Sequence: "9-i"}).WillReturn("0-1")
+			} else {
+				get.WillReturn(&driver.Document{
+					Rev:  "0-4",
+					Body: ioutil.NopCloser(strings.NewReader(tc.saved)),
+				})
+			}
+
+			d := newDispatcher(t, client.DB(context.Background(), "channel"))
+			s := &subscriberDispatch{logger: zap.NewNop().Sugar(), checkpointID: "_local/knative-subscriber-1234"}
+			since, err := d.resume(context.Background(), s)
+			if err != nil {
+				t.Fatal("resume() =", err)
+			}
+			if since != tc.wantSince {
+				t.Errorf("since = %q, want %q", since, tc.wantSince)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestDispatchChanges(t *testing.T) {
+	doc := func(id string) json.RawMessage {
+		b, err := json.Marshal(&eventDocument{Event: newEvent(id)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	client, mock := kivikmock.NewT(t)
+	db := mock.NewDB()
+	mock.ExpectDB().WithName("channel").WillReturn(db)
+	db.ExpectChanges().WillReturn(kivikmock.NewChanges().
+		AddChange(&driver.Change{ID: "1", Seq: "2-b", Changes: driver.ChangedRevs{"1-a"}, Doc: doc("event-1")}).
+		AddChange(&driver.Change{ID: "_design/views", Seq: "3-c", Changes: driver.ChangedRevs{"1-a"}, Doc: json.RawMessage(`{}`)}).
+		AddChange(&driver.Change{ID: "2", Seq: "4-d", Changes: driver.ChangedRevs{"2-a"}, Deleted: true}).
+		AddChange(&driver.Change{ID: "3", Seq: "5-e", Changes: driver.ChangedRevs{"1-a"}, Doc: doc("event-3")}))
+	// The checkpoint is saved once per checkpointSaveInterval.
+	db.ExpectPut().WithDocID("_local/knative-subscriber-1234").WithDoc(&savedCheckpoint{Rev: "0-1", Sequence: "2-b"}).WillReturn("0-2")
+
+	subscriber := &recorder{status: http.StatusAccepted}
+	server := httptest.NewServer(subscriber)
+	defer server.Close()
+
+	d := newDispatcher(t, client.DB(context.Background(), "channel"))
+	s := &subscriberDispatch{
+		logger:       zap.NewNop().Sugar(),
+		retry:        kncloudevents.NoRetries(),
+		checkpointID: "_local/knative-subscriber-1234",
+		rev:          "0-1",
+		saved:        "1-a",
+	}
+	s.SubscriberURI, _ = apis.ParseURL(server.URL)
+
+	since, err := d.dispatchChanges(context.Background(), s, "1-a")
+	if err != nil {
+		t.Fatal("dispatchChanges() =", err)
+	}
+	if since != "5-e" {
+		t.Errorf("since = %q, want 5-e", since)
+	}
+	if got := strings.Join(subscriber.received(), ","); got != "event-1,event-3" {
+		t.Errorf("subscriber received %s, want event-1,event-3", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	// The last sequence is saved once the feed stops.
+	db.ExpectPut().WithDocID("_local/knative-subscriber-1234").WithDoc(&savedCheckpoint{Rev: "0-2", Sequence: "5-e"}).WillReturn("0-3")
+	d.saveCheckpoint(s, since, time.Now(), true)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
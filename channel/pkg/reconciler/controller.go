@@ -0,0 +1,74 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"os"
+
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/cache"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
+	deploymentinformer "knative.dev/pkg/client/injection/kube/informers/apps/v1/deployment"
+	"knative.dev/pkg/configmap"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/resolver"
+
+	"knative.dev/eventing-couchdb/channel/pkg/apis/messaging/v1alpha1"
+	couchdbchannelinformer "knative.dev/eventing-couchdb/channel/pkg/client/injection/informers/messaging/v1alpha1/couchdbchannel"
+	couchdbchannelreconciler "knative.dev/eventing-couchdb/channel/pkg/client/injection/reconciler/messaging/v1alpha1/couchdbchannel"
+)
+
+func init() {
+	v1alpha1.AddToScheme(scheme.Scheme)
+}
+
+// NewController initializes the controller and is called by the generated code
+// Registers event handlers to enqueue events
+func NewController(
+	ctx context.Context,
+	cmw configmap.Watcher,
+) *controller.Impl {
+	deploymentInformer := deploymentinformer.Get(ctx)
+	couchdbChannelInformer := couchdbchannelinformer.Get(ctx)
+
+	dispatcherImage, defined := os.LookupEnv(dispatcherImageEnvVar)
+	if !defined {
+		logging.FromContext(ctx).Errorf("required environment variable %q not defined", dispatcherImageEnvVar)
+		return nil
+	}
+
+	r := &Reconciler{
+		dispatcherImage:  dispatcherImage,
+		kubeClientSet:    kubeclient.Get(ctx),
+		deploymentLister: deploymentInformer.Lister(),
+	}
+	impl := couchdbchannelreconciler.NewImpl(ctx, r)
+	r.sinkResolver = resolver.NewURIResolver(ctx, impl.EnqueueKey)
+
+	logging.FromContext(ctx).Info("Setting up event handlers")
+	couchdbChannelInformer.Informer().AddEventHandler(controller.HandleAll(impl.Enqueue))
+
+	ownsChannel := controller.FilterControllerGK(v1alpha1.Kind("CouchDbChannel"))
+	deploymentInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: ownsChannel,
+		Handler:    controller.HandleAll(impl.EnqueueControllerOf),
+	})
+
+	return impl
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	appsv1listers "k8s.io/client-go/listers/apps/v1"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/network"
+	pkgreconciler "knative.dev/pkg/reconciler"
+	"knative.dev/pkg/resolver"
+
+	"knative.dev/eventing-couchdb/channel/pkg/apis/messaging/v1alpha1"
+	couchdbchannelreconciler "knative.dev/eventing-couchdb/channel/pkg/client/injection/reconciler/messaging/v1alpha1/couchdbchannel"
+	"knative.dev/eventing-couchdb/channel/pkg/reconciler/resources"
+	"knative.dev/eventing-couchdb/pkg/component"
+)
+
+const (
+	// Name of the corev1.Events emitted from the reconciliation process
+	couchdbchannelDeploymentCreated = "CouchDbChannelDeploymentCreated"
+	couchdbchannelDeploymentUpdated = "CouchDbChannelDeploymentUpdated"
+	couchdbchannelServiceCreated    = "CouchDbChannelServiceCreated"
+	couchdbchannelServiceUpdated    = "CouchDbChannelServiceUpdated"
+
+	// dispatcherImageEnvVar is the environment variable of the controller
+	// holding the image of the dispatchers.
+	dispatcherImageEnvVar = "COUCHDB_CHANNEL_DISPATCHER_IMAGE"
+)
+
+// Reconciler reconciles the dispatcher Deployment and Service of the
+// channels.
+type Reconciler struct {
+	dispatcherImage string
+
+	kubeClientSet    kubernetes.Interface
+	deploymentLister appsv1listers.DeploymentLister
+	sinkResolver     *resolver.URIResolver
+}
+
+// Check that our Reconciler implements Interface
+var _ couchdbchannelreconciler.Interface = (*Reconciler)(nil)
+
+// ReconcileKind implements Interface.ReconcileKind.
+func (r *Reconciler) ReconcileKind(ctx context.Context, channel *v1alpha1.CouchDbChannel) pkgreconciler.Event {
+	channel.Status.InitializeConditions()
+
+	subscribers, err := r.resolveSubscribers(ctx, channel)
+	if err != nil {
+		channel.Status.MarkNoDeployment("DeadLetterSinkNotFound", "Unable to resolve a dead letter sink: %v", err)
+		channel.Status.MarkSubscribers(channel.Spec.Subscribers, false)
+		return err
+	}
+	args := &resources.DispatcherArgs{
+		Image:       r.dispatcherImage,
+		Channel:     channel,
+		Labels:      resources.Labels(channel.Name),
+		Subscribers: subscribers,
+	}
+
+	expected, err := resources.MakeDispatcher(args)
+	if err != nil {
+		channel.Status.MarkNoDeployment("DeploymentFailed", "Unable to make the dispatcher: %v", err)
+		return err
+	}
+	d, err := r.reconcileDispatcher(ctx, channel, expected)
+	if err != nil {
+		channel.Status.MarkNoDeployment("DeploymentFailed", "Unable to reconcile the dispatcher: %v", err)
+		channel.Status.MarkSubscribers(channel.Spec.Subscribers, false)
+		return err
+	}
+	channel.Status.PropagateDeploymentAvailability(d)
+	// The subscribers are delivered to once the dispatcher knowing about
+	// them rolled out.
+	channel.Status.MarkSubscribers(channel.Spec.Subscribers, component.RolledOut(d))
+
+	svc, err := r.reconcileService(ctx, channel, resources.MakeService(args))
+	if err != nil {
+		channel.Status.MarkNoAddress("ServiceFailed", "Unable to reconcile the service: %v", err)
+		return err
+	}
+	channel.Status.MarkAddress(&apis.URL{
+		Scheme: "http",
+		Host:   network.GetServiceHostname(svc.Name, svc.Namespace),
+	})
+	return nil
+}
+
+// resolveSubscribers returns the subscribers of the channel with their
+// delivery, or else the one of the channel, and its dead letter sink
+// resolved.
+func (r *Reconciler) resolveSubscribers(ctx context.Context, channel *v1alpha1.CouchDbChannel) ([]resources.Subscriber, error) {
+	subscribers := make([]resources.Subscriber, 0, len(channel.Spec.Subscribers))
+	for _, sub := range channel.Spec.Subscribers {
+		delivery := sub.Delivery
+		if delivery == nil {
+			delivery = channel.Spec.Delivery
+		}
+		s := resources.Subscriber{
+			UID:           sub.UID,
+			SubscriberURI: sub.SubscriberURI,
+			ReplyURI:      sub.ReplyURI,
+		}
+		if delivery != nil {
+			s.Delivery = delivery.DeepCopy()
+			s.Delivery.DeadLetterSink = nil
+			if delivery.DeadLetterSink != nil {
+				uri, err := r.resolveDeadLetterSink(ctx, channel, delivery)
+				if err != nil {
+					return nil, fmt.Errorf("subscriber %s: %w", sub.UID, err)
+				}
+				s.DeadLetterSinkURI = uri
+			}
+		}
+		subscribers = append(subscribers, s)
+	}
+	return subscribers, nil
+}
+
+func (r *Reconciler) resolveDeadLetterSink(ctx context.Context, channel *v1alpha1.CouchDbChannel, delivery *eventingduckv1.DeliverySpec) (*apis.URL, error) {
+	dls := delivery.DeadLetterSink.DeepCopy()
+	if dls.Ref != nil && dls.Ref.Namespace == "" {
+		dls.Ref.Namespace = channel.GetNamespace()
+	}
+	return r.sinkResolver.URIFromDestinationV1(ctx, *dls, channel)
+}
+
+// reconcileDispatcher creates the dispatcher Deployment of the channel, or
+// updates it when its pod template changed.
+func (r *Reconciler) reconcileDispatcher(ctx context.Context, channel *v1alpha1.CouchDbChannel, expected *appsv1.Deployment) (*appsv1.Deployment, error) {
+	d, err := r.deploymentLister.Deployments(channel.Namespace).Get(expected.Name)
+	if apierrors.IsNotFound(err) {
+		d, err = r.kubeClientSet.AppsV1().Deployments(channel.Namespace).Create(ctx, expected, metav1.CreateOptions{})
+		if err != nil {
+			return nil, err
+		}
+		controller.GetEventRecorder(ctx).Event(channel, corev1.EventTypeNormal, couchdbchannelDeploymentCreated, "Deployment created")
+		return d, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error getting the dispatcher: %w", err)
+	} else if !metav1.IsControlledBy(d, channel) {
+		return nil, fmt.Errorf("deployment %q is not owned by CouchDbChannel %q", d.Name, channel.Name)
+	}
+
+	if !component.PodSpecChanged(d.Spec.Template.Spec, expected.Spec.Template.Spec) &&
+		equality.Semantic.DeepEqual(d.Spec.Template.Labels, expected.Spec.Template.Labels) {
+		logging.FromContext(ctx).Debugw("Reusing the existing dispatcher", zap.String("deployment", d.Name))
+		return d, nil
+	}
+	d = d.DeepCopy()
+	d.Spec.Template.Spec = expected.Spec.Template.Spec
+	d.Spec.Template.Labels = expected.Spec.Template.Labels
+	if d, err = r.kubeClientSet.AppsV1().Deployments(channel.Namespace).Update(ctx, d, metav1.UpdateOptions{}); err != nil {
+		return nil, err
+	}
+	controller.GetEventRecorder(ctx).Event(channel, corev1.EventTypeNormal, couchdbchannelDeploymentUpdated, "Deployment updated")
+	return d, nil
+}
+
+// reconcileService creates the Service of the channel, or updates it when its
+// selector or ports changed.
+func (r *Reconciler) reconcileService(ctx context.Context, channel *v1alpha1.CouchDbChannel, expected *corev1.Service) (*corev1.Service, error) {
+	// The Services are not watched, only the reconciler changes them.
+	svc, err := r.kubeClientSet.CoreV1().Services(channel.Namespace).Get(ctx, expected.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		svc, err = r.kubeClientSet.CoreV1().Services(channel.Namespace).Create(ctx, expected, metav1.CreateOptions{})
+		if err != nil {
+			return nil, err
+		}
+		controller.GetEventRecorder(ctx).Event(channel, corev1.EventTypeNormal, couchdbchannelServiceCreated, "Service created")
+		return svc, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error getting the service: %w", err)
+	} else if !metav1.IsControlledBy(svc, channel) {
+		return nil, fmt.Errorf("service %q is not owned by CouchDbChannel %q", svc.Name, channel.Name)
+	}
+
+	if !component.ServiceChanged(svc, expected) {
+		return svc, nil
+	}
+	svc = svc.DeepCopy()
+	svc.Spec.Selector = expected.Spec.Selector
+	svc.Spec.Ports = expected.Spec.Ports
+	if svc, err = r.kubeClientSet.CoreV1().Services(channel.Namespace).Update(ctx, svc, metav1.UpdateOptions{}); err != nil {
+		return nil, err
+	}
+	controller.GetEventRecorder(ctx).Event(channel, corev1.EventTypeNormal, couchdbchannelServiceUpdated, "Service updated")
+	return svc, nil
+}
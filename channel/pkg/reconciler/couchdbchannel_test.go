@@ -0,0 +1,118 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	"knative.dev/eventing-couchdb/channel/pkg/apis/messaging/v1alpha1"
+	"knative.dev/eventing-couchdb/channel/pkg/reconciler/resources"
+	"knative.dev/eventing-couchdb/pkg/component"
+)
+
+func newArgs() *resources.DispatcherArgs {
+	channel := &v1alpha1.CouchDbChannel{
+		ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "ns", UID: "1234"},
+		Spec: v1alpha1.CouchDbChannelSpec{
+			CouchDbCredentials: corev1.ObjectReference{Name: "couchdb"},
+		},
+	}
+	return &resources.DispatcherArgs{Image: "dispatcher", Channel: channel, Labels: resources.Labels(channel.Name)}
+}
+
+func makeDispatcher(t *testing.T, args *resources.DispatcherArgs) corev1.PodSpec {
+	t.Helper()
+	d, err := resources.MakeDispatcher(args)
+	if err != nil {
+		t.Fatal("MakeDispatcher() =", err)
+	}
+	return d.Spec.Template.Spec
+}
+
+func TestPodSpecChanged(t *testing.T) {
+	expected := makeDispatcher(t, newArgs())
+
+	// The API server defaults some fields of the pod spec.
+	defaulted := *expected.DeepCopy()
+	defaulted.RestartPolicy = corev1.RestartPolicyAlways
+	defaulted.Containers[0].TerminationMessagePath = "/dev/termination-log"
+	if component.PodSpecChanged(defaulted, expected) {
+		t.Error("PodSpecChanged() = true for the defaulted pod spec")
+	}
+
+	args := newArgs()
+	args.Subscribers = []resources.Subscriber{{UID: "5678", SubscriberURI: apis.HTTP("subscriber")}}
+	if !component.PodSpecChanged(defaulted, makeDispatcher(t, args)) {
+		t.Error("PodSpecChanged() = false after a subscriber was added")
+	}
+}
+
+func TestResolveSubscribers(t *testing.T) {
+	retry := int32(3)
+	channelRetry := int32(1)
+	subscriber := apis.HTTP("subscriber.ns.svc.cluster.local")
+	dls := apis.HTTP("dls.ns.svc.cluster.local")
+
+	channel := &v1alpha1.CouchDbChannel{
+		ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "ns"},
+		Spec: v1alpha1.CouchDbChannelSpec{
+			ChannelableSpec: eventingduckv1.ChannelableSpec{
+				SubscribableSpec: eventingduckv1.SubscribableSpec{
+					Subscribers: []eventingduckv1.SubscriberSpec{{
+						UID:           "1",
+						SubscriberURI: subscriber,
+					}, {
+						UID:           "2",
+						SubscriberURI: subscriber,
+						Delivery: &eventingduckv1.DeliverySpec{
+							Retry:          &retry,
+							DeadLetterSink: &duckv1.Destination{URI: dls},
+						},
+					}},
+				},
+				Delivery: &eventingduckv1.DeliverySpec{Retry: &channelRetry},
+			},
+		},
+	}
+
+	r := &Reconciler{}
+	got, err := r.resolveSubscribers(context.Background(), channel)
+	if err != nil {
+		t.Fatal("resolveSubscribers() =", err)
+	}
+	want := []resources.Subscriber{{
+		UID:           "1",
+		SubscriberURI: subscriber,
+		Delivery:      &eventingduckv1.DeliverySpec{Retry: &channelRetry},
+	}, {
+		UID:               "2",
+		SubscriberURI:     subscriber,
+		DeadLetterSinkURI: dls,
+		Delivery:          &eventingduckv1.DeliverySpec{Retry: &retry},
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error("Unexpected subscribers (-want, +got) =", diff)
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"knative.dev/pkg/kmeta"
+
+	"knative.dev/eventing-couchdb/channel/pkg/apis/messaging/v1alpha1"
+)
+
+// DispatcherPort is the port the dispatcher receives the events on.
+const DispatcherPort = 8080
+
+// CredentialsVolumeName is the volume of the credentials of the channel.
+const CredentialsVolumeName = "couchdb-credentials"
+
+// DispatcherArgs are the arguments needed to create the dispatcher of a
+// CouchDbChannel. Every field is required, but Subscribers.
+type DispatcherArgs struct {
+	Image       string
+	Channel     *v1alpha1.CouchDbChannel
+	Labels      map[string]string
+	Subscribers []Subscriber
+}
+
+// DispatcherName returns the name of the Deployment and the Service of the
+// dispatcher of the channel.
+func DispatcherName(channel *v1alpha1.CouchDbChannel) string {
+	return kmeta.ChildName(fmt.Sprintf("couchdbchannel-%s-", channel.Name), string(channel.UID))
+}
+
+func makeObjectMeta(args *DispatcherArgs) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: args.Channel.Namespace,
+		Name:      DispatcherName(args.Channel),
+		Labels:    args.Labels,
+		OwnerReferences: []metav1.OwnerReference{
+			*kmeta.NewControllerRef(args.Channel),
+		},
+	}
+}
+
+// MakeDispatcher generates (but does not insert into K8s) the dispatcher
+// Deployment of the channel. The subscribers are part of its environment, so
+// that the dispatcher rolls out whenever they change.
+func MakeDispatcher(args *DispatcherArgs) (*appsv1.Deployment, error) {
+	subscribers, err := json.Marshal(args.Subscribers)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode the subscribers: %w", err)
+	}
+
+	// A single replica tails the _changes feed, for every subscriber to
+	// receive each event once. The rollouts briefly run two of them, which
+	// may deliver some events twice.
+	replicas := int32(1)
+	spec := args.Channel.Spec
+	return &appsv1.Deployment{
+		ObjectMeta: makeObjectMeta(args),
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: args.Labels,
+			},
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: args.Labels,
+				},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: spec.ServiceAccountName,
+					Containers: []corev1.Container{{
+						Name:  "dispatcher",
+						Image: args.Image,
+						Ports: []corev1.ContainerPort{{
+							Name:          "http",
+							ContainerPort: DispatcherPort,
+						}},
+						Env: []corev1.EnvVar{{
+							Name:  "PORT",
+							Value: fmt.Sprint(DispatcherPort),
+						}, {
+							Name:  "K_LOGGING_CONFIG",
+							Value: "",
+						}, {
+							Name:  "COUCHDB_CREDENTIALS",
+							Value: "/etc/couchdb-credentials",
+						}, {
+							Name:  "COUCHDB_DATABASE",
+							Value: args.Channel.DatabaseName(),
+						}, {
+							Name:  SubscribersEnvKey,
+							Value: string(subscribers),
+						}},
+						VolumeMounts: []corev1.VolumeMount{{
+							Name:      CredentialsVolumeName,
+							MountPath: "/etc/couchdb-credentials",
+							ReadOnly:  true,
+						}},
+						ReadinessProbe: &corev1.Probe{
+							Handler: corev1.Handler{
+								HTTPGet: &corev1.HTTPGetAction{
+									Path: "/healthz",
+									Port: intstr.FromInt(DispatcherPort),
+								},
+							},
+						},
+					}},
+					Volumes: []corev1.Volume{{
+						Name: CredentialsVolumeName,
+						VolumeSource: corev1.VolumeSource{
+							Secret: &corev1.SecretVolumeSource{
+								SecretName: spec.CouchDbCredentials.Name,
+							},
+						},
+					}},
+				},
+			},
+		},
+	}, nil
+}
+
+// MakeService generates (but does not insert into K8s) the Service the
+// events are sent to, in front of the dispatcher.
+func MakeService(args *DispatcherArgs) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: makeObjectMeta(args),
+		Spec: corev1.ServiceSpec{
+			Selector: args.Labels,
+			Ports: []corev1.ServicePort{{
+				Name:       "http",
+				Port:       80,
+				TargetPort: intstr.FromInt(DispatcherPort),
+			}},
+		},
+	}
+}
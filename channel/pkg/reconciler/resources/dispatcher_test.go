@@ -0,0 +1,104 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"knative.dev/pkg/apis"
+
+	"knative.dev/eventing-couchdb/channel/pkg/apis/messaging/v1alpha1"
+)
+
+func newChannel() *v1alpha1.CouchDbChannel {
+	return &v1alpha1.CouchDbChannel{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "orders",
+			Namespace: "ns",
+			UID:       "1234",
+		},
+		Spec: v1alpha1.CouchDbChannelSpec{
+			ServiceAccountName: "dispatcher",
+			CouchDbCredentials: corev1.ObjectReference{Name: "couchdb"},
+		},
+	}
+}
+
+func TestMakeDispatcher(t *testing.T) {
+	channel := newChannel()
+	subscribers := []Subscriber{{
+		UID:               "5678",
+		SubscriberURI:     apis.HTTP("subscriber.ns.svc.cluster.local"),
+		DeadLetterSinkURI: apis.HTTP("dls.ns.svc.cluster.local"),
+	}}
+	d, err := MakeDispatcher(&DispatcherArgs{Image: "dispatcher", Channel: channel, Labels: Labels(channel.Name), Subscribers: subscribers})
+	if err != nil {
+		t.Fatal("MakeDispatcher() =", err)
+	}
+
+	if d.Name != DispatcherName(channel) || d.Namespace != "ns" {
+		t.Errorf("dispatcher = %s/%s, want ns/%s", d.Namespace, d.Name, DispatcherName(channel))
+	}
+	if !metav1.IsControlledBy(d, channel) {
+		t.Error("the dispatcher is not controlled by the channel")
+	}
+	spec := d.Spec.Template.Spec
+	if spec.ServiceAccountName != "dispatcher" {
+		t.Errorf("serviceAccountName = %q, want dispatcher", spec.ServiceAccountName)
+	}
+	if got := spec.Volumes[0].Secret.SecretName; got != "couchdb" {
+		t.Errorf("credentials secret = %q, want couchdb", got)
+	}
+
+	env := map[string]string{}
+	for _, e := range spec.Containers[0].Env {
+		env[e.Name] = e.Value
+	}
+	want := map[string]string{
+		"PORT":                "8080",
+		"K_LOGGING_CONFIG":    "",
+		"COUCHDB_CREDENTIALS": "/etc/couchdb-credentials",
+		"COUCHDB_DATABASE":    "channel-ns-orders",
+		"COUCHDB_SUBSCRIBERS": `[{"uid":"5678","subscriberUri":"http://subscriber.ns.svc.cluster.local","deadLetterSinkUri":"http://dls.ns.svc.cluster.local"}]`,
+	}
+	if diff := cmp.Diff(want, env); diff != "" {
+		t.Error("Unexpected env (-want, +got) =", diff)
+	}
+}
+
+func TestMakeService(t *testing.T) {
+	channel := newChannel()
+	labels := Labels(channel.Name)
+	svc := MakeService(&DispatcherArgs{Image: "dispatcher", Channel: channel, Labels: labels})
+
+	if svc.Name != DispatcherName(channel) {
+		t.Errorf("service = %s, want %s", svc.Name, DispatcherName(channel))
+	}
+	if !metav1.IsControlledBy(svc, channel) {
+		t.Error("the service is not controlled by the channel")
+	}
+	if diff := cmp.Diff(labels, svc.Spec.Selector); diff != "" {
+		t.Error("Unexpected selector (-want, +got) =", diff)
+	}
+	if got := svc.Spec.Ports[0].TargetPort; got != intstr.FromInt(DispatcherPort) {
+		t.Errorf("targetPort = %v, want %d", got, DispatcherPort)
+	}
+}
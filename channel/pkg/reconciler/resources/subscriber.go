@@ -0,0 +1,53 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"k8s.io/apimachinery/pkg/types"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+	"knative.dev/pkg/apis"
+)
+
+// SubscribersEnvKey is the environment variable holding the subscribers of
+// the channel, as a JSON array of Subscriber.
+const SubscribersEnvKey = "COUCHDB_SUBSCRIBERS"
+
+// Subscriber is a subscriber of the channel as the dispatcher delivers to it,
+// its dead letter sink resolved to a URI.
+type Subscriber struct {
+	// UID identifies the subscriber, and its checkpoint in the database.
+	UID types.UID `json:"uid"`
+
+	// SubscriberURI is where the events are sent, if any.
+	// +optional
+	SubscriberURI *apis.URL `json:"subscriberUri,omitempty"`
+
+	// ReplyURI is where the replies of the subscriber are sent, or the
+	// events themselves without a subscriber URI.
+	// +optional
+	ReplyURI *apis.URL `json:"replyUri,omitempty"`
+
+	// DeadLetterSinkURI is where the events that could not be delivered are
+	// sent, if any.
+	// +optional
+	DeadLetterSinkURI *apis.URL `json:"deadLetterSinkUri,omitempty"`
+
+	// Delivery holds the retries of the subscriber, or else the ones of the
+	// channel, whose dead letter sink is resolved into DeadLetterSinkURI.
+	// +optional
+	Delivery *eventingduckv1.DeliverySpec `json:"delivery,omitempty"`
+}
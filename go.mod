@@ -10,11 +10,17 @@ require (
 	github.com/go-kivik/kivikmock/v3 v3.0.0
 	github.com/google/go-cmp v0.5.6
 	github.com/influxdata/tdigest v0.0.1 // indirect
+	github.com/kelseyhightower/envconfig v1.4.0
 	github.com/otiai10/copy v1.2.0 // indirect
+	github.com/rickb777/date v1.13.0
 	gitlab.com/flimzy/testy v0.2.1 // indirect
+	go.opencensus.io v0.23.0
 	go.uber.org/zap v1.18.1
 	golang.org/x/net v0.0.0-20210726213435-c6fcb2dbf985
+	golang.org/x/time v0.0.0-20210220033141-f8bda1e9f3ba
+	gomodules.xyz/jsonpatch/v2 v2.2.0
 	k8s.io/api v0.20.7
+	k8s.io/apiextensions-apiserver v0.20.7
 	k8s.io/apimachinery v0.20.7
 	k8s.io/client-go v0.20.7
 	knative.dev/eventing v0.25.1-0.20210823153835-b2700c2dcf57
@@ -0,0 +1,94 @@
+#!/usr/bin/env bash
+
+# Copyright 2020 The Knative Authors
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# Rewrites the resolved manifests of the CouchDB source, read on stdin, into
+# an additional named installation, e.g. a canary next to the production one:
+#
+#   ko resolve -f source/config | ./hack/install-installation.sh canary knative-sources-canary | kubectl apply -f -
+#
+# The installation runs in its own namespace, its cluster-scoped objects are
+# prefixed with its name, and its controller and webhooks only handle the
+# sources labeled with couchdb.sources.knative.dev/installation=<name>. The
+# CustomResourceDefinition is left out: it belongs to the default installation.
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+if [[ $# -ne 2 ]]; then
+  echo "Usage: $0 NAME NAMESPACE < release.yaml" >&2
+  exit 1
+fi
+readonly NAME="$1"
+readonly NAMESPACE="$2"
+
+if [[ ! "${NAME}" =~ ^[a-z0-9]([-a-z0-9]*[a-z0-9])?$ ]]; then
+  echo "Invalid installation name ${NAME}: it must be a DNS label" >&2
+  exit 1
+fi
+
+awk -v name="${NAME}" -v namespace="${NAMESPACE}" '
+function flush(    i, kind, line, indent, block, installation) {
+  kind = ""
+  for (i = 1; i <= n; i++) {
+    if (doc[i] ~ /^kind: /) {
+      kind = substr(doc[i], 7)
+    }
+  }
+  if (kind == "CustomResourceDefinition") {
+    n = 0
+    return
+  }
+  block = ""
+  installation = 0
+  for (i = 1; i <= n; i++) {
+    line = doc[i]
+    if (line ~ /^[a-zA-Z]/) {
+      block = line
+    }
+    gsub(/namespace: knative-sources$/, "namespace: " namespace, line)
+    if (kind == "Namespace" && line ~ /^  name: knative-sources$/) {
+      line = "  name: " namespace
+    }
+    if ((kind == "ClusterRole" || kind == "ClusterRoleBinding") && block == "metadata:" && line ~ /^  name: /) {
+      sub(/name: /, "name: " name "-", line)
+    }
+    if (kind == "ClusterRoleBinding" && block == "roleRef:" && line ~ /^  name: / && line !~ /addressable-resolver$/) {
+      sub(/name: /, "name: " name "-", line)
+    }
+    gsub(/(defaulting|validation)\.webhook\.couchdb\.messaging\.knative\.dev/, name ".&", line)
+    if (line ~ /name: COUCHDB_INSTALLATION$/) {
+      installation = 1
+    } else if (installation && line ~ /value: ""$/) {
+      sub(/""$/, name, line)
+      installation = 0
+    }
+    if (line ~ /operator: DoesNotExist$/) {
+      indent = line
+      sub(/operator:.*/, "", indent)
+      print indent "operator: In"
+      print indent "values:"
+      print indent "- " name
+      continue
+    }
+    print line
+  }
+  n = 0
+}
+/^---/ { flush(); print; next }
+{ doc[++n] = $0 }
+END { flush() }
+'
@@ -25,6 +25,11 @@ export GO111MODULE=on
 declare -A COMPONENTS
 COMPONENTS=(
   ["couchdb.yaml"]="source/config"
+  ["couchdb-post-install.yaml"]="source/config/post-install"
+  ["couchdb-sandbox-migration.yaml"]="source/config/migration"
+  ["couchdb-sink.yaml"]="sink/config"
+  ["couchdb-channel.yaml"]="channel/config"
+  ["couchdb-broker.yaml"]="broker/config"
 )
 readonly COMPONENTS
 
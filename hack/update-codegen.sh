@@ -38,11 +38,21 @@ ${CODEGEN_PKG}/generate-groups.sh "deepcopy,client,informer,lister" \
 "sources:v1alpha1" \
 --go-header-file ${REPO_ROOT_DIR}/hack/boilerplate.go.txt
 
+${CODEGEN_PKG}/generate-groups.sh "deepcopy,client,informer,lister" \
+"knative.dev/eventing-couchdb/sink/pkg/client" "knative.dev/eventing-couchdb/sink/pkg/apis" \
+"sinks:v1alpha1" \
+--go-header-file ${REPO_ROOT_DIR}/hack/boilerplate.go.txt
+
+${CODEGEN_PKG}/generate-groups.sh "deepcopy,client,informer,lister" \
+"knative.dev/eventing-couchdb/channel/pkg/client" "knative.dev/eventing-couchdb/channel/pkg/apis" \
+"messaging:v1alpha1" \
+--go-header-file ${REPO_ROOT_DIR}/hack/boilerplate.go.txt
+
 # Depends on generate-groups.sh to install bin/deepcopy-gen
 ${GOPATH}/bin/deepcopy-gen \
   -O zz_generated.deepcopy \
   --go-header-file ${REPO_ROOT_DIR}/hack/boilerplate.go.txt \
-  -i knative.dev/eventing-couchdb/source/pkg/apis \
+  -i knative.dev/eventing-couchdb/source/pkg/apis,knative.dev/eventing-couchdb/source/pkg/apis/sources/v1beta1 \
 
 group "Knative Codegen"
 
@@ -52,6 +62,16 @@ ${KNATIVE_CODEGEN_PKG}/hack/generate-knative.sh "injection" \
 "sources:v1alpha1" \
 --go-header-file ${REPO_ROOT_DIR}/hack/boilerplate.go.txt
 
+${KNATIVE_CODEGEN_PKG}/hack/generate-knative.sh "injection" \
+"knative.dev/eventing-couchdb/sink/pkg/client" "knative.dev/eventing-couchdb/sink/pkg/apis" \
+"sinks:v1alpha1" \
+--go-header-file ${REPO_ROOT_DIR}/hack/boilerplate.go.txt
+
+${KNATIVE_CODEGEN_PKG}/hack/generate-knative.sh "injection" \
+"knative.dev/eventing-couchdb/channel/pkg/client" "knative.dev/eventing-couchdb/channel/pkg/apis" \
+"messaging:v1alpha1" \
+--go-header-file ${REPO_ROOT_DIR}/hack/boilerplate.go.txt
+
 group "Update deps post-codegen"
 
 # Make sure our dependencies are up-to-date
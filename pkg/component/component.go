@@ -0,0 +1,77 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package component holds the helpers shared by the controllers and the data
+// planes of the CouchDB sink, channel and broker.
+package component
+
+import (
+	"net/http"
+
+	"github.com/go-kivik/kivik/v3"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+// PodSpecChanged is whether the pod spec differs from the expected one,
+// ignoring the fields defaulted by the API server, except for the
+// environment of the containers, which must match exactly.
+func PodSpecChanged(oldPodSpec corev1.PodSpec, newPodSpec corev1.PodSpec) bool {
+	if !equality.Semantic.DeepDerivative(newPodSpec, oldPodSpec) {
+		return true
+	}
+	if len(oldPodSpec.Containers) != len(newPodSpec.Containers) {
+		return true
+	}
+	for i := range newPodSpec.Containers {
+		if !equality.Semantic.DeepEqual(newPodSpec.Containers[i].Env, oldPodSpec.Containers[i].Env) {
+			return true
+		}
+	}
+	return false
+}
+
+// ServiceChanged is whether the selector or the ports of the Service differ
+// from the expected ones, ignoring the fields defaulted by the API server.
+func ServiceChanged(svc, expected *corev1.Service) bool {
+	return !equality.Semantic.DeepEqual(svc.Spec.Selector, expected.Spec.Selector) ||
+		!equality.Semantic.DeepDerivative(expected.Spec.Ports, svc.Spec.Ports)
+}
+
+// RolledOut is whether all the replicas of the Deployment run its latest
+// template.
+func RolledOut(d *appsv1.Deployment) bool {
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	return d.Status.ObservedGeneration >= d.Generation &&
+		d.Status.UpdatedReplicas == replicas &&
+		d.Status.AvailableReplicas >= replicas
+}
+
+// WriteStatus returns the status of the response to an event CouchDB failed
+// to write: 400 Bad Request when CouchDB rejects the document, which a retry
+// would not fix, otherwise 502 Bad Gateway.
+func WriteStatus(err error) int {
+	switch kivik.StatusCode(err) {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge:
+		return http.StatusBadRequest
+	default:
+		return http.StatusBadGateway
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package component
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/go-kivik/kivik/v3"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRolledOut(t *testing.T) {
+	replicas := int32(1)
+	testCases := map[string]struct {
+		status appsv1.DeploymentStatus
+		want   bool
+	}{
+		"rolled out": {
+			status: appsv1.DeploymentStatus{ObservedGeneration: 2, UpdatedReplicas: 1, AvailableReplicas: 1},
+			want:   true,
+		},
+		"not observed": {
+			status: appsv1.DeploymentStatus{ObservedGeneration: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+		},
+		"rolling out": {
+			status: appsv1.DeploymentStatus{ObservedGeneration: 2, UpdatedReplicas: 0, AvailableReplicas: 1},
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			d := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+				Status:     tc.status,
+			}
+			if got := RolledOut(d); got != tc.want {
+				t.Errorf("RolledOut() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWriteStatus(t *testing.T) {
+	testCases := map[string]struct {
+		err  error
+		want int
+	}{
+		"rejected document": {
+			err:  &kivik.Error{HTTPStatus: http.StatusBadRequest},
+			want: http.StatusBadRequest,
+		},
+		"document too large": {
+			err:  &kivik.Error{HTTPStatus: http.StatusRequestEntityTooLarge},
+			want: http.StatusBadRequest,
+		},
+		"unavailable": {
+			err:  &kivik.Error{HTTPStatus: http.StatusServiceUnavailable},
+			want: http.StatusBadGateway,
+		},
+		"network": {
+			err:  errors.New("connection refused"),
+			want: http.StatusBadGateway,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			if got := WriteStatus(tc.err); got != tc.want {
+				t.Errorf("WriteStatus() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
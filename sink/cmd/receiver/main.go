@@ -0,0 +1,97 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	_ "github.com/go-kivik/couchdb/v3" // The CouchDB driver
+	"github.com/go-kivik/kivik/v3"
+	"github.com/kelseyhightower/envconfig"
+	"go.uber.org/zap"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/signals"
+
+	"knative.dev/eventing-couchdb/sink/pkg/apis/sinks/v1alpha1"
+	"knative.dev/eventing-couchdb/sink/pkg/receiver"
+)
+
+// shutdownTimeout bounds the time given to the events being written once the
+// receiver is asked to stop.
+const shutdownTimeout = 30 * time.Second
+
+// healthPath is the path the receiver answers its readiness probe on.
+const healthPath = "/healthz"
+
+type envConfig struct {
+	Port            int    `envconfig:"PORT" default:"8080"`
+	LoggingConfig   string `envconfig:"K_LOGGING_CONFIG"`
+	CredentialsPath string `envconfig:"COUCHDB_CREDENTIALS" required:"true"`
+	Database        string `envconfig:"COUCHDB_DATABASE" required:"true"`
+	IDAttribute     string `envconfig:"COUCHDB_ID_ATTRIBUTE" default:"id"`
+	IDPrefix        string `envconfig:"COUCHDB_ID_PREFIX"`
+	Document        string `envconfig:"COUCHDB_DOCUMENT" default:"data"`
+	OnConflict      string `envconfig:"COUCHDB_ON_CONFLICT" default:"skip"`
+}
+
+func main() {
+	ctx := signals.NewContext()
+
+	var env envConfig
+	if err := envconfig.Process("", &env); err != nil {
+		log.Fatal("Error processing the environment: ", err)
+	}
+	logger, _ := logging.NewLogger(env.LoggingConfig, "info")
+	defer logger.Sync()
+
+	url, err := ioutil.ReadFile(env.CredentialsPath + "/url")
+	if err != nil {
+		logger.Fatalw("Missing url key in secret", zap.Error(err))
+	}
+	client, err := kivik.New("couch", string(url))
+	if err != nil {
+		logger.Fatalw("Error creating the CouchDB client", zap.Error(err))
+	}
+	db := client.DB(ctx, env.Database)
+	if err := db.Err(); err != nil {
+		logger.Fatalw("Error opening the database", zap.String("database", env.Database), zap.Error(err))
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(healthPath, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.Handle("/", receiver.NewReceiver(logger, db, env.IDAttribute, env.IDPrefix,
+		v1alpha1.DocumentContent(env.Document), v1alpha1.ConflictPolicy(env.OnConflict)))
+	server := &http.Server{Addr: ":" + strconv.Itoa(env.Port), Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
+	logger.Infow("Writing the events into CouchDB", zap.String("database", env.Database), zap.Int("port", env.Port))
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		logger.Fatalw("Error serving the events", zap.Error(err))
+	}
+}
@@ -0,0 +1,38 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import "context"
+
+// SetDefaults implements apis.Defaultable.
+func (s *CouchDbSink) SetDefaults(ctx context.Context) {
+	s.Spec.SetDefaults(ctx)
+}
+
+// SetDefaults fills the _id attribute, the document content and the conflict
+// policy, so that the stored sinks spell out the behavior of their receiver.
+func (ss *CouchDbSinkSpec) SetDefaults(ctx context.Context) {
+	if ss.ID.Attribute == "" {
+		ss.ID.Attribute = IDAttributeID
+	}
+	if ss.Document == "" {
+		ss.Document = DocumentData
+	}
+	if ss.OnConflict == "" {
+		ss.OnConflict = ConflictSkip
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCouchDbSinkSetDefaults(t *testing.T) {
+	testCases := map[string]struct {
+		spec CouchDbSinkSpec
+		want CouchDbSinkSpec
+	}{
+		"empty": {
+			want: CouchDbSinkSpec{
+				ID:         DocumentIDSpec{Attribute: IDAttributeID},
+				Document:   DocumentData,
+				OnConflict: ConflictSkip,
+			},
+		},
+		"set": {
+			spec: CouchDbSinkSpec{
+				ID:         DocumentIDSpec{Attribute: IDAttributeSubject, Prefix: "orders:"},
+				Document:   DocumentEvent,
+				OnConflict: ConflictOverwrite,
+			},
+			want: CouchDbSinkSpec{
+				ID:         DocumentIDSpec{Attribute: IDAttributeSubject, Prefix: "orders:"},
+				Document:   DocumentEvent,
+				OnConflict: ConflictOverwrite,
+			},
+		},
+		"prefix only": {
+			spec: CouchDbSinkSpec{
+				ID: DocumentIDSpec{Prefix: "orders:"},
+			},
+			want: CouchDbSinkSpec{
+				ID:         DocumentIDSpec{Attribute: IDAttributeID, Prefix: "orders:"},
+				Document:   DocumentData,
+				OnConflict: ConflictSkip,
+			},
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			sink := &CouchDbSink{Spec: tc.spec}
+			sink.SetDefaults(context.Background())
+			if diff := cmp.Diff(tc.want, sink.Spec); diff != "" {
+				t.Error("Unexpected spec (-want, +got) =", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	"knative.dev/eventing/pkg/apis/duck"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+)
+
+const (
+	// CouchDbSinkConditionReady has status True when the CouchDbSink is ready to receive events.
+	CouchDbSinkConditionReady = apis.ConditionReady
+
+	// CouchDbSinkConditionDeployed has status True when the receiver of the
+	// CouchDbSink is available.
+	CouchDbSinkConditionDeployed apis.ConditionType = "Deployed"
+
+	// CouchDbSinkConditionAddressable has status True when the CouchDbSink
+	// has an address to send the events to.
+	CouchDbSinkConditionAddressable apis.ConditionType = "Addressable"
+)
+
+var CouchDbSinkCondSet = apis.NewLivingConditionSet(
+	CouchDbSinkConditionDeployed,
+	CouchDbSinkConditionAddressable,
+)
+
+// GetConditionSet retrieves the condition set for this resource. Implements the KRShaped interface.
+func (*CouchDbSink) GetConditionSet() apis.ConditionSet {
+	return CouchDbSinkCondSet
+}
+
+// GetCondition returns the condition currently associated with the given type, or nil.
+func (s *CouchDbSinkStatus) GetCondition(t apis.ConditionType) *apis.Condition {
+	return CouchDbSinkCondSet.Manage(s).GetCondition(t)
+}
+
+// IsReady returns true if the resource is ready overall.
+func (s *CouchDbSinkStatus) IsReady() bool {
+	return CouchDbSinkCondSet.Manage(s).IsHappy()
+}
+
+// InitializeConditions sets relevant unset conditions to Unknown state.
+func (s *CouchDbSinkStatus) InitializeConditions() {
+	CouchDbSinkCondSet.Manage(s).InitializeConditions()
+}
+
+// MarkAddress sets the address of the CouchDbSink, and the condition that it
+// is addressable when the address is not empty.
+func (s *CouchDbSinkStatus) MarkAddress(url *apis.URL) {
+	if url.IsEmpty() {
+		s.Address = nil
+		CouchDbSinkCondSet.Manage(s).MarkFalse(CouchDbSinkConditionAddressable, "AddressEmpty", "The address is empty.")
+		return
+	}
+	s.Address = &duckv1.Addressable{URL: url}
+	CouchDbSinkCondSet.Manage(s).MarkTrue(CouchDbSinkConditionAddressable)
+}
+
+// MarkNoAddress sets the condition that the CouchDbSink has no address.
+func (s *CouchDbSinkStatus) MarkNoAddress(reason, messageFormat string, messageA ...interface{}) {
+	s.Address = nil
+	CouchDbSinkCondSet.Manage(s).MarkFalse(CouchDbSinkConditionAddressable, reason, messageFormat, messageA...)
+}
+
+// PropagateDeploymentAvailability uses the availability of the receiver
+// Deployment to determine the CouchDbSinkConditionDeployed condition.
+func (s *CouchDbSinkStatus) PropagateDeploymentAvailability(d *appsv1.Deployment) {
+	if duck.DeploymentIsAvailable(&d.Status, false) {
+		CouchDbSinkCondSet.Manage(s).MarkTrue(CouchDbSinkConditionDeployed)
+	} else {
+		CouchDbSinkCondSet.Manage(s).MarkFalse(CouchDbSinkConditionDeployed, "DeploymentUnavailable", "The Deployment '%s' is unavailable.", d.Name)
+	}
+}
+
+// MarkNoDeployment sets the condition that the receiver could not be deployed.
+func (s *CouchDbSinkStatus) MarkNoDeployment(reason, messageFormat string, messageA ...interface{}) {
+	CouchDbSinkCondSet.Manage(s).MarkFalse(CouchDbSinkConditionDeployed, reason, messageFormat, messageA...)
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/apis"
+)
+
+var (
+	availableDeployment = &appsv1.Deployment{
+		Status: appsv1.DeploymentStatus{
+			Conditions: []appsv1.DeploymentCondition{{
+				Type:   appsv1.DeploymentAvailable,
+				Status: corev1.ConditionTrue,
+			}},
+		},
+	}
+
+	unavailableDeployment = &appsv1.Deployment{
+		Status: appsv1.DeploymentStatus{
+			Conditions: []appsv1.DeploymentCondition{{
+				Type:   appsv1.DeploymentAvailable,
+				Status: corev1.ConditionFalse,
+			}},
+		},
+	}
+
+	address = apis.HTTP("couchdbsink-orders.ns.svc.cluster.local")
+)
+
+func TestCouchDbSinkGetConditionSet(t *testing.T) {
+	r := &CouchDbSink{}
+
+	if got, want := r.GetConditionSet().GetTopLevelConditionType(), apis.ConditionReady; got != want {
+		t.Errorf("GetTopLevelCondition=%v, want=%v", got, want)
+	}
+}
+
+func TestCouchDbSinkStatusIsReady(t *testing.T) {
+	tests := []struct {
+		name        string
+		deployment  *appsv1.Deployment
+		address     *apis.URL
+		wantReady   bool
+		wantAddress bool
+	}{{
+		name: "initialized",
+	}, {
+		name:       "deployed",
+		deployment: availableDeployment,
+	}, {
+		name:        "addressable",
+		address:     address,
+		wantAddress: true,
+	}, {
+		name:        "deployed and addressable",
+		deployment:  availableDeployment,
+		address:     address,
+		wantReady:   true,
+		wantAddress: true,
+	}, {
+		name:        "unavailable",
+		deployment:  unavailableDeployment,
+		address:     address,
+		wantAddress: true,
+	}, {
+		name:       "empty address",
+		deployment: availableDeployment,
+		address:    &apis.URL{},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := &CouchDbSinkStatus{}
+			s.InitializeConditions()
+			if test.deployment != nil {
+				s.PropagateDeploymentAvailability(test.deployment)
+			}
+			if test.address != nil {
+				s.MarkAddress(test.address)
+			}
+			if got := s.IsReady(); got != test.wantReady {
+				t.Errorf("IsReady() = %v, want %v", got, test.wantReady)
+			}
+			if got := s.Address != nil; got != test.wantAddress {
+				t.Errorf("has an address = %v, want %v", got, test.wantAddress)
+			}
+		})
+	}
+}
+
+func TestCouchDbSinkMarkNoAddress(t *testing.T) {
+	s := &CouchDbSinkStatus{}
+	s.InitializeConditions()
+	s.PropagateDeploymentAvailability(availableDeployment)
+	s.MarkAddress(address)
+	s.MarkNoAddress("ServiceFailed", "Unable to reconcile the service")
+
+	if s.Address != nil {
+		t.Error("Address =", s.Address)
+	}
+	c := s.GetCondition(CouchDbSinkConditionAddressable)
+	if c == nil || !c.IsFalse() || c.Reason != "ServiceFailed" {
+		t.Errorf("Addressable condition = %+v", c)
+	}
+	if s.IsReady() {
+		t.Error("IsReady() = true without an address")
+	}
+}
+
+func TestCouchDbSinkMarkNoDeployment(t *testing.T) {
+	s := &CouchDbSinkStatus{}
+	s.InitializeConditions()
+	s.MarkAddress(address)
+	s.MarkNoDeployment("DeploymentFailed", "Unable to reconcile the receiver")
+
+	c := s.GetCondition(CouchDbSinkConditionDeployed)
+	if c == nil || !c.IsFalse() || c.Reason != "DeploymentFailed" {
+		t.Errorf("Deployed condition = %+v", c)
+	}
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/apis/duck"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"knative.dev/pkg/kmeta"
+)
+
+// +genclient
+// +genreconciler
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CouchDbSink is an Addressable writing the CloudEvents it receives as
+// documents into a CouchDB database.
+// +k8s:openapi-gen=true
+type CouchDbSink struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CouchDbSinkSpec   `json:"spec,omitempty"`
+	Status CouchDbSinkStatus `json:"status,omitempty"`
+}
+
+// Check that CouchDbSink is a runtime.Object.
+var _ runtime.Object = (*CouchDbSink)(nil)
+
+// Check that CouchDbSink can be validated and can be defaulted.
+var _ apis.Validatable = (*CouchDbSink)(nil)
+var _ apis.Defaultable = (*CouchDbSink)(nil)
+
+// Check that we can create OwnerReferences to a CouchDbSink.
+var _ kmeta.OwnerRefable = (*CouchDbSink)(nil)
+
+// Check that the type conforms to the duck Knative Resource shape.
+var _ duckv1.KRShaped = (*CouchDbSink)(nil)
+
+// Check that CouchDbSink implements the Conditions and Addressable duck types.
+var _ = duck.VerifyType(&CouchDbSink{}, &duckv1.Conditions{})
+var _ = duck.VerifyType(&CouchDbSink{}, &duckv1.Addressable{})
+
+// The CloudEvents context attributes the _id of the documents can be taken
+// from. Any other value names an extension attribute.
+const (
+	IDAttributeID      = "id"
+	IDAttributeSource  = "source"
+	IDAttributeSubject = "subject"
+	IDAttributeType    = "type"
+)
+
+// DocumentContent is what the documents written hold.
+type DocumentContent string
+
+const (
+	// DocumentData writes the data of the events, which must be JSON objects.
+	DocumentData DocumentContent = "data"
+
+	// DocumentEvent writes the events in the JSON format of CloudEvents, their
+	// context attributes along with their data.
+	DocumentEvent DocumentContent = "event"
+)
+
+// ConflictPolicy is what happens to an event whose document already exists.
+type ConflictPolicy string
+
+const (
+	// ConflictSkip keeps the existing document and accepts the event, which
+	// makes the redeliveries of an event idempotent.
+	ConflictSkip ConflictPolicy = "skip"
+
+	// ConflictOverwrite replaces the existing document with a new revision.
+	ConflictOverwrite ConflictPolicy = "overwrite"
+
+	// ConflictFail rejects the event with 409 Conflict, for its sender to
+	// retry it or send it to its dead letter sink.
+	ConflictFail ConflictPolicy = "fail"
+)
+
+// CouchDbSinkSpec defines the desired state of CouchDbSink
+type CouchDbSinkSpec struct {
+	// ServiceAccountName holds the name of the Kubernetes service account
+	// as which the underlying K8s resources should be run. If unspecified
+	// this will default to the "default" service account for the namespace
+	// in which the CouchDbSink exists.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// CouchDbCredentials is the Secret whose url key holds the url of
+	// CouchDB, with the credentials to write into the database.
+	CouchDbCredentials corev1.ObjectReference `json:"credentials"`
+
+	// Database is the database the documents are written into.
+	Database string `json:"database"`
+
+	// ID chooses the _id of the documents. Defaults to the id of the events.
+	// +optional
+	ID DocumentIDSpec `json:"id,omitempty"`
+
+	// Document is what the documents hold, data or event. Defaults to data.
+	// +optional
+	Document DocumentContent `json:"document,omitempty"`
+
+	// OnConflict is what happens to an event whose document already exists:
+	// skip, overwrite or fail. Defaults to skip.
+	// +optional
+	OnConflict ConflictPolicy `json:"onConflict,omitempty"`
+}
+
+// DocumentIDSpec maps the events to the _id of their documents.
+type DocumentIDSpec struct {
+	// Attribute is the CloudEvents attribute whose value is the _id: id,
+	// source, subject, type or the name of an extension. The events without
+	// the attribute are rejected with 400 Bad Request.
+	// +optional
+	Attribute string `json:"attribute,omitempty"`
+
+	// Prefix is prepended to the value of the attribute, e.g. the partition
+	// of a partitioned database followed by a colon.
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// CouchDbSinkStatus defines the observed state of CouchDbSink
+type CouchDbSinkStatus struct {
+	// inherits duck/v1 Status, which currently provides:
+	// * ObservedGeneration - the 'Generation' of the Service that was last
+	//   processed by the controller.
+	// * Conditions - the latest available observations of a resource's current
+	//   state.
+	duckv1.Status `json:",inline"`
+
+	// AddressStatus is the address the CloudEvents are sent to.
+	duckv1.AddressStatus `json:",inline"`
+}
+
+// GetGroupVersionKind returns the GroupVersionKind.
+func (*CouchDbSink) GetGroupVersionKind() schema.GroupVersionKind {
+	return SchemeGroupVersion.WithKind("CouchDbSink")
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CouchDbSinkList contains a list of CouchDbSink
+type CouchDbSinkList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CouchDbSink `json:"items"`
+}
+
+// GetStatus retrieves the duck status for this resource. Implements the KRShaped interface.
+func (s *CouchDbSink) GetStatus() *duckv1.Status {
+	return &s.Status.Status
+}
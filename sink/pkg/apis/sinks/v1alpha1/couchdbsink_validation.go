@@ -0,0 +1,94 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"knative.dev/pkg/apis"
+)
+
+// extensionNameRegexp matches valid CloudEvents extension attribute names.
+var extensionNameRegexp = regexp.MustCompile(`^[a-z0-9]+$`)
+
+// databaseNameRegexp matches the names CouchDB accepts for databases.
+var databaseNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9_$()+/-]*$`)
+
+// Validate implements apis.Validatable.
+func (s *CouchDbSink) Validate(ctx context.Context) *apis.FieldError {
+	return s.Spec.Validate(apis.WithinParent(ctx, s.ObjectMeta)).ViaField("spec")
+}
+
+// Validate checks the spec of the sink once defaulted.
+func (ss *CouchDbSinkSpec) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+
+	if ss.ServiceAccountName != "" {
+		if msgs := validation.IsDNS1123Subdomain(ss.ServiceAccountName); len(msgs) > 0 {
+			fe := apis.ErrInvalidValue(ss.ServiceAccountName, "serviceAccountName")
+			fe.Details = strings.Join(msgs, ", ")
+			errs = errs.Also(fe)
+		}
+	}
+
+	if ss.CouchDbCredentials.Name == "" {
+		errs = errs.Also(apis.ErrMissingField("credentials.name"))
+	}
+
+	if ss.Database == "" {
+		errs = errs.Also(apis.ErrMissingField("database"))
+	} else if !databaseNameRegexp.MatchString(ss.Database) {
+		fe := apis.ErrInvalidValue(ss.Database, "database")
+		fe.Details = "database names start with a lowercase letter, followed by lowercase letters, digits and any of _$()+-/"
+		errs = errs.Also(fe)
+	}
+
+	errs = errs.Also(ss.ID.Validate(ctx).ViaField("id"))
+
+	switch ss.Document {
+	case DocumentData, DocumentEvent:
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(ss.Document, "document"))
+	}
+
+	switch ss.OnConflict {
+	case ConflictSkip, ConflictOverwrite, ConflictFail:
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(ss.OnConflict, "onConflict"))
+	}
+	return errs
+}
+
+// Validate checks the attribute and the prefix of the _id.
+func (is *DocumentIDSpec) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+	if !extensionNameRegexp.MatchString(is.Attribute) {
+		fe := apis.ErrInvalidValue(is.Attribute, "attribute")
+		fe.Details = "attribute names must only contain lowercase letters and digits"
+		errs = errs.Also(fe)
+	}
+	// CouchDB reserves the IDs starting with an underscore.
+	if strings.HasPrefix(is.Prefix, "_") {
+		fe := apis.ErrInvalidValue(is.Prefix, "prefix")
+		fe.Details = "document IDs must not start with an underscore"
+		errs = errs.Also(fe)
+	}
+	return errs
+}
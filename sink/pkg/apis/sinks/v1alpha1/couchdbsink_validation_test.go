@@ -0,0 +1,131 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/apis"
+)
+
+func TestCouchDbSinkValidate(t *testing.T) {
+	valid := func() CouchDbSinkSpec {
+		return CouchDbSinkSpec{
+			CouchDbCredentials: corev1.ObjectReference{Name: "couchdb"},
+			Database:           "orders",
+			ID:                 DocumentIDSpec{Attribute: IDAttributeID},
+			Document:           DocumentData,
+			OnConflict:         ConflictSkip,
+		}
+	}
+
+	testCases := map[string]struct {
+		spec    func(*CouchDbSinkSpec)
+		wantErr *apis.FieldError
+	}{
+		"valid": {
+			spec: func(*CouchDbSinkSpec) {},
+		},
+		"extension attribute with a prefix": {
+			spec: func(s *CouchDbSinkSpec) {
+				s.ID = DocumentIDSpec{Attribute: "orderid", Prefix: "orders:"}
+			},
+		},
+		"database with special characters": {
+			spec: func(s *CouchDbSinkSpec) {
+				s.Database = "shop/orders_2021"
+			},
+		},
+		"missing credentials": {
+			spec: func(s *CouchDbSinkSpec) {
+				s.CouchDbCredentials = corev1.ObjectReference{}
+			},
+			wantErr: apis.ErrMissingField("spec.credentials.name"),
+		},
+		"missing database": {
+			spec: func(s *CouchDbSinkSpec) {
+				s.Database = ""
+			},
+			wantErr: apis.ErrMissingField("spec.database"),
+		},
+		"invalid database": {
+			spec: func(s *CouchDbSinkSpec) {
+				s.Database = "Orders"
+			},
+			wantErr: &apis.FieldError{
+				Message: `invalid value: Orders`,
+				Paths:   []string{"spec.database"},
+				Details: "database names start with a lowercase letter, followed by lowercase letters, digits and any of _$()+-/",
+			},
+		},
+		"invalid attribute": {
+			spec: func(s *CouchDbSinkSpec) {
+				s.ID.Attribute = "order-id"
+			},
+			wantErr: &apis.FieldError{
+				Message: `invalid value: order-id`,
+				Paths:   []string{"spec.id.attribute"},
+				Details: "attribute names must only contain lowercase letters and digits",
+			},
+		},
+		"reserved prefix": {
+			spec: func(s *CouchDbSinkSpec) {
+				s.ID.Prefix = "_design/"
+			},
+			wantErr: &apis.FieldError{
+				Message: `invalid value: _design/`,
+				Paths:   []string{"spec.id.prefix"},
+				Details: "document IDs must not start with an underscore",
+			},
+		},
+		"invalid document": {
+			spec: func(s *CouchDbSinkSpec) {
+				s.Document = "attachment"
+			},
+			wantErr: apis.ErrInvalidValue("attachment", "spec.document"),
+		},
+		"invalid conflict policy": {
+			spec: func(s *CouchDbSinkSpec) {
+				s.OnConflict = "merge"
+			},
+			wantErr: apis.ErrInvalidValue("merge", "spec.onConflict"),
+		},
+		"invalid service account": {
+			spec: func(s *CouchDbSinkSpec) {
+				s.ServiceAccountName = "Writer"
+			},
+			wantErr: &apis.FieldError{
+				Message: `invalid value: Writer`,
+				Paths:   []string{"spec.serviceAccountName"},
+				Details: "a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')",
+			},
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			spec := valid()
+			tc.spec(&spec)
+			sink := &CouchDbSink{Spec: spec}
+			got := sink.Validate(context.Background())
+			if got.Error() != tc.wantErr.Error() {
+				t.Errorf("Validate() = %v, want %v", got, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+// +build !ignore_autogenerated
+
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by deepcopy-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CouchDbSink) DeepCopyInto(out *CouchDbSink) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CouchDbSink.
+func (in *CouchDbSink) DeepCopy() *CouchDbSink {
+	if in == nil {
+		return nil
+	}
+	out := new(CouchDbSink)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CouchDbSink) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CouchDbSinkList) DeepCopyInto(out *CouchDbSinkList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]CouchDbSink, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CouchDbSinkList.
+func (in *CouchDbSinkList) DeepCopy() *CouchDbSinkList {
+	if in == nil {
+		return nil
+	}
+	out := new(CouchDbSinkList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CouchDbSinkList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CouchDbSinkSpec) DeepCopyInto(out *CouchDbSinkSpec) {
+	*out = *in
+	out.CouchDbCredentials = in.CouchDbCredentials
+	out.ID = in.ID
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CouchDbSinkSpec.
+func (in *CouchDbSinkSpec) DeepCopy() *CouchDbSinkSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CouchDbSinkSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CouchDbSinkStatus) DeepCopyInto(out *CouchDbSinkStatus) {
+	*out = *in
+	in.Status.DeepCopyInto(&out.Status)
+	in.AddressStatus.DeepCopyInto(&out.AddressStatus)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CouchDbSinkStatus.
+func (in *CouchDbSinkStatus) DeepCopy() *CouchDbSinkStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CouchDbSinkStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DocumentIDSpec) DeepCopyInto(out *DocumentIDSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DocumentIDSpec.
+func (in *DocumentIDSpec) DeepCopy() *DocumentIDSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DocumentIDSpec)
+	in.DeepCopyInto(out)
+	return out
+}
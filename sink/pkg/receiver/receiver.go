@@ -0,0 +1,203 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package receiver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/binding"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+	"github.com/cloudevents/sdk-go/v2/types"
+	"github.com/go-kivik/kivik/v3"
+	"go.uber.org/zap"
+
+	"knative.dev/eventing-couchdb/pkg/component"
+	"knative.dev/eventing-couchdb/sink/pkg/apis/sinks/v1alpha1"
+)
+
+// overwriteAttempts bounds the attempts to overwrite a document updated
+// concurrently by someone else.
+const overwriteAttempts = 3
+
+// Receiver writes the CloudEvents it receives over HTTP as documents into a
+// CouchDB database.
+type Receiver struct {
+	logger *zap.SugaredLogger
+	db     *kivik.DB
+
+	idAttribute string
+	idPrefix    string
+	document    v1alpha1.DocumentContent
+	onConflict  v1alpha1.ConflictPolicy
+}
+
+var _ http.Handler = (*Receiver)(nil)
+
+// NewReceiver returns the Receiver writing the events into db, with the IDs
+// of the documents taken from the idAttribute of the events, prefixed with
+// idPrefix.
+func NewReceiver(logger *zap.SugaredLogger, db *kivik.DB, idAttribute, idPrefix string, document v1alpha1.DocumentContent, onConflict v1alpha1.ConflictPolicy) *Receiver {
+	return &Receiver{
+		logger:      logger,
+		db:          db,
+		idAttribute: idAttribute,
+		idPrefix:    idPrefix,
+		document:    document,
+		onConflict:  onConflict,
+	}
+}
+
+// ServeHTTP writes the event of the request. It responds 201 Created once the
+// document is written, 200 OK when an existing document is kept, 400 Bad
+// Request for an event that cannot be written, 409 Conflict for an existing
+// document with the fail conflict policy, and 502 Bad Gateway when CouchDB
+// fails, for the sender to retry the event.
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	event, err := binding.ToEvent(req.Context(), cehttp.NewMessageFromHttpRequest(req))
+	if err != nil {
+		http.Error(w, fmt.Sprint("invalid CloudEvent: ", err), http.StatusBadRequest)
+		return
+	}
+	id, err := r.documentID(event)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	doc, err := r.makeDocument(event)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger := r.logger.With(zap.String("id", id), zap.String("event", event.ID()))
+	status, err := r.write(req.Context(), id, doc)
+	if err != nil {
+		logger.Warnw("Unable to write the document", zap.Int("status", status), zap.Error(err))
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Debugw("Wrote the document", zap.Int("status", status))
+	w.WriteHeader(status)
+}
+
+// documentID returns the _id of the document of the event.
+func (r *Receiver) documentID(event *cloudevents.Event) (string, error) {
+	var value string
+	switch r.idAttribute {
+	case v1alpha1.IDAttributeID:
+		value = event.ID()
+	case v1alpha1.IDAttributeSource:
+		value = event.Source()
+	case v1alpha1.IDAttributeSubject:
+		value = event.Subject()
+	case v1alpha1.IDAttributeType:
+		value = event.Type()
+	default:
+		if ext, ok := event.Extensions()[r.idAttribute]; ok {
+			s, err := types.Format(ext)
+			if err != nil {
+				return "", fmt.Errorf("invalid %s attribute: %w", r.idAttribute, err)
+			}
+			value = s
+		}
+	}
+	if value == "" {
+		return "", fmt.Errorf("the event has no %s attribute for the document ID", r.idAttribute)
+	}
+	id := r.idPrefix + value
+	// CouchDB reserves the IDs starting with an underscore.
+	if strings.HasPrefix(id, "_") {
+		return "", fmt.Errorf("invalid document ID %q: it must not start with an underscore", id)
+	}
+	return id, nil
+}
+
+// makeDocument returns the document of the event, without the top-level
+// members starting with an underscore, like _id and _rev, which CouchDB
+// reserves.
+func (r *Receiver) makeDocument(event *cloudevents.Event) (map[string]interface{}, error) {
+	var raw []byte
+	switch r.document {
+	case v1alpha1.DocumentEvent:
+		b, err := json.Marshal(event)
+		if err != nil {
+			return nil, fmt.Errorf("unable to encode the event: %w", err)
+		}
+		raw = b
+	default:
+		if len(event.Data()) == 0 {
+			return nil, errors.New("the event has no data")
+		}
+		raw = event.Data()
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil || doc == nil {
+		return nil, errors.New("the data of the event must be a JSON object")
+	}
+	for k := range doc {
+		if strings.HasPrefix(k, "_") {
+			delete(doc, k)
+		}
+	}
+	return doc, nil
+}
+
+// write writes the document, applying the conflict policy when it exists,
+// and returns the status of the response.
+func (r *Receiver) write(ctx context.Context, id string, doc map[string]interface{}) (int, error) {
+	for attempt := 1; ; attempt++ {
+		_, err := r.db.Put(ctx, id, doc)
+		if err == nil {
+			return http.StatusCreated, nil
+		}
+		if kivik.StatusCode(err) != http.StatusConflict {
+			return component.WriteStatus(err), err
+		}
+
+		switch r.onConflict {
+		case v1alpha1.ConflictSkip:
+			return http.StatusOK, nil
+		case v1alpha1.ConflictFail:
+			return http.StatusConflict, fmt.Errorf("the document %s already exists", id)
+		}
+		if attempt == overwriteAttempts {
+			return http.StatusConflict, fmt.Errorf("the document %s kept changing while overwriting it", id)
+		}
+		_, rev, err := r.db.GetMeta(ctx, id)
+		switch {
+		case kivik.StatusCode(err) == http.StatusNotFound:
+			// Deleted in the meantime.
+			delete(doc, "_rev")
+		case err != nil:
+			return component.WriteStatus(err), err
+		default:
+			doc["_rev"] = rev
+		}
+	}
+}
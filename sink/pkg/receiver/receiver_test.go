@@ -0,0 +1,235 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package receiver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/binding"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+	"github.com/go-kivik/kivik/v3"
+	"github.com/go-kivik/kivikmock/v3"
+	"go.uber.org/zap"
+
+	"knative.dev/eventing-couchdb/sink/pkg/apis/sinks/v1alpha1"
+)
+
+func newEvent(data interface{}) cloudevents.Event {
+	event := cloudevents.NewEvent()
+	event.SetID("abc")
+	event.SetSource("/orders")
+	event.SetType("order.created")
+	if data != nil {
+		event.SetData(cloudevents.ApplicationJSON, data)
+	}
+	return event
+}
+
+func TestReceiver(t *testing.T) {
+	withSubject := newEvent(map[string]interface{}{"total": 3})
+	withSubject.SetSubject("order-1")
+	withExtension := newEvent(map[string]interface{}{"total": 3})
+	withExtension.SetExtension("orderid", "order-1")
+
+	conflict := &kivik.Error{HTTPStatus: http.StatusConflict, Message: "conflict"}
+
+	testCases := map[string]struct {
+		event       cloudevents.Event
+		idAttribute string
+		idPrefix    string
+		document    v1alpha1.DocumentContent
+		onConflict  v1alpha1.ConflictPolicy
+		expect      func(db *kivikmock.DB)
+
+		wantStatus int
+	}{
+		"data": {
+			event: newEvent(map[string]interface{}{"total": 3, "_id": "other", "_rev": "1-a"}),
+			expect: func(db *kivikmock.DB) {
+				db.ExpectPut().WithDocID("abc").WithDoc(map[string]interface{}{"total": 3}).WillReturn("1-b")
+			},
+			wantStatus: http.StatusCreated,
+		},
+		"event": {
+			event:    newEvent(map[string]interface{}{"total": 3}),
+			document: v1alpha1.DocumentEvent,
+			expect: func(db *kivikmock.DB) {
+				db.ExpectPut().WithDocID("abc").WithDoc(map[string]interface{}{
+					"specversion":     "1.0",
+					"id":              "abc",
+					"source":          "/orders",
+					"type":            "order.created",
+					"datacontenttype": "application/json",
+					"data":            map[string]interface{}{"total": 3},
+				}).WillReturn("1-b")
+			},
+			wantStatus: http.StatusCreated,
+		},
+		"subject with a prefix": {
+			event:       withSubject,
+			idAttribute: v1alpha1.IDAttributeSubject,
+			idPrefix:    "orders:",
+			expect: func(db *kivikmock.DB) {
+				db.ExpectPut().WithDocID("orders:order-1").WillReturn("1-b")
+			},
+			wantStatus: http.StatusCreated,
+		},
+		"extension": {
+			event:       withExtension,
+			idAttribute: "orderid",
+			expect: func(db *kivikmock.DB) {
+				db.ExpectPut().WithDocID("order-1").WillReturn("1-b")
+			},
+			wantStatus: http.StatusCreated,
+		},
+		"missing attribute": {
+			event:       newEvent(map[string]interface{}{"total": 3}),
+			idAttribute: v1alpha1.IDAttributeSubject,
+			wantStatus:  http.StatusBadRequest,
+		},
+		"reserved id": {
+			event:       withSubject,
+			idAttribute: v1alpha1.IDAttributeSubject,
+			idPrefix:    "_design/",
+			wantStatus:  http.StatusBadRequest,
+		},
+		"no data": {
+			event:      newEvent(nil),
+			wantStatus: http.StatusBadRequest,
+		},
+		"data not an object": {
+			event:      newEvent([]int{1, 2}),
+			wantStatus: http.StatusBadRequest,
+		},
+		"conflict skipped": {
+			event: newEvent(map[string]interface{}{"total": 3}),
+			expect: func(db *kivikmock.DB) {
+				db.ExpectPut().WithDocID("abc").WillReturnError(conflict)
+			},
+			wantStatus: http.StatusOK,
+		},
+		"conflict failed": {
+			event:      newEvent(map[string]interface{}{"total": 3}),
+			onConflict: v1alpha1.ConflictFail,
+			expect: func(db *kivikmock.DB) {
+				db.ExpectPut().WithDocID("abc").WillReturnError(conflict)
+			},
+			wantStatus: http.StatusConflict,
+		},
+		"conflict overwritten": {
+			event:      newEvent(map[string]interface{}{"total": 3}),
+			onConflict: v1alpha1.ConflictOverwrite,
+			expect: func(db *kivikmock.DB) {
+				db.ExpectPut().WithDocID("abc").WillReturnError(conflict)
+				db.ExpectGetMeta().WithDocID("abc").WillReturn(10, "1-a")
+				db.ExpectPut().WithDocID("abc").WithDoc(map[string]interface{}{"total": 3, "_rev": "1-a"}).WillReturn("2-b")
+			},
+			wantStatus: http.StatusCreated,
+		},
+		"conflict overwritten after a deletion": {
+			event:      newEvent(map[string]interface{}{"total": 3}),
+			onConflict: v1alpha1.ConflictOverwrite,
+			expect: func(db *kivikmock.DB) {
+				db.ExpectPut().WithDocID("abc").WillReturnError(conflict)
+				db.ExpectGetMeta().WithDocID("abc").WillReturnError(&kivik.Error{HTTPStatus: http.StatusNotFound})
+				db.ExpectPut().WithDocID("abc").WithDoc(map[string]interface{}{"total": 3}).WillReturn("3-c")
+			},
+			wantStatus: http.StatusCreated,
+		},
+		"conflicts while overwriting": {
+			event:      newEvent(map[string]interface{}{"total": 3}),
+			onConflict: v1alpha1.ConflictOverwrite,
+			expect: func(db *kivikmock.DB) {
+				for i := 1; i < overwriteAttempts; i++ {
+					db.ExpectPut().WithDocID("abc").WillReturnError(conflict)
+					db.ExpectGetMeta().WithDocID("abc").WillReturn(10, "1-a")
+				}
+				db.ExpectPut().WithDocID("abc").WillReturnError(conflict)
+			},
+			wantStatus: http.StatusConflict,
+		},
+		"rejected by CouchDB": {
+			event: newEvent(map[string]interface{}{"total": 3}),
+			expect: func(db *kivikmock.DB) {
+				db.ExpectPut().WithDocID("abc").WillReturnError(&kivik.Error{HTTPStatus: http.StatusBadRequest})
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		"CouchDB unavailable": {
+			event: newEvent(map[string]interface{}{"total": 3}),
+			expect: func(db *kivikmock.DB) {
+				db.ExpectPut().WithDocID("abc").WillReturnError(&kivik.Error{HTTPStatus: http.StatusServiceUnavailable})
+			},
+			wantStatus: http.StatusBadGateway,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			client, mock := kivikmock.NewT(t)
+			db := mock.NewDB()
+			mock.ExpectDB().WithName("orders").WillReturn(db)
+			if tc.expect != nil {
+				tc.expect(db)
+			}
+
+			r := &Receiver{
+				logger:      zap.NewNop().Sugar(),
+				db:          client.DB(context.Background(), "orders"),
+				idAttribute: v1alpha1.IDAttributeID,
+				idPrefix:    tc.idPrefix,
+				document:    v1alpha1.DocumentData,
+				onConflict:  v1alpha1.ConflictSkip,
+			}
+			if tc.idAttribute != "" {
+				r.idAttribute = tc.idAttribute
+			}
+			if tc.document != "" {
+				r.document = tc.document
+			}
+			if tc.onConflict != "" {
+				r.onConflict = tc.onConflict
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			if err := cehttp.WriteRequest(context.Background(), binding.ToMessage(&tc.event), req); err != nil {
+				t.Fatal("WriteRequest() =", err)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tc.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tc.wantStatus, w.Body)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestReceiverMethod(t *testing.T) {
+	r := &Receiver{logger: zap.NewNop().Sugar()}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"os"
+
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/cache"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
+	deploymentinformer "knative.dev/pkg/client/injection/kube/informers/apps/v1/deployment"
+	"knative.dev/pkg/configmap"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+
+	"knative.dev/eventing-couchdb/sink/pkg/apis/sinks/v1alpha1"
+	couchdbsinkinformer "knative.dev/eventing-couchdb/sink/pkg/client/injection/informers/sinks/v1alpha1/couchdbsink"
+	couchdbsinkreconciler "knative.dev/eventing-couchdb/sink/pkg/client/injection/reconciler/sinks/v1alpha1/couchdbsink"
+)
+
+func init() {
+	v1alpha1.AddToScheme(scheme.Scheme)
+}
+
+// NewController initializes the controller and is called by the generated code
+// Registers event handlers to enqueue events
+func NewController(
+	ctx context.Context,
+	cmw configmap.Watcher,
+) *controller.Impl {
+	deploymentInformer := deploymentinformer.Get(ctx)
+	couchdbSinkInformer := couchdbsinkinformer.Get(ctx)
+
+	receiverImage, defined := os.LookupEnv(receiverImageEnvVar)
+	if !defined {
+		logging.FromContext(ctx).Errorf("required environment variable %q not defined", receiverImageEnvVar)
+		return nil
+	}
+
+	r := &Reconciler{
+		receiverImage:    receiverImage,
+		kubeClientSet:    kubeclient.Get(ctx),
+		deploymentLister: deploymentInformer.Lister(),
+	}
+	impl := couchdbsinkreconciler.NewImpl(ctx, r)
+
+	logging.FromContext(ctx).Info("Setting up event handlers")
+	couchdbSinkInformer.Informer().AddEventHandler(controller.HandleAll(impl.Enqueue))
+
+	ownsSink := controller.FilterControllerGK(v1alpha1.Kind("CouchDbSink"))
+	deploymentInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: ownsSink,
+		Handler:    controller.HandleAll(impl.EnqueueControllerOf),
+	})
+
+	return impl
+}
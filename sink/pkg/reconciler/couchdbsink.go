@@ -0,0 +1,156 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	appsv1listers "k8s.io/client-go/listers/apps/v1"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/network"
+	pkgreconciler "knative.dev/pkg/reconciler"
+
+	"knative.dev/eventing-couchdb/pkg/component"
+	"knative.dev/eventing-couchdb/sink/pkg/apis/sinks/v1alpha1"
+	couchdbsinkreconciler "knative.dev/eventing-couchdb/sink/pkg/client/injection/reconciler/sinks/v1alpha1/couchdbsink"
+	"knative.dev/eventing-couchdb/sink/pkg/reconciler/resources"
+)
+
+const (
+	// Name of the corev1.Events emitted from the reconciliation process
+	couchdbsinkDeploymentCreated = "CouchDbSinkDeploymentCreated"
+	couchdbsinkDeploymentUpdated = "CouchDbSinkDeploymentUpdated"
+	couchdbsinkServiceCreated    = "CouchDbSinkServiceCreated"
+	couchdbsinkServiceUpdated    = "CouchDbSinkServiceUpdated"
+
+	// receiverImageEnvVar is the environment variable of the controller
+	// holding the image of the receivers.
+	receiverImageEnvVar = "COUCHDB_SINK_RECEIVER_IMAGE"
+)
+
+// Reconciler reconciles the receiver Deployment and Service of the sinks.
+type Reconciler struct {
+	receiverImage string
+
+	kubeClientSet    kubernetes.Interface
+	deploymentLister appsv1listers.DeploymentLister
+}
+
+// Check that our Reconciler implements Interface
+var _ couchdbsinkreconciler.Interface = (*Reconciler)(nil)
+
+// ReconcileKind implements Interface.ReconcileKind.
+func (r *Reconciler) ReconcileKind(ctx context.Context, sink *v1alpha1.CouchDbSink) pkgreconciler.Event {
+	sink.Status.InitializeConditions()
+
+	args := &resources.ReceiverArgs{
+		Image:  r.receiverImage,
+		Sink:   sink,
+		Labels: resources.Labels(sink.Name),
+	}
+
+	d, err := r.reconcileReceiver(ctx, sink, resources.MakeReceiver(args))
+	if err != nil {
+		sink.Status.MarkNoDeployment("DeploymentFailed", "Unable to reconcile the receiver: %v", err)
+		return err
+	}
+	sink.Status.PropagateDeploymentAvailability(d)
+
+	svc, err := r.reconcileService(ctx, sink, resources.MakeService(args))
+	if err != nil {
+		sink.Status.MarkNoAddress("ServiceFailed", "Unable to reconcile the service: %v", err)
+		return err
+	}
+	sink.Status.MarkAddress(&apis.URL{
+		Scheme: "http",
+		Host:   network.GetServiceHostname(svc.Name, svc.Namespace),
+	})
+	return nil
+}
+
+// reconcileReceiver creates the receiver Deployment of the sink, or updates it
+// when its pod template changed.
+func (r *Reconciler) reconcileReceiver(ctx context.Context, sink *v1alpha1.CouchDbSink, expected *appsv1.Deployment) (*appsv1.Deployment, error) {
+	d, err := r.deploymentLister.Deployments(sink.Namespace).Get(expected.Name)
+	if apierrors.IsNotFound(err) {
+		d, err = r.kubeClientSet.AppsV1().Deployments(sink.Namespace).Create(ctx, expected, metav1.CreateOptions{})
+		if err != nil {
+			return nil, err
+		}
+		controller.GetEventRecorder(ctx).Event(sink, corev1.EventTypeNormal, couchdbsinkDeploymentCreated, "Deployment created")
+		return d, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error getting the receiver: %w", err)
+	} else if !metav1.IsControlledBy(d, sink) {
+		return nil, fmt.Errorf("deployment %q is not owned by CouchDbSink %q", d.Name, sink.Name)
+	}
+
+	if !component.PodSpecChanged(d.Spec.Template.Spec, expected.Spec.Template.Spec) &&
+		equality.Semantic.DeepEqual(d.Spec.Template.Labels, expected.Spec.Template.Labels) {
+		logging.FromContext(ctx).Debugw("Reusing the existing receiver", zap.String("deployment", d.Name))
+		return d, nil
+	}
+	d = d.DeepCopy()
+	d.Spec.Template.Spec = expected.Spec.Template.Spec
+	d.Spec.Template.Labels = expected.Spec.Template.Labels
+	if d, err = r.kubeClientSet.AppsV1().Deployments(sink.Namespace).Update(ctx, d, metav1.UpdateOptions{}); err != nil {
+		return nil, err
+	}
+	controller.GetEventRecorder(ctx).Event(sink, corev1.EventTypeNormal, couchdbsinkDeploymentUpdated, "Deployment updated")
+	return d, nil
+}
+
+// reconcileService creates the Service of the sink, or updates it when its
+// selector or ports changed.
+func (r *Reconciler) reconcileService(ctx context.Context, sink *v1alpha1.CouchDbSink, expected *corev1.Service) (*corev1.Service, error) {
+	// The Services are not watched, only the reconciler changes them.
+	svc, err := r.kubeClientSet.CoreV1().Services(sink.Namespace).Get(ctx, expected.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		svc, err = r.kubeClientSet.CoreV1().Services(sink.Namespace).Create(ctx, expected, metav1.CreateOptions{})
+		if err != nil {
+			return nil, err
+		}
+		controller.GetEventRecorder(ctx).Event(sink, corev1.EventTypeNormal, couchdbsinkServiceCreated, "Service created")
+		return svc, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error getting the service: %w", err)
+	} else if !metav1.IsControlledBy(svc, sink) {
+		return nil, fmt.Errorf("service %q is not owned by CouchDbSink %q", svc.Name, sink.Name)
+	}
+
+	if !component.ServiceChanged(svc, expected) {
+		return svc, nil
+	}
+	svc = svc.DeepCopy()
+	svc.Spec.Selector = expected.Spec.Selector
+	svc.Spec.Ports = expected.Spec.Ports
+	if svc, err = r.kubeClientSet.CoreV1().Services(sink.Namespace).Update(ctx, svc, metav1.UpdateOptions{}); err != nil {
+		return nil, err
+	}
+	controller.GetEventRecorder(ctx).Event(sink, corev1.EventTypeNormal, couchdbsinkServiceUpdated, "Service updated")
+	return svc, nil
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"knative.dev/eventing-couchdb/pkg/component"
+	"knative.dev/eventing-couchdb/sink/pkg/apis/sinks/v1alpha1"
+	"knative.dev/eventing-couchdb/sink/pkg/reconciler/resources"
+)
+
+func newArgs() *resources.ReceiverArgs {
+	sink := &v1alpha1.CouchDbSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "ns", UID: "1234"},
+		Spec: v1alpha1.CouchDbSinkSpec{
+			CouchDbCredentials: corev1.ObjectReference{Name: "couchdb"},
+			Database:           "orders",
+			ID:                 v1alpha1.DocumentIDSpec{Attribute: v1alpha1.IDAttributeID},
+			Document:           v1alpha1.DocumentData,
+			OnConflict:         v1alpha1.ConflictSkip,
+		},
+	}
+	return &resources.ReceiverArgs{Image: "receiver", Sink: sink, Labels: resources.Labels(sink.Name)}
+}
+
+func TestPodSpecChanged(t *testing.T) {
+	expected := resources.MakeReceiver(newArgs()).Spec.Template.Spec
+
+	// The API server defaults some fields of the pod spec.
+	defaulted := *expected.DeepCopy()
+	defaulted.RestartPolicy = corev1.RestartPolicyAlways
+	defaulted.Containers[0].TerminationMessagePath = "/dev/termination-log"
+	if component.PodSpecChanged(defaulted, expected) {
+		t.Error("PodSpecChanged() = true for the defaulted pod spec")
+	}
+
+	args := newArgs()
+	args.Sink.Spec.OnConflict = v1alpha1.ConflictOverwrite
+	if !component.PodSpecChanged(defaulted, resources.MakeReceiver(args).Spec.Template.Spec) {
+		t.Error("PodSpecChanged() = false after the conflict policy changed")
+	}
+
+	args = newArgs()
+	args.Sink.Spec.Database = "invoices"
+	if !component.PodSpecChanged(defaulted, resources.MakeReceiver(args).Spec.Template.Spec) {
+		t.Error("PodSpecChanged() = false after the database changed")
+	}
+}
+
+func TestServiceChanged(t *testing.T) {
+	expected := resources.MakeService(newArgs())
+
+	// The API server defaults the protocol of the ports and the cluster IP.
+	defaulted := expected.DeepCopy()
+	defaulted.Spec.ClusterIP = "10.0.0.1"
+	defaulted.Spec.Ports[0].Protocol = corev1.ProtocolTCP
+	if component.ServiceChanged(defaulted, expected) {
+		t.Error("ServiceChanged() = true for the defaulted service")
+	}
+
+	changed := defaulted.DeepCopy()
+	changed.Spec.Selector = map[string]string{"app": "other"}
+	if !component.ServiceChanged(changed, expected) {
+		t.Error("ServiceChanged() = false after the selector changed")
+	}
+
+	changed = defaulted.DeepCopy()
+	changed.Spec.Ports[0].Port = 8080
+	if !component.ServiceChanged(changed, expected) {
+		t.Error("ServiceChanged() = false after the port changed")
+	}
+}
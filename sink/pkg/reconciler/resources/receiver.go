@@ -0,0 +1,154 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"knative.dev/pkg/kmeta"
+
+	"knative.dev/eventing-couchdb/sink/pkg/apis/sinks/v1alpha1"
+)
+
+// ReceiverPort is the port the receiver serves the events on.
+const ReceiverPort = 8080
+
+// CredentialsVolumeName is the volume of the credentials of the sink.
+const CredentialsVolumeName = "couchdb-credentials"
+
+// ReceiverArgs are the arguments needed to create the receiver of a
+// CouchDbSink. Every field is required.
+type ReceiverArgs struct {
+	Image  string
+	Sink   *v1alpha1.CouchDbSink
+	Labels map[string]string
+}
+
+// ReceiverName returns the name of the Deployment and the Service of the
+// receiver of the sink.
+func ReceiverName(sink *v1alpha1.CouchDbSink) string {
+	return kmeta.ChildName(fmt.Sprintf("couchdbsink-%s-", sink.Name), string(sink.UID))
+}
+
+func makeObjectMeta(args *ReceiverArgs) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: args.Sink.Namespace,
+		Name:      ReceiverName(args.Sink),
+		Labels:    args.Labels,
+		OwnerReferences: []metav1.OwnerReference{
+			*kmeta.NewControllerRef(args.Sink),
+		},
+	}
+}
+
+// MakeReceiver generates (but does not insert into K8s) the receiver
+// Deployment of the sink.
+func MakeReceiver(args *ReceiverArgs) *appsv1.Deployment {
+	replicas := int32(1)
+	spec := args.Sink.Spec
+	return &appsv1.Deployment{
+		ObjectMeta: makeObjectMeta(args),
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: args.Labels,
+			},
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: args.Labels,
+				},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: spec.ServiceAccountName,
+					Containers: []corev1.Container{{
+						Name:  "receiver",
+						Image: args.Image,
+						Ports: []corev1.ContainerPort{{
+							Name:          "http",
+							ContainerPort: ReceiverPort,
+						}},
+						Env: []corev1.EnvVar{{
+							Name:  "PORT",
+							Value: fmt.Sprint(ReceiverPort),
+						}, {
+							Name:  "K_LOGGING_CONFIG",
+							Value: "",
+						}, {
+							Name:  "COUCHDB_CREDENTIALS",
+							Value: "/etc/couchdb-credentials",
+						}, {
+							Name:  "COUCHDB_DATABASE",
+							Value: spec.Database,
+						}, {
+							Name:  "COUCHDB_ID_ATTRIBUTE",
+							Value: spec.ID.Attribute,
+						}, {
+							Name:  "COUCHDB_ID_PREFIX",
+							Value: spec.ID.Prefix,
+						}, {
+							Name:  "COUCHDB_DOCUMENT",
+							Value: string(spec.Document),
+						}, {
+							Name:  "COUCHDB_ON_CONFLICT",
+							Value: string(spec.OnConflict),
+						}},
+						VolumeMounts: []corev1.VolumeMount{{
+							Name:      CredentialsVolumeName,
+							MountPath: "/etc/couchdb-credentials",
+							ReadOnly:  true,
+						}},
+						ReadinessProbe: &corev1.Probe{
+							Handler: corev1.Handler{
+								HTTPGet: &corev1.HTTPGetAction{
+									Path: "/healthz",
+									Port: intstr.FromInt(ReceiverPort),
+								},
+							},
+						},
+					}},
+					Volumes: []corev1.Volume{{
+						Name: CredentialsVolumeName,
+						VolumeSource: corev1.VolumeSource{
+							Secret: &corev1.SecretVolumeSource{
+								SecretName: spec.CouchDbCredentials.Name,
+							},
+						},
+					}},
+				},
+			},
+		},
+	}
+}
+
+// MakeService generates (but does not insert into K8s) the Service the
+// events are sent to, in front of the receiver.
+func MakeService(args *ReceiverArgs) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: makeObjectMeta(args),
+		Spec: corev1.ServiceSpec{
+			Selector: args.Labels,
+			Ports: []corev1.ServicePort{{
+				Name:       "http",
+				Port:       80,
+				TargetPort: intstr.FromInt(ReceiverPort),
+			}},
+		},
+	}
+}
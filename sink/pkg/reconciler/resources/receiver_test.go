@@ -0,0 +1,102 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"knative.dev/eventing-couchdb/sink/pkg/apis/sinks/v1alpha1"
+)
+
+func newSink() *v1alpha1.CouchDbSink {
+	return &v1alpha1.CouchDbSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "orders",
+			Namespace: "ns",
+			UID:       "1234",
+		},
+		Spec: v1alpha1.CouchDbSinkSpec{
+			ServiceAccountName: "writer",
+			CouchDbCredentials: corev1.ObjectReference{Name: "couchdb"},
+			Database:           "orders",
+			ID:                 v1alpha1.DocumentIDSpec{Attribute: "subject", Prefix: "orders:"},
+			Document:           v1alpha1.DocumentEvent,
+			OnConflict:         v1alpha1.ConflictOverwrite,
+		},
+	}
+}
+
+func TestMakeReceiver(t *testing.T) {
+	sink := newSink()
+	d := MakeReceiver(&ReceiverArgs{Image: "receiver", Sink: sink, Labels: Labels(sink.Name)})
+
+	if d.Name != ReceiverName(sink) || d.Namespace != "ns" {
+		t.Errorf("receiver = %s/%s, want ns/%s", d.Namespace, d.Name, ReceiverName(sink))
+	}
+	if !metav1.IsControlledBy(d, sink) {
+		t.Error("the receiver is not controlled by the sink")
+	}
+	spec := d.Spec.Template.Spec
+	if spec.ServiceAccountName != "writer" {
+		t.Errorf("serviceAccountName = %q, want writer", spec.ServiceAccountName)
+	}
+	if got := spec.Volumes[0].Secret.SecretName; got != "couchdb" {
+		t.Errorf("credentials secret = %q, want couchdb", got)
+	}
+
+	env := map[string]string{}
+	for _, e := range spec.Containers[0].Env {
+		env[e.Name] = e.Value
+	}
+	want := map[string]string{
+		"PORT":                 "8080",
+		"K_LOGGING_CONFIG":     "",
+		"COUCHDB_CREDENTIALS":  "/etc/couchdb-credentials",
+		"COUCHDB_DATABASE":     "orders",
+		"COUCHDB_ID_ATTRIBUTE": "subject",
+		"COUCHDB_ID_PREFIX":    "orders:",
+		"COUCHDB_DOCUMENT":     "event",
+		"COUCHDB_ON_CONFLICT":  "overwrite",
+	}
+	if diff := cmp.Diff(want, env); diff != "" {
+		t.Error("Unexpected env (-want, +got) =", diff)
+	}
+}
+
+func TestMakeService(t *testing.T) {
+	sink := newSink()
+	labels := Labels(sink.Name)
+	svc := MakeService(&ReceiverArgs{Image: "receiver", Sink: sink, Labels: labels})
+
+	if svc.Name != ReceiverName(sink) {
+		t.Errorf("service = %s, want %s", svc.Name, ReceiverName(sink))
+	}
+	if !metav1.IsControlledBy(svc, sink) {
+		t.Error("the service is not controlled by the sink")
+	}
+	if diff := cmp.Diff(labels, svc.Spec.Selector); diff != "" {
+		t.Error("Unexpected selector (-want, +got) =", diff)
+	}
+	if got := svc.Spec.Ports[0].TargetPort; got != intstr.FromInt(ReceiverPort) {
+		t.Errorf("targetPort = %v, want %d", got, ReceiverPort)
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// kn-source-couchdb is the plugin of the kn CLI managing the CouchDbSources.
+// Installed in the PATH, or in the plugins directory of kn, it runs as
+// kn source couchdb.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"k8s.io/client-go/tools/clientcmd"
+
+	"knative.dev/eventing-couchdb/source/pkg/client/clientset/versioned"
+	clientv1alpha1 "knative.dev/eventing-couchdb/source/pkg/client/clientset/versioned/typed/sources/v1alpha1"
+	"knative.dev/eventing-couchdb/source/pkg/knplugin"
+)
+
+// client loads the kubeconfig the way kubectl and kn do.
+func client(kubeconfig string) (clientv1alpha1.SourcesV1alpha1Interface, string, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	config := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{})
+
+	namespace, _, err := config.Namespace()
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to read the namespace of the kubeconfig: %v", err)
+	}
+	cfg, err := config.ClientConfig()
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to load the kubeconfig: %v", err)
+	}
+	clientset, err := versioned.NewForConfig(cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	return clientset.SourcesV1alpha1(), namespace, nil
+}
+
+func main() {
+	plugin := &knplugin.Plugin{Out: os.Stdout, Client: client}
+	if err := plugin.Run(context.Background(), os.Args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"log"
+
+	"k8s.io/client-go/kubernetes"
+	"knative.dev/pkg/injection"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/signals"
+
+	"knative.dev/eventing-couchdb/source/pkg/client/clientset/versioned"
+	"knative.dev/eventing-couchdb/source/pkg/sandboxmigration"
+)
+
+func main() {
+	ctx := signals.NewContext()
+	cfg := injection.ParseAndGetRESTConfigOrDie()
+
+	logger, _ := logging.NewLogger("", "info")
+	defer logger.Sync()
+	ctx = logging.WithLogger(ctx, logger.Named("sandboxmigration"))
+
+	migrator := sandboxmigration.NewMigrator(
+		kubernetes.NewForConfigOrDie(cfg).CoreV1(),
+		versioned.NewForConfigOrDie(cfg),
+	)
+	if err := migrator.Migrate(ctx); err != nil {
+		log.Fatal("Migration from knative-sandbox failed: ", err)
+	}
+	logger.Info("Migration from knative-sandbox finished")
+}
@@ -17,31 +17,99 @@ package main
 
 import (
 	"context"
+	"log"
+	"os"
 
+	"github.com/rickb777/date/period"
+	"go.uber.org/zap"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
+	"knative.dev/pkg/injection"
 	"knative.dev/pkg/injection/sharedmain"
+	"knative.dev/pkg/logging"
 	"knative.dev/pkg/signals"
 	"knative.dev/pkg/webhook"
 	"knative.dev/pkg/webhook/certificates"
 	"knative.dev/pkg/webhook/resourcesemantics"
+	"knative.dev/pkg/webhook/resourcesemantics/conversion"
 	"knative.dev/pkg/webhook/resourcesemantics/defaulting"
 	"knative.dev/pkg/webhook/resourcesemantics/validation"
 
+	"knative.dev/eventing-couchdb/source/pkg/apis/config"
 	couchdbv1alpha1 "knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	couchdbv1beta1 "knative.dev/eventing-couchdb/source/pkg/apis/sources/v1beta1"
+	couchdbwebhook "knative.dev/eventing-couchdb/source/pkg/webhook"
 )
 
 var types = map[schema.GroupVersionKind]resourcesemantics.GenericCRD{
 	couchdbv1alpha1.SchemeGroupVersion.WithKind("CouchDbSource"): &couchdbv1alpha1.CouchDbSource{},
+	couchdbv1beta1.SchemeGroupVersion.WithKind("CouchDbSource"):  &couchdbv1beta1.CouchDbSource{},
 }
 
-var callbacks = map[schema.GroupVersionKind]validation.Callback{}
+// callbacks check what the types cannot check by themselves, like the
+// credentials Secret the sources reference.
+var callbacks = map[schema.GroupVersionKind]validation.Callback{
+	couchdbv1alpha1.SchemeGroupVersion.WithKind("CouchDbSource"): couchdbwebhook.NewCredentialsCallback(),
+	couchdbv1beta1.SchemeGroupVersion.WithKind("CouchDbSource"):  couchdbwebhook.NewCredentialsCallback(),
+}
+
+// installationEnvVar is the name of the environment variable holding the name
+// of the installation, when several are installed in the cluster. It prefixes
+// the names of the webhook configurations, whose objectSelector restricts
+// them to the sources of the installation.
+const installationEnvVar = "COUCHDB_INSTALLATION"
+
+// defaultHeartbeatEnvVar is the name of the environment variable holding the
+// ISO 8601 heartbeat given to the continuous feeds setting neither a heartbeat
+// nor a timeout. It matches the one of the controller.
+const defaultHeartbeatEnvVar = "COUCHDB_DEFAULT_HEARTBEAT"
+
+// configName returns the name of a webhook configuration of the installation.
+func configName(name string) string {
+	if installation := os.Getenv(installationEnvVar); installation != "" {
+		return installation + "." + name
+	}
+	return name
+}
+
+// newConfigStore returns the store of the configuration of the sources,
+// whose load balancers default to the ones of the infrastructure detected
+// from the nodes.
+func newConfigStore(ctx context.Context, cmw configmap.Watcher) *config.Store {
+	store := config.NewStore(logging.FromContext(ctx).Named("config-store"))
+	infrastructure, err := config.DetectInfrastructure(ctx, kubeclient.Get(ctx))
+	if err != nil {
+		logging.FromContext(ctx).Warnw("Unable to detect the infrastructure", zap.Error(err))
+	}
+	store.Infrastructure = infrastructure
+	store.WatchConfigs(cmw)
+	return store
+}
+
+// withDefaultHeartbeat returns the function infusing the contexts of the
+// defaulting with the default heartbeat of the controller, which the
+// continuous feeds get, and with the load balancers in front of CouchDB,
+// whose idle timeout their heartbeat is derived from otherwise.
+func withDefaultHeartbeat(ctx context.Context, cmw configmap.Watcher) func(context.Context) context.Context {
+	heartbeat := os.Getenv(defaultHeartbeatEnvVar)
+	if heartbeat != "" {
+		if _, err := period.Parse(heartbeat); err != nil {
+			logging.FromContext(ctx).Errorw("Ignoring the invalid default heartbeat", zap.String(defaultHeartbeatEnvVar, heartbeat), zap.Error(err))
+			heartbeat = ""
+		}
+	}
+	store := newConfigStore(ctx, cmw)
+	return func(ctx context.Context) context.Context {
+		return couchdbv1alpha1.WithDefaultHeartbeat(store.ToContext(ctx), heartbeat)
+	}
+}
 
 func NewDefaultingAdmissionController(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
 	return defaulting.NewAdmissionController(ctx,
 		// Name of the resource webhook.
-		"defaulting.webhook.couchdb.messaging.knative.dev",
+		configName("defaulting.webhook.couchdb.messaging.knative.dev"),
 
 		// The path on which to serve the webhook.
 		"/defaulting",
@@ -50,9 +118,7 @@ func NewDefaultingAdmissionController(ctx context.Context, cmw configmap.Watcher
 		types,
 
 		// A function that infuses the context passed to Validate/SetDefaults with custom metadata.
-		func(ctx context.Context) context.Context {
-			return ctx
-		},
+		withDefaultHeartbeat(ctx, cmw),
 
 		// Whether to disallow unknown fields.
 		true,
@@ -60,9 +126,14 @@ func NewDefaultingAdmissionController(ctx context.Context, cmw configmap.Watcher
 }
 
 func NewValidationAdmissionController(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
+	// Sources without a sink are only valid in the namespaces with a default
+	// sink, and their heartbeat must be below the idle timeout of the load
+	// balancers.
+	store := newConfigStore(ctx, cmw)
+
 	return validation.NewAdmissionController(ctx,
 		// Name of the resource webhook.
-		"validation.webhook.couchdb.messaging.knative.dev",
+		configName("validation.webhook.couchdb.messaging.knative.dev"),
 
 		// The path on which to serve the webhook.
 		"/validation",
@@ -71,9 +142,7 @@ func NewValidationAdmissionController(ctx context.Context, cmw configmap.Watcher
 		types,
 
 		// A function that infuses the context passed to Validate/SetDefaults with custom metadata.
-		func(ctx context.Context) context.Context {
-			return ctx
-		},
+		store.ToContext,
 
 		// Whether to disallow unknown fields.
 		true,
@@ -83,17 +152,54 @@ func NewValidationAdmissionController(ctx context.Context, cmw configmap.Watcher
 	)
 }
 
+// NewConversionController serves the conversion of the sources between
+// their API versions, through v1beta1, and sets its CA bundle on the CRD.
+func NewConversionController(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
+	return conversion.NewConversionController(ctx,
+		// The path on which to serve the webhook.
+		"/resource-conversion",
+
+		// Specify the types of custom resource definitions that should be converted.
+		map[schema.GroupKind]conversion.GroupKindConversion{
+			couchdbv1alpha1.Kind("CouchDbSource"): {
+				DefinitionName: couchdbv1alpha1.Resource("couchdbsources").String(),
+				HubVersion:     couchdbv1beta1.SchemeGroupVersion.Version,
+				Zygotes: map[string]conversion.ConvertibleObject{
+					couchdbv1alpha1.SchemeGroupVersion.Version: &couchdbv1alpha1.CouchDbSource{},
+					couchdbv1beta1.SchemeGroupVersion.Version:  &couchdbv1beta1.CouchDbSource{},
+				},
+			},
+		},
+
+		// A function that infuses the context passed to ConvertTo/ConvertFrom/SetDefaults with custom metadata.
+		withDefaultHeartbeat(ctx, cmw),
+	)
+}
+
 func main() {
+	// Record the reasons of the rejections along with the request latencies.
+	reporter, err := couchdbwebhook.NewStatsReporter()
+	if err != nil {
+		log.Fatal("Error creating the webhook stats reporter: ", err)
+	}
+
 	// Set up a signal context with our webhook options
 	ctx := webhook.WithOptions(signals.NewContext(), webhook.Options{
-		ServiceName: webhook.NameFromEnv(),
-		Port:        8443,
-		SecretName:  "eventing-webhook-certs",
+		ServiceName:   webhook.NameFromEnv(),
+		Port:          8443,
+		SecretName:    "eventing-webhook-certs",
+		StatsReporter: reporter,
 	})
 
-	sharedmain.WebhookMainWithContext(ctx, webhook.NameFromEnv(),
+	ctors := []injection.ControllerConstructor{
 		certificates.NewController,
 		NewDefaultingAdmissionController,
 		NewValidationAdmissionController,
-	)
+	}
+	// Only the default installation, which owns the CRD, converts the
+	// sources: the other ones share its CRD.
+	if os.Getenv(installationEnvVar) == "" {
+		ctors = append(ctors, NewConversionController)
+	}
+	sharedmain.WebhookMainWithContext(ctx, webhook.NameFromEnv(), ctors...)
 }
@@ -0,0 +1,93 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/webhook/resourcesemantics"
+
+	couchdbv1beta1 "knative.dev/eventing-couchdb/source/pkg/apis/sources/v1beta1"
+)
+
+// admit decodes the object as the admission webhooks do, then defaults and
+// validates it.
+func admit(t *testing.T, raw string) (resourcesemantics.GenericCRD, *apis.FieldError) {
+	t.Helper()
+	gvk := couchdbv1beta1.SchemeGroupVersion.WithKind("CouchDbSource")
+	handler, ok := types[gvk]
+	if !ok {
+		t.Fatalf("%v is not admitted", gvk)
+	}
+	if _, ok := callbacks[gvk]; !ok {
+		t.Errorf("%v has no credentials callback", gvk)
+	}
+
+	obj := handler.DeepCopyObject().(resourcesemantics.GenericCRD)
+	decoder := json.NewDecoder(bytes.NewBufferString(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&obj); err != nil {
+		t.Fatalf("Decode() = %v", err)
+	}
+	ctx := apis.WithinCreate(context.Background())
+	obj.SetDefaults(ctx)
+	return obj, obj.Validate(ctx)
+}
+
+func TestAdmitV1beta1(t *testing.T) {
+	obj, err := admit(t, `{
+		"apiVersion": "sources.knative.dev/v1beta1",
+		"kind": "CouchDbSource",
+		"metadata": {"namespace": "ns", "name": "orders"},
+		"spec": {
+			"credentials": {"secretRef": {"name": "couchdb"}},
+			"database": "orders",
+			"sink": {"uri": "http://sink"}
+		}
+	}`)
+	if err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+	if spec := obj.(*couchdbv1beta1.CouchDbSource).Spec; spec.Feed == "" || spec.Heartbeat == "" {
+		t.Errorf("spec = %+v, want the feed and heartbeat defaulted", spec)
+	}
+}
+
+func TestAdmitInvalidV1beta1(t *testing.T) {
+	_, err := admit(t, `{
+		"apiVersion": "sources.knative.dev/v1beta1",
+		"kind": "CouchDbSource",
+		"metadata": {"namespace": "ns", "name": "orders"},
+		"spec": {
+			"credentials": {"secretRef": {"name": "couchdb"}},
+			"database": "orders",
+			"feed": "longpoll",
+			"sink": {"uri": "http://sink"}
+		}
+	}`)
+	if err == nil {
+		t.Fatal("Validate() = nil, want the unknown feed rejected")
+	}
+	if got, want := err.Error(), "invalid value: longpoll: spec.feed"; !strings.Contains(got, want) {
+		t.Errorf("Validate() = %q, want it to contain %q", got, want)
+	}
+}
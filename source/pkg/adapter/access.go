@@ -0,0 +1,69 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+// access restricts the events to the documents allowing one of the roles of
+// the source in their roles field.
+type access struct {
+	field string
+	roles map[string]bool
+}
+
+func newAccess(env *envConfig) *access {
+	if len(env.AccessRoles) == 0 {
+		return nil
+	}
+	ac := &access{
+		field: env.AccessField,
+		roles: make(map[string]bool, len(env.AccessRoles)),
+	}
+	if ac.field == "" {
+		ac.field = v1alpha1.DefaultAccessField
+	}
+	for _, r := range env.AccessRoles {
+		ac.roles[r] = true
+	}
+	return ac
+}
+
+// allows returns whether the changed document allows one of the roles of the
+// source. Documents without roles, including the tombstones of deleted
+// documents that did not keep them, are only reported to the admin role.
+func (ac *access) allows(changes change) bool {
+	if ac == nil || ac.roles[v1alpha1.AdminRole] {
+		return true
+	}
+	var doc map[string]interface{}
+	if err := changes.ScanDoc(&doc); err != nil {
+		return false
+	}
+	switch v := doc[ac.field].(type) {
+	case string:
+		return ac.roles[v]
+	case []interface{}:
+		for _, r := range v {
+			if s, ok := r.(string); ok && ac.roles[s] {
+				return true
+			}
+		}
+	}
+	return false
+}
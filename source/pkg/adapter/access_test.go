@@ -0,0 +1,74 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"testing"
+)
+
+func TestAccessAllows(t *testing.T) {
+	testCases := map[string]struct {
+		env  envConfig
+		doc  string
+		want bool
+	}{
+		"no access": {
+			doc:  `{}`,
+			want: true,
+		},
+		"allowed role": {
+			env:  envConfig{AccessRoles: []string{"tenant-a"}},
+			doc:  `{"roles": ["tenant-b", "tenant-a"]}`,
+			want: true,
+		},
+		"other roles": {
+			env: envConfig{AccessRoles: []string{"tenant-a"}},
+			doc: `{"roles": ["tenant-b"]}`,
+		},
+		"single role": {
+			env:  envConfig{AccessRoles: []string{"tenant-a"}},
+			doc:  `{"roles": "tenant-a"}`,
+			want: true,
+		},
+		"without roles": {
+			env: envConfig{AccessRoles: []string{"tenant-a"}},
+			doc: `{"_id": "doc", "_deleted": true}`,
+		},
+		"custom field": {
+			env:  envConfig{AccessRoles: []string{"tenant-a"}, AccessField: "tenants"},
+			doc:  `{"roles": ["tenant-b"], "tenants": ["tenant-a"]}`,
+			want: true,
+		},
+		"admin": {
+			env:  envConfig{AccessRoles: []string{"_admin"}},
+			doc:  `{"_id": "doc"}`,
+			want: true,
+		},
+		"not an object": {
+			env: envConfig{AccessRoles: []string{"tenant-a"}},
+			doc: `null`,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			ac := newAccess(&tc.env)
+			if got := ac.allows(&backfillDoc{id: "doc", rev: "1-a", doc: []byte(tc.doc)}); got != tc.want {
+				t.Errorf("allows() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
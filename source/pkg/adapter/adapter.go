@@ -18,19 +18,21 @@ package adapter
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
-	"io/ioutil"
-	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	cloudevents "github.com/cloudevents/sdk-go/v2"
-	"github.com/go-kivik/couchdb/v3"
 	"github.com/go-kivik/kivik/v3"
+	"go.opencensus.io/trace"
 	"go.uber.org/zap"
-	"golang.org/x/net/http2"
-	"k8s.io/apimachinery/pkg/util/wait"
+	"golang.org/x/time/rate"
 	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	cdbevents "knative.dev/eventing-couchdb/source/pkg/events"
 	"knative.dev/eventing/pkg/adapter/v2"
 	"knative.dev/pkg/logging"
 )
@@ -38,31 +40,221 @@ import (
 type envConfig struct {
 	adapter.EnvConfig
 
-	CouchDbCredentialsPath string `envconfig:"COUCHDB_CREDENTIALS" required:"true"`
-	Database               string `envconfig:"COUCHDB_DATABASE" required:"true"`
-	EventSource            string `envconfig:"EVENT_SOURCE" required:"true"`
-	Feed                   string `envconfig:"COUCHDB_FEED" required:"true"`
+	CouchDbCredentialsPath string   `envconfig:"COUCHDB_CREDENTIALS" required:"true"`
+	LoggingDir             string   `envconfig:"COUCHDB_LOGGING_DIR"`
+	PprofPort              string   `envconfig:"COUCHDB_PPROF_PORT"`
+	Database               string   `envconfig:"COUCHDB_DATABASE" required:"true"`
+	DatabasePattern        string   `envconfig:"COUCHDB_DATABASE_PATTERN"`
+	Shards                 int      `envconfig:"COUCHDB_SHARDS"`
+	PodName                string   `envconfig:"COUCHDB_POD_NAME"`
+	EventSource            string   `envconfig:"EVENT_SOURCE" required:"true"`
+	Feed                   string   `envconfig:"COUCHDB_FEED" required:"true"`
+	Auth                   string   `envconfig:"COUCHDB_AUTH"`
+	CreateDatabase         bool     `envconfig:"COUCHDB_CREATE_DATABASE"`
+	ProxyURL               string   `envconfig:"COUCHDB_PROXY_URL"`
+	NoProxy                []string `envconfig:"COUCHDB_NO_PROXY"`
+	EventTypeTemplate      string   `envconfig:"COUCHDB_EVENT_TYPE_TEMPLATE"`
+	EventTypeField         string   `envconfig:"COUCHDB_EVENT_TYPE_FIELD"`
+	SubjectTemplate        string   `envconfig:"COUCHDB_SUBJECT_TEMPLATE"`
+	IDTemplate             string   `envconfig:"COUCHDB_ID_TEMPLATE"`
+	Partitions             []string `envconfig:"COUCHDB_PARTITIONS"`
+	Attachments            string   `envconfig:"COUCHDB_ATTACHMENTS"`
+	Payload                string   `envconfig:"COUCHDB_PAYLOAD"`
+	Projection             string   `envconfig:"COUCHDB_PROJECTION"`
+	Match                  string   `envconfig:"COUCHDB_MATCH"`
+	LeaseName              string   `envconfig:"COUCHDB_LEASE_NAME"`
+	DeletedDocs            string   `envconfig:"COUCHDB_DELETED_DOCS"`
+	DesignDocs             string   `envconfig:"COUCHDB_DESIGN_DOCS"`
+	OnDecodeError          string   `envconfig:"COUCHDB_ON_DECODE_ERROR"`
+	Conflicts              bool     `envconfig:"COUCHDB_CONFLICTS"`
+	DatabaseUpdates        bool     `envconfig:"COUCHDB_DATABASE_UPDATES"`
+	ContentMode            string   `envconfig:"COUCHDB_CONTENT_MODE"`
+	Ordering               string   `envconfig:"COUCHDB_ORDERING"`
+	Batch                  bool     `envconfig:"COUCHDB_BATCH"`
+	RateLimit              int      `envconfig:"COUCHDB_RATE_LIMIT"`
+	RateLimitBurst         int      `envconfig:"COUCHDB_RATE_LIMIT_BURST"`
+	BatchMaxCount          int      `envconfig:"COUCHDB_BATCH_MAX_COUNT"`
+	BatchMaxWait           string   `envconfig:"COUCHDB_BATCH_MAX_WAIT"`
+	MaxLineBytes           int64    `envconfig:"COUCHDB_MAX_LINE_BYTES"`
+	MaxJSONDepth           int      `envconfig:"COUCHDB_MAX_JSON_DEPTH"`
+	MaxResults             int      `envconfig:"COUCHDB_MAX_RESULTS"`
+	Backfill               bool     `envconfig:"COUCHDB_BACKFILL"`
+	BackfillBookmark       string   `envconfig:"COUCHDB_BACKFILL_BOOKMARK"`
+	CheckpointDoc          string   `envconfig:"COUCHDB_CHECKPOINT_DOC"`
+	StatusPort             string   `envconfig:"COUCHDB_STATUS_PORT"`
+	Stats                  bool     `envconfig:"COUCHDB_STATS"`
+	GroupField             string   `envconfig:"COUCHDB_GROUP_FIELD"`
+	GroupDelay             string   `envconfig:"COUCHDB_GROUP_DELAY"`
+	GroupBatch             bool     `envconfig:"COUCHDB_GROUP_BATCH"`
+	AccessRoles            []string `envconfig:"COUCHDB_ACCESS_ROLES"`
+	AccessField            string   `envconfig:"COUCHDB_ACCESS_FIELD"`
+	Heartbeat              string   `envconfig:"COUCHDB_HEARTBEAT"`
+	Polling                bool     `envconfig:"COUCHDB_POLLING"`
+	PollMinInterval        string   `envconfig:"COUCHDB_POLL_MIN_INTERVAL"`
+	PollMaxInterval        string   `envconfig:"COUCHDB_POLL_MAX_INTERVAL"`
+	ReconnectInitialDelay  string   `envconfig:"COUCHDB_RECONNECT_INITIAL_DELAY"`
+	ReconnectMaxDelay      string   `envconfig:"COUCHDB_RECONNECT_MAX_DELAY"`
+	Timeout                string   `envconfig:"COUCHDB_TIMEOUT"`
+	Since                  string   `envconfig:"COUCHDB_SINCE"`
+	ReplayFrom             string   `envconfig:"COUCHDB_REPLAY_FROM"`
+	SeqInterval            int32    `envconfig:"COUCHDB_SEQ_INTERVAL"`
+	WindowSince            string   `envconfig:"COUCHDB_WINDOW_SINCE"`
+	WindowUntil            string   `envconfig:"COUCHDB_WINDOW_UNTIL"`
+	HealthMetricsInterval  string   `envconfig:"COUCHDB_HEALTH_METRICS_INTERVAL"`
+	LagMetricsInterval     string   `envconfig:"COUCHDB_LAG_METRICS_INTERVAL"`
+	HealthPort             string   `envconfig:"COUCHDB_HEALTH_PORT"`
+	DisconnectedThreshold  string   `envconfig:"COUCHDB_DISCONNECTED_THRESHOLD"`
+	SourceUID              string   `envconfig:"COUCHDB_SOURCE_UID"`
+	JoinDatabase           string   `envconfig:"COUCHDB_JOIN_DATABASE"`
+	JoinKey                string   `envconfig:"COUCHDB_JOIN_KEY"`
+	JoinAs                 string   `envconfig:"COUCHDB_JOIN_AS"`
+	JoinCacheSize          int      `envconfig:"COUCHDB_JOIN_CACHE_SIZE"`
+	JoinMaxAge             string   `envconfig:"COUCHDB_JOIN_MAX_AGE"`
+	JoinOnMissing          string   `envconfig:"COUCHDB_JOIN_ON_MISSING"`
+	ReplySink              string   `envconfig:"COUCHDB_REPLY_SINK"`
+	BufferDir              string   `envconfig:"COUCHDB_BUFFER_DIR"`
+	BufferMaxBytes         int64    `envconfig:"COUCHDB_BUFFER_MAX_BYTES"`
+	BufferOnFull           string   `envconfig:"COUCHDB_BUFFER_ON_FULL"`
+	SinkToken              string   `envconfig:"COUCHDB_SINK_TOKEN"`
+	SinkCACerts            string   `envconfig:"COUCHDB_SINK_CA_CERTS"`
+
+	DeliveryRetry         int    `envconfig:"DELIVERY_RETRY"`
+	DeliveryBackoffPolicy string `envconfig:"DELIVERY_BACKOFF_POLICY"`
+	DeliveryBackoffDelay  string `envconfig:"DELIVERY_BACKOFF_DELAY"`
+	DeadLetterSink        string `envconfig:"DELIVERY_DEAD_LETTER_SINK"`
 }
 
 type couchDbAdapter struct {
 	namespace string
+	name      string
 	ce        cloudevents.Client
 	logger    *zap.SugaredLogger
 
-	source  string
-	feed    string
-	couchDB *kivik.DB
-	options kivik.Options
-}
+	source    string
+	feed      string
+	couchDB   *kivik.DB
+	database  string
+	options   kivik.Options
+	delivery  *deliveryConfig
+	eventType *v1alpha1.EventTypeTemplate
+	subject   *v1alpha1.SubjectTemplate
+	eventIDs  *v1alpha1.EventIDTemplate
 
-func init() {
-	// Need to disable compression for Cloudant.
-	var transport = http2.Transport{
-		DisableCompression: true,
-	}
-	kivik.Register("cloudant", &couchdb.Couch{
-		HTTPClient: &http.Client{Transport: &transport},
-	})
+	// eventTypeField, when set, is the document field holding the type of
+	// the update events.
+	eventTypeField string
+
+	// partitions, when set, restricts the events to the documents of these
+	// partitions of a partitioned database.
+	partitions []string
+
+	// deletedDocs selects which changes of deleted documents are reported.
+	deletedDocs v1alpha1.DeletedDocsPolicy
+
+	// designDocs selects which changes of design documents are reported.
+	designDocs v1alpha1.DesignDocsPolicy
+
+	// decodeErrors handles the changes that cannot be turned into events.
+	decodeErrors *decodeErrors
+
+	// conflicts reports the documents with conflicting revisions.
+	conflicts bool
+
+	// dbUpdates, when set, is the client of the server whose _db_updates
+	// feed is reported.
+	dbUpdates *kivik.Client
+
+	// structured sends the events in the structured content mode.
+	structured bool
+
+	// attachments, when set, makes the events carry the changed documents.
+	attachments  string
+	documentsURL string
+
+	// payload selects the data of the update and delete events.
+	payload v1alpha1.Payload
+
+	// projection, when set, renders the data of the update and delete events
+	// from the changed documents.
+	projection *v1alpha1.DataProjection
+
+	// window, when set, bounds the reported changes.
+	window *window
+
+	// grouper, when set, gathers the changes of related documents.
+	grouper *grouper
+
+	// joiner, when set, adds the documents of a secondary database to the
+	// changed documents.
+	joiner *joiner
+
+	// buffer, when set, keeps the events the sink does not accept on the
+	// volume of spec.buffer until it does.
+	buffer *buffer
+
+	// match, when set, restricts the events to the documents satisfying it.
+	match *v1alpha1.MatchExpression
+
+	// access, when set, restricts the events to the documents allowing the
+	// roles of the source.
+	access *access
+
+	// backfill, when set, reports the existing documents before the changes.
+	backfill *backfill
+
+	// statusPort, when set, is the port the adapter serves its status on.
+	statusPort string
+
+	// stats, when set, counts the delivered events.
+	stats *deliveryStats
+
+	// checkpoint tracks the sequence up to which the changes were delivered.
+	checkpoint *checkpoint
+
+	// rejections, when set, counts the deliveries of the events the sink
+	// rejected for good, which are skipped after a few without a dead
+	// letter sink.
+	rejections *rejections
+
+	// checkpointDoc, when set, saves the checkpoint in the database.
+	checkpointDoc *checkpointDoc
+
+	// backpressure holds the deliveries while the sink asks to.
+	backpressure *backpressure
+
+	// sequencer, when set, delivers the changes in the order of the feed.
+	sequencer *sequencer
+
+	// lease, when set, makes the adapter only read and deliver the changes
+	// while it holds the lease of the source.
+	lease *lease
+
+	// limiter, when set, limits the rate of the events sent to the sink.
+	limiter *rate.Limiter
+
+	// poller, when set, adapts the interval between the polls of the normal
+	// feed.
+	poller *poller
+
+	// reconnect spaces out the reopenings of the changes feed after it
+	// failed.
+	reconnect *reconnectBackoff
+
+	// batcher, when set, delivers the events in the CloudEvents batch format.
+	batcher *batcher
+
+	// health, when set, scrapes the health of the CouchDB server.
+	health *healthScraper
+
+	// lagMonitor, when set, exports the lag of the source.
+	lagMonitor *lagMonitor
+
+	// liveness, when set, serves the health endpoints.
+	liveness *liveness
+
+	// events, when set, records the failures as Kubernetes Events on the
+	// source.
+	events *failureEvents
 }
 
 // NewEnvConfig creates an empty configuration
@@ -70,54 +262,326 @@ func NewEnvConfig() adapter.EnvConfigAccessor {
 	return &envConfig{}
 }
 
+// GetLogger implements adapter.EnvConfigAccessor, with a level following the
+// config-logging mounted in LoggingDir.
+func (e *envConfig) GetLogger() *zap.SugaredLogger {
+	return processLogger.get(e.LoggingDir, e.LoggingConfigJson, e.Component)
+}
+
 // NewAdapter creates an adapter to convert incoming CouchDb changes events to CloudEvents and
 // then sends them to the specified Sink
 func NewAdapter(ctx context.Context, processed adapter.EnvConfigAccessor, ceClient cloudevents.Client) adapter.Adapter {
 	logger := logging.FromContext(ctx)
 	env := processed.(*envConfig)
 
-	rawurl, err := ioutil.ReadFile(env.CouchDbCredentialsPath + "/url")
+	url, err := readCredentialsURL(env.CouchDbCredentialsPath)
 	if err != nil {
 		logger.Fatal("Missing url key in secret", zap.Error(err))
 	}
-	url := string(rawurl)
 
-	driver := "couch"
-
-	// Use cloudant driver only when the server is Cloudant.
-	if strings.Contains(url, "cloudant") {
-		driver = "cloudant"
+	if env.ProxyURL != "" {
+		if err := setProxy(env.ProxyURL, env.NoProxy); err != nil {
+			logger.Fatal("Invalid proxy url", zap.Error(err))
+		}
+	}
+	setLimits(env.MaxLineBytes, env.MaxJSONDepth)
+	go processLogger.watch(ctx, env.LoggingDir, env.Component)
+	servePprof(ctx, logger, env.PprofPort)
+	if env.SourceUID != "" {
+		ctx = withInClusterEventRecorder(ctx, env.Component)
+	}
+	if env.DatabasePattern != "" {
+		sharded, err := newShardedAdapter(ctx, env, ceClient)
+		if err != nil {
+			logger.Fatal("Error creating the adapter", zap.Error(err))
+		}
+		return sharded
 	}
 
-	return newAdapter(ctx, env, ceClient, url, driver)
+	// The adapter is replaced when the credentials are rotated.
+	return &reloadingAdapter{
+		logger:   logger,
+		adapter:  newAdapter(ctx, env, ceClient, url, serverDriver(ctx, logger, url)).(*couchDbAdapter),
+		url:      url,
+		interval: credentialsPollInterval,
+		readURL: func(context.Context) (string, error) {
+			return readCredentialsURL(env.CouchDbCredentialsPath)
+		},
+		build: func(url string) (*couchDbAdapter, error) {
+			return buildAdapter(ctx, env, ceClient, url, serverDriver(ctx, logger, url))
+		},
+	}
 }
 
 func newAdapter(ctx context.Context, env *envConfig, ceClient cloudevents.Client, url string, driver string) adapter.Adapter {
+	a, err := buildAdapter(ctx, env, ceClient, url, driver)
+	if err != nil {
+		logging.FromContext(ctx).Fatal("Error creating the adapter", zap.Error(err))
+	}
+	return a
+}
+
+// buildAdapter connects to the database and configures the adapter, without
+// exiting on errors, so that the multi-tenant adapter can retry a source
+// without stopping the others.
+func buildAdapter(ctx context.Context, env *envConfig, ceClient cloudevents.Client, url string, driver string) (*couchDbAdapter, error) {
 	logger := logging.FromContext(ctx)
 
-	client, err := kivik.New(driver, url)
+	// The health is scraped with the credentials of the url, before the
+	// authenticator strips them.
+	health, err := newHealthScraper(env, url, driver)
+	if err != nil {
+		return nil, fmt.Errorf("error configuring the health metrics: %w", err)
+	}
+
+	client, url, err := connect(ctx, env, url, driver)
 	if err != nil {
-		logger.Fatal("Error creating connection to couchDB", zap.Error(err))
+		return nil, err
+	}
+
+	if env.CreateDatabase {
+		exists, err := client.DBExists(ctx, env.Database)
+		if err != nil {
+			return nil, fmt.Errorf("error checking couchDB database %q: %w", env.Database, err)
+		}
+		if !exists {
+			if err := client.CreateDB(ctx, env.Database); err != nil {
+				return nil, fmt.Errorf("error creating couchDB database %q: %w", env.Database, err)
+			}
+		}
 	}
 
 	db := client.DB(context.TODO(), env.Database)
 	if db.Err() != nil {
-		logger.Fatal("Error connection to couchDB database", zap.Any("dabase", env.Database), zap.Error(err))
+		return nil, fmt.Errorf("error connecting to couchDB database %q: %w", env.Database, db.Err())
+	}
+	j, err := newJoiner(ctx, client, env)
+	if err != nil {
+		return nil, fmt.Errorf("invalid join: %w", err)
+	}
+	lag, err := newLagMonitor(env)
+	if err != nil {
+		return nil, fmt.Errorf("invalid lag metrics interval: %w", err)
+	}
+	live, err := newLiveness(env)
+	if err != nil {
+		return nil, fmt.Errorf("invalid disconnected threshold: %w", err)
+	}
+	buf, err := newBuffer(env)
+	if err != nil {
+		return nil, fmt.Errorf("invalid buffer: %w", err)
+	}
+
+	delivery, err := newDeliveryConfig(env)
+	if err != nil {
+		return nil, fmt.Errorf("invalid delivery configuration: %w", err)
+	}
+	if env.SinkToken != "" {
+		// The sink authenticates the events with the OIDC token of its
+		// audience.
+		if err := sinkTokens.set(delivery.sink, env.SinkToken); err != nil {
+			return nil, fmt.Errorf("invalid sink token: %w", err)
+		}
+	}
+	if env.SinkCACerts != "" {
+		if err := setSinkCACerts(env.SinkCACerts); err != nil {
+			return nil, fmt.Errorf("invalid sink CA certificates: %w", err)
+		}
+	}
+
+	eventType, err := v1alpha1.ParseEventTypeTemplate(env.EventTypeTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid event type template: %w", err)
+	}
+
+	subject, err := v1alpha1.ParseSubjectTemplate(env.SubjectTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subject template: %w", err)
+	}
+
+	eventIDs, err := v1alpha1.ParseEventIDTemplate(env.IDTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid event ID template: %w", err)
+	}
+
+	docsURL, err := documentsURL(url, env.Database)
+	if err != nil {
+		return nil, fmt.Errorf("invalid couchDB url: %w", err)
+	}
+
+	w, err := newWindow(ctx, db, env.WindowUntil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid window: %w", err)
+	}
+
+	since := "0"
+	if env.Since != "" {
+		since = env.Since
+	}
+	if env.WindowSince != "" {
+		since = env.WindowSince
+	}
+	if env.ReplayFrom != "" {
+		logger.Infow("Replaying the changes", zap.String("since", env.ReplayFrom))
+		since = env.ReplayFrom
+	}
+	options := kivik.Options{
+		"feed":  env.Feed,
+		"since": since,
+	}
+	if env.Attachments != "" || env.Projection != "" || env.Match != "" || env.EventTypeField != "" || env.GroupField != "" || env.Conflicts || len(env.AccessRoles) > 0 || env.JoinDatabase != "" {
+		options["include_docs"] = true
+	}
+	if env.Conflicts {
+		options["conflicts"] = true
+	}
+	timing, err := feedTiming(env)
+	if err != nil {
+		return nil, fmt.Errorf("invalid feed timing: %w", err)
+	}
+	for k, v := range timing {
+		options[k] = v
+	}
+	if env.SeqInterval > 0 {
+		options["seq_interval"] = env.SeqInterval
+	}
+	dialectFor(driver).changesOptions(env, options)
+	if env.MaxResults > 0 {
+		// The following changes are requested by the next poll.
+		options["limit"] = env.MaxResults
+	}
+
+	g, err := newGrouper(env)
+	if err != nil {
+		return nil, fmt.Errorf("invalid grouping: %w", err)
+	}
+	p, err := newPoller(env)
+	if err != nil {
+		return nil, fmt.Errorf("invalid polling: %w", err)
+	}
+	reconnect, err := newReconnectBackoff(env)
+	if err != nil {
+		return nil, fmt.Errorf("invalid reconnect: %w", err)
+	}
+	b, err := newBatcher(env)
+	if err != nil {
+		return nil, fmt.Errorf("invalid batching: %w", err)
+	}
+	if v1alpha1.AttachmentsPolicy(env.Attachments) == v1alpha1.AttachmentsInline {
+		options["attachments"] = true
+	}
+
+	match, err := v1alpha1.ParseMatch(env.Match)
+	if err != nil {
+		return nil, fmt.Errorf("invalid match expression: %w", err)
+	}
+	projection, err := parseProjection(env.Projection)
+	if err != nil {
+		return nil, fmt.Errorf("invalid projection: %w", err)
+	}
+
+	decodeErrors, err := newDecodeErrors(env)
+	if err != nil {
+		return nil, fmt.Errorf("invalid onDecodeError: %w", err)
+	}
+
+	l, err := newLease(env, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error configuring the lease: %w", err)
+	}
+
+	var bf *backfill
+	if env.Backfill && env.ReplayFrom == "" {
+		bf = &backfill{bookmarkID: env.BackfillBookmark}
+	}
+	var dbUpdates *kivik.Client
+	if env.DatabaseUpdates {
+		dbUpdates = client
+	}
+	var stats *deliveryStats
+	if env.Stats {
+		stats = newDeliveryStats(time.Now())
 	}
 
 	return &couchDbAdapter{
 		namespace: env.Namespace,
+		name:      env.Name,
 		ce:        ceClient,
 		logger:    logger,
 
-		couchDB: db,
-		source:  env.EventSource,
-		feed:    env.Feed,
-		options: map[string]interface{}{
-			"feed":  env.Feed,
-			"since": "0",
-		},
+		couchDB:   db,
+		database:  env.Database,
+		source:    env.EventSource,
+		feed:      env.Feed,
+		options:   options,
+		delivery:  delivery,
+		eventType: eventType,
+		subject:   subject,
+		eventIDs:  eventIDs,
+
+		eventTypeField: env.EventTypeField,
+
+		partitions:   env.Partitions,
+		deletedDocs:  v1alpha1.DeletedDocsPolicy(env.DeletedDocs),
+		designDocs:   v1alpha1.DesignDocsPolicy(env.DesignDocs),
+		decodeErrors: decodeErrors,
+		conflicts:    env.Conflicts,
+		dbUpdates:    dbUpdates,
+		structured:   v1alpha1.ContentMode(env.ContentMode) == v1alpha1.ContentModeStructured,
+		attachments:  env.Attachments,
+		documentsURL: docsURL,
+		payload:      v1alpha1.Payload(env.Payload),
+		projection:   projection,
+		window:       w,
+		grouper:      g,
+		joiner:       j,
+		buffer:       buf,
+		match:        match,
+		access:       newAccess(env),
+		batcher:      b,
+		health:       health,
+		lagMonitor:   lag,
+		liveness:     live,
+		events:       newFailureEvents(ctx, env),
+		backfill:     bf,
+		statusPort:   env.StatusPort,
+		stats:        stats,
+		poller:       p,
+		reconnect:    reconnect,
+		limiter:      newLimiter(env),
+		backpressure: sinkBackpressure,
+		sequencer:    newSequencer(env),
+		lease:        l,
+		checkpoint:   newCheckpoint(since),
+		rejections:   newRejections(),
+
+		checkpointDoc: newCheckpointDoc(env),
+	}, nil
+}
+
+// connect returns a client of the CouchDB server authenticated as the source
+// configures, along with the url left once the authenticator took the
+// credentials out of it.
+func connect(ctx context.Context, env *envConfig, url string, driver string) (*kivik.Client, string, error) {
+	authenticator, url, err := makeAuthenticator(env.Auth, url)
+	if err != nil {
+		return nil, "", fmt.Errorf("error configuring couchDB authentication: %w", err)
+	}
+	if s, ok := authenticator.(*sessionAuth); ok {
+		s.timeout = dialectFor(driver).sessionTimeout()
+	}
+
+	client, err := kivik.New(driver, url)
+	if err != nil {
+		return nil, "", fmt.Errorf("error creating connection to couchDB: %w", err)
+	}
+
+	if authenticator != nil {
+		if err := client.Authenticate(ctx, authenticator); err != nil {
+			return nil, "", fmt.Errorf("error authenticating with couchDB: %w", err)
+		}
 	}
+	return client, url, nil
 }
 
 func (a *couchDbAdapter) Start(ctx context.Context) error {
@@ -125,60 +589,489 @@ func (a *couchDbAdapter) Start(ctx context.Context) error {
 }
 
 func (a *couchDbAdapter) start(stopCh <-chan struct{}) error {
-	period := 2 * time.Second
-	if a.feed == "continuous" {
-		a.options["heartbeat"] = 6000
+	ctx, cancel := context.WithCancel(context.Background())
+	// The servers release their ports before the adapter returns, for the
+	// adapter replacing it on a credentials rotation to listen on them.
+	var servers sync.WaitGroup
+	defer servers.Wait()
+	defer cancel()
+	go func() {
+		select {
+		case <-stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	if a.statusPort != "" {
+		a.serveStatus(ctx, a.statusPort, &servers)
+	}
+	if a.liveness != nil {
+		a.liveness.serve(ctx, a.logger, &servers)
+	}
+	if a.health != nil {
+		go a.runHealthScraper(ctx)
+	}
+	if a.lagMonitor != nil {
+		go a.runLagMonitor(ctx)
 	}
-	wait.Until(a.processChanges, period, stopCh)
+	if a.buffer != nil {
+		go a.drainBuffer(ctx)
+	}
+	if a.lease != nil {
+		a.lease.run(ctx, func(ctx context.Context) {
+			a.process(ctx, cancel)
+		})
+		return nil
+	}
+	a.process(ctx, cancel)
 	return nil
 }
 
-func (a *couchDbAdapter) processChanges() {
-	changes, err := a.couchDB.Changes(context.TODO(), a.options)
+// flushTimeout bounds the delivery of the groups and batches still gathered
+// once the adapter stops, when its own context is done already.
+const flushTimeout = 10 * time.Second
+
+// process reads and delivers the changes until ctx is done, or cancels it
+// once the window is exhausted.
+func (a *couchDbAdapter) process(ctx context.Context, cancel context.CancelFunc) {
+	a.resumeCheckpoint(ctx)
+	a.resolveNow(ctx)
+	if a.dbUpdates != nil {
+		go a.runDatabaseUpdates(ctx)
+	}
+	interval := defaultPollInterval
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+			continue
+		case <-timer.C:
+		}
+
+		if a.backfill != nil && !a.backfill.completed() {
+			if err := a.runBackfill(ctx); err != nil {
+				a.logger.Error("Error backfilling the existing documents", zap.Error(err))
+			}
+			timer.Reset(interval)
+			continue
+		}
+		read, retry := a.processChanges(ctx)
+		if a.window != nil && a.window.exhausted {
+			// Sources bounded by a window run as Jobs, which complete
+			// when the adapter returns.
+			cancel()
+		}
+		if a.poller != nil {
+			interval = a.poller.next(read > 0)
+		}
+		a.saveCheckpoint(ctx, time.Now(), false)
+		if retry > 0 {
+			timer.Reset(retry)
+		} else {
+			timer.Reset(interval)
+		}
+	}
+
+	// Do not lose the groups and batches being gathered.
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancelFlush()
+	a.flushGroups(flushCtx)
+	a.flushBatches(flushCtx)
+	a.saveCheckpoint(flushCtx, time.Now(), true)
+}
+
+// resolveNow replaces a "now" starting sequence by the current update
+// sequence of the database, so that the feed can be read again from it after
+// a failed delivery.
+func (a *couchDbAdapter) resolveNow(ctx context.Context) {
+	if a.options["since"] != v1alpha1.SequenceNow {
+		return
+	}
+	stats, err := a.couchDB.Stats(ctx)
 	if err != nil {
+		a.logger.Warnw("Unable to read the update sequence of the database, the changes made before the first delivery may be lost if it fails", zap.Error(err))
+		return
+	}
+	a.options["since"] = stats.UpdateSeq
+	a.checkpoint.restart(stats.UpdateSeq)
+}
+
+// rewind reads the changes feed again from the checkpoint after a failed
+// delivery.
+func (a *couchDbAdapter) rewind() bool {
+	since, ok := a.checkpoint.rewind()
+	if !ok {
+		return false
+	}
+	a.logger.Warnw("An event could not be delivered, reading the changes again", zap.String("since", since))
+	a.options["since"] = since
+	return true
+}
+
+// processChanges reports the changes of a response of the changes feed. It
+// returns how many changes it read and, when the feed failed, the delay
+// before reopening it. Cancelling ctx closes the feed, so that a continuous
+// feed without changes does not hold up the shutdown of the adapter.
+func (a *couchDbAdapter) processChanges(ctx context.Context) (read int, retry time.Duration) {
+	if a.window != nil && a.window.exhausted {
+		return
+	}
+	// The batches and groups may have failed since the last response.
+	a.rewind()
+
+	changes, err := a.couchDB.Changes(ctx, a.options)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
 		a.logger.Error("Error getting the list of changes", zap.Error(err))
+		retry = a.reconnectAfter("connect", 0)
+		a.liveness.failed(time.Now())
+		a.events.feedFailed(err)
 		return
 	}
+	opened := time.Now()
+	a.liveness.connected()
+	a.events.feedConnected()
 
 	for changes.Next() {
-		if changes.Seq() != "" {
-			event, err := a.makeEvent(changes)
+		seq := sequence(changes)
+		if seq == "" && changes.ID() == "" {
+			// The last_seq line of a continuous feed.
+			continue
+		}
+		read++
+		if seq != "" && a.window.after(seq) {
+			a.exhaustWindow(changes)
+			return
+		}
 
-			if err != nil {
-				a.logger.Error("error making event", zap.Error(err))
+		ctx, span := a.startChangeSpan(ctx, changes, seq)
+		c, reports := a.join(ctx, changes)
+		reports = reports && a.reports(c)
+		a.checkpoint.read(a.eventID(c), seq, reports)
+		if reports {
+			// A failure rewinds the feed to the change.
+			_ = a.emit(ctx, c)
+		}
+		endChangeSpan(span, reports)
+		if a.rewind() {
+			if err := changes.Close(); err != nil {
+				a.logger.Warn("Error closing the changes feed", zap.Error(err))
 			}
+			return
+		}
 
-			if err := a.ce.Send(context.TODO(), *event); err != nil {
-				a.logger.Error("event delivery failed", zap.Error(err))
-			}
+		// With a seq_interval, only some changes carry their sequence.
+		if seq != "" {
+			a.options["since"] = seq
 
-			a.options["since"] = changes.Seq()
+			if a.window.reached(seq) {
+				a.exhaustWindow(changes)
+				return
+			}
 		}
 	}
 
+	lastSeq := a.lastSeq(changes)
+	if a.window.reached(lastSeq) {
+		a.exhaustWindow(changes)
+		return
+	}
+
+	if lastSeq != "" && changes.Err() == nil {
+		// Resume after the end of the response, rather than from "now" again.
+		a.options["since"] = lastSeq
+		a.checkpoint.read("", lastSeq, false)
+	}
+
+	if changes.Err() != nil && ctx.Err() != nil {
+		// The adapter is stopping, e.g. for a credentials rotation.
+		return
+	}
 	if changes.Err() != nil {
-		if changes.Err() == io.EOF {
+		var limitErr *limitError
+		if errors.As(changes.Err(), &limitErr) {
+			a.reportLimitExceeded(limitErr.Limit)
+			retry = a.reconnectAfter("limit", time.Since(opened))
+			a.logger.Errorw("The changes feed response was rejected",
+				zap.String("limit", limitErr.Limit), zap.Int64("max", limitErr.Max), zap.Any("since", a.options["since"]))
+		} else if changes.Err() == io.EOF {
+			retry = a.reconnectAfter("interrupted", time.Since(opened))
+			a.liveness.failed(time.Now())
+			a.events.feedFailed(changes.Err())
 			a.logger.Error("The connection to the changes feed was interrupted.", zap.Error(changes.Err()))
 		} else {
+			retry = a.reconnectAfter("error", time.Since(opened))
+			a.liveness.failed(time.Now())
+			a.events.feedFailed(changes.Err())
 			a.logger.Error("Error found in the changes feed.", zap.Error(changes.Err()))
 		}
+		return
+	}
+	a.reconnect.succeeded()
+	return
+}
+
+// reconnectAfter returns the delay before reopening the changes feed, which
+// failed for the reason after staying open for the given duration.
+func (a *couchDbAdapter) reconnectAfter(reason string, open time.Duration) time.Duration {
+	delay := a.reconnect.failed(open)
+	a.reportFeedReconnect(reason, delay)
+	a.logger.Infow("Reopening the changes feed", zap.String("reason", reason), zap.Duration("delay", delay))
+	return delay
+}
+
+// lastSeq returns the last sequence of a response of the normal feed. The
+// continuous feed sends it as its last line instead, and the CouchDB driver
+// panics when asked for it.
+func (a *couchDbAdapter) lastSeq(changes *kivik.Changes) string {
+	if feed, _ := a.options["feed"].(string); feed == string(v1alpha1.FeedContinuous) {
+		return ""
 	}
+	return changes.LastSeq()
+}
+
+// change is a change of the feed, or an existing document while backfilling.
+// It is implemented by *kivik.Changes.
+type change interface {
+	ID() string
+	Seq() string
+	Changes() []string
+	Deleted() bool
+	ScanDoc(dest interface{}) error
+}
+
+// reports returns whether the change is reported to the sink.
+func (a *couchDbAdapter) reports(changes change) bool {
+	return a.inPartitions(changes.ID()) &&
+		a.designDocs.Reports(changes.ID()) &&
+		a.deletedDocs.Reports(changes.Deleted()) &&
+		a.matches(changes) &&
+		a.access.allows(changes)
 }
 
-func (a *couchDbAdapter) makeEvent(changes *kivik.Changes) (*cloudevents.Event, error) {
+// emit sends the event of a change, or queues it in its group. It returns an
+// error when the change could not be turned into an event and must be read
+// again, as spec.onDecodeError says.
+func (a *couchDbAdapter) emit(ctx context.Context, changes change) error {
+	_, span := trace.StartSpan(ctx, "couchdb.transform")
+	event, err := a.makeEvent(ctx, changes)
+	setSpanError(ctx, err)
+	span.End()
+	if err != nil {
+		return a.decodeFailed(changes, err)
+	}
+	if a.grouper != nil {
+		if key := a.grouper.key(changes); key != "" {
+			a.grouper.add(key, *event, func(key string) { a.flushGroup(ctx, key) })
+			return nil
+		}
+	}
+	a.deliver(ctx, *event)
+	return nil
+}
+
+// exhaustWindow stops the processing of changes once the end of the window
+// is reached.
+func (a *couchDbAdapter) exhaustWindow(changes *kivik.Changes) {
+	a.window.exhausted = true
+	a.logger.Infow("The window of changes is exhausted, no more events will be sent", zap.Any("since", a.options["since"]))
+	if err := changes.Close(); err != nil {
+		a.logger.Warn("Error closing the changes feed", zap.Error(err))
+	}
+}
+
+// documentEventType returns the event type held by the spec.eventTypeField
+// field of the changed document, or an empty string to fall back to the
+// type of the change.
+func (a *couchDbAdapter) documentEventType(changes change) string {
+	if a.eventTypeField == "" {
+		return ""
+	}
+	var doc map[string]interface{}
+	if err := changes.ScanDoc(&doc); err != nil {
+		a.logger.Warnw("Unable to read the document event type", zap.String("id", changes.ID()), zap.Error(err))
+		return ""
+	}
+	t, _ := doc[a.eventTypeField].(string)
+	return t
+}
+
+// matches returns whether the changed document satisfies spec.match.
+func (a *couchDbAdapter) matches(changes change) bool {
+	if a.match == nil {
+		return true
+	}
+	var doc map[string]interface{}
+	if err := changes.ScanDoc(&doc); err != nil {
+		a.logger.Warnw("Unable to read the document to match", zap.String("id", changes.ID()), zap.Error(err))
+		return false
+	}
+	return a.match.Matches(doc)
+}
+
+// inPartitions returns whether the document belongs to one of the partitions
+// the adapter is scoped to. Partitioned databases prefix document IDs with
+// their partition followed by a colon. CouchDB has no per partition changes
+// feed, so the changes of the other partitions are read and skipped.
+func (a *couchDbAdapter) inPartitions(id string) bool {
+	if len(a.partitions) == 0 {
+		return true
+	}
+	for _, p := range a.partitions {
+		if strings.HasPrefix(id, p+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// sequence returns the update sequence of the change, or an empty string when
+// CouchDB did not compute it, as with a seq_interval.
+func sequence(changes change) string {
+	if seq := changes.Seq(); seq != "null" {
+		return seq
+	}
+	return ""
+}
+
+// changeData returns the data the ID and subject templates are evaluated
+// against.
+func (a *couchDbAdapter) changeData(changes change) v1alpha1.SubjectData {
+	return v1alpha1.SubjectData{
+		ID:       changes.ID(),
+		Rev:      firstRev(changes.Changes()),
+		Database: a.database,
+	}
+}
+
+// eventID returns the ID of the event of the change. It identifies the
+// revision of the document rather than the position of the change in the
+// feed, so it stays the same when the change is delivered again, e.g. after
+// a restart, from another CouchDB node, or by the backfill.
+func (a *couchDbAdapter) eventID(changes change) string {
+	data := a.changeData(changes)
+	id, err := a.eventIDs.Render(data)
+	if err != nil {
+		a.logger.Warnw("Error rendering the event ID, using the default one", zap.String("id", changes.ID()), zap.Error(err))
+		return v1alpha1.DefaultEventID(data)
+	}
+	return id
+}
+
+func (a *couchDbAdapter) makeEvent(ctx context.Context, changes change) (*cloudevents.Event, error) {
 	event := cloudevents.NewEvent(cloudevents.VersionV1)
-	event.SetID(changes.Seq())
+	event.SetID(a.eventID(changes))
 	event.SetSource(a.source)
-	event.SetSubject(changes.ID())
 
+	subject, err := a.subject.Render(a.changeData(changes))
+	if err != nil {
+		return nil, err
+	}
+	event.SetSubject(subject)
+
+	changeType := v1alpha1.ChangeTypeUpdate
+	var conflicting []string
 	if changes.Deleted() {
-		event.SetType(v1alpha1.CouchDbSourceDeleteEventType)
-	} else {
-		event.SetType(v1alpha1.CouchDbSourceUpdateEventType)
+		changeType = v1alpha1.ChangeTypeDelete
+	} else if a.conflicts {
+		if conflicting = conflicts(changes); len(conflicting) > 0 {
+			changeType = v1alpha1.ChangeTypeConflict
+		}
+	}
+	eventType, err := a.eventType.Render(v1alpha1.EventTypeData{ChangeType: changeType, Database: a.database})
+	if err != nil {
+		return nil, err
+	}
+	if changeType == v1alpha1.ChangeTypeUpdate {
+		if t := a.documentEventType(changes); t != "" {
+			eventType = t
+		}
+	}
+	event.SetType(eventType)
+	cdbevents.SetPosition(&event, cdbevents.Position{
+		Database: a.database,
+		Sequence: changes.Seq(),
+		Rev:      firstRev(changes.Changes()),
+		Deleted:  changes.Deleted(),
+	})
+
+	if a.attachments != "" {
+		doc, err := a.documentData(changes)
+		if err != nil {
+			return nil, err
+		}
+		if err := event.SetData(cloudevents.ApplicationJSON, doc); err != nil {
+			return nil, err
+		}
+		return &event, nil
+	}
+
+	if len(conflicting) > 0 {
+		data := cdbevents.ConflictData{Rev: firstRev(changes.Changes()), Conflicts: conflicting}
+		if err := event.SetData(cloudevents.ApplicationJSON, data); err != nil {
+			return nil, err
+		}
+		return &event, nil
 	}
 
-	if err := event.SetData(cloudevents.ApplicationJSON, changes.Changes()); err != nil {
+	if a.projection != nil {
+		var doc map[string]interface{}
+		if err := changes.ScanDoc(&doc); err != nil {
+			return nil, err
+		}
+		data, err := a.projection.Apply(doc)
+		if err != nil {
+			return nil, err
+		}
+		if err := event.SetData(cloudevents.ApplicationJSON, data); err != nil {
+			return nil, err
+		}
+		return &event, nil
+	}
+
+	var data interface{} = changes.Changes()
+	switch a.payload {
+	case v1alpha1.PayloadDiff:
+		diff, err := a.diffData(ctx, changes)
+		if err != nil {
+			return nil, err
+		}
+		data = diff
+	case v1alpha1.PayloadMetadata:
+		data = cdbevents.DocumentMetadata{
+			ID:       changes.ID(),
+			Rev:      firstRev(changes.Changes()),
+			Seq:      changes.Seq(),
+			Deleted:  changes.Deleted(),
+			Database: a.database,
+		}
+	}
+	if err := event.SetData(cloudevents.ApplicationJSON, data); err != nil {
 		return nil, err
 	}
 	return &event, nil
 }
+
+// parseProjection decodes the JSON object of COUCHDB_PROJECTION.
+func parseProjection(text string) (*v1alpha1.DataProjection, error) {
+	if text == "" {
+		return nil, nil
+	}
+	var fields map[string]string
+	if err := json.Unmarshal([]byte(text), &fields); err != nil {
+		return nil, err
+	}
+	return v1alpha1.ParseProjection(fields)
+}
+
+// firstRev returns the first revision listed by a change, which is the
+// winning revision unless the document is in conflict.
+func firstRev(revs []string) string {
+	if len(revs) == 0 {
+		return ""
+	}
+	return revs[0]
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"net/url"
+	"path"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+// documentsURL returns the URL of the database, without credentials, under
+// which documents and their attachments are fetched.
+func documentsURL(rawurl, database string) (string, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", err
+	}
+	u.User = nil
+	u.Path = path.Join("/", u.Path, database)
+	u.RawPath = ""
+	return u.String(), nil
+}
+
+// documentData returns the changed document, with its attachments
+// represented according to the attachments policy. Inline attachments are
+// already embedded by CouchDB.
+func (a *couchDbAdapter) documentData(changes change) (map[string]interface{}, error) {
+	var doc map[string]interface{}
+	if err := changes.ScanDoc(&doc); err != nil {
+		return nil, err
+	}
+	atts, ok := doc["_attachments"].(map[string]interface{})
+	if !ok {
+		return doc, nil
+	}
+
+	switch v1alpha1.AttachmentsPolicy(a.attachments) {
+	case v1alpha1.AttachmentsNone:
+		delete(doc, "_attachments")
+	case v1alpha1.AttachmentsReference:
+		for name, att := range atts {
+			if stub, ok := att.(map[string]interface{}); ok {
+				stub["url"] = a.documentsURL + "/" + url.PathEscape(changes.ID()) + "/" + url.PathEscape(name)
+			}
+		}
+	}
+	return doc, nil
+}
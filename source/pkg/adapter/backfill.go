@@ -0,0 +1,258 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/go-kivik/kivik/v3"
+	"go.uber.org/zap"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+// backfillPageSize is the number of documents read per _all_docs request.
+const backfillPageSize = 100
+
+// backfillDoc is an existing document reported by the backfill.
+type backfillDoc struct {
+	id  string
+	rev string
+	doc json.RawMessage
+}
+
+var _ change = (*backfillDoc)(nil)
+
+func (d *backfillDoc) ID() string { return d.id }
+
+// Seq is empty, as existing documents are not reported at an update sequence.
+func (d *backfillDoc) Seq() string { return "" }
+
+func (d *backfillDoc) Changes() []string { return []string{d.rev} }
+
+func (d *backfillDoc) Deleted() bool { return false }
+
+func (d *backfillDoc) ScanDoc(dest interface{}) error { return json.Unmarshal(d.doc, dest) }
+
+// backfill tracks the progress of spec.backfill.
+type backfill struct {
+	mu     sync.Mutex
+	status v1alpha1.BackfillStatus
+
+	// startKey is the ID of the last document read, from which a failed
+	// backfill resumes.
+	startKey string
+
+	// bookmarkID, when set, is the local document of the database holding
+	// the progress of the backfill, from which a restarted receive adapter
+	// resumes, and bookmarkRev its current revision.
+	bookmarkID  string
+	bookmarkRev string
+}
+
+// backfillBookmark is the progress of a backfill saved in a local document,
+// which is not replicated nor reported by the changes feed.
+type backfillBookmark struct {
+	Rev string `json:"_rev,omitempty"`
+
+	// StartKey is the ID of the last document whose event was delivered.
+	StartKey  string              `json:"startKey"`
+	Documents int64               `json:"documents"`
+	Total     int64               `json:"total"`
+	Sequence  v1alpha1.SequenceID `json:"sequence"`
+}
+
+// progress returns a copy of the backfill status.
+func (b *backfill) progress() *v1alpha1.BackfillStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.status.DeepCopy()
+}
+
+func (b *backfill) completed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.status.State == v1alpha1.BackfillCompleted
+}
+
+func (b *backfill) update(f func(*v1alpha1.BackfillStatus)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	f(&b.status)
+}
+
+// runBackfill reports the existing documents of the database, and then makes
+// the changes feed start at the update sequence of the database when the
+// backfill started.
+func (a *couchDbAdapter) runBackfill(ctx context.Context) error {
+	b := a.backfill
+	if b.progress().State == "" {
+		if err := a.startBackfill(ctx); err != nil {
+			return err
+		}
+	}
+
+	for {
+		options := kivik.Options{
+			"include_docs": true,
+			"limit":        backfillPageSize,
+		}
+		if b.startKey != "" {
+			options["startkey"] = b.startKey
+			options["skip"] = 1
+		}
+		rows, err := a.couchDB.AllDocs(ctx, options)
+		if err != nil {
+			return err
+		}
+
+		read := 0
+		for rows.Next() {
+			read++
+			var value struct {
+				Rev string `json:"rev"`
+			}
+			if err := rows.ScanValue(&value); err != nil {
+				return err
+			}
+			var doc json.RawMessage
+			if err := rows.ScanDoc(&doc); err != nil {
+				return err
+			}
+
+			d := &backfillDoc{id: rows.ID(), rev: value.Rev, doc: doc}
+			ctx, span := a.startChangeSpan(ctx, d, "")
+			c, reports := a.join(ctx, d)
+			reports = reports && a.reports(c)
+			var err error
+			if reports {
+				err = a.emit(ctx, c)
+			}
+			endChangeSpan(span, reports)
+			if err != nil {
+				// The next page starts at the document.
+				if err := rows.Close(); err != nil {
+					a.logger.Warn("Error closing the documents", zap.Error(err))
+				}
+				return err
+			}
+			b.startKey = d.id
+			b.update(func(s *v1alpha1.BackfillStatus) { s.Documents++ })
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if read > 0 {
+			a.saveBackfillBookmark(ctx)
+		}
+		if read < backfillPageSize {
+			break
+		}
+	}
+
+	status := b.progress()
+	a.options["since"] = status.Sequence
+	a.checkpoint.restart(status.Sequence)
+	b.update(func(s *v1alpha1.BackfillStatus) { s.State = v1alpha1.BackfillCompleted })
+	a.deleteBackfillBookmark(ctx)
+	a.logger.Infow("Backfill completed", zap.Int64("documents", status.Documents))
+	return nil
+}
+
+// startBackfill resumes the backfill from its bookmark, or starts it over at
+// the current update sequence of the database.
+func (a *couchDbAdapter) startBackfill(ctx context.Context) error {
+	b := a.backfill
+	if b.bookmarkID != "" {
+		var bookmark backfillBookmark
+		err := a.couchDB.Get(ctx, b.bookmarkID).ScanDoc(&bookmark)
+		switch {
+		case kivik.StatusCode(err) == http.StatusNotFound:
+		case err != nil:
+			return err
+		default:
+			b.bookmarkRev = bookmark.Rev
+			b.startKey = bookmark.StartKey
+			b.update(func(s *v1alpha1.BackfillStatus) {
+				s.State = v1alpha1.BackfillInProgress
+				s.Documents = bookmark.Documents
+				s.Total = bookmark.Total
+				s.Sequence = string(bookmark.Sequence)
+			})
+			a.logger.Infow("Resuming the backfill", zap.String("startKey", bookmark.StartKey),
+				zap.Int64("documents", bookmark.Documents), zap.String("sequence", string(bookmark.Sequence)))
+			return nil
+		}
+	}
+
+	stats, err := a.couchDB.Stats(ctx)
+	if err != nil {
+		return err
+	}
+	b.update(func(s *v1alpha1.BackfillStatus) {
+		s.State = v1alpha1.BackfillInProgress
+		s.Total = stats.DocCount
+		s.Sequence = stats.UpdateSeq
+	})
+	a.logger.Infow("Backfilling the existing documents", zap.Int64("total", stats.DocCount), zap.String("sequence", stats.UpdateSeq))
+	return nil
+}
+
+// saveBackfillBookmark records the progress of the backfill once the events of
+// the documents read so far are delivered: the batches and groups being
+// gathered are sent first, which waits for the sinks asking to slow down. A
+// bookmark that cannot be saved only makes a restarted backfill start over, so
+// the backfill goes on without it.
+func (a *couchDbAdapter) saveBackfillBookmark(ctx context.Context) {
+	b := a.backfill
+	if b.bookmarkID == "" {
+		return
+	}
+	a.flushBatches(ctx)
+	a.flushGroups(ctx)
+
+	status := b.progress()
+	rev, err := a.couchDB.Put(ctx, b.bookmarkID, &backfillBookmark{
+		Rev:       b.bookmarkRev,
+		StartKey:  b.startKey,
+		Documents: status.Documents,
+		Total:     status.Total,
+		Sequence:  v1alpha1.SequenceID(status.Sequence),
+	})
+	if err != nil {
+		a.logger.Warnw("Unable to save the backfill bookmark, a restarted backfill starts over", zap.String("id", b.bookmarkID), zap.Error(err))
+		b.bookmarkID = ""
+		return
+	}
+	b.bookmarkRev = rev
+}
+
+// deleteBackfillBookmark removes the bookmark of a completed backfill, so that
+// a restarted receive adapter backfills again.
+func (a *couchDbAdapter) deleteBackfillBookmark(ctx context.Context) {
+	b := a.backfill
+	if b.bookmarkID == "" || b.bookmarkRev == "" {
+		return
+	}
+	if _, err := a.couchDB.Delete(ctx, b.bookmarkID, b.bookmarkRev); err != nil {
+		a.logger.Warnw("Unable to delete the backfill bookmark", zap.String("id", b.bookmarkID), zap.Error(err))
+	}
+	b.bookmarkRev = ""
+}
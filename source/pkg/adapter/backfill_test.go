@@ -0,0 +1,219 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kivik/kivik/v3"
+	"github.com/go-kivik/kivik/v3/driver"
+	"github.com/go-kivik/kivikmock/v3"
+	"github.com/google/go-cmp/cmp"
+	"knative.dev/eventing/pkg/adapter/v2"
+	kncetesting "knative.dev/eventing/pkg/adapter/v2/test"
+	pkgtesting "knative.dev/pkg/reconciler/testing"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+func TestBackfill(t *testing.T) {
+	env := envConfig{
+		EnvConfig: adapter.EnvConfig{
+			Namespace: "default",
+		},
+		EventSource: "test-source",
+		Database:    "testdb",
+		Feed:        "normal",
+		Backfill:    true,
+		DesignDocs:  "exclude",
+	}
+	ctx, _ := pkgtesting.SetupFakeContext(t)
+
+	c, mock := kivikmock.NewT(t)
+
+	mockDB := mock.NewDB()
+	mock.ExpectDB().WithName("testdb").WillReturn(mockDB)
+	mockDB.ExpectStats().WillReturn(&driver.DBStats{DocCount: 3, UpdateSeq: "7-g"})
+	mockDB.ExpectAllDocs().WillReturn(kivikmock.NewRows().AddRow(&driver.Row{
+		ID:    "_design/app",
+		Value: json.RawMessage(`{"rev":"1-d"}`),
+		Doc:   json.RawMessage(`{"_id":"_design/app","_rev":"1-d"}`),
+	}).AddRow(&driver.Row{
+		ID:    "a",
+		Value: json.RawMessage(`{"rev":"3-a"}`),
+		Doc:   json.RawMessage(`{"_id":"a","_rev":"3-a"}`),
+	}).AddRow(&driver.Row{
+		ID:    "b",
+		Value: json.RawMessage(`{"rev":"1-b"}`),
+		Doc:   json.RawMessage(`{"_id":"b","_rev":"1-b"}`),
+	}))
+
+	a := newAdapter(ctx, &env, kncetesting.NewTestClient(), c.DSN(), "kivikmock").(*couchDbAdapter)
+	ce := a.ce.(*kncetesting.TestCloudEventsClient)
+	if err := a.runBackfill(context.Background()); err != nil {
+		t.Fatalf("runBackfill() = %v", err)
+	}
+
+	var got []string
+	for _, event := range ce.Sent() {
+		if event.Type() != v1alpha1.CouchDbSourceUpdateEventType {
+			t.Errorf("type = %q, want %q", event.Type(), v1alpha1.CouchDbSourceUpdateEventType)
+		}
+		got = append(got, event.ID())
+	}
+	if diff := cmp.Diff([]string{"testdb/a/3-a", "testdb/b/1-b"}, got); diff != "" {
+		t.Errorf("unexpected events (-want, +got) = %v", diff)
+	}
+	if since := a.options["since"]; since != "7-g" {
+		t.Errorf("since = %v, want 7-g", since)
+	}
+
+	want := v1alpha1.AdapterStatus{
+		Backfill: &v1alpha1.BackfillStatus{
+			State:     v1alpha1.BackfillCompleted,
+			Documents: 3,
+			Total:     3,
+			Sequence:  "7-g",
+		},
+		// The changes are read after the backfill.
+		Sequence: "7-g",
+	}
+	rec := httptest.NewRecorder()
+	a.serveStatusHTTP(rec, httptest.NewRequest("GET", v1alpha1.AdapterStatusPath, nil))
+	var status v1alpha1.AdapterStatus
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatalf("invalid status: %v", err)
+	}
+	if diff := cmp.Diff(want, status); diff != "" {
+		t.Errorf("unexpected status (-want, +got) = %v", diff)
+	}
+}
+
+func TestBackfillBookmark(t *testing.T) {
+	env := envConfig{
+		EnvConfig: adapter.EnvConfig{
+			Namespace: "default",
+		},
+		EventSource:      "test-source",
+		Database:         "testdb",
+		Feed:             "normal",
+		Backfill:         true,
+		BackfillBookmark: "_local/knative-backfill-1234",
+	}
+	ctx, _ := pkgtesting.SetupFakeContext(t)
+
+	c, mock := kivikmock.NewT(t)
+
+	mockDB := mock.NewDB()
+	mock.ExpectDB().WithName("testdb").WillReturn(mockDB)
+	mockDB.ExpectGet().WithDocID("_local/knative-backfill-1234").WillReturnError(&kivik.Error{HTTPStatus: http.StatusNotFound})
+	mockDB.ExpectStats().WillReturn(&driver.DBStats{DocCount: 1, UpdateSeq: "2-g"})
+	mockDB.ExpectAllDocs().WillReturn(kivikmock.NewRows().AddRow(&driver.Row{
+		ID:    "a",
+		Value: json.RawMessage(`{"rev":"1-a"}`),
+		Doc:   json.RawMessage(`{"_id":"a","_rev":"1-a"}`),
+	}))
+	mockDB.ExpectPut().WithDocID("_local/knative-backfill-1234").WithDoc(&backfillBookmark{
+		StartKey:  "a",
+		Documents: 1,
+		Total:     1,
+		Sequence:  "2-g",
+	}).WillReturn("0-1")
+	mockDB.ExpectDelete().WithDocID("_local/knative-backfill-1234").WithRev("0-1").WillReturn("0-2")
+
+	a := newAdapter(ctx, &env, kncetesting.NewTestClient(), c.DSN(), "kivikmock").(*couchDbAdapter)
+	if err := a.runBackfill(context.Background()); err != nil {
+		t.Fatalf("runBackfill() = %v", err)
+	}
+	if got := len(a.ce.(*kncetesting.TestCloudEventsClient).Sent()); got != 1 {
+		t.Errorf("sent %d events, want 1", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBackfillResume(t *testing.T) {
+	env := envConfig{
+		EnvConfig: adapter.EnvConfig{
+			Namespace: "default",
+		},
+		EventSource:      "test-source",
+		Database:         "testdb",
+		Feed:             "normal",
+		Backfill:         true,
+		BackfillBookmark: "_local/knative-backfill-1234",
+	}
+	ctx, _ := pkgtesting.SetupFakeContext(t)
+
+	c, mock := kivikmock.NewT(t)
+
+	mockDB := mock.NewDB()
+	mock.ExpectDB().WithName("testdb").WillReturn(mockDB)
+	mockDB.ExpectGet().WithDocID("_local/knative-backfill-1234").WillReturn(&driver.Document{
+		Rev:  "0-4",
+		Body: ioutil.NopCloser(strings.NewReader(`{"_id":"_local/knative-backfill-1234","_rev":"0-4","startKey":"a","documents":1,"total":2,"sequence":"7-g"}`)),
+	})
+	// The documents up to the bookmark are not read again.
+	mockDB.ExpectAllDocs().WithOptions(map[string]interface{}{
+		"include_docs": true,
+		"limit":        backfillPageSize,
+		"startkey":     "a",
+		"skip":         1,
+	}).WillReturn(kivikmock.NewRows().AddRow(&driver.Row{
+		ID:    "b",
+		Value: json.RawMessage(`{"rev":"1-b"}`),
+		Doc:   json.RawMessage(`{"_id":"b","_rev":"1-b"}`),
+	}))
+	mockDB.ExpectPut().WithDocID("_local/knative-backfill-1234").WillReturn("0-5")
+	mockDB.ExpectDelete().WithDocID("_local/knative-backfill-1234").WithRev("0-5").WillReturn("0-6")
+
+	a := newAdapter(ctx, &env, kncetesting.NewTestClient(), c.DSN(), "kivikmock").(*couchDbAdapter)
+	ce := a.ce.(*kncetesting.TestCloudEventsClient)
+	if err := a.runBackfill(context.Background()); err != nil {
+		t.Fatalf("runBackfill() = %v", err)
+	}
+
+	var got []string
+	for _, event := range ce.Sent() {
+		got = append(got, event.ID())
+	}
+	if diff := cmp.Diff([]string{"testdb/b/1-b"}, got); diff != "" {
+		t.Errorf("unexpected events (-want, +got) = %v", diff)
+	}
+	if since := a.options["since"]; since != "7-g" {
+		t.Errorf("since = %v, want 7-g", since)
+	}
+	want := &v1alpha1.BackfillStatus{
+		State:     v1alpha1.BackfillCompleted,
+		Documents: 2,
+		Total:     2,
+		Sequence:  "7-g",
+	}
+	if diff := cmp.Diff(want, a.backfill.progress()); diff != "" {
+		t.Errorf("unexpected progress (-want, +got) = %v", diff)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maxRetryAfter caps the pause a sink may ask for, so that a bogus
+// Retry-After header does not stall the source for days.
+const maxRetryAfter = 5 * time.Minute
+
+// sinkBackpressure records the pauses asked by the sinks. Events are sent to
+// the sink through http.DefaultTransport, which it wraps, while the CouchDB
+// traffic has transports of its own.
+var sinkBackpressure = newBackpressure()
+
+func init() {
+	http.DefaultTransport = &backpressureTransport{
+		base:         http.DefaultTransport,
+		backpressure: sinkBackpressure,
+	}
+}
+
+// backpressure holds, by host, until when the sinks that answered
+// 429 Too Many Requests or 503 Service Unavailable with a Retry-After header
+// asked not to be sent events.
+type backpressure struct {
+	mu    sync.Mutex
+	until map[string]time.Time
+}
+
+func newBackpressure() *backpressure {
+	return &backpressure{until: make(map[string]time.Time)}
+}
+
+// pause holds the deliveries to host for d.
+func (b *backpressure) pause(host string, now time.Time, d time.Duration) {
+	if d > maxRetryAfter {
+		d = maxRetryAfter
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if until := now.Add(d); until.After(b.until[host]) {
+		b.until[host] = until
+	}
+}
+
+// pausedFor returns how long the deliveries to host are still held.
+func (b *backpressure) pausedFor(host string, now time.Time) time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	until, ok := b.until[host]
+	if !ok {
+		return 0
+	}
+	if !until.After(now) {
+		delete(b.until, host)
+		return 0
+	}
+	return until.Sub(now)
+}
+
+// backpressureTransport records the Retry-After of the busy responses.
+type backpressureTransport struct {
+	base         http.RoundTripper
+	backpressure *backpressure
+}
+
+func (t *backpressureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || !busy(resp.StatusCode) {
+		return resp, err
+	}
+	now := time.Now()
+	if d, ok := retryAfter(resp.Header.Get("Retry-After"), now); ok {
+		t.backpressure.pause(req.URL.Host, now, d)
+	}
+	return resp, err
+}
+
+// busy is whether the status code tells that the sink is overloaded.
+func busy(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable
+}
+
+// retryAfter parses a Retry-After header, either a number of seconds or an
+// HTTP date.
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if d := t.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// sinkPaused is whether the sink asked to hold the deliveries.
+func (a *couchDbAdapter) sinkPaused() bool {
+	return a.backpressure.pausedFor(a.delivery.sinkHost, time.Now()) > 0
+}
+
+// waitForSink waits until the sink is ready for events again. Deliveries are
+// made while reading the changes feed, so waiting holds the feed rather than
+// piling up changes or retrying against an overloaded sink.
+func (a *couchDbAdapter) waitForSink(ctx context.Context) error {
+	d := a.backpressure.pausedFor(a.delivery.sinkHost, time.Now())
+	if d <= 0 {
+		return nil
+	}
+	a.logger.Infow("The sink asked to slow down, pausing deliveries", zap.Duration("pause", d))
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
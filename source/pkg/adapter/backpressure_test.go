@@ -0,0 +1,204 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/protocol"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+	"go.uber.org/zap"
+	kncetesting "knative.dev/eventing/pkg/adapter/v2/test"
+)
+
+// busySinkClient answers 429 to the first deliveries, as the transport would
+// have recorded a Retry-After.
+type busySinkClient struct {
+	*kncetesting.TestCloudEventsClient
+	backpressure *backpressure
+	host         string
+	busy         int
+	attempts     int
+}
+
+func (c *busySinkClient) Send(ctx context.Context, event cloudevents.Event) cloudevents.Result {
+	c.attempts++
+	if c.attempts <= c.busy {
+		c.backpressure.pause(c.host, time.Now(), 10*time.Millisecond)
+		return cehttp.NewResult(http.StatusTooManyRequests, "%w", protocol.ResultNACK)
+	}
+	return c.TestCloudEventsClient.Send(ctx, event)
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC)
+	testCases := map[string]struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		"missing": {},
+		"seconds": {
+			value:  "120",
+			want:   2 * time.Minute,
+			wantOK: true,
+		},
+		"negative": {
+			value: "-1",
+		},
+		"date": {
+			value:  "Mon, 01 Mar 2021 12:00:30 GMT",
+			want:   30 * time.Second,
+			wantOK: true,
+		},
+		"past date": {
+			value:  "Mon, 01 Mar 2021 11:00:00 GMT",
+			wantOK: true,
+		},
+		"invalid": {
+			value: "soon",
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			got, ok := retryAfter(tc.value, now)
+			if got != tc.want || ok != tc.wantOK {
+				t.Errorf("retryAfter(%q) = %v, %v, want %v, %v", tc.value, got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestBackpressureTransport(t *testing.T) {
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/busy" {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer sink.Close()
+	host := sink.Listener.Addr().String()
+
+	b := newBackpressure()
+	client := &http.Client{Transport: &backpressureTransport{base: http.DefaultTransport, backpressure: b}}
+
+	resp, err := client.Get(sink.URL)
+	if err != nil {
+		t.Fatalf("Get() = %v", err)
+	}
+	resp.Body.Close()
+	if d := b.pausedFor(host, time.Now()); d != 0 {
+		t.Errorf("paused for %v without Retry-After, want 0", d)
+	}
+
+	resp, err = client.Get(sink.URL + "/busy")
+	if err != nil {
+		t.Fatalf("Get() = %v", err)
+	}
+	resp.Body.Close()
+	if d := b.pausedFor(host, time.Now()); d <= maxRetryAfter-time.Minute || d > maxRetryAfter {
+		t.Errorf("paused for %v, want the cap %v", d, maxRetryAfter)
+	}
+	if d := b.pausedFor("other.example.com", time.Now()); d != 0 {
+		t.Errorf("other host paused for %v, want 0", d)
+	}
+}
+
+func TestSendBackpressure(t *testing.T) {
+	sink, _ := url.Parse("http://sink.example.com")
+	b := newBackpressure()
+	ce := &busySinkClient{
+		TestCloudEventsClient: kncetesting.NewTestClient(),
+		backpressure:          b,
+		host:                  sink.Host,
+		busy:                  3,
+	}
+	a := &couchDbAdapter{
+		ce:           ce,
+		logger:       zap.NewNop().Sugar(),
+		backpressure: b,
+		// No retries: the pauses asked by the sink do not count as such.
+		delivery: &deliveryConfig{sinkHost: sink.Host},
+	}
+
+	event := cloudevents.NewEvent()
+	event.SetID("1")
+	event.SetType("test")
+	event.SetSource("test")
+	start := time.Now()
+	if err := a.send(context.Background(), event); err != nil {
+		t.Fatalf("send() = %v", err)
+	}
+	if ce.attempts != 4 {
+		t.Errorf("attempts = %d, want 4", ce.attempts)
+	}
+	if got := len(ce.Sent()); got != 1 {
+		t.Errorf("delivered %d events, want 1", got)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("send() took %v, want at least the 3 pauses of 10ms", elapsed)
+	}
+}
+
+func TestFlushBatchStopping(t *testing.T) {
+	sink, _ := url.Parse("http://sink.example.com")
+	b := newBackpressure()
+	b.pause(sink.Host, time.Now(), time.Hour)
+	ce := kncetesting.NewTestClient()
+	a := &couchDbAdapter{
+		ce:           ce,
+		logger:       zap.NewNop().Sugar(),
+		backpressure: b,
+		delivery:     &deliveryConfig{sinkHost: sink.Host},
+		batcher:      &batcher{size: 2, wait: time.Hour, sink: sink.String()},
+		checkpoint:   newCheckpoint(""),
+	}
+	event := cloudevents.NewEvent()
+	event.SetID("1")
+	event.SetType("test")
+	event.SetSource("test")
+	a.checkpoint.read("1", "1-seq", true)
+	a.deliver(context.Background(), event)
+
+	// The adapter stopping ends the pause asked by the sink.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		a.flushBatch(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("flushBatch() did not return once the adapter stopped")
+	}
+	if got := len(ce.Sent()); got != 0 {
+		t.Errorf("delivered %d events, want none", got)
+	}
+	if got := a.checkpoint.sequence(); got != "" {
+		t.Errorf("checkpoint = %q, want the event to be read again", got)
+	}
+}
@@ -0,0 +1,287 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"go.uber.org/zap"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	cdbevents "knative.dev/eventing-couchdb/source/pkg/events"
+)
+
+const (
+	// defaultBatchSize and defaultBatchWait bound the events of a batch, and
+	// how long the first of them waits for the others.
+	defaultBatchSize = 100
+	defaultBatchWait = time.Second
+)
+
+// errBatchUnsupported is returned when the sink rejects the batch format.
+var errBatchUnsupported = errors.New("the sink does not accept CloudEvents batches")
+
+// batcher gathers events to deliver them to the sink in the CloudEvents JSON
+// batch format or, when coalescing, as a single batch event.
+type batcher struct {
+	size      int
+	wait      time.Duration
+	coalesce  bool
+	sink      string
+	overrides *duckv1.CloudEventOverrides
+	client    *http.Client
+
+	mu          sync.Mutex
+	events      []cloudevents.Event
+	timer       *time.Timer
+	unsupported bool
+}
+
+func newBatcher(env *envConfig) (*batcher, error) {
+	batchMode := v1alpha1.ContentMode(env.ContentMode) == v1alpha1.ContentModeBatch
+	if !batchMode && !env.Batch {
+		return nil, nil
+	}
+	b := &batcher{
+		size:     defaultBatchSize,
+		wait:     defaultBatchWait,
+		coalesce: !batchMode,
+		sink:     env.Sink,
+		client:   http.DefaultClient,
+	}
+	if env.BatchMaxCount > 0 {
+		b.size = env.BatchMaxCount
+	}
+	if env.BatchMaxWait != "" {
+		wait, err := parseDuration(env.BatchMaxWait)
+		if err != nil {
+			return nil, fmt.Errorf("invalid batch wait %q: %v", env.BatchMaxWait, err)
+		}
+		b.wait = wait
+	}
+	if batchMode {
+		overrides, err := env.GetCloudEventOverrides()
+		if err != nil {
+			return nil, fmt.Errorf("invalid CloudEvent overrides: %v", err)
+		}
+		b.overrides = overrides
+	}
+	return b, nil
+}
+
+// add queues the event in the current batch, and returns false when the sink
+// is known not to accept batches. flush is called once the batch is full or
+// its first event has waited long enough.
+func (b *batcher) add(event cloudevents.Event, flush func()) bool {
+	b.mu.Lock()
+	if b.unsupported {
+		b.mu.Unlock()
+		return false
+	}
+	b.events = append(b.events, event)
+	full := len(b.events) >= b.size
+	if !full && b.timer == nil {
+		b.timer = time.AfterFunc(b.wait, flush)
+	}
+	b.mu.Unlock()
+
+	if full {
+		flush()
+	}
+	return true
+}
+
+// take removes and returns the events of the current batch.
+func (b *batcher) take() []cloudevents.Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	events := b.events
+	b.events = nil
+	return events
+}
+
+// disable makes the following events bypass the batcher.
+func (b *batcher) disable() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.unsupported = true
+}
+
+// send posts the events to the sink as a single batch. The adapter framework
+// client only sends single events, so the CloudEvent overrides it would apply
+// are applied here.
+func (b *batcher) send(ctx context.Context, events []cloudevents.Event) error {
+	batch := make([]cloudevents.Event, 0, len(events))
+	for _, event := range events {
+		event = event.Clone()
+		for n, v := range b.overrides.Extensions {
+			event.SetExtension(n, v)
+		}
+		batch = append(batch, event)
+	}
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.sink, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", cloudevents.ApplicationCloudEventsBatchJSON)
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnsupportedMediaType:
+		return errBatchUnsupported
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return fmt.Errorf("the sink responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// deliver sends the event to the sink, as part of a batch when the sink
+// takes batches.
+func (a *couchDbAdapter) deliver(ctx context.Context, event cloudevents.Event) {
+	if a.batcher != nil && a.batcher.add(event, func() { a.flushBatch(ctx) }) {
+		return
+	}
+	a.sequencer.lock()
+	defer a.sequencer.unlock()
+	if a.held(event) {
+		return
+	}
+	err := a.send(ctx, event)
+	setSpanError(ctx, err)
+	if err != nil {
+		a.logger.Error("event delivery failed", zap.String("id", event.ID()), zap.Error(err))
+	}
+	a.checkpoint.ack(event.ID(), err)
+}
+
+// flushBatch sends the current batch. The events of a batch the sink did not
+// accept are sent again one at a time, so that the delivery retries and dead
+// letter sink still apply to them.
+func (a *couchDbAdapter) flushBatch(ctx context.Context) {
+	// The batch is taken under the lock, so that batches are delivered in
+	// the order they were gathered.
+	a.sequencer.lock()
+	defer a.sequencer.unlock()
+	events := a.batcher.take()
+	if len(events) == 0 || a.held(events...) {
+		return
+	}
+	if a.batcher.coalesce {
+		event, err := cdbevents.NewBatchEvent(events...)
+		if err != nil {
+			// The events are delivered on their own rather than skipped,
+			// which would move the checkpoint past them.
+			a.logger.Errorw("Error making the batch event, sending its events one at a time", zap.Int("events", len(events)), zap.Error(err))
+			a.sendEach(ctx, events)
+			return
+		}
+		a.checkpoint.merge(event.ID(), eventIDs(events))
+		err = a.send(ctx, event)
+		if err != nil {
+			a.logger.Error("event delivery failed", zap.String("id", event.ID()), zap.Error(err))
+		}
+		a.checkpoint.ack(event.ID(), err)
+		return
+	}
+	if err := a.throttle(ctx, len(events)); err != nil {
+		a.logger.Error("Batch delivery failed", zap.Int("events", len(events)), zap.Error(err))
+		a.ackAll(events, err)
+		return
+	}
+	var err error
+	for {
+		if err = a.waitForSink(ctx); err != nil {
+			a.logger.Error("Batch delivery failed", zap.Int("events", len(events)), zap.Error(err))
+			a.ackAll(events, err)
+			return
+		}
+		if err = a.batcher.send(ctx, events); err == nil || !a.sinkPaused() {
+			break
+		}
+	}
+	switch {
+	case err == nil:
+		a.stats.recordDelivered(time.Now(), len(events))
+		a.ackAll(events, nil)
+		return
+	case errors.Is(err, errBatchUnsupported):
+		a.logger.Warn("The sink does not accept CloudEvents batches, sending events one at a time from now on")
+		a.batcher.disable()
+	default:
+		a.logger.Warnw("Batch delivery failed, sending its events one at a time", zap.Int("events", len(events)), zap.Error(err))
+	}
+	a.sendEach(ctx, events)
+}
+
+// sendEach sends the events of a batch one at a time.
+func (a *couchDbAdapter) sendEach(ctx context.Context, events []cloudevents.Event) {
+	for _, event := range events {
+		if a.held(event) {
+			continue
+		}
+		err := a.send(ctx, event)
+		if err != nil {
+			a.logger.Error("event delivery failed", zap.String("id", event.ID()), zap.Error(err))
+		}
+		a.checkpoint.ack(event.ID(), err)
+	}
+}
+
+// ackAll records the outcome of the delivery of the events.
+func (a *couchDbAdapter) ackAll(events []cloudevents.Event, err error) {
+	for _, event := range events {
+		a.checkpoint.ack(event.ID(), err)
+	}
+}
+
+// eventIDs returns the IDs of the events.
+func eventIDs(events []cloudevents.Event) []string {
+	ids := make([]string, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.ID())
+	}
+	return ids
+}
+
+// flushBatches sends the batch being gathered.
+func (a *couchDbAdapter) flushBatches(ctx context.Context) {
+	if a.batcher != nil {
+		a.flushBatch(ctx)
+	}
+}
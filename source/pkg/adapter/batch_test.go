@@ -0,0 +1,194 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+	kncetesting "knative.dev/eventing/pkg/adapter/v2/test"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	cdbevents "knative.dev/eventing-couchdb/source/pkg/events"
+)
+
+func TestFlushBatch(t *testing.T) {
+	testCases := map[string]struct {
+		status          int
+		wantBatched     int
+		wantSingle      int
+		wantUnsupported bool
+	}{
+		"accepted": {
+			status:      http.StatusAccepted,
+			wantBatched: 2,
+		},
+		"unsupported": {
+			status:          http.StatusUnsupportedMediaType,
+			wantSingle:      2,
+			wantUnsupported: true,
+		},
+		"failed": {
+			status:     http.StatusInternalServerError,
+			wantSingle: 2,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			var batched []map[string]interface{}
+			sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Content-Type"); got != cloudevents.ApplicationCloudEventsBatchJSON {
+					t.Errorf("Content-Type = %q, want %q", got, cloudevents.ApplicationCloudEventsBatchJSON)
+				}
+				if tc.status < 300 {
+					if err := json.NewDecoder(r.Body).Decode(&batched); err != nil {
+						t.Errorf("invalid batch: %v", err)
+					}
+				}
+				w.WriteHeader(tc.status)
+			}))
+			defer sink.Close()
+
+			ce := kncetesting.NewTestClient()
+			a := &couchDbAdapter{
+				ce:       ce,
+				logger:   zap.NewNop().Sugar(),
+				delivery: &deliveryConfig{},
+				batcher: &batcher{
+					size:      2,
+					wait:      time.Hour,
+					sink:      sink.URL,
+					overrides: &duckv1.CloudEventOverrides{Extensions: map[string]string{"env": "test"}},
+					client:    sink.Client(),
+				},
+			}
+
+			for _, id := range []string{"1", "2"} {
+				event := cloudevents.NewEvent()
+				event.SetID(id)
+				event.SetType("test")
+				event.SetSource("test")
+				// The second event fills the batch, which is then flushed.
+				a.deliver(context.Background(), event)
+			}
+
+			if got := len(batched); got != tc.wantBatched {
+				t.Errorf("batched %d events, want %d", got, tc.wantBatched)
+			}
+			for _, event := range batched {
+				if event["env"] != "test" {
+					t.Errorf("batched event %v misses the overridden extension", event)
+				}
+			}
+			if got := len(ce.Sent()); got != tc.wantSingle {
+				t.Errorf("sent %d single events, want %d", got, tc.wantSingle)
+			}
+			if a.batcher.unsupported != tc.wantUnsupported {
+				t.Errorf("unsupported = %v, want %v", a.batcher.unsupported, tc.wantUnsupported)
+			}
+		})
+	}
+}
+
+func TestCoalesceBatch(t *testing.T) {
+	b, err := newBatcher(&envConfig{Batch: true, BatchMaxCount: 3, BatchMaxWait: "PT1H"})
+	if err != nil {
+		t.Fatalf("newBatcher() = %v", err)
+	}
+	if !b.coalesce || b.size != 3 || b.wait != time.Hour {
+		t.Fatalf("newBatcher() = %+v, want a coalescing batcher of 3 events waiting an hour", b)
+	}
+
+	ce := kncetesting.NewTestClient()
+	a := &couchDbAdapter{
+		ce:       ce,
+		logger:   zap.NewNop().Sugar(),
+		delivery: &deliveryConfig{},
+		batcher:  b,
+	}
+	for _, id := range []string{"1", "2", "3"} {
+		event := cloudevents.NewEvent()
+		event.SetID(id)
+		event.SetType(v1alpha1.CouchDbSourceUpdateEventType)
+		event.SetSource("test")
+		event.SetSubject("doc-" + id)
+		// The third event fills the batch, which is then flushed.
+		a.deliver(context.Background(), event)
+	}
+
+	sent := ce.Sent()
+	if len(sent) != 1 {
+		t.Fatalf("sent %d events, want a single batch event", len(sent))
+	}
+	if got := sent[0].Type(); got != v1alpha1.CouchDbSourceBatchEventType {
+		t.Errorf("type = %q, want %q", got, v1alpha1.CouchDbSourceBatchEventType)
+	}
+	var entries []cdbevents.BatchEntry
+	if err := json.Unmarshal(sent[0].Data(), &entries); err != nil {
+		t.Fatalf("invalid batch data: %v", err)
+	}
+	if len(entries) != 3 || entries[2].Subject != "doc-3" {
+		t.Errorf("batch entries = %+v, want the 3 changes", entries)
+	}
+}
+
+func TestCoalesceBatchWithoutBatchEvent(t *testing.T) {
+	b, err := newBatcher(&envConfig{Batch: true, BatchMaxCount: 2, BatchMaxWait: "PT1H"})
+	if err != nil {
+		t.Fatalf("newBatcher() = %v", err)
+	}
+	ce := kncetesting.NewTestClient()
+	a := &couchDbAdapter{
+		ce:         ce,
+		logger:     zap.NewNop().Sugar(),
+		delivery:   &deliveryConfig{},
+		batcher:    b,
+		checkpoint: newCheckpoint(""),
+	}
+	for _, id := range []string{"1", "2"} {
+		event := cloudevents.NewEvent()
+		event.SetID(id)
+		event.SetType(v1alpha1.CouchDbSourceUpdateEventType)
+		event.SetSource("test")
+		event.SetExtension(cdbevents.RevExtension, "1-rev")
+		if id == "2" {
+			// An extension the batch entries cannot hold.
+			event.Context.AsV1().Extensions[cdbevents.RevExtension] = []string{"1-rev"}
+		}
+		a.checkpoint.read(id, id+"-seq", true)
+		a.deliver(context.Background(), event)
+	}
+
+	var got []string
+	for _, event := range ce.Sent() {
+		got = append(got, event.ID())
+	}
+	if diff := cmp.Diff([]string{"1", "2"}, got); diff != "" {
+		t.Errorf("unexpected events (-want, +got) = %v", diff)
+	}
+	if got := a.checkpoint.sequence(); got != "2-seq" {
+		t.Errorf("checkpoint = %q, want 2-seq", got)
+	}
+}
@@ -0,0 +1,477 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"go.uber.org/zap"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+const (
+	// maxBufferSegmentBytes is the size from which the buffer appends to a
+	// new segment file, so that the delivered events are freed a segment at
+	// a time.
+	maxBufferSegmentBytes = 16 << 20
+
+	// bufferRetryInterval is how long the buffer waits after failing to
+	// deliver its oldest event before trying again.
+	bufferRetryInterval = 5 * time.Second
+
+	bufferSegmentSuffix = ".wal"
+	bufferCursorFile    = "cursor"
+)
+
+// errBufferFull is returned for the events that do not fit in a full buffer
+// holding the changes feed.
+var errBufferFull = errors.New("the buffer is full")
+
+// buffer is a write-ahead log, on the volume of spec.buffer, of the events the
+// sink did not accept. The events are appended as JSON lines to segment
+// files, and delivered in order from the oldest one, whose delivered part is
+// recorded by the cursor file.
+type buffer struct {
+	dir          string
+	maxBytes     int64
+	segmentBytes int64
+	dropOldest   bool
+
+	mu sync.Mutex
+	// segments are the segment files, oldest first. Events are appended to
+	// the last one.
+	segments []bufferSegment
+	// size is the size of all the segments, offset how much of the first
+	// one was delivered.
+	size   int64
+	offset int64
+	tail   *os.File
+
+	// ready is signaled when an event is appended.
+	ready chan struct{}
+}
+
+type bufferSegment struct {
+	seq  int64
+	size int64
+}
+
+// bufferPosition is where a buffered event starts, and its length.
+type bufferPosition struct {
+	seq    int64
+	offset int64
+	n      int64
+}
+
+// bufferCursor is the content of the cursor file.
+type bufferCursor struct {
+	Segment int64 `json:"segment"`
+	Offset  int64 `json:"offset"`
+}
+
+func newBuffer(env *envConfig) (*buffer, error) {
+	if env.BufferDir == "" {
+		return nil, nil
+	}
+	if env.BufferMaxBytes <= 0 {
+		return nil, fmt.Errorf("invalid max bytes %d", env.BufferMaxBytes)
+	}
+	b := &buffer{
+		dir:          env.BufferDir,
+		maxBytes:     env.BufferMaxBytes,
+		segmentBytes: maxBufferSegmentBytes,
+		dropOldest:   v1alpha1.BufferFullPolicy(env.BufferOnFull) == v1alpha1.BufferFullDropOldest,
+		ready:        make(chan struct{}, 1),
+	}
+	// Small buffers drop their oldest events a fraction at a time.
+	if s := b.maxBytes / 8; s < b.segmentBytes {
+		b.segmentBytes = s
+	}
+	if err := b.open(); err != nil {
+		return nil, fmt.Errorf("error opening the buffer in %s: %w", b.dir, err)
+	}
+	return b, nil
+}
+
+// open reads back the segments and the cursor left by a previous run.
+func (b *buffer) open() error {
+	files, err := ioutil.ReadDir(b.dir)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		name := f.Name()
+		if !strings.HasSuffix(name, bufferSegmentSuffix) {
+			continue
+		}
+		seq, err := strconv.ParseInt(strings.TrimSuffix(name, bufferSegmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		b.segments = append(b.segments, bufferSegment{seq: seq, size: f.Size()})
+	}
+	sort.Slice(b.segments, func(i, j int) bool { return b.segments[i].seq < b.segments[j].seq })
+
+	var cursor bufferCursor
+	if raw, err := ioutil.ReadFile(filepath.Join(b.dir, bufferCursorFile)); err == nil {
+		if err := json.Unmarshal(raw, &cursor); err != nil {
+			return fmt.Errorf("invalid cursor: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	// The segments delivered before the cursor may not have been removed.
+	for len(b.segments) > 1 && b.segments[0].seq < cursor.Segment {
+		if err := os.Remove(b.segmentPath(b.segments[0].seq)); err != nil {
+			return err
+		}
+		b.segments = b.segments[1:]
+	}
+	if len(b.segments) > 0 && b.segments[0].seq == cursor.Segment {
+		b.offset = cursor.Offset
+	}
+
+	if len(b.segments) == 0 {
+		b.segments = []bufferSegment{{seq: 1}}
+	}
+	last := &b.segments[len(b.segments)-1]
+	b.tail, err = os.OpenFile(b.segmentPath(last.seq), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+	// An event partly written when the adapter stopped is dropped.
+	if last.size, err = completeLines(b.tail, last.size); err != nil {
+		return err
+	}
+	if _, err := b.tail.Seek(last.size, io.SeekStart); err != nil {
+		return err
+	}
+	if b.offset > b.segments[0].size {
+		b.offset = b.segments[0].size
+	}
+	for _, s := range b.segments {
+		b.size += s.size
+	}
+	return nil
+}
+
+// completeLines truncates the file after its last complete line, and returns
+// its new size.
+func completeLines(f *os.File, size int64) (int64, error) {
+	if size == 0 {
+		return 0, nil
+	}
+	content := make([]byte, size)
+	if _, err := f.ReadAt(content, 0); err != nil {
+		return 0, err
+	}
+	complete := int64(bytes.LastIndexByte(content, '\n') + 1)
+	if complete == size {
+		return size, nil
+	}
+	return complete, f.Truncate(complete)
+}
+
+func (b *buffer) segmentPath(seq int64) string {
+	return filepath.Join(b.dir, fmt.Sprintf("%016d%s", seq, bufferSegmentSuffix))
+}
+
+// empty is whether every buffered event was delivered.
+func (b *buffer) empty() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.pending() == 0
+}
+
+// pending returns the size of the events left to deliver.
+func (b *buffer) pending() int64 {
+	return b.size - b.offset
+}
+
+// bytes returns the size of the events left to deliver.
+func (b *buffer) bytes() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.pending()
+}
+
+// append writes the event durably at the end of the buffer, and returns how
+// many of the oldest events were dropped to make room for it.
+func (b *buffer) append(event cloudevents.Event) (dropped int, err error) {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+	line = append(line, '\n')
+	n := int64(len(line))
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if n > b.maxBytes {
+		return 0, errBufferFull
+	}
+	if b.size+n > b.maxBytes {
+		if !b.dropOldest {
+			return 0, errBufferFull
+		}
+		if dropped, err = b.drop(n); err != nil {
+			return dropped, err
+		}
+	}
+	if last := b.segments[len(b.segments)-1]; last.size > 0 && last.size+n > b.segmentBytes {
+		if err := b.rotate(); err != nil {
+			return dropped, err
+		}
+	}
+	if _, err := b.tail.Write(line); err != nil {
+		return dropped, err
+	}
+	if err := b.tail.Sync(); err != nil {
+		return dropped, err
+	}
+	b.segments[len(b.segments)-1].size += n
+	b.size += n
+
+	select {
+	case b.ready <- struct{}{}:
+	default:
+	}
+	return dropped, nil
+}
+
+// rotate starts a new segment.
+func (b *buffer) rotate() error {
+	seq := b.segments[len(b.segments)-1].seq + 1
+	tail, err := os.OpenFile(b.segmentPath(seq), os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := b.tail.Close(); err != nil {
+		tail.Close()
+		return err
+	}
+	b.tail = tail
+	b.segments = append(b.segments, bufferSegment{seq: seq})
+	return nil
+}
+
+// drop removes the oldest segments until n more bytes fit, and returns how
+// many undelivered events they held.
+func (b *buffer) drop(n int64) (int, error) {
+	dropped := 0
+	for b.size+n > b.maxBytes && b.pending() > 0 {
+		head := b.segments[0]
+		count, err := b.countLines(head.seq, b.offset)
+		if err != nil {
+			return dropped, err
+		}
+		dropped += count
+		if len(b.segments) == 1 {
+			if err := b.reset(); err != nil {
+				return dropped, err
+			}
+			break
+		}
+		if err := os.Remove(b.segmentPath(head.seq)); err != nil {
+			return dropped, err
+		}
+		b.segments = b.segments[1:]
+		b.size -= head.size
+		b.offset = 0
+	}
+	return dropped, b.saveCursor()
+}
+
+// reset empties the last remaining segment.
+func (b *buffer) reset() error {
+	if err := b.tail.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := b.tail.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	b.segments[0].size = 0
+	b.size = 0
+	b.offset = 0
+	return nil
+}
+
+func (b *buffer) countLines(seq, offset int64) (int, error) {
+	content, err := ioutil.ReadFile(b.segmentPath(seq))
+	if err != nil {
+		return 0, err
+	}
+	if offset > int64(len(content)) {
+		return 0, nil
+	}
+	return bytes.Count(content[offset:], []byte{'\n'}), nil
+}
+
+// peek returns the oldest buffered event and its position, or false when
+// every event was delivered. An event that cannot be decoded is returned
+// with its position and the error, so that it can be skipped.
+func (b *buffer) peek() (*cloudevents.Event, bufferPosition, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.pending() == 0 {
+		return nil, bufferPosition{}, false, nil
+	}
+	head := b.segments[0]
+	f, err := os.Open(b.segmentPath(head.seq))
+	if err != nil {
+		return nil, bufferPosition{}, false, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(b.offset, io.SeekStart); err != nil {
+		return nil, bufferPosition{}, false, err
+	}
+	line, err := bufio.NewReader(f).ReadBytes('\n')
+	if err != nil {
+		return nil, bufferPosition{}, false, err
+	}
+	pos := bufferPosition{seq: head.seq, offset: b.offset, n: int64(len(line))}
+	event := cloudevents.NewEvent()
+	if err := json.Unmarshal(line, &event); err != nil {
+		return nil, pos, true, err
+	}
+	return &event, pos, true, nil
+}
+
+// ack records that the event at the position was delivered. The events
+// dropped in the meantime are not acknowledged again.
+func (b *buffer) ack(pos bufferPosition) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.pending() == 0 || b.segments[0].seq != pos.seq || b.offset != pos.offset {
+		return nil
+	}
+	b.offset += pos.n
+	if b.offset >= b.segments[0].size {
+		if len(b.segments) == 1 {
+			if err := b.reset(); err != nil {
+				return err
+			}
+		} else {
+			if err := os.Remove(b.segmentPath(pos.seq)); err != nil {
+				return err
+			}
+			b.size -= b.segments[0].size
+			b.segments = b.segments[1:]
+			b.offset = 0
+		}
+	}
+	return b.saveCursor()
+}
+
+// saveCursor records the delivered part of the oldest segment. A cursor lost
+// when the adapter stops only makes it deliver some events again.
+func (b *buffer) saveCursor() error {
+	raw, err := json.Marshal(bufferCursor{Segment: b.segments[0].seq, Offset: b.offset})
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(b.dir, bufferCursorFile)
+	if err := ioutil.WriteFile(path+".tmp", raw, 0600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// sendOrBuffer sends the event to the sink, or appends it to the buffer when
+// the sink does not accept it, or when older events wait in the buffer. A
+// buffered event counts as delivered, so that the changes feed moves on.
+func (a *couchDbAdapter) sendOrBuffer(ctx context.Context, event cloudevents.Event) error {
+	if a.buffer.empty() {
+		err := a.sendNow(ctx, event)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		a.logger.Warnw("Event delivery failed, buffering it until the sink recovers", zap.String("id", event.ID()), zap.Error(err))
+	}
+	dropped, err := a.buffer.append(event)
+	if dropped > 0 {
+		a.logger.Warnw("The buffer is full, dropped its oldest events", zap.Int("dropped", dropped))
+		a.reportBufferDropped(dropped)
+	}
+	a.reportBufferBytes(a.buffer.bytes())
+	return err
+}
+
+// drainBuffer delivers the buffered events, oldest first, until ctx is done.
+func (a *couchDbAdapter) drainBuffer(ctx context.Context) {
+	a.reportBufferBytes(a.buffer.bytes())
+	for {
+		event, pos, ok, err := a.buffer.peek()
+		switch {
+		case err != nil && ok:
+			a.logger.Errorw("Dropping an undecodable buffered event", zap.Error(err))
+		case err != nil:
+			a.logger.Errorw("Unable to read the buffer", zap.Error(err))
+			if !wait(ctx, bufferRetryInterval) {
+				return
+			}
+			continue
+		case !ok:
+			select {
+			case <-ctx.Done():
+				return
+			case <-a.buffer.ready:
+			}
+			continue
+		default:
+			if err := a.sendNow(ctx, *event); err != nil {
+				a.logger.Warnw("Buffered event delivery failed", zap.String("id", event.ID()), zap.Error(err))
+				if !wait(ctx, bufferRetryInterval) {
+					return
+				}
+				continue
+			}
+		}
+		if err := a.buffer.ack(pos); err != nil {
+			a.logger.Errorw("Unable to record the delivery of a buffered event", zap.Error(err))
+		}
+		a.reportBufferBytes(a.buffer.bytes())
+	}
+}
+
+// wait waits for d, and returns false when ctx is done first.
+func wait(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
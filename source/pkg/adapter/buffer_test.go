@@ -0,0 +1,210 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"go.uber.org/zap"
+	kncetesting "knative.dev/eventing/pkg/adapter/v2/test"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+func bufferedEvent(id string) cloudevents.Event {
+	event := cloudevents.NewEvent()
+	event.SetID(id)
+	event.SetType("test")
+	event.SetSource("test")
+	return event
+}
+
+// drain peeks and acknowledges every buffered event, and returns their IDs.
+func drain(t *testing.T, b *buffer) []string {
+	t.Helper()
+	var ids []string
+	for {
+		event, pos, ok, err := b.peek()
+		if err != nil {
+			t.Fatalf("peek() = %v", err)
+		}
+		if !ok {
+			return ids
+		}
+		ids = append(ids, event.ID())
+		if err := b.ack(pos); err != nil {
+			t.Fatalf("ack() = %v", err)
+		}
+	}
+}
+
+func TestBuffer(t *testing.T) {
+	dir := t.TempDir()
+	env := &envConfig{BufferDir: dir, BufferMaxBytes: 1 << 20}
+	b, err := newBuffer(env)
+	if err != nil {
+		t.Fatalf("newBuffer() = %v", err)
+	}
+	// Small segments, to cover the rotations.
+	b.segmentBytes = 200
+	for i := 0; i < 10; i++ {
+		if _, err := b.append(bufferedEvent(fmt.Sprint(i))); err != nil {
+			t.Fatalf("append() = %v", err)
+		}
+	}
+	if len(b.segments) < 2 {
+		t.Fatalf("wrote %d segments, want several", len(b.segments))
+	}
+
+	// Deliver the first 3 events, then read the buffer back as after a
+	// restart.
+	for i := 0; i < 3; i++ {
+		_, pos, _, err := b.peek()
+		if err != nil {
+			t.Fatalf("peek() = %v", err)
+		}
+		if err := b.ack(pos); err != nil {
+			t.Fatalf("ack() = %v", err)
+		}
+	}
+	b.tail.Close()
+	if b, err = newBuffer(env); err != nil {
+		t.Fatalf("newBuffer() = %v", err)
+	}
+	if got, want := fmt.Sprint(drain(t, b)), "[3 4 5 6 7 8 9]"; got != want {
+		t.Errorf("buffered events = %s, want %s", got, want)
+	}
+	if !b.empty() {
+		t.Errorf("empty() = false after draining the buffer")
+	}
+
+	// The buffer is reusable once drained.
+	if _, err := b.append(bufferedEvent("10")); err != nil {
+		t.Fatalf("append() = %v", err)
+	}
+	if got, want := fmt.Sprint(drain(t, b)), "[10]"; got != want {
+		t.Errorf("buffered events = %s, want %s", got, want)
+	}
+}
+
+func TestBufferFull(t *testing.T) {
+	size := func(t *testing.T) int64 {
+		b, err := newBuffer(&envConfig{BufferDir: t.TempDir(), BufferMaxBytes: 1 << 20})
+		if err != nil {
+			t.Fatalf("newBuffer() = %v", err)
+		}
+		if _, err := b.append(bufferedEvent("0")); err != nil {
+			t.Fatalf("append() = %v", err)
+		}
+		return b.size
+	}(t)
+
+	testCases := map[string]struct {
+		onFull      v1alpha1.BufferFullPolicy
+		wantErr     error
+		wantDropped int
+		want        string
+	}{
+		"block": {
+			onFull:  v1alpha1.BufferFullBlock,
+			wantErr: errBufferFull,
+			want:    "[0 1 2 3 4 5 6 7]",
+		},
+		"drop oldest": {
+			onFull:      v1alpha1.BufferFullDropOldest,
+			wantDropped: 1,
+			want:        "[1 2 3 4 5 6 7 8]",
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			// Room for 8 events, a segment each.
+			b, err := newBuffer(&envConfig{
+				BufferDir:      t.TempDir(),
+				BufferMaxBytes: 8 * size,
+				BufferOnFull:   string(tc.onFull),
+			})
+			if err != nil {
+				t.Fatalf("newBuffer() = %v", err)
+			}
+			for i := 0; i < 8; i++ {
+				if _, err := b.append(bufferedEvent(fmt.Sprint(i))); err != nil {
+					t.Fatalf("append() = %v", err)
+				}
+			}
+			dropped, err := b.append(bufferedEvent("8"))
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("append() = %v, want %v", err, tc.wantErr)
+			}
+			if dropped != tc.wantDropped {
+				t.Errorf("append() dropped %d events, want %d", dropped, tc.wantDropped)
+			}
+			if got := fmt.Sprint(drain(t, b)); got != tc.want {
+				t.Errorf("buffered events = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSendBuffered(t *testing.T) {
+	b, err := newBuffer(&envConfig{BufferDir: t.TempDir(), BufferMaxBytes: 1 << 20})
+	if err != nil {
+		t.Fatalf("newBuffer() = %v", err)
+	}
+	ce := &failingSinkClient{TestCloudEventsClient: kncetesting.NewTestClient()}
+	a := &couchDbAdapter{
+		ce:       ce,
+		logger:   zap.NewNop().Sugar(),
+		delivery: &deliveryConfig{policy: "linear", delay: time.Millisecond},
+		buffer:   b,
+	}
+
+	// The sink is down: the events count as delivered once buffered.
+	for _, id := range []string{"1", "2"} {
+		if err := a.send(context.Background(), bufferedEvent(id)); err != nil {
+			t.Fatalf("send() = %v", err)
+		}
+	}
+	if ce.attempts != 1 {
+		t.Errorf("attempts = %d, want 1 before buffering the events in order", ce.attempts)
+	}
+
+	// The sink recovers: the buffer is delivered in order.
+	a.ce = kncetesting.NewTestClient()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go a.drainBuffer(ctx)
+	deadline := time.Now().Add(5 * time.Second)
+	for !b.empty() {
+		if time.Now().After(deadline) {
+			t.Fatal("the buffer was not drained")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	var ids []string
+	for _, event := range a.ce.(*kncetesting.TestCloudEventsClient).Sent() {
+		ids = append(ids, event.ID())
+	}
+	if got, want := fmt.Sprint(ids), "[1 2]"; got != want {
+		t.Errorf("delivered %s, want %s", got, want)
+	}
+}
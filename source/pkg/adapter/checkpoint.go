@@ -0,0 +1,174 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"sync"
+)
+
+// checkpoint tracks the sequence of the changes feed up to which every change
+// was accepted by the sink, or its dead letter sink. Events may be delivered
+// out of order by the batches and groups, so the sequence only advances over
+// the changes read before the oldest change still in flight. After a failed
+// delivery, the feed is read again from that sequence, which makes the
+// delivery at least once.
+type checkpoint struct {
+	mu    sync.Mutex
+	since string
+
+	// pending holds the changes read after since, in the order of the feed,
+	// and byID the ones in flight by the ID of the event carrying them.
+	pending []*pendingChange
+	byID    map[string][]*pendingChange
+	failed  bool
+}
+
+// pendingChange is a change read from the feed, possibly without a sequence
+// when CouchDB skipped it as asked by spec.seqInterval.
+type pendingChange struct {
+	seq  string
+	done bool
+}
+
+func newCheckpoint(since string) *checkpoint {
+	return &checkpoint{
+		since: since,
+		byID:  make(map[string][]*pendingChange),
+	}
+}
+
+// read records a change read from the feed. A change sent to the sink is in
+// flight until the event with the given ID is acknowledged, while the others
+// are done as soon as they are read.
+func (c *checkpoint) read(id, seq string, sent bool) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p := &pendingChange{seq: seq, done: !sent}
+	c.pending = append(c.pending, p)
+	if sent {
+		c.byID[id] = append(c.byID[id], p)
+	}
+	c.advance()
+}
+
+// merge makes the event with the given ID carry the changes of the events
+// coalesced into it.
+func (c *checkpoint) merge(into string, ids []string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var merged []*pendingChange
+	for _, id := range ids {
+		merged = append(merged, c.byID[id]...)
+		delete(c.byID, id)
+	}
+	c.byID[into] = append(c.byID[into], merged...)
+}
+
+// ack records the outcome of the delivery of the event with the given ID. The
+// IDs of events that carry no tracked change, e.g. backfilled documents, are
+// ignored.
+func (c *checkpoint) ack(id string, err error) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	changes, ok := c.byID[id]
+	if !ok {
+		return
+	}
+	delete(c.byID, id)
+	if err != nil {
+		c.failed = true
+		return
+	}
+	for _, p := range changes {
+		p.done = true
+	}
+	c.advance()
+}
+
+// advance moves the sequence over the changes done, up to the first change in
+// flight.
+func (c *checkpoint) advance() {
+	i := 0
+	for ; i < len(c.pending) && c.pending[i].done; i++ {
+		if seq := c.pending[i].seq; seq != "" {
+			c.since = seq
+		}
+	}
+	c.pending = c.pending[i:]
+}
+
+// rewind returns the sequence to read the feed from again when a delivery
+// failed, and forgets the changes in flight.
+func (c *checkpoint) rewind() (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.failed {
+		return "", false
+	}
+	c.reset(c.since)
+	return c.since, true
+}
+
+// failing returns whether a delivery failed since the feed was last read
+// again.
+func (c *checkpoint) failing() bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.failed
+}
+
+// restart starts tracking the changes after the given sequence.
+func (c *checkpoint) restart(since string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reset(since)
+}
+
+func (c *checkpoint) reset(since string) {
+	c.since = since
+	c.pending = nil
+	c.byID = make(map[string][]*pendingChange)
+	c.failed = false
+}
+
+// sequence returns the sequence up to which every change was delivered.
+func (c *checkpoint) sequence() string {
+	if c == nil {
+		return ""
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.since
+}
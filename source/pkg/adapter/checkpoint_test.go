@@ -0,0 +1,156 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/go-kivik/kivik/v3/driver"
+	"github.com/go-kivik/kivikmock/v3"
+	"knative.dev/eventing/pkg/adapter/v2"
+	kncetesting "knative.dev/eventing/pkg/adapter/v2/test"
+	pkgtesting "knative.dev/pkg/reconciler/testing"
+)
+
+// flakySinkClient fails the first delivery of the events with the given IDs.
+type flakySinkClient struct {
+	*kncetesting.TestCloudEventsClient
+	fail map[string]bool
+}
+
+func (c *flakySinkClient) Send(ctx context.Context, event cloudevents.Event) cloudevents.Result {
+	if c.fail[event.ID()] {
+		delete(c.fail, event.ID())
+		return errors.New("sink unavailable")
+	}
+	return c.TestCloudEventsClient.Send(ctx, event)
+}
+
+func TestCheckpoint(t *testing.T) {
+	c := newCheckpoint("0")
+
+	c.read("1-a", "1-a", true)
+	c.read("", "2-b", false)
+	c.read("3-c", "3-c", true)
+	c.read("d@1-rev", "", true)
+	if got := c.sequence(); got != "0" {
+		t.Errorf("sequence() = %q with 1-a in flight, want 0", got)
+	}
+
+	// Out of order acknowledgements wait for the oldest change in flight.
+	c.ack("3-c", nil)
+	if got := c.sequence(); got != "0" {
+		t.Errorf("sequence() = %q with 1-a in flight, want 0", got)
+	}
+	c.ack("1-a", nil)
+	if got := c.sequence(); got != "3-c" {
+		t.Errorf("sequence() = %q, want 3-c", got)
+	}
+
+	// A batch event carries the changes coalesced into it.
+	c.read("5-e", "5-e", true)
+	c.merge("5-e", []string{"d@1-rev", "5-e"})
+	c.ack("5-e", nil)
+	if got := c.sequence(); got != "5-e" {
+		t.Errorf("sequence() = %q, want 5-e", got)
+	}
+	if _, ok := c.rewind(); ok {
+		t.Error("rewind() = true without failure")
+	}
+
+	c.read("6-f", "6-f", true)
+	c.read("7-g", "7-g", true)
+	c.ack("7-g", nil)
+	c.ack("6-f", errors.New("sink unavailable"))
+	if since, ok := c.rewind(); !ok || since != "5-e" {
+		t.Errorf("rewind() = %q, %v, want 5-e, true", since, ok)
+	}
+	// The acknowledgements of the events sent before rewinding are ignored.
+	c.ack("7-g", nil)
+	c.read("", "8-h", false)
+	if got := c.sequence(); got != "8-h" {
+		t.Errorf("sequence() = %q, want 8-h", got)
+	}
+
+	// Untracked events, e.g. backfilled documents, are ignored.
+	var nilCheckpoint *checkpoint
+	nilCheckpoint.ack("doc@1-rev", nil)
+	c.ack("doc@1-rev", errors.New("sink unavailable"))
+	if _, ok := c.rewind(); ok {
+		t.Error("rewind() = true after the failure of an untracked event")
+	}
+}
+
+func TestReceiveEventRedelivery(t *testing.T) {
+	env := envConfig{
+		EnvConfig: adapter.EnvConfig{
+			Namespace: "default",
+		},
+		EventSource: "test-source",
+		Database:    "testdb",
+		Feed:        "normal",
+	}
+	ctx, _ := pkgtesting.SetupFakeContext(t)
+
+	c, mock := kivikmock.NewT(t)
+
+	mockDB := mock.NewDB()
+	mock.ExpectDB().WithName("testdb").WillReturn(mockDB)
+	newChanges := func(seqs ...string) *kivikmock.Changes {
+		changes := kivikmock.NewChanges()
+		for _, seq := range seqs {
+			changes.AddChange(&driver.Change{
+				ID:      "doc-" + seq,
+				Seq:     seq,
+				Changes: driver.ChangedRevs{"1-rev"},
+			})
+		}
+		return changes
+	}
+	mockDB.ExpectChanges().WillReturn(newChanges("1-a", "2-b", "3-c"))
+	mockDB.ExpectChanges().WillReturn(newChanges("2-b", "3-c").LastSeq("3-c"))
+
+	ce := &flakySinkClient{
+		TestCloudEventsClient: kncetesting.NewTestClient(),
+		fail:                  map[string]bool{"testdb/doc-2-b/1-rev": true},
+	}
+	a := newAdapter(ctx, &env, ce, c.DSN(), "kivikmock").(*couchDbAdapter)
+
+	// The failed delivery stops the response, and the feed is read again
+	// after the last change delivered.
+	a.processChanges(context.Background())
+	if got := a.options["since"]; got != "1-a" {
+		t.Errorf("since = %v, want 1-a", got)
+	}
+	if got := len(ce.Sent()); got != 1 {
+		t.Errorf("sent %d events, want 1", got)
+	}
+
+	a.processChanges(context.Background())
+	if got := a.options["since"]; got != "3-c" {
+		t.Errorf("since = %v, want 3-c", got)
+	}
+	if got := a.checkpoint.sequence(); got != "3-c" {
+		t.Errorf("checkpoint = %v, want 3-c", got)
+	}
+	if got := len(ce.Sent()); got != 3 {
+		t.Errorf("sent %d events, want 3", got)
+	}
+}
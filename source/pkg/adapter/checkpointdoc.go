@@ -0,0 +1,129 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-kivik/kivik/v3"
+	"go.uber.org/zap"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+// checkpointSaveInterval is how often, at most, the checkpoint is saved while
+// the changes are read.
+const checkpointSaveInterval = 5 * time.Second
+
+// checkpointDoc saves the checkpoint in a local document of the database, from
+// which a receive adapter scaled to zero resumes once scaled up again, and
+// from which the controller tells KEDA how many changes wait for the adapter.
+type checkpointDoc struct {
+	id string
+
+	// replayFrom is the sequence replayed by the replay annotation, saved
+	// along with the checkpoint so that a replay happens once.
+	replayFrom string
+
+	// rev is the current revision of the document, saved the sequence it
+	// holds, and last when it was last written.
+	rev   string
+	saved string
+	last  time.Time
+}
+
+// savedCheckpoint is the checkpoint saved in a local document, which is not
+// replicated nor reported by the changes feed. The sequence is read back as
+// saved by the adapters of CouchDB 1.x and BigCouch sources too.
+type savedCheckpoint struct {
+	Rev        string              `json:"_rev,omitempty"`
+	Sequence   v1alpha1.SequenceID `json:"sequence"`
+	ReplayFrom string              `json:"replayFrom,omitempty"`
+}
+
+func newCheckpointDoc(env *envConfig) *checkpointDoc {
+	if env.CheckpointDoc == "" {
+		return nil
+	}
+	return &checkpointDoc{id: env.CheckpointDoc, replayFrom: env.ReplayFrom}
+}
+
+// resumeCheckpoint reads the changes from the saved checkpoint, if any,
+// rather than from spec.since. A checkpoint saved before the replay
+// annotation changed is ignored, so that the replay happens.
+func (a *couchDbAdapter) resumeCheckpoint(ctx context.Context) {
+	d := a.checkpointDoc
+	if d == nil {
+		return
+	}
+	var saved savedCheckpoint
+	err := a.couchDB.Get(ctx, d.id).ScanDoc(&saved)
+	switch {
+	case kivik.StatusCode(err) == http.StatusNotFound:
+	case err != nil:
+		a.logger.Warnw("Unable to read the saved checkpoint, reading the changes from the start of the source", zap.String("id", d.id), zap.Error(err))
+	default:
+		d.rev = saved.Rev
+		if saved.ReplayFrom != d.replayFrom || saved.Sequence == "" {
+			return
+		}
+		since := string(saved.Sequence)
+		d.saved = since
+		a.logger.Infow("Resuming from the saved checkpoint", zap.String("since", since))
+		a.options["since"] = since
+		a.checkpoint.restart(since)
+	}
+}
+
+// saveCheckpoint writes the checkpoint when it advanced, at most every
+// checkpointSaveInterval unless forced. A checkpoint that cannot be saved only
+// makes a restarted receive adapter read some changes again.
+func (a *couchDbAdapter) saveCheckpoint(ctx context.Context, now time.Time, force bool) {
+	d := a.checkpointDoc
+	if d == nil {
+		return
+	}
+	seq := a.checkpoint.sequence()
+	if seq == "" || seq == v1alpha1.SequenceNow || seq == d.saved {
+		return
+	}
+	if !force && now.Sub(d.last) < checkpointSaveInterval {
+		return
+	}
+	d.last = now
+
+	rev, err := a.couchDB.Put(ctx, d.id, &savedCheckpoint{
+		Rev:        d.rev,
+		Sequence:   v1alpha1.SequenceID(seq),
+		ReplayFrom: d.replayFrom,
+	})
+	if err != nil {
+		a.logger.Warnw("Unable to save the checkpoint", zap.String("id", d.id), zap.Error(err))
+		if kivik.StatusCode(err) == http.StatusConflict {
+			// Another replica saved it while holding the lease, catch up
+			// with its revision.
+			if _, rev, err := a.couchDB.GetMeta(ctx, d.id); err == nil {
+				d.rev = rev
+			}
+		}
+		return
+	}
+	d.rev = rev
+	d.saved = seq
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kivik/kivik/v3"
+	"github.com/go-kivik/kivik/v3/driver"
+	"github.com/go-kivik/kivikmock/v3"
+	"knative.dev/eventing/pkg/adapter/v2"
+	kncetesting "knative.dev/eventing/pkg/adapter/v2/test"
+	pkgtesting "knative.dev/pkg/reconciler/testing"
+)
+
+func TestCheckpointDoc(t *testing.T) {
+	testCases := map[string]struct {
+		saved      string
+		replayFrom string
+		wantSince  string
+	}{
+		"nothing saved": {
+			wantSince: "1-a",
+		},
+		"saved": {
+			saved:     `{"_id":"_local/knative-checkpoint-1234","_rev":"0-4","sequence":"7-g"}`,
+			wantSince: "7-g",
+		},
+		"saved by a couchdb 1.x source": {
+			saved:     `{"_id":"_local/knative-checkpoint-1234","_rev":"0-4","sequence":7}`,
+			wantSince: "7",
+		},
+		"saved by a bigcouch source": {
+			saved:     `{"_id":"_local/knative-checkpoint-1234","_rev":"0-4","sequence":[7,"g1AAAA"]}`,
+			wantSince: `[7,"g1AAAA"]`,
+		},
+		"saved during the replay": {
+			saved:      `{"_id":"_local/knative-checkpoint-1234","_rev":"0-4","sequence":"7-g","replayFrom":"3-c"}`,
+			replayFrom: "3-c",
+			wantSince:  "7-g",
+		},
+		"saved before the replay": {
+			saved:      `{"_id":"_local/knative-checkpoint-1234","_rev":"0-4","sequence":"7-g"}`,
+			replayFrom: "3-c",
+			wantSince:  "3-c",
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			env := envConfig{
+				EnvConfig: adapter.EnvConfig{
+					Namespace: "default",
+				},
+				EventSource:   "test-source",
+				Database:      "testdb",
+				Feed:          "normal",
+				Since:         "1-a",
+				ReplayFrom:    tc.replayFrom,
+				CheckpointDoc: "_local/knative-checkpoint-1234",
+			}
+			ctx, _ := pkgtesting.SetupFakeContext(t)
+
+			c, mock := kivikmock.NewT(t)
+
+			mockDB := mock.NewDB()
+			mock.ExpectDB().WithName("testdb").WillReturn(mockDB)
+			get := mockDB.ExpectGet().WithDocID("_local/knative-checkpoint-1234")
+			wantRev := ""
+			if tc.saved == "" {
+				get.WillReturnError(&kivik.Error{HTTPStatus: http.StatusNotFound})
+			} else {
+				wantRev = "0-4"
+				get.WillReturn(&driver.Document{
+					Rev:  wantRev,
+					Body: ioutil.NopCloser(strings.NewReader(tc.saved)),
+				})
+			}
+			mockDB.ExpectChanges().WillReturn(kivikmock.NewChanges().AddChange(&driver.Change{
+				ID:      "doc",
+				Seq:     "8-h",
+				Changes: driver.ChangedRevs{"1-rev"},
+			}))
+			mockDB.ExpectPut().WithDocID("_local/knative-checkpoint-1234").WithDoc(&savedCheckpoint{
+				Rev:        wantRev,
+				Sequence:   "8-h",
+				ReplayFrom: tc.replayFrom,
+			}).WillReturn("0-5")
+
+			a := newAdapter(ctx, &env, kncetesting.NewTestClient(), c.DSN(), "kivikmock").(*couchDbAdapter)
+			a.resumeCheckpoint(context.Background())
+			if got := a.options["since"]; got != tc.wantSince {
+				t.Errorf("since = %v, want %v", got, tc.wantSince)
+			}
+
+			a.processChanges(context.Background())
+			a.saveCheckpoint(context.Background(), time.Now(), false)
+			// Not saved again until the checkpoint advances.
+			a.saveCheckpoint(context.Background(), time.Now().Add(checkpointSaveInterval), true)
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+// conflicts returns the conflicting revisions of the changed document. The
+// document only carries them when the feed is requested with conflicts=true.
+func conflicts(changes change) []string {
+	var doc struct {
+		Conflicts []string `json:"_conflicts"`
+	}
+	if err := changes.ScanDoc(&doc); err != nil {
+		return nil
+	}
+	return doc.Conflicts
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"io/ioutil"
+	"time"
+
+	"go.uber.org/zap"
+	"knative.dev/eventing/pkg/adapter/v2"
+)
+
+const (
+	// credentialsPollInterval is how often the receive adapter checks the
+	// credentials Secret mounted in its pod for changes. The kubelet updates
+	// the Secret volumes about a minute after the Secret changes.
+	credentialsPollInterval = 10 * time.Second
+
+	// tenantCredentialsPollInterval is how often the multi-tenant adapter
+	// reads the credentials Secret of a source again, through the API
+	// server.
+	tenantCredentialsPollInterval = time.Minute
+)
+
+// reloadingAdapter runs the adapter of a source, and replaces it when the
+// url of its credentials changes, e.g. when the password or the API key is
+// rotated, so that the rotation needs neither a new source nor a restarted
+// pod. The new adapter connects with the new credentials before the current
+// one stops, and carries on from where the current one stopped.
+type reloadingAdapter struct {
+	logger   *zap.SugaredLogger
+	adapter  *couchDbAdapter
+	url      string
+	interval time.Duration
+
+	// readURL reads the url of the credentials.
+	readURL func(ctx context.Context) (string, error)
+
+	// build connects to CouchDB with the url and configures an adapter.
+	build func(url string) (*couchDbAdapter, error)
+}
+
+var _ adapter.Adapter = (*reloadingAdapter)(nil)
+
+// Start runs the adapter until ctx is done or the adapter returns, e.g. once
+// its window is exhausted.
+func (r *reloadingAdapter) Start(ctx context.Context) error {
+	for {
+		runCtx, cancel := context.WithCancel(ctx)
+		done := make(chan error, 1)
+		go func(a *couchDbAdapter) {
+			done <- a.Start(runCtx)
+		}(r.adapter)
+
+		next, url, err := r.waitForRotation(ctx, done)
+		cancel()
+		if next == nil {
+			return err
+		}
+		// Two adapters of a source must not deliver its changes at once.
+		if err := <-done; err != nil {
+			r.logger.Warnw("The adapter with the previous credentials failed", zap.Error(err))
+		}
+		next.resumeFrom(r.adapter)
+		r.adapter, r.url = next, url
+		r.logger.Info("Reconnected to CouchDB with the rotated credentials")
+	}
+}
+
+// waitForRotation returns an adapter connected with the new credentials once
+// they change, or the outcome of the current adapter once it returns.
+func (r *reloadingAdapter) waitForRotation(ctx context.Context, done <-chan error) (*couchDbAdapter, string, error) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-done:
+			return nil, "", err
+		case <-ticker.C:
+		}
+		url, err := r.readURL(ctx)
+		if err != nil {
+			r.logger.Warnw("Unable to read the credentials", zap.Error(err))
+			continue
+		}
+		if url == r.url {
+			continue
+		}
+		next, err := r.build(url)
+		if err != nil {
+			// The rotation may not be over, e.g. when the Secret changed
+			// before the password, so try again later.
+			r.logger.Errorw("Unable to connect to CouchDB with the rotated credentials, keeping the current ones", zap.Error(err))
+			continue
+		}
+		return next, url, nil
+	}
+}
+
+// resumeFrom makes the adapter carry on from where the previous adapter of
+// the source stopped: from the sequence up to which it delivered the changes,
+// with its window, backfill and delivery statistics.
+func (a *couchDbAdapter) resumeFrom(previous *couchDbAdapter) {
+	since := previous.checkpoint.sequence()
+	if since == "" {
+		since, _ = previous.options["since"].(string)
+	}
+	if since != "" {
+		a.options["since"] = since
+		a.checkpoint.restart(since)
+	}
+	a.window = previous.window
+	a.backfill = previous.backfill
+	a.stats = previous.stats
+}
+
+// readCredentialsURL reads the url of the credentials Secret mounted in dir.
+func readCredentialsURL(dir string) (string, error) {
+	url, err := ioutil.ReadFile(dir + "/url")
+	if err != nil {
+		return "", err
+	}
+	return string(url), nil
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	cdbevents "knative.dev/eventing-couchdb/source/pkg/events"
+)
+
+// databaseUpdatesRetryInterval is how long the adapter waits before reading
+// the _db_updates feed again once it failed or was closed.
+const databaseUpdatesRetryInterval = 5 * time.Second
+
+// runDatabaseUpdates reports the updates of the _db_updates feed until ctx is
+// done. The feed starts from now whenever it is read again, so the updates
+// made while it was closed are not reported, and neither are the ones whose
+// delivery failed: the database events are not checkpointed.
+func (a *couchDbAdapter) runDatabaseUpdates(ctx context.Context) {
+	for {
+		if err := a.processDatabaseUpdates(ctx); err != nil && ctx.Err() == nil {
+			a.logger.Warnw("Error reading the _db_updates feed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(databaseUpdatesRetryInterval):
+		}
+	}
+}
+
+// processDatabaseUpdates reports the updates of the _db_updates feed until it
+// is closed.
+func (a *couchDbAdapter) processDatabaseUpdates(ctx context.Context) error {
+	updates, err := a.dbUpdates.DBUpdates(ctx)
+	if err != nil {
+		return err
+	}
+	defer updates.Close()
+
+	for updates.Next() {
+		update := cdbevents.DatabaseUpdate{
+			Database: updates.DBName(),
+			Type:     updates.Type(),
+			Seq:      updates.Seq(),
+		}
+		if cdbevents.DatabaseEventType(update.Type) == "" {
+			a.logger.Debugw("Ignoring the database update", zap.String("database", update.Database), zap.String("type", update.Type))
+			continue
+		}
+		event, err := cdbevents.NewDatabaseEvent(a.source, update)
+		if err != nil {
+			a.logger.Errorw("Error making the database event", zap.String("database", update.Database), zap.Error(err))
+			continue
+		}
+		if err := a.send(ctx, event); err != nil {
+			a.logger.Errorw("event delivery failed", zap.String("id", event.ID()), zap.Error(err))
+		}
+	}
+	return updates.Err()
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kivik/kivik/v3/driver"
+	"github.com/go-kivik/kivikmock/v3"
+	"knative.dev/eventing/pkg/adapter/v2"
+	kncetesting "knative.dev/eventing/pkg/adapter/v2/test"
+	pkgtesting "knative.dev/pkg/reconciler/testing"
+
+	cdbevents "knative.dev/eventing-couchdb/source/pkg/events"
+)
+
+func TestProcessDatabaseUpdates(t *testing.T) {
+	env := envConfig{
+		EnvConfig: adapter.EnvConfig{
+			Namespace: "default",
+		},
+		EventSource:     "test-source",
+		Database:        "testdb",
+		Feed:            "continuous",
+		DatabaseUpdates: true,
+	}
+	ctx, _ := pkgtesting.SetupFakeContext(t)
+
+	c, mock := kivikmock.NewT(t)
+	mock.ExpectDB().WithName("testdb").WillReturn(mock.NewDB())
+	mock.ExpectDBUpdates().WillReturn(kivikmock.NewDBUpdates().
+		AddUpdate(&driver.DBUpdate{DBName: "tenant-1", Type: "created", Seq: "1-g1A"}).
+		AddUpdate(&driver.DBUpdate{DBName: "tenant-1", Type: "ddoc_updated", Seq: "2-g1A"}).
+		AddUpdate(&driver.DBUpdate{DBName: "tenant-2", Type: "deleted", Seq: "3-g1A"}))
+
+	ce := kncetesting.NewTestClient()
+	a := newAdapter(ctx, &env, ce, c.DSN(), "kivikmock").(*couchDbAdapter)
+	if err := a.processDatabaseUpdates(context.Background()); err != nil {
+		t.Fatalf("processDatabaseUpdates() = %v", err)
+	}
+
+	want := []struct{ eventType, subject string }{
+		{cdbevents.DatabaseCreatedEventType, "tenant-1"},
+		{cdbevents.DatabaseDeletedEventType, "tenant-2"},
+	}
+	sent := ce.Sent()
+	if len(sent) != len(want) {
+		t.Fatalf("Sent %d events, want %d", len(sent), len(want))
+	}
+	for i, w := range want {
+		if sent[i].Type() != w.eventType || sent[i].Subject() != w.subject || sent[i].Source() != "test-source" {
+			t.Errorf("event %d = %s %s %s, want %s %s test-source", i, sent[i].Type(), sent[i].Subject(), sent[i].Source(), w.eventType, w.subject)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"go.uber.org/zap"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	cdbevents "knative.dev/eventing-couchdb/source/pkg/events"
+)
+
+// decodeLogInterval is the minimum interval between the logs of the changes
+// that could not be turned into events. The errors in between are counted in
+// the next log, so that a database full of bad documents does not flood the
+// logs.
+const decodeLogInterval = 10 * time.Second
+
+// decodeErrors applies spec.onDecodeError to the changes that could not be
+// turned into events, and samples their logs.
+type decodeErrors struct {
+	policy v1alpha1.DecodeErrorPolicy
+
+	mu         sync.Mutex
+	lastLog    time.Time
+	suppressed int
+}
+
+func newDecodeErrors(env *envConfig) (*decodeErrors, error) {
+	d := &decodeErrors{policy: v1alpha1.DecodeErrorPolicy(env.OnDecodeError)}
+	switch d.policy {
+	case "":
+		d.policy = v1alpha1.DecodeErrorSkip
+	case v1alpha1.DecodeErrorSkip, v1alpha1.DecodeErrorFail:
+	case v1alpha1.DecodeErrorDeadLetter:
+		if env.DeadLetterSink == "" {
+			return nil, fmt.Errorf("%s requires a dead letter sink", d.policy)
+		}
+	default:
+		return nil, fmt.Errorf("unknown policy %q", env.OnDecodeError)
+	}
+	return d, nil
+}
+
+// policyOrDefault returns the policy, skip when d is nil.
+func (d *decodeErrors) policyOrDefault() v1alpha1.DecodeErrorPolicy {
+	if d == nil {
+		return v1alpha1.DecodeErrorSkip
+	}
+	return d.policy
+}
+
+// sample returns whether the error happening now is logged, and how many
+// errors were not logged since the last one.
+func (d *decodeErrors) sample(now time.Time) (bool, int) {
+	if d == nil {
+		return true, 0
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.lastLog.IsZero() && now.Sub(d.lastLog) < decodeLogInterval {
+		d.suppressed++
+		return false, 0
+	}
+	suppressed := d.suppressed
+	d.lastLog = now
+	d.suppressed = 0
+	return true, suppressed
+}
+
+// decodeFailed handles a change that could not be turned into an event, as
+// spec.onDecodeError says. It returns an error when the change must be read
+// again.
+func (a *couchDbAdapter) decodeFailed(changes change, err error) error {
+	policy := a.decodeErrors.policyOrDefault()
+	a.reportDecodeError(policy)
+	if log, suppressed := a.decodeErrors.sample(time.Now()); log {
+		a.logger.Errorw("Unable to make the event of a change",
+			zap.String("id", changes.ID()), zap.String("policy", string(policy)),
+			zap.Int("suppressed", suppressed), zap.Error(err))
+		a.events.decodeFailed(changes.ID(), policy, suppressed, err)
+	}
+
+	switch policy {
+	case v1alpha1.DecodeErrorFail:
+		// The feed is read again from the change.
+		err = fmt.Errorf("unable to make the event of %s: %w", changes.ID(), err)
+	case v1alpha1.DecodeErrorDeadLetter:
+		err = a.sendUndecodable(changes, err)
+	default:
+		err = nil
+	}
+	a.checkpoint.ack(a.eventID(changes), err)
+	return err
+}
+
+// sendUndecodable sends an undecodable event describing the change to the
+// dead letter sink.
+func (a *couchDbAdapter) sendUndecodable(changes change, decodeErr error) error {
+	event, err := cdbevents.NewUndecodableEvent(a.source, cdbevents.DecodeErrorData{
+		ID:       changes.ID(),
+		Rev:      firstRev(changes.Changes()),
+		Seq:      changes.Seq(),
+		Deleted:  changes.Deleted(),
+		Database: a.database,
+		Error:    decodeErr.Error(),
+	})
+	if err != nil {
+		return err
+	}
+	event.SetID(a.eventID(changes))
+	ctx := cloudevents.ContextWithTarget(context.Background(), a.delivery.deadLetterSink)
+	if result := a.ce.Send(ctx, event); !cloudevents.IsACK(result) {
+		return fmt.Errorf("delivery of the undecodable event of %s to the dead letter sink failed: %w", changes.ID(), result)
+	}
+	a.stats.recordDeadLettered()
+	return nil
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	kncetesting "knative.dev/eventing/pkg/adapter/v2/test"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	cdbevents "knative.dev/eventing-couchdb/source/pkg/events"
+)
+
+func TestNewDecodeErrors(t *testing.T) {
+	testCases := map[string]struct {
+		env     envConfig
+		want    v1alpha1.DecodeErrorPolicy
+		wantErr bool
+	}{
+		"default": {
+			want: v1alpha1.DecodeErrorSkip,
+		},
+		"fail": {
+			env:  envConfig{OnDecodeError: "fail"},
+			want: v1alpha1.DecodeErrorFail,
+		},
+		"deadLetter": {
+			env:  envConfig{OnDecodeError: "deadLetter", DeadLetterSink: "http://dls"},
+			want: v1alpha1.DecodeErrorDeadLetter,
+		},
+		"deadLetter without dead letter sink": {
+			env:     envConfig{OnDecodeError: "deadLetter"},
+			wantErr: true,
+		},
+		"unknown": {
+			env:     envConfig{OnDecodeError: "crash"},
+			wantErr: true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			got, err := newDecodeErrors(&tc.env)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("newDecodeErrors() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err == nil && got.policy != tc.want {
+				t.Errorf("policy = %q, want %q", got.policy, tc.want)
+			}
+		})
+	}
+}
+
+func TestDecodeErrorsSample(t *testing.T) {
+	d := &decodeErrors{}
+	now := time.Now()
+	if log, _ := d.sample(now); !log {
+		t.Error("sample() = false for the first error, want true")
+	}
+	for i := 0; i < 3; i++ {
+		if log, _ := d.sample(now.Add(time.Second)); log {
+			t.Error("sample() = true within the log interval, want false")
+		}
+	}
+	log, suppressed := d.sample(now.Add(decodeLogInterval))
+	if !log || suppressed != 3 {
+		t.Errorf("sample() = %v, %d after the log interval, want true, 3", log, suppressed)
+	}
+}
+
+func TestEmitDecodeError(t *testing.T) {
+	testCases := map[string]struct {
+		policy         v1alpha1.DecodeErrorPolicy
+		wantErr        bool
+		wantDeadLetter bool
+	}{
+		"skip": {
+			policy: v1alpha1.DecodeErrorSkip,
+		},
+		"deadLetter": {
+			policy:         v1alpha1.DecodeErrorDeadLetter,
+			wantDeadLetter: true,
+		},
+		"fail": {
+			policy:  v1alpha1.DecodeErrorFail,
+			wantErr: true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			ce := kncetesting.NewTestClient()
+			a := &couchDbAdapter{
+				ce:           ce,
+				logger:       zap.NewNop().Sugar(),
+				source:       "http://couchdb/testdb",
+				database:     "testdb",
+				eventType:    &v1alpha1.EventTypeTemplate{},
+				subject:      &v1alpha1.SubjectTemplate{},
+				delivery:     &deliveryConfig{deadLetterSink: "http://dls.example.com"},
+				attachments:  string(v1alpha1.AttachmentsNone),
+				decodeErrors: &decodeErrors{policy: tc.policy},
+				checkpoint:   newCheckpoint("0"),
+			}
+
+			// The document is not an object.
+			doc := &backfillDoc{id: "doc", rev: "1-a", doc: []byte(`[1, 2]`)}
+			a.checkpoint.read(a.eventID(doc), "1-a", true)
+			err := a.emit(context.Background(), doc)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("emit() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got := a.checkpoint.failing(); got != tc.wantErr {
+				t.Errorf("failing() = %v, want %v", got, tc.wantErr)
+			}
+
+			sent := ce.Sent()
+			if !tc.wantDeadLetter {
+				if len(sent) != 0 {
+					t.Errorf("sent %d events, want none", len(sent))
+				}
+				return
+			}
+			if len(sent) != 1 {
+				t.Fatalf("sent %d events, want 1", len(sent))
+			}
+			data, err := cdbevents.DecodeError(sent[0])
+			if err != nil {
+				t.Fatalf("DecodeError() = %v", err)
+			}
+			if data.ID != "doc" || data.Rev != "1-a" || data.Database != "testdb" || data.Error == "" {
+				t.Errorf("decode error data = %+v", data)
+			}
+		})
+	}
+}
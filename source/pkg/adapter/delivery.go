@@ -0,0 +1,298 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/binding"
+	cecontext "github.com/cloudevents/sdk-go/v2/context"
+	"github.com/cloudevents/sdk-go/v2/protocol"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+	"github.com/rickb777/date/period"
+	"go.uber.org/zap"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	cdbevents "knative.dev/eventing-couchdb/source/pkg/events"
+)
+
+const (
+	// defaultBackoffDelay is used when retries are requested without a
+	// spec.delivery.backoffDelay, by the sources stored before the webhook
+	// defaulted it.
+	defaultBackoffDelay = v1alpha1.DefaultBackoffDelay
+)
+
+// deliveryConfig is the adapter side of spec.delivery.
+type deliveryConfig struct {
+	retries        int
+	policy         eventingduckv1.BackoffPolicyType
+	delay          time.Duration
+	sink           string
+	sinkHost       string
+	deadLetterSink string
+
+	// replySink, when set, receives the events the sink responds with.
+	replySink string
+}
+
+func newDeliveryConfig(env *envConfig) (*deliveryConfig, error) {
+	d := &deliveryConfig{
+		retries:        env.DeliveryRetry,
+		policy:         eventingduckv1.BackoffPolicyType(env.DeliveryBackoffPolicy),
+		delay:          defaultBackoffDelay,
+		sink:           env.Sink,
+		deadLetterSink: env.DeadLetterSink,
+		replySink:      env.ReplySink,
+	}
+	if d.sink != "" {
+		u, err := url.Parse(d.sink)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sink %q: %v", d.sink, err)
+		}
+		d.sinkHost = u.Host
+	}
+	if d.policy == "" {
+		d.policy = eventingduckv1.BackoffPolicyExponential
+	}
+	if env.DeliveryBackoffDelay != "" {
+		p, err := period.Parse(env.DeliveryBackoffDelay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid backoff delay %q: %v", env.DeliveryBackoffDelay, err)
+		}
+		d.delay = p.DurationApprox()
+	}
+	return d, nil
+}
+
+// retryParams are the CloudEvents retry parameters matching the delivery
+// configuration.
+func (d *deliveryConfig) retryParams() *cecontext.RetryParams {
+	strategy := cecontext.BackoffStrategy(cecontext.BackoffStrategyExponential)
+	if d.policy == eventingduckv1.BackoffPolicyLinear {
+		strategy = cecontext.BackoffStrategyLinear
+	}
+	return &cecontext.RetryParams{
+		Strategy: strategy,
+		Period:   d.delay,
+		MaxTries: d.retries,
+	}
+}
+
+// retriableStatusCodes are the sink responses worth retrying, as in the
+// CloudEvents HTTP protocol.
+var retriableStatusCodes = map[int]bool{
+	http.StatusNotFound:              true,
+	http.StatusRequestEntityTooLarge: true,
+	http.StatusTooEarly:              true,
+	http.StatusTooManyRequests:       true,
+	http.StatusBadGateway:            true,
+	http.StatusServiceUnavailable:    true,
+	http.StatusGatewayTimeout:        true,
+}
+
+// maxRejections is how many times an event the sink rejects for good is
+// delivered, the changes feed being read again from it in between, before it
+// is skipped for want of a dead letter sink.
+const maxRejections = 3
+
+// rejections counts the deliveries of the events the sink rejected for good,
+// by event ID.
+type rejections struct {
+	mu   sync.Mutex
+	byID map[string]int
+}
+
+func newRejections() *rejections {
+	return &rejections{byID: make(map[string]int)}
+}
+
+// add records that the sink rejected the event, and returns how many times
+// it did so far.
+func (r *rejections) add(id string) int {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.byID[id]++
+	return r.byID[id]
+}
+
+// forget drops the count of the event once it is delivered or skipped.
+func (r *rejections) forget(id string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.byID, id)
+}
+
+func newDeliveryAttempt(t time.Time, result error) cdbevents.DeliveryAttempt {
+	attempt := cdbevents.DeliveryAttempt{
+		Time:       t.UTC(),
+		ErrorClass: "network",
+		Error:      result.Error(),
+	}
+	var httpResult *cehttp.Result
+	var netErr net.Error
+	switch {
+	case protocol.ResultAs(result, &httpResult):
+		attempt.StatusCode = httpResult.StatusCode
+		if httpResult.StatusCode >= 500 {
+			attempt.ErrorClass = "server"
+		} else {
+			attempt.ErrorClass = "client"
+		}
+	case errors.Is(result, context.DeadlineExceeded),
+		errors.As(result, &netErr) && netErr.Timeout():
+		attempt.ErrorClass = "timeout"
+	}
+	return attempt
+}
+
+// retriable is whether the delivery is worth trying again after the attempt.
+func retriable(attempt cdbevents.DeliveryAttempt) bool {
+	return attempt.StatusCode == 0 || retriableStatusCodes[attempt.StatusCode]
+}
+
+// sendToSink sends the event to the sink and, with a reply sink, forwards the
+// event the sink responds with. As with a Subscription, a reply that cannot
+// be forwarded fails the delivery of the event.
+func (a *couchDbAdapter) sendToSink(ctx context.Context, event cloudevents.Event) error {
+	if a.delivery.replySink == "" {
+		return a.ce.Send(ctx, event)
+	}
+	reply, result := a.ce.Request(ctx, event)
+	if !cloudevents.IsACK(result) || reply == nil {
+		return result
+	}
+	if result := a.ce.Send(cloudevents.ContextWithTarget(ctx, a.delivery.replySink), *reply); !cloudevents.IsACK(result) {
+		return fmt.Errorf("forwarding the reply %s: %w", reply.ID(), result)
+	}
+	return nil
+}
+
+// send delivers the event, through the buffer of spec.buffer when the sink
+// does not accept it.
+func (a *couchDbAdapter) send(ctx context.Context, event cloudevents.Event) error {
+	if a.buffer == nil {
+		return a.sendNow(ctx, event)
+	}
+	return a.sendOrBuffer(ctx, event)
+}
+
+// sendNow delivers the event to the sink, retrying as configured, and falls
+// back to the dead letter sink once retries are exhausted. Dead lettered
+// events carry the history of the failed attempts in the couchdbattempts
+// extension. Without a dead letter sink, an event the sink rejects for good
+// is skipped once it was rejected maxRejections times. A sink answering
+// with a Retry-After is not retried but waited for, without counting
+// against the retries. With the structured content mode, the event is sent
+// as application/cloudevents+json rather than in binary mode.
+func (a *couchDbAdapter) sendNow(ctx context.Context, event cloudevents.Event) error {
+	if a.structured {
+		ctx = binding.WithForceStructured(ctx)
+	}
+	if err := a.throttle(ctx, 1); err != nil {
+		return err
+	}
+	params := a.delivery.retryParams()
+	var attempts []cdbevents.DeliveryAttempt
+	var result error
+	for tries := 0; ; {
+		if err := a.waitForSink(ctx); err != nil {
+			return err
+		}
+		start := time.Now()
+		if result = a.sendToSink(ctx, event); cloudevents.IsACK(result) {
+			a.stats.recordDelivered(time.Now(), 1)
+			a.reportEmitted(event, time.Since(start))
+			a.rejections.forget(event.ID())
+			return nil
+		}
+		attempt := newDeliveryAttempt(start, result)
+		a.reportDeliveryError(attempt, time.Since(start))
+		if busy(attempt.StatusCode) && a.sinkPaused() {
+			continue
+		}
+		attempts = append(attempts, attempt)
+		if !retriable(attempt) {
+			break
+		}
+		tries++
+		if tries > params.MaxTries {
+			// Without retries, there is no budget to exhaust.
+			if params.MaxTries > 0 {
+				a.reportRetryBudgetExhausted(attempt.ErrorClass)
+			}
+			break
+		}
+		if params.Backoff(ctx, tries) != nil {
+			break
+		}
+		a.reportRetry(attempt.ErrorClass)
+	}
+	if a.delivery.deadLetterSink == "" {
+		return a.rejected(event, attempts[len(attempts)-1], result)
+	}
+
+	a.logger.Warnw("Event delivery failed, sending it to the dead letter sink",
+		zap.String("id", event.ID()), zap.Int("attempts", len(attempts)), zap.Error(result))
+	dead := event.Clone()
+	history, err := json.Marshal(attempts)
+	if err != nil {
+		return err
+	}
+	dead.SetExtension(cdbevents.AttemptsExtension, string(history))
+	if last := attempts[len(attempts)-1]; last.StatusCode != 0 {
+		dead.SetExtension(cdbevents.ErrorCodeExtension, last.StatusCode)
+	}
+	if a.delivery.sink != "" {
+		dead.SetExtension(cdbevents.ErrorDestExtension, a.delivery.sink)
+	}
+	if dlResult := a.ce.Send(cloudevents.ContextWithTarget(ctx, a.delivery.deadLetterSink), dead); !cloudevents.IsACK(dlResult) {
+		return fmt.Errorf("delivery to the dead letter sink failed: %w (original failure: %v)", dlResult, result)
+	}
+	a.stats.recordDeadLettered()
+	return nil
+}
+
+// rejected returns the error of the failed delivery of the event without a
+// dead letter sink, unless the sink rejected it for good too many times: the
+// event is then skipped, so that it no longer holds the source.
+func (a *couchDbAdapter) rejected(event cloudevents.Event, last cdbevents.DeliveryAttempt, result error) error {
+	if retriable(last) || a.rejections.add(event.ID()) < maxRejections {
+		return result
+	}
+	a.logger.Errorw("The sink keeps rejecting the event, skipping it",
+		zap.String("id", event.ID()), zap.Int("status", last.StatusCode), zap.Int("rejections", maxRejections), zap.Error(result))
+	a.rejections.forget(event.ID())
+	a.reportSkipped(last.ErrorClass)
+	return nil
+}
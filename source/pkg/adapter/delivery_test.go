@@ -0,0 +1,483 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	cecontext "github.com/cloudevents/sdk-go/v2/context"
+	"github.com/cloudevents/sdk-go/v2/protocol"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+	"go.opencensus.io/stats/view"
+	"go.uber.org/zap"
+	kncetesting "knative.dev/eventing/pkg/adapter/v2/test"
+	_ "knative.dev/pkg/metrics/testing"
+
+	cdbevents "knative.dev/eventing-couchdb/source/pkg/events"
+)
+
+// failingSinkClient fails every delivery that does not target the dead
+// letter sink.
+type failingSinkClient struct {
+	*kncetesting.TestCloudEventsClient
+	deadLetterSink string
+	attempts       int
+}
+
+func (c *failingSinkClient) Send(ctx context.Context, event cloudevents.Event) cloudevents.Result {
+	if target := cecontext.TargetFrom(ctx); target != nil && target.String() == c.deadLetterSink {
+		return c.TestCloudEventsClient.Send(ctx, event)
+	}
+	c.attempts++
+	return errors.New("sink unavailable")
+}
+
+// statusSinkClient answers every delivery with the status.
+type statusSinkClient struct {
+	*kncetesting.TestCloudEventsClient
+	status   int
+	attempts int
+}
+
+func (c *statusSinkClient) Send(ctx context.Context, event cloudevents.Event) cloudevents.Result {
+	c.attempts++
+	return cehttp.NewResult(c.status, "%w", protocol.ResultNACK)
+}
+
+func TestNewDeliveryConfig(t *testing.T) {
+	testCases := map[string]struct {
+		env     envConfig
+		want    deliveryConfig
+		wantErr bool
+	}{
+		"defaults": {
+			want: deliveryConfig{policy: "exponential", delay: defaultBackoffDelay},
+		},
+		"linear": {
+			env: envConfig{
+				DeliveryRetry:         5,
+				DeliveryBackoffPolicy: "linear",
+				DeliveryBackoffDelay:  "PT2S",
+				DeadLetterSink:        "http://dls",
+			},
+			want: deliveryConfig{retries: 5, policy: "linear", delay: 2 * time.Second, deadLetterSink: "http://dls"},
+		},
+		"invalid delay": {
+			env:     envConfig{DeliveryBackoffDelay: "2s"},
+			wantErr: true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			got, err := newDeliveryConfig(&tc.env)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("newDeliveryConfig() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err == nil && *got != tc.want {
+				t.Errorf("newDeliveryConfig() = %+v, want %+v", *got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewDeliveryAttempt(t *testing.T) {
+	now := time.Now()
+	testCases := map[string]struct {
+		result        error
+		wantCode      int
+		wantClass     string
+		wantRetriable bool
+	}{
+		"unavailable": {
+			result:        cehttp.NewResult(503, "%w", protocol.ResultNACK),
+			wantCode:      503,
+			wantClass:     "server",
+			wantRetriable: true,
+		},
+		"bad request": {
+			result:    cehttp.NewResult(400, "%w", protocol.ResultNACK),
+			wantCode:  400,
+			wantClass: "client",
+		},
+		"timeout": {
+			result:        fmt.Errorf("sending: %w", context.DeadlineExceeded),
+			wantClass:     "timeout",
+			wantRetriable: true,
+		},
+		"connection refused": {
+			result:        errors.New("connection refused"),
+			wantClass:     "network",
+			wantRetriable: true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			got := newDeliveryAttempt(now, tc.result)
+			if got.StatusCode != tc.wantCode || got.ErrorClass != tc.wantClass {
+				t.Errorf("newDeliveryAttempt() = %+v, want status code %d and error class %q", got, tc.wantCode, tc.wantClass)
+			}
+			if retriable(got) != tc.wantRetriable {
+				t.Errorf("retriable() = %v, want %v", retriable(got), tc.wantRetriable)
+			}
+		})
+	}
+}
+
+func TestSendDeadLetter(t *testing.T) {
+	testCases := map[string]struct {
+		deadLetterSink string
+		wantErr        bool
+		wantDeadLetter int
+	}{
+		"without dead letter sink": {
+			wantErr: true,
+		},
+		"with dead letter sink": {
+			deadLetterSink: "http://dls.example.com",
+			wantDeadLetter: 1,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			ce := &failingSinkClient{
+				TestCloudEventsClient: kncetesting.NewTestClient(),
+				deadLetterSink:        tc.deadLetterSink,
+			}
+			a := &couchDbAdapter{
+				ce:     ce,
+				logger: zap.NewNop().Sugar(),
+				delivery: &deliveryConfig{
+					retries:        3,
+					policy:         "linear",
+					delay:          time.Millisecond,
+					deadLetterSink: tc.deadLetterSink,
+				},
+			}
+
+			event := cloudevents.NewEvent()
+			event.SetID("1")
+			event.SetType("test")
+			event.SetSource("test")
+			err := a.send(context.Background(), event)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("send() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			// The first try and 3 retries.
+			if ce.attempts != 4 {
+				t.Errorf("attempts = %d, want 4", ce.attempts)
+			}
+			if got := len(ce.Sent()); got != tc.wantDeadLetter {
+				t.Fatalf("dead lettered %d events, want %d", got, tc.wantDeadLetter)
+			}
+			for _, dead := range ce.Sent() {
+				attempts, err := cdbevents.Attempts(dead)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(attempts) != 4 {
+					t.Errorf("recorded %d attempts, want 4", len(attempts))
+				}
+				if got := attempts[0].ErrorClass; got != "network" {
+					t.Errorf("error class = %q, want network", got)
+				}
+			}
+		})
+	}
+}
+
+func TestSendContentMode(t *testing.T) {
+	testCases := map[string]struct {
+		structured      bool
+		wantContentType string
+	}{
+		"binary": {
+			wantContentType: cloudevents.ApplicationJSON,
+		},
+		"structured": {
+			structured:      true,
+			wantContentType: cloudevents.ApplicationCloudEventsJSON,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			var contentType string
+			sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				contentType = r.Header.Get("Content-Type")
+				w.WriteHeader(http.StatusAccepted)
+			}))
+			defer sink.Close()
+
+			ce, err := cloudevents.NewClientHTTP(cloudevents.WithTarget(sink.URL))
+			if err != nil {
+				t.Fatalf("NewClientHTTP() = %v", err)
+			}
+			a := &couchDbAdapter{
+				ce:         ce,
+				logger:     zap.NewNop().Sugar(),
+				delivery:   &deliveryConfig{policy: "linear", delay: time.Millisecond},
+				structured: tc.structured,
+			}
+
+			event := cloudevents.NewEvent()
+			event.SetID("1")
+			event.SetType("test")
+			event.SetSource("test")
+			if err := event.SetData(cloudevents.ApplicationJSON, map[string]string{"id": "doc"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := a.send(context.Background(), event); err != nil {
+				t.Fatalf("send() = %v", err)
+			}
+			if !strings.HasPrefix(contentType, tc.wantContentType) {
+				t.Errorf("Content-Type = %q, want %q", contentType, tc.wantContentType)
+			}
+		})
+	}
+}
+
+// countFor returns the count of the view for the source, by reason.
+func countFor(t *testing.T, name, source string) map[string]int64 {
+	t.Helper()
+	rows, err := view.RetrieveData(name)
+	if err != nil {
+		t.Fatalf("RetrieveData(%s) = %v", name, err)
+	}
+	counts := map[string]int64{}
+	for _, row := range rows {
+		var reason string
+		var matches bool
+		for _, tag := range row.Tags {
+			switch tag.Key {
+			case nameKey:
+				matches = tag.Value == source
+			case reasonKey:
+				reason = tag.Value
+			}
+		}
+		if matches {
+			counts[reason] = row.Data.(*view.CountData).Value
+		}
+	}
+	return counts
+}
+
+func TestSendRetryMetrics(t *testing.T) {
+	ce := &failingSinkClient{TestCloudEventsClient: kncetesting.NewTestClient()}
+	a := &couchDbAdapter{
+		ce:        ce,
+		logger:    zap.NewNop().Sugar(),
+		namespace: "default",
+		name:      "retry-metrics",
+		delivery:  &deliveryConfig{retries: 2, policy: "linear", delay: time.Millisecond},
+	}
+
+	event := cloudevents.NewEvent()
+	event.SetID("1")
+	event.SetType("test")
+	event.SetSource("test")
+	if err := a.send(context.Background(), event); err == nil {
+		t.Fatal("send() succeeded, want an error")
+	}
+
+	if got := countFor(t, "couchdb_delivery_retry_count", a.name); got["network"] != 2 {
+		t.Errorf("retries = %v, want 2 network retries", got)
+	}
+	if got := countFor(t, "couchdb_delivery_retry_budget_exhausted_count", a.name); got["network"] != 1 {
+		t.Errorf("exhausted budgets = %v, want 1 after network errors", got)
+	}
+}
+
+func TestSendWithoutRetriesMetrics(t *testing.T) {
+	ce := &failingSinkClient{TestCloudEventsClient: kncetesting.NewTestClient()}
+	a := &couchDbAdapter{
+		ce:        ce,
+		logger:    zap.NewNop().Sugar(),
+		namespace: "default",
+		name:      "no-retries-metrics",
+		delivery:  &deliveryConfig{},
+	}
+
+	event := cloudevents.NewEvent()
+	event.SetID("1")
+	event.SetType("test")
+	event.SetSource("test")
+	if err := a.send(context.Background(), event); err == nil {
+		t.Fatal("send() succeeded, want an error")
+	}
+
+	if got := countFor(t, "couchdb_delivery_retry_budget_exhausted_count", a.name); len(got) != 0 {
+		t.Errorf("exhausted budgets = %v, want none without retries", got)
+	}
+}
+
+func TestSendRejected(t *testing.T) {
+	ce := &statusSinkClient{TestCloudEventsClient: kncetesting.NewTestClient(), status: http.StatusBadRequest}
+	a := &couchDbAdapter{
+		ce:         ce,
+		logger:     zap.NewNop().Sugar(),
+		namespace:  "default",
+		name:       "rejected",
+		delivery:   &deliveryConfig{retries: 2, policy: "linear", delay: time.Millisecond},
+		rejections: newRejections(),
+	}
+
+	event := cloudevents.NewEvent()
+	event.SetID("1")
+	event.SetType("test")
+	event.SetSource("test")
+	// The feed is read again from the event after each rejection.
+	for i := 1; i < maxRejections; i++ {
+		if err := a.send(context.Background(), event); err == nil {
+			t.Fatalf("send() #%d succeeded, want an error", i)
+		}
+	}
+	if err := a.send(context.Background(), event); err != nil {
+		t.Fatalf("send() = %v, want the event to be skipped", err)
+	}
+	// A rejection is not retried.
+	if ce.attempts != maxRejections {
+		t.Errorf("attempts = %d, want %d", ce.attempts, maxRejections)
+	}
+	if got := countFor(t, "couchdb_events_skipped_count", a.name); got["client"] != 1 {
+		t.Errorf("skipped events = %v, want 1 after client errors", got)
+	}
+	if len(a.rejections.byID) != 0 {
+		t.Errorf("rejections = %v, want the skipped event forgotten", a.rejections.byID)
+	}
+}
+
+func TestSendReply(t *testing.T) {
+	testCases := map[string]struct {
+		reply       bool
+		replyStatus int
+		wantErr     bool
+		wantReplies int
+	}{
+		"no reply": {},
+		"reply": {
+			reply:       true,
+			replyStatus: http.StatusAccepted,
+			wantReplies: 1,
+		},
+		"reply rejected": {
+			reply:       true,
+			replyStatus: http.StatusBadRequest,
+			wantErr:     true,
+			wantReplies: 1,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tc.reply {
+					w.Header().Set("Ce-Specversion", "1.0")
+					w.Header().Set("Ce-Id", "reply-1")
+					w.Header().Set("Ce-Type", "com.example.reply")
+					w.Header().Set("Ce-Source", "/replier")
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer sink.Close()
+			var replies []string
+			replySink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				replies = append(replies, r.Header.Get("Ce-Id"))
+				w.WriteHeader(tc.replyStatus)
+			}))
+			defer replySink.Close()
+
+			ce, err := cloudevents.NewClientHTTP(cloudevents.WithTarget(sink.URL))
+			if err != nil {
+				t.Fatalf("NewClientHTTP() = %v", err)
+			}
+			a := &couchDbAdapter{
+				ce:       ce,
+				logger:   zap.NewNop().Sugar(),
+				delivery: &deliveryConfig{policy: "linear", delay: time.Millisecond, replySink: replySink.URL},
+			}
+
+			event := cloudevents.NewEvent()
+			event.SetID("1")
+			event.SetType("test")
+			event.SetSource("test")
+			if err := a.send(context.Background(), event); (err != nil) != tc.wantErr {
+				t.Errorf("send() = %v, wantErr %v", err, tc.wantErr)
+			}
+			if len(replies) != tc.wantReplies {
+				t.Fatalf("forwarded %d replies, want %d", len(replies), tc.wantReplies)
+			}
+			for _, id := range replies {
+				if id != "reply-1" {
+					t.Errorf("forwarded reply %q, want reply-1", id)
+				}
+			}
+		})
+	}
+}
+
+func TestSendMetrics(t *testing.T) {
+	event := cloudevents.NewEvent()
+	event.SetID("1")
+	event.SetType("test")
+	event.SetSource("test")
+
+	a := &couchDbAdapter{
+		ce:        kncetesting.NewTestClient(),
+		logger:    zap.NewNop().Sugar(),
+		namespace: "default",
+		name:      "send-metrics",
+		database:  "db",
+		delivery:  &deliveryConfig{policy: "linear", delay: time.Millisecond},
+	}
+	if err := a.send(context.Background(), event); err != nil {
+		t.Fatalf("send() = %v", err)
+	}
+	a.ce = &failingSinkClient{TestCloudEventsClient: kncetesting.NewTestClient()}
+	a.delivery.retries = 1
+	if err := a.send(context.Background(), event); err == nil {
+		t.Fatal("send() succeeded, want an error")
+	}
+
+	if got := countFor(t, "couchdb_events_emitted_count", a.name); got[""] != 1 {
+		t.Errorf("emitted events = %v, want 1", got)
+	}
+	if got := countFor(t, "couchdb_delivery_error_count", a.name); got["network"] != 2 {
+		t.Errorf("delivery errors = %v, want 2 network errors", got)
+	}
+	rows, err := view.RetrieveData("couchdb_delivery_latencies")
+	if err != nil {
+		t.Fatalf("RetrieveData() = %v", err)
+	}
+	var attempts int64
+	for _, row := range rows {
+		for _, tag := range row.Tags {
+			if tag.Key == nameKey && tag.Value == a.name {
+				attempts += row.Data.(*view.DistributionData).Count
+			}
+		}
+	}
+	if attempts != 3 {
+		t.Errorf("delivery latencies recorded %d attempts, want 3", attempts)
+	}
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kivik/couchdb/v3"
+	"github.com/go-kivik/kivik/v3"
+	"go.uber.org/zap"
+)
+
+const (
+	// bannerTimeout bounds the request reading the banner of the server.
+	bannerTimeout = 10 * time.Second
+)
+
+// dialect encapsulates the behaviors that differ between the servers speaking
+// the CouchDB API, so that tuning the adapter for one of them does not change
+// how it talks to the others. Each dialect is a kivik driver registered under
+// its name by the file implementing it, which build tags can leave out.
+type dialect interface {
+	// matches is whether the server at rawurl speaks the dialect, from its
+	// banner, or from its url alone when the banner could not be read.
+	matches(rawurl string, banner *serverBanner) bool
+
+	// transport carries the CouchDB traffic of the dialect.
+	transport() *http.Transport
+
+	// sessionTimeout is how long the session cookies last when the server
+	// does not say.
+	sessionTimeout() time.Duration
+
+	// changesOptions adds to the options of the changes feed those the
+	// server favors.
+	changesOptions(env *envConfig, options kivik.Options)
+}
+
+// serverBanner is the welcome message served at the root of the server.
+type serverBanner struct {
+	CouchDB string `json:"couchdb"`
+	Version string `json:"version"`
+	Vendor  struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"vendor"`
+}
+
+// dialects are the dialects built in, by name.
+var dialects = map[string]dialect{}
+
+// registerDialect registers the kivik driver of the dialect, whose requests
+// go through rt.
+func registerDialect(name string, d dialect, rt http.RoundTripper) {
+	dialects[name] = d
+	kivik.Register(name, &couchdb.Couch{
+		HTTPClient: &http.Client{Transport: &limitTransport{base: rt}},
+	})
+}
+
+// dialectFor returns the dialect of the driver. The drivers of no dialect,
+// such as kivikmock, speak CouchDB's.
+func dialectFor(driver string) dialect {
+	if d, ok := dialects[driver]; ok {
+		return d
+	}
+	return couchDialect{}
+}
+
+// serverDriver returns the driver of the dialect spoken by the server at
+// rawurl: the first other than CouchDB's matching it, by name, or CouchDB's.
+func serverDriver(ctx context.Context, logger *zap.SugaredLogger, rawurl string) string {
+	banner, err := readBanner(ctx, rawurl)
+	if err != nil {
+		logger.Warnw("Unable to read the banner of the CouchDB server, guessing its dialect from its url", zap.Error(err))
+	}
+	names := make([]string, 0, len(dialects))
+	for name := range dialects {
+		if name != couchDriver {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if dialects[name].matches(rawurl, banner) {
+			return name
+		}
+	}
+	return couchDriver
+}
+
+// readBanner reads the banner of the server at rawurl, with its credentials.
+func readBanner(ctx context.Context, rawurl string) (*serverBanner, error) {
+	ctx, cancel := context.WithTimeout(ctx, bannerTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(rawurl, "/")+"/", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	client := &http.Client{Transport: &limitTransport{base: couchTransport}}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	var banner serverBanner
+	if err := json.NewDecoder(resp.Body).Decode(&banner); err != nil {
+		return nil, err
+	}
+	return &banner, nil
+}
+
+// couchDialect is the dialect of Apache CouchDB, and of the servers no other
+// dialect matches.
+type couchDialect struct{}
+
+var _ dialect = couchDialect{}
+
+func (couchDialect) matches(string, *serverBanner) bool { return true }
+
+func (couchDialect) transport() *http.Transport { return couchTransport }
+
+func (couchDialect) sessionTimeout() time.Duration { return defaultSessionTimeout }
+
+func (couchDialect) changesOptions(*envConfig, kivik.Options) {}
@@ -0,0 +1,143 @@
+//go:build !nocloudant
+// +build !nocloudant
+
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-kivik/kivik/v3"
+	"golang.org/x/net/http2"
+)
+
+const (
+	// cloudantDriver is the kivik driver, and the dialect, used to talk to
+	// IBM Cloudant servers.
+	cloudantDriver = "cloudant"
+
+	// cloudantSeqInterval is the seq_interval of the changes feeds read from
+	// Cloudant, unless spec.seqInterval is set. Computing the sequence of
+	// every change is expensive on Cloudant clusters.
+	cloudantSeqInterval = 100
+
+	// cloudantSessionTimeout is the lifetime of Cloudant session cookies.
+	cloudantSessionTimeout = 24 * time.Hour
+
+	// cloudantRateLimitRetries is how many times a request refused for
+	// exceeding the throughput of the Cloudant plan is tried again.
+	cloudantRateLimitRetries = 5
+	// cloudantRateLimitDelay is the delay before the first of these tries,
+	// doubled for the next ones.
+	cloudantRateLimitDelay = 250 * time.Millisecond
+)
+
+var (
+	// cloudantTransport carries the Cloudant traffic of the adapter.
+	cloudantTransport = http.DefaultTransport.(*http.Transport).Clone()
+)
+
+func init() {
+	// Need to disable compression for Cloudant.
+	cloudantTransport.DisableCompression = true
+	if err := http2.ConfigureTransport(cloudantTransport); err != nil {
+		panic(err)
+	}
+	registerDialect(cloudantDriver, cloudantDialect{}, &rateLimitTransport{
+		base:    cloudantTransport,
+		retries: cloudantRateLimitRetries,
+		delay:   cloudantRateLimitDelay,
+	})
+}
+
+// cloudantDialect is the dialect of IBM Cloudant.
+type cloudantDialect struct{}
+
+var _ dialect = cloudantDialect{}
+
+func (cloudantDialect) matches(rawurl string, banner *serverBanner) bool {
+	if banner != nil {
+		return strings.Contains(banner.Vendor.Name, "Cloudant")
+	}
+	return strings.Contains(rawurl, "cloudant")
+}
+
+func (cloudantDialect) transport() *http.Transport { return cloudantTransport }
+
+func (cloudantDialect) sessionTimeout() time.Duration { return cloudantSessionTimeout }
+
+func (cloudantDialect) changesOptions(env *envConfig, options kivik.Options) {
+	if env.SeqInterval == 0 {
+		options["seq_interval"] = cloudantSeqInterval
+	}
+}
+
+// rateLimitTransport tries again, with an exponential backoff, the requests
+// refused with 429 Too Many Requests, which Cloudant answers to the requests
+// exceeding the throughput of its plan. A Retry-After header is honored.
+type rateLimitTransport struct {
+	base    http.RoundTripper
+	retries int
+	delay   time.Duration
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	delay := t.delay
+	for try := 0; ; try++ {
+		resp, err := t.base.RoundTrip(req)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || try == t.retries {
+			return resp, err
+		}
+		// Requests whose body cannot be read again are not tried again.
+		if req.Body != nil && req.GetBody == nil {
+			return resp, nil
+		}
+		wait := delay
+		if d, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			wait = d
+		}
+		if wait > maxRetryAfter {
+			wait = maxRetryAfter
+		}
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+		delay *= 2
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
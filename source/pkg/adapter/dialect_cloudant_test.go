@@ -0,0 +1,160 @@
+//go:build !nocloudant
+// +build !nocloudant
+
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kivik/kivik/v3"
+	"go.uber.org/zap"
+)
+
+func TestServerDriver(t *testing.T) {
+	testCases := map[string]struct {
+		banner string
+		// url replaces the url of the server, when set.
+		url  string
+		want string
+	}{
+		"couchdb": {
+			banner: `{"couchdb":"Welcome","version":"3.1.1","vendor":{"name":"The Apache Software Foundation"}}`,
+			want:   couchDriver,
+		},
+		"cloudant": {
+			banner: `{"couchdb":"Welcome","version":"2.1.1","vendor":{"name":"IBM Cloudant","version":"8162"}}`,
+			want:   cloudantDriver,
+		},
+		"unreadable banner": {
+			banner: `<html>`,
+			want:   couchDriver,
+		},
+		"unreachable cloudant": {
+			url:  "http://127.0.0.1:1/cloudant",
+			want: cloudantDriver,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/" {
+					http.NotFound(w, r)
+					return
+				}
+				w.Write([]byte(tc.banner))
+			}))
+			defer server.Close()
+			url := server.URL
+			if tc.url != "" {
+				url = tc.url
+			}
+			if got := serverDriver(context.Background(), zap.NewNop().Sugar(), url); got != tc.want {
+				t.Errorf("serverDriver() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDialectChangesOptions(t *testing.T) {
+	testCases := map[string]struct {
+		driver string
+		env    envConfig
+		want   interface{}
+	}{
+		"couchdb": {
+			driver: couchDriver,
+		},
+		"cloudant": {
+			driver: cloudantDriver,
+			want:   cloudantSeqInterval,
+		},
+		"cloudant with seqInterval": {
+			driver: cloudantDriver,
+			env:    envConfig{SeqInterval: 1},
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			options := kivik.Options{}
+			dialectFor(tc.driver).changesOptions(&tc.env, options)
+			if got := options["seq_interval"]; got != tc.want {
+				t.Errorf("seq_interval = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitTransport(t *testing.T) {
+	testCases := map[string]struct {
+		limited    int
+		wantStatus int
+		wantTries  int
+	}{
+		"not limited": {
+			wantStatus: http.StatusOK,
+			wantTries:  1,
+		},
+		"limited": {
+			limited:    2,
+			wantStatus: http.StatusOK,
+			wantTries:  3,
+		},
+		"retries exhausted": {
+			limited:    10,
+			wantStatus: http.StatusTooManyRequests,
+			wantTries:  4,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			tries := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tries++
+				if body, _ := ioutil.ReadAll(r.Body); string(body) != `{"selector":{}}` {
+					t.Errorf("body = %q, want the body of the request", body)
+				}
+				if tries <= tc.limited {
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			client := &http.Client{Transport: &rateLimitTransport{base: http.DefaultTransport, retries: 3, delay: time.Millisecond}}
+			resp, err := client.Post(server.URL, "application/json", strings.NewReader(`{"selector":{}}`))
+			if err != nil {
+				t.Fatalf("Post() = %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tc.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tc.wantStatus)
+			}
+			if tries != tc.wantTries {
+				t.Errorf("tries = %d, want %d", tries, tc.wantTries)
+			}
+		})
+	}
+}
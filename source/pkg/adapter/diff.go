@@ -0,0 +1,109 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-kivik/kivik/v3"
+	"gomodules.xyz/jsonpatch/v2"
+
+	cdbevents "knative.dev/eventing-couchdb/source/pkg/events"
+)
+
+// diffData returns the patch turning the previous revision of the changed
+// document into the changed revision. Both are fetched from the database, as
+// the feed only carries the latest revision. Without a previous revision,
+// for new documents or once it was compacted, the patch adds the whole
+// document.
+func (a *couchDbAdapter) diffData(ctx context.Context, changes change) (*cdbevents.DiffData, error) {
+	rev := firstRev(changes.Changes())
+	var doc map[string]interface{}
+	if err := a.couchDB.Get(ctx, changes.ID(), kivik.Options{"rev": rev, "revs": true}).ScanDoc(&doc); err != nil {
+		return nil, err
+	}
+
+	previous := previousRev(doc["_revisions"])
+	prev := map[string]interface{}{}
+	if previous != "" {
+		err := a.couchDB.Get(ctx, changes.ID(), kivik.Options{"rev": previous}).ScanDoc(&prev)
+		switch {
+		case kivik.StatusCode(err) == http.StatusNotFound:
+			previous = ""
+		case err != nil:
+			return nil, err
+		}
+	}
+
+	patch, err := diff(prev, doc)
+	if err != nil {
+		return nil, err
+	}
+	return &cdbevents.DiffData{Rev: rev, Previous: previous, Patch: patch}, nil
+}
+
+// previousRev returns the revision preceding the document revision, from
+// its _revisions, or an empty string for the first revision.
+func previousRev(revisions interface{}) string {
+	var history struct {
+		Start int      `json:"start"`
+		IDs   []string `json:"ids"`
+	}
+	b, err := json.Marshal(revisions)
+	if err != nil || json.Unmarshal(b, &history) != nil {
+		return ""
+	}
+	if history.Start < 2 || len(history.IDs) < 2 {
+		return ""
+	}
+	return strconv.Itoa(history.Start-1) + "-" + history.IDs[1]
+}
+
+// diff returns the JSON patch turning from into to, ignoring the revision
+// fields.
+func diff(from, to map[string]interface{}) ([]jsonpatch.Operation, error) {
+	a, err := json.Marshal(withoutRevs(from))
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(withoutRevs(to))
+	if err != nil {
+		return nil, err
+	}
+	patch, err := jsonpatch.CreatePatch(a, b)
+	if err != nil {
+		return nil, err
+	}
+	if patch == nil {
+		patch = []jsonpatch.Operation{}
+	}
+	return patch, nil
+}
+
+func withoutRevs(doc map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(doc))
+	for k, v := range doc {
+		if k == "_rev" || k == "_revisions" {
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/go-kivik/kivik/v3"
+	"github.com/go-kivik/kivik/v3/driver"
+	"github.com/go-kivik/kivikmock/v3"
+	"github.com/google/go-cmp/cmp"
+	"knative.dev/eventing/pkg/adapter/v2"
+	pkgtesting "knative.dev/pkg/reconciler/testing"
+
+	cdbevents "knative.dev/eventing-couchdb/source/pkg/events"
+)
+
+func document(body string) *driver.Document {
+	return &driver.Document{Body: ioutil.NopCloser(strings.NewReader(body))}
+}
+
+func TestReceiveEventDiff(t *testing.T) {
+	current := `{"_id":"anid","_rev":"3-c","_revisions":{"start":3,"ids":["c","b","a"]},"status":"shipped","total":3}`
+	testCases := map[string]struct {
+		rev      string
+		current  string
+		previous *driver.Document
+		prevErr  error
+		wantData string
+	}{
+		"updated": {
+			rev:      "3-c",
+			current:  current,
+			previous: document(`{"_id":"anid","_rev":"2-b","status":"pending","total":3}`),
+			wantData: `{"rev":"3-c","previous":"2-b","patch":[{"op":"replace","path":"/status","value":"shipped"}]}`,
+		},
+		"unchanged": {
+			rev:      "3-c",
+			current:  current,
+			previous: document(`{"_id":"anid","_rev":"2-b","status":"shipped","total":3}`),
+			wantData: `{"rev":"3-c","previous":"2-b","patch":[]}`,
+		},
+		"compacted": {
+			rev:      "3-c",
+			current:  `{"_id":"anid","_rev":"3-c","_revisions":{"start":3,"ids":["c","b","a"]}}`,
+			prevErr:  &kivik.Error{HTTPStatus: 404, Err: errors.New("missing")},
+			wantData: `{"rev":"3-c","patch":[{"op":"add","path":"/_id","value":"anid"}]}`,
+		},
+		"new document": {
+			rev:      "1-a",
+			current:  `{"_id":"anid","_rev":"1-a","_revisions":{"start":1,"ids":["a"]}}`,
+			wantData: `{"rev":"1-a","patch":[{"op":"add","path":"/_id","value":"anid"}]}`,
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			env := envConfig{
+				EnvConfig: adapter.EnvConfig{
+					Namespace: "default",
+				},
+				EventSource: "test-source",
+				Database:    "testdb",
+				Feed:        "normal",
+				Payload:     "diff",
+			}
+			ctx, _ := pkgtesting.SetupFakeContext(t)
+
+			c, mock := kivikmock.NewT(t)
+
+			mockDB := mock.NewDB()
+			mock.ExpectDB().WithName("testdb").WillReturn(mockDB)
+			mockDB.ExpectChanges().WillReturn(kivikmock.NewChanges().AddChange(&driver.Change{
+				ID:      "anid",
+				Seq:     "aseq",
+				Changes: driver.ChangedRevs{tc.rev},
+			}))
+			mockDB.ExpectGet().WithDocID("anid").
+				WithOptions(map[string]interface{}{"rev": tc.rev, "revs": true}).
+				WillReturn(document(tc.current))
+			if tc.previous != nil || tc.prevErr != nil {
+				get := mockDB.ExpectGet().WithDocID("anid").WithOptions(map[string]interface{}{"rev": "2-b"})
+				if tc.prevErr != nil {
+					get.WillReturnError(tc.prevErr)
+				} else {
+					get.WillReturn(tc.previous)
+				}
+			}
+
+			ctx, cancel := context.WithCancel(ctx)
+			ce := newAdapterTestClient(cancel)
+
+			a := newAdapter(ctx, &env, ce, c.DSN(), "kivikmock").(*couchDbAdapter)
+			if _, ok := a.options["include_docs"]; ok {
+				t.Error("include_docs is set, want the revisions fetched instead")
+			}
+			if err := a.Start(ctx); err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			validateSent(t, ce, tc.wantData)
+
+			data, err := cdbevents.Diff(ce.Sent()[0])
+			if err != nil {
+				t.Fatalf("Diff() = %v", err)
+			}
+			if data.Rev != tc.rev {
+				t.Errorf("Diff().Rev = %q, want %q", data.Rev, tc.rev)
+			}
+		})
+	}
+}
+
+func TestPreviousRev(t *testing.T) {
+	testCases := map[string]struct {
+		revisions interface{}
+		want      string
+	}{
+		"missing": {},
+		"first revision": {
+			revisions: map[string]interface{}{"start": 1, "ids": []interface{}{"a"}},
+		},
+		"third revision": {
+			revisions: map[string]interface{}{"start": 3, "ids": []interface{}{"c", "b", "a"}},
+			want:      "2-b",
+		},
+		"invalid": {
+			revisions: "3-c",
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			if got := previousRev(tc.revisions); got != tc.want {
+				t.Errorf("previousRev() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	from := map[string]interface{}{"_id": "doc", "_rev": "1-a", "items": []interface{}{"x"}, "note": "old"}
+	to := map[string]interface{}{"_id": "doc", "_rev": "2-b", "items": []interface{}{"x", "y"}}
+	got, err := diff(from, to)
+	if err != nil {
+		t.Fatalf("diff() = %v", err)
+	}
+	paths := make(map[string]string, len(got))
+	for _, op := range got {
+		paths[op.Path] = op.Operation
+	}
+	want := map[string]string{"/items/1": "add", "/note": "remove"}
+	if d := cmp.Diff(want, paths); d != "" {
+		t.Errorf("unexpected patch (-want, +got) = %v", d)
+	}
+}
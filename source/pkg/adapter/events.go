@@ -0,0 +1,159 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+const (
+	// feedFailuresBeforeEvent is how many times in a row the changes feed
+	// fails before the failures are reported on the source, so that a
+	// reconnection does not raise a warning.
+	feedFailuresBeforeEvent = 3
+
+	// The reasons of the Kubernetes Events recorded on the source.
+	feedFailedReason    = "FeedFailed"
+	feedRecoveredReason = "FeedRecovered"
+	decodeFailedReason  = "ChangeDecodeFailed"
+)
+
+// newEventRecorder returns a recorder of the Kubernetes Events of the
+// sources, stopped once ctx is done.
+func newEventRecorder(ctx context.Context, kube kubernetes.Interface, component string) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	watches := []interface{ Stop() }{
+		broadcaster.StartLogging(logging.FromContext(ctx).Named("event-broadcaster").Infof),
+		broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kube.CoreV1().Events("")}),
+	}
+	go func() {
+		<-ctx.Done()
+		for _, w := range watches {
+			w.Stop()
+		}
+	}()
+	// The hostname of a pod is its name.
+	host, _ := os.Hostname()
+	return broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: component, Host: host})
+}
+
+// withInClusterEventRecorder returns ctx with a recorder of the Kubernetes
+// Events of the source, through the service account of the pod. It returns
+// ctx unchanged when the pod cannot reach the API server.
+func withInClusterEventRecorder(ctx context.Context, component string) context.Context {
+	cfg, err := rest.InClusterConfig()
+	if err != nil {
+		logging.FromContext(ctx).Warnw("Not recording Kubernetes Events", zap.Error(err))
+		return ctx
+	}
+	kube, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		logging.FromContext(ctx).Warnw("Not recording Kubernetes Events", zap.Error(err))
+		return ctx
+	}
+	return controller.WithEventRecorder(ctx, newEventRecorder(ctx, kube, component))
+}
+
+// failureEvents records the repeated failures of the adapter as Kubernetes
+// Events on its source, so that kubectl describe shows them.
+type failureEvents struct {
+	recorder record.EventRecorder
+	source   *corev1.ObjectReference
+
+	mu           sync.Mutex
+	feedFailures int
+}
+
+// newFailureEvents returns nil without a recorder in ctx, or without the
+// identity of the source.
+func newFailureEvents(ctx context.Context, env *envConfig) *failureEvents {
+	recorder := controller.GetEventRecorder(ctx)
+	if recorder == nil || env.SourceUID == "" {
+		return nil
+	}
+	return &failureEvents{
+		recorder: recorder,
+		source: &corev1.ObjectReference{
+			APIVersion: v1alpha1.SchemeGroupVersion.String(),
+			Kind:       "CouchDbSource",
+			Namespace:  env.Namespace,
+			Name:       env.Name,
+			UID:        types.UID(env.SourceUID),
+		},
+	}
+}
+
+// feedFailed records that the changes feed could not be opened, or failed
+// while read, once it failed feedFailuresBeforeEvent times in a row.
+func (e *failureEvents) feedFailed(err error) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	e.feedFailures++
+	failures := e.feedFailures
+	e.mu.Unlock()
+	if failures >= feedFailuresBeforeEvent {
+		e.recorder.Eventf(e.source, corev1.EventTypeWarning, feedFailedReason,
+			"The changes feed failed %d times in a row: %v", failures, err)
+	}
+}
+
+// feedConnected records that the changes feed was opened, and reports the
+// recovery of a feed reported as failing.
+func (e *failureEvents) feedConnected() {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	failures := e.feedFailures
+	e.feedFailures = 0
+	e.mu.Unlock()
+	if failures >= feedFailuresBeforeEvent {
+		e.recorder.Eventf(e.source, corev1.EventTypeNormal, feedRecoveredReason,
+			"The changes feed reconnected after %d failures", failures)
+	}
+}
+
+// decodeFailed records that the change of the document could not be turned
+// into an event.
+func (e *failureEvents) decodeFailed(id string, policy v1alpha1.DecodeErrorPolicy, suppressed int, err error) {
+	if e == nil {
+		return
+	}
+	msg := fmt.Sprintf("Unable to make the event of the change of %q (%s): %v", id, policy, err)
+	if suppressed > 0 {
+		msg += fmt.Sprintf(", and of %d other changes", suppressed)
+	}
+	e.recorder.Event(e.source, corev1.EventTypeWarning, decodeFailedReason, msg)
+}
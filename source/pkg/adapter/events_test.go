@@ -0,0 +1,98 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"k8s.io/client-go/tools/record"
+	"knative.dev/eventing/pkg/adapter/v2"
+	"knative.dev/pkg/controller"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+func TestFailureEvents(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	ctx := controller.WithEventRecorder(context.Background(), recorder)
+	env := &envConfig{
+		EnvConfig: adapter.EnvConfig{Namespace: "ns", Name: "source"},
+		SourceUID: "1234",
+	}
+	e := newFailureEvents(ctx, env)
+	if e == nil {
+		t.Fatal("newFailureEvents() = nil, want the events of the source")
+	}
+	if e.source.Name != "source" || e.source.Namespace != "ns" || e.source.UID != "1234" || e.source.Kind != "CouchDbSource" {
+		t.Errorf("source = %v, want the CouchDbSource ns/source", e.source)
+	}
+
+	// A reconnection raises no warning.
+	e.feedFailed(errors.New("connection refused"))
+	e.feedConnected()
+	for i := 0; i < feedFailuresBeforeEvent; i++ {
+		e.feedFailed(errors.New("connection refused"))
+	}
+	e.feedConnected()
+	e.decodeFailed("anid", v1alpha1.DecodeErrorSkip, 2, errors.New("invalid character"))
+
+	for _, want := range []string{
+		"Warning FeedFailed The changes feed failed 3 times in a row: connection refused",
+		"Normal FeedRecovered The changes feed reconnected after 3 failures",
+		`Warning ChangeDecodeFailed Unable to make the event of the change of "anid" (skip): invalid character, and of 2 other changes`,
+	} {
+		select {
+		case got := <-recorder.Events:
+			if got != want {
+				t.Errorf("event = %q, want %q", got, want)
+			}
+		default:
+			t.Errorf("missing event %q", want)
+		}
+	}
+	select {
+	case got := <-recorder.Events:
+		t.Errorf("unexpected event %q", got)
+	default:
+	}
+}
+
+func TestFailureEventsDisabled(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	for name, ctx := range map[string]context.Context{
+		"no recorder": context.Background(),
+		"no identity": controller.WithEventRecorder(context.Background(), recorder),
+	} {
+		t.Run(name, func(t *testing.T) {
+			e := newFailureEvents(ctx, &envConfig{})
+			if e != nil {
+				t.Fatalf("newFailureEvents() = %v, want nil", e)
+			}
+			// Events are optional.
+			for i := 0; i < feedFailuresBeforeEvent; i++ {
+				e.feedFailed(errors.New("connection refused"))
+			}
+			e.feedConnected()
+			e.decodeFailed("anid", v1alpha1.DecodeErrorSkip, 0, errors.New("invalid character"))
+		})
+	}
+	if len(recorder.Events) > 0 {
+		t.Errorf("recorded %q, want no event", <-recorder.Events)
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-kivik/kivik/v3"
+	"github.com/rickb777/date/period"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+// feedTiming returns the heartbeat or timeout options of the changes feed, in
+// milliseconds as CouchDB expects them. They only apply to continuous feeds.
+func feedTiming(env *envConfig) (kivik.Options, error) {
+	if env.Feed != string(v1alpha1.FeedContinuous) {
+		return nil, nil
+	}
+	if env.Timeout != "" {
+		timeout, err := parseDuration(env.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout %q: %v", env.Timeout, err)
+		}
+		return kivik.Options{"timeout": timeout.Milliseconds()}, nil
+	}
+	heartbeat := v1alpha1.DefaultHeartbeat
+	if env.Heartbeat != "" {
+		var err error
+		if heartbeat, err = parseDuration(env.Heartbeat); err != nil {
+			return nil, fmt.Errorf("invalid heartbeat %q: %v", env.Heartbeat, err)
+		}
+	}
+	return kivik.Options{"heartbeat": heartbeat.Milliseconds()}, nil
+}
+
+func parseDuration(iso8601 string) (time.Duration, error) {
+	p, err := period.Parse(iso8601)
+	if err != nil {
+		return 0, err
+	}
+	return p.DurationApprox(), nil
+}
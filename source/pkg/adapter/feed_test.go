@@ -0,0 +1,63 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"testing"
+
+	"github.com/go-kivik/kivik/v3"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFeedTiming(t *testing.T) {
+	testCases := map[string]struct {
+		env     envConfig
+		want    kivik.Options
+		wantErr bool
+	}{
+		"normal feed": {
+			env: envConfig{Feed: "normal", Heartbeat: "PT10S"},
+		},
+		"continuous feed default": {
+			env:  envConfig{Feed: "continuous"},
+			want: kivik.Options{"heartbeat": int64(6000)},
+		},
+		"heartbeat": {
+			env:  envConfig{Feed: "continuous", Heartbeat: "PT30S"},
+			want: kivik.Options{"heartbeat": int64(30000)},
+		},
+		"timeout": {
+			env:  envConfig{Feed: "continuous", Timeout: "PT45S"},
+			want: kivik.Options{"timeout": int64(45000)},
+		},
+		"invalid heartbeat": {
+			env:     envConfig{Feed: "continuous", Heartbeat: "30s"},
+			wantErr: true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			got, err := feedTiming(&tc.env)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("feedTiming() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("feedTiming() (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/rickb777/date/period"
+	"go.uber.org/zap"
+
+	cdbevents "knative.dev/eventing-couchdb/source/pkg/events"
+)
+
+const (
+	// defaultGroupDelay is used when grouping without a spec.grouping.delay.
+	defaultGroupDelay = time.Second
+)
+
+// grouper gathers the events of a group until the delay following its first
+// event elapses.
+type grouper struct {
+	field string
+	delay time.Duration
+	batch bool
+
+	mu     sync.Mutex
+	groups map[string][]cloudevents.Event
+}
+
+func newGrouper(env *envConfig) (*grouper, error) {
+	if env.GroupField == "" {
+		return nil, nil
+	}
+	g := &grouper{
+		field:  env.GroupField,
+		delay:  defaultGroupDelay,
+		batch:  env.GroupBatch,
+		groups: make(map[string][]cloudevents.Event),
+	}
+	if env.GroupDelay != "" {
+		p, err := period.Parse(env.GroupDelay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid group delay %q: %v", env.GroupDelay, err)
+		}
+		g.delay = p.DurationApprox()
+	}
+	return g, nil
+}
+
+// key returns the group of the changed document, or "" when it has none.
+func (g *grouper) key(changes change) string {
+	var doc map[string]interface{}
+	if err := changes.ScanDoc(&doc); err != nil {
+		return ""
+	}
+	switch v := doc[g.field].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// add queues the event in its group, and schedules flush the first time the
+// group is seen.
+func (g *grouper) add(key string, event cloudevents.Event, flush func(string)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	events, ok := g.groups[key]
+	g.groups[key] = append(events, event)
+	if !ok {
+		time.AfterFunc(g.delay, func() { flush(key) })
+	}
+}
+
+// take removes and returns the events of a group.
+func (g *grouper) take(key string) []cloudevents.Event {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	events := g.groups[key]
+	delete(g.groups, key)
+	return events
+}
+
+// keys returns the groups currently gathered.
+func (g *grouper) keys() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	keys := make([]string, 0, len(g.groups))
+	for k := range g.groups {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// flushGroup sends the events gathered for a group.
+func (a *couchDbAdapter) flushGroup(ctx context.Context, key string) {
+	events := a.grouper.take(key)
+	if len(events) == 0 {
+		return
+	}
+
+	batched := false
+	if a.grouper.batch {
+		event, err := cdbevents.NewBatchEvent(events...)
+		if err != nil {
+			// The events are delivered on their own rather than skipped,
+			// which would move the checkpoint past them.
+			a.logger.Errorw("Error making the batch event of the group, sending its events one at a time", zap.String("group", key), zap.Error(err))
+		} else {
+			a.checkpoint.merge(event.ID(), eventIDs(events))
+			event.SetSubject(key)
+			event.SetExtension(cdbevents.GroupExtension, key)
+			events = []cloudevents.Event{event}
+			batched = true
+		}
+	}
+	if !batched {
+		for i := range events {
+			events[i].SetExtension(cdbevents.GroupExtension, key)
+			events[i].SetExtension(cdbevents.GroupSizeExtension, len(events))
+			events[i].SetExtension(cdbevents.GroupIndexExtension, i)
+		}
+	}
+
+	for _, event := range events {
+		a.deliver(ctx, event)
+	}
+}
+
+// flushGroups sends all the groups being gathered.
+func (a *couchDbAdapter) flushGroups(ctx context.Context) {
+	if a.grouper == nil {
+		return
+	}
+	for _, key := range a.grouper.keys() {
+		a.flushGroup(ctx, key)
+	}
+}
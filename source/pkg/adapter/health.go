@@ -0,0 +1,287 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+	eventingmetrics "knative.dev/eventing/pkg/metrics"
+	"knative.dev/pkg/metrics"
+)
+
+var (
+	// serverUpM is 1 while the CouchDB server answers _up, and 0 otherwise.
+	serverUpM = stats.Int64(
+		"couchdb_server_up",
+		"Whether the CouchDB server of the source is up",
+		stats.UnitDimensionless,
+	)
+	// clusterNodesM and connectedNodesM are the nodes of the cluster, and the
+	// ones the node serving the source is connected to, from _membership.
+	clusterNodesM = stats.Int64(
+		"couchdb_cluster_nodes",
+		"Number of nodes of the CouchDB cluster",
+		stats.UnitDimensionless,
+	)
+	connectedNodesM = stats.Int64(
+		"couchdb_connected_nodes",
+		"Number of CouchDB nodes the node serving the source is connected to",
+		stats.UnitDimensionless,
+	)
+
+	nameKey = tag.MustNewKey(eventingmetrics.LabelName)
+)
+
+// nodeStats are the statistics of _node/_local/_stats exported as gauges, by
+// their path in the response.
+var nodeStats = []struct {
+	path    []string
+	measure *stats.Float64Measure
+}{{
+	path:    []string{"couchdb", "open_databases"},
+	measure: stats.Float64("couchdb_open_databases", "Number of databases open on the CouchDB node", stats.UnitDimensionless),
+}, {
+	path:    []string{"couchdb", "open_os_files"},
+	measure: stats.Float64("couchdb_open_os_files", "Number of files open on the CouchDB node", stats.UnitDimensionless),
+}, {
+	path:    []string{"couchdb", "database_reads"},
+	measure: stats.Float64("couchdb_database_reads", "Number of document reads by the CouchDB node since it started", stats.UnitDimensionless),
+}, {
+	path:    []string{"couchdb", "database_writes"},
+	measure: stats.Float64("couchdb_database_writes", "Number of document writes by the CouchDB node since it started", stats.UnitDimensionless),
+}, {
+	path:    []string{"couchdb", "httpd", "requests"},
+	measure: stats.Float64("couchdb_httpd_requests", "Number of HTTP requests served by the CouchDB node since it started", stats.UnitDimensionless),
+}}
+
+func init() {
+	tags := []tag.Key{namespaceKey, nameKey, databaseKey}
+	views := []*view.View{{
+		Description: serverUpM.Description(),
+		Measure:     serverUpM,
+		Aggregation: view.LastValue(),
+		TagKeys:     tags,
+	}, {
+		Description: clusterNodesM.Description(),
+		Measure:     clusterNodesM,
+		Aggregation: view.LastValue(),
+		TagKeys:     tags,
+	}, {
+		Description: connectedNodesM.Description(),
+		Measure:     connectedNodesM,
+		Aggregation: view.LastValue(),
+		TagKeys:     tags,
+	}}
+	for _, s := range nodeStats {
+		views = append(views, &view.View{
+			Description: s.measure.Description(),
+			Measure:     s.measure,
+			Aggregation: view.LastValue(),
+			TagKeys:     tags,
+		})
+	}
+	if err := view.Register(views...); err != nil {
+		panic(err)
+	}
+}
+
+// healthScraper periodically reads the health of the CouchDB server of the
+// source, so that it is monitored along with the source by teams without
+// access to the monitoring of CouchDB itself.
+type healthScraper struct {
+	client   *http.Client
+	url      string
+	username string
+	password string
+	interval time.Duration
+
+	// failing is whether the last scrape failed, so that a lasting failure,
+	// e.g. credentials that cannot read the node statistics, is only logged
+	// once.
+	failing bool
+}
+
+// serverHealth is the outcome of a scrape. The membership and statistics
+// are missing when they could not be read.
+type serverHealth struct {
+	up             bool
+	membership     bool
+	clusterNodes   int
+	connectedNodes int
+	stats          map[string]float64
+}
+
+func newHealthScraper(env *envConfig, rawurl, driver string) (*healthScraper, error) {
+	if env.HealthMetricsInterval == "" {
+		return nil, nil
+	}
+	interval, err := time.ParseDuration(env.HealthMetricsInterval)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	s := &healthScraper{interval: interval}
+	if u.User != nil {
+		s.username = u.User.Username()
+		s.password, _ = u.User.Password()
+		u.User = nil
+	}
+	s.url = strings.TrimSuffix(u.String(), "/")
+
+	// The health is read through the transport of the CouchDB traffic, and
+	// so through its proxy.
+	s.client = &http.Client{Transport: dialectFor(driver).transport(), Timeout: interval}
+	return s, nil
+}
+
+// runHealthScraper scrapes the health every interval until ctx is done.
+func (a *couchDbAdapter) runHealthScraper(ctx context.Context) {
+	s := a.health
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		health, err := s.scrape(ctx)
+		switch {
+		case err != nil && !s.failing:
+			a.logger.Warnw("Unable to scrape the health of the CouchDB server", zap.Error(err))
+		case err == nil && s.failing:
+			a.logger.Info("Scraping the health of the CouchDB server again")
+		}
+		s.failing = err != nil
+		a.recordHealth(health)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scrape reads _up, _membership and _node/_local/_stats. The health read
+// before an error is returned along with it.
+func (s *healthScraper) scrape(ctx context.Context) (*serverHealth, error) {
+	health := &serverHealth{stats: make(map[string]float64)}
+
+	var up struct {
+		Status string `json:"status"`
+	}
+	if err := s.get(ctx, "/_up", &up); err != nil {
+		return health, err
+	}
+	health.up = up.Status == "ok"
+
+	var membership struct {
+		AllNodes     []string `json:"all_nodes"`
+		ClusterNodes []string `json:"cluster_nodes"`
+	}
+	if err := s.get(ctx, "/_membership", &membership); err != nil {
+		return health, err
+	}
+	health.membership = true
+	health.clusterNodes = len(membership.ClusterNodes)
+	health.connectedNodes = len(membership.AllNodes)
+
+	var nodeStatsResponse map[string]interface{}
+	if err := s.get(ctx, "/_node/_local/_stats", &nodeStatsResponse); err != nil {
+		return health, err
+	}
+	for _, ns := range nodeStats {
+		if v, ok := statValue(nodeStatsResponse, ns.path); ok {
+			health.stats[ns.measure.Name()] = v
+		}
+	}
+	return health, nil
+}
+
+func (s *healthScraper) get(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if s.username != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// statValue returns the value of the statistic at the given path, e.g.
+// {"couchdb": {"open_databases": {"value": 12, "type": "counter"}}}.
+func statValue(stats map[string]interface{}, path []string) (float64, bool) {
+	var node interface{} = stats
+	for _, key := range path {
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return 0, false
+		}
+		node = m[key]
+	}
+	m, ok := node.(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+	v, ok := m["value"].(float64)
+	return v, ok
+}
+
+// recordHealth records the health as metrics of the source.
+func (a *couchDbAdapter) recordHealth(health *serverHealth) {
+	ctx, err := tag.New(context.Background(),
+		tag.Insert(namespaceKey, a.namespace),
+		tag.Insert(nameKey, a.name),
+		tag.Insert(databaseKey, a.database))
+	if err != nil {
+		a.logger.Warnw("Unable to tag metric", zap.Error(err))
+		return
+	}
+	up := int64(0)
+	if health.up {
+		up = 1
+	}
+	metrics.Record(ctx, serverUpM.M(up))
+	if health.membership {
+		metrics.Record(ctx, clusterNodesM.M(int64(health.clusterNodes)))
+		metrics.Record(ctx, connectedNodesM.M(int64(health.connectedNodes)))
+	}
+	for _, ns := range nodeStats {
+		if v, ok := health.stats[ns.measure.Name()]; ok {
+			metrics.Record(ctx, ns.measure.M(v))
+		}
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHealthScrape(t *testing.T) {
+	testCases := map[string]struct {
+		stats   string
+		want    serverHealth
+		wantErr bool
+	}{
+		"admin": {
+			stats: `{"couchdb": {"open_databases": {"value": 12, "type": "counter"}, "httpd": {"requests": {"value": 345, "type": "counter"}}}}`,
+			want: serverHealth{
+				up:             true,
+				membership:     true,
+				clusterNodes:   3,
+				connectedNodes: 2,
+				stats: map[string]float64{
+					"couchdb_open_databases": 12,
+					"couchdb_httpd_requests": 345,
+				},
+			},
+		},
+		"not admin": {
+			want: serverHealth{
+				up:             true,
+				membership:     true,
+				clusterNodes:   3,
+				connectedNodes: 2,
+				stats:          map[string]float64{},
+			},
+			wantErr: true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if user, password, ok := r.BasicAuth(); !ok || user != "user" || password != "secret" {
+					t.Errorf("%s: missing credentials", r.URL.Path)
+				}
+				switch r.URL.Path {
+				case "/_up":
+					w.Write([]byte(`{"status": "ok"}`))
+				case "/_membership":
+					w.Write([]byte(`{"all_nodes": ["a", "b"], "cluster_nodes": ["a", "b", "c"]}`))
+				case "/_node/_local/_stats":
+					if tc.stats == "" {
+						w.WriteHeader(http.StatusUnauthorized)
+						return
+					}
+					w.Write([]byte(tc.stats))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			rawurl := strings.Replace(server.URL, "http://", "http://user:secret@", 1)
+			s, err := newHealthScraper(&envConfig{HealthMetricsInterval: "30s"}, rawurl, couchDriver)
+			if err != nil {
+				t.Fatalf("newHealthScraper() = %v", err)
+			}
+			got, err := s.scrape(context.Background())
+			if (err != nil) != tc.wantErr {
+				t.Errorf("scrape() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, *got, cmp.AllowUnexported(serverHealth{})); diff != "" {
+				t.Errorf("unexpected health (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
+
+func TestHealthScrapeDown(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	s, err := newHealthScraper(&envConfig{HealthMetricsInterval: "30s"}, server.URL, couchDriver)
+	if err != nil {
+		t.Fatalf("newHealthScraper() = %v", err)
+	}
+	got, err := s.scrape(context.Background())
+	if err == nil {
+		t.Error("scrape() = nil, want an error")
+	}
+	if got.up || got.membership {
+		t.Errorf("scrape() = %+v, want the server down", got)
+	}
+}
+
+func TestNewHealthScraperDisabled(t *testing.T) {
+	s, err := newHealthScraper(&envConfig{}, "http://couchdb:5984", couchDriver)
+	if err != nil || s != nil {
+		t.Errorf("newHealthScraper() = %v, %v, want nil", s, err)
+	}
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-kivik/kivik/v3"
+	"github.com/rickb777/date/period"
+	"go.uber.org/zap"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+// joiner adds to the changed documents the documents of a secondary database
+// whose IDs they hold, read through a bounded cache. It is only used by the
+// goroutine reading the changes.
+type joiner struct {
+	db        *kivik.DB
+	key       string
+	as        string
+	size      int
+	maxAge    time.Duration
+	onMissing v1alpha1.JoinMissingPolicy
+
+	// entries are the cached documents, the most recently used first.
+	entries *list.List
+	byID    map[string]*list.Element
+}
+
+// joinEntry is a cached document of the secondary database.
+type joinEntry struct {
+	id string
+	// doc is nil when the document does not exist.
+	doc  json.RawMessage
+	read time.Time
+}
+
+func newJoiner(ctx context.Context, client *kivik.Client, env *envConfig) (*joiner, error) {
+	if env.JoinDatabase == "" {
+		return nil, nil
+	}
+	j := &joiner{
+		key:       env.JoinKey,
+		as:        env.JoinAs,
+		size:      v1alpha1.DefaultJoinCacheSize,
+		maxAge:    v1alpha1.DefaultJoinMaxAge,
+		onMissing: v1alpha1.JoinMissingPolicy(env.JoinOnMissing),
+		entries:   list.New(),
+		byID:      make(map[string]*list.Element),
+	}
+	if j.as == "" {
+		j.as = env.JoinDatabase
+	}
+	if env.JoinCacheSize > 0 {
+		j.size = env.JoinCacheSize
+	}
+	if env.JoinMaxAge != "" {
+		p, err := period.Parse(env.JoinMaxAge)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max age %q: %v", env.JoinMaxAge, err)
+		}
+		j.maxAge = p.DurationApprox()
+	}
+	j.db = client.DB(ctx, env.JoinDatabase)
+	if err := j.db.Err(); err != nil {
+		return nil, fmt.Errorf("error connecting to couchDB database %q: %w", env.JoinDatabase, err)
+	}
+	return j, nil
+}
+
+// lookup returns the document of the given ID, from the cache unless it is
+// older than maxAge. When the document cannot be read again, the stale one is
+// returned along with the error.
+func (j *joiner) lookup(ctx context.Context, id string, now time.Time) (*joinEntry, error) {
+	var cached *joinEntry
+	if el, ok := j.byID[id]; ok {
+		j.entries.MoveToFront(el)
+		cached = el.Value.(*joinEntry)
+		if now.Sub(cached.read) < j.maxAge {
+			return cached, nil
+		}
+	}
+
+	var doc json.RawMessage
+	err := j.db.Get(ctx, id).ScanDoc(&doc)
+	switch {
+	case kivik.StatusCode(err) == http.StatusNotFound:
+		doc = nil
+	case err != nil:
+		return cached, err
+	}
+
+	if cached != nil {
+		cached.doc, cached.read = doc, now
+		return cached, nil
+	}
+	e := &joinEntry{id: id, doc: doc, read: now}
+	j.byID[id] = j.entries.PushFront(e)
+	if j.entries.Len() > j.size {
+		oldest := j.entries.Back()
+		j.entries.Remove(oldest)
+		delete(j.byID, oldest.Value.(*joinEntry).id)
+	}
+	return e, nil
+}
+
+// joinedChange is a change whose document holds the joined document.
+type joinedChange struct {
+	change
+	doc json.RawMessage
+}
+
+func (c *joinedChange) ScanDoc(dest interface{}) error {
+	return json.Unmarshal(c.doc, dest)
+}
+
+// join returns the change with the joined document added to its document,
+// and whether the change is still reported: the changes whose joined document
+// does not exist are skipped with onMissing skip. The documents without the
+// key, e.g. deleted ones, are not joined.
+func (a *couchDbAdapter) join(ctx context.Context, c change) (change, bool) {
+	j := a.joiner
+	if j == nil {
+		return c, true
+	}
+	var doc map[string]json.RawMessage
+	if err := c.ScanDoc(&doc); err != nil || doc == nil {
+		return c, true
+	}
+	var id string
+	if err := json.Unmarshal(doc[j.key], &id); err != nil || id == "" {
+		return c, true
+	}
+
+	e, err := j.lookup(ctx, id, time.Now())
+	joined := json.RawMessage("null")
+	switch {
+	case err != nil && e == nil:
+		// A transient error does not drop the change.
+		a.logger.Warnw("Unable to read the joined document, reporting the change without it",
+			zap.String("id", c.ID()), zap.String("joined", id), zap.Error(err))
+	case err != nil:
+		a.logger.Warnw("Unable to read the joined document again, using the cached one",
+			zap.String("id", c.ID()), zap.String("joined", id), zap.Error(err))
+		fallthrough
+	default:
+		if e.doc == nil && j.onMissing == v1alpha1.JoinMissingSkip {
+			return c, false
+		}
+		if e.doc != nil {
+			joined = e.doc
+		}
+	}
+
+	doc[j.as] = joined
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return c, true
+	}
+	return &joinedChange{change: c, doc: raw}, true
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kivik/kivik/v3"
+	"github.com/go-kivik/kivik/v3/driver"
+	"github.com/go-kivik/kivikmock/v3"
+	"knative.dev/eventing/pkg/adapter/v2"
+	kncetesting "knative.dev/eventing/pkg/adapter/v2/test"
+	pkgtesting "knative.dev/pkg/reconciler/testing"
+)
+
+func TestJoin(t *testing.T) {
+	testCases := map[string]struct {
+		onMissing string
+		wantData  []string
+	}{
+		"emit missing": {
+			wantData: []string{
+				`{"customer":"Ada","order":"o1"}`,
+				`{"customer":"Ada","order":"o2"}`,
+				`{"order":"o3"}`,
+				`{"order":"o4"}`,
+			},
+		},
+		"skip missing": {
+			onMissing: "skip",
+			wantData: []string{
+				`{"customer":"Ada","order":"o1"}`,
+				`{"customer":"Ada","order":"o2"}`,
+				`{"order":"o4"}`,
+			},
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			env := envConfig{
+				EnvConfig: adapter.EnvConfig{
+					Namespace: "default",
+				},
+				EventSource:   "test-source",
+				Database:      "orders",
+				Feed:          "normal",
+				Projection:    `{"order":"{._id}","customer":"{.customer.name}"}`,
+				JoinDatabase:  "customers",
+				JoinKey:       "customer_id",
+				JoinAs:        "customer",
+				JoinOnMissing: tc.onMissing,
+			}
+			ctx, _ := pkgtesting.SetupFakeContext(t)
+
+			c, mock := kivikmock.NewT(t)
+
+			mockDB := mock.NewDB()
+			mock.ExpectDB().WithName("orders").WillReturn(mockDB)
+			customersDB := mock.NewDB()
+			mock.ExpectDB().WithName("customers").WillReturn(customersDB)
+			mockDB.ExpectChanges().WillReturn(kivikmock.NewChanges().AddChange(&driver.Change{
+				ID:      "o1",
+				Seq:     "1-a",
+				Changes: driver.ChangedRevs{"1-rev"},
+				Doc:     json.RawMessage(`{"_id":"o1","_rev":"1-rev","customer_id":"c1"}`),
+			}).AddChange(&driver.Change{
+				ID:      "o2",
+				Seq:     "2-b",
+				Changes: driver.ChangedRevs{"1-rev"},
+				Doc:     json.RawMessage(`{"_id":"o2","_rev":"1-rev","customer_id":"c1"}`),
+			}).AddChange(&driver.Change{
+				ID:      "o3",
+				Seq:     "3-c",
+				Changes: driver.ChangedRevs{"1-rev"},
+				Doc:     json.RawMessage(`{"_id":"o3","_rev":"1-rev","customer_id":"c2"}`),
+			}).AddChange(&driver.Change{
+				ID:      "o4",
+				Seq:     "4-d",
+				Changes: driver.ChangedRevs{"1-rev"},
+				Doc:     json.RawMessage(`{"_id":"o4","_rev":"1-rev"}`),
+			}))
+			// The second order of c1 reads it from the cache.
+			customersDB.ExpectGet().WithDocID("c1").WillReturn(&driver.Document{
+				Rev:  "1-c1",
+				Body: ioutil.NopCloser(strings.NewReader(`{"_id":"c1","_rev":"1-c1","name":"Ada"}`)),
+			})
+			customersDB.ExpectGet().WithDocID("c2").WillReturnError(&kivik.Error{HTTPStatus: http.StatusNotFound})
+
+			ce := kncetesting.NewTestClient()
+			a := newAdapter(ctx, &env, ce, c.DSN(), "kivikmock").(*couchDbAdapter)
+			a.processChanges(context.Background())
+
+			sent := ce.Sent()
+			if len(sent) != len(tc.wantData) {
+				t.Fatalf("sent %d events, want %d", len(sent), len(tc.wantData))
+			}
+			for i, want := range tc.wantData {
+				if got := string(sent[i].Data()); got != want {
+					t.Errorf("event %d data = %s, want %s", i, got, want)
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestJoinLookup(t *testing.T) {
+	c, mock := kivikmock.NewT(t)
+	mockDB := mock.NewDB()
+	mock.ExpectDB().WithName("customers").WillReturn(mockDB)
+	doc := func(id string) *driver.Document {
+		return &driver.Document{
+			Rev:  "1-" + id,
+			Body: ioutil.NopCloser(strings.NewReader(`{"_id":"` + id + `","_rev":"1-` + id + `"}`)),
+		}
+	}
+	mockDB.ExpectGet().WithDocID("c1").WillReturn(doc("c1"))
+	mockDB.ExpectGet().WithDocID("c2").WillReturn(doc("c2"))
+	// c1 was evicted by c2.
+	mockDB.ExpectGet().WithDocID("c1").WillReturn(doc("c1"))
+	// c1 is stale and cannot be read again.
+	mockDB.ExpectGet().WithDocID("c1").WillReturnError(&kivik.Error{HTTPStatus: http.StatusServiceUnavailable})
+
+	ctx := context.Background()
+	j, err := newJoiner(ctx, c, &envConfig{JoinDatabase: "customers", JoinKey: "customer_id", JoinCacheSize: 1, JoinMaxAge: "PT1M"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for _, id := range []string{"c1", "c1", "c2", "c1"} {
+		if _, err := j.lookup(ctx, id, now); err != nil {
+			t.Fatalf("lookup(%s) = %v", id, err)
+		}
+	}
+	if got := j.entries.Len(); got != 1 {
+		t.Errorf("cached %d documents, want 1", got)
+	}
+
+	e, err := j.lookup(ctx, "c1", now.Add(time.Minute))
+	if err == nil {
+		t.Error("lookup() succeeded, want an error")
+	}
+	if e == nil || e.id != "c1" {
+		t.Errorf("lookup() = %v, want the stale c1", e)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
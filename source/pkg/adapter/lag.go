@@ -0,0 +1,124 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+var (
+	// feedLagM is how many changes of the database follow the last one
+	// delivered, from the update sequence of the database.
+	feedLagM = stats.Int64(
+		"couchdb_feed_lag",
+		"Number of changes of the database following the last one delivered",
+		stats.UnitDimensionless,
+	)
+)
+
+func init() {
+	if err := view.Register(&view.View{
+		Description: feedLagM.Description(),
+		Measure:     feedLagM,
+		Aggregation: view.LastValue(),
+		TagKeys:     []tag.Key{namespaceKey, nameKey, databaseKey},
+	}); err != nil {
+		panic(err)
+	}
+}
+
+// lagMonitor polls the update sequence of the database to export the lag of
+// the source.
+type lagMonitor struct {
+	interval time.Duration
+	// failing is whether the last poll failed, to only log the first
+	// failure.
+	failing bool
+}
+
+func newLagMonitor(env *envConfig) (*lagMonitor, error) {
+	if env.LagMetricsInterval == "" {
+		return nil, nil
+	}
+	interval, err := time.ParseDuration(env.LagMetricsInterval)
+	if err != nil {
+		return nil, err
+	}
+	return &lagMonitor{interval: interval}, nil
+}
+
+// runLagMonitor records the lag every interval until ctx is done.
+func (a *couchDbAdapter) runLagMonitor(ctx context.Context) {
+	m := a.lagMonitor
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		lag, ok, err := a.lag(ctx)
+		switch {
+		case err != nil && !m.failing:
+			a.logger.Warnw("Unable to compute the lag of the source", zap.Error(err))
+		case err == nil && m.failing:
+			a.logger.Info("Computing the lag of the source again")
+		}
+		m.failing = err != nil
+		if ok {
+			a.recordSource(feedLagM.M(lag))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// lag returns how many changes follow the last one delivered: the difference
+// between the numbers of the update sequence of the database and of the
+// checkpoint. It is approximate on clusters, whose sequence numbers sum the
+// ones of the shards, and unknown until a "now" starting sequence resolves.
+func (a *couchDbAdapter) lag(ctx context.Context) (int64, bool, error) {
+	since := a.checkpoint.sequence()
+	if since == "" || since == v1alpha1.SequenceNow {
+		return 0, false, nil
+	}
+	stats, err := a.couchDB.Stats(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	latest, err := v1alpha1.SequenceNumber(stats.UpdateSeq)
+	if err != nil {
+		return 0, false, err
+	}
+	delivered, err := v1alpha1.SequenceNumber(since)
+	if err != nil {
+		return 0, false, err
+	}
+	if latest < delivered {
+		// The database was recreated, or the stats lag behind the feed.
+		return 0, true, nil
+	}
+	return latest - delivered, true, nil
+}
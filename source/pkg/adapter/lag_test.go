@@ -0,0 +1,96 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-kivik/kivik/v3/driver"
+	"github.com/go-kivik/kivikmock/v3"
+	"knative.dev/eventing/pkg/adapter/v2"
+	kncetesting "knative.dev/eventing/pkg/adapter/v2/test"
+	pkgtesting "knative.dev/pkg/reconciler/testing"
+)
+
+func TestLag(t *testing.T) {
+	testCases := map[string]struct {
+		since     string
+		updateSeq string
+		statsErr  error
+		want      int64
+		wantOK    bool
+		wantErr   bool
+	}{
+		"behind": {
+			since:     "10-g1AAAAB",
+			updateSeq: "15-g1AAAAC",
+			want:      5,
+			wantOK:    true,
+		},
+		"caught up": {
+			since:     "15-g1AAAAC",
+			updateSeq: "15-g1AAAAC",
+			wantOK:    true,
+		},
+		"from the start": {
+			since:     "0",
+			updateSeq: "3-g1AAAAA",
+			want:      3,
+			wantOK:    true,
+		},
+		"now": {
+			since: "now",
+		},
+		"stats error": {
+			since:    "10-g1AAAAB",
+			statsErr: errors.New("unavailable"),
+			wantErr:  true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			env := envConfig{
+				EnvConfig: adapter.EnvConfig{
+					Namespace: "default",
+				},
+				EventSource: "test-source",
+				Database:    "testdb",
+				Feed:        "normal",
+			}
+			ctx, _ := pkgtesting.SetupFakeContext(t)
+
+			c, mock := kivikmock.NewT(t)
+			mockDB := mock.NewDB()
+			mock.ExpectDB().WithName("testdb").WillReturn(mockDB)
+			if tc.since != "now" {
+				mockDB.ExpectStats().WillReturn(&driver.DBStats{UpdateSeq: tc.updateSeq}).WillReturnError(tc.statsErr)
+			}
+			a := newAdapter(ctx, &env, kncetesting.NewTestClient(), c.DSN(), "kivikmock").(*couchDbAdapter)
+			a.checkpoint.restart(tc.since)
+
+			got, ok, err := a.lag(context.Background())
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("lag() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want || ok != tc.wantOK {
+				t.Errorf("lag() = %d, %v, want %d, %v", got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+const (
+	// The timings of the lease, as in the Kubernetes controllers. A replica
+	// losing the lease stops at the renew deadline, before the lease expires
+	// for the standby.
+	leaseDuration = 15 * time.Second
+	renewDeadline = 10 * time.Second
+	retryPeriod   = 2 * time.Second
+)
+
+// lease makes a single receive adapter read and deliver the changes, in the
+// global ordering mode or with spec.highAvailability, while the other
+// replicas stand by to take over.
+type lease struct {
+	lock   resourcelock.Interface
+	logger *zap.SugaredLogger
+
+	leaseDuration time.Duration
+	renewDeadline time.Duration
+	retryPeriod   time.Duration
+
+	// lost is called when the lease is lost while delivering. It stops the
+	// process, so that nothing is delivered past the lease.
+	lost func()
+}
+
+func newLease(env *envConfig, logger *zap.SugaredLogger) (*lease, error) {
+	if env.LeaseName == "" {
+		return nil, nil
+	}
+	cfg, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	kube, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	return &lease{
+		lock: &resourcelock.LeaseLock{
+			LeaseMeta: metav1.ObjectMeta{
+				Namespace: env.Namespace,
+				Name:      env.LeaseName,
+			},
+			Client:     kube.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+		},
+		logger:        logger,
+		leaseDuration: leaseDuration,
+		renewDeadline: renewDeadline,
+		retryPeriod:   retryPeriod,
+		lost: func() {
+			logger.Fatal("Lost the lease of the source")
+		},
+	}, nil
+}
+
+// run waits for the lease and runs process while holding it, until ctx is
+// done. The lease is only released once process returned, so that the
+// standby never delivers alongside it.
+func (l *lease) run(ctx context.Context, process func(context.Context)) {
+	// The election outlives ctx while process winds down.
+	election, stop := context.WithCancel(context.Background())
+	defer stop()
+
+	leading := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			select {
+			case <-leading:
+			default:
+				// Still standing by.
+				stop()
+			}
+		case <-leading:
+		}
+	}()
+
+	leaderelection.RunOrDie(election, leaderelection.LeaderElectionConfig{
+		Lock:            l.lock,
+		LeaseDuration:   l.leaseDuration,
+		RenewDeadline:   l.renewDeadline,
+		RetryPeriod:     l.retryPeriod,
+		ReleaseOnCancel: true,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(held context.Context) {
+				close(leading)
+				l.logger.Infow("Acquired the lease, delivering the changes", zap.String("lease", l.lock.Describe()))
+				go func() {
+					<-held.Done()
+					if ctx.Err() == nil {
+						l.lost()
+					}
+				}()
+				process(ctx)
+				stop()
+			},
+			OnStoppedLeading: func() {},
+		},
+	})
+}
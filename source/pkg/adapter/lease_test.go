@@ -0,0 +1,212 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// memoryLease is a lease shared in memory by the candidates.
+type memoryLease struct {
+	mu     sync.Mutex
+	record *resourcelock.LeaderElectionRecord
+}
+
+// memoryLock is the lock of a candidate on a memoryLease.
+type memoryLock struct {
+	lease    *memoryLease
+	identity string
+}
+
+func (l *memoryLock) Get(ctx context.Context) (*resourcelock.LeaderElectionRecord, []byte, error) {
+	l.lease.mu.Lock()
+	defer l.lease.mu.Unlock()
+	if l.lease.record == nil {
+		return nil, nil, apierrors.NewNotFound(schema.GroupResource{Resource: "leases"}, "test")
+	}
+	record := *l.lease.record
+	raw, err := json.Marshal(record)
+	return &record, raw, err
+}
+
+func (l *memoryLock) Create(ctx context.Context, ler resourcelock.LeaderElectionRecord) error {
+	return l.Update(ctx, ler)
+}
+
+func (l *memoryLock) Update(ctx context.Context, ler resourcelock.LeaderElectionRecord) error {
+	l.lease.mu.Lock()
+	defer l.lease.mu.Unlock()
+	l.lease.record = &ler
+	return nil
+}
+
+func (l *memoryLock) RecordEvent(string) {}
+func (l *memoryLock) Identity() string   { return l.identity }
+func (l *memoryLock) Describe() string   { return "test" }
+
+func newTestLease(shared *memoryLease, identity string) *lease {
+	return &lease{
+		lock:          &memoryLock{lease: shared, identity: identity},
+		logger:        zap.NewNop().Sugar(),
+		leaseDuration: time.Second,
+		renewDeadline: 500 * time.Millisecond,
+		retryPeriod:   50 * time.Millisecond,
+		lost:          func() {},
+	}
+}
+
+func TestLeaseFailover(t *testing.T) {
+	shared := &memoryLease{}
+	var mu sync.Mutex
+	var delivering []string
+	process := func(identity string) func(context.Context) {
+		return func(ctx context.Context) {
+			mu.Lock()
+			delivering = append(delivering, identity)
+			active := len(delivering)
+			mu.Unlock()
+			if active > 1 {
+				t.Errorf("%s delivers alongside %v", identity, delivering)
+			}
+			<-ctx.Done()
+			// Winding down, e.g. flushing the batches, before releasing.
+			time.Sleep(100 * time.Millisecond)
+			mu.Lock()
+			delivering = delivering[1:]
+			mu.Unlock()
+		}
+	}
+
+	activeCtx, stopActive := context.WithCancel(context.Background())
+	activeDone := make(chan struct{})
+	go func() {
+		defer close(activeDone)
+		newTestLease(shared, "active").run(activeCtx, process("active"))
+	}()
+	waitForHolder(t, shared, "active")
+
+	standbyCtx, stopStandby := context.WithCancel(context.Background())
+	standbyDone := make(chan struct{})
+	go func() {
+		defer close(standbyDone)
+		newTestLease(shared, "standby").run(standbyCtx, process("standby"))
+	}()
+
+	// The standby waits while the active replica holds the lease.
+	time.Sleep(200 * time.Millisecond)
+	mu.Lock()
+	if len(delivering) != 1 || delivering[0] != "active" {
+		t.Errorf("delivering = %v, want the active replica only", delivering)
+	}
+	mu.Unlock()
+
+	stopActive()
+	<-activeDone
+	waitForHolder(t, shared, "standby")
+
+	stopStandby()
+	select {
+	case <-standbyDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the standby did not stop")
+	}
+}
+
+func TestLeaseStopWhileStandingBy(t *testing.T) {
+	shared := &memoryLease{record: &resourcelock.LeaderElectionRecord{
+		HolderIdentity:       "other",
+		LeaseDurationSeconds: 60,
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		newTestLease(shared, "standby").run(ctx, func(context.Context) {
+			t.Error("the standby delivered while another replica holds the lease")
+		})
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the standby did not stop")
+	}
+}
+
+func TestLeaseLost(t *testing.T) {
+	shared := &memoryLease{}
+	l := newTestLease(shared, "active")
+	lost := make(chan struct{})
+	l.lost = func() { close(lost) }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go l.run(ctx, func(ctx context.Context) { <-ctx.Done() })
+	waitForHolder(t, shared, "active")
+
+	// Another replica takes the lease over, e.g. after a network partition.
+	go func() {
+		for {
+			select {
+			case <-lost:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+			shared.mu.Lock()
+			shared.record = &resourcelock.LeaderElectionRecord{
+				HolderIdentity:       "other",
+				LeaseDurationSeconds: 60,
+				RenewTime:            metav1.Now(),
+			}
+			shared.mu.Unlock()
+		}
+	}()
+	select {
+	case <-lost:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the loss of the lease was not detected")
+	}
+}
+
+func waitForHolder(t *testing.T, shared *memoryLease, identity string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		shared.mu.Lock()
+		holder := ""
+		if shared.record != nil {
+			holder = shared.record.HolderIdentity
+		}
+		shared.mu.Unlock()
+		if holder == identity {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%s did not acquire the lease", identity)
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// defaultMaxLineBytes and defaultMaxJSONDepth are the parsing limits used
+	// when the source does not set spec.limits.
+	defaultMaxLineBytes = 64 << 20
+	defaultMaxJSONDepth = 512
+
+	// The names of the parsing limits, as reported in errors and metrics.
+	limitLineBytes = "maxLineBytes"
+	limitJSONDepth = "maxJSONDepth"
+)
+
+// parsingLimits bound what the adapter accepts from the changes feed.
+type parsingLimits struct {
+	maxLineBytes int64
+	maxJSONDepth int
+}
+
+// limits are the parsing limits of the changes feed responses.
+var limits = parsingLimits{
+	maxLineBytes: defaultMaxLineBytes,
+	maxJSONDepth: defaultMaxJSONDepth,
+}
+
+// setLimits overrides the default parsing limits. Zero values keep the
+// defaults.
+func setLimits(maxLineBytes int64, maxJSONDepth int) {
+	if maxLineBytes > 0 {
+		limits.maxLineBytes = maxLineBytes
+	}
+	if maxJSONDepth > 0 {
+		limits.maxJSONDepth = maxJSONDepth
+	}
+}
+
+// limitError is returned by the changes feed when a response trips a parsing
+// limit.
+type limitError struct {
+	// Limit is the name of the limit that tripped.
+	Limit string
+
+	// Max is the value of the limit.
+	Max int64
+}
+
+func (e *limitError) Error() string {
+	return fmt.Sprintf("the changes feed response exceeds the %s limit of %d", e.Limit, e.Max)
+}
+
+// limitTransport enforces the parsing limits on the changes feed responses
+// before they reach the JSON decoder of the driver.
+type limitTransport struct {
+	base http.RoundTripper
+}
+
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || !strings.HasSuffix(req.URL.Path, "/_changes") {
+		return resp, err
+	}
+	resp.Body = &limitReader{ReadCloser: resp.Body, limits: limits}
+	return resp, nil
+}
+
+// limitReader tracks the length of the current line and the JSON nesting
+// depth of the bytes read through it.
+type limitReader struct {
+	io.ReadCloser
+	limits parsingLimits
+
+	line     int64
+	depth    int
+	inString bool
+	escaped  bool
+	err      error
+}
+
+func (r *limitReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	n, err := r.ReadCloser.Read(p)
+	for i, b := range p[:n] {
+		if r.err = r.scan(b); r.err != nil {
+			return i, r.err
+		}
+	}
+	return n, err
+}
+
+func (r *limitReader) scan(b byte) error {
+	if b == '\n' {
+		r.line = 0
+	} else if r.line++; r.line > r.limits.maxLineBytes {
+		return &limitError{Limit: limitLineBytes, Max: r.limits.maxLineBytes}
+	}
+
+	switch {
+	case r.escaped:
+		r.escaped = false
+	case r.inString:
+		switch b {
+		case '\\':
+			r.escaped = true
+		case '"':
+			r.inString = false
+		}
+	case b == '"':
+		r.inString = true
+	case b == '{' || b == '[':
+		if r.depth++; r.depth > r.limits.maxJSONDepth {
+			return &limitError{Limit: limitJSONDepth, Max: int64(r.limits.maxJSONDepth)}
+		}
+	case b == '}' || b == ']':
+		r.depth--
+	}
+	return nil
+}
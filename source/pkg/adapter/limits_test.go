@@ -0,0 +1,93 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kivik/kivik/v3"
+)
+
+func TestLimitReader(t *testing.T) {
+	testLimits := parsingLimits{maxLineBytes: 32, maxJSONDepth: 3}
+	testCases := map[string]struct {
+		body      string
+		wantLimit string
+	}{
+		"within limits": {
+			body: "{\"results\":[\n{\"id\":\"a\",\"seq\":\"1\"}\n]}\n",
+		},
+		"brackets in strings": {
+			body: `{"a":{"b":"[[[{{{\"]]]"}}}`,
+		},
+		"long line": {
+			body:      `{"id":"` + strings.Repeat("a", 32) + `"}`,
+			wantLimit: limitLineBytes,
+		},
+		"deep nesting": {
+			body:      `{"a":{"b":[{"c":1}]}}`,
+			wantLimit: limitJSONDepth,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			r := &limitReader{ReadCloser: ioutil.NopCloser(strings.NewReader(tc.body)), limits: testLimits}
+			_, err := ioutil.ReadAll(r)
+			var limitErr *limitError
+			if tc.wantLimit == "" {
+				if err != nil {
+					t.Errorf("ReadAll() = %v, want no error", err)
+				}
+			} else if !errors.As(err, &limitErr) || limitErr.Limit != tc.wantLimit {
+				t.Errorf("ReadAll() = %v, want the %s limit to trip", err, tc.wantLimit)
+			}
+		})
+	}
+}
+
+func TestLimitTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{\"results\":[\n{\"seq\":\"1-a\",\"id\":\"" + strings.Repeat("a", 64) + "\",\"changes\":[{\"rev\":\"1-x\"}]}\n],\n\"last_seq\":\"1-a\",\"pending\":0}\n"))
+	}))
+	defer server.Close()
+
+	defer func(l parsingLimits) { limits = l }(limits)
+	setLimits(32, 0)
+
+	client, err := kivik.New(couchDriver, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := client.DB(context.Background(), "db").Changes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for changes.Next() {
+		t.Errorf("unexpected change %s", changes.ID())
+	}
+	var limitErr *limitError
+	if !errors.As(changes.Err(), &limitErr) || limitErr.Limit != limitLineBytes {
+		t.Errorf("Err() = %v, want the %s limit to trip", changes.Err(), limitLineBytes)
+	}
+}
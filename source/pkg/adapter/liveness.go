@@ -0,0 +1,124 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+// liveness tracks whether the changes feed can be read, and serves it on the
+// health endpoints: /readyz fails as soon as the feed fails, and /healthz
+// once it has failed for longer than the threshold, so that Kubernetes
+// restarts the adapters stuck on a feed they cannot reconnect. The standby
+// replicas, which do not read the feed, stay healthy.
+type liveness struct {
+	port      string
+	threshold time.Duration
+
+	mu sync.Mutex
+	// failing is when the feed started failing, zero while it can be read.
+	failing time.Time
+}
+
+func newLiveness(env *envConfig) (*liveness, error) {
+	if env.HealthPort == "" {
+		return nil, nil
+	}
+	threshold := v1alpha1.DefaultDisconnectedThreshold
+	if env.DisconnectedThreshold != "" {
+		var err error
+		if threshold, err = time.ParseDuration(env.DisconnectedThreshold); err != nil {
+			return nil, err
+		}
+	}
+	return &liveness{port: env.HealthPort, threshold: threshold}, nil
+}
+
+// connected records that the feed was opened, or read to its end.
+func (l *liveness) connected() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.failing = time.Time{}
+}
+
+// failed records that the feed could not be opened, or failed while read.
+func (l *liveness) failed(now time.Time) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.failing.IsZero() {
+		l.failing = now
+	}
+}
+
+// check returns an error when the feed has failed for longer than max.
+func (l *liveness) check(now time.Time, max time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.failing.IsZero() {
+		return nil
+	}
+	if d := now.Sub(l.failing); d >= max {
+		return fmt.Errorf("the changes feed has been failing for %v", d.Round(time.Second))
+	}
+	return nil
+}
+
+func (l *liveness) handler(max time.Duration, logger *zap.SugaredLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := l.check(time.Now(), max); err != nil {
+			if max > 0 {
+				logger.Errorw("Reporting unhealthy", zap.Error(err))
+			}
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// serve serves the health endpoints until ctx is done.
+func (l *liveness) serve(ctx context.Context, logger *zap.SugaredLogger, servers *sync.WaitGroup) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", l.handler(l.threshold, logger))
+	mux.HandleFunc("/readyz", l.handler(0, logger))
+	server := &http.Server{Addr: ":" + l.port, Handler: mux}
+	servers.Add(1)
+	go func() {
+		defer servers.Done()
+		<-ctx.Done()
+		server.Close()
+	}()
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Errorw("The health server failed", zap.Error(err))
+		}
+	}()
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestLiveness(t *testing.T) {
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	l, err := newLiveness(&envConfig{HealthPort: "8081", DisconnectedThreshold: "1m"})
+	if err != nil {
+		t.Fatalf("newLiveness() = %v", err)
+	}
+
+	if err := l.check(start, 0); err != nil {
+		t.Errorf("check() before any failure = %v, want nil", err)
+	}
+	l.failed(start)
+	// A later failure does not restart the disconnection.
+	l.failed(start.Add(30 * time.Second))
+	if err := l.check(start.Add(30*time.Second), 0); err == nil {
+		t.Error("check() while failing = nil, want an error when not ready")
+	}
+	if err := l.check(start.Add(30*time.Second), l.threshold); err != nil {
+		t.Errorf("check() under the threshold = %v, want nil", err)
+	}
+	if err := l.check(start.Add(time.Minute), l.threshold); err == nil {
+		t.Error("check() past the threshold = nil, want an error")
+	}
+	l.connected()
+	if err := l.check(start.Add(2*time.Minute), 0); err != nil {
+		t.Errorf("check() once connected = %v, want nil", err)
+	}
+
+	if _, err := newLiveness(&envConfig{HealthPort: "8081", DisconnectedThreshold: "soon"}); err == nil {
+		t.Error("newLiveness() with an invalid threshold = nil, want an error")
+	}
+
+	// Liveness is optional.
+	disabled, err := newLiveness(&envConfig{})
+	if err != nil || disabled != nil {
+		t.Fatalf("newLiveness() without a port = %v, %v, want nil, nil", disabled, err)
+	}
+	disabled.failed(start)
+	disabled.connected()
+}
+
+func TestLivenessHandler(t *testing.T) {
+	l := &liveness{threshold: time.Hour}
+	logger := zap.NewNop().Sugar()
+
+	serve := func(h http.Handler) int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w.Code
+	}
+
+	if got := serve(l.handler(0, logger)); got != http.StatusOK {
+		t.Errorf("readyz while connected = %d, want %d", got, http.StatusOK)
+	}
+	l.failed(time.Now())
+	if got := serve(l.handler(0, logger)); got != http.StatusServiceUnavailable {
+		t.Errorf("readyz while failing = %d, want %d", got, http.StatusServiceUnavailable)
+	}
+	if got := serve(l.handler(l.threshold, logger)); got != http.StatusOK {
+		t.Errorf("healthz under the threshold = %d, want %d", got, http.StatusOK)
+	}
+	l.failing = time.Now().Add(-2 * time.Hour)
+	if got := serve(l.handler(l.threshold, logger)); got != http.StatusServiceUnavailable {
+		t.Errorf("healthz past the threshold = %d, want %d", got, http.StatusServiceUnavailable)
+	}
+}
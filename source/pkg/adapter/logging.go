@@ -0,0 +1,109 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/logging"
+)
+
+// loggingPollInterval is how often the logging configuration is read again.
+// The kubelet refreshes the mounted ConfigMaps about every minute.
+const loggingPollInterval = 10 * time.Second
+
+// dynamicLogger is the logger of an adapter process, whose level follows
+// config-logging, mounted in a directory with a file per key.
+type dynamicLogger struct {
+	logger *zap.SugaredLogger
+	level  zap.AtomicLevel
+}
+
+// processLogger is the logger of the adapter process, single or multi-tenant.
+var processLogger dynamicLogger
+
+// get returns the logger of the component, built on first use from the
+// logging configuration mounted in dir, or else from K_LOGGING_CONFIG.
+func (l *dynamicLogger) get(dir, jsonConfig, component string) *zap.SugaredLogger {
+	if l.logger != nil {
+		return l.logger
+	}
+	config, err := logging.JSONToConfig(jsonConfig)
+	if err != nil {
+		// An empty map is the default configuration, which cannot fail.
+		config, _ = logging.NewConfigFromMap(map[string]string{})
+	}
+	if dir != "" {
+		if data, err := readLoggingConfig(dir); err == nil && len(data) > 0 {
+			if mounted, err := logging.NewConfigFromMap(data); err == nil {
+				config = mounted
+			}
+		}
+	}
+	l.logger, l.level = logging.NewLoggerFromConfig(config, component)
+	return l.logger
+}
+
+// watch updates the level of the logger as the logging configuration
+// mounted in dir changes, until ctx is done.
+func (l *dynamicLogger) watch(ctx context.Context, dir, component string) {
+	if dir == "" || l.logger == nil {
+		return
+	}
+	update := logging.UpdateLevelFromConfigMap(l.logger, l.level, component)
+	for {
+		data, err := readLoggingConfig(dir)
+		if err != nil {
+			l.logger.Warnw("Unable to read the logging configuration", zap.Error(err))
+		} else {
+			update(&corev1.ConfigMap{Data: data})
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(loggingPollInterval):
+		}
+	}
+}
+
+// readLoggingConfig reads the keys of a ConfigMap mounted in dir, skipping
+// the entries the kubelet uses to swap its contents atomically.
+func readLoggingConfig(dir string) (map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string]string, len(entries))
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), "..") {
+			continue
+		}
+		value, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			// Directories cannot be read, and are not keys.
+			continue
+		}
+		data[e.Name()] = string(value)
+	}
+	return data, nil
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestDynamicLogger(t *testing.T) {
+	dir := t.TempDir()
+	write := func(level string) {
+		// The kubelet links the keys to a hidden directory it swaps.
+		data := filepath.Join(dir, "..data")
+		os.RemoveAll(data)
+		if err := os.Mkdir(data, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(data, "loglevel.couchdbsource"), []byte(level), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("debug")
+	link := filepath.Join(dir, "loglevel.couchdbsource")
+	if err := os.Symlink(filepath.Join("..data", "loglevel.couchdbsource"), link); err != nil {
+		t.Fatal(err)
+	}
+
+	var l dynamicLogger
+	l.get(dir, "", "couchdbsource")
+	if got := l.level.Level(); got != zapcore.DebugLevel {
+		t.Fatalf("level = %v, want debug", got)
+	}
+	if again := l.get("", "", "other"); again != l.logger {
+		t.Error("get built another logger")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	write("error")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.watch(ctx, dir, "couchdbsource")
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for l.level.Level() != zapcore.ErrorLevel {
+		if time.Now().After(deadline) {
+			t.Fatalf("level = %v, want error", l.level.Level())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+}
+
+func TestReadLoggingConfigMissing(t *testing.T) {
+	if _, err := readLoggingConfig(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("readLoggingConfig() = nil, want an error")
+	}
+}
@@ -0,0 +1,341 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+	eventingmetrics "knative.dev/eventing/pkg/metrics"
+	"knative.dev/pkg/metrics"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	cdbevents "knative.dev/eventing-couchdb/source/pkg/events"
+)
+
+var (
+	// limitExceededM counts the changes feed responses rejected for tripping
+	// a parsing limit.
+	limitExceededM = stats.Int64(
+		"couchdb_limit_exceeded_count",
+		"Number of changes feed responses rejected for exceeding a parsing limit",
+		stats.UnitDimensionless,
+	)
+
+	// decodeErrorM counts the changes that could not be turned into events.
+	decodeErrorM = stats.Int64(
+		"couchdb_decode_error_count",
+		"Number of changes that could not be turned into events",
+		stats.UnitDimensionless,
+	)
+
+	// deliveryRetryM counts the deliveries to the sink tried again after a
+	// failed attempt.
+	deliveryRetryM = stats.Int64(
+		"couchdb_delivery_retry_count",
+		"Number of event deliveries retried after a failed attempt",
+		stats.UnitDimensionless,
+	)
+
+	// retryBudgetExhaustedM counts the deliveries given up on after using
+	// all the retries of spec.delivery.
+	retryBudgetExhaustedM = stats.Int64(
+		"couchdb_delivery_retry_budget_exhausted_count",
+		"Number of event deliveries given up on after exhausting their retries",
+		stats.UnitDimensionless,
+	)
+
+	// eventsSkippedM counts the events skipped after the sink rejected them
+	// for good too many times, without a dead letter sink.
+	eventsSkippedM = stats.Int64(
+		"couchdb_events_skipped_count",
+		"Number of events skipped after the sink kept rejecting them",
+		stats.UnitDimensionless,
+	)
+
+	// bufferBytesM is the size of the events waiting in the buffer of
+	// spec.buffer.
+	bufferBytesM = stats.Int64(
+		"couchdb_buffer_bytes",
+		"Size of the events waiting in the buffer for the sink",
+		stats.UnitBytes,
+	)
+
+	// bufferDroppedM counts the buffered events dropped to make room for
+	// newer ones.
+	bufferDroppedM = stats.Int64(
+		"couchdb_buffer_dropped_count",
+		"Number of buffered events dropped because the buffer was full",
+		stats.UnitDimensionless,
+	)
+
+	// eventsEmittedM counts the events accepted by the sink.
+	eventsEmittedM = stats.Int64(
+		"couchdb_events_emitted_count",
+		"Number of events accepted by the sink",
+		stats.UnitDimensionless,
+	)
+
+	// deliveryErrorM counts the failed attempts to deliver an event to the
+	// sink.
+	deliveryErrorM = stats.Int64(
+		"couchdb_delivery_error_count",
+		"Number of failed attempts to deliver an event to the sink",
+		stats.UnitDimensionless,
+	)
+
+	// deliveryLatencyM is the duration of the attempts to deliver an event
+	// to the sink.
+	deliveryLatencyM = stats.Float64(
+		"couchdb_delivery_latencies",
+		"Duration of the attempts to deliver an event to the sink",
+		stats.UnitMilliseconds,
+	)
+
+	// feedReconnectM counts the changes feeds opened again after the
+	// previous one failed.
+	feedReconnectM = stats.Int64(
+		"couchdb_feed_reconnect_count",
+		"Number of changes feeds opened again after a failure",
+		stats.UnitDimensionless,
+	)
+
+	// feedReconnectDelayM is the delay before opening the changes feed
+	// again after a failure.
+	feedReconnectDelayM = stats.Float64(
+		"couchdb_feed_reconnect_delays",
+		"Delay before opening the changes feed again after a failure",
+		stats.UnitMilliseconds,
+	)
+
+	namespaceKey = tag.MustNewKey(eventingmetrics.LabelNamespaceName)
+	databaseKey  = tag.MustNewKey("database")
+	limitKey     = tag.MustNewKey("limit")
+	policyKey    = tag.MustNewKey("policy")
+	reasonKey    = tag.MustNewKey("reason")
+
+	eventTypeKey         = tag.MustNewKey(eventingmetrics.LabelEventType)
+	responseCodeKey      = tag.MustNewKey(eventingmetrics.LabelResponseCode)
+	responseCodeClassKey = tag.MustNewKey(eventingmetrics.LabelResponseCodeClass)
+)
+
+func init() {
+	if err := view.Register(
+		&view.View{
+			Description: limitExceededM.Description(),
+			Measure:     limitExceededM,
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{namespaceKey, databaseKey, limitKey},
+		},
+		&view.View{
+			Description: decodeErrorM.Description(),
+			Measure:     decodeErrorM,
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{namespaceKey, databaseKey, policyKey},
+		},
+		&view.View{
+			Description: deliveryRetryM.Description(),
+			Measure:     deliveryRetryM,
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{namespaceKey, nameKey, reasonKey},
+		},
+		&view.View{
+			Description: retryBudgetExhaustedM.Description(),
+			Measure:     retryBudgetExhaustedM,
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{namespaceKey, nameKey, reasonKey},
+		},
+		&view.View{
+			Description: eventsSkippedM.Description(),
+			Measure:     eventsSkippedM,
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{namespaceKey, nameKey, reasonKey},
+		},
+		&view.View{
+			Description: eventsEmittedM.Description(),
+			Measure:     eventsEmittedM,
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{namespaceKey, nameKey, databaseKey, eventTypeKey},
+		},
+		&view.View{
+			Description: deliveryErrorM.Description(),
+			Measure:     deliveryErrorM,
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{namespaceKey, nameKey, databaseKey, responseCodeKey, reasonKey},
+		},
+		&view.View{
+			Description: deliveryLatencyM.Description(),
+			Measure:     deliveryLatencyM,
+			Aggregation: view.Distribution(metrics.Buckets125(1, 10000)...),
+			TagKeys:     []tag.Key{namespaceKey, nameKey, databaseKey, responseCodeClassKey},
+		},
+		&view.View{
+			Description: feedReconnectM.Description(),
+			Measure:     feedReconnectM,
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{namespaceKey, nameKey, databaseKey, reasonKey},
+		},
+		&view.View{
+			Description: feedReconnectDelayM.Description(),
+			Measure:     feedReconnectDelayM,
+			Aggregation: view.Distribution(metrics.Buckets125(100, 1000000)...),
+			TagKeys:     []tag.Key{namespaceKey, nameKey, databaseKey},
+		},
+		&view.View{
+			Description: bufferBytesM.Description(),
+			Measure:     bufferBytesM,
+			Aggregation: view.LastValue(),
+			TagKeys:     []tag.Key{namespaceKey, nameKey},
+		},
+		&view.View{
+			Description: bufferDroppedM.Description(),
+			Measure:     bufferDroppedM,
+			Aggregation: view.Sum(),
+			TagKeys:     []tag.Key{namespaceKey, nameKey},
+		},
+	); err != nil {
+		panic(err)
+	}
+}
+
+// reportLimitExceeded records that a changes feed response tripped a limit.
+func (a *couchDbAdapter) reportLimitExceeded(limit string) {
+	ctx, err := tag.New(context.Background(),
+		tag.Insert(namespaceKey, a.namespace),
+		tag.Insert(databaseKey, a.database),
+		tag.Insert(limitKey, limit))
+	if err != nil {
+		a.logger.Warnw("Unable to tag metric", zap.Error(err))
+		return
+	}
+	metrics.Record(ctx, limitExceededM.M(1))
+}
+
+// reportDecodeError records that a change could not be turned into an event.
+func (a *couchDbAdapter) reportDecodeError(policy v1alpha1.DecodeErrorPolicy) {
+	ctx, err := tag.New(context.Background(),
+		tag.Insert(namespaceKey, a.namespace),
+		tag.Insert(databaseKey, a.database),
+		tag.Insert(policyKey, string(policy)))
+	if err != nil {
+		a.logger.Warnw("Unable to tag metric", zap.Error(err))
+		return
+	}
+	metrics.Record(ctx, decodeErrorM.M(1))
+}
+
+// reportRetry records that a delivery is tried again after an attempt failed
+// for the reason, the error class of the attempt: timeout, server (5xx),
+// client (retriable 4xx such as 429) or network.
+func (a *couchDbAdapter) reportRetry(reason string) {
+	a.recordDelivery(deliveryRetryM, reason)
+}
+
+// reportRetryBudgetExhausted records that a delivery was given up on after
+// its last retry failed for the reason.
+func (a *couchDbAdapter) reportRetryBudgetExhausted(reason string) {
+	a.recordDelivery(retryBudgetExhaustedM, reason)
+}
+
+// reportSkipped records that an event was skipped after the sink rejected it
+// for good, for the reason of its last attempt.
+func (a *couchDbAdapter) reportSkipped(reason string) {
+	a.recordDelivery(eventsSkippedM, reason)
+}
+
+func (a *couchDbAdapter) recordDelivery(m *stats.Int64Measure, reason string) {
+	ctx, err := tag.New(context.Background(),
+		tag.Insert(namespaceKey, a.namespace),
+		tag.Insert(nameKey, a.name),
+		tag.Insert(reasonKey, reason))
+	if err != nil {
+		a.logger.Warnw("Unable to tag metric", zap.Error(err))
+		return
+	}
+	metrics.Record(ctx, m.M(1))
+}
+
+// reportEmitted records that the sink accepted the event, after an attempt
+// lasting latency.
+func (a *couchDbAdapter) reportEmitted(event cloudevents.Event, latency time.Duration) {
+	a.recordSource(eventsEmittedM.M(1), tag.Insert(eventTypeKey, event.Type()))
+	a.recordSource(deliveryLatencyM.M(float64(latency)/float64(time.Millisecond)), tag.Insert(responseCodeClassKey, "2xx"))
+}
+
+// reportDeliveryError records the failed attempt, which lasted latency. The
+// attempts without a response are tagged with their error class instead of
+// a response code.
+func (a *couchDbAdapter) reportDeliveryError(attempt cdbevents.DeliveryAttempt, latency time.Duration) {
+	code, class := "", attempt.ErrorClass
+	if attempt.StatusCode != 0 {
+		code = strconv.Itoa(attempt.StatusCode)
+		class = fmt.Sprintf("%dxx", attempt.StatusCode/100)
+	}
+	a.recordSource(deliveryErrorM.M(1), tag.Insert(responseCodeKey, code), tag.Insert(reasonKey, attempt.ErrorClass))
+	a.recordSource(deliveryLatencyM.M(float64(latency)/float64(time.Millisecond)), tag.Insert(responseCodeClassKey, class))
+}
+
+// reportFeedReconnect records that the changes feed is opened again, after
+// the delay, for failing for the reason: connect, interrupted, limit or error.
+func (a *couchDbAdapter) reportFeedReconnect(reason string, delay time.Duration) {
+	a.recordSource(feedReconnectM.M(1), tag.Insert(reasonKey, reason))
+	a.recordSource(feedReconnectDelayM.M(float64(delay) / float64(time.Millisecond)))
+}
+
+// recordSource records the measurement tagged with the namespace, name and
+// database of the source, and the mutators.
+func (a *couchDbAdapter) recordSource(m stats.Measurement, mutators ...tag.Mutator) {
+	ctx, err := tag.New(context.Background(), append([]tag.Mutator{
+		tag.Insert(namespaceKey, a.namespace),
+		tag.Insert(nameKey, a.name),
+		tag.Insert(databaseKey, a.database),
+	}, mutators...)...)
+	if err != nil {
+		a.logger.Warnw("Unable to tag metric", zap.Error(err))
+		return
+	}
+	metrics.Record(ctx, m)
+}
+
+// reportBufferBytes records the size of the events waiting in the buffer.
+func (a *couchDbAdapter) reportBufferBytes(n int64) {
+	a.recordBuffer(bufferBytesM.M(n))
+}
+
+// reportBufferDropped records that n buffered events were dropped.
+func (a *couchDbAdapter) reportBufferDropped(n int) {
+	a.recordBuffer(bufferDroppedM.M(int64(n)))
+}
+
+func (a *couchDbAdapter) recordBuffer(m stats.Measurement) {
+	ctx, err := tag.New(context.Background(),
+		tag.Insert(namespaceKey, a.namespace),
+		tag.Insert(nameKey, a.name))
+	if err != nil {
+		a.logger.Warnw("Unable to tag metric", zap.Error(err))
+		return
+	}
+	metrics.Record(ctx, m)
+}
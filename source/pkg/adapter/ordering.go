@@ -0,0 +1,67 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"errors"
+	"sync"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"go.uber.org/zap"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+// errHeld is the outcome of the events held back in ordered mode.
+var errHeld = errors.New("held back by an earlier failed delivery")
+
+// sequencer delivers the events one request at a time, in the order of the
+// changes feed.
+type sequencer struct {
+	mu sync.Mutex
+}
+
+func newSequencer(env *envConfig) *sequencer {
+	if !v1alpha1.Ordering(env.Ordering).Ordered() {
+		return nil
+	}
+	return &sequencer{}
+}
+
+func (s *sequencer) lock() {
+	if s != nil {
+		s.mu.Lock()
+	}
+}
+
+func (s *sequencer) unlock() {
+	if s != nil {
+		s.mu.Unlock()
+	}
+}
+
+// held returns whether the events must not be sent because, in ordered mode,
+// the delivery of an earlier event failed. The checkpoint does not move past
+// the failed event, so they are read again from the feed right after it.
+func (a *couchDbAdapter) held(events ...cloudevents.Event) bool {
+	if a.sequencer == nil || !a.checkpoint.failing() {
+		return false
+	}
+	a.logger.Debugw("Holding back events after a failed delivery", zap.Int("events", len(events)))
+	a.ackAll(events, errHeld)
+	return true
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"go.uber.org/zap"
+	kncetesting "knative.dev/eventing/pkg/adapter/v2/test"
+)
+
+func TestDeliverOrdered(t *testing.T) {
+	testCases := map[string]struct {
+		ordering string
+		wantSent int
+	}{
+		"unordered": {
+			wantSent: 1,
+		},
+		"ordered": {
+			ordering: "ordered",
+		},
+		"global": {
+			ordering: "global",
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			ce := &flakySinkClient{
+				TestCloudEventsClient: kncetesting.NewTestClient(),
+				fail:                  map[string]bool{"1-a": true},
+			}
+			a := &couchDbAdapter{
+				ce:         ce,
+				logger:     zap.NewNop().Sugar(),
+				delivery:   &deliveryConfig{policy: "linear", delay: time.Millisecond},
+				checkpoint: newCheckpoint("0"),
+				sequencer:  newSequencer(&envConfig{Ordering: tc.ordering}),
+			}
+
+			for _, seq := range []string{"1-a", "2-b"} {
+				a.checkpoint.read(seq, seq, true)
+				event := cloudevents.NewEvent()
+				event.SetID(seq)
+				event.SetType("test")
+				event.SetSource("test")
+				a.deliver(context.Background(), event)
+			}
+
+			if got := len(ce.Sent()); got != tc.wantSent {
+				t.Errorf("sent %d events after the failed delivery, want %d", got, tc.wantSent)
+			}
+			if since, ok := a.checkpoint.rewind(); !ok || since != "0" {
+				t.Errorf("rewind() = %q, %v, want 0, true", since, ok)
+			}
+		})
+	}
+}
+
+func TestFlushBatchOrdered(t *testing.T) {
+	ce := &flakySinkClient{TestCloudEventsClient: kncetesting.NewTestClient()}
+	a := &couchDbAdapter{
+		ce:         ce,
+		logger:     zap.NewNop().Sugar(),
+		delivery:   &deliveryConfig{policy: "linear", delay: time.Millisecond},
+		checkpoint: newCheckpoint("0"),
+		sequencer:  newSequencer(&envConfig{Ordering: "ordered"}),
+		batcher:    &batcher{size: 10, wait: time.Hour, coalesce: true},
+	}
+
+	// The delivery of an earlier batch failed.
+	a.checkpoint.read("1-a", "1-a", true)
+	a.checkpoint.ack("1-a", errors.New("sink unavailable"))
+
+	for _, seq := range []string{"2-b", "3-c"} {
+		a.checkpoint.read(seq, seq, true)
+		event := cloudevents.NewEvent()
+		event.SetID(seq)
+		event.SetType("test")
+		event.SetSource("test")
+		a.deliver(context.Background(), event)
+	}
+	a.flushBatch(context.Background())
+	if got := len(ce.Sent()); got != 0 {
+		t.Errorf("sent %d events after the failed delivery, want 0", got)
+	}
+	if got := a.checkpoint.sequence(); got != "0" {
+		t.Errorf("checkpoint = %q, want 0", got)
+	}
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"fmt"
+	"time"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+// defaultPollInterval is the interval between the polls of the normal feed
+// without spec.polling, and before reopening the continuous feed once it
+// ended without error.
+const defaultPollInterval = 2 * time.Second
+
+// poller adapts the interval between the polls of the normal feed to the rate
+// of changes: the interval halves after a poll returning changes, and grows
+// by the minimum interval after an idle poll.
+type poller struct {
+	min      time.Duration
+	max      time.Duration
+	interval time.Duration
+}
+
+func newPoller(env *envConfig) (*poller, error) {
+	if !env.Polling {
+		return nil, nil
+	}
+	p := &poller{
+		min: v1alpha1.DefaultMinPollInterval,
+		max: v1alpha1.DefaultMaxPollInterval,
+	}
+	if env.PollMinInterval != "" {
+		min, err := parseDuration(env.PollMinInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid minimum poll interval %q: %v", env.PollMinInterval, err)
+		}
+		p.min = min
+		if p.max < min {
+			p.max = min
+		}
+	}
+	if env.PollMaxInterval != "" {
+		max, err := parseDuration(env.PollMaxInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid maximum poll interval %q: %v", env.PollMaxInterval, err)
+		}
+		p.max = max
+	}
+	p.interval = p.min
+	return p, nil
+}
+
+// next returns the interval before the next poll, given whether the last one
+// returned changes.
+func (p *poller) next(changed bool) time.Duration {
+	if changed {
+		p.interval /= 2
+	} else {
+		p.interval += p.min
+	}
+	if p.interval < p.min {
+		p.interval = p.min
+	}
+	if p.interval > p.max {
+		p.interval = p.max
+	}
+	return p.interval
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPoller(t *testing.T) {
+	p, err := newPoller(&envConfig{Polling: true, PollMinInterval: "PT1S", PollMaxInterval: "PT4S"})
+	if err != nil {
+		t.Fatalf("newPoller() = %v", err)
+	}
+
+	var got []time.Duration
+	for _, changed := range []bool{false, false, false, false, true, true, false} {
+		got = append(got, p.next(changed))
+	}
+	want := []time.Duration{
+		// Idle polls grow the interval by the minimum, up to the maximum.
+		2 * time.Second, 3 * time.Second, 4 * time.Second, 4 * time.Second,
+		// Changes halve it, down to the minimum.
+		2 * time.Second, time.Second,
+		2 * time.Second,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected intervals (-want, +got) = %v", diff)
+	}
+}
+
+func TestNewPoller(t *testing.T) {
+	testCases := map[string]struct {
+		env     envConfig
+		wantMin time.Duration
+		wantMax time.Duration
+		wantErr bool
+	}{
+		"defaults": {
+			env:     envConfig{Polling: true},
+			wantMin: time.Second,
+			wantMax: time.Minute,
+		},
+		"minimum above the default maximum": {
+			env:     envConfig{Polling: true, PollMinInterval: "PT2M"},
+			wantMin: 2 * time.Minute,
+			wantMax: 2 * time.Minute,
+		},
+		"invalid maximum": {
+			env:     envConfig{Polling: true, PollMaxInterval: "60s"},
+			wantErr: true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			p, err := newPoller(&tc.env)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("newPoller() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err == nil && (p.min != tc.wantMin || p.max != tc.wantMax) {
+				t.Errorf("newPoller() bounds = [%v, %v], want [%v, %v]", p.min, p.max, tc.wantMin, tc.wantMax)
+			}
+		})
+	}
+
+	if p, _ := newPoller(&envConfig{}); p != nil {
+		t.Errorf("newPoller() = %+v, want nil without spec.polling", p)
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/pprof"
+
+	"go.uber.org/zap"
+)
+
+// newPprofHandler returns the handler of the net/http/pprof profiles.
+func newPprofHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// servePprof serves the profiles of the process on the given port of
+// localhost until ctx is done, when the port is set. Binding localhost keeps
+// the profiles out of reach of the network, while kubectl port-forward still
+// reaches them.
+func servePprof(ctx context.Context, logger *zap.SugaredLogger, port string) {
+	if port == "" {
+		return
+	}
+	server := &http.Server{Addr: net.JoinHostPort("127.0.0.1", port), Handler: newPprofHandler()}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	go func() {
+		logger.Infow("Serving the profiles", zap.String("address", server.Addr))
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Errorw("The profiling server failed", zap.Error(err))
+		}
+	}()
+}
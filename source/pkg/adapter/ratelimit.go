@@ -0,0 +1,52 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+
+	"golang.org/x/time/rate"
+)
+
+// newLimiter returns the limiter of the events sent to the sink, or nil when
+// they are not limited. The burst defaults to a second worth of events.
+func newLimiter(env *envConfig) *rate.Limiter {
+	if env.RateLimit <= 0 {
+		return nil
+	}
+	burst := env.RateLimitBurst
+	if burst <= 0 {
+		burst = env.RateLimit
+	}
+	return rate.NewLimiter(rate.Limit(env.RateLimit), burst)
+}
+
+// throttle waits until n more events may be sent to the sink. Waiting holds
+// the reading of the changes feed, so the adapter keeps up with the sink
+// rather than buffering the changes.
+func (a *couchDbAdapter) throttle(ctx context.Context, n int) error {
+	if a.limiter == nil {
+		return nil
+	}
+	// A CloudEvents batch may hold more events than the burst.
+	for burst := a.limiter.Burst(); n > burst; n -= burst {
+		if err := a.limiter.WaitN(ctx, burst); err != nil {
+			return err
+		}
+	}
+	return a.limiter.WaitN(ctx, n)
+}
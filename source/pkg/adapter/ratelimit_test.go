@@ -0,0 +1,62 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNewLimiter(t *testing.T) {
+	if l := newLimiter(&envConfig{}); l != nil {
+		t.Errorf("newLimiter() = %v, want nil without a rate limit", l)
+	}
+	if got := newLimiter(&envConfig{RateLimit: 10}).Burst(); got != 10 {
+		t.Errorf("Burst() = %d, want the rate limit 10", got)
+	}
+	if got := newLimiter(&envConfig{RateLimit: 10, RateLimitBurst: 3}).Burst(); got != 3 {
+		t.Errorf("Burst() = %d, want 3", got)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	a := &couchDbAdapter{}
+	if err := a.throttle(context.Background(), 1000); err != nil {
+		t.Errorf("throttle() without limiter = %v", err)
+	}
+
+	a.limiter = newLimiter(&envConfig{RateLimit: 100, RateLimitBurst: 10})
+	start := time.Now()
+	// The burst goes at once, and a batch larger than the burst waits for
+	// the tokens it lacks instead of failing.
+	if err := a.throttle(context.Background(), 10); err != nil {
+		t.Fatalf("throttle() = %v", err)
+	}
+	if err := a.throttle(context.Background(), 25); err != nil {
+		t.Fatalf("throttle() = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("35 events took %v, want about 250ms at 100 events/s", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := a.throttle(ctx, 1); err == nil {
+		t.Error("throttle() with a canceled context = nil, want an error")
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+// reconnectBackoff spaces out the reopenings of the changes feed after it
+// failed, so that the adapters do not hammer a struggling CouchDB cluster:
+// the delay doubles after each consecutive failure, up to the maximum, and a
+// random jitter of up to half of it keeps the adapters from reconnecting in
+// lockstep.
+type reconnectBackoff struct {
+	initial  time.Duration
+	max      time.Duration
+	failures int
+
+	// jitter returns a random duration between 0 and d.
+	jitter func(d time.Duration) time.Duration
+}
+
+func newReconnectBackoff(env *envConfig) (*reconnectBackoff, error) {
+	b := &reconnectBackoff{
+		initial: v1alpha1.DefaultReconnectInitialDelay,
+		max:     v1alpha1.DefaultReconnectMaxDelay,
+		jitter:  randomJitter,
+	}
+	if env.ReconnectInitialDelay != "" {
+		initial, err := parseDuration(env.ReconnectInitialDelay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid initial reconnect delay %q: %v", env.ReconnectInitialDelay, err)
+		}
+		b.initial = initial
+		if b.max < initial {
+			b.max = initial
+		}
+	}
+	if env.ReconnectMaxDelay != "" {
+		max, err := parseDuration(env.ReconnectMaxDelay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid maximum reconnect delay %q: %v", env.ReconnectMaxDelay, err)
+		}
+		b.max = max
+		if b.initial > max {
+			// The ConfigMap and the spec may each set one of the bounds.
+			b.initial = max
+		}
+	}
+	return b, nil
+}
+
+func randomJitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// failed returns the delay before reopening the feed, which failed after
+// staying open for the given duration. A feed failing after staying open for
+// longer than the maximum delay starts the backoff over. Without a backoff,
+// the feed is reopened after the default initial delay.
+func (b *reconnectBackoff) failed(open time.Duration) time.Duration {
+	if b == nil {
+		return v1alpha1.DefaultReconnectInitialDelay
+	}
+	if open > b.max {
+		b.failures = 0
+	}
+	delay := b.initial
+	for i := 0; i < b.failures && delay < b.max; i++ {
+		delay *= 2
+	}
+	if delay > b.max {
+		delay = b.max
+	}
+	b.failures++
+	return delay - b.jitter(delay/2)
+}
+
+// succeeded starts the backoff over once the feed was read without error.
+func (b *reconnectBackoff) succeeded() {
+	if b != nil {
+		b.failures = 0
+	}
+}
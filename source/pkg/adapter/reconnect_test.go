@@ -0,0 +1,105 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReconnectBackoff(t *testing.T) {
+	b, err := newReconnectBackoff(&envConfig{ReconnectInitialDelay: "PT1S", ReconnectMaxDelay: "PT6S"})
+	if err != nil {
+		t.Fatalf("newReconnectBackoff() = %v", err)
+	}
+	// The largest jitter.
+	b.jitter = func(d time.Duration) time.Duration { return d }
+
+	var got []time.Duration
+	for i := 0; i < 5; i++ {
+		got = append(got, b.failed(0))
+	}
+	// A feed read without error starts over.
+	b.succeeded()
+	got = append(got, b.failed(0), b.failed(0))
+	// So does a feed failing after staying open for long.
+	got = append(got, b.failed(time.Minute))
+
+	want := []time.Duration{
+		// The delays double up to the maximum, minus up to half of them.
+		500 * time.Millisecond, time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second,
+		500 * time.Millisecond, time.Second,
+		500 * time.Millisecond,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected delays (-want, +got) = %v", diff)
+	}
+}
+
+func TestReconnectBackoffJitter(t *testing.T) {
+	b, err := newReconnectBackoff(&envConfig{})
+	if err != nil {
+		t.Fatalf("newReconnectBackoff() = %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		b.failures = 0
+		if got := b.failed(0); got < time.Second || got > 2*time.Second {
+			t.Fatalf("failed() = %v, want between 1s and 2s", got)
+		}
+	}
+}
+
+func TestNewReconnectBackoff(t *testing.T) {
+	testCases := map[string]struct {
+		env         envConfig
+		wantInitial time.Duration
+		wantMax     time.Duration
+		wantErr     bool
+	}{
+		"defaults": {
+			wantInitial: 2 * time.Second,
+			wantMax:     time.Minute,
+		},
+		"initial delay above the default maximum": {
+			env:         envConfig{ReconnectInitialDelay: "PT2M"},
+			wantInitial: 2 * time.Minute,
+			wantMax:     2 * time.Minute,
+		},
+		"maximum below the initial delay": {
+			env:         envConfig{ReconnectInitialDelay: "PT10S", ReconnectMaxDelay: "PT5S"},
+			wantInitial: 5 * time.Second,
+			wantMax:     5 * time.Second,
+		},
+		"invalid initial delay": {
+			env:     envConfig{ReconnectInitialDelay: "2s"},
+			wantErr: true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			b, err := newReconnectBackoff(&tc.env)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("newReconnectBackoff() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err == nil && (b.initial != tc.wantInitial || b.max != tc.wantMax) {
+				t.Errorf("newReconnectBackoff() bounds = [%v, %v], want [%v, %v]", b.initial, b.max, tc.wantInitial, tc.wantMax)
+			}
+		})
+	}
+}
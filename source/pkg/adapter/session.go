@@ -0,0 +1,184 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kivik/couchdb/v3"
+	"github.com/go-kivik/couchdb/v3/chttp"
+	"github.com/go-kivik/kivik/v3"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+const (
+	// defaultSessionTimeout is CouchDB's default [chttpd_auth] timeout. It is
+	// used when the server does not say when the session cookie expires.
+	defaultSessionTimeout = 10 * time.Minute
+)
+
+// makeAuthenticator returns the kivik authenticator for the given auth method
+// along with the url stripped of its credentials. A nil authenticator means
+// the url should be used as-is.
+func makeAuthenticator(method string, rawurl string) (interface{}, string, error) {
+	if method == "" {
+		return nil, rawurl, nil
+	}
+
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, "", err
+	}
+	if u.User == nil {
+		return nil, "", fmt.Errorf("auth method %q requires credentials in the url", method)
+	}
+	username := u.User.Username()
+	password, _ := u.User.Password()
+	u.User = nil
+
+	switch v1alpha1.AuthMethod(method) {
+	case v1alpha1.AuthBasic:
+		return couchdb.BasicAuth(username, password), u.String(), nil
+	case v1alpha1.AuthSession:
+		return &sessionAuth{username: username, password: password, now: time.Now}, u.String(), nil
+	default:
+		return nil, "", fmt.Errorf("unknown auth method %q", method)
+	}
+}
+
+// sessionAuth authenticates requests with the AuthSession cookie returned by
+// the _session endpoint. Unlike basic auth, the credentials are only sent
+// when a session is established, and the session is renewed once 80% of its
+// lifetime has elapsed so that a long-running feed never presents an
+// expired cookie.
+type sessionAuth struct {
+	username string
+	password string
+
+	transport  http.RoundTripper
+	sessionURL string
+	now        func() time.Time
+	// timeout is the lifetime of the cookies whose expiry the server does
+	// not tell, defaultSessionTimeout when unset.
+	timeout time.Duration
+
+	mu      sync.Mutex
+	cookie  *http.Cookie
+	renewAt time.Time
+}
+
+var _ chttp.Authenticator = (*sessionAuth)(nil)
+var _ http.RoundTripper = (*sessionAuth)(nil)
+
+// Authenticate implements chttp.Authenticator by wrapping the client transport.
+func (a *sessionAuth) Authenticate(c *chttp.Client) error {
+	u, err := url.Parse(c.DSN())
+	if err != nil {
+		return err
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/_session"
+	a.sessionURL = u.String()
+
+	a.transport = c.Transport
+	if a.transport == nil {
+		a.transport = http.DefaultTransport
+	}
+	c.Transport = a
+	return nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (a *sessionAuth) RoundTrip(req *http.Request) (*http.Response, error) {
+	cookie, err := a.session(req)
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.AddCookie(cookie)
+	resp, err := a.transport.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized {
+		// The server dropped our session (e.g. restart or secret change), so
+		// log in again on the next request.
+		a.mu.Lock()
+		a.cookie = nil
+		a.mu.Unlock()
+	}
+	return resp, err
+}
+
+// session returns a valid session cookie, logging in when there is none or
+// when the current one is due for renewal.
+func (a *sessionAuth) session(req *http.Request) (*http.Cookie, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	if a.cookie != nil && now.Before(a.renewAt) {
+		return a.cookie, nil
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"name":     a.username,
+		"password": a.password,
+	})
+	if err != nil {
+		return nil, err
+	}
+	login, err := http.NewRequestWithContext(req.Context(), http.MethodPost, a.sessionURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	login.Header.Set("Content-Type", "application/json")
+	login.Header.Set("Accept", "application/json")
+
+	resp, err := a.transport.RoundTrip(login)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &kivik.Error{HTTPStatus: resp.StatusCode, Err: fmt.Errorf("session login failed: %s", resp.Status)}
+	}
+
+	for _, c := range resp.Cookies() {
+		if c.Name != kivik.SessionCookieName {
+			continue
+		}
+		lifetime := a.timeout
+		if lifetime == 0 {
+			lifetime = defaultSessionTimeout
+		}
+		if c.MaxAge > 0 {
+			lifetime = time.Duration(c.MaxAge) * time.Second
+		} else if !c.Expires.IsZero() {
+			lifetime = c.Expires.Sub(now)
+		}
+		a.cookie = &http.Cookie{Name: c.Name, Value: c.Value}
+		a.renewAt = now.Add(lifetime * 4 / 5)
+		return a.cookie, nil
+	}
+	return nil, &kivik.Error{HTTPStatus: http.StatusBadGateway, Err: fmt.Errorf("session login did not return an %s cookie", kivik.SessionCookieName)}
+}
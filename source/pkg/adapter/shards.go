@@ -0,0 +1,253 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"go.uber.org/zap"
+	"knative.dev/eventing/pkg/adapter/v2"
+	"knative.dev/pkg/logging"
+)
+
+// discoveryInterval is how often a replica lists the databases of the
+// server, to tail the databases of its shard created since, and stop
+// tailing the deleted ones.
+const discoveryInterval = time.Minute
+
+// shardedAdapter is a replica of the receive adapter StatefulSet of a source
+// watching the databases of a pattern. It tails the matching databases that
+// hash to its ordinal, each with an adapter of its own run like a source of
+// the multi-tenant adapter, so that every database is tailed by exactly one
+// replica.
+type shardedAdapter struct {
+	logger   *zap.SugaredLogger
+	pattern  string
+	shard    int
+	shards   int
+	interval time.Duration
+
+	// list returns the names of the databases of the server.
+	list func(ctx context.Context) ([]string, error)
+
+	// serve runs the adapter of a database until ctx is done.
+	serve func(ctx context.Context, database string) error
+
+	// liveness, when set, reports whether the databases can be listed.
+	liveness *liveness
+
+	// tails are the adapters of the databases of the shard.
+	tails map[string]*tenant
+}
+
+var _ adapter.Adapter = (*shardedAdapter)(nil)
+
+func newShardedAdapter(ctx context.Context, env *envConfig, ceClient cloudevents.Client) (*shardedAdapter, error) {
+	logger := logging.FromContext(ctx)
+	shard, err := shardOrdinal(env.PodName)
+	if err != nil {
+		return nil, err
+	}
+	shards := env.Shards
+	if shards < 1 {
+		shards = 1
+	}
+	live, err := newLiveness(env)
+	if err != nil {
+		return nil, fmt.Errorf("invalid disconnected threshold: %w", err)
+	}
+	logger.Infow("Tailing the databases of the shard", zap.String("pattern", env.DatabasePattern),
+		zap.Int("shard", shard), zap.Int("shards", shards))
+
+	return &shardedAdapter{
+		logger:   logger,
+		pattern:  env.DatabasePattern,
+		shard:    shard,
+		shards:   shards,
+		interval: discoveryInterval,
+		liveness: live,
+		list: func(ctx context.Context) ([]string, error) {
+			// The credentials are read again, for the discovery to follow
+			// their rotations.
+			url, err := readCredentialsURL(env.CouchDbCredentialsPath)
+			if err != nil {
+				return nil, err
+			}
+			client, _, err := connect(ctx, env, url, serverDriver(ctx, logger, url))
+			if err != nil {
+				return nil, err
+			}
+			defer client.Close(ctx)
+			return client.AllDBs(ctx)
+		},
+		serve: func(ctx context.Context, database string) error {
+			url, err := readCredentialsURL(env.CouchDbCredentialsPath)
+			if err != nil {
+				return err
+			}
+			env := env.forDatabase(database)
+			ctx = logging.WithLogger(ctx, logger.With(zap.String("database", database)))
+			build := func(url string) (*couchDbAdapter, error) {
+				return buildAdapter(ctx, env, ceClient, url, serverDriver(ctx, logging.FromContext(ctx), url))
+			}
+			ra, err := build(url)
+			if err != nil {
+				return err
+			}
+			// The adapter is replaced when the credentials are rotated.
+			return (&reloadingAdapter{
+				logger:   logging.FromContext(ctx),
+				adapter:  ra,
+				url:      url,
+				interval: credentialsPollInterval,
+				readURL: func(context.Context) (string, error) {
+					return readCredentialsURL(env.CouchDbCredentialsPath)
+				},
+				build: build,
+			}).Start(ctx)
+		},
+		tails: make(map[string]*tenant),
+	}, nil
+}
+
+// Start tails the databases of the shard until ctx is done, discovering them
+// every interval.
+func (s *shardedAdapter) Start(ctx context.Context) error {
+	var servers sync.WaitGroup
+	defer servers.Wait()
+	if s.liveness != nil {
+		// Not ready until the databases are listed.
+		s.liveness.failed(time.Now())
+		s.liveness.serve(ctx, s.logger, &servers)
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		s.discover(ctx)
+		select {
+		case <-ctx.Done():
+			for _, t := range s.tails {
+				t.cancel()
+			}
+			for _, t := range s.tails {
+				<-t.done
+			}
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// discover lists the databases of the server, starts tailing the databases
+// of the shard not tailed yet, and stops tailing the ones no longer listed.
+func (s *shardedAdapter) discover(ctx context.Context) {
+	databases, err := s.list(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Errorw("Unable to list the databases", zap.Error(err))
+			s.liveness.failed(time.Now())
+		}
+		return
+	}
+	s.liveness.connected()
+
+	owned := make(map[string]bool)
+	for _, database := range databases {
+		if s.owns(database) {
+			owned[database] = true
+		}
+	}
+	for database, t := range s.tails {
+		if !owned[database] {
+			s.logger.Infow("No longer tailing the database", zap.String("database", database))
+			t.stop()
+			delete(s.tails, database)
+		}
+	}
+	for database := range owned {
+		if _, ok := s.tails[database]; ok {
+			continue
+		}
+		s.logger.Infow("Tailing the database", zap.String("database", database))
+		tailCtx, cancel := context.WithCancel(ctx)
+		t := &tenant{cancel: cancel, done: make(chan struct{})}
+		s.tails[database] = t
+		go func(database string) {
+			defer close(t.done)
+			runWithRetries(tailCtx, s.logger.With(zap.String("database", database)), func(ctx context.Context) error {
+				return s.serve(ctx, database)
+			})
+		}(database)
+	}
+}
+
+// owns is whether the database matches the pattern and hashes to the shard.
+// The system databases, e.g. _users, are only matched by patterns starting
+// with an underscore.
+func (s *shardedAdapter) owns(database string) bool {
+	if strings.HasPrefix(database, "_") && !strings.HasPrefix(s.pattern, "_") {
+		return false
+	}
+	if ok, _ := path.Match(s.pattern, database); !ok {
+		return false
+	}
+	return shardOf(database, s.shards) == s.shard
+}
+
+// shardOf returns the shard tailing the database.
+func shardOf(database string, shards int) int {
+	h := fnv.New32a()
+	h.Write([]byte(database))
+	return int(h.Sum32() % uint32(shards))
+}
+
+// shardOrdinal returns the ordinal of the StatefulSet pod, the suffix of its
+// name.
+func shardOrdinal(podName string) (int, error) {
+	i := strings.LastIndex(podName, "-")
+	if i < 0 {
+		return 0, fmt.Errorf("%q is not the name of a StatefulSet pod", podName)
+	}
+	n, err := strconv.Atoi(podName[i+1:])
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q is not the name of a StatefulSet pod", podName)
+	}
+	return n, nil
+}
+
+// forDatabase returns the configuration of the adapter of a database of the
+// pattern. Its events come from that database, and the replica serves the
+// health endpoints for all its databases.
+func (e *envConfig) forDatabase(database string) *envConfig {
+	env := *e
+	env.Database = database
+	env.DatabasePattern = ""
+	env.HealthPort = ""
+	if strings.HasSuffix(env.EventSource, "/"+e.DatabasePattern) {
+		env.EventSource = strings.TrimSuffix(env.EventSource, e.DatabasePattern) + database
+	}
+	return &env
+}
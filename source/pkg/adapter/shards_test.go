@@ -0,0 +1,167 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+)
+
+// fakeTails records the databases a sharded adapter tails.
+type fakeTails struct {
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+func (f *fakeTails) serve(ctx context.Context, database string) error {
+	f.mu.Lock()
+	f.running[database] = true
+	f.mu.Unlock()
+	<-ctx.Done()
+	f.mu.Lock()
+	delete(f.running, database)
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *fakeTails) databases() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var got []string
+	for database := range f.running {
+		got = append(got, database)
+	}
+	sort.Strings(got)
+	return got
+}
+
+func TestShardedAdapterDiscover(t *testing.T) {
+	databases := []string{"_users", "orders-1", "orders-2", "orders-3", "orders-4", "invoices"}
+	list := func(context.Context) ([]string, error) { return databases, nil }
+
+	// Every matching database is tailed by exactly one of the shards.
+	var all []string
+	tails := make([]*fakeTails, 2)
+	adapters := make([]*shardedAdapter, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for shard := range adapters {
+		tails[shard] = &fakeTails{running: map[string]bool{}}
+		adapters[shard] = &shardedAdapter{
+			logger:  zap.NewNop().Sugar(),
+			pattern: "orders-*",
+			shard:   shard,
+			shards:  2,
+			list:    list,
+			serve:   tails[shard].serve,
+			tails:   make(map[string]*tenant),
+		}
+		adapters[shard].discover(ctx)
+	}
+	waitFor(t, func() bool { return len(tails[0].databases())+len(tails[1].databases()) == 4 })
+	for _, f := range tails {
+		all = append(all, f.databases()...)
+	}
+	sort.Strings(all)
+	if diff := cmp.Diff([]string{"orders-1", "orders-2", "orders-3", "orders-4"}, all); diff != "" {
+		t.Errorf("unexpected tailed databases (-want, +got) = %v", diff)
+	}
+
+	// A deleted database is no longer tailed.
+	deleted := all[0]
+	shard := shardOf(deleted, 2)
+	databases = []string{"orders-1", "orders-2", "orders-3", "orders-4"}
+	for i, database := range databases {
+		if database == deleted {
+			databases = append(databases[:i:i], databases[i+1:]...)
+			break
+		}
+	}
+	adapters[shard].discover(ctx)
+	for _, database := range tails[shard].databases() {
+		if database == deleted {
+			t.Errorf("the deleted database %s is still tailed", deleted)
+		}
+	}
+}
+
+func TestShardedAdapterSystemDatabases(t *testing.T) {
+	a := &shardedAdapter{pattern: "_*", shards: 1}
+	if !a.owns("_users") {
+		t.Error("the _users database is not matched by _*")
+	}
+	a.pattern = "*"
+	if a.owns("_users") {
+		t.Error("the _users database is matched by *")
+	}
+	if !a.owns("orders") {
+		t.Error("the orders database is not matched by *")
+	}
+}
+
+func TestShardOrdinal(t *testing.T) {
+	for name, want := range map[string]int{
+		"couchdbsource-orders-1234-0": 0,
+		"couchdbsource-orders-1234-3": 3,
+	} {
+		if got, err := shardOrdinal(name); err != nil || got != want {
+			t.Errorf("shardOrdinal(%q) = %d, %v, want %d", name, got, err, want)
+		}
+	}
+	for _, name := range []string{"", "adapter", "couchdbsource-orders-1234-abcde"} {
+		if _, err := shardOrdinal(name); err == nil {
+			t.Errorf("shardOrdinal(%q) = nil, wanted an error", name)
+		}
+	}
+}
+
+func TestForDatabase(t *testing.T) {
+	env := &envConfig{
+		EventSource:     "couchdb.example.com/orders-*",
+		DatabasePattern: "orders-*",
+		HealthPort:      "8081",
+	}
+	got := env.forDatabase("orders-1")
+	if got.Database != "orders-1" || got.DatabasePattern != "" || got.HealthPort != "" {
+		t.Errorf("forDatabase() = %+v, want the adapter of orders-1", got)
+	}
+	if got.EventSource != "couchdb.example.com/orders-1" {
+		t.Errorf("EventSource = %q, want couchdb.example.com/orders-1", got.EventSource)
+	}
+
+	env.EventSource = "https://example.com/orders"
+	if got := env.forDatabase("orders-1").EventSource; got != env.EventSource {
+		t.Errorf("EventSource = %q, want the one of the source kept", got)
+	}
+}
+
+// waitFor polls cond for a second.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
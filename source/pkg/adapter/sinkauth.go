@@ -0,0 +1,123 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sinkTokenRefresh is how long a sink token read from its file is used
+// before reading it again. The kubelet rotates the projected tokens well
+// before they expire.
+const sinkTokenRefresh = time.Minute
+
+// sinkTokens holds, by sink, the OIDC tokens the events are sent with. Like
+// the backpressure, it wraps http.DefaultTransport, which the events are
+// sent through, and leaves the requests to the other targets, like the dead
+// letter sink, alone.
+var sinkTokens = newSinkAuth()
+
+func init() {
+	http.DefaultTransport = &sinkAuthTransport{
+		base:  http.DefaultTransport,
+		auth:  sinkTokens,
+		clock: time.Now,
+	}
+}
+
+// sinkAuth maps the sinks to the files of the ServiceAccount tokens issued
+// for their audience.
+type sinkAuth struct {
+	mu     sync.Mutex
+	tokens map[string]*sinkToken
+}
+
+// sinkToken is a token file and its last read content.
+type sinkToken struct {
+	path  string
+	value string
+	read  time.Time
+}
+
+func newSinkAuth() *sinkAuth {
+	return &sinkAuth{tokens: make(map[string]*sinkToken)}
+}
+
+// set sends the token of the file at path with the events to sink.
+func (a *sinkAuth) set(sink, path string) error {
+	u, err := url.Parse(sink)
+	if err != nil {
+		return fmt.Errorf("invalid sink %q: %v", sink, err)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.tokens[sinkKey(u)] = &sinkToken{path: path}
+	return nil
+}
+
+// token returns the token to send to u, empty for the targets without one.
+func (a *sinkAuth) token(u *url.URL, now time.Time) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	t, ok := a.tokens[sinkKey(u)]
+	if !ok {
+		return "", nil
+	}
+	if t.value == "" || now.Sub(t.read) >= sinkTokenRefresh {
+		b, err := ioutil.ReadFile(t.path)
+		if err != nil {
+			return "", fmt.Errorf("unable to read the sink token: %v", err)
+		}
+		t.value = strings.TrimSpace(string(b))
+		t.read = now
+	}
+	return t.value, nil
+}
+
+// sinkKey identifies a sink by its scheme, host and path, the query being
+// irrelevant to its audience.
+func sinkKey(u *url.URL) string {
+	return u.Scheme + "://" + u.Host + u.Path
+}
+
+// sinkAuthTransport authenticates the requests to the sinks with an OIDC
+// audience with their token.
+type sinkAuthTransport struct {
+	base  http.RoundTripper
+	auth  *sinkAuth
+	clock func() time.Time
+}
+
+func (t *sinkAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.auth.token(req.URL, t.clock())
+	if err != nil {
+		return nil, err
+	}
+	if token == "" {
+		return t.base.RoundTrip(req)
+	}
+	// A RoundTripper must not modify the request.
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSinkAuthTransport(t *testing.T) {
+	var got []string
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer sink.Close()
+
+	path := filepath.Join(t.TempDir(), "token")
+	if err := ioutil.WriteFile(path, []byte("first\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	auth := newSinkAuth()
+	if err := auth.set(sink.URL+"/events?x=1", path); err != nil {
+		t.Fatalf("set() = %v", err)
+	}
+	client := &http.Client{Transport: &sinkAuthTransport{
+		base:  http.DefaultTransport,
+		auth:  auth,
+		clock: func() time.Time { return now },
+	}}
+	get := func(u string) {
+		t.Helper()
+		resp, err := client.Get(u)
+		if err != nil {
+			t.Fatalf("Get() = %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	get(sink.URL + "/events")
+	// The dead letter sink may share the host of the sink.
+	get(sink.URL + "/dead-letters")
+
+	// The token is read again once rotated.
+	if err := ioutil.WriteFile(path, []byte("second"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	get(sink.URL + "/events")
+	now = now.Add(sinkTokenRefresh)
+	get(sink.URL + "/events")
+
+	want := []string{"Bearer first", "", "Bearer first", "Bearer second"}
+	if len(got) != len(want) {
+		t.Fatalf("Authorization headers = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Authorization header #%d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSinkAuthMissingToken(t *testing.T) {
+	auth := newSinkAuth()
+	if err := auth.set("http://sink.example.com", filepath.Join(t.TempDir(), "token")); err != nil {
+		t.Fatalf("set() = %v", err)
+	}
+	client := &http.Client{Transport: &sinkAuthTransport{base: http.DefaultTransport, auth: auth, clock: time.Now}}
+	if resp, err := client.Get("http://sink.example.com"); err == nil {
+		resp.Body.Close()
+		t.Error("Get() succeeded without the token, want an error")
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+// deliveryStats counts the events delivered since the adapter started. The
+// controller reflects them in status.stats.
+type deliveryStats struct {
+	mu           sync.Mutex
+	start        time.Time
+	delivered    int64
+	deadLettered int64
+	last         time.Time
+
+	// perSecond counts the events delivered during each second of the last
+	// minute, indexed by the Unix second modulo 60, and seconds holds which
+	// second each bucket counts.
+	perSecond [60]int64
+	seconds   [60]int64
+}
+
+func newDeliveryStats(now time.Time) *deliveryStats {
+	return &deliveryStats{start: now}
+}
+
+// recordDelivered counts n events accepted by the sink.
+func (s *deliveryStats) recordDelivered(now time.Time, n int) {
+	if s == nil || n == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delivered += int64(n)
+	s.last = now
+	sec := now.Unix()
+	i := sec % int64(len(s.perSecond))
+	if s.seconds[i] != sec {
+		s.seconds[i] = sec
+		s.perSecond[i] = 0
+	}
+	s.perSecond[i] += int64(n)
+}
+
+// recordDeadLettered counts an event sent to the dead letter sink.
+func (s *deliveryStats) recordDeadLettered() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deadLettered++
+}
+
+// snapshot returns the statistics reported on the status endpoint.
+func (s *deliveryStats) snapshot(now time.Time) *v1alpha1.AdapterStats {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := &v1alpha1.AdapterStats{
+		StartTime:          metav1.NewTime(s.start),
+		EventsDelivered:    s.delivered,
+		EventsDeadLettered: s.deadLettered,
+	}
+	if !s.last.IsZero() {
+		last := metav1.NewTime(s.last)
+		stats.LastDeliveryTime = &last
+	}
+	sec := now.Unix()
+	for i, n := range s.perSecond {
+		if sec-s.seconds[i] < int64(len(s.perSecond)) {
+			stats.EventsPerMinute += n
+		}
+	}
+	return stats
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeliveryStats(t *testing.T) {
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	s := newDeliveryStats(start)
+	s.recordDelivered(start.Add(10*time.Second), 3)
+	s.recordDelivered(start.Add(70*time.Second), 2)
+	s.recordDelivered(start.Add(80*time.Second), 1)
+	s.recordDeadLettered()
+
+	got := s.snapshot(start.Add(90 * time.Second))
+	if got.EventsDelivered != 6 || got.EventsDeadLettered != 1 {
+		t.Errorf("snapshot() counted %d delivered and %d dead lettered events, want 6 and 1", got.EventsDelivered, got.EventsDeadLettered)
+	}
+	// The events of the first delivery are older than a minute.
+	if got.EventsPerMinute != 3 {
+		t.Errorf("EventsPerMinute = %d, want 3", got.EventsPerMinute)
+	}
+	if got.LastDeliveryTime == nil || !got.LastDeliveryTime.Time.Equal(start.Add(80*time.Second)) {
+		t.Errorf("LastDeliveryTime = %v, want %v", got.LastDeliveryTime, start.Add(80*time.Second))
+	}
+
+	// Stats are optional.
+	var disabled *deliveryStats
+	disabled.recordDelivered(start, 1)
+	if got := disabled.snapshot(start); got != nil {
+		t.Errorf("snapshot() = %v, want nil", got)
+	}
+}
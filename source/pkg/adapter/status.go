@@ -0,0 +1,69 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+// adapterStatus returns the status reported on the status endpoint.
+func (a *couchDbAdapter) adapterStatus() v1alpha1.AdapterStatus {
+	var status v1alpha1.AdapterStatus
+	if a.backfill != nil {
+		status.Backfill = a.backfill.progress()
+	}
+	status.Stats = a.stats.snapshot(time.Now())
+	if seq := a.checkpoint.sequence(); seq != v1alpha1.SequenceNow {
+		status.Sequence = seq
+	}
+	return status
+}
+
+func (a *couchDbAdapter) serveStatusHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(a.adapterStatus()); err != nil {
+		a.logger.Warnw("Unable to write the adapter status", zap.Error(err))
+	}
+}
+
+// serveStatus serves the status of the adapter on the given port until ctx is
+// done, when servers is done once the server is closed. The controller
+// reflects it in the status of the source.
+func (a *couchDbAdapter) serveStatus(ctx context.Context, port string, servers *sync.WaitGroup) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(v1alpha1.AdapterStatusPath, a.serveStatusHTTP)
+	server := &http.Server{Addr: ":" + port, Handler: mux}
+	servers.Add(1)
+	go func() {
+		defer servers.Done()
+		<-ctx.Done()
+		server.Close()
+	}()
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			a.logger.Errorw("The status server failed", zap.Error(err))
+		}
+	}()
+}
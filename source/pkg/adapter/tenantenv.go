@@ -0,0 +1,96 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// processTenantEnv sets the fields of spec tagged with envconfig from the
+// environment of a source served by the multi-tenant adapter, as
+// envconfig.Process does from the environment of the process. It supports
+// the field types of envConfig.
+func processTenantEnv(vars map[string]string, spec interface{}) error {
+	return processTenantStruct(vars, reflect.ValueOf(spec).Elem())
+}
+
+func processTenantStruct(vars map[string]string, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f, field := t.Field(i), v.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if err := processTenantStruct(vars, field); err != nil {
+				return err
+			}
+			continue
+		}
+		key := f.Tag.Get("envconfig")
+		if key == "" {
+			continue
+		}
+		value, ok := vars[key]
+		if !ok {
+			if f.Tag.Get("required") == "true" {
+				return fmt.Errorf("required key %s missing value", key)
+			}
+			if value, ok = f.Tag.Lookup("default"); !ok {
+				continue
+			}
+		}
+		if err := setTenantField(field, value); err != nil {
+			return fmt.Errorf("invalid %s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+func setTenantField(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 0, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(i)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", field.Type())
+		}
+		var items []string
+		if value != "" {
+			items = strings.Split(value, ",")
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
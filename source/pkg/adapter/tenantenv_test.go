@@ -0,0 +1,98 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"knative.dev/eventing/pkg/adapter/v2"
+)
+
+func TestProcessTenantEnv(t *testing.T) {
+	testCases := map[string]struct {
+		vars    map[string]string
+		want    envConfig
+		wantErr bool
+	}{
+		"fields": {
+			vars: map[string]string{
+				"NAMESPACE":            "default",
+				"NAME":                 "orders",
+				"K_SINK":               "http://sink",
+				"COUCHDB_CREDENTIALS":  "/etc/couchdb-credentials",
+				"COUCHDB_DATABASE":     "orders",
+				"EVENT_SOURCE":         "couchdb/orders",
+				"COUCHDB_FEED":         "continuous",
+				"COUCHDB_CONFLICTS":    "true",
+				"COUCHDB_RATE_LIMIT":   "10",
+				"COUCHDB_SEQ_INTERVAL": "100",
+				"COUCHDB_PARTITIONS":   "a,b",
+				"DELIVERY_RETRY":       "3",
+			},
+			want: envConfig{
+				EnvConfig: adapter.EnvConfig{
+					Namespace:         "default",
+					Name:              "orders",
+					Sink:              "http://sink",
+					ResourceGroup:     "adapter.sources.knative.dev",
+					MetricsConfigJson: "{}",
+					LoggingConfigJson: "{}",
+				},
+				CouchDbCredentialsPath: "/etc/couchdb-credentials",
+				Database:               "orders",
+				EventSource:            "couchdb/orders",
+				Feed:                   "continuous",
+				Conflicts:              true,
+				RateLimit:              10,
+				SeqInterval:            100,
+				Partitions:             []string{"a", "b"},
+				DeliveryRetry:          3,
+			},
+		},
+		"missing required": {
+			vars:    map[string]string{"COUCHDB_DATABASE": "orders"},
+			wantErr: true,
+		},
+		"invalid bool": {
+			vars: map[string]string{
+				"COUCHDB_CREDENTIALS": "/etc/couchdb-credentials",
+				"COUCHDB_DATABASE":    "orders",
+				"EVENT_SOURCE":        "couchdb/orders",
+				"COUCHDB_FEED":        "normal",
+				"COUCHDB_CONFLICTS":   "yes please",
+			},
+			wantErr: true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			var got envConfig
+			err := processTenantEnv(tc.vars, &got)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("processTenantEnv() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.want, got, cmpopts.IgnoreUnexported(adapter.EnvConfig{})); diff != "" {
+				t.Errorf("unexpected env (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,324 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"knative.dev/eventing/pkg/adapter/v2"
+	"knative.dev/eventing/pkg/metrics/source"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+
+	"knative.dev/eventing-couchdb/source/pkg/contract"
+)
+
+const (
+	// tenantRetryDelay and maxTenantRetryDelay bound the backoff between the
+	// attempts to start the adapter of a source.
+	tenantRetryDelay    = 5 * time.Second
+	maxTenantRetryDelay = 5 * time.Minute
+)
+
+// mtEnvConfig is the configuration of the multi-tenant receive adapter.
+type mtEnvConfig struct {
+	adapter.EnvConfig
+
+	// Installation is the installation of the sources the adapter serves,
+	// empty for the default one.
+	Installation string `envconfig:"COUCHDB_INSTALLATION"`
+
+	// LoggingDir is where config-logging is mounted.
+	LoggingDir string `envconfig:"COUCHDB_LOGGING_DIR"`
+
+	// PprofPort, when set, is the localhost port serving the profiles of the
+	// process.
+	PprofPort string `envconfig:"COUCHDB_PPROF_PORT"`
+}
+
+// NewMTEnvConfig creates an empty configuration of the multi-tenant receive
+// adapter.
+func NewMTEnvConfig() adapter.EnvConfigAccessor {
+	return &mtEnvConfig{}
+}
+
+// GetLogger implements adapter.EnvConfigAccessor, with a level following the
+// config-logging mounted in LoggingDir.
+func (e *mtEnvConfig) GetLogger() *zap.SugaredLogger {
+	return processLogger.get(e.LoggingDir, e.LoggingConfigJson, e.Component)
+}
+
+// mtAdapter serves many sources from a single process, like the multi-tenant
+// PingSource adapter. The controller writes the configuration of each source
+// to a ConfigMap labeled with contract.TenantLabelKey, in place of its
+// receive adapter Deployment, and the adapter runs a couchDbAdapter per
+// ConfigMap.
+type mtAdapter struct {
+	ctx          context.Context
+	logger       *zap.SugaredLogger
+	installation string
+
+	// serve runs the adapter of a source until ctx is done.
+	serve func(ctx context.Context, key string, config map[string]string) error
+
+	// credentials returns the url of the CouchDB credentials secret.
+	credentials func(ctx context.Context, namespace, name string) (string, error)
+	reporter    source.StatsReporter
+
+	mu      sync.Mutex
+	tenants map[string]*tenant
+}
+
+// tenant is a source served by the multi-tenant adapter.
+type tenant struct {
+	config map[string]string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// stop stops the adapter of the source and waits for it to flush its groups
+// and batches.
+func (t *tenant) stop() {
+	t.cancel()
+	<-t.done
+}
+
+// NewMTAdapter creates the multi-tenant receive adapter. The CloudEvents
+// client is not used: each source gets one targeting its sink.
+func NewMTAdapter(ctx context.Context, processed adapter.EnvConfigAccessor, _ cloudevents.Client) adapter.Adapter {
+	logger := logging.FromContext(ctx)
+	env := processed.(*mtEnvConfig)
+
+	reporter, err := source.NewStatsReporter()
+	if err != nil {
+		logger.Fatal("Error creating the stats reporter", zap.Error(err))
+	}
+	kube := kubeclient.Get(ctx)
+	go processLogger.watch(ctx, env.LoggingDir, env.Component)
+	servePprof(ctx, logger, env.PprofPort)
+	// The Kubernetes Events of all the sources go through the service
+	// account of the adapter.
+	ctx = controller.WithEventRecorder(ctx, newEventRecorder(ctx, kube, env.Component))
+
+	a := &mtAdapter{
+		ctx:          ctx,
+		logger:       logger,
+		installation: env.Installation,
+		credentials: func(ctx context.Context, namespace, name string) (string, error) {
+			secret, err := kube.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return "", err
+			}
+			url, ok := secret.Data["url"]
+			if !ok {
+				return "", fmt.Errorf("missing url key in secret %s/%s", namespace, name)
+			}
+			return string(url), nil
+		},
+		reporter: reporter,
+		tenants:  make(map[string]*tenant),
+	}
+	a.serve = a.serveTenant
+	return a
+}
+
+// Start waits for ctx to be done, and then stops the adapters of the
+// sources. The sources are started by the controller.
+func (a *mtAdapter) Start(ctx context.Context) error {
+	<-ctx.Done()
+	a.mu.Lock()
+	tenants := a.tenants
+	a.tenants = make(map[string]*tenant)
+	a.mu.Unlock()
+	for _, t := range tenants {
+		t.stop()
+	}
+	return nil
+}
+
+// update serves the source with the configuration, restarting its adapter
+// when the configuration changed.
+func (a *mtAdapter) update(key string, config map[string]string) {
+	a.mu.Lock()
+	old, ok := a.tenants[key]
+	if ok && reflect.DeepEqual(old.config, config) {
+		a.mu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(a.ctx)
+	t := &tenant{config: config, cancel: cancel, done: make(chan struct{})}
+	a.tenants[key] = t
+	a.mu.Unlock()
+
+	if ok {
+		// Two adapters of a source must not deliver its changes at once.
+		old.stop()
+	}
+	a.logger.Infow("Serving the source", zap.String("tenant", key))
+	go func() {
+		defer close(t.done)
+		a.run(ctx, key, config)
+	}()
+}
+
+// remove stops serving the source.
+func (a *mtAdapter) remove(key string) {
+	a.mu.Lock()
+	t, ok := a.tenants[key]
+	delete(a.tenants, key)
+	a.mu.Unlock()
+	if ok {
+		a.logger.Infow("No longer serving the source", zap.String("tenant", key))
+		t.stop()
+	}
+}
+
+// run serves the source until ctx is done.
+func (a *mtAdapter) run(ctx context.Context, key string, config map[string]string) {
+	runWithRetries(ctx, a.logger.With(zap.String("tenant", key)), func(ctx context.Context) error {
+		return a.serve(ctx, key, config)
+	})
+}
+
+// runWithRetries runs an adapter until ctx is done, restarting it with a
+// backoff when it fails, e.g. while CouchDB is unreachable.
+func runWithRetries(ctx context.Context, logger *zap.SugaredLogger, serve func(ctx context.Context) error) {
+	delay := tenantRetryDelay
+	for {
+		err := serve(ctx)
+		if err == nil || ctx.Err() != nil {
+			return
+		}
+		logger.Errorw("The adapter failed, restarting it", zap.Duration("delay", delay), zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxTenantRetryDelay {
+			delay = maxTenantRetryDelay
+		}
+	}
+}
+
+// serveTenant runs the adapter of the source until ctx is done.
+func (a *mtAdapter) serveTenant(ctx context.Context, key string, config map[string]string) error {
+	var vars map[string]string
+	if err := json.Unmarshal([]byte(config[contract.TenantEnvKey]), &vars); err != nil {
+		return fmt.Errorf("invalid %s: %v", contract.TenantEnvKey, err)
+	}
+	env := &envConfig{}
+	if err := processTenantEnv(vars, env); err != nil {
+		return err
+	}
+	// The proxy and the parsing limits are set on the transports shared by
+	// the sources, and the lease and status port are per pod.
+	if env.ProxyURL != "" || env.MaxLineBytes > 0 || env.MaxJSONDepth > 0 || env.LeaseName != "" || env.StatusPort != "" {
+		return errors.New("the source needs a receive adapter of its own")
+	}
+
+	url, err := a.credentials(ctx, env.Namespace, config[contract.TenantCredentialsKey])
+	if err != nil {
+		return fmt.Errorf("unable to read the credentials: %w", err)
+	}
+	overrides, err := env.GetCloudEventOverrides()
+	if err != nil {
+		return fmt.Errorf("invalid CloudEvent overrides: %w", err)
+	}
+	ceClient, err := adapter.NewCloudEventsClient(env.Sink, overrides, a.reporter)
+	if err != nil {
+		return fmt.Errorf("error creating the CloudEvents client: %w", err)
+	}
+
+	ctx = logging.WithLogger(ctx, a.logger.With(zap.String("namespace", env.Namespace), zap.String("source", env.Name)))
+	build := func(url string) (*couchDbAdapter, error) {
+		return buildAdapter(ctx, env, ceClient, url, serverDriver(ctx, logging.FromContext(ctx), url))
+	}
+	ra, err := build(url)
+	if err != nil {
+		return err
+	}
+	// The adapter is replaced when the credentials are rotated.
+	return (&reloadingAdapter{
+		logger:   logging.FromContext(ctx),
+		adapter:  ra,
+		url:      url,
+		interval: tenantCredentialsPollInterval,
+		readURL: func(ctx context.Context) (string, error) {
+			return a.credentials(ctx, env.Namespace, config[contract.TenantCredentialsKey])
+		},
+		build: build,
+	}).Start(ctx)
+}
+
+// NewMTController returns the controller starting and stopping the adapters
+// of the sources as the controller writes and deletes their ConfigMaps.
+func NewMTController(ctx context.Context, a adapter.Adapter) *controller.Impl {
+	mt := a.(*mtAdapter)
+	selector := labels.SelectorFromSet(labels.Set{contract.TenantLabelKey: mt.installation}).String()
+	factory := informers.NewSharedInformerFactoryWithOptions(kubeclient.Get(ctx), controller.GetResyncPeriod(ctx),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = selector
+		}))
+	configMaps := factory.Core().V1().ConfigMaps()
+
+	impl := controller.NewContext(ctx, &tenantReconciler{lister: configMaps.Lister(), adapter: mt}, controller.ControllerOptions{
+		WorkQueueName: "CouchDbSourceTenants",
+		Logger:        logging.FromContext(ctx),
+	})
+	configMaps.Informer().AddEventHandler(controller.HandleAll(impl.Enqueue))
+	factory.Start(ctx.Done())
+	return impl
+}
+
+// tenantReconciler serves the sources of the ConfigMaps.
+type tenantReconciler struct {
+	lister  corev1listers.ConfigMapLister
+	adapter *mtAdapter
+}
+
+// Reconcile implements controller.Reconciler.
+func (r *tenantReconciler) Reconcile(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil
+	}
+	cm, err := r.lister.ConfigMaps(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		r.adapter.remove(key)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	r.adapter.update(key, cm.Data)
+	return nil
+}
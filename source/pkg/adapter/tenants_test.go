@@ -0,0 +1,122 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// fakeTenants records the adapters of the sources started and stopped by the
+// multi-tenant adapter.
+type fakeTenants struct {
+	mu      sync.Mutex
+	running map[string]string
+	starts  int
+}
+
+func (f *fakeTenants) serve(ctx context.Context, key string, config map[string]string) error {
+	f.mu.Lock()
+	if prev, ok := f.running[key]; ok {
+		f.mu.Unlock()
+		panic("two adapters of " + key + " at once: " + prev + " and " + config["env"])
+	}
+	f.running[key] = config["env"]
+	f.starts++
+	f.mu.Unlock()
+
+	<-ctx.Done()
+
+	f.mu.Lock()
+	delete(f.running, key)
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *fakeTenants) snapshot() (map[string]string, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	running := make(map[string]string, len(f.running))
+	for k, v := range f.running {
+		running[k] = v
+	}
+	return running, f.starts
+}
+
+func TestMTAdapter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f := &fakeTenants{running: map[string]string{}}
+	a := &mtAdapter{
+		ctx:     ctx,
+		logger:  zap.NewNop().Sugar(),
+		serve:   f.serve,
+		tenants: map[string]*tenant{},
+	}
+	done := make(chan error)
+	go func() { done <- a.Start(ctx) }()
+
+	waitFor := func(want map[string]string, wantStarts int) {
+		t.Helper()
+		var running map[string]string
+		var starts int
+		for i := 0; i < 100; i++ {
+			if running, starts = f.snapshot(); len(running) == len(want) && starts == wantStarts {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if starts != wantStarts {
+			t.Fatalf("started %d adapters, want %d", starts, wantStarts)
+		}
+		for k, v := range want {
+			if running[k] != v {
+				t.Fatalf("running = %v, want %v", running, want)
+			}
+		}
+		if len(running) != len(want) {
+			t.Fatalf("running = %v, want %v", running, want)
+		}
+	}
+
+	a.update("ns/a", map[string]string{"env": "1"})
+	a.update("ns/b", map[string]string{"env": "1"})
+	waitFor(map[string]string{"ns/a": "1", "ns/b": "1"}, 2)
+
+	// An unchanged configuration leaves the adapter running.
+	a.update("ns/a", map[string]string{"env": "1"})
+	waitFor(map[string]string{"ns/a": "1", "ns/b": "1"}, 2)
+
+	// A changed configuration restarts it.
+	a.update("ns/a", map[string]string{"env": "2"})
+	waitFor(map[string]string{"ns/a": "2", "ns/b": "1"}, 3)
+
+	a.remove("ns/b")
+	waitFor(map[string]string{"ns/a": "2"}, 3)
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+	if running, _ := f.snapshot(); len(running) != 0 {
+		t.Errorf("running = %v after the adapter stopped, want none", running)
+	}
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+
+	"go.opencensus.io/trace"
+)
+
+// changeSpanName is the name of the span of the delivery of a change.
+const changeSpanName = "couchdb.change"
+
+// startChangeSpan starts the span of the delivery of a change, from its read
+// to the response of the sink, as sampled by config-tracing. The events sent
+// with the returned context carry the trace context to the sink, so that the
+// traces of the downstream processing join it.
+func (a *couchDbAdapter) startChangeSpan(ctx context.Context, c change, seq string) (context.Context, *trace.Span) {
+	ctx, span := trace.StartSpan(ctx, changeSpanName)
+	span.AddAttributes(
+		trace.StringAttribute("couchdb.source", a.namespace+"/"+a.name),
+		trace.StringAttribute("couchdb.database", a.database),
+		trace.StringAttribute("couchdb.id", c.ID()),
+	)
+	if seq != "" {
+		span.AddAttributes(trace.StringAttribute("couchdb.seq", seq))
+	}
+	return ctx, span
+}
+
+// endChangeSpan ends the span of a change, whose event was reported or not.
+func endChangeSpan(span *trace.Span, reported bool) {
+	span.AddAttributes(trace.BoolAttribute("couchdb.reported", reported))
+	span.End()
+}
+
+// setSpanError records the error in the span of ctx, if any.
+func setSpanError(ctx context.Context, err error) {
+	if err == nil {
+		return
+	}
+	if span := trace.FromContext(ctx); span != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"knative.dev/eventing-couchdb/source/pkg/contract"
+)
+
+const (
+	// couchDriver is the kivik driver, and the dialect, used to talk to
+	// CouchDB servers.
+	couchDriver = "couchdb-adapter"
+)
+
+var (
+	// couchTransport carries the CouchDB traffic of the adapter. It is kept
+	// apart from http.DefaultTransport, which delivers events to the sink, so
+	// that CouchDB-only settings such as the egress proxy do not leak into
+	// sink deliveries.
+	couchTransport = http.DefaultTransport.(*http.Transport).Clone()
+
+	// sinkTransport is the transport under http.DefaultTransport, captured
+	// before the init functions wrap it, which sends the events.
+	sinkTransport = http.DefaultTransport.(*http.Transport)
+)
+
+func init() {
+	registerDialect(couchDriver, couchDialect{}, couchTransport)
+}
+
+// setProxy routes the CouchDB traffic through the given proxy, except for
+// the hosts matching noProxy.
+func setProxy(proxyURL string, noProxy []string) error {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return err
+	}
+	proxy := contract.ProxyFunc(u, noProxy)
+	for _, d := range dialects {
+		d.transport().Proxy = proxy
+	}
+	return nil
+}
+
+// setSinkCACerts verifies the sink with the PEM certificates on top of the
+// system ones, e.g. for sinks signed by a cluster-internal CA. The dead letter
+// sink and the reply, usually in the same cluster, trust them as well.
+func setSinkCACerts(caCerts string) error {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM([]byte(caCerts)) {
+		return errors.New("no certificate found")
+	}
+	cfg := sinkTransport.TLSClientConfig.Clone()
+	if cfg == nil {
+		cfg = &tls.Config{}
+	}
+	cfg.RootCAs = pool
+	sinkTransport.TLSClientConfig = cfg
+	return nil
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-kivik/kivik/v3"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+// window is the adapter side of spec.window. A nil window is unbounded.
+type window struct {
+	until     int64
+	exhausted bool
+}
+
+// newWindow resolves the end of the window, reading the current update
+// sequence of the database when it is "now".
+func newWindow(ctx context.Context, db *kivik.DB, until string) (*window, error) {
+	if until == "" {
+		return nil, nil
+	}
+	if until == v1alpha1.SequenceNow {
+		stats, err := db.Stats(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the update sequence of the database: %v", err)
+		}
+		until = stats.UpdateSeq
+	}
+	n, err := v1alpha1.SequenceNumber(until)
+	if err != nil {
+		return nil, fmt.Errorf("invalid window end %q: %v", until, err)
+	}
+	return &window{until: n}, nil
+}
+
+// after returns whether the sequence lies past the end of the window.
+func (w *window) after(seq string) bool {
+	if w == nil {
+		return false
+	}
+	n, err := v1alpha1.SequenceNumber(seq)
+	return err == nil && n > w.until
+}
+
+// reached returns whether the sequence is at or past the end of the window.
+func (w *window) reached(seq string) bool {
+	if w == nil {
+		return false
+	}
+	n, err := v1alpha1.SequenceNumber(seq)
+	return err == nil && n >= w.until
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+)
+
+const (
+	// DefaultSinksConfigName is the name of the ConfigMap holding the sinks
+	// of the sources without spec.sink.
+	DefaultSinksConfigName = "config-couchdb-default-sinks"
+
+	// DefaultSinksKey is the key of the ConfigMap holding the default sinks.
+	DefaultSinksKey = "default-sinks"
+)
+
+// DefaultSinks are the sinks of the sources without spec.sink, e.g. the
+// default Broker of their namespace.
+// +k8s:deepcopy-gen=false
+type DefaultSinks struct {
+	// ClusterDefault is the sink of the sources of the namespaces without
+	// their own default. A reference without a namespace targets the
+	// namespace of the source.
+	ClusterDefault *duckv1.Destination `json:"clusterDefault,omitempty"`
+
+	// NamespaceDefaults are the sinks of the sources of the given
+	// namespaces.
+	NamespaceDefaults map[string]*duckv1.Destination `json:"namespaceDefaults,omitempty"`
+}
+
+// NewDefaultSinksFromConfigMap parses the default sinks ConfigMap.
+func NewDefaultSinksFromConfigMap(cm *corev1.ConfigMap) (*DefaultSinks, error) {
+	ds := &DefaultSinks{}
+	value, ok := cm.Data[DefaultSinksKey]
+	if !ok {
+		return ds, nil
+	}
+	if err := yaml.Unmarshal([]byte(value), ds); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", DefaultSinksKey, err)
+	}
+	if ds.ClusterDefault != nil {
+		if err := ds.ClusterDefault.Validate(context.Background()); err != nil {
+			return nil, fmt.Errorf("invalid clusterDefault: %v", err)
+		}
+	}
+	for ns, d := range ds.NamespaceDefaults {
+		if d == nil {
+			return nil, fmt.Errorf("missing sink for namespace %q", ns)
+		}
+		if err := d.Validate(context.Background()); err != nil {
+			return nil, fmt.Errorf("invalid sink for namespace %q: %v", ns, err)
+		}
+	}
+	return ds, nil
+}
+
+// Sink returns a copy of the default sink of the sources of the namespace,
+// or nil when there is none.
+func (ds *DefaultSinks) Sink(namespace string) *duckv1.Destination {
+	if ds == nil {
+		return nil
+	}
+	if d, ok := ds.NamespaceDefaults[namespace]; ok {
+		return d.DeepCopy()
+	}
+	return ds.ClusterDefault.DeepCopy()
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+)
+
+func TestNewDefaultSinksFromConfigMap(t *testing.T) {
+	broker := &duckv1.Destination{
+		Ref: &duckv1.KReference{APIVersion: "eventing.knative.dev/v1", Kind: "Broker", Name: "default"},
+	}
+	testCases := map[string]struct {
+		data    map[string]string
+		want    *DefaultSinks
+		wantErr bool
+	}{
+		"empty": {
+			want: &DefaultSinks{},
+		},
+		"cluster and namespace defaults": {
+			data: map[string]string{DefaultSinksKey: `
+clusterDefault:
+  ref:
+    apiVersion: eventing.knative.dev/v1
+    kind: Broker
+    name: default
+namespaceDefaults:
+  team-a:
+    uri: http://sink.team-a.svc.cluster.local
+`},
+			want: &DefaultSinks{
+				ClusterDefault: broker,
+				NamespaceDefaults: map[string]*duckv1.Destination{
+					"team-a": {URI: apis.HTTP("sink.team-a.svc.cluster.local")},
+				},
+			},
+		},
+		"invalid yaml": {
+			data:    map[string]string{DefaultSinksKey: "clusterDefault: ["},
+			wantErr: true,
+		},
+		"invalid cluster default": {
+			data:    map[string]string{DefaultSinksKey: "clusterDefault: {}"},
+			wantErr: true,
+		},
+		"missing namespace default": {
+			data:    map[string]string{DefaultSinksKey: "namespaceDefaults: {team-a: null}"},
+			wantErr: true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			got, err := NewDefaultSinksFromConfigMap(&corev1.ConfigMap{Data: tc.data})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("NewDefaultSinksFromConfigMap() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("NewDefaultSinksFromConfigMap() (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
+
+func TestDefaultSinksSink(t *testing.T) {
+	cluster := &duckv1.Destination{URI: apis.HTTP("cluster")}
+	teamA := &duckv1.Destination{URI: apis.HTTP("team-a")}
+	ds := &DefaultSinks{
+		ClusterDefault:    cluster,
+		NamespaceDefaults: map[string]*duckv1.Destination{"team-a": teamA},
+	}
+	if got := ds.Sink("team-a"); !cmp.Equal(got, teamA) {
+		t.Errorf("Sink(team-a) = %v, want %v", got, teamA)
+	}
+	if got := ds.Sink("team-b"); !cmp.Equal(got, cluster) {
+		t.Errorf("Sink(team-b) = %v, want %v", got, cluster)
+	}
+	if got := (&DefaultSinks{}).Sink("team-b"); got != nil {
+		t.Errorf("Sink() without defaults = %v, want nil", got)
+	}
+
+	// The returned sink is a copy, which the reconciler fills in.
+	ds.Sink("team-a").URI.Host = "changed"
+	if teamA.URI.Host != "team-a" {
+		t.Error("Sink() returned the configured sink rather than a copy")
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// FeedConfigName is the name of the ConfigMap describing the network
+	// between the receive adapters and CouchDB, which the heartbeats of the
+	// continuous feeds are tuned to.
+	FeedConfigName = "config-couchdb-feed"
+
+	// FeedInfrastructureKey is the key of the ConfigMap holding the
+	// infrastructure whose load balancers sit in front of CouchDB. It
+	// defaults to the infrastructure detected from the nodes of the cluster.
+	FeedInfrastructureKey = "infrastructure"
+
+	// FeedIdleTimeoutKey is the key of the ConfigMap holding the ISO 8601
+	// idle timeout of the load balancers and proxies in front of CouchDB. It
+	// overrides the one of the infrastructure.
+	FeedIdleTimeoutKey = "idle-timeout"
+)
+
+// The infrastructures whose load balancers have a known idle timeout.
+const (
+	// InfrastructureAWS is AWS, whose Elastic Load Balancers drop the
+	// connections idle for 60 seconds.
+	InfrastructureAWS = "aws"
+
+	// InfrastructureAzure is Azure, whose Load Balancers drop the
+	// connections idle for 4 minutes.
+	InfrastructureAzure = "azure"
+
+	// InfrastructureGCP is Google Cloud, whose firewalls forget the
+	// connections idle for 10 minutes.
+	InfrastructureGCP = "gcp"
+
+	// InfrastructureNone is a network without load balancers in front of
+	// CouchDB, e.g. a CouchDB running in the cluster.
+	InfrastructureNone = "none"
+)
+
+// idleTimeouts are the default idle timeouts of the infrastructures.
+var idleTimeouts = map[string]time.Duration{
+	InfrastructureAWS:   60 * time.Second,
+	InfrastructureAzure: 4 * time.Minute,
+	InfrastructureGCP:   10 * time.Minute,
+	InfrastructureNone:  0,
+}
+
+// providerIDPrefixes map the prefixes of the provider IDs of the nodes to
+// their infrastructure.
+var providerIDPrefixes = map[string]string{
+	"aws://":   InfrastructureAWS,
+	"azure://": InfrastructureAzure,
+	"gce://":   InfrastructureGCP,
+}
+
+// Feed describes the network between the receive adapters and CouchDB.
+// +k8s:deepcopy-gen=false
+type Feed struct {
+	// Infrastructure is the infrastructure whose load balancers sit in
+	// front of CouchDB, empty when unknown.
+	Infrastructure string
+
+	// IdleTimeout is the idle timeout of the load balancers configured in
+	// the ConfigMap, zero when it is the one of the infrastructure.
+	IdleTimeout time.Duration
+}
+
+// NewFeedFromConfigMap parses the feed ConfigMap.
+func NewFeedFromConfigMap(cm *corev1.ConfigMap) (*Feed, error) {
+	f := &Feed{Infrastructure: cm.Data[FeedInfrastructureKey]}
+	if _, ok := idleTimeouts[f.Infrastructure]; f.Infrastructure != "" && !ok {
+		return nil, fmt.Errorf("unknown %s %q: must be one of %s, %s, %s or %s", FeedInfrastructureKey, f.Infrastructure,
+			InfrastructureAWS, InfrastructureAzure, InfrastructureGCP, InfrastructureNone)
+	}
+	idle, err := parseDelay(FeedIdleTimeoutKey, cm.Data[FeedIdleTimeoutKey])
+	if err != nil {
+		return nil, err
+	}
+	f.IdleTimeout = idle
+	return f, nil
+}
+
+// LoadBalancerIdleTimeout returns the idle timeout after which the load
+// balancers in front of CouchDB drop the connections of the feeds, zero
+// when there is none or it is unknown.
+func (f *Feed) LoadBalancerIdleTimeout() time.Duration {
+	if f == nil {
+		return 0
+	}
+	if f.IdleTimeout > 0 {
+		return f.IdleTimeout
+	}
+	return idleTimeouts[f.Infrastructure]
+}
+
+// DetectInfrastructure returns the infrastructure of the cluster, from the
+// provider ID of its nodes, or an empty string when it cannot be told.
+func DetectInfrastructure(ctx context.Context, client kubernetes.Interface) (string, error) {
+	nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{Limit: 1})
+	if err != nil {
+		return "", fmt.Errorf("failed to list the nodes: %w", err)
+	}
+	if len(nodes.Items) == 0 {
+		return "", nil
+	}
+	return infrastructureOf(nodes.Items[0].Spec.ProviderID), nil
+}
+
+// infrastructureOf returns the infrastructure of a node from its provider
+// ID, e.g. aws:///us-east-1a/i-0123, or an empty string when it is unknown.
+func infrastructureOf(providerID string) string {
+	for prefix, infrastructure := range providerIDPrefixes {
+		if strings.HasPrefix(providerID, prefix) {
+			return infrastructure
+		}
+	}
+	return ""
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestNewFeedFromConfigMap(t *testing.T) {
+	testCases := map[string]struct {
+		data     map[string]string
+		want     *Feed
+		wantIdle time.Duration
+		wantErr  bool
+	}{
+		"empty": {
+			want: &Feed{},
+		},
+		"infrastructure": {
+			data:     map[string]string{FeedInfrastructureKey: InfrastructureAWS},
+			want:     &Feed{Infrastructure: InfrastructureAWS},
+			wantIdle: time.Minute,
+		},
+		"no load balancer": {
+			data: map[string]string{FeedInfrastructureKey: InfrastructureNone},
+			want: &Feed{Infrastructure: InfrastructureNone},
+		},
+		"idle timeout overriding the infrastructure": {
+			data:     map[string]string{FeedInfrastructureKey: InfrastructureAzure, FeedIdleTimeoutKey: "PT30S"},
+			want:     &Feed{Infrastructure: InfrastructureAzure, IdleTimeout: 30 * time.Second},
+			wantIdle: 30 * time.Second,
+		},
+		"unknown infrastructure": {
+			data:    map[string]string{FeedInfrastructureKey: "openstack"},
+			wantErr: true,
+		},
+		"invalid idle timeout": {
+			data:    map[string]string{FeedIdleTimeoutKey: "30s"},
+			wantErr: true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			got, err := NewFeedFromConfigMap(&corev1.ConfigMap{Data: tc.data})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("NewFeedFromConfigMap() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("unexpected feed (-want, +got) = %v", diff)
+			}
+			if got != nil && got.LoadBalancerIdleTimeout() != tc.wantIdle {
+				t.Errorf("LoadBalancerIdleTimeout() = %v, want %v", got.LoadBalancerIdleTimeout(), tc.wantIdle)
+			}
+		})
+	}
+}
+
+func TestInfrastructureOf(t *testing.T) {
+	for providerID, want := range map[string]string{
+		"aws:///us-east-1a/i-0123456789abcdef0":                                                        InfrastructureAWS,
+		"azure:///subscriptions/1234/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm": InfrastructureAzure,
+		"gce://project/us-central1-a/node-1":                                                           InfrastructureGCP,
+		"kind://docker/kind/kind-control-plane":                                                        "",
+		"":                                                                                             "",
+	} {
+		if got := infrastructureOf(providerID); got != want {
+			t.Errorf("infrastructureOf(%q) = %q, want %q", providerID, got, want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rickb777/date/period"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// ReconnectConfigName is the name of the ConfigMap holding the default
+	// reconnect backoff of the sources.
+	ReconnectConfigName = "config-couchdb-reconnect"
+
+	// ReconnectInitialDelayKey and ReconnectMaxDelayKey are the keys of the
+	// ConfigMap holding the ISO 8601 bounds of the backoff.
+	ReconnectInitialDelayKey = "initial-delay"
+	ReconnectMaxDelayKey     = "max-delay"
+)
+
+// Reconnect is the reconnect backoff of the sources whose spec.reconnect
+// leaves it unset. Empty bounds fall back to the defaults of the adapter.
+// +k8s:deepcopy-gen=false
+type Reconnect struct {
+	// InitialDelay is the ISO 8601 delay before reopening a feed after its
+	// first failure.
+	InitialDelay string
+
+	// MaxDelay is the ISO 8601 longest delay before reopening a feed.
+	MaxDelay string
+}
+
+// NewReconnectFromConfigMap parses the reconnect ConfigMap.
+func NewReconnectFromConfigMap(cm *corev1.ConfigMap) (*Reconnect, error) {
+	r := &Reconnect{
+		InitialDelay: cm.Data[ReconnectInitialDelayKey],
+		MaxDelay:     cm.Data[ReconnectMaxDelayKey],
+	}
+	initial, err := parseDelay(ReconnectInitialDelayKey, r.InitialDelay)
+	if err != nil {
+		return nil, err
+	}
+	max, err := parseDelay(ReconnectMaxDelayKey, r.MaxDelay)
+	if err != nil {
+		return nil, err
+	}
+	if initial > 0 && max > 0 && initial > max {
+		return nil, fmt.Errorf("%s %s is shorter than %s %s", ReconnectMaxDelayKey, r.MaxDelay, ReconnectInitialDelayKey, r.InitialDelay)
+	}
+	return r, nil
+}
+
+// parseDelay parses an optional, positive ISO 8601 delay of the ConfigMap.
+func parseDelay(key, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	p, err := period.Parse(value)
+	if err != nil || p.DurationApprox() <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a positive ISO 8601 duration", key, value)
+	}
+	return p.DurationApprox(), nil
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestNewReconnectFromConfigMap(t *testing.T) {
+	testCases := map[string]struct {
+		data    map[string]string
+		want    *Reconnect
+		wantErr bool
+	}{
+		"empty": {
+			want: &Reconnect{},
+		},
+		"both delays": {
+			data: map[string]string{ReconnectInitialDelayKey: "PT1S", ReconnectMaxDelayKey: "PT5M"},
+			want: &Reconnect{InitialDelay: "PT1S", MaxDelay: "PT5M"},
+		},
+		"only the max delay": {
+			data: map[string]string{ReconnectMaxDelayKey: "PT30S"},
+			want: &Reconnect{MaxDelay: "PT30S"},
+		},
+		"invalid initial delay": {
+			data:    map[string]string{ReconnectInitialDelayKey: "1s"},
+			wantErr: true,
+		},
+		"zero max delay": {
+			data:    map[string]string{ReconnectMaxDelayKey: "PT0S"},
+			wantErr: true,
+		},
+		"max delay shorter than the initial delay": {
+			data:    map[string]string{ReconnectInitialDelayKey: "PT1M", ReconnectMaxDelayKey: "PT10S"},
+			wantErr: true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			got, err := NewReconnectFromConfigMap(&corev1.ConfigMap{Data: tc.data})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("NewReconnectFromConfigMap() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("unexpected reconnect (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package config holds the configuration of the sources shared by the
+// webhook and the controller, loaded from ConfigMaps of the system namespace.
+package config
+
+import (
+	"context"
+
+	"knative.dev/pkg/configmap"
+)
+
+type cfgKey struct{}
+
+// Config holds the collection of configurations that we attach to contexts.
+// +k8s:deepcopy-gen=false
+type Config struct {
+	DefaultSinks *DefaultSinks
+	Reconnect    *Reconnect
+	Feed         *Feed
+}
+
+// FromContext extracts a Config from the provided context.
+func FromContext(ctx context.Context) *Config {
+	x, ok := ctx.Value(cfgKey{}).(*Config)
+	if ok {
+		return x
+	}
+	return nil
+}
+
+// FromContextOrDefaults is like FromContext, but when no Config is attached
+// it returns a Config without default sinks, reconnect backoff nor load
+// balancers in front of CouchDB.
+func FromContextOrDefaults(ctx context.Context) *Config {
+	if cfg := FromContext(ctx); cfg != nil {
+		return cfg
+	}
+	return &Config{DefaultSinks: &DefaultSinks{}, Reconnect: &Reconnect{}, Feed: &Feed{}}
+}
+
+// ToContext attaches the provided Config to the provided context, returning
+// the new context with the Config attached.
+func ToContext(ctx context.Context, c *Config) context.Context {
+	return context.WithValue(ctx, cfgKey{}, c)
+}
+
+// Store is a typed wrapper around configmap.UntypedStore to handle our
+// ConfigMaps.
+// +k8s:deepcopy-gen=false
+type Store struct {
+	*configmap.UntypedStore
+
+	// Infrastructure is the infrastructure detected from the nodes of the
+	// cluster, which the feed ConfigMap defaults to.
+	Infrastructure string
+}
+
+// NewStore creates a new store of Configs and optionally calls functions
+// when ConfigMaps are updated.
+func NewStore(logger configmap.Logger, onAfterStore ...func(name string, value interface{})) *Store {
+	return &Store{
+		UntypedStore: configmap.NewUntypedStore(
+			"couchdb",
+			logger,
+			configmap.Constructors{
+				DefaultSinksConfigName: NewDefaultSinksFromConfigMap,
+				ReconnectConfigName:    NewReconnectFromConfigMap,
+				FeedConfigName:         NewFeedFromConfigMap,
+			},
+			onAfterStore...,
+		),
+	}
+}
+
+// ToContext attaches the current Config state to the provided context.
+func (s *Store) ToContext(ctx context.Context) context.Context {
+	return ToContext(ctx, s.Load())
+}
+
+// Load creates a Config from the current config state of the Store.
+func (s *Store) Load() *Config {
+	cfg := &Config{DefaultSinks: &DefaultSinks{}, Reconnect: &Reconnect{}, Feed: &Feed{}}
+	if ds, ok := s.UntypedLoad(DefaultSinksConfigName).(*DefaultSinks); ok && ds != nil {
+		cfg.DefaultSinks = ds
+	}
+	if r, ok := s.UntypedLoad(ReconnectConfigName).(*Reconnect); ok && r != nil {
+		cfg.Reconnect = r
+	}
+	if f, ok := s.UntypedLoad(FeedConfigName).(*Feed); ok && f != nil {
+		cfg.Feed = f
+	}
+	if cfg.Feed.Infrastructure == "" && s.Infrastructure != "" {
+		feed := *cfg.Feed
+		feed.Infrastructure = s.Infrastructure
+		cfg.Feed = &feed
+	}
+	return cfg
+}
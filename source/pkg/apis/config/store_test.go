@@ -0,0 +1,68 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logtesting "knative.dev/pkg/logging/testing"
+)
+
+func TestStoreLoadWithContext(t *testing.T) {
+	store := NewStore(logtesting.TestLogger(t))
+	store.Infrastructure = InfrastructureGCP
+	store.OnConfigChanged(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: DefaultSinksConfigName, Namespace: "knative-sources"},
+		Data:       map[string]string{DefaultSinksKey: "clusterDefault: {uri: http://sink.example.com}"},
+	})
+	store.OnConfigChanged(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: ReconnectConfigName, Namespace: "knative-sources"},
+		Data:       map[string]string{ReconnectMaxDelayKey: "PT5M"},
+	})
+	store.OnConfigChanged(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: FeedConfigName, Namespace: "knative-sources"},
+		Data:       map[string]string{FeedIdleTimeoutKey: "PT1M"},
+	})
+
+	cfg := FromContext(store.ToContext(context.Background()))
+	if got := cfg.DefaultSinks.Sink("any"); got == nil || got.URI.String() != "http://sink.example.com" {
+		t.Errorf("Sink() = %v, want http://sink.example.com", got)
+	}
+	if got := cfg.Reconnect.MaxDelay; got != "PT5M" {
+		t.Errorf("Reconnect.MaxDelay = %q, want PT5M", got)
+	}
+	// The infrastructure detected fills in the one of the ConfigMap.
+	if got, want := *cfg.Feed, (Feed{Infrastructure: InfrastructureGCP, IdleTimeout: time.Minute}); got != want {
+		t.Errorf("Feed = %+v, want %+v", got, want)
+	}
+}
+
+func TestFromContextOrDefaults(t *testing.T) {
+	if got := FromContextOrDefaults(context.Background()).DefaultSinks.Sink("any"); got != nil {
+		t.Errorf("Sink() without configuration = %v, want nil", got)
+	}
+	if got := FromContextOrDefaults(context.Background()).Reconnect; *got != (Reconnect{}) {
+		t.Errorf("Reconnect without configuration = %+v, want none", got)
+	}
+	if got := FromContextOrDefaults(context.Background()).Feed.LoadBalancerIdleTimeout(); got != 0 {
+		t.Errorf("LoadBalancerIdleTimeout() without configuration = %v, want none", got)
+	}
+}
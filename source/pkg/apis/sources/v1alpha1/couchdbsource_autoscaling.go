@@ -0,0 +1,144 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"strconv"
+
+	"knative.dev/pkg/apis"
+)
+
+// The annotations scaling the receive adapter with KEDA, named after the ones
+// of the Kafka source. KEDA reads the number of changes waiting for the
+// adapter from the controller, and scales the adapter to zero while the
+// database is idle.
+const (
+	// AutoscalingClassAnnotationKey selects the autoscaler of the receive
+	// adapter. Only KedaAutoscalingClass is supported.
+	AutoscalingClassAnnotationKey = "autoscaling.knative.dev/class"
+	KedaAutoscalingClass          = "keda.autoscaling.knative.dev"
+
+	// AutoscalingMinScaleAnnotationKey and AutoscalingMaxScaleAnnotationKey
+	// bound the replicas of the receive adapter, 0 and the replicas of the
+	// source by default.
+	AutoscalingMinScaleAnnotationKey = "autoscaling.knative.dev/minScale"
+	AutoscalingMaxScaleAnnotationKey = "autoscaling.knative.dev/maxScale"
+
+	// KedaPollingIntervalAnnotationKey is how often, in seconds, KEDA reads
+	// the lag of the source.
+	KedaPollingIntervalAnnotationKey = "keda.autoscaling.knative.dev/pollingInterval"
+
+	// KedaCooldownPeriodAnnotationKey is how long, in seconds, the source
+	// stays idle before its receive adapter is scaled to zero.
+	KedaCooldownPeriodAnnotationKey = "keda.autoscaling.knative.dev/cooldownPeriod"
+
+	// KedaLagThresholdAnnotationKey is the number of changes waiting for
+	// each replica of the receive adapter KEDA aims for.
+	KedaLagThresholdAnnotationKey = "keda.autoscaling.knative.dev/couchDbLagThreshold"
+)
+
+// The defaults of the KEDA annotations.
+const (
+	DefaultKedaPollingInterval = 30
+	DefaultKedaCooldownPeriod  = 300
+	DefaultKedaLagThreshold    = 10
+)
+
+// KedaAutoscaling is how KEDA scales the receive adapter of a source.
+type KedaAutoscaling struct {
+	MinReplicas int32
+	MaxReplicas int32
+
+	// PollingInterval and CooldownPeriod are in seconds.
+	PollingInterval int32
+	CooldownPeriod  int32
+
+	LagThreshold int32
+}
+
+// KedaAutoscaled is whether KEDA scales the receive adapter of the source.
+func (c *CouchDbSource) KedaAutoscaled() bool {
+	return c.Annotations[AutoscalingClassAnnotationKey] == KedaAutoscalingClass
+}
+
+// KedaAutoscaling returns how KEDA scales the receive adapter of the source,
+// or nil when it does not. The errors are relative to the annotations.
+func (c *CouchDbSource) KedaAutoscaling() (*KedaAutoscaling, *apis.FieldError) {
+	class, ok := c.Annotations[AutoscalingClassAnnotationKey]
+	if !ok {
+		return nil, nil
+	}
+	if class != KedaAutoscalingClass {
+		fe := apis.ErrInvalidValue(class, AutoscalingClassAnnotationKey)
+		fe.Details = "only " + KedaAutoscalingClass + " is supported"
+		return nil, fe
+	}
+
+	var errs *apis.FieldError
+	annotation := func(key string, def, min int32) int32 {
+		v, ok := c.Annotations[key]
+		if !ok {
+			return def
+		}
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || int32(n) < min {
+			fe := apis.ErrInvalidValue(v, key)
+			fe.Details = "must be an integer of at least " + strconv.Itoa(int(min))
+			errs = errs.Also(fe)
+			return def
+		}
+		return int32(n)
+	}
+	k := &KedaAutoscaling{
+		MinReplicas:     annotation(AutoscalingMinScaleAnnotationKey, 0, 0),
+		MaxReplicas:     annotation(AutoscalingMaxScaleAnnotationKey, c.Spec.AdapterReplicas(), 1),
+		PollingInterval: annotation(KedaPollingIntervalAnnotationKey, DefaultKedaPollingInterval, 1),
+		CooldownPeriod:  annotation(KedaCooldownPeriodAnnotationKey, DefaultKedaCooldownPeriod, 1),
+		LagThreshold:    annotation(KedaLagThresholdAnnotationKey, DefaultKedaLagThreshold, 1),
+	}
+	if errs != nil {
+		return nil, errs
+	}
+
+	if k.MinReplicas > k.MaxReplicas {
+		fe := apis.ErrInvalidValue(c.Annotations[AutoscalingMaxScaleAnnotationKey], AutoscalingMaxScaleAnnotationKey)
+		fe.Details = "must be at least " + AutoscalingMinScaleAnnotationKey
+		errs = errs.Also(fe)
+	}
+	if k.MaxReplicas > 1 && !c.Spec.Leased() {
+		// The replicas would deliver every change as many times.
+		fe := apis.ErrInvalidValue(c.Annotations[AutoscalingMaxScaleAnnotationKey], AutoscalingMaxScaleAnnotationKey)
+		fe.Details = "above 1 requires spec.highAvailability"
+		errs = errs.Also(fe)
+	}
+	if c.Spec.Window != nil {
+		errs = errs.Also(apis.ErrGeneric("not supported with spec.window", AutoscalingClassAnnotationKey))
+	}
+	if c.Spec.Backfill {
+		// Every scale from zero would backfill again.
+		errs = errs.Also(apis.ErrGeneric("not supported with spec.backfill", AutoscalingClassAnnotationKey))
+	}
+	if c.Spec.Buffer != nil {
+		// The lag of the changes feed does not count the buffered events,
+		// which a scale to zero would hold.
+		errs = errs.Also(apis.ErrGeneric("not supported with spec.buffer", AutoscalingClassAnnotationKey))
+	}
+	if errs != nil {
+		return nil, errs
+	}
+	return k, nil
+}
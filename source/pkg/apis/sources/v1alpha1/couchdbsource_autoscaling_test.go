@@ -0,0 +1,118 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestKedaAutoscaling(t *testing.T) {
+	keda := func(annotations map[string]string) map[string]string {
+		annotations[AutoscalingClassAnnotationKey] = KedaAutoscalingClass
+		return annotations
+	}
+	testCases := map[string]struct {
+		annotations map[string]string
+		spec        CouchDbSourceSpec
+		want        *KedaAutoscaling
+		wantErr     string
+	}{
+		"not autoscaled": {},
+		"defaults": {
+			annotations: keda(map[string]string{}),
+			want: &KedaAutoscaling{
+				MaxReplicas:     1,
+				PollingInterval: DefaultKedaPollingInterval,
+				CooldownPeriod:  DefaultKedaCooldownPeriod,
+				LagThreshold:    DefaultKedaLagThreshold,
+			},
+		},
+		"high availability": {
+			annotations: keda(map[string]string{
+				AutoscalingMinScaleAnnotationKey: "1",
+				KedaPollingIntervalAnnotationKey: "10",
+				KedaCooldownPeriodAnnotationKey:  "60",
+				KedaLagThresholdAnnotationKey:    "100",
+			}),
+			spec: CouchDbSourceSpec{HighAvailability: &HighAvailabilitySpec{Replicas: 3}},
+			want: &KedaAutoscaling{
+				MinReplicas:     1,
+				MaxReplicas:     3,
+				PollingInterval: 10,
+				CooldownPeriod:  60,
+				LagThreshold:    100,
+			},
+		},
+		"other class": {
+			annotations: map[string]string{AutoscalingClassAnnotationKey: "hpa.autoscaling.knative.dev"},
+			wantErr:     "invalid value: hpa.autoscaling.knative.dev: autoscaling.knative.dev/class\nonly keda.autoscaling.knative.dev is supported",
+		},
+		"not an integer": {
+			annotations: keda(map[string]string{AutoscalingMinScaleAnnotationKey: "one"}),
+			wantErr:     "invalid value: one: autoscaling.knative.dev/minScale\nmust be an integer of at least 0",
+		},
+		"inverted bounds": {
+			annotations: keda(map[string]string{
+				AutoscalingMinScaleAnnotationKey: "3",
+				AutoscalingMaxScaleAnnotationKey: "2",
+			}),
+			spec:    CouchDbSourceSpec{HighAvailability: &HighAvailabilitySpec{Replicas: 2}},
+			wantErr: "invalid value: 2: autoscaling.knative.dev/maxScale\nmust be at least autoscaling.knative.dev/minScale",
+		},
+		"replicas without lease": {
+			annotations: keda(map[string]string{AutoscalingMaxScaleAnnotationKey: "2"}),
+			wantErr:     "invalid value: 2: autoscaling.knative.dev/maxScale\nabove 1 requires spec.highAvailability",
+		},
+		"window": {
+			annotations: keda(map[string]string{}),
+			spec:        CouchDbSourceSpec{Window: &WindowSpec{Since: "now"}},
+			wantErr:     "not supported with spec.window: autoscaling.knative.dev/class",
+		},
+		"backfill": {
+			annotations: keda(map[string]string{}),
+			spec:        CouchDbSourceSpec{Backfill: true},
+			wantErr:     "not supported with spec.backfill: autoscaling.knative.dev/class",
+		},
+		"buffer": {
+			annotations: keda(map[string]string{}),
+			spec:        CouchDbSourceSpec{Buffer: &BufferSpec{Size: resource.MustParse("1Gi")}},
+			wantErr:     "not supported with spec.buffer: autoscaling.knative.dev/class",
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			src := &CouchDbSource{
+				ObjectMeta: metav1.ObjectMeta{Annotations: tc.annotations},
+				Spec:       tc.spec,
+			}
+			got, fe := src.KedaAutoscaling()
+			if diff := cmp.Diff(tc.wantErr, fe.Error()); diff != "" {
+				t.Errorf("KedaAutoscaling() error (-want, +got) = %v", diff)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("KedaAutoscaling() (-want, +got) = %v", diff)
+			}
+			if want := tc.want != nil; src.KedaAutoscaled() != want && tc.wantErr == "" {
+				t.Errorf("KedaAutoscaled() = %v, want %v", src.KedaAutoscaled(), want)
+			}
+		})
+	}
+}
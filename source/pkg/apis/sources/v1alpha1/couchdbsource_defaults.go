@@ -18,14 +18,117 @@ package v1alpha1
 
 import (
 	"context"
+	"time"
+
+	"github.com/rickb777/date/period"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+	"knative.dev/pkg/apis"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/config"
 )
 
+// defaultHeartbeatKey is the context key of the heartbeat given to the
+// continuous feeds.
+type defaultHeartbeatKey struct{}
+
+// WithDefaultHeartbeat makes SetDefaults give the continuous feeds the ISO
+// 8601 heartbeat rather than DefaultHeartbeat, like the controller does with
+// the sources stored without one.
+func WithDefaultHeartbeat(ctx context.Context, heartbeat string) context.Context {
+	return context.WithValue(ctx, defaultHeartbeatKey{}, heartbeat)
+}
+
+// DefaultHeartbeatFor returns the ISO 8601 heartbeat given to the
+// continuous feeds: the one of the controller, else half the idle timeout of
+// the load balancers in front of CouchDB, else DefaultHeartbeat.
+func DefaultHeartbeatFor(ctx context.Context) string {
+	if heartbeat, ok := ctx.Value(defaultHeartbeatKey{}).(string); ok && heartbeat != "" {
+		return heartbeat
+	}
+	if idle := config.FromContextOrDefaults(ctx).Feed.LoadBalancerIdleTimeout(); idle > 0 {
+		return isoDuration(heartbeatWithin(idle))
+	}
+	return isoDuration(DefaultHeartbeat)
+}
+
+// heartbeatWithin returns the heartbeat of the feeds behind load balancers
+// with the idle timeout: half of it, in whole seconds and within the bounds
+// of the heartbeats.
+func heartbeatWithin(idle time.Duration) time.Duration {
+	heartbeat := (idle / 2).Truncate(time.Second)
+	if heartbeat < MinFeedTiming {
+		return MinFeedTiming
+	}
+	if heartbeat > MaxFeedTiming {
+		return MaxFeedTiming
+	}
+	return heartbeat
+}
+
 func (c *CouchDbSource) SetDefaults(ctx context.Context) {
 	c.Spec.SetDefaults(ctx)
 }
 
+// SetDefaults fills the feed, heartbeat, payload, content mode, batching and
+// delivery so that the stored sources spell out the behavior of their adapter.
 func (cs *CouchDbSourceSpec) SetDefaults(ctx context.Context) {
 	if cs.Feed == "" {
 		cs.Feed = FeedContinuous
 	}
+
+	heartbeat := DefaultHeartbeatFor(ctx)
+	switch cs.Feed {
+	case FeedContinuous:
+		if cs.Heartbeat == "" && cs.Timeout == "" {
+			cs.Heartbeat = heartbeat
+		} else if cs.Timeout != "" && heartbeatDefaulted(ctx, cs.Heartbeat, heartbeat) {
+			// Drop the heartbeat defaulted before the timeout was set, which
+			// would otherwise keep the update from validating.
+			cs.Heartbeat = ""
+		}
+	case FeedNormal:
+		// Drop the heartbeat defaulted while the feed was continuous, which
+		// would otherwise keep the switch to the normal feed from validating.
+		if heartbeatDefaulted(ctx, cs.Heartbeat, heartbeat) {
+			cs.Heartbeat = ""
+		}
+	}
+
+	if cs.Payload == "" {
+		cs.Payload = PayloadRevisions
+	}
+	if cs.ContentMode == "" {
+		cs.ContentMode = ContentModeBinary
+	}
+	// A JobSink starts a Job for each event, so the changes are sent to it
+	// in batches rather than one Job each.
+	if IsJobSink(cs.Sink) && cs.Batch == nil && cs.ContentMode != ContentModeBatch &&
+		(cs.Grouping == nil || !cs.Grouping.Batch) {
+		cs.Batch = &BatchSpec{}
+	}
+
+	if d := cs.Delivery; d != nil && d.Retry != nil && *d.Retry > 0 {
+		if d.BackoffPolicy == nil {
+			policy := eventingduckv1.BackoffPolicyExponential
+			d.BackoffPolicy = &policy
+		}
+		if d.BackoffDelay == nil {
+			delay := isoDuration(DefaultBackoffDelay)
+			d.BackoffDelay = &delay
+		}
+	}
+}
+
+// heartbeatDefaulted returns whether the heartbeat of an updated source is
+// the one SetDefaults gave to the continuous feed it had without a timeout.
+func heartbeatDefaulted(ctx context.Context, heartbeat, defaulted string) bool {
+	old, ok := apis.GetBaseline(ctx).(*CouchDbSource)
+	return ok && old.Spec.Feed == FeedContinuous && old.Spec.Timeout == "" &&
+		old.Spec.Heartbeat == heartbeat && heartbeat == defaulted
+}
+
+// isoDuration formats the duration in ISO 8601, e.g. PT6S.
+func isoDuration(d time.Duration) string {
+	p, _ := period.NewOf(d)
+	return p.String()
 }
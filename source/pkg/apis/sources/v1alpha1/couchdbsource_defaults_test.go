@@ -19,12 +19,22 @@ package v1alpha1
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/rickb777/date/period"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/ptr"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/config"
 )
 
 func TestCouchDbDefaults(t *testing.T) {
+	exponential := eventingduckv1.BackoffPolicyExponential
+	linear := eventingduckv1.BackoffPolicyLinear
 	testCases := map[string]struct {
+		ctx      context.Context
 		initial  CouchDbSource
 		expected CouchDbSource
 	}{
@@ -32,7 +42,10 @@ func TestCouchDbDefaults(t *testing.T) {
 			initial: CouchDbSource{},
 			expected: CouchDbSource{
 				Spec: CouchDbSourceSpec{
-					Feed: FeedContinuous,
+					Feed:        FeedContinuous,
+					Heartbeat:   "PT6S",
+					Payload:     PayloadRevisions,
+					ContentMode: ContentModeBinary,
 				},
 			},
 		},
@@ -42,18 +55,237 @@ func TestCouchDbDefaults(t *testing.T) {
 			},
 			expected: CouchDbSource{
 				Spec: CouchDbSourceSpec{
-
-					Feed: FeedContinuous,
+					Feed:        FeedContinuous,
+					Heartbeat:   "PT6S",
+					Payload:     PayloadRevisions,
+					ContentMode: ContentModeBinary,
+				},
+			},
+		},
+		"default heartbeat of the installation": {
+			ctx: WithDefaultHeartbeat(context.Background(), "PT20S"),
+			initial: CouchDbSource{
+				Spec: CouchDbSourceSpec{Feed: FeedContinuous},
+			},
+			expected: CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Feed:        FeedContinuous,
+					Heartbeat:   "PT20S",
+					Payload:     PayloadRevisions,
+					ContentMode: ContentModeBinary,
+				},
+			},
+		},
+		"heartbeat of the load balancers": {
+			ctx: config.ToContext(context.Background(), &config.Config{
+				Feed: &config.Feed{Infrastructure: config.InfrastructureAWS},
+			}),
+			initial: CouchDbSource{
+				Spec: CouchDbSourceSpec{Feed: FeedContinuous},
+			},
+			expected: CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Feed:        FeedContinuous,
+					Heartbeat:   "PT30S",
+					Payload:     PayloadRevisions,
+					ContentMode: ContentModeBinary,
+				},
+			},
+		},
+		"heartbeat of the installation behind load balancers": {
+			ctx: WithDefaultHeartbeat(config.ToContext(context.Background(), &config.Config{
+				Feed: &config.Feed{Infrastructure: config.InfrastructureAWS},
+			}), "PT20S"),
+			initial: CouchDbSource{
+				Spec: CouchDbSourceSpec{Feed: FeedContinuous},
+			},
+			expected: CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Feed:        FeedContinuous,
+					Heartbeat:   "PT20S",
+					Payload:     PayloadRevisions,
+					ContentMode: ContentModeBinary,
+				},
+			},
+		},
+		"continuous feed with a timeout": {
+			initial: CouchDbSource{
+				Spec: CouchDbSourceSpec{Timeout: "PT30S"},
+			},
+			expected: CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Feed:        FeedContinuous,
+					Timeout:     "PT30S",
+					Payload:     PayloadRevisions,
+					ContentMode: ContentModeBinary,
+				},
+			},
+		},
+		"normal feed": {
+			initial: CouchDbSource{
+				Spec: CouchDbSourceSpec{Feed: FeedNormal, Payload: PayloadDiff, ContentMode: ContentModeBatch},
+			},
+			expected: CouchDbSource{
+				Spec: CouchDbSourceSpec{Feed: FeedNormal, Payload: PayloadDiff, ContentMode: ContentModeBatch},
+			},
+		},
+		"switch to the normal feed": {
+			ctx: apis.WithinUpdate(context.Background(), &CouchDbSource{
+				Spec: CouchDbSourceSpec{Feed: FeedContinuous, Heartbeat: "PT6S"},
+			}),
+			initial: CouchDbSource{
+				Spec: CouchDbSourceSpec{Feed: FeedNormal, Heartbeat: "PT6S"},
+			},
+			expected: CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Feed:        FeedNormal,
+					Payload:     PayloadRevisions,
+					ContentMode: ContentModeBinary,
+				},
+			},
+		},
+		"timeout set on the continuous feed": {
+			ctx: apis.WithinUpdate(context.Background(), &CouchDbSource{
+				Spec: CouchDbSourceSpec{Feed: FeedContinuous, Heartbeat: "PT6S"},
+			}),
+			initial: CouchDbSource{
+				Spec: CouchDbSourceSpec{Feed: FeedContinuous, Heartbeat: "PT6S", Timeout: "PT30S"},
+			},
+			expected: CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Feed:        FeedContinuous,
+					Timeout:     "PT30S",
+					Payload:     PayloadRevisions,
+					ContentMode: ContentModeBinary,
+				},
+			},
+		},
+		"timeout set along a heartbeat of the user": {
+			ctx: apis.WithinUpdate(context.Background(), &CouchDbSource{
+				Spec: CouchDbSourceSpec{Feed: FeedContinuous, Heartbeat: "PT20S"},
+			}),
+			initial: CouchDbSource{
+				Spec: CouchDbSourceSpec{Feed: FeedContinuous, Heartbeat: "PT20S", Timeout: "PT30S"},
+			},
+			expected: CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Feed:        FeedContinuous,
+					Heartbeat:   "PT20S",
+					Timeout:     "PT30S",
+					Payload:     PayloadRevisions,
+					ContentMode: ContentModeBinary,
+				},
+			},
+		},
+		"JobSink": {
+			initial: CouchDbSource{
+				Spec: CouchDbSourceSpec{Feed: FeedNormal, Sink: &jobSink},
+			},
+			expected: CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Feed:        FeedNormal,
+					Sink:        &jobSink,
+					Payload:     PayloadRevisions,
+					ContentMode: ContentModeBinary,
+					Batch:       &BatchSpec{},
+				},
+			},
+		},
+		"JobSink with groups": {
+			initial: CouchDbSource{
+				Spec: CouchDbSourceSpec{Feed: FeedNormal, Sink: &jobSink, Grouping: &GroupingSpec{Batch: true}},
+			},
+			expected: CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Feed:        FeedNormal,
+					Sink:        &jobSink,
+					Payload:     PayloadRevisions,
+					ContentMode: ContentModeBinary,
+					Grouping:    &GroupingSpec{Batch: true},
+				},
+			},
+		},
+		"retries": {
+			initial: CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Feed:     FeedNormal,
+					Delivery: &eventingduckv1.DeliverySpec{Retry: ptr.Int32(3)},
+				},
+			},
+			expected: CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Feed:        FeedNormal,
+					Payload:     PayloadRevisions,
+					ContentMode: ContentModeBinary,
+					Delivery: &eventingduckv1.DeliverySpec{
+						Retry:         ptr.Int32(3),
+						BackoffPolicy: &exponential,
+						BackoffDelay:  ptr.String("PT0.2S"),
+					},
+				},
+			},
+		},
+		"retries with a backoff": {
+			initial: CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Feed: FeedNormal,
+					Delivery: &eventingduckv1.DeliverySpec{
+						Retry:         ptr.Int32(3),
+						BackoffPolicy: &linear,
+						BackoffDelay:  ptr.String("PT1S"),
+					},
+				},
+			},
+			expected: CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Feed:        FeedNormal,
+					Payload:     PayloadRevisions,
+					ContentMode: ContentModeBinary,
+					Delivery: &eventingduckv1.DeliverySpec{
+						Retry:         ptr.Int32(3),
+						BackoffPolicy: &linear,
+						BackoffDelay:  ptr.String("PT1S"),
+					},
 				},
 			},
 		},
 	}
 	for n, tc := range testCases {
 		t.Run(n, func(t *testing.T) {
-			tc.initial.SetDefaults(context.TODO())
+			ctx := tc.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			tc.initial.SetDefaults(ctx)
 			if diff := cmp.Diff(tc.expected, tc.initial); diff != "" {
 				t.Fatalf("Unexpected defaults (-want, +got): %s", diff)
 			}
 		})
 	}
 }
+
+func TestHeartbeatWithin(t *testing.T) {
+	for idle, want := range map[time.Duration]time.Duration{
+		time.Second:      MinFeedTiming,
+		45 * time.Second: 22 * time.Second,
+		time.Minute:      30 * time.Second,
+		10 * time.Minute: MaxFeedTiming,
+	} {
+		if got := heartbeatWithin(idle); got != want {
+			t.Errorf("heartbeatWithin(%v) = %v, want %v", idle, got, want)
+		}
+	}
+}
+
+func TestDefaultsValidate(t *testing.T) {
+	// The defaulted durations must parse like the ones of the users.
+	for _, d := range []string{isoDuration(DefaultHeartbeat), isoDuration(DefaultBackoffDelay)} {
+		p, err := period.Parse(d)
+		if err != nil {
+			t.Errorf("period.Parse(%q) = %v", d, err)
+		}
+		if got := p.DurationApprox(); got != DefaultHeartbeat && got != DefaultBackoffDelay {
+			t.Errorf("period.Parse(%q) = %v, want a default duration", d, got)
+		}
+	}
+}
@@ -17,8 +17,14 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"fmt"
+	"strings"
+
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	"knative.dev/eventing/pkg/apis/duck"
+	sourcesv1 "knative.dev/eventing/pkg/apis/sources/v1"
 	"knative.dev/pkg/apis"
 )
 
@@ -31,10 +37,36 @@ const (
 
 	// CouchDbConditionDeployed has status True when the CouchDbSource has had it's deployment created.
 	CouchDbConditionDeployed apis.ConditionType = "Deployed"
+
+	// CouchDbConditionConnectionEstablished has status True when the
+	// controller reached the database of the CouchDbSource with its
+	// credentials, before deploying the receive adapter.
+	CouchDbConditionConnectionEstablished apis.ConditionType = "ConnectionEstablished"
+
+	// CouchDbConditionCompleted has status True when a CouchDbSource bounded by a window has
+	// reported all the changes of the window. It does not contribute to readiness.
+	CouchDbConditionCompleted apis.ConditionType = "Completed"
+
+	// CouchDbConditionWebhookHealthy has status True when the admission
+	// webhooks admit sources in a timely manner, as probed by the controller.
+	// It does not contribute to readiness.
+	CouchDbConditionWebhookHealthy apis.ConditionType = "WebhookHealthy"
+
+	// CouchDbConditionChangesApplied has status True when the receive adapter
+	// matches the spec, and False while, with spec.applyMode manual, the plan
+	// of the changes waits for approval. It does not contribute to readiness.
+	CouchDbConditionChangesApplied apis.ConditionType = "ChangesApplied"
+
+	// CouchDbConditionJobsSucceeded has status True when none of the Jobs the
+	// JobSink the events are sent to started for the events of the source
+	// failed, and Unknown while some are running. It does not contribute to
+	// readiness.
+	CouchDbConditionJobsSucceeded apis.ConditionType = "JobsSucceeded"
 )
 
 var CouchDbCondSet = apis.NewLivingConditionSet(
 	CouchDbConditionSinkProvided,
+	CouchDbConditionConnectionEstablished,
 	CouchDbConditionDeployed,
 )
 
@@ -63,11 +95,47 @@ func (s *CouchDbSourceStatus) MarkSink(uri *apis.URL) {
 	}
 }
 
+// MarkSinkAudience records the OIDC audience of the sink and the service
+// account whose tokens the events are sent with, or clears them when the
+// sink has no audience.
+func (s *CouchDbSourceStatus) MarkSinkAudience(audience, serviceAccountName string) {
+	if audience == "" {
+		s.SinkAudience = nil
+		s.Auth = nil
+		return
+	}
+	s.SinkAudience = &audience
+	s.Auth = &AuthStatus{ServiceAccountName: &serviceAccountName}
+}
+
+// MarkSinkCACerts records the certificates the sink is verified with, or
+// clears them when it has none.
+func (s *CouchDbSourceStatus) MarkSinkCACerts(caCerts string) {
+	if caCerts == "" {
+		s.SinkCACerts = nil
+		return
+	}
+	s.SinkCACerts = &caCerts
+}
+
 // MarkNoSink sets the condition that the source does not have a sink configured.
 func (s *CouchDbSourceStatus) MarkNoSink(reason, messageFormat string, messageA ...interface{}) {
 	CouchDbCondSet.Manage(s).MarkFalse(CouchDbConditionSinkProvided, reason, messageFormat, messageA...)
 }
 
+// MarkConnectionEstablished sets the condition that the database of the
+// source can be reached with its credentials.
+func (s *CouchDbSourceStatus) MarkConnectionEstablished() {
+	CouchDbCondSet.Manage(s).MarkTrue(CouchDbConditionConnectionEstablished)
+}
+
+// MarkConnectionNotChecked sets the condition when the controller does not
+// check the connection to the database, which it assumes.
+func (s *CouchDbSourceStatus) MarkConnectionNotChecked() {
+	CouchDbCondSet.Manage(s).MarkTrueWithReason(CouchDbConditionConnectionEstablished, "NotChecked",
+		"The controller does not check the connection to CouchDB.")
+}
+
 // PropagateDeploymentAvailability uses the availability of the provided Deployment to determine if
 // CouchDbConditionDeployed should be marked as true or false.
 func (s *CouchDbSourceStatus) PropagateDeploymentAvailability(d *appsv1.Deployment) {
@@ -80,6 +148,184 @@ func (s *CouchDbSourceStatus) PropagateDeploymentAvailability(d *appsv1.Deployme
 	}
 }
 
+// PropagateStatefulSetAvailability uses the readiness of the replicas of the
+// StatefulSet tailing the databases of a pattern to determine the
+// CouchDbConditionDeployed condition: every shard must be served.
+func (s *CouchDbSourceStatus) PropagateStatefulSetAvailability(ss *appsv1.StatefulSet) {
+	replicas := int32(1)
+	if ss.Spec.Replicas != nil {
+		replicas = *ss.Spec.Replicas
+	}
+	if ss.Status.ObservedGeneration >= ss.Generation && ss.Status.ReadyReplicas >= replicas {
+		CouchDbCondSet.Manage(s).MarkTrue(CouchDbConditionDeployed)
+	} else {
+		CouchDbCondSet.Manage(s).MarkFalse(CouchDbConditionDeployed, "StatefulSetUnavailable",
+			"The StatefulSet '%s' has %d ready replicas out of %d.", ss.Name, ss.Status.ReadyReplicas, replicas)
+	}
+}
+
+// PropagateContainerSourceStatus uses the readiness of the ContainerSource
+// running the receive adapter to determine the CouchDbConditionDeployed
+// condition.
+func (s *CouchDbSourceStatus) PropagateContainerSourceStatus(cs *sourcesv1.ContainerSource) {
+	c := cs.Status.GetCondition(apis.ConditionReady)
+	switch {
+	case c == nil:
+		CouchDbCondSet.Manage(s).MarkUnknown(CouchDbConditionDeployed, "ContainerSourceNotReady", "The ContainerSource '%s' has not reported its readiness.", cs.Name)
+	case c.IsTrue():
+		CouchDbCondSet.Manage(s).MarkTrue(CouchDbConditionDeployed)
+	default:
+		CouchDbCondSet.Manage(s).MarkFalse(CouchDbConditionDeployed, "ContainerSourceNotReady", "The ContainerSource '%s' is not ready: %s", cs.Name, c.Message)
+	}
+}
+
+// MarkNoDeployment sets the condition that the receive adapter could not be deployed.
+func (s *CouchDbSourceStatus) MarkNoDeployment(reason, messageFormat string, messageA ...interface{}) {
+	CouchDbCondSet.Manage(s).MarkFalse(CouchDbConditionDeployed, reason, messageFormat, messageA...)
+}
+
+// PropagateJobStatus uses the status of the receive adapter Job of a source bounded by a
+// window to determine the CouchDbConditionDeployed and CouchDbConditionCompleted conditions.
+func (s *CouchDbSourceStatus) PropagateJobStatus(j *batchv1.Job) {
+	for _, c := range j.Status.Conditions {
+		if c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue {
+			CouchDbCondSet.Manage(s).MarkFalse(CouchDbConditionDeployed, "JobFailed", "The Job '%s' failed: %s", j.Name, c.Message)
+			CouchDbCondSet.Manage(s).MarkFalse(CouchDbConditionCompleted, "JobFailed", "The Job '%s' failed: %s", j.Name, c.Message)
+			return
+		}
+	}
+
+	CouchDbCondSet.Manage(s).MarkTrue(CouchDbConditionDeployed)
+	if j.Status.Succeeded > 0 {
+		CouchDbCondSet.Manage(s).MarkTrue(CouchDbConditionCompleted)
+	} else {
+		CouchDbCondSet.Manage(s).MarkUnknown(CouchDbConditionCompleted, "InProgress", "The changes of the window are being reported.")
+	}
+}
+
+// PropagateJobSinkJobs counts the Jobs the JobSink started for the events of
+// the source to determine the CouchDbConditionJobsSucceeded condition.
+func (s *CouchDbSourceStatus) PropagateJobSinkJobs(jobSink string, jobs []batchv1.Job) {
+	counts := &JobsStatus{}
+	for i := range jobs {
+		switch {
+		case jobFinished(&jobs[i], batchv1.JobFailed):
+			counts.Failed++
+		case jobFinished(&jobs[i], batchv1.JobComplete):
+			counts.Succeeded++
+		default:
+			counts.Active++
+		}
+	}
+	s.Jobs = counts
+
+	switch {
+	case counts.Failed > 0:
+		CouchDbCondSet.Manage(s).MarkFalse(CouchDbConditionJobsSucceeded, "JobsFailed",
+			"%d of the Jobs the JobSink '%s' started for the source failed.", counts.Failed, jobSink)
+	case counts.Active > 0:
+		CouchDbCondSet.Manage(s).MarkUnknown(CouchDbConditionJobsSucceeded, "JobsActive",
+			"%d of the Jobs the JobSink '%s' started for the source are running.", counts.Active, jobSink)
+	default:
+		CouchDbCondSet.Manage(s).MarkTrue(CouchDbConditionJobsSucceeded)
+	}
+}
+
+// MarkNoJobSink clears the Jobs and their condition when the events are not
+// sent to a JobSink.
+func (s *CouchDbSourceStatus) MarkNoJobSink() {
+	s.Jobs = nil
+	_ = CouchDbCondSet.Manage(s).ClearCondition(CouchDbConditionJobsSucceeded)
+}
+
+// jobFinished returns whether the Job has the finished condition of the type.
+func jobFinished(j *batchv1.Job, t batchv1.JobConditionType) bool {
+	for _, c := range j.Status.Conditions {
+		if c.Type == t && c.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// MarkWebhookHealthy sets the condition that the admission webhooks are healthy.
+func (s *CouchDbSourceStatus) MarkWebhookHealthy() {
+	CouchDbCondSet.Manage(s).MarkTrue(CouchDbConditionWebhookHealthy)
+}
+
+// MarkWebhookUnhealthy sets the condition that the admission webhooks are slow
+// or failing, e.g. WebhookSlow or WebhookUnavailable.
+func (s *CouchDbSourceStatus) MarkWebhookUnhealthy(reason, message string) {
+	CouchDbCondSet.Manage(s).MarkFalse(CouchDbConditionWebhookHealthy, reason, "%s", message)
+}
+
+// MarkChangesApplied sets the condition that the receive adapter matches the
+// spec, and clears the plan.
+func (s *CouchDbSourceStatus) MarkChangesApplied() {
+	delete(s.Annotations, PlanAnnotationKey)
+	delete(s.Annotations, PlanIDAnnotationKey)
+	if len(s.Annotations) == 0 {
+		s.Annotations = nil
+	}
+	CouchDbCondSet.Manage(s).MarkTrue(CouchDbConditionChangesApplied)
+}
+
+// MarkPlanPending writes the plan of the changes waiting for approval to the
+// status annotations, and sets the condition that they are not applied.
+func (s *CouchDbSourceStatus) MarkPlanPending(id, plan string) {
+	if s.Annotations == nil {
+		s.Annotations = make(map[string]string, 2)
+	}
+	s.Annotations[PlanAnnotationKey] = plan
+	s.Annotations[PlanIDAnnotationKey] = id
+	CouchDbCondSet.Manage(s).MarkFalse(CouchDbConditionChangesApplied, "PlanPending",
+		"The changes wait for the approval of plan %s with the %s annotation.", id, ApprovedPlanAnnotationKey)
+}
+
+// ConditionFailure is the failure to reconcile a sub-resource of the source,
+// reflected in one of its conditions with a stable reason.
+// +k8s:deepcopy-gen=false
+type ConditionFailure struct {
+	Condition apis.ConditionType
+	Reason    string
+	Message   string
+}
+
+// MarkFailures reflects the failures in their conditions, each with the reason
+// of its first failure and the messages of all of them. When several
+// conditions fail, the Ready condition lists them all under the
+// MultipleFailures reason, rather than only the last one marked.
+func (s *CouchDbSourceStatus) MarkFailures(failures []ConditionFailure) {
+	var conditions []apis.ConditionType
+	byCondition := make(map[apis.ConditionType][]ConditionFailure)
+	for _, f := range failures {
+		if _, ok := byCondition[f.Condition]; !ok {
+			conditions = append(conditions, f.Condition)
+		}
+		byCondition[f.Condition] = append(byCondition[f.Condition], f)
+	}
+
+	summary := make([]string, 0, len(conditions))
+	for _, c := range conditions {
+		fs := byCondition[c]
+		messages := make([]string, 0, len(fs))
+		for _, f := range fs {
+			messages = append(messages, f.Message)
+		}
+		message := strings.Join(messages, "; ")
+		CouchDbCondSet.Manage(s).MarkFalse(c, fs[0].Reason, "%s", message)
+		summary = append(summary, fmt.Sprintf("%s: %s: %s", c, fs[0].Reason, message))
+	}
+	if len(conditions) > 1 {
+		CouchDbCondSet.Manage(s).MarkFalse(CouchDbConditionReady, "MultipleFailures", "%s", strings.Join(summary, "; "))
+	}
+}
+
+// IsCompleted returns true if the source reported all the changes of its window.
+func (s *CouchDbSourceStatus) IsCompleted() bool {
+	return s.GetCondition(CouchDbConditionCompleted).IsTrue()
+}
+
 // IsReady returns true if the resource is ready overall.
 func (s *CouchDbSourceStatus) IsReady() bool {
 	return CouchDbCondSet.Manage(s).IsHappy()
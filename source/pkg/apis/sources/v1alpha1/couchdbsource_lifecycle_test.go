@@ -22,7 +22,10 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	sourcesv1 "knative.dev/eventing/pkg/apis/sources/v1"
 	"knative.dev/pkg/apis"
 	duckv1 "knative.dev/pkg/apis/duck/v1"
 )
@@ -98,13 +101,220 @@ func TestCouchDbGetCondition(t *testing.T) {
 			Type:   CouchDbConditionReady,
 			Status: corev1.ConditionUnknown,
 		},
+	}, {
+		name: "mark no deployment",
+		cs: func() *CouchDbSourceStatus {
+			s := &CouchDbSourceStatus{}
+			s.InitializeConditions()
+			s.MarkSink(apis.HTTP("example"))
+			s.MarkNoDeployment("AdapterImageNotAllowed", "")
+			return s
+		}(),
+		condQuery: CouchDbConditionReady,
+		want: &apis.Condition{
+			Type:   CouchDbConditionReady,
+			Status: corev1.ConditionFalse,
+			Reason: "AdapterImageNotAllowed",
+		},
+	}, {
+		name: "mark one failure",
+		cs: func() *CouchDbSourceStatus {
+			s := &CouchDbSourceStatus{}
+			s.InitializeConditions()
+			s.MarkSink(apis.HTTP("example"))
+			s.MarkFailures([]ConditionFailure{{
+				Condition: CouchDbConditionDeployed,
+				Reason:    "DevInstanceFailed",
+				Message:   "quota exceeded",
+			}, {
+				Condition: CouchDbConditionDeployed,
+				Reason:    "AdapterImageNotAllowed",
+				Message:   "not in the allowlist",
+			}})
+			return s
+		}(),
+		condQuery: CouchDbConditionReady,
+		want: &apis.Condition{
+			Type:    CouchDbConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  "DevInstanceFailed",
+			Message: "quota exceeded; not in the allowlist",
+		},
+	}, {
+		name: "mark multiple failures",
+		cs: func() *CouchDbSourceStatus {
+			s := &CouchDbSourceStatus{}
+			s.InitializeConditions()
+			s.MarkFailures([]ConditionFailure{{
+				Condition: CouchDbConditionSinkProvided,
+				Reason:    "NotFound",
+				Message:   "no sink",
+			}, {
+				Condition: CouchDbConditionDeployed,
+				Reason:    "AdapterImageNotAllowed",
+				Message:   "not in the allowlist",
+			}})
+			return s
+		}(),
+		condQuery: CouchDbConditionReady,
+		want: &apis.Condition{
+			Type:    CouchDbConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  "MultipleFailures",
+			Message: "SinkProvided: NotFound: no sink; Deployed: AdapterImageNotAllowed: not in the allowlist",
+		},
 	}, {
 		name: "mark sink and deployed",
 		cs: func() *CouchDbSourceStatus {
 			s := &CouchDbSourceStatus{}
 			s.InitializeConditions()
 			s.MarkSink(apis.HTTP("example"))
+			s.MarkConnectionEstablished()
+			s.PropagateDeploymentAvailability(availableDeployment)
+			return s
+		}(),
+		condQuery: CouchDbConditionReady,
+		want: &apis.Condition{
+			Type:   CouchDbConditionReady,
+			Status: corev1.ConditionTrue,
+		},
+	}, {
+		name: "connection not checked",
+		cs: func() *CouchDbSourceStatus {
+			s := &CouchDbSourceStatus{}
+			s.InitializeConditions()
+			s.MarkSink(apis.HTTP("example"))
+			s.MarkConnectionNotChecked()
+			s.PropagateDeploymentAvailability(availableDeployment)
+			return s
+		}(),
+		condQuery: CouchDbConditionReady,
+		want: &apis.Condition{
+			Type:   CouchDbConditionReady,
+			Status: corev1.ConditionTrue,
+		},
+	}, {
+		name: "connection failed",
+		cs: func() *CouchDbSourceStatus {
+			s := &CouchDbSourceStatus{}
+			s.InitializeConditions()
+			s.MarkSink(apis.HTTP("example"))
+			s.MarkFailures([]ConditionFailure{{
+				Condition: CouchDbConditionConnectionEstablished,
+				Reason:    "Unauthorized",
+				Message:   "CouchDB rejected the credentials: 401 Unauthorized",
+			}})
+			return s
+		}(),
+		condQuery: CouchDbConditionReady,
+		want: &apis.Condition{
+			Type:    CouchDbConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  "Unauthorized",
+			Message: "CouchDB rejected the credentials: 401 Unauthorized",
+		},
+	}, {
+		name: "running job is ready but not completed",
+		cs: func() *CouchDbSourceStatus {
+			s := &CouchDbSourceStatus{}
+			s.InitializeConditions()
+			s.MarkSink(apis.HTTP("example"))
+			s.MarkConnectionEstablished()
+			s.PropagateJobStatus(&batchv1.Job{})
+			return s
+		}(),
+		condQuery: CouchDbConditionReady,
+		want: &apis.Condition{
+			Type:   CouchDbConditionReady,
+			Status: corev1.ConditionTrue,
+		},
+	}, {
+		name: "succeeded job is completed",
+		cs: func() *CouchDbSourceStatus {
+			s := &CouchDbSourceStatus{}
+			s.InitializeConditions()
+			s.MarkSink(apis.HTTP("example"))
+			s.PropagateJobStatus(&batchv1.Job{Status: batchv1.JobStatus{Succeeded: 1}})
+			return s
+		}(),
+		condQuery: CouchDbConditionCompleted,
+		want: &apis.Condition{
+			Type:   CouchDbConditionCompleted,
+			Status: corev1.ConditionTrue,
+		},
+	}, {
+		name: "failed job",
+		cs: func() *CouchDbSourceStatus {
+			s := &CouchDbSourceStatus{}
+			s.InitializeConditions()
+			s.MarkSink(apis.HTTP("example"))
+			s.PropagateJobStatus(&batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Name: "replay"},
+				Status: batchv1.JobStatus{
+					Conditions: []batchv1.JobCondition{{
+						Type:    batchv1.JobFailed,
+						Status:  corev1.ConditionTrue,
+						Message: "BackoffLimitExceeded",
+					}},
+				},
+			})
+			return s
+		}(),
+		condQuery: CouchDbConditionReady,
+		want: &apis.Condition{
+			Type:    CouchDbConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  "JobFailed",
+			Message: "The Job 'replay' failed: BackoffLimitExceeded",
+		},
+	}, {
+		name: "ready container source",
+		cs: func() *CouchDbSourceStatus {
+			s := &CouchDbSourceStatus{}
+			s.InitializeConditions()
+			s.MarkSink(apis.HTTP("example"))
+			s.MarkConnectionEstablished()
+			cs := &sourcesv1.ContainerSource{}
+			cs.Status.SetConditions(apis.Conditions{{Type: apis.ConditionReady, Status: corev1.ConditionTrue}})
+			s.PropagateContainerSourceStatus(cs)
+			return s
+		}(),
+		condQuery: CouchDbConditionReady,
+		want: &apis.Condition{
+			Type:   CouchDbConditionReady,
+			Status: corev1.ConditionTrue,
+		},
+	}, {
+		name: "container source not ready",
+		cs: func() *CouchDbSourceStatus {
+			s := &CouchDbSourceStatus{}
+			s.InitializeConditions()
+			s.MarkSink(apis.HTTP("example"))
+			cs := &sourcesv1.ContainerSource{ObjectMeta: metav1.ObjectMeta{Name: "adapter"}}
+			cs.Status.SetConditions(apis.Conditions{{
+				Type:    apis.ConditionReady,
+				Status:  corev1.ConditionFalse,
+				Message: "the SinkBinding is not ready",
+			}})
+			s.PropagateContainerSourceStatus(cs)
+			return s
+		}(),
+		condQuery: CouchDbConditionReady,
+		want: &apis.Condition{
+			Type:    CouchDbConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  "ContainerSourceNotReady",
+			Message: "The ContainerSource 'adapter' is not ready: the SinkBinding is not ready",
+		},
+	}, {
+		name: "unhealthy webhook keeps the source ready",
+		cs: func() *CouchDbSourceStatus {
+			s := &CouchDbSourceStatus{}
+			s.InitializeConditions()
+			s.MarkSink(apis.HTTP("example"))
+			s.MarkConnectionEstablished()
 			s.PropagateDeploymentAvailability(availableDeployment)
+			s.MarkWebhookUnhealthy("WebhookSlow", "The API server took 7s to admit a dry run CouchDbSource")
 			return s
 		}(),
 		condQuery: CouchDbConditionReady,
@@ -112,6 +322,50 @@ func TestCouchDbGetCondition(t *testing.T) {
 			Type:   CouchDbConditionReady,
 			Status: corev1.ConditionTrue,
 		},
+	}, {
+		name: "unhealthy webhook",
+		cs: func() *CouchDbSourceStatus {
+			s := &CouchDbSourceStatus{}
+			s.InitializeConditions()
+			s.MarkWebhookUnhealthy("WebhookSlow", "The API server took 7s to admit a dry run CouchDbSource")
+			return s
+		}(),
+		condQuery: CouchDbConditionWebhookHealthy,
+		want: &apis.Condition{
+			Type:    CouchDbConditionWebhookHealthy,
+			Status:  corev1.ConditionFalse,
+			Reason:  "WebhookSlow",
+			Message: "The API server took 7s to admit a dry run CouchDbSource",
+		},
+	}, {
+		name: "plan pending",
+		cs: func() *CouchDbSourceStatus {
+			s := &CouchDbSourceStatus{}
+			s.InitializeConditions()
+			s.MarkPlanPending("0123456789ab", "~ replicas: 1 -> 2")
+			return s
+		}(),
+		condQuery: CouchDbConditionChangesApplied,
+		want: &apis.Condition{
+			Type:    CouchDbConditionChangesApplied,
+			Status:  corev1.ConditionFalse,
+			Reason:  "PlanPending",
+			Message: "The changes wait for the approval of plan 0123456789ab with the couchdb.sources.knative.dev/approved-plan annotation.",
+		},
+	}, {
+		name: "plan applied",
+		cs: func() *CouchDbSourceStatus {
+			s := &CouchDbSourceStatus{}
+			s.InitializeConditions()
+			s.MarkPlanPending("0123456789ab", "~ replicas: 1 -> 2")
+			s.MarkChangesApplied()
+			return s
+		}(),
+		condQuery: CouchDbConditionChangesApplied,
+		want: &apis.Condition{
+			Type:   CouchDbConditionChangesApplied,
+			Status: corev1.ConditionTrue,
+		},
 	}}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -125,6 +379,115 @@ func TestCouchDbGetCondition(t *testing.T) {
 	}
 }
 
+func TestCouchDbPlanAnnotations(t *testing.T) {
+	s := &CouchDbSourceStatus{}
+	s.InitializeConditions()
+	s.MarkPlanPending("0123456789ab", "~ replicas: 1 -> 2")
+	want := map[string]string{
+		PlanAnnotationKey:   "~ replicas: 1 -> 2",
+		PlanIDAnnotationKey: "0123456789ab",
+	}
+	if diff := cmp.Diff(want, s.Annotations); diff != "" {
+		t.Errorf("unexpected status annotations (-want, +got) = %v", diff)
+	}
+	if s.GetCondition(CouchDbConditionReady).IsFalse() {
+		t.Error("a pending plan made the source not ready")
+	}
+
+	s.MarkChangesApplied()
+	if s.Annotations != nil {
+		t.Errorf("status annotations = %v, want the plan cleared", s.Annotations)
+	}
+}
+
+func TestCouchDbPropagateStatefulSetAvailability(t *testing.T) {
+	replicas := int32(3)
+	for n, tc := range map[string]struct {
+		status appsv1.StatefulSetStatus
+		want   corev1.ConditionStatus
+	}{
+		"all shards ready": {
+			status: appsv1.StatefulSetStatus{ObservedGeneration: 2, ReadyReplicas: 3},
+			want:   corev1.ConditionTrue,
+		},
+		"a shard not ready": {
+			status: appsv1.StatefulSetStatus{ObservedGeneration: 2, ReadyReplicas: 2},
+			want:   corev1.ConditionFalse,
+		},
+		"update not observed": {
+			status: appsv1.StatefulSetStatus{ObservedGeneration: 1, ReadyReplicas: 3},
+			want:   corev1.ConditionFalse,
+		},
+	} {
+		t.Run(n, func(t *testing.T) {
+			s := &CouchDbSourceStatus{}
+			s.InitializeConditions()
+			s.PropagateStatefulSetAvailability(&appsv1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "adapter", Generation: 2},
+				Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+				Status:     tc.status,
+			})
+			if got := s.GetCondition(CouchDbConditionDeployed).Status; got != tc.want {
+				t.Errorf("Deployed = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCouchDbPropagateJobSinkJobs(t *testing.T) {
+	job := func(t batchv1.JobConditionType) batchv1.Job {
+		j := batchv1.Job{}
+		if t != "" {
+			j.Status.Conditions = []batchv1.JobCondition{{Type: t, Status: corev1.ConditionTrue}}
+		}
+		return j
+	}
+	for n, tc := range map[string]struct {
+		jobs       []batchv1.Job
+		wantJobs   JobsStatus
+		wantStatus corev1.ConditionStatus
+	}{
+		"no jobs": {
+			wantStatus: corev1.ConditionTrue,
+		},
+		"all succeeded": {
+			jobs:       []batchv1.Job{job(batchv1.JobComplete), job(batchv1.JobComplete)},
+			wantJobs:   JobsStatus{Succeeded: 2},
+			wantStatus: corev1.ConditionTrue,
+		},
+		"running": {
+			jobs:       []batchv1.Job{job(batchv1.JobComplete), job("")},
+			wantJobs:   JobsStatus{Active: 1, Succeeded: 1},
+			wantStatus: corev1.ConditionUnknown,
+		},
+		"failed": {
+			jobs:       []batchv1.Job{job(batchv1.JobFailed), job(""), job(batchv1.JobComplete)},
+			wantJobs:   JobsStatus{Active: 1, Succeeded: 1, Failed: 1},
+			wantStatus: corev1.ConditionFalse,
+		},
+	} {
+		t.Run(n, func(t *testing.T) {
+			s := &CouchDbSourceStatus{}
+			s.InitializeConditions()
+			s.PropagateJobSinkJobs("batches", tc.jobs)
+			if diff := cmp.Diff(&tc.wantJobs, s.Jobs); diff != "" {
+				t.Errorf("Unexpected jobs (-want, +got) = %v", diff)
+			}
+			if got := s.GetCondition(CouchDbConditionJobsSucceeded).Status; got != tc.wantStatus {
+				t.Errorf("JobsSucceeded = %v, want %v", got, tc.wantStatus)
+			}
+			if !s.IsReady() && s.GetCondition(CouchDbConditionReady).Status != corev1.ConditionUnknown {
+				t.Errorf("the Jobs changed the readiness: %v", s.GetCondition(CouchDbConditionReady))
+			}
+
+			s.MarkNoJobSink()
+			if s.Jobs != nil || s.GetCondition(CouchDbConditionJobsSucceeded) != nil {
+				t.Errorf("MarkNoJobSink() kept %v and %v", s.Jobs, s.GetCondition(CouchDbConditionJobsSucceeded))
+			}
+		})
+	}
+}
+
 func TestCouchDbInitializeConditions(t *testing.T) {
 	tests := []struct {
 		name string
@@ -137,6 +500,9 @@ func TestCouchDbInitializeConditions(t *testing.T) {
 			SourceStatus: duckv1.SourceStatus{
 				Status: duckv1.Status{
 					Conditions: []apis.Condition{{
+						Type:   CouchDbConditionConnectionEstablished,
+						Status: corev1.ConditionUnknown,
+					}, {
 						Type:   CouchDbConditionDeployed,
 						Status: corev1.ConditionUnknown,
 					}, {
@@ -165,6 +531,9 @@ func TestCouchDbInitializeConditions(t *testing.T) {
 			SourceStatus: duckv1.SourceStatus{
 				Status: duckv1.Status{
 					Conditions: []apis.Condition{{
+						Type:   CouchDbConditionConnectionEstablished,
+						Status: corev1.ConditionUnknown,
+					}, {
 						Type:   CouchDbConditionDeployed,
 						Status: corev1.ConditionUnknown,
 					}, {
@@ -193,6 +562,9 @@ func TestCouchDbInitializeConditions(t *testing.T) {
 			SourceStatus: duckv1.SourceStatus{
 				Status: duckv1.Status{
 					Conditions: []apis.Condition{{
+						Type:   CouchDbConditionConnectionEstablished,
+						Status: corev1.ConditionUnknown,
+					}, {
 						Type:   CouchDbConditionDeployed,
 						Status: corev1.ConditionUnknown,
 					}, {
@@ -216,6 +588,9 @@ func TestCouchDbInitializeConditions(t *testing.T) {
 			SourceStatus: duckv1.SourceStatus{
 				Status: duckv1.Status{
 					Conditions: []apis.Condition{{
+						Type:   CouchDbConditionConnectionEstablished,
+						Status: corev1.ConditionUnknown,
+					}, {
 						Type:   CouchDbConditionDeployed,
 						Status: corev1.ConditionUnknown,
 					}, {
@@ -240,6 +615,9 @@ func TestCouchDbInitializeConditions(t *testing.T) {
 			SourceStatus: duckv1.SourceStatus{
 				Status: duckv1.Status{
 					Conditions: []apis.Condition{{
+						Type:   CouchDbConditionConnectionEstablished,
+						Status: corev1.ConditionUnknown,
+					}, {
 						Type:   CouchDbConditionDeployed,
 						Status: corev1.ConditionUnknown,
 					}, {
@@ -266,3 +644,19 @@ func TestCouchDbInitializeConditions(t *testing.T) {
 		})
 	}
 }
+
+func TestCouchDbSourceMarkSinkAudience(t *testing.T) {
+	s := &CouchDbSourceStatus{}
+	s.MarkSinkAudience("eventing.knative.dev/broker/default/default", "couchdb")
+	if s.SinkAudience == nil || *s.SinkAudience != "eventing.knative.dev/broker/default/default" {
+		t.Errorf("SinkAudience = %v, want the audience", s.SinkAudience)
+	}
+	if s.Auth == nil || s.Auth.ServiceAccountName == nil || *s.Auth.ServiceAccountName != "couchdb" {
+		t.Errorf("Auth = %+v, want the service account couchdb", s.Auth)
+	}
+
+	s.MarkSinkAudience("", "couchdb")
+	if s.SinkAudience != nil || s.Auth != nil {
+		t.Errorf("SinkAudience, Auth = %v, %v, want them cleared", s.SinkAudience, s.Auth)
+	}
+}
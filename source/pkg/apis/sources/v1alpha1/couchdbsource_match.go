@@ -0,0 +1,418 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// MatchExpression is a parsed spec.match: a predicate on the fields of the
+// changed document, e.g. doc.type == "order" && doc.total > 100.
+//
+// The expressions compare the fields of doc, reached with doc.field,
+// doc["field"] or doc.list[0], with string, number, boolean and null
+// literals using ==, !=, <, <=, > and >=, and combine the comparisons with
+// &&, ||, ! and parentheses. Missing fields are null, and comparisons of
+// values of different types are false.
+// +k8s:deepcopy-gen=false
+type MatchExpression struct {
+	root matchNode
+}
+
+// ParseMatch parses spec.match. An empty text yields nil, which matches every
+// document.
+func ParseMatch(text string) (*MatchExpression, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+	tokens, err := lexMatch(text)
+	if err != nil {
+		return nil, err
+	}
+	p := &matchParser{tokens: tokens}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s at offset %d", t, t.pos)
+	}
+	return &MatchExpression{root: root}, nil
+}
+
+// Matches returns whether the document satisfies the expression.
+func (m *MatchExpression) Matches(doc map[string]interface{}) bool {
+	if m == nil {
+		return true
+	}
+	return m.root.eval(doc) == true
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOp
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	pos   int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+// matchOperators are the operators and punctuation of the expressions, the
+// longer first.
+var matchOperators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]", "."}
+
+func lexMatch(text string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(text); {
+		c := rune(text[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(text) && text[end] != text[i] {
+				if text[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(text) {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			quoted := text[i : end+1]
+			if c == '\'' {
+				inner := strings.ReplaceAll(quoted[1:len(quoted)-1], `\'`, `'`)
+				quoted = `"` + strings.ReplaceAll(inner, `"`, `\"`) + `"`
+			}
+			s, err := strconv.Unquote(quoted)
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at offset %d: %v", i, err)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text[i : end+1], value: s, pos: i})
+			i = end + 1
+		case c == '-' || unicode.IsDigit(c):
+			end := i + 1
+			for end < len(text) && (unicode.IsDigit(rune(text[end])) || strings.ContainsRune(".eE+-", rune(text[end]))) {
+				end++
+			}
+			f, err := strconv.ParseFloat(text[i:end], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at offset %d", text[i:end], i)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: text[i:end], value: f, pos: i})
+			i = end
+		case c == '_' || unicode.IsLetter(c):
+			end := i + 1
+			for end < len(text) && (text[end] == '_' || unicode.IsLetter(rune(text[end])) || unicode.IsDigit(rune(text[end]))) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: text[i:end], pos: i})
+			i = end
+		default:
+			op := ""
+			for _, o := range matchOperators {
+				if strings.HasPrefix(text[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at offset %d", c, i)
+			}
+			tokens = append(tokens, token{kind: tokenOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(text)}), nil
+}
+
+type matchParser struct {
+	tokens []token
+	next   int
+}
+
+func (p *matchParser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *matchParser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokenOp && t.text == op {
+		p.next++
+		return true
+	}
+	return false
+}
+
+func (p *matchParser) expect(op string) error {
+	if !p.accept(op) {
+		t := p.peek()
+		return fmt.Errorf("expected %q, got %s at offset %d", op, t, t.pos)
+	}
+	return nil
+}
+
+func (p *matchParser) or() (matchNode, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *matchParser) and() (matchNode, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *matchParser) unary() (matchNode, error) {
+	if p.accept("!") {
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	}
+	return p.comparison()
+}
+
+func (p *matchParser) comparison() (matchNode, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.accept(op) {
+			right, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			return compareNode{op: op, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *matchParser) operand() (matchNode, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokenString, tokenNumber:
+		p.next++
+		return literalNode{t.value}, nil
+	case tokenIdent:
+		p.next++
+		switch t.text {
+		case "true":
+			return literalNode{true}, nil
+		case "false":
+			return literalNode{false}, nil
+		case "null":
+			return literalNode{nil}, nil
+		case "doc":
+			return p.path()
+		}
+		return nil, fmt.Errorf("unknown identifier %s at offset %d, fields are reached from doc", t, t.pos)
+	case tokenOp:
+		if p.accept("(") {
+			inner, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			return inner, p.expect(")")
+		}
+	}
+	return nil, fmt.Errorf("unexpected %s at offset %d", t, t.pos)
+}
+
+func (p *matchParser) path() (matchNode, error) {
+	var path pathNode
+	for {
+		switch {
+		case p.accept("."):
+			t := p.peek()
+			if t.kind != tokenIdent {
+				return nil, fmt.Errorf("expected a field name, got %s at offset %d", t, t.pos)
+			}
+			p.next++
+			path = append(path, t.text)
+		case p.accept("["):
+			t := p.peek()
+			switch t.kind {
+			case tokenString:
+				path = append(path, t.value)
+			case tokenNumber:
+				i := int(t.value.(float64))
+				if float64(i) != t.value.(float64) || i < 0 {
+					return nil, fmt.Errorf("invalid index %s at offset %d", t, t.pos)
+				}
+				path = append(path, i)
+			default:
+				return nil, fmt.Errorf("expected a field name or an index, got %s at offset %d", t, t.pos)
+			}
+			p.next++
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+		default:
+			return path, nil
+		}
+	}
+}
+
+// matchNode is a node of a parsed expression, evaluating to a JSON value.
+type matchNode interface {
+	eval(doc map[string]interface{}) interface{}
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n literalNode) eval(map[string]interface{}) interface{} {
+	return n.value
+}
+
+// pathNode holds the field names (string) and list indices (int) leading
+// from doc to a value.
+type pathNode []interface{}
+
+func (n pathNode) eval(doc map[string]interface{}) interface{} {
+	var v interface{} = doc
+	for _, step := range n {
+		switch s := step.(type) {
+		case string:
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			v = m[s]
+		case int:
+			l, ok := v.([]interface{})
+			if !ok || s >= len(l) {
+				return nil
+			}
+			v = l[s]
+		}
+	}
+	return v
+}
+
+type notNode struct {
+	operand matchNode
+}
+
+func (n notNode) eval(doc map[string]interface{}) interface{} {
+	return n.operand.eval(doc) != true
+}
+
+type andNode struct {
+	left, right matchNode
+}
+
+func (n andNode) eval(doc map[string]interface{}) interface{} {
+	return n.left.eval(doc) == true && n.right.eval(doc) == true
+}
+
+type orNode struct {
+	left, right matchNode
+}
+
+func (n orNode) eval(doc map[string]interface{}) interface{} {
+	return n.left.eval(doc) == true || n.right.eval(doc) == true
+}
+
+type compareNode struct {
+	op          string
+	left, right matchNode
+}
+
+func (n compareNode) eval(doc map[string]interface{}) interface{} {
+	l, r := n.left.eval(doc), n.right.eval(doc)
+	switch n.op {
+	case "==":
+		return reflect.DeepEqual(l, r)
+	case "!=":
+		return !reflect.DeepEqual(l, r)
+	}
+	var cmp int
+	switch lv := l.(type) {
+	case float64:
+		rv, ok := r.(float64)
+		if !ok {
+			return false
+		}
+		switch {
+		case lv < rv:
+			cmp = -1
+		case lv > rv:
+			cmp = 1
+		}
+	case string:
+		rv, ok := r.(string)
+		if !ok {
+			return false
+		}
+		cmp = strings.Compare(lv, rv)
+	default:
+		return false
+	}
+	switch n.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
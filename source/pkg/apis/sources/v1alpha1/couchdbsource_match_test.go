@@ -0,0 +1,168 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMatchExpression(t *testing.T) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(`{
+		"type": "order",
+		"total": 120,
+		"status": "shipped",
+		"paid": true,
+		"customer": {"tier": "gold", "name": "O'Brien"},
+		"items": [{"sku": "a"}, {"sku": "b"}],
+		"content-type": "application/json",
+		"coupon": null
+	}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	testCases := map[string]struct {
+		expr    string
+		want    bool
+		wantErr bool
+	}{
+		"empty": {
+			want: true,
+		},
+		"equal string": {
+			expr: `doc.type == "order"`,
+			want: true,
+		},
+		"single quotes": {
+			expr: `doc.customer.name == 'O\'Brien'`,
+			want: true,
+		},
+		"and": {
+			expr: `doc.type == "order" && doc.total > 100`,
+			want: true,
+		},
+		"and false": {
+			expr: `doc.type == "order" && doc.total > 200`,
+		},
+		"or": {
+			expr: `doc.type == "invoice" || doc.customer.tier == "gold"`,
+			want: true,
+		},
+		"precedence": {
+			expr: `doc.type == "invoice" && doc.total > 100 || doc.paid`,
+			want: true,
+		},
+		"parentheses": {
+			expr: `doc.type == "invoice" && (doc.total > 100 || doc.paid)`,
+		},
+		"not": {
+			expr: `!(doc.status == "cancelled")`,
+			want: true,
+		},
+		"boolean field": {
+			expr: `doc.paid`,
+			want: true,
+		},
+		"non boolean field": {
+			expr: `doc.type`,
+		},
+		"not equal": {
+			expr: `doc.status != "cancelled"`,
+			want: true,
+		},
+		"less or equal": {
+			expr: `doc.total <= 120`,
+			want: true,
+		},
+		"negative number": {
+			expr: `doc.total >= -1.5e2`,
+			want: true,
+		},
+		"string ordering": {
+			expr: `doc.status < "t"`,
+			want: true,
+		},
+		"mismatched types": {
+			expr: `doc.total > "100"`,
+		},
+		"index": {
+			expr: `doc.items[1].sku == "b"`,
+			want: true,
+		},
+		"index out of range": {
+			expr: `doc.items[2].sku == null`,
+			want: true,
+		},
+		"quoted field": {
+			expr: `doc["content-type"] == "application/json"`,
+			want: true,
+		},
+		"missing field": {
+			expr: `doc.region == null`,
+			want: true,
+		},
+		"null field": {
+			expr: `doc.coupon == null`,
+			want: true,
+		},
+		"missing parent": {
+			expr: `doc.shipping.country == "FR"`,
+		},
+		"unknown identifier": {
+			expr:    `type == "order"`,
+			wantErr: true,
+		},
+		"missing operand": {
+			expr:    `doc.type ==`,
+			wantErr: true,
+		},
+		"unbalanced parentheses": {
+			expr:    `(doc.type == "order"`,
+			wantErr: true,
+		},
+		"trailing tokens": {
+			expr:    `doc.type == "order" "invoice"`,
+			wantErr: true,
+		},
+		"unterminated string": {
+			expr:    `doc.type == "order`,
+			wantErr: true,
+		},
+		"unknown operator": {
+			expr:    `doc.total % 2`,
+			wantErr: true,
+		},
+		"invalid index": {
+			expr:    `doc.items[1.5]`,
+			wantErr: true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			m, err := ParseMatch(tc.expr)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseMatch() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := m.Matches(doc); got != tc.want {
+				t.Errorf("Matches() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SequenceNow stands for the current update sequence of the database.
+const SequenceNow = "now"
+
+// SequenceNumber returns the numeric part of an update sequence. CouchDB 1.x
+// and PouchDB-Server sequences are plain numbers while later versions prefix
+// an opaque string with the number and a dash, e.g. "42-g1AAAA...". BigCouch
+// and the early Cloudant clusters write them as a JSON array of the number
+// and the opaque string, e.g. [42,"g1AAAA..."]. The numbers of the sequences
+// of a database grow with every update, so they can be compared even though
+// the sequences themselves are opaque.
+func SequenceNumber(seq string) (int64, error) {
+	seq = strings.TrimSpace(seq)
+	if strings.HasPrefix(seq, "[") {
+		var parts []json.RawMessage
+		if err := json.Unmarshal([]byte(seq), &parts); err != nil || len(parts) == 0 {
+			return 0, fmt.Errorf("invalid update sequence %q", seq)
+		}
+		seq = string(parts[0])
+	}
+	return strconv.ParseInt(strings.SplitN(seq, "-", 2)[0], 10, 64)
+}
+
+// SequenceID is an update sequence read from JSON, which CouchDB 2.x and later
+// write as strings, CouchDB 1.x and PouchDB-Server as numbers, and BigCouch as
+// arrays. The numbers and arrays keep their JSON text, which the changes feed
+// accepts back as its since parameter.
+type SequenceID string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *SequenceID) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		*s = SequenceID(str)
+		return nil
+	}
+	if _, err := SequenceNumber(string(data)); err != nil {
+		return fmt.Errorf("invalid update sequence %s", data)
+	}
+	*s = SequenceID(strings.TrimSpace(string(data)))
+	return nil
+}
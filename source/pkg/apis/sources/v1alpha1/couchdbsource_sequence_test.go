@@ -0,0 +1,108 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSequenceNumber(t *testing.T) {
+	testCases := map[string]struct {
+		seq     string
+		want    int64
+		wantErr bool
+	}{
+		"couchdb 1.x": {
+			seq:  "42",
+			want: 42,
+		},
+		"couchdb 2.x": {
+			seq:  "42-g1AAAAFTeJzLYWBg4MhgTmHgz8tPSTV0MDQy1zMAQsMcoARTIkOS_P___7MymBMZc4EC7MaWSWmGiUboenEakaQAJJPsoaYwgE1JMjUzSTYzI0Y_QlmKBMJ-Hg",
+			want: 42,
+		},
+		"bigcouch": {
+			seq:  `[42,"g1AAAAFTeJzLYWBg4MhgTmHgz8tPSTV0MDQy1zMAQsMcoARTIkOS_P___7MymBMZc4EC7MaWSWmGiUboenEakaQAJJPsoaYwgE1JMjUzSTYzI0Y_QlmKBMJ-Hg"]`,
+			want: 42,
+		},
+		"bigcouch with spaces": {
+			seq:  ` [42, "g1AAAA"] `,
+			want: 42,
+		},
+		"empty array": {
+			seq:     "[]",
+			wantErr: true,
+		},
+		"now": {
+			seq:     "now",
+			wantErr: true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			got, err := SequenceNumber(tc.seq)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("SequenceNumber() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("SequenceNumber() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSequenceID(t *testing.T) {
+	testCases := map[string]struct {
+		json    string
+		want    SequenceID
+		wantErr bool
+	}{
+		"string": {
+			json: `{"sequence": "42-g1AAAA"}`,
+			want: "42-g1AAAA",
+		},
+		"number": {
+			json: `{"sequence": 42}`,
+			want: "42",
+		},
+		"array": {
+			json: `{"sequence": [42, "g1AAAA"]}`,
+			want: `[42, "g1AAAA"]`,
+		},
+		"null": {
+			json: `{"sequence": null}`,
+		},
+		"object": {
+			json:    `{"sequence": {"seq": 42}}`,
+			wantErr: true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			var got struct {
+				Sequence SequenceID `json:"sequence"`
+			}
+			err := json.Unmarshal([]byte(tc.json), &got)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got.Sequence != tc.want {
+				t.Errorf("Sequence = %q, want %q", got.Sequence, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"k8s.io/client-go/util/jsonpath"
+)
+
+const (
+	// ChangeTypeUpdate is the change type of documents that were created or updated.
+	ChangeTypeUpdate = "update"
+
+	// ChangeTypeDelete is the change type of documents that were deleted.
+	ChangeTypeDelete = "delete"
+
+	// ChangeTypeConflict is the change type of documents with conflicting
+	// revisions, reported when spec.conflicts is set.
+	ChangeTypeConflict = "conflicted"
+)
+
+// ChangeTypes are the change types a CouchDbSource reports by default.
+var ChangeTypes = []string{
+	ChangeTypeUpdate,
+	ChangeTypeDelete,
+}
+
+// EventTypeData is the data spec.eventTypeTemplate is evaluated against.
+// +k8s:deepcopy-gen=false
+type EventTypeData struct {
+	// ChangeType is one of the ChangeTypes.
+	ChangeType string
+
+	// Database is the name of the database the change comes from.
+	Database string
+}
+
+// EventTypeTemplate renders the CloudEvent type of a change.
+// +k8s:deepcopy-gen=false
+type EventTypeTemplate struct {
+	tmpl *template.Template
+}
+
+// ParseEventTypeTemplate parses spec.eventTypeTemplate. An empty text yields
+// the default org.apache.couchdb.document.* event types.
+func ParseEventTypeTemplate(text string) (*EventTypeTemplate, error) {
+	tmpl, err := parse("eventType", text)
+	if err != nil {
+		return nil, err
+	}
+	return &EventTypeTemplate{tmpl: tmpl}, nil
+}
+
+// Render returns the CloudEvent type for the given change.
+func (t *EventTypeTemplate) Render(data EventTypeData) (string, error) {
+	if t.tmpl == nil {
+		return "org.apache.couchdb.document." + data.ChangeType, nil
+	}
+	s, err := execute(t.tmpl, data)
+	if err != nil {
+		return "", err
+	}
+	if s == "" {
+		return "", fmt.Errorf("event type template rendered an empty type for %q", data.ChangeType)
+	}
+	return s, nil
+}
+
+// SubjectData is the data spec.subjectTemplate is evaluated against.
+// +k8s:deepcopy-gen=false
+type SubjectData struct {
+	// ID is the ID of the changed document.
+	ID string
+
+	// Rev is the revision of the changed document.
+	Rev string
+
+	// Database is the name of the database the change comes from.
+	Database string
+}
+
+// SubjectTemplate renders the CloudEvent subject of a change.
+// +k8s:deepcopy-gen=false
+type SubjectTemplate struct {
+	tmpl *template.Template
+}
+
+// ParseSubjectTemplate parses spec.subjectTemplate. An empty text yields the
+// document ID as subject.
+func ParseSubjectTemplate(text string) (*SubjectTemplate, error) {
+	tmpl, err := parse("subject", text)
+	if err != nil {
+		return nil, err
+	}
+	return &SubjectTemplate{tmpl: tmpl}, nil
+}
+
+// Render returns the CloudEvent subject for the given change.
+func (t *SubjectTemplate) Render(data SubjectData) (string, error) {
+	if t.tmpl == nil {
+		return data.ID, nil
+	}
+	return execute(t.tmpl, data)
+}
+
+// EventIDTemplate renders the CloudEvent ID of a change.
+// +k8s:deepcopy-gen=false
+type EventIDTemplate struct {
+	tmpl *template.Template
+}
+
+// ParseEventIDTemplate parses spec.idTemplate. An empty text yields the
+// DefaultEventID.
+func ParseEventIDTemplate(text string) (*EventIDTemplate, error) {
+	tmpl, err := parse("id", text)
+	if err != nil {
+		return nil, err
+	}
+	return &EventIDTemplate{tmpl: tmpl}, nil
+}
+
+// Render returns the CloudEvent ID for the given change.
+func (t *EventIDTemplate) Render(data SubjectData) (string, error) {
+	if t == nil || t.tmpl == nil {
+		return DefaultEventID(data), nil
+	}
+	s, err := execute(t.tmpl, data)
+	if err != nil {
+		return "", err
+	}
+	if s == "" {
+		return "", fmt.Errorf("event ID template rendered an empty ID for %q", data.ID)
+	}
+	return s, nil
+}
+
+// DefaultEventID returns <database>/<document ID>/<revision>, which is the
+// same wherever and however many times the change is read.
+func DefaultEventID(data SubjectData) string {
+	return data.Database + "/" + data.ID + "/" + data.Rev
+}
+
+// DataProjection renders the data of the events of changed documents from
+// the JSONPath expressions of spec.projection.
+// +k8s:deepcopy-gen=false
+type DataProjection struct {
+	fields map[string]*jsonpath.JSONPath
+}
+
+// ParseProjection parses spec.projection. An empty projection yields nil,
+// which leaves the data of the events as is.
+func ParseProjection(fields map[string]string) (*DataProjection, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	p := &DataProjection{fields: make(map[string]*jsonpath.JSONPath, len(fields))}
+	for name, expr := range fields {
+		if name == "" {
+			return nil, fmt.Errorf("empty field name for %q", expr)
+		}
+		j := jsonpath.New(name).AllowMissingKeys(true)
+		if err := j.Parse(expr); err != nil {
+			return nil, fmt.Errorf("field %q: %w", name, err)
+		}
+		p.fields[name] = j
+	}
+	return p, nil
+}
+
+// Apply returns the fields of the projection evaluated against the document.
+// A field whose expression matches nothing is left out, and one matching
+// several values, e.g. with a wildcard, is a list of them.
+func (p *DataProjection) Apply(doc map[string]interface{}) (map[string]interface{}, error) {
+	data := make(map[string]interface{}, len(p.fields))
+	for name, j := range p.fields {
+		results, err := j.FindResults(doc)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", name, err)
+		}
+		var values []interface{}
+		for _, r := range results {
+			for _, v := range r {
+				values = append(values, valueOf(v))
+			}
+		}
+		switch len(values) {
+		case 0:
+		case 1:
+			data[name] = values[0]
+		default:
+			data[name] = values
+		}
+	}
+	return data, nil
+}
+
+// valueOf unwraps the value found by a JSONPath expression, which holds the
+// interface{} values of the decoded document.
+func valueOf(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
+
+func parse(name, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	return template.New(name).Option("missingkey=error").Parse(text)
+}
+
+func execute(tmpl *template.Template, data interface{}) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,225 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEventTypeTemplate(t *testing.T) {
+	testCases := map[string]struct {
+		template string
+		data     EventTypeData
+		want     string
+		wantErr  bool
+	}{
+		"default update": {
+			data: EventTypeData{ChangeType: ChangeTypeUpdate},
+			want: CouchDbSourceUpdateEventType,
+		},
+		"default delete": {
+			data: EventTypeData{ChangeType: ChangeTypeDelete},
+			want: CouchDbSourceDeleteEventType,
+		},
+		"change type": {
+			template: "com.acme.orders.{{.ChangeType}}",
+			data:     EventTypeData{ChangeType: ChangeTypeDelete, Database: "orders"},
+			want:     "com.acme.orders.delete",
+		},
+		"database": {
+			template: "com.acme.{{.Database}}.{{.ChangeType}}",
+			data:     EventTypeData{ChangeType: ChangeTypeUpdate, Database: "orders"},
+			want:     "com.acme.orders.update",
+		},
+		"unknown field": {
+			template: "com.acme.{{.Table}}",
+			data:     EventTypeData{ChangeType: ChangeTypeUpdate},
+			wantErr:  true,
+		},
+		"empty type": {
+			template: `{{if eq .ChangeType "update"}}com.acme.update{{end}}`,
+			data:     EventTypeData{ChangeType: ChangeTypeDelete},
+			wantErr:  true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			tmpl, err := ParseEventTypeTemplate(tc.template)
+			if err != nil {
+				t.Fatalf("ParseEventTypeTemplate() = %v", err)
+			}
+			got, err := tmpl.Render(tc.data)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("Render() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSubjectTemplate(t *testing.T) {
+	data := SubjectData{ID: "order-1", Rev: "2-abc", Database: "orders"}
+	testCases := map[string]struct {
+		template string
+		want     string
+		wantErr  bool
+	}{
+		"default": {
+			want: "order-1",
+		},
+		"template": {
+			template: "{{.Database}}/{{.ID}}@{{.Rev}}",
+			want:     "orders/order-1@2-abc",
+		},
+		"unknown field": {
+			template: "{{.Table}}",
+			wantErr:  true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			tmpl, err := ParseSubjectTemplate(tc.template)
+			if err != nil {
+				t.Fatalf("ParseSubjectTemplate() = %v", err)
+			}
+			got, err := tmpl.Render(data)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("Render() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEventIDTemplate(t *testing.T) {
+	data := SubjectData{ID: "order-1", Rev: "2-abc", Database: "orders"}
+	testCases := map[string]struct {
+		template string
+		want     string
+		wantErr  bool
+	}{
+		"default": {
+			want: "orders/order-1/2-abc",
+		},
+		"template": {
+			template: "{{.ID}}@{{.Rev}}",
+			want:     "order-1@2-abc",
+		},
+		"empty": {
+			template: "{{if false}}{{.ID}}{{end}}",
+			wantErr:  true,
+		},
+		"unknown field": {
+			template: "{{.Seq}}",
+			wantErr:  true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			tmpl, err := ParseEventIDTemplate(tc.template)
+			if err != nil {
+				t.Fatalf("ParseEventIDTemplate() = %v", err)
+			}
+			got, err := tmpl.Render(data)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("Render() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestProjection(t *testing.T) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(`{
+		"_id": "order-1",
+		"customer": {"name": "Ada", "email": "ada@example.com"},
+		"items": [{"sku": "a", "qty": 1}, {"sku": "b", "qty": 2}],
+		"total": 12.5
+	}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	testCases := map[string]struct {
+		fields   map[string]string
+		want     map[string]interface{}
+		wantErr  bool
+		wantNone bool
+	}{
+		"none": {
+			wantNone: true,
+		},
+		"rename": {
+			fields: map[string]string{"order": "{._id}", "customer": "{.customer.name}", "total": "{.total}"},
+			want:   map[string]interface{}{"order": "order-1", "customer": "Ada", "total": 12.5},
+		},
+		"object": {
+			fields: map[string]string{"customer": "{.customer}"},
+			want: map[string]interface{}{"customer": map[string]interface{}{
+				"name": "Ada", "email": "ada@example.com",
+			}},
+		},
+		"wildcard": {
+			fields: map[string]string{"skus": "{.items[*].sku}"},
+			want:   map[string]interface{}{"skus": []interface{}{"a", "b"}},
+		},
+		"missing": {
+			fields: map[string]string{"id": "{._id}", "phone": "{.customer.phone}"},
+			want:   map[string]interface{}{"id": "order-1"},
+		},
+		"invalid expression": {
+			fields:  map[string]string{"customer": "{.customer"},
+			wantErr: true,
+		},
+		"empty field name": {
+			fields:  map[string]string{"": "{._id}"},
+			wantErr: true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			p, err := ParseProjection(tc.fields)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseProjection() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if tc.wantNone {
+				if p != nil {
+					t.Errorf("ParseProjection() = %v, want nil", p)
+				}
+				return
+			}
+			got, err := p.Apply(doc)
+			if err != nil {
+				t.Fatalf("Apply() = %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Apply() (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
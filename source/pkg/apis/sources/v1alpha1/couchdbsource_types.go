@@ -17,10 +17,17 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"strings"
+	"time"
+
+	"github.com/rickb777/date/period"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+	"knative.dev/pkg/apis"
 	"knative.dev/pkg/apis/duck"
 	duckv1 "knative.dev/pkg/apis/duck/v1"
 	"knative.dev/pkg/kmeta"
@@ -52,6 +59,46 @@ var _ duckv1.KRShaped = (*CouchDbSource)(nil)
 // Check that CouchDbSource implements the Conditions duck type.
 var _ = duck.VerifyType(&CouchDbSource{}, &duckv1.Conditions{})
 
+const (
+	// AdapterImageAnnotationKey selects an alternative receive adapter image
+	// for a single source, e.g. to canary a patched adapter. The image must be
+	// allowed by the controller's allowlist.
+	AdapterImageAnnotationKey = "couchdb.sources.knative.dev/adapter-image"
+
+	// ReplayFromAnnotationKey rewinds the source to the given update sequence:
+	// the receive adapter is restarted and re-emits the changes made after it.
+	// It takes precedence over spec.since and spec.window.since, and skips the
+	// backfill.
+	ReplayFromAnnotationKey = "couchdb.sources.knative.dev/replay-from"
+
+	// PprofPortAnnotationKey serves the net/http/pprof profiles of the
+	// receive adapter on the given port of its localhost, to profile it in
+	// place through kubectl port-forward.
+	PprofPortAnnotationKey = "couchdb.sources.knative.dev/pprof-port"
+
+	// InstallationLabelKey assigns a source to one of the installations of
+	// the controller in the cluster, e.g. a canary. Unlabeled sources belong
+	// to the default installation.
+	InstallationLabelKey = "couchdb.sources.knative.dev/installation"
+
+	// ApprovedPlanAnnotationKey approves, with spec.applyMode manual, the
+	// plan of the given ID to be applied to the receive adapter.
+	ApprovedPlanAnnotationKey = "couchdb.sources.knative.dev/approved-plan"
+
+	// PlanAnnotationKey and PlanIDAnnotationKey are the status annotations
+	// holding the plan waiting for approval and its ID.
+	PlanAnnotationKey   = "couchdb.sources.knative.dev/plan"
+	PlanIDAnnotationKey = "couchdb.sources.knative.dev/plan-id"
+)
+
+// MinFeedTiming and MaxFeedTiming bound the heartbeat and timeout of the
+// changes feed. The maximum stays below the 60 seconds idle timeout common to
+// load balancers and reverse proxies.
+const (
+	MinFeedTiming = time.Second
+	MaxFeedTiming = 50 * time.Second
+)
+
 // FeedType is the type of Feed
 type FeedType string
 
@@ -67,6 +114,27 @@ const (
 	// CouchDbSourceDeleteEventType is the CouchDbSource CloudEvent type for deletion.
 	CouchDbSourceDeleteEventType = "org.apache.couchdb.document.delete"
 
+	// CouchDbSourceConflictEventType is the CouchDbSource CloudEvent type for documents
+	// with conflicting revisions.
+	CouchDbSourceConflictEventType = "org.apache.couchdb.document.conflicted"
+
+	// CouchDbSourceUndecodableEventType is the CouchDbSource CloudEvent type
+	// sent to the dead letter sink for the changes of documents that cannot be
+	// turned into events, with spec.onDecodeError: deadLetter.
+	CouchDbSourceUndecodableEventType = "org.apache.couchdb.document.undecodable"
+
+	// CouchDbSourceBatchEventType is the CouchDbSource CloudEvent type for a group of changes
+	// sent as one event.
+	CouchDbSourceBatchEventType = "org.apache.couchdb.document.batch"
+
+	// CouchDbSourceDatabaseCreatedEventType, CouchDbSourceDatabaseDeletedEventType
+	// and CouchDbSourceDatabaseUpdatedEventType are the CouchDbSource CloudEvent
+	// types for the databases of the server created, deleted or updated, sent
+	// with spec.databaseUpdates.
+	CouchDbSourceDatabaseCreatedEventType = "org.apache.couchdb.database.created"
+	CouchDbSourceDatabaseDeletedEventType = "org.apache.couchdb.database.deleted"
+	CouchDbSourceDatabaseUpdatedEventType = "org.apache.couchdb.database.updated"
+
 	// FeedNormal corresponds to the "normal" feed. The connection to the server
 	// is closed after reporting changes.
 	FeedNormal = FeedType("normal")
@@ -76,6 +144,36 @@ const (
 	FeedContinuous = FeedType("continuous")
 )
 
+// AuthMethod is the mechanism the receive adapter uses to authenticate
+// against CouchDB with the credentials found in the secret url.
+type AuthMethod string
+
+const (
+	// AuthBasic sends the credentials with every request using HTTP Basic
+	// authentication.
+	AuthBasic = AuthMethod("basic")
+
+	// AuthSession logs in through the _session endpoint and authenticates
+	// requests with the returned AuthSession cookie. The session is renewed
+	// before it expires.
+	AuthSession = AuthMethod("session")
+)
+
+// AttachmentsPolicy controls how document attachments appear in the events.
+type AttachmentsPolicy string
+
+const (
+	// AttachmentsNone strips the attachments from the documents.
+	AttachmentsNone = AttachmentsPolicy("none")
+
+	// AttachmentsInline embeds the attachments, base64 encoded.
+	AttachmentsInline = AttachmentsPolicy("inline")
+
+	// AttachmentsReference keeps the attachment metadata and adds the URL
+	// the attachment can be fetched from.
+	AttachmentsReference = AttachmentsPolicy("reference")
+)
+
 // CouchDbSourceSpec defines the desired state of CouchDbSource
 type CouchDbSourceSpec struct {
 	// ServiceAccountName holds the name of the Kubernetes service account
@@ -93,12 +191,964 @@ type CouchDbSourceSpec struct {
 	// More information: https://docs.couchdb.org/en/stable/api/database/changes.html#changes-feeds
 	Feed FeedType `json:"feed"`
 
+	// Heartbeat is the ISO-8601 duration after which CouchDB sends an empty
+	// line on an idle continuous feed, keeping the connection open through
+	// load balancers and proxies. It must stay below their idle timeout.
+	// Defaults to the controller's default heartbeat, else half the idle
+	// timeout of the load balancers of config-couchdb-feed, or PT6S.
+	// +optional
+	Heartbeat string `json:"heartbeat,omitempty"`
+
+	// Timeout is the ISO-8601 duration after which CouchDB closes an idle
+	// continuous feed, which the receive adapter then reopens. It cannot be
+	// combined with Heartbeat, which keeps the feed open indefinitely.
+	// +optional
+	Timeout string `json:"timeout,omitempty"`
+
+	// Polling adapts the interval between the polls of the normal feed to
+	// the rate of changes. Without it, the feed is polled every 2 seconds.
+	// +optional
+	Polling *PollingSpec `json:"polling,omitempty"`
+
+	// Reconnect spaces out the reopenings of the feed after it failed or was
+	// dropped, with an exponential backoff and jitter. Its fields default to
+	// the config-couchdb-reconnect ConfigMap.
+	// +optional
+	Reconnect *ReconnectSpec `json:"reconnect,omitempty"`
+
 	// Database is the database to watch for changes
 	Database string `json:"database"`
 
+	// DatabasePattern, in place of Database, watches every database of the
+	// server whose name matches this glob, e.g. "orders-*". The receive
+	// adapter runs as a StatefulSet, whose replicas discover the matching
+	// databases and each tail the ones hashing to their ordinal.
+	// +optional
+	DatabasePattern string `json:"databasePattern,omitempty"`
+
+	// Shards is the number of replicas the databases of DatabasePattern are
+	// partitioned across. Defaults to 1.
+	// +optional
+	Shards int32 `json:"shards,omitempty"`
+
+	// Auth selects how the adapter authenticates against CouchDB. When
+	// unspecified, the credentials embedded in the url are handed to the
+	// CouchDB client as-is.
+	// More information: https://docs.couchdb.org/en/stable/api/server/authn.html
+	// +optional
+	Auth AuthMethod `json:"auth,omitempty"`
+
+	// DevInstance asks the controller to provision a throwaway single-node
+	// CouchDB, along with its credentials, for this source. Data is not
+	// persisted. This is meant for demos and local development and is
+	// mutually exclusive with CouchDbCredentials.
+	// +optional
+	DevInstance bool `json:"devInstance,omitempty"`
+
+	// Proxy routes the adapter's connections to CouchDB through an HTTP(S)
+	// egress proxy. Deliveries to the sink are not affected.
+	// +optional
+	Proxy *ProxySpec `json:"proxy,omitempty"`
+
 	// Sink is a reference to an object that will resolve to a domain name to use as the sink.
 	// +optional
 	Sink *duckv1.Destination `json:"sink,omitempty"`
+
+	// SinkAudience is the OIDC audience of the sink, for the sinks given by
+	// URI: the Addressables the sink references advertise theirs in
+	// status.address.audience. The events are then sent to the sink with a
+	// token of the service account of the receive adapter for the audience.
+	// +optional
+	SinkAudience string `json:"sinkAudience,omitempty"`
+
+	// SinkCACerts are the PEM certificates of the certificate authorities the
+	// HTTPS sink is verified with, on top of the system ones, e.g. the
+	// cluster-internal CA of the Knative Eventing transport encryption. The
+	// Addressables the sink references advertise theirs in
+	// status.address.CACerts.
+	// +optional
+	SinkCACerts string `json:"sinkCACerts,omitempty"`
+
+	// Delivery configures how failed deliveries to the sink are retried and
+	// where they end up once retries are exhausted.
+	// +optional
+	Delivery *eventingduckv1.DeliverySpec `json:"delivery,omitempty"`
+
+	// Reply is where the events the sink responds with are forwarded, as
+	// with the reply of a Subscription. Without it, they are discarded.
+	// +optional
+	Reply *duckv1.Destination `json:"reply,omitempty"`
+
+	// CloudEventOverrides defines overrides to control the output format and
+	// modifications of the event sent to the sink.
+	// +optional
+	CloudEventOverrides *duckv1.CloudEventOverrides `json:"ceOverrides,omitempty"`
+
+	// EventTypeTemplate is a Go template producing the CloudEvent type
+	// attribute, e.g. "com.acme.orders.{{.ChangeType}}". It is evaluated with
+	// .ChangeType ("update" or "delete") and .Database. When unspecified the
+	// org.apache.couchdb.document.* types are used.
+	// +optional
+	EventTypeTemplate string `json:"eventTypeTemplate,omitempty"`
+
+	// EventTypeField is the top-level document field whose value is the
+	// CloudEvent type of the update events, e.g. type. Updates of documents
+	// without a non-empty string in the field, deletions and conflicts keep
+	// the type of eventTypeTemplate, or the default one.
+	// +optional
+	EventTypeField string `json:"eventTypeField,omitempty"`
+
+	// CloudEventSource overrides the CloudEvent source attribute, which
+	// defaults to the CouchDB host and database. It must be a URI-reference.
+	// +optional
+	CloudEventSource string `json:"ceSource,omitempty"`
+
+	// SubjectTemplate is a Go template producing the CloudEvent subject
+	// attribute, e.g. "{{.Database}}/{{.ID}}". It is evaluated with .ID,
+	// .Rev and .Database. When unspecified the subject is the document ID.
+	// +optional
+	SubjectTemplate string `json:"subjectTemplate,omitempty"`
+
+	// IDTemplate is a Go template producing the CloudEvent id attribute,
+	// evaluated with .ID, .Rev and .Database. It must identify the revision
+	// of the document, so that the redelivered events can be deduplicated.
+	// When unspecified the ID is "{{.Database}}/{{.ID}}/{{.Rev}}".
+	// +optional
+	IDTemplate string `json:"idTemplate,omitempty"`
+
+	// Partitions restricts the events of a partitioned database to the
+	// documents of the given partitions.
+	// +optional
+	Partitions []string `json:"partitions,omitempty"`
+
+	// Attachments, when set, makes the events carry the changed documents
+	// and selects how their attachments are represented: none, inline or
+	// reference. When unspecified the events only carry the changed
+	// revisions.
+	// +optional
+	Attachments AttachmentsPolicy `json:"attachments,omitempty"`
+
+	// Payload selects the data of the update and delete events: the changed
+	// revisions (revisions), a JSON patch turning the previous revision of
+	// the document into the changed one (diff), which keeps the events of
+	// large documents with small edits small, or the metadata of the change
+	// without the document contents (metadata). Defaults to revisions.
+	// +optional
+	Payload Payload `json:"payload,omitempty"`
+
+	// Match only reports the changes of the documents satisfying the
+	// expression, e.g. doc.type == "order" && doc.total > 100. It is
+	// evaluated by the receive adapter, for the databases where server side
+	// filters or design documents cannot be created.
+	// +optional
+	Match string `json:"match,omitempty"`
+
+	// Projection replaces the data of the update and delete events with the
+	// given fields, each evaluating a JSONPath expression in the kubectl
+	// syntax, e.g. "{.customer.name}", against the changed document. It
+	// renames and trims the documents without a transformer in front of
+	// the sink.
+	// +optional
+	Projection map[string]string `json:"projection,omitempty"`
+
+	// Since is the update sequence the changes feed starts after: "0" to
+	// replay the whole history of the database, "now" to only report the
+	// changes made from now on, or an update sequence. Defaults to "0".
+	// +optional
+	Since string `json:"since,omitempty"`
+
+	// SeqInterval makes CouchDB only compute the update sequence of one
+	// change out of SeqInterval, which lightens the changes feed of busy
+	// clustered databases. The other changes are reported without a sequence,
+	// and the adapter resumes from the last sequence it got.
+	// +optional
+	SeqInterval int32 `json:"seqInterval,omitempty"`
+
+	// Window bounds the changes reported by the source, e.g. to replay a
+	// historical slice of the database into the sink.
+	// +optional
+	Window *WindowSpec `json:"window,omitempty"`
+
+	// DeletedDocs controls whether the deletions of documents are reported
+	// (include), suppressed (exclude) or the only changes reported (only).
+	// Defaults to include.
+	// +optional
+	DeletedDocs DeletedDocsPolicy `json:"deletedDocs,omitempty"`
+
+	// DesignDocs controls whether the changes of design documents (_design/*)
+	// are reported (include), suppressed (exclude) or the only changes
+	// reported (only). Defaults to include.
+	// +optional
+	DesignDocs DesignDocsPolicy `json:"designDocs,omitempty"`
+
+	// OnDecodeError controls what happens to a change whose document cannot
+	// be parsed or transformed into an event: it is dropped (skip), reported
+	// to the dead letter sink of spec.delivery (deadLetter), or the feed
+	// stops at it until the document is fixed (fail). Defaults to skip.
+	// +optional
+	OnDecodeError DecodeErrorPolicy `json:"onDecodeError,omitempty"`
+
+	// Conflicts makes the feed report the conflicting revisions of documents.
+	// Changes of documents in conflict are then reported as
+	// org.apache.couchdb.document.conflicted events carrying the revisions.
+	// +optional
+	Conflicts bool `json:"conflicts,omitempty"`
+
+	// DatabaseUpdates makes the source also report the databases of the
+	// server created, deleted or updated, from its global _db_updates feed,
+	// as org.apache.couchdb.database.* events whose subject is the name of
+	// the database. Reading the feed requires the credentials of a server
+	// admin.
+	// +optional
+	DatabaseUpdates bool `json:"databaseUpdates,omitempty"`
+
+	// ContentMode selects how events are delivered to the sink: one event
+	// per request with its attributes in HTTP headers (binary) or in a JSON
+	// envelope (structured), or many events per request in the CloudEvents
+	// JSON batch format (batch). Sinks answering batches with 415 Unsupported
+	// Media Type get one event per request instead. Defaults to binary.
+	// +optional
+	ContentMode ContentMode `json:"contentMode,omitempty"`
+
+	// ApplyMode selects whether the changes of the spec are applied to the
+	// receive adapter right away (automatic), or once the plan written to
+	// the status is approved with the approved-plan annotation (manual).
+	// Defaults to automatic.
+	// +optional
+	ApplyMode ApplyMode `json:"applyMode,omitempty"`
+
+	// Ordering selects whether the changes are delivered strictly in the
+	// order of the changes feed (ordered), each waiting for the sink to
+	// accept the previous one, or possibly out of order by concurrent
+	// batches (unordered). The global ordering also makes a single receive
+	// adapter deliver at any time, with a standby taking over when it fails.
+	// Defaults to unordered.
+	// +optional
+	Ordering Ordering `json:"ordering,omitempty"`
+
+	// Batch coalesces changes to cut the requests to the sink. With the
+	// binary content mode, the changes are sent as a single
+	// org.apache.couchdb.document.batch event whose data is a JSON array.
+	// With the batch content mode, it sizes the CloudEvents batches.
+	// +optional
+	Batch *BatchSpec `json:"batch,omitempty"`
+
+	// RateLimit caps the rate of the events sent to the sink, e.g. so that a
+	// bulk import into the database does not flood the downstream broker.
+	// The receive adapter then reads the changes feed at that pace.
+	// +optional
+	RateLimit *RateLimitSpec `json:"rateLimit,omitempty"`
+
+	// Limits caps what the receive adapter accepts from the changes feed, to
+	// protect it from pathological or malicious responses.
+	// +optional
+	Limits *LimitsSpec `json:"limits,omitempty"`
+
+	// Backfill makes the receive adapter first report every existing
+	// document of the database, as update events, before reporting the
+	// changes that follow. The progress is reported in status.backfill.
+	// +optional
+	Backfill bool `json:"backfill,omitempty"`
+
+	// Stats reports the delivery statistics of the source in status.stats.
+	// +optional
+	Stats *StatsSpec `json:"stats,omitempty"`
+
+	// Grouping gathers the changes of related documents, e.g. written by the
+	// same transaction, so that they can be handled together downstream.
+	// +optional
+	Grouping *GroupingSpec `json:"grouping,omitempty"`
+
+	// Access restricts the events to the documents granting one of the roles
+	// of the source, so that the tenants of a shared database each get their
+	// own source.
+	// +optional
+	Access *AccessSpec `json:"access,omitempty"`
+
+	// HealthMetrics makes the receive adapter scrape the health of the
+	// CouchDB server, and export it along with the metrics of the source.
+	// +optional
+	HealthMetrics *HealthMetricsSpec `json:"healthMetrics,omitempty"`
+
+	// LagMetrics makes the receive adapter poll the update sequence of the
+	// database, and export how far behind it the source is.
+	// +optional
+	LagMetrics *LagMetricsSpec `json:"lagMetrics,omitempty"`
+
+	// Liveness tunes when the receive adapter reports unhealthy on its
+	// liveness endpoint, so that Kubernetes restarts it.
+	// +optional
+	Liveness *LivenessSpec `json:"liveness,omitempty"`
+
+	// HighAvailability runs standby replicas of the receive adapter, which
+	// take over within seconds when the replica delivering the changes
+	// fails, rather than waiting for its pod to be rescheduled.
+	// +optional
+	HighAvailability *HighAvailabilitySpec `json:"highAvailability,omitempty"`
+
+	// Template customizes the pod of the receive adapter, e.g. to pin it to
+	// dedicated nodes or to size its resources.
+	// +optional
+	Template *AdapterTemplateSpec `json:"template,omitempty"`
+
+	// Join enriches the changed documents with the documents of a secondary
+	// database of the server, e.g. a lookup table, whose IDs they hold.
+	// Experimental.
+	// +optional
+	Join *JoinSpec `json:"join,omitempty"`
+
+	// Buffer keeps the events the sink does not accept on a persistent
+	// volume, so that the changes feed moves on during sink outages.
+	// +optional
+	Buffer *BufferSpec `json:"buffer,omitempty"`
+}
+
+// DefaultStatsInterval and MinStatsInterval are the default and minimum
+// periods between two updates of status.stats.
+const (
+	DefaultStatsInterval = time.Minute
+	MinStatsInterval     = 10 * time.Second
+)
+
+// DefaultHealthMetricsInterval and MinHealthMetricsInterval are the default
+// and minimum periods between two scrapes of the health of the CouchDB server.
+const (
+	DefaultHealthMetricsInterval = time.Minute
+	MinHealthMetricsInterval     = 10 * time.Second
+)
+
+// HealthMetricsSpec configures the health metrics of the CouchDB server: its
+// _up endpoint, its _membership and selected statistics of
+// _node/_local/_stats, which require the _admin role.
+type HealthMetricsSpec struct {
+	// Interval is the period between two scrapes, as an ISO-8601 duration
+	// of at least PT10S. Defaults to PT1M.
+	// +optional
+	Interval string `json:"interval,omitempty"`
+}
+
+// DefaultLagMetricsInterval and MinLagMetricsInterval are the default and
+// minimum periods between two polls of the update sequence of the database.
+const (
+	DefaultLagMetricsInterval = 30 * time.Second
+	MinLagMetricsInterval     = 10 * time.Second
+)
+
+// LagMetricsSpec configures the lag metrics of the source: how many changes
+// of the database follow the last one delivered.
+type LagMetricsSpec struct {
+	// Interval is the period between two polls of the update sequence, as
+	// an ISO-8601 duration of at least PT10S. Defaults to PT30S.
+	// +optional
+	Interval string `json:"interval,omitempty"`
+}
+
+// DefaultDisconnectedThreshold and MinDisconnectedThreshold are the default
+// and minimum durations the changes feed stays disconnected before the
+// receive adapter reports unhealthy.
+const (
+	DefaultDisconnectedThreshold = 5 * time.Minute
+	MinDisconnectedThreshold     = 30 * time.Second
+)
+
+// LivenessSpec configures the liveness endpoint of the receive adapter.
+type LivenessSpec struct {
+	// DisconnectedThreshold is how long the changes feed may fail to
+	// connect, or fail while being read, before the receive adapter reports
+	// unhealthy, as an ISO-8601 duration of at least PT30S. Defaults to PT5M.
+	// +optional
+	DisconnectedThreshold string `json:"disconnectedThreshold,omitempty"`
+}
+
+// HighAvailabilitySpec configures the standby replicas of the receive
+// adapter.
+type HighAvailabilitySpec struct {
+	// Replicas is the number of replicas of the receive adapter, at least 2.
+	// Only the one holding the lease of the source reads and delivers the
+	// changes, while the others stand by.
+	Replicas int32 `json:"replicas"`
+}
+
+// AdapterTemplateSpec customizes the pod of the receive adapter. The settings
+// of the source take precedence: the labels selecting the pods and the
+// environment configuring the adapter cannot be overridden.
+type AdapterTemplateSpec struct {
+	// Metadata holds the labels and annotations added to the pod.
+	// +optional
+	Metadata *AdapterTemplateMetadata `json:"metadata,omitempty"`
+
+	// Resources are the compute resources of the receive adapter container.
+	// GOMAXPROCS follows its CPU limit, and GOMEMLIMIT is 90% of its memory
+	// limit, if any.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// NodeSelector and Tolerations select the nodes of the pod.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Affinity holds the scheduling constraints of the pod. Its pod
+	// anti-affinity replaces the one spreading the replicas across nodes.
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// Env holds additional environment variables of the receive adapter
+	// container. The variables configuring the adapter, prefixed with
+	// COUCHDB_, DELIVERY_ or K_, are reserved.
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+}
+
+// AdapterTemplateMetadata holds the labels and annotations added to the pod
+// of the receive adapter.
+type AdapterTemplateMetadata struct {
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// DefaultJoinCacheSize and DefaultJoinMaxAge are the defaults of
+// spec.join.cacheSize and spec.join.maxAge.
+const (
+	DefaultJoinCacheSize = 1000
+	DefaultJoinMaxAge    = time.Minute
+)
+
+// JoinSpec adds to each changed document the document of the secondary
+// database whose ID is the value of its key field.
+type JoinSpec struct {
+	// Database is the secondary database, on the same server.
+	Database string `json:"database"`
+
+	// Key is the top-level document field holding the ID of the joined
+	// document, e.g. customer_id. Documents without it are not joined.
+	Key string `json:"key"`
+
+	// As is the top-level field the joined document is added under.
+	// Defaults to the name of the secondary database.
+	// +optional
+	As string `json:"as,omitempty"`
+
+	// CacheSize is how many joined documents are cached, the least recently
+	// used being evicted first. Defaults to 1000.
+	// +optional
+	CacheSize int32 `json:"cacheSize,omitempty"`
+
+	// MaxAge is how stale a cached document may be before it is read again,
+	// as an ISO-8601 duration. A cached document is still used when it
+	// cannot be read again. Defaults to PT1M.
+	// +optional
+	MaxAge string `json:"maxAge,omitempty"`
+
+	// OnMissing is what happens to the changes whose joined document does
+	// not exist. Defaults to emit.
+	// +optional
+	OnMissing JoinMissingPolicy `json:"onMissing,omitempty"`
+}
+
+// JoinMissingPolicy is what happens to a change whose joined document does
+// not exist.
+type JoinMissingPolicy string
+
+const (
+	// JoinMissingEmit reports the change with a null joined document.
+	JoinMissingEmit = JoinMissingPolicy("emit")
+	// JoinMissingSkip does not report the change.
+	JoinMissingSkip = JoinMissingPolicy("skip")
+)
+
+// Field returns the field the joined document is added under.
+func (js *JoinSpec) Field() string {
+	if js.As == "" {
+		return js.Database
+	}
+	return js.As
+}
+
+// BufferSpec is a write-ahead log of the events the sink does not accept, on
+// a PersistentVolumeClaim of the receive adapter, from which they are
+// delivered once the sink recovers.
+type BufferSpec struct {
+	// Size is the size of the PersistentVolumeClaim, e.g. 1Gi. The buffered
+	// events fill up to 90% of it.
+	Size resource.Quantity `json:"size"`
+
+	// StorageClassName is the storage class of the PersistentVolumeClaim.
+	// Defaults to the default storage class of the cluster.
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+
+	// OnFull is what happens to the events that do not fit in the buffer.
+	// Defaults to block.
+	// +optional
+	OnFull BufferFullPolicy `json:"onFull,omitempty"`
+}
+
+// BufferFullPolicy is what happens to the events that do not fit in a full
+// buffer.
+type BufferFullPolicy string
+
+const (
+	// BufferFullBlock holds the changes feed until the buffer drains, as
+	// without a buffer.
+	BufferFullBlock = BufferFullPolicy("block")
+	// BufferFullDropOldest discards the oldest buffered events to make room.
+	BufferFullDropOldest = BufferFullPolicy("dropOldest")
+)
+
+// MaxBytes returns how many bytes the buffered events fill, leaving room for
+// the file system.
+func (bs *BufferSpec) MaxBytes() int64 {
+	return bs.Size.Value() / 10 * 9
+}
+
+// ScrapeInterval returns the period between two scrapes of the health.
+func (hs *HealthMetricsSpec) ScrapeInterval() time.Duration {
+	if hs.Interval == "" {
+		return DefaultHealthMetricsInterval
+	}
+	p, err := period.Parse(hs.Interval)
+	if err != nil {
+		return DefaultHealthMetricsInterval
+	}
+	return p.DurationApprox()
+}
+
+// PollInterval returns the period between two polls of the update sequence.
+func (ls *LagMetricsSpec) PollInterval() time.Duration {
+	if ls.Interval == "" {
+		return DefaultLagMetricsInterval
+	}
+	p, err := period.Parse(ls.Interval)
+	if err != nil {
+		return DefaultLagMetricsInterval
+	}
+	return p.DurationApprox()
+}
+
+// Threshold returns how long the changes feed may stay disconnected before
+// the receive adapter reports unhealthy.
+func (ls *LivenessSpec) Threshold() time.Duration {
+	if ls == nil || ls.DisconnectedThreshold == "" {
+		return DefaultDisconnectedThreshold
+	}
+	p, err := period.Parse(ls.DisconnectedThreshold)
+	if err != nil {
+		return DefaultDisconnectedThreshold
+	}
+	return p.DurationApprox()
+}
+
+// StatsSpec configures status.stats.
+type StatsSpec struct {
+	// Interval is the minimum period between two updates of status.stats, as
+	// an ISO-8601 duration of at least PT10S. Defaults to PT1M.
+	// +optional
+	Interval string `json:"interval,omitempty"`
+}
+
+// UpdateInterval returns the period between two updates of status.stats.
+func (ss *StatsSpec) UpdateInterval() time.Duration {
+	if ss.Interval == "" {
+		return DefaultStatsInterval
+	}
+	p, err := period.Parse(ss.Interval)
+	if err != nil {
+		return DefaultStatsInterval
+	}
+	return p.DurationApprox()
+}
+
+// AdapterReplicas returns the number of replicas of the receive adapter.
+// The global ordering runs a standby at least.
+func (cs *CouchDbSourceSpec) AdapterReplicas() int32 {
+	replicas := int32(1)
+	if cs.Ordering == OrderingGlobal {
+		replicas = 2
+	}
+	if cs.HighAvailability != nil && cs.HighAvailability.Replicas > replicas {
+		replicas = cs.HighAvailability.Replicas
+	}
+	return replicas
+}
+
+// Sharded is whether the source watches the databases matching a pattern,
+// partitioned across the replicas of a StatefulSet.
+func (cs *CouchDbSourceSpec) Sharded() bool {
+	return cs.DatabasePattern != ""
+}
+
+// AdapterShards returns the number of replicas the databases of the pattern
+// are partitioned across.
+func (cs *CouchDbSourceSpec) AdapterShards() int32 {
+	if cs.Shards > 0 {
+		return cs.Shards
+	}
+	return 1
+}
+
+// Leased is whether only the replica of the receive adapter holding the lease
+// of the source reads and delivers the changes.
+func (cs *CouchDbSourceSpec) Leased() bool {
+	return cs.Ordering == OrderingGlobal || cs.HighAvailability != nil
+}
+
+// ServesStatus is whether the receive adapter serves its AdapterStatus.
+func (cs *CouchDbSourceSpec) ServesStatus() bool {
+	return cs.Backfill || cs.Stats != nil
+}
+
+// DefaultHeartbeat keeps continuous feeds alive when neither a heartbeat nor
+// a timeout is configured, by the controller or the source.
+const DefaultHeartbeat = 6 * time.Second
+
+// DefaultBackoffDelay is the delay between the retries of the sources that
+// retry without a spec.delivery.backoffDelay.
+const DefaultBackoffDelay = 200 * time.Millisecond
+
+// DefaultMinPollInterval and DefaultMaxPollInterval bound the adaptive
+// polling interval by default.
+const (
+	DefaultMinPollInterval = time.Second
+	DefaultMaxPollInterval = time.Minute
+)
+
+// PollingSpec bounds the adaptive interval between the polls of the normal
+// feed. The interval halves after each poll returning changes, and grows by
+// MinInterval after each idle poll.
+type PollingSpec struct {
+	// MinInterval is the shortest interval, as an ISO-8601 duration.
+	// Defaults to PT1S.
+	// +optional
+	MinInterval string `json:"minInterval,omitempty"`
+
+	// MaxInterval is the longest interval, as an ISO-8601 duration.
+	// Defaults to PT1M, or to MinInterval when it is longer.
+	// +optional
+	MaxInterval string `json:"maxInterval,omitempty"`
+}
+
+// DefaultReconnectInitialDelay and DefaultReconnectMaxDelay bound the delay
+// before reopening a failed feed by default.
+const (
+	DefaultReconnectInitialDelay = 2 * time.Second
+	DefaultReconnectMaxDelay     = time.Minute
+)
+
+// ReconnectSpec bounds the delay before reopening the feed after it failed.
+// The delay doubles after each consecutive failure, and a random jitter of up
+// to half of it keeps the adapters from reconnecting in lockstep.
+type ReconnectSpec struct {
+	// InitialDelay is the delay after the first failure, as an ISO-8601
+	// duration. Defaults to PT2S.
+	// +optional
+	InitialDelay string `json:"initialDelay,omitempty"`
+
+	// MaxDelay is the longest delay, as an ISO-8601 duration. Defaults to
+	// PT1M, or to InitialDelay when it is longer.
+	// +optional
+	MaxDelay string `json:"maxDelay,omitempty"`
+}
+
+// RateLimitSpec is a token bucket limiting the events sent to the sink. An
+// event in a CloudEvents batch counts as one event, and so does an
+// org.apache.couchdb.document.batch event.
+type RateLimitSpec struct {
+	// EventsPerSecond is the sustained rate of events.
+	EventsPerSecond int32 `json:"eventsPerSecond"`
+
+	// Burst is the number of events that may be sent at once after an idle
+	// period. Defaults to EventsPerSecond.
+	// +optional
+	Burst int32 `json:"burst,omitempty"`
+}
+
+// GroupingSpec groups changes by the value of a document field.
+type GroupingSpec struct {
+	// Field is the top-level document field holding the group, e.g. txn_id.
+	// Changes of documents without the field are not grouped.
+	Field string `json:"field"`
+
+	// Delay is how long the changes of a group are gathered after its first
+	// change, as an ISO-8601 duration. Defaults to PT1S.
+	// +optional
+	Delay string `json:"delay,omitempty"`
+
+	// Batch sends each group as a single event of type
+	// org.apache.couchdb.document.batch. Otherwise the events of a group are
+	// sent individually with the couchdbgroup extension.
+	// +optional
+	Batch bool `json:"batch,omitempty"`
+}
+
+// DefaultAccessField is the document field holding the roles allowed to
+// read a document, unless spec.access.field says otherwise.
+const DefaultAccessField = "roles"
+
+// AdminRole is the role reading every document, as the CouchDB server admins.
+const AdminRole = "_admin"
+
+// AccessSpec matches the roles of the source against the roles allowed by
+// each document, the way CouchDB matches the roles of a user against the
+// members of a database _security object.
+type AccessSpec struct {
+	// Roles are the roles of the source. A document is reported when it
+	// allows at least one of them. The _admin role reads every document.
+	Roles []string `json:"roles"`
+
+	// Field is the top-level document field holding the roles allowed to
+	// read the document, as an array of strings or a single string. Defaults
+	// to roles. Documents without roles are only reported to the _admin role.
+	// +optional
+	Field string `json:"field,omitempty"`
+}
+
+// IsBounded returns whether the source stops once the changes up to the end of
+// its window are reported.
+func (cs *CouchDbSourceSpec) IsBounded() bool {
+	return cs.Window != nil && cs.Window.Until != ""
+}
+
+// ChangeTypes returns the change types reported by the source.
+func (cs *CouchDbSourceSpec) ChangeTypes() []string {
+	changeTypes := cs.DeletedDocs.ChangeTypes()
+	if cs.Conflicts && cs.DeletedDocs != DeletedDocsOnly {
+		changeTypes = append(changeTypes, ChangeTypeConflict)
+	}
+	return changeTypes
+}
+
+// ApplyMode is how the changes of the spec reach the receive adapter.
+type ApplyMode string
+
+const (
+	// ApplyModeAutomatic applies the changes as soon as they are made.
+	ApplyModeAutomatic = ApplyMode("automatic")
+
+	// ApplyModeManual holds the changes until their plan is approved.
+	ApplyModeManual = ApplyMode("manual")
+)
+
+// Payload is the data of the update and delete events.
+type Payload string
+
+const (
+	// PayloadRevisions makes the data the changed revisions of the document.
+	PayloadRevisions = Payload("revisions")
+
+	// PayloadDiff makes the data the JSON patch (RFC 6902) from the previous
+	// revision of the document, fetched from the database.
+	PayloadDiff = Payload("diff")
+
+	// PayloadMetadata makes the data the ID, revision, sequence and deletion
+	// of the changed document, and its database, without its contents.
+	PayloadMetadata = Payload("metadata")
+)
+
+// DeletedDocsPolicy controls which changes of deleted documents produce events.
+type DeletedDocsPolicy string
+
+const (
+	// DeletedDocsInclude reports the deletions along with the other changes.
+	DeletedDocsInclude = DeletedDocsPolicy("include")
+
+	// DeletedDocsExclude suppresses the deletions.
+	DeletedDocsExclude = DeletedDocsPolicy("exclude")
+
+	// DeletedDocsOnly reports the deletions only.
+	DeletedDocsOnly = DeletedDocsPolicy("only")
+)
+
+// ChangeTypes returns the change types reported under the policy.
+func (p DeletedDocsPolicy) ChangeTypes() []string {
+	switch p {
+	case DeletedDocsExclude:
+		return []string{ChangeTypeUpdate}
+	case DeletedDocsOnly:
+		return []string{ChangeTypeDelete}
+	default:
+		return append([]string(nil), ChangeTypes...)
+	}
+}
+
+// Reports returns whether a change is reported under the policy.
+func (p DeletedDocsPolicy) Reports(deleted bool) bool {
+	switch p {
+	case DeletedDocsExclude:
+		return !deleted
+	case DeletedDocsOnly:
+		return deleted
+	default:
+		return true
+	}
+}
+
+// LimitsSpec caps the changes feed responses.
+type LimitsSpec struct {
+	// MaxLineBytes caps the length of a line of the changes feed, which holds
+	// a single change. Defaults to 64Mi.
+	// +optional
+	MaxLineBytes int64 `json:"maxLineBytes,omitempty"`
+
+	// MaxJSONDepth caps the nesting depth of the changes feed JSON. Defaults
+	// to 512.
+	// +optional
+	MaxJSONDepth int32 `json:"maxJSONDepth,omitempty"`
+
+	// MaxResults caps the number of changes in a response of the changes
+	// feed. The remaining changes are read by the following requests.
+	// Unbounded by default.
+	// +optional
+	MaxResults int32 `json:"maxResults,omitempty"`
+}
+
+const (
+	// JobSinkGroup and JobSinkKind identify the JobSinks of Knative
+	// Eventing, which start a Job for each event they receive.
+	JobSinkGroup = "sinks.knative.dev"
+	JobSinkKind  = "JobSink"
+)
+
+// IsJobSink returns whether the destination is a JobSink, to which the
+// changes are sent in batches so that each Job processes a batch.
+func IsJobSink(dest *duckv1.Destination) bool {
+	if dest == nil || dest.Ref == nil || dest.Ref.Kind != JobSinkKind {
+		return false
+	}
+	group := dest.Ref.Group
+	if group == "" {
+		group = strings.SplitN(dest.Ref.APIVersion, "/", 2)[0]
+	}
+	return group == JobSinkGroup
+}
+
+// BatchSpec bounds the changes coalesced by spec.batch.
+type BatchSpec struct {
+	// MaxCount is the maximum number of changes of a batch. Defaults to 100.
+	// +optional
+	MaxCount int32 `json:"maxCount,omitempty"`
+
+	// MaxWait is how long the first change of a batch waits for the others,
+	// as an ISO-8601 duration. Defaults to PT1S.
+	// +optional
+	MaxWait string `json:"maxWait,omitempty"`
+}
+
+// ContentMode is how events are encoded in the requests to the sink.
+type ContentMode string
+
+const (
+	// ContentModeBinary sends each event in its own request, in the
+	// CloudEvents HTTP binary content mode.
+	ContentModeBinary = ContentMode("binary")
+
+	// ContentModeStructured sends each event in its own request, in the
+	// CloudEvents HTTP structured content mode (application/cloudevents+json).
+	ContentModeStructured = ContentMode("structured")
+
+	// ContentModeBatch sends events in the CloudEvents JSON batch format
+	// (application/cloudevents-batch+json).
+	ContentModeBatch = ContentMode("batch")
+)
+
+// Ordering is the order in which the changes are delivered to the sink.
+type Ordering string
+
+const (
+	// OrderingUnordered lets the batches be delivered concurrently.
+	OrderingUnordered = Ordering("unordered")
+
+	// OrderingOrdered delivers the changes one request at a time, in the
+	// order of the changes feed, and holds the following changes while a
+	// delivery is retried.
+	OrderingOrdered = Ordering("ordered")
+
+	// OrderingGlobal delivers the changes as OrderingOrdered, from the single
+	// receive adapter holding the lease of the source, while a standby
+	// replica waits to take over. It trades throughput for a total order
+	// across all the documents of the database.
+	OrderingGlobal = Ordering("global")
+)
+
+// Ordered returns whether the changes are delivered in the order of the
+// changes feed.
+func (o Ordering) Ordered() bool {
+	return o == OrderingOrdered || o == OrderingGlobal
+}
+
+// DecodeErrorPolicy controls what happens to the changes of documents that
+// cannot be turned into events.
+type DecodeErrorPolicy string
+
+const (
+	// DecodeErrorSkip drops the change.
+	DecodeErrorSkip = DecodeErrorPolicy("skip")
+
+	// DecodeErrorDeadLetter sends an org.apache.couchdb.document.undecodable
+	// event describing the change to the dead letter sink.
+	DecodeErrorDeadLetter = DecodeErrorPolicy("deadLetter")
+
+	// DecodeErrorFail stops the feed at the change, which is read again until
+	// the document is fixed.
+	DecodeErrorFail = DecodeErrorPolicy("fail")
+)
+
+// DesignDocsPolicy controls which changes of design documents produce events.
+type DesignDocsPolicy string
+
+const (
+	// DesignDocsInclude reports the design documents along with the others.
+	DesignDocsInclude = DesignDocsPolicy("include")
+
+	// DesignDocsExclude suppresses the design documents.
+	DesignDocsExclude = DesignDocsPolicy("exclude")
+
+	// DesignDocsOnly reports the design documents only.
+	DesignDocsOnly = DesignDocsPolicy("only")
+)
+
+// designDocPrefix is the ID prefix of CouchDB design documents.
+const designDocPrefix = "_design/"
+
+// Reports returns whether the change of the document with the given ID is
+// reported under the policy.
+func (p DesignDocsPolicy) Reports(id string) bool {
+	design := strings.HasPrefix(id, designDocPrefix)
+	switch p {
+	case DesignDocsExclude:
+		return !design
+	case DesignDocsOnly:
+		return design
+	default:
+		return true
+	}
+}
+
+// WindowSpec is a range of update sequences of the changes feed.
+type WindowSpec struct {
+	// Since is the update sequence the feed starts after, "0" for the
+	// beginning of the database or "now". Defaults to "0".
+	// +optional
+	Since string `json:"since,omitempty"`
+
+	// Until is the last update sequence reported, or "now" for the update
+	// sequence of the database when the adapter starts. The adapter stops
+	// reporting changes once the window is exhausted.
+	// +optional
+	Until string `json:"until,omitempty"`
+}
+
+// ProxySpec configures the egress proxy used to reach CouchDB.
+type ProxySpec struct {
+	// URL is the address of the proxy, e.g. http://proxy.example.com:3128.
+	URL string `json:"url"`
+
+	// NoProxy lists the hosts and domain suffixes reached without the proxy.
+	// +optional
+	NoProxy []string `json:"noProxy,omitempty"`
 }
 
 // GetGroupVersionKind returns the GroupVersionKind.
@@ -116,6 +1166,198 @@ type CouchDbSourceStatus struct {
 	// * SinkURI - the current active sink URI that has been configured for the
 	//   Source.
 	duckv1.SourceStatus `json:",inline"`
+
+	// DeadLetterSinkURI is the resolved URI of spec.delivery.deadLetterSink.
+	// +optional
+	DeadLetterSinkURI *apis.URL `json:"deadLetterSinkUri,omitempty"`
+
+	// ReplyURI is the resolved URI of spec.reply.
+	// +optional
+	ReplyURI *apis.URL `json:"replyUri,omitempty"`
+
+	// SinkAudience is the OIDC audience of the sink, when it has one.
+	// +optional
+	SinkAudience *string `json:"sinkAudience,omitempty"`
+
+	// SinkCACerts are the PEM certificates the HTTPS sink is verified with,
+	// when it has any.
+	// +optional
+	SinkCACerts *string `json:"sinkCACerts,omitempty"`
+
+	// Auth is the identity the events are sent to the sink with, when it
+	// has an OIDC audience.
+	// +optional
+	Auth *AuthStatus `json:"auth,omitempty"`
+
+	// CloudEventExtensions are the names of the extension attributes the
+	// events of the source carry, e.g. couchdbsequence.
+	// +optional
+	CloudEventExtensions []string `json:"ceExtensions,omitempty"`
+
+	// FeedConfig is the changes feed request of the receive adapter, once
+	// defaulted.
+	// +optional
+	FeedConfig *FeedConfig `json:"feedConfig,omitempty"`
+
+	// Backfill is the progress of spec.backfill.
+	// +optional
+	Backfill *BackfillStatus `json:"backfill,omitempty"`
+
+	// Stats are the delivery statistics requested by spec.stats.
+	// +optional
+	Stats *DeliveryStats `json:"stats,omitempty"`
+
+	// LastSequence is the update sequence up to which the receive adapter
+	// delivered every change, updated along with the stats.
+	// +optional
+	LastSequence string `json:"lastSequence,omitempty"`
+
+	// LastEventTime is when the receive adapter last delivered an event,
+	// updated along with the stats.
+	// +optional
+	LastEventTime *metav1.Time `json:"lastEventTime,omitempty"`
+
+	// Jobs are the Jobs the JobSink the events are sent to started for the
+	// events of the source.
+	// +optional
+	Jobs *JobsStatus `json:"jobs,omitempty"`
+}
+
+// JobsStatus counts the Jobs a JobSink started, each for an event of the
+// source, or a batch of its changes with spec.batch. The Jobs of the other
+// clients of the JobSink are not counted, nor the ones removed once their
+// time to live expired.
+type JobsStatus struct {
+	// Active is the number of Jobs still running.
+	Active int32 `json:"active"`
+
+	// Succeeded is the number of Jobs that completed.
+	Succeeded int32 `json:"succeeded"`
+
+	// Failed is the number of Jobs that failed.
+	Failed int32 `json:"failed"`
+}
+
+// AuthStatus is the identity of the receive adapter towards the sink, as the
+// one of the Knative Eventing sources.
+type AuthStatus struct {
+	// ServiceAccountName is the service account whose OIDC tokens the
+	// events are sent with.
+	// +optional
+	ServiceAccountName *string `json:"serviceAccountName,omitempty"`
+}
+
+// FeedConfig is the changes feed request the receive adapter starts with.
+// Adapters resuming from a checkpoint start after it rather than after since.
+type FeedConfig struct {
+	// URL is the changes feed of the database, without credentials, e.g.
+	// https://couchdb.example.com/orders/_changes?feed=continuous&since=0.
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// Parameters are the query parameters of the request, e.g. feed, since
+	// and heartbeat.
+	Parameters map[string]string `json:"parameters"`
+}
+
+// DeliveryStats are the delivery statistics of a source since its creation.
+// The counters are pulled from the receive adapters, so the events delivered
+// by an adapter shortly before it stopped may be missing.
+type DeliveryStats struct {
+	// EventsDelivered is the number of events accepted by the sink.
+	EventsDelivered int64 `json:"eventsDelivered"`
+
+	// EventsDeadLettered is the number of events sent to the dead letter
+	// sink.
+	EventsDeadLettered int64 `json:"eventsDeadLettered"`
+
+	// LastDeliveryTime is when the sink last accepted an event.
+	// +optional
+	LastDeliveryTime *metav1.Time `json:"lastDeliveryTime,omitempty"`
+
+	// EventsPerMinute is the number of events accepted by the sink over the
+	// last minute.
+	EventsPerMinute int64 `json:"eventsPerMinute"`
+
+	// UpdateTime is when the statistics were last updated.
+	UpdateTime metav1.Time `json:"updateTime"`
+
+	// Adapters are the counters last observed on each receive adapter, so
+	// that only what they delivered since is added to the totals.
+	// +optional
+	Adapters []AdapterStatsObservation `json:"adapters,omitempty"`
+}
+
+// AdapterStatsObservation is the last observed run of a receive adapter.
+type AdapterStatsObservation struct {
+	// Pod is the name of the receive adapter pod.
+	Pod string `json:"pod"`
+
+	// StartTime is when the receive adapter started, which tells its
+	// restarts apart.
+	StartTime metav1.Time `json:"startTime"`
+
+	// EventsDelivered and EventsDeadLettered are the counters of that run.
+	EventsDelivered    int64 `json:"eventsDelivered"`
+	EventsDeadLettered int64 `json:"eventsDeadLettered"`
+}
+
+// BackfillState is the state of a backfill.
+type BackfillState string
+
+const (
+	// BackfillInProgress is the state of a backfill reporting the existing
+	// documents.
+	BackfillInProgress = BackfillState("InProgress")
+
+	// BackfillCompleted is the state of a backfill that reported all the
+	// existing documents.
+	BackfillCompleted = BackfillState("Completed")
+)
+
+// BackfillStatus is the progress of a backfill.
+type BackfillStatus struct {
+	// State is InProgress or Completed.
+	State BackfillState `json:"state"`
+
+	// Documents is the number of existing documents processed so far.
+	Documents int64 `json:"documents"`
+
+	// Total is the number of documents in the database when the backfill
+	// started.
+	Total int64 `json:"total"`
+
+	// Sequence is the update sequence of the database when the backfill
+	// started. The changes after it are reported once the backfill completes.
+	// +optional
+	Sequence string `json:"sequence,omitempty"`
+}
+
+// AdapterStatusPath is where the receive adapter serves its AdapterStatus.
+const AdapterStatusPath = "/status"
+
+// AdapterStatus is what the receive adapter reports on its status endpoint.
+// +k8s:deepcopy-gen=false
+type AdapterStatus struct {
+	// Backfill is the progress of spec.backfill.
+	Backfill *BackfillStatus `json:"backfill,omitempty"`
+
+	// Stats are the delivery statistics of the adapter since it started.
+	Stats *AdapterStats `json:"stats,omitempty"`
+
+	// Sequence is the update sequence up to which the adapter delivered
+	// every change, empty until it read one.
+	Sequence string `json:"sequence,omitempty"`
+}
+
+// AdapterStats are the delivery statistics of one run of a receive adapter.
+// +k8s:deepcopy-gen=false
+type AdapterStats struct {
+	StartTime          metav1.Time  `json:"startTime"`
+	EventsDelivered    int64        `json:"eventsDelivered"`
+	EventsDeadLettered int64        `json:"eventsDeadLettered"`
+	LastDeliveryTime   *metav1.Time `json:"lastDeliveryTime,omitempty"`
+	EventsPerMinute    int64        `json:"eventsPerMinute"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
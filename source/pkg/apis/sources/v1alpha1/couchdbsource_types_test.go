@@ -44,3 +44,102 @@ func TestCouchDbSourceGetStatus(t *testing.T) {
 		t.Errorf("GetStatus did not retrieve status. Got=%v Want=%v", config.GetStatus(), status)
 	}
 }
+
+func TestCouchDbSourceSpecChangeTypes(t *testing.T) {
+	testCases := map[string]struct {
+		spec CouchDbSourceSpec
+		want []string
+	}{
+		"default": {
+			want: []string{ChangeTypeUpdate, ChangeTypeDelete},
+		},
+		"conflicts": {
+			spec: CouchDbSourceSpec{Conflicts: true},
+			want: []string{ChangeTypeUpdate, ChangeTypeDelete, ChangeTypeConflict},
+		},
+		"conflicts without deletions": {
+			spec: CouchDbSourceSpec{Conflicts: true, DeletedDocs: DeletedDocsExclude},
+			want: []string{ChangeTypeUpdate, ChangeTypeConflict},
+		},
+		"conflicts with only deletions": {
+			spec: CouchDbSourceSpec{Conflicts: true, DeletedDocs: DeletedDocsOnly},
+			want: []string{ChangeTypeDelete},
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, tc.spec.ChangeTypes()); diff != "" {
+				t.Errorf("ChangeTypes() (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
+
+func TestCouchDbSourceSpecAdapterReplicas(t *testing.T) {
+	testCases := map[string]struct {
+		spec       CouchDbSourceSpec
+		want       int32
+		wantLeased bool
+	}{
+		"default": {
+			want: 1,
+		},
+		"global ordering": {
+			spec:       CouchDbSourceSpec{Ordering: OrderingGlobal},
+			want:       2,
+			wantLeased: true,
+		},
+		"high availability": {
+			spec:       CouchDbSourceSpec{HighAvailability: &HighAvailabilitySpec{Replicas: 3}},
+			want:       3,
+			wantLeased: true,
+		},
+		"global ordering with high availability": {
+			spec: CouchDbSourceSpec{
+				Ordering:         OrderingGlobal,
+				HighAvailability: &HighAvailabilitySpec{Replicas: 3},
+			},
+			want:       3,
+			wantLeased: true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			if got := tc.spec.AdapterReplicas(); got != tc.want {
+				t.Errorf("AdapterReplicas() = %d, want %d", got, tc.want)
+			}
+			if got := tc.spec.Leased(); got != tc.wantLeased {
+				t.Errorf("Leased() = %v, want %v", got, tc.wantLeased)
+			}
+		})
+	}
+}
+
+func TestIsJobSink(t *testing.T) {
+	for n, tc := range map[string]struct {
+		dest *duckv1.Destination
+		want bool
+	}{
+		"no sink": {},
+		"URI": {
+			dest: &duckv1.Destination{URI: validSink.URI},
+		},
+		"apiVersion": {
+			dest: &jobSink,
+			want: true,
+		},
+		"group": {
+			dest: &duckv1.Destination{Ref: &duckv1.KReference{Group: JobSinkGroup, Kind: JobSinkKind, Name: "batches"}},
+			want: true,
+		},
+		"another group": {
+			dest: &duckv1.Destination{Ref: &duckv1.KReference{APIVersion: "example.com/v1", Kind: JobSinkKind, Name: "batches"}},
+		},
+	} {
+		t.Run(n, func(t *testing.T) {
+			if got := IsJobSink(tc.dest); got != tc.want {
+				t.Errorf("IsJobSink() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -18,23 +18,837 @@ package v1alpha1
 
 import (
 	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/rickb777/date/period"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"knative.dev/pkg/apis"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/config"
 )
 
+// extensionNameRegexp matches valid CloudEvents extension attribute names.
+var extensionNameRegexp = regexp.MustCompile(`^[a-z0-9]+$`)
+
 func (c *CouchDbSource) Validate(ctx context.Context) *apis.FieldError {
-	return c.Spec.Validate(ctx).ViaField("spec")
+	var errs *apis.FieldError
+	if image, ok := c.Annotations[AdapterImageAnnotationKey]; ok && strings.TrimSpace(image) == "" {
+		errs = errs.Also(apis.ErrInvalidValue(image, AdapterImageAnnotationKey).ViaField("metadata", "annotations"))
+	}
+	if seq, ok := c.Annotations[ReplayFromAnnotationKey]; ok {
+		if _, err := SequenceNumber(seq); err != nil {
+			errs = errs.Also(apis.ErrInvalidValue(seq, ReplayFromAnnotationKey).ViaField("metadata", "annotations"))
+		}
+	}
+	if port, ok := c.Annotations[PprofPortAnnotationKey]; ok {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			errs = errs.Also(apis.ErrInvalidValue(port, PprofPortAnnotationKey).ViaField("metadata", "annotations"))
+		}
+	}
+	if _, fe := c.KedaAutoscaling(); fe != nil {
+		errs = errs.Also(fe.ViaField("metadata", "annotations"))
+	}
+	if c.Spec.Sharded() {
+		// The sequences and the checkpoint are those of a single database.
+		if _, ok := c.Annotations[ReplayFromAnnotationKey]; ok {
+			errs = errs.Also(apis.ErrGeneric("not supported with a databasePattern", ReplayFromAnnotationKey).ViaField("metadata", "annotations"))
+		}
+		if c.KedaAutoscaled() {
+			errs = errs.Also(apis.ErrGeneric("not supported with a databasePattern", AutoscalingClassAnnotationKey).ViaField("metadata", "annotations"))
+		}
+	}
+	return errs.Also(c.Spec.Validate(apis.WithinParent(ctx, c.ObjectMeta)).ViaField("spec"))
 }
 
 func (cs *CouchDbSourceSpec) Validate(ctx context.Context) *apis.FieldError {
 	var errs *apis.FieldError
 
-	// Validate sink
+	// Validate sink, which the sources of the namespaces with a default sink
+	// can leave out.
 	if cs.Sink == nil {
-		fe := apis.ErrMissingField("sink")
-		errs = errs.Also(fe)
+		if config.FromContextOrDefaults(ctx).DefaultSinks.Sink(apis.ParentMeta(ctx).Namespace) == nil {
+			errs = errs.Also(apis.ErrMissingField("sink"))
+		}
 	} else if fe := cs.Sink.Validate(ctx); fe != nil {
 		errs = errs.Also(fe.ViaField("sink"))
 	}
+
+	if cs.SinkCACerts != "" && !validCACerts(cs.SinkCACerts) {
+		fe := apis.ErrInvalidValue("<certificates>", "sinkCACerts")
+		fe.Details = "sinkCACerts must hold PEM encoded certificates"
+		errs = errs.Also(fe)
+	}
+
+	if cs.ServiceAccountName != "" {
+		if msgs := validation.IsDNS1123Subdomain(cs.ServiceAccountName); len(msgs) > 0 {
+			fe := apis.ErrInvalidValue(cs.ServiceAccountName, "serviceAccountName")
+			fe.Details = strings.Join(msgs, ", ")
+			errs = errs.Also(fe)
+		}
+	}
+
+	if cs.Delivery != nil {
+		errs = errs.Also(cs.Delivery.Validate(ctx).ViaField("delivery"))
+	}
+
+	if cs.Reply != nil {
+		errs = errs.Also(cs.Reply.Validate(ctx).ViaField("reply"))
+		// A batch gets a single response for all its events.
+		if cs.ContentMode == ContentModeBatch {
+			errs = errs.Also(apis.ErrGeneric("not supported by the batch content mode", "reply"))
+		}
+	}
+
+	// A JobSink reads a single event from each request.
+	if IsJobSink(cs.Sink) && cs.ContentMode == ContentModeBatch {
+		errs = errs.Also(apis.ErrGeneric("not supported by the JobSink sinks", "contentMode"))
+	}
+
+	if cs.CloudEventOverrides != nil {
+		for name := range cs.CloudEventOverrides.Extensions {
+			if !extensionNameRegexp.MatchString(name) {
+				errs = errs.Also(apis.ErrInvalidKeyName(name, "ceOverrides.extensions",
+					"extension names must only contain lowercase letters and digits"))
+			}
+		}
+	}
+
+	if cs.EventTypeTemplate != "" {
+		if err := validateEventTypeTemplate(cs.EventTypeTemplate, cs.Database, cs.ChangeTypes()); err != nil {
+			fe := apis.ErrInvalidValue(cs.EventTypeTemplate, "eventTypeTemplate")
+			fe.Details = err.Error()
+			errs = errs.Also(fe)
+		}
+	}
+
+	if cs.CloudEventSource != "" {
+		if _, err := url.Parse(cs.CloudEventSource); err != nil {
+			errs = errs.Also(apis.ErrInvalidValue(cs.CloudEventSource, "ceSource"))
+		}
+	}
+
+	if cs.SubjectTemplate != "" {
+		if err := validateSubjectTemplate(cs.SubjectTemplate, cs.Database); err != nil {
+			fe := apis.ErrInvalidValue(cs.SubjectTemplate, "subjectTemplate")
+			fe.Details = err.Error()
+			errs = errs.Also(fe)
+		}
+	}
+
+	if cs.IDTemplate != "" {
+		if err := validateEventIDTemplate(cs.IDTemplate, cs.Database); err != nil {
+			fe := apis.ErrInvalidValue(cs.IDTemplate, "idTemplate")
+			fe.Details = err.Error()
+			errs = errs.Also(fe)
+		}
+	}
+
+	if cs.Match != "" {
+		if _, err := ParseMatch(cs.Match); err != nil {
+			fe := apis.ErrInvalidValue(cs.Match, "match")
+			fe.Details = err.Error()
+			errs = errs.Also(fe)
+		}
+	}
+
+	for i, p := range cs.Partitions {
+		// Partition names are document ID prefixes: they can neither start
+		// with an underscore nor contain the separating colon.
+		if p == "" || strings.HasPrefix(p, "_") || strings.ContainsAny(p, ":,") {
+			errs = errs.Also(apis.ErrInvalidArrayValue(p, "partitions", i))
+		}
+	}
+
+	if cs.Grouping != nil {
+		errs = errs.Also(cs.Grouping.Validate(ctx).ViaField("grouping"))
+	}
+
+	if cs.Access != nil {
+		errs = errs.Also(cs.Access.Validate(ctx).ViaField("access"))
+	}
+
+	switch cs.Feed {
+	case "", FeedNormal, FeedContinuous:
+	default:
+		fe := apis.ErrInvalidValue(cs.Feed, "feed")
+		fe.Details = fmt.Sprintf("the feed must be %q or %q", FeedContinuous, FeedNormal)
+		errs = errs.Also(fe)
+	}
+	errs = errs.Also(validateFeedTiming(cs.Feed, cs.Heartbeat, "heartbeat"))
+	errs = errs.Also(validateFeedTiming(cs.Feed, cs.Timeout, "timeout"))
+	if idle := config.FromContextOrDefaults(ctx).Feed.LoadBalancerIdleTimeout(); idle > 0 {
+		errs = errs.Also(validateHeartbeatIdle(cs.Heartbeat, idle))
+	}
+	if cs.Polling != nil {
+		if cs.Feed != FeedNormal {
+			errs = errs.Also(apis.ErrGeneric("only supported by the normal feed", "polling"))
+		}
+		errs = errs.Also(cs.Polling.Validate(ctx).ViaField("polling"))
+	}
+	if cs.Reconnect != nil {
+		errs = errs.Also(cs.Reconnect.Validate(ctx).ViaField("reconnect"))
+	}
+	if cs.Heartbeat != "" && cs.Timeout != "" {
+		errs = errs.Also(apis.ErrMultipleOneOf("heartbeat", "timeout"))
+	}
+
+	if cs.Since != "" && cs.Since != SequenceNow {
+		if _, err := SequenceNumber(cs.Since); err != nil {
+			errs = errs.Also(apis.ErrInvalidValue(cs.Since, "since"))
+		}
+	}
+
+	if cs.Window != nil {
+		errs = errs.Also(cs.Window.Validate(ctx).ViaField("window"))
+		if cs.Since != "" && cs.Window.Since != "" {
+			errs = errs.Also(apis.ErrMultipleOneOf("since", "window.since"))
+		}
+	}
+
+	if cs.DevInstance && cs.CouchDbCredentials.Name != "" {
+		errs = errs.Also(apis.ErrMultipleOneOf("credentials", "devInstance"))
+	}
+
+	errs = errs.Also(cs.validateDatabasePattern())
+
+	if cs.Proxy != nil {
+		errs = errs.Also(cs.Proxy.Validate(ctx).ViaField("proxy"))
+	}
+
+	switch cs.Auth {
+	case "", AuthBasic, AuthSession:
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(cs.Auth, "auth"))
+	}
+
+	switch cs.DeletedDocs {
+	case "", DeletedDocsInclude, DeletedDocsExclude, DeletedDocsOnly:
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(cs.DeletedDocs, "deletedDocs"))
+	}
+
+	switch cs.DesignDocs {
+	case "", DesignDocsInclude, DesignDocsExclude, DesignDocsOnly:
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(cs.DesignDocs, "designDocs"))
+	}
+
+	switch cs.OnDecodeError {
+	case "", DecodeErrorSkip, DecodeErrorFail:
+	case DecodeErrorDeadLetter:
+		if cs.Delivery == nil || cs.Delivery.DeadLetterSink == nil {
+			errs = errs.Also(apis.ErrMissingField("delivery.deadLetterSink"))
+		}
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(cs.OnDecodeError, "onDecodeError"))
+	}
+
+	if cs.Backfill && cs.Window != nil {
+		errs = errs.Also(apis.ErrMultipleOneOf("backfill", "window"))
+	}
+	if cs.Backfill && cs.Since != "" {
+		errs = errs.Also(apis.ErrMultipleOneOf("backfill", "since"))
+	}
+
+	if cs.SeqInterval < 0 {
+		errs = errs.Also(apis.ErrInvalidValue(cs.SeqInterval, "seqInterval"))
+	}
+
+	if cs.Batch != nil {
+		errs = errs.Also(cs.Batch.Validate(ctx).ViaField("batch"))
+		if cs.Grouping != nil && cs.Grouping.Batch {
+			errs = errs.Also(apis.ErrMultipleOneOf("batch", "grouping.batch"))
+		}
+	}
+	if cs.RateLimit != nil {
+		errs = errs.Also(cs.RateLimit.Validate(ctx).ViaField("rateLimit"))
+	}
+
+	if cs.Limits != nil {
+		errs = errs.Also(cs.Limits.Validate(ctx).ViaField("limits"))
+	}
+
+	if cs.Stats != nil {
+		errs = errs.Also(cs.Stats.Validate(ctx).ViaField("stats"))
+	}
+
+	if cs.HealthMetrics != nil {
+		errs = errs.Also(cs.HealthMetrics.Validate(ctx).ViaField("healthMetrics"))
+	}
+
+	if cs.LagMetrics != nil {
+		errs = errs.Also(cs.LagMetrics.Validate(ctx).ViaField("lagMetrics"))
+	}
+	if cs.Liveness != nil {
+		errs = errs.Also(cs.Liveness.Validate(ctx).ViaField("liveness"))
+	}
+
+	if cs.HighAvailability != nil {
+		errs = errs.Also(cs.HighAvailability.Validate(ctx).ViaField("highAvailability"))
+		// A window is replayed by a Job, which runs a single pod.
+		if cs.Window != nil {
+			errs = errs.Also(apis.ErrMultipleOneOf("highAvailability", "window"))
+		}
+	}
+
+	if cs.Template != nil {
+		errs = errs.Also(cs.Template.Validate(ctx).ViaField("template"))
+	}
+
+	if cs.Join != nil {
+		errs = errs.Also(cs.Join.Validate(ctx).ViaField("join"))
+	}
+
+	if cs.Buffer != nil {
+		errs = errs.Also(cs.Buffer.Validate(ctx).ViaField("buffer"))
+		// The volume of the buffer is mounted by a single pod.
+		if cs.HighAvailability != nil {
+			errs = errs.Also(apis.ErrMultipleOneOf("buffer", "highAvailability"))
+		}
+		if cs.Ordering == OrderingGlobal {
+			errs = errs.Also(apis.ErrGeneric("not supported by the global ordering", "buffer"))
+		}
+		// A Job would complete with events left in the buffer.
+		if cs.Window != nil {
+			errs = errs.Also(apis.ErrMultipleOneOf("buffer", "window"))
+		}
+		if cs.ContentMode == ContentModeBatch {
+			errs = errs.Also(apis.ErrGeneric("not supported by the batch content mode", "buffer"))
+		}
+	}
+
+	switch cs.ContentMode {
+	case "", ContentModeBinary, ContentModeStructured, ContentModeBatch:
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(cs.ContentMode, "contentMode"))
+	}
+
+	switch cs.Ordering {
+	case "", OrderingUnordered:
+	case OrderingOrdered, OrderingGlobal:
+		// Groups hold their changes back while later ones are delivered.
+		if cs.Grouping != nil {
+			errs = errs.Also(apis.ErrGeneric("not supported by the ordered delivery", "grouping"))
+		}
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(cs.Ordering, "ordering"))
+	}
+
+	switch cs.Attachments {
+	case "", AttachmentsNone, AttachmentsInline, AttachmentsReference:
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(cs.Attachments, "attachments"))
+	}
+
+	switch cs.ApplyMode {
+	case "", ApplyModeAutomatic, ApplyModeManual:
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(cs.ApplyMode, "applyMode"))
+	}
+
+	switch cs.Payload {
+	case "", PayloadRevisions:
+	case PayloadDiff, PayloadMetadata:
+		// The events with attachments carry the whole document.
+		if cs.Attachments != "" {
+			errs = errs.Also(apis.ErrMultipleOneOf("attachments", "payload"))
+		}
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(cs.Payload, "payload"))
+	}
+
+	if len(cs.Projection) > 0 {
+		errs = errs.Also(validateProjection(cs.Projection))
+		if cs.Attachments != "" {
+			errs = errs.Also(apis.ErrMultipleOneOf("attachments", "projection"))
+		}
+		if cs.Payload != "" && cs.Payload != PayloadRevisions {
+			errs = errs.Also(apis.ErrMultipleOneOf("payload", "projection"))
+		}
+	}
+	return errs
+}
+
+func (ps *ProxySpec) Validate(ctx context.Context) *apis.FieldError {
+	if ps.URL == "" {
+		return apis.ErrMissingField("url")
+	}
+	u, err := url.Parse(ps.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return apis.ErrInvalidValue(ps.URL, "url")
+	}
+	return nil
+}
+
+func (gs *GroupingSpec) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+	if gs.Field == "" {
+		errs = errs.Also(apis.ErrMissingField("field"))
+	}
+	if gs.Delay != "" {
+		if _, err := period.Parse(gs.Delay); err != nil {
+			errs = errs.Also(apis.ErrInvalidValue(gs.Delay, "delay"))
+		}
+	}
+	return errs
+}
+
+func (js *JoinSpec) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+	if js.Database == "" {
+		errs = errs.Also(apis.ErrMissingField("database"))
+	}
+	if js.Key == "" {
+		errs = errs.Also(apis.ErrMissingField("key"))
+	}
+	// The top-level fields starting with an underscore are reserved by
+	// CouchDB.
+	if strings.HasPrefix(js.Field(), "_") {
+		errs = errs.Also(apis.ErrInvalidValue(js.As, "as"))
+	}
+	if js.CacheSize < 0 {
+		errs = errs.Also(apis.ErrInvalidValue(js.CacheSize, "cacheSize"))
+	}
+	if js.MaxAge != "" {
+		if _, err := period.Parse(js.MaxAge); err != nil {
+			errs = errs.Also(apis.ErrInvalidValue(js.MaxAge, "maxAge"))
+		}
+	}
+	switch js.OnMissing {
+	case "", JoinMissingEmit, JoinMissingSkip:
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(js.OnMissing, "onMissing"))
+	}
+	return errs
+}
+
+func (bs *BufferSpec) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+	if bs.Size.Sign() <= 0 {
+		errs = errs.Also(apis.ErrInvalidValue(bs.Size.String(), "size"))
+	}
+	switch bs.OnFull {
+	case "", BufferFullBlock, BufferFullDropOldest:
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(bs.OnFull, "onFull"))
+	}
+	return errs
+}
+
+func (as *AccessSpec) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+	if len(as.Roles) == 0 {
+		errs = errs.Also(apis.ErrMissingField("roles"))
+	}
+	for i, r := range as.Roles {
+		// The roles are handed to the receive adapter as a comma separated
+		// list.
+		if r == "" || strings.Contains(r, ",") {
+			errs = errs.Also(apis.ErrInvalidArrayValue(r, "roles", i))
+		}
+	}
+	return errs
+}
+
+func (ss *StatsSpec) Validate(ctx context.Context) *apis.FieldError {
+	if ss.Interval == "" {
+		return nil
+	}
+	p, err := period.Parse(ss.Interval)
+	if err != nil {
+		return apis.ErrInvalidValue(ss.Interval, "interval")
+	}
+	if p.DurationApprox() < MinStatsInterval {
+		fe := apis.ErrInvalidValue(ss.Interval, "interval")
+		fe.Details = "must be at least PT10S"
+		return fe
+	}
+	return nil
+}
+
+func (hs *HealthMetricsSpec) Validate(ctx context.Context) *apis.FieldError {
+	if hs.Interval == "" {
+		return nil
+	}
+	p, err := period.Parse(hs.Interval)
+	if err != nil {
+		return apis.ErrInvalidValue(hs.Interval, "interval")
+	}
+	if p.DurationApprox() < MinHealthMetricsInterval {
+		fe := apis.ErrInvalidValue(hs.Interval, "interval")
+		fe.Details = "must be at least PT10S"
+		return fe
+	}
+	return nil
+}
+
+func (ls *LagMetricsSpec) Validate(ctx context.Context) *apis.FieldError {
+	if ls.Interval == "" {
+		return nil
+	}
+	p, err := period.Parse(ls.Interval)
+	if err != nil {
+		return apis.ErrInvalidValue(ls.Interval, "interval")
+	}
+	if p.DurationApprox() < MinLagMetricsInterval {
+		fe := apis.ErrInvalidValue(ls.Interval, "interval")
+		fe.Details = "must be at least PT10S"
+		return fe
+	}
+	return nil
+}
+
+func (ls *LivenessSpec) Validate(ctx context.Context) *apis.FieldError {
+	if ls.DisconnectedThreshold == "" {
+		return nil
+	}
+	p, err := period.Parse(ls.DisconnectedThreshold)
+	if err != nil {
+		return apis.ErrInvalidValue(ls.DisconnectedThreshold, "disconnectedThreshold")
+	}
+	if p.DurationApprox() < MinDisconnectedThreshold {
+		fe := apis.ErrInvalidValue(ls.DisconnectedThreshold, "disconnectedThreshold")
+		fe.Details = "must be at least PT30S"
+		return fe
+	}
+	return nil
+}
+
+func (ws *WindowSpec) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+	if ws.Since != "" && ws.Since != SequenceNow {
+		if _, err := SequenceNumber(ws.Since); err != nil {
+			errs = errs.Also(apis.ErrInvalidValue(ws.Since, "since"))
+		}
+	}
+	if ws.Until != "" && ws.Until != SequenceNow {
+		if _, err := SequenceNumber(ws.Until); err != nil {
+			errs = errs.Also(apis.ErrInvalidValue(ws.Until, "until"))
+		}
+	}
+	return errs
+}
+
+func (ls *LimitsSpec) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+	if ls.MaxLineBytes < 0 {
+		errs = errs.Also(apis.ErrInvalidValue(ls.MaxLineBytes, "maxLineBytes"))
+	}
+	if ls.MaxJSONDepth < 0 {
+		errs = errs.Also(apis.ErrInvalidValue(ls.MaxJSONDepth, "maxJSONDepth"))
+	}
+	if ls.MaxResults < 0 {
+		errs = errs.Also(apis.ErrInvalidValue(ls.MaxResults, "maxResults"))
+	}
+	return errs
+}
+
+func (ps *PollingSpec) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+	min, max := DefaultMinPollInterval, DefaultMaxPollInterval
+	if ps.MinInterval != "" {
+		p, err := period.Parse(ps.MinInterval)
+		if err != nil || p.DurationApprox() <= 0 {
+			errs = errs.Also(apis.ErrInvalidValue(ps.MinInterval, "minInterval"))
+		} else {
+			min = p.DurationApprox()
+		}
+	}
+	if ps.MaxInterval != "" {
+		p, err := period.Parse(ps.MaxInterval)
+		if err != nil || p.DurationApprox() <= 0 {
+			errs = errs.Also(apis.ErrInvalidValue(ps.MaxInterval, "maxInterval"))
+		} else {
+			max = p.DurationApprox()
+		}
+	}
+	if errs == nil && ps.MaxInterval != "" && min > max {
+		fe := apis.ErrInvalidValue(ps.MaxInterval, "maxInterval")
+		fe.Details = "must not be shorter than minInterval"
+		errs = errs.Also(fe)
+	}
+	return errs
+}
+
+func (rs *ReconnectSpec) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+	initial, max := DefaultReconnectInitialDelay, DefaultReconnectMaxDelay
+	if rs.InitialDelay != "" {
+		p, err := period.Parse(rs.InitialDelay)
+		if err != nil || p.DurationApprox() <= 0 {
+			errs = errs.Also(apis.ErrInvalidValue(rs.InitialDelay, "initialDelay"))
+		} else {
+			initial = p.DurationApprox()
+		}
+	}
+	if rs.MaxDelay != "" {
+		p, err := period.Parse(rs.MaxDelay)
+		if err != nil || p.DurationApprox() <= 0 {
+			errs = errs.Also(apis.ErrInvalidValue(rs.MaxDelay, "maxDelay"))
+		} else {
+			max = p.DurationApprox()
+		}
+	}
+	if errs == nil && rs.MaxDelay != "" && initial > max {
+		fe := apis.ErrInvalidValue(rs.MaxDelay, "maxDelay")
+		fe.Details = "must not be shorter than initialDelay"
+		errs = errs.Also(fe)
+	}
+	return errs
+}
+
+func (bs *BatchSpec) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+	if bs.MaxCount < 0 {
+		errs = errs.Also(apis.ErrInvalidValue(bs.MaxCount, "maxCount"))
+	}
+	if bs.MaxWait != "" {
+		if _, err := period.Parse(bs.MaxWait); err != nil {
+			errs = errs.Also(apis.ErrInvalidValue(bs.MaxWait, "maxWait"))
+		}
+	}
+	return errs
+}
+
+func (hs *HighAvailabilitySpec) Validate(ctx context.Context) *apis.FieldError {
+	if hs.Replicas < 2 {
+		fe := apis.ErrInvalidValue(hs.Replicas, "replicas")
+		fe.Details = "must be at least 2"
+		return fe
+	}
+	return nil
+}
+
+// reservedEnv are the environment variables configuring the receive adapter,
+// and reservedEnvPrefixes their prefixes.
+var (
+	reservedEnv         = []string{"EVENT_SOURCE", "NAME", "NAMESPACE", "METRICS_DOMAIN"}
+	reservedEnvPrefixes = []string{"COUCHDB_", "DELIVERY_", "K_"}
+)
+
+func reservedEnvName(name string) bool {
+	for _, prefix := range reservedEnvPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	for _, reserved := range reservedEnv {
+		if name == reserved {
+			return true
+		}
+	}
+	return false
+}
+
+func (ts *AdapterTemplateSpec) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+	if ts.Metadata != nil {
+		for k, v := range ts.Metadata.Labels {
+			if msgs := validation.IsQualifiedName(k); len(msgs) > 0 {
+				errs = errs.Also(apis.ErrInvalidKeyName(k, "metadata.labels", msgs...))
+			} else if msgs := validation.IsValidLabelValue(v); len(msgs) > 0 {
+				fe := apis.ErrInvalidValue(v, "metadata.labels."+k)
+				fe.Details = strings.Join(msgs, ", ")
+				errs = errs.Also(fe)
+			}
+		}
+		for k := range ts.Metadata.Annotations {
+			if msgs := validation.IsQualifiedName(strings.ToLower(k)); len(msgs) > 0 {
+				errs = errs.Also(apis.ErrInvalidKeyName(k, "metadata.annotations", msgs...))
+			}
+		}
+	}
+	for i, e := range ts.Env {
+		switch {
+		case e.Name == "":
+			errs = errs.Also(apis.ErrMissingField("name").ViaFieldIndex("env", i))
+		case reservedEnvName(e.Name):
+			fe := apis.ErrInvalidValue(e.Name, "name")
+			fe.Details = "reserved for the configuration of the receive adapter"
+			errs = errs.Also(fe.ViaFieldIndex("env", i))
+		}
+	}
+	return errs
+}
+
+func (rs *RateLimitSpec) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+	if rs.EventsPerSecond <= 0 {
+		errs = errs.Also(apis.ErrInvalidValue(rs.EventsPerSecond, "eventsPerSecond"))
+	}
+	if rs.Burst < 0 {
+		errs = errs.Also(apis.ErrInvalidValue(rs.Burst, "burst"))
+	}
+	return errs
+}
+
+// validateFeedTiming checks a heartbeat or timeout of the changes feed. Both
+// only apply to the continuous feed, and must stay within MaxFeedTiming so
+// that load balancers do not drop the idle connection first.
+func validateFeedTiming(feed FeedType, value, field string) *apis.FieldError {
+	if value == "" {
+		return nil
+	}
+	if feed == FeedNormal {
+		return apis.ErrGeneric("only supported by the continuous feed", field)
+	}
+	p, err := period.Parse(value)
+	if err != nil {
+		fe := apis.ErrInvalidValue(value, field)
+		fe.Details = err.Error()
+		return fe
+	}
+	if d := p.DurationApprox(); d < MinFeedTiming || d > MaxFeedTiming {
+		return apis.ErrOutOfBoundsValue(value, isoDuration(MinFeedTiming), isoDuration(MaxFeedTiming), field)
+	}
+	return nil
+}
+
+// validateHeartbeatIdle checks that the heartbeat is below the idle timeout
+// of the load balancers in front of CouchDB, which otherwise drop the
+// connection of the feed while it waits for changes.
+func validateHeartbeatIdle(heartbeat string, idle time.Duration) *apis.FieldError {
+	if heartbeat == "" {
+		return nil
+	}
+	// The invalid heartbeats are reported by validateFeedTiming.
+	p, err := period.Parse(heartbeat)
+	if err != nil || p.DurationApprox() < idle {
+		return nil
+	}
+	fe := apis.ErrInvalidValue(heartbeat, "heartbeat")
+	fe.Details = fmt.Sprintf("the heartbeat must be below the %v idle timeout of the load balancers in front of CouchDB", idle)
+	return fe
+}
+
+// validateEventTypeTemplate checks that the template parses and renders a
+// type for every reported change type.
+func validateEventTypeTemplate(text, database string, changeTypes []string) error {
+	tmpl, err := ParseEventTypeTemplate(text)
+	if err != nil {
+		return err
+	}
+	for _, ct := range changeTypes {
+		if _, err := tmpl.Render(EventTypeData{ChangeType: ct, Database: database}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSubjectTemplate checks that the template parses and renders.
+func validateSubjectTemplate(text, database string) error {
+	tmpl, err := ParseSubjectTemplate(text)
+	if err != nil {
+		return err
+	}
+	_, err = tmpl.Render(SubjectData{ID: "id", Rev: "1-rev", Database: database})
+	return err
+}
+
+// validateProjection checks that the field names are set and that their
+// expressions parse.
+func validateProjection(fields map[string]string) *apis.FieldError {
+	var errs *apis.FieldError
+	for name, expr := range fields {
+		if name == "" {
+			errs = errs.Also(apis.ErrInvalidKeyName(name, "projection", "field names must not be empty"))
+			continue
+		}
+		if _, err := ParseProjection(map[string]string{name: expr}); err != nil {
+			fe := apis.ErrInvalidValue(expr, apis.CurrentField).ViaFieldKey("projection", name)
+			fe.Details = err.Error()
+			errs = errs.Also(fe)
+		}
+	}
+	return errs
+}
+
+// validateEventIDTemplate checks that the template parses and renders.
+func validateEventIDTemplate(text, database string) error {
+	tmpl, err := ParseEventIDTemplate(text)
+	if err != nil {
+		return err
+	}
+	_, err = tmpl.Render(SubjectData{ID: "id", Rev: "1-rev", Database: database})
+	return err
+}
+
+// validCACerts is whether the PEM data holds certificates, and only those.
+func validCACerts(data string) bool {
+	rest := []byte(data)
+	found := false
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return false
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return false
+		}
+		found = true
+	}
+	return found && strings.TrimSpace(string(rest)) == ""
+}
+
+// validateDatabasePattern validates the glob of the databases of a sharded
+// source, and leaves out the features bound to a single database or a single
+// receive adapter pod.
+func (cs *CouchDbSourceSpec) validateDatabasePattern() *apis.FieldError {
+	if !cs.Sharded() {
+		if cs.Shards != 0 {
+			return apis.ErrGeneric("only supported with a databasePattern", "shards")
+		}
+		return nil
+	}
+	var errs *apis.FieldError
+	if cs.Database != "" {
+		errs = errs.Also(apis.ErrMultipleOneOf("database", "databasePattern"))
+	}
+	if _, err := path.Match(cs.DatabasePattern, ""); err != nil {
+		fe := apis.ErrInvalidValue(cs.DatabasePattern, "databasePattern")
+		fe.Details = err.Error()
+		errs = errs.Also(fe)
+	}
+	if cs.Shards < 0 {
+		errs = errs.Also(apis.ErrInvalidValue(cs.Shards, "shards"))
+	}
+	if cs.Since != "" && cs.Since != "0" && cs.Since != SequenceNow {
+		fe := apis.ErrInvalidValue(cs.Since, "since")
+		fe.Details = `the databases of a databasePattern only start from "0" or "now"`
+		errs = errs.Also(fe)
+	}
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{"devInstance", cs.DevInstance},
+		{"window", cs.Window != nil},
+		{"backfill", cs.Backfill},
+		{"stats", cs.Stats != nil},
+		{"healthMetrics", cs.HealthMetrics != nil},
+		{"lagMetrics", cs.LagMetrics != nil},
+		{"highAvailability", cs.HighAvailability != nil},
+		{"databaseUpdates", cs.DatabaseUpdates},
+		{"buffer", cs.Buffer != nil},
+		{"applyMode", cs.ApplyMode == ApplyModeManual},
+		{"ordering", cs.Ordering == OrderingGlobal},
+	} {
+		if f.set {
+			errs = errs.Also(apis.ErrGeneric("not supported with a databasePattern", f.name))
+		}
+	}
 	return errs
 }
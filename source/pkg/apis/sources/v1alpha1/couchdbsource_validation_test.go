@@ -18,14 +18,46 @@ package v1alpha1
 
 import (
 	"context"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
 	"knative.dev/pkg/webhook/resourcesemantics"
 
 	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/config"
 )
 
+var validSink = duckv1.Destination{
+	URI: apis.HTTP("example.com"),
+}
+
+var jobSink = duckv1.Destination{
+	Ref: &duckv1.KReference{APIVersion: "sinks.knative.dev/v1alpha1", Kind: "JobSink", Name: "batches"},
+}
+
+// caCerts is a self-signed CA certificate.
+const caCerts = `-----BEGIN CERTIFICATE-----
+MIIBlDCCATmgAwIBAgIUEb6m7+KYJRnZDrFtARDYtTDe72owCgYIKoZIzj0EAwIw
+HjEcMBoGA1UEAwwTa25hdGl2ZS1ldmVudGluZy1jYTAgFw0yNjEwMTYxNTUwNDla
+GA8yMTI2MDkyMjE1NTA0OVowHjEcMBoGA1UEAwwTa25hdGl2ZS1ldmVudGluZy1j
+YTBZMBMGByqGSM49AgEGCCqGSM49AwEHA0IABMkEyYVQs9oemLwCm6KXp/JFVE1X
+Yo+prBZdRQiCmM+aqgKxmcaeK7e1uGWJCB4YvCcY0uUMr7wC2j+GnlXM/5+jUzBR
+MB0GA1UdDgQWBBRChLRCLW49EAKzH5PLNaUOvUteCTAfBgNVHSMEGDAWgBRChLRC
+LW49EAKzH5PLNaUOvUteCTAPBgNVHRMBAf8EBTADAQH/MAoGCCqGSM49BAMCA0kA
+MEYCIQD+7S0esRbk0R05HluC21K0IjwOZOpqCPfejhVIVqh8mwIhAPYdmhc3lgPL
+tcuphSbN5G9gktbi38MkdbIU5PSwSukd
+-----END CERTIFICATE-----
+`
+
 func TestCouchDbSourceValidation(t *testing.T) {
 	testCases := map[string]struct {
 		cr   resourcesemantics.GenericCRD
@@ -42,6 +74,901 @@ func TestCouchDbSourceValidation(t *testing.T) {
 				return errs
 			}(),
 		},
+		"invalid auth": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink: &validSink,
+					Auth: AuthMethod("digest"),
+				},
+			},
+			want: apis.ErrInvalidValue("digest", "spec.auth"),
+		},
+		"unknown feed": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink: &validSink,
+					Feed: FeedType("longpoll"),
+				},
+			},
+			want: &apis.FieldError{
+				Message: "invalid value: longpoll",
+				Paths:   []string{"spec.feed"},
+				Details: `the feed must be "continuous" or "normal"`,
+			},
+		},
+		"sink CA certificates": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:        &validSink,
+					SinkCACerts: caCerts,
+				},
+			},
+		},
+		"invalid sink CA certificates": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:        &validSink,
+					SinkCACerts: "not a certificate",
+				},
+			},
+			want: &apis.FieldError{
+				Message: "invalid value: <certificates>",
+				Paths:   []string{"spec.sinkCACerts"},
+				Details: "sinkCACerts must hold PEM encoded certificates",
+			},
+		},
+		"invalid serviceAccountName": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:               &validSink,
+					ServiceAccountName: "Adapter_SA",
+				},
+			},
+			want: &apis.FieldError{
+				Message: "invalid value: Adapter_SA",
+				Paths:   []string{"spec.serviceAccountName"},
+				Details: strings.Join(validation.IsDNS1123Subdomain("Adapter_SA"), ", "),
+			},
+		},
+		"buffer": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:   &validSink,
+					Buffer: &BufferSpec{Size: resource.MustParse("1Gi"), OnFull: BufferFullDropOldest},
+				},
+			},
+		},
+		"invalid buffer": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:   &validSink,
+					Buffer: &BufferSpec{OnFull: "spill"},
+				},
+			},
+			want: apis.ErrInvalidValue("0", "spec.buffer.size").Also(
+				apis.ErrInvalidValue("spill", "spec.buffer.onFull")),
+		},
+		"highly available buffer": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:             &validSink,
+					Buffer:           &BufferSpec{Size: resource.MustParse("1Gi")},
+					HighAvailability: &HighAvailabilitySpec{Replicas: 2},
+					ContentMode:      ContentModeBatch,
+				},
+			},
+			want: apis.ErrMultipleOneOf("spec.buffer", "spec.highAvailability").Also(
+				apis.ErrGeneric("not supported by the batch content mode", "spec.buffer")),
+		},
+		"globally ordered buffer": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:     &validSink,
+					Buffer:   &BufferSpec{Size: resource.MustParse("1Gi")},
+					Ordering: OrderingGlobal,
+				},
+			},
+			want: apis.ErrGeneric("not supported by the global ordering", "spec.buffer"),
+		},
+		"reply": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:  &validSink,
+					Reply: &duckv1.Destination{URI: apis.HTTP("replies.example.com")},
+				},
+			},
+		},
+		"invalid reply": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:  &validSink,
+					Reply: &duckv1.Destination{},
+				},
+			},
+			want: apis.ErrGeneric("expected at least one, got none", "spec.reply.ref", "spec.reply.uri"),
+		},
+		"batched reply": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:        &validSink,
+					ContentMode: ContentModeBatch,
+					Reply:       &validSink,
+				},
+			},
+			want: apis.ErrGeneric("not supported by the batch content mode", "spec.reply"),
+		},
+		"invalid grouping": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:     &validSink,
+					Grouping: &GroupingSpec{Delay: "1s"},
+				},
+			},
+			want: apis.ErrMissingField("spec.grouping.field").Also(
+				apis.ErrInvalidValue("1s", "spec.grouping.delay")),
+		},
+		"invalid deletedDocs": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:        &validSink,
+					DeletedDocs: DeletedDocsPolicy("never"),
+				},
+			},
+			want: apis.ErrInvalidValue("never", "spec.deletedDocs"),
+		},
+		"invalid designDocs": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:       &validSink,
+					DesignDocs: DesignDocsPolicy("hidden"),
+				},
+			},
+			want: apis.ErrInvalidValue("hidden", "spec.designDocs"),
+		},
+		"invalid onDecodeError": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:          &validSink,
+					OnDecodeError: DecodeErrorPolicy("crash"),
+				},
+			},
+			want: apis.ErrInvalidValue("crash", "spec.onDecodeError"),
+		},
+		"onDecodeError deadLetter without dead letter sink": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:          &validSink,
+					OnDecodeError: DecodeErrorDeadLetter,
+				},
+			},
+			want: apis.ErrMissingField("spec.delivery.deadLetterSink"),
+		},
+		"onDecodeError deadLetter": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:          &validSink,
+					OnDecodeError: DecodeErrorDeadLetter,
+					Delivery:      &eventingduckv1.DeliverySpec{DeadLetterSink: &validSink},
+				},
+			},
+		},
+		"heartbeat on the normal feed": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:      &validSink,
+					Feed:      FeedNormal,
+					Heartbeat: "PT10S",
+				},
+			},
+			want: apis.ErrGeneric("only supported by the continuous feed", "spec.heartbeat"),
+		},
+		"heartbeat above the load balancer idle timeout": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:      &validSink,
+					Feed:      FeedContinuous,
+					Heartbeat: "PT2M",
+				},
+			},
+			want: apis.ErrOutOfBoundsValue("PT2M", "PT1S", "PT50S", "spec.heartbeat"),
+		},
+		"heartbeat with timeout": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:      &validSink,
+					Feed:      FeedContinuous,
+					Heartbeat: "PT10S",
+					Timeout:   "PT30S",
+				},
+			},
+			want: apis.ErrMultipleOneOf("spec.heartbeat", "spec.timeout"),
+		},
+		"couchdb 1.x since": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:  &validSink,
+					Since: "42",
+				},
+			},
+		},
+		"bigcouch since": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:  &validSink,
+					Since: `[42,"g1AAAA"]`,
+				},
+			},
+		},
+		"invalid since": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:  &validSink,
+					Since: "yesterday",
+				},
+			},
+			want: apis.ErrInvalidValue("yesterday", "spec.since"),
+		},
+		"since with window since": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:   &validSink,
+					Since:  "now",
+					Window: &WindowSpec{Since: "10", Until: "now"},
+				},
+			},
+			want: apis.ErrMultipleOneOf("spec.since", "spec.window.since"),
+		},
+		"backfill with since": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:     &validSink,
+					Backfill: true,
+					Since:    "now",
+				},
+			},
+			want: apis.ErrMultipleOneOf("spec.backfill", "spec.since"),
+		},
+		"backfill with window": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:     &validSink,
+					Backfill: true,
+					Window:   &WindowSpec{Since: "now"},
+				},
+			},
+			want: apis.ErrMultipleOneOf("spec.backfill", "spec.window"),
+		},
+		"negative limits": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:   &validSink,
+					Limits: &LimitsSpec{MaxLineBytes: -1, MaxResults: -5},
+				},
+			},
+			want: apis.ErrInvalidValue(int64(-1), "spec.limits.maxLineBytes").Also(
+				apis.ErrInvalidValue(int32(-5), "spec.limits.maxResults")),
+		},
+		"structured contentMode": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:        &validSink,
+					ContentMode: ContentModeStructured,
+				},
+			},
+		},
+		"invalid contentMode": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:        &validSink,
+					ContentMode: ContentMode("multipart"),
+				},
+			},
+			want: apis.ErrInvalidValue("multipart", "spec.contentMode"),
+		},
+		"batch contentMode to a JobSink": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:        &jobSink,
+					ContentMode: ContentModeBatch,
+				},
+			},
+			want: apis.ErrGeneric("not supported by the JobSink sinks", "spec.contentMode"),
+		},
+		"invalid ordering": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:     &validSink,
+					Ordering: Ordering("per-document"),
+				},
+			},
+			want: apis.ErrInvalidValue("per-document", "spec.ordering"),
+		},
+		"join": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink: &validSink,
+					Join: &JoinSpec{Database: "customers", Key: "customer_id", MaxAge: "PT5M", OnMissing: JoinMissingSkip},
+				},
+			},
+		},
+		"invalid join": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink: &validSink,
+					Join: &JoinSpec{As: "_customer", CacheSize: -1, MaxAge: "5m", OnMissing: "drop"},
+				},
+			},
+			want: apis.ErrMissingField("spec.join.database", "spec.join.key").Also(
+				apis.ErrInvalidValue("_customer", "spec.join.as"),
+				apis.ErrInvalidValue(-1, "spec.join.cacheSize"),
+				apis.ErrInvalidValue("5m", "spec.join.maxAge"),
+				apis.ErrInvalidValue("drop", "spec.join.onMissing")),
+		},
+		"ordered grouping": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:     &validSink,
+					Ordering: OrderingOrdered,
+					Grouping: &GroupingSpec{Field: "txn_id"},
+				},
+			},
+			want: apis.ErrGeneric("not supported by the ordered delivery", "spec.grouping"),
+		},
+		"global ordering grouping": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:     &validSink,
+					Ordering: OrderingGlobal,
+					Grouping: &GroupingSpec{Field: "txn_id"},
+				},
+			},
+			want: apis.ErrGeneric("not supported by the ordered delivery", "spec.grouping"),
+		},
+		"invalid attachments": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:        &validSink,
+					Attachments: AttachmentsPolicy("base64"),
+				},
+			},
+			want: apis.ErrInvalidValue("base64", "spec.attachments"),
+		},
+		"invalid apply mode": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:      &validSink,
+					ApplyMode: ApplyMode("scheduled"),
+				},
+			},
+			want: apis.ErrInvalidValue("scheduled", "spec.applyMode"),
+		},
+		"invalid payload": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:    &validSink,
+					Payload: Payload("merge"),
+				},
+			},
+			want: apis.ErrInvalidValue("merge", "spec.payload"),
+		},
+		"match": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:  &validSink,
+					Match: `doc.type == "order" && doc.total > 100`,
+				},
+			},
+		},
+		"invalid match": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:  &validSink,
+					Match: `type == "order"`,
+				},
+			},
+			want: func() *apis.FieldError {
+				fe := apis.ErrInvalidValue(`type == "order"`, "spec.match")
+				fe.Details = `unknown identifier "type" at offset 0, fields are reached from doc`
+				return fe
+			}(),
+		},
+		"projection": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:       &validSink,
+					Projection: map[string]string{"customer": "{.customer.name}"},
+				},
+			},
+		},
+		"invalid projection": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:       &validSink,
+					Projection: map[string]string{"customer": "{.customer"},
+				},
+			},
+			want: func() *apis.FieldError {
+				fe := apis.ErrInvalidValue("{.customer", "spec.projection[customer]")
+				fe.Details = `field "customer": unclosed action`
+				return fe
+			}(),
+		},
+		"projection with diff payload": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:       &validSink,
+					Payload:    PayloadDiff,
+					Projection: map[string]string{"customer": "{.customer.name}"},
+				},
+			},
+			want: apis.ErrMultipleOneOf("spec.payload", "spec.projection"),
+		},
+		"projection with attachments": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:        &validSink,
+					Attachments: AttachmentsInline,
+					Projection:  map[string]string{"customer": "{.customer.name}"},
+				},
+			},
+			want: apis.ErrMultipleOneOf("spec.attachments", "spec.projection"),
+		},
+		"diff payload with attachments": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:        &validSink,
+					Attachments: AttachmentsNone,
+					Payload:     PayloadDiff,
+				},
+			},
+			want: apis.ErrMultipleOneOf("spec.attachments", "spec.payload"),
+		},
+		"metadata payload with attachments": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:        &validSink,
+					Attachments: AttachmentsReference,
+					Payload:     PayloadMetadata,
+				},
+			},
+			want: apis.ErrMultipleOneOf("spec.attachments", "spec.payload"),
+		},
+		"invalid window": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:   &validSink,
+					Window: &WindowSpec{Since: "yesterday", Until: "now"},
+				},
+			},
+			want: apis.ErrInvalidValue("yesterday", "spec.window.since"),
+		},
+		"dev instance with credentials": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:               &validSink,
+					DevInstance:        true,
+					CouchDbCredentials: corev1.ObjectReference{Name: "couchdb-binding"},
+				},
+			},
+			want: apis.ErrMultipleOneOf("spec.credentials", "spec.devInstance"),
+		},
+		"empty adapter image annotation": {
+			cr: &CouchDbSource{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{AdapterImageAnnotationKey: " "},
+				},
+				Spec: CouchDbSourceSpec{
+					Sink: &validSink,
+				},
+			},
+			want: apis.ErrInvalidValue(" ", "metadata.annotations."+AdapterImageAnnotationKey),
+		},
+		"invalid pprof port annotation": {
+			cr: &CouchDbSource{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{PprofPortAnnotationKey: "65536"},
+				},
+				Spec: CouchDbSourceSpec{
+					Sink: &validSink,
+				},
+			},
+			want: apis.ErrInvalidValue("65536", "metadata.annotations."+PprofPortAnnotationKey),
+		},
+		"polling of the continuous feed": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:    &validSink,
+					Feed:    FeedContinuous,
+					Polling: &PollingSpec{},
+				},
+			},
+			want: apis.ErrGeneric("only supported by the normal feed", "spec.polling"),
+		},
+		"polling bounds inverted": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:    &validSink,
+					Feed:    FeedNormal,
+					Polling: &PollingSpec{MinInterval: "PT10S", MaxInterval: "PT5S"},
+				},
+			},
+			want: func() *apis.FieldError {
+				fe := apis.ErrInvalidValue("PT5S", "spec.polling.maxInterval")
+				fe.Details = "must not be shorter than minInterval"
+				return fe
+			}(),
+		},
+		"reconnect": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:      &validSink,
+					Reconnect: &ReconnectSpec{InitialDelay: "PT0.5S", MaxDelay: "PT5M"},
+				},
+			},
+		},
+		"invalid reconnect": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:      &validSink,
+					Reconnect: &ReconnectSpec{InitialDelay: "1s", MaxDelay: "PT0S"},
+				},
+			},
+			want: apis.ErrInvalidValue("1s", "spec.reconnect.initialDelay").Also(
+				apis.ErrInvalidValue("PT0S", "spec.reconnect.maxDelay")),
+		},
+		"reconnect initialDelay longer than the default maxDelay": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:      &validSink,
+					Reconnect: &ReconnectSpec{InitialDelay: "PT2M"},
+				},
+			},
+		},
+		"reconnect maxDelay shorter than initialDelay": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:      &validSink,
+					Reconnect: &ReconnectSpec{InitialDelay: "PT10S", MaxDelay: "PT5S"},
+				},
+			},
+			want: func() *apis.FieldError {
+				fe := apis.ErrInvalidValue("PT5S", "spec.reconnect.maxDelay")
+				fe.Details = "must not be shorter than initialDelay"
+				return fe
+			}(),
+		},
+		"invalid batch": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:  &validSink,
+					Batch: &BatchSpec{MaxCount: -1, MaxWait: "1s"},
+				},
+			},
+			want: apis.ErrInvalidValue(-1, "spec.batch.maxCount").Also(
+				apis.ErrInvalidValue("1s", "spec.batch.maxWait")),
+		},
+		"batch with grouping batch": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:     &validSink,
+					Batch:    &BatchSpec{},
+					Grouping: &GroupingSpec{Field: "txn_id", Batch: true},
+				},
+			},
+			want: apis.ErrMultipleOneOf("spec.batch", "spec.grouping.batch"),
+		},
+		"invalid rateLimit": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:      &validSink,
+					RateLimit: &RateLimitSpec{Burst: -1},
+				},
+			},
+			want: apis.ErrInvalidValue(0, "spec.rateLimit.eventsPerSecond").Also(
+				apis.ErrInvalidValue(-1, "spec.rateLimit.burst")),
+		},
+		"negative seqInterval": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:        &validSink,
+					SeqInterval: -1,
+				},
+			},
+			want: apis.ErrInvalidValue(-1, "spec.seqInterval"),
+		},
+		"stats interval too short": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:  &validSink,
+					Stats: &StatsSpec{Interval: "PT1S"},
+				},
+			},
+			want: func() *apis.FieldError {
+				fe := apis.ErrInvalidValue("PT1S", "spec.stats.interval")
+				fe.Details = "must be at least PT10S"
+				return fe
+			}(),
+		},
+		"health metrics interval too short": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:          &validSink,
+					HealthMetrics: &HealthMetricsSpec{Interval: "PT5S"},
+				},
+			},
+			want: func() *apis.FieldError {
+				fe := apis.ErrInvalidValue("PT5S", "spec.healthMetrics.interval")
+				fe.Details = "must be at least PT10S"
+				return fe
+			}(),
+		},
+		"lag metrics interval too short": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:       &validSink,
+					LagMetrics: &LagMetricsSpec{Interval: "PT5S"},
+				},
+			},
+			want: func() *apis.FieldError {
+				fe := apis.ErrInvalidValue("PT5S", "spec.lagMetrics.interval")
+				fe.Details = "must be at least PT10S"
+				return fe
+			}(),
+		},
+		"liveness threshold too short": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:     &validSink,
+					Liveness: &LivenessSpec{DisconnectedThreshold: "PT10S"},
+				},
+			},
+			want: func() *apis.FieldError {
+				fe := apis.ErrInvalidValue("PT10S", "spec.liveness.disconnectedThreshold")
+				fe.Details = "must be at least PT30S"
+				return fe
+			}(),
+		},
+		"single replica": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:             &validSink,
+					HighAvailability: &HighAvailabilitySpec{Replicas: 1},
+				},
+			},
+			want: func() *apis.FieldError {
+				fe := apis.ErrInvalidValue(1, "spec.highAvailability.replicas")
+				fe.Details = "must be at least 2"
+				return fe
+			}(),
+		},
+		"high availability with window": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:             &validSink,
+					HighAvailability: &HighAvailabilitySpec{Replicas: 2},
+					Window:           &WindowSpec{Since: "now"},
+				},
+			},
+			want: apis.ErrMultipleOneOf("spec.highAvailability", "spec.window"),
+		},
+		"database pattern": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:            &validSink,
+					DatabasePattern: "orders-*",
+					Shards:          3,
+					Since:           "now",
+				},
+			},
+		},
+		"shards without a database pattern": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:     &validSink,
+					Database: "orders",
+					Shards:   3,
+				},
+			},
+			want: apis.ErrGeneric("only supported with a databasePattern", "spec.shards"),
+		},
+		"database and database pattern": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:            &validSink,
+					Database:        "orders",
+					DatabasePattern: "orders-[",
+				},
+			},
+			want: apis.ErrMultipleOneOf("spec.database", "spec.databasePattern").Also(func() *apis.FieldError {
+				fe := apis.ErrInvalidValue("orders-[", "spec.databasePattern")
+				fe.Details = "syntax error in pattern"
+				return fe
+			}()),
+		},
+		"database pattern with a sequence": {
+			cr: &CouchDbSource{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{ReplayFromAnnotationKey: "0"},
+				},
+				Spec: CouchDbSourceSpec{
+					Sink:            &validSink,
+					DatabasePattern: "orders-*",
+					Since:           "12",
+				},
+			},
+			want: apis.ErrGeneric("not supported with a databasePattern", "metadata.annotations."+ReplayFromAnnotationKey).Also(func() *apis.FieldError {
+				fe := apis.ErrInvalidValue("12", "spec.since")
+				fe.Details = `the databases of a databasePattern only start from "0" or "now"`
+				return fe
+			}()),
+		},
+		"database pattern with a single pod feature": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:             &validSink,
+					DatabasePattern:  "orders-*",
+					HighAvailability: &HighAvailabilitySpec{Replicas: 2},
+					Backfill:         true,
+				},
+			},
+			want: apis.ErrGeneric("not supported with a databasePattern", "spec.backfill", "spec.highAvailability"),
+		},
+		"invalid replay annotation": {
+			cr: &CouchDbSource{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{ReplayFromAnnotationKey: "now"},
+				},
+				Spec: CouchDbSourceSpec{
+					Sink: &validSink,
+				},
+			},
+			want: apis.ErrInvalidValue("now", "metadata.annotations."+ReplayFromAnnotationKey),
+		},
+		"reserved template env": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink: &validSink,
+					Template: &AdapterTemplateSpec{
+						Env: []corev1.EnvVar{{Name: "GOMAXPROCS", Value: "2"}, {Name: "COUCHDB_FEED", Value: "continuous"}},
+					},
+				},
+			},
+			want: func() *apis.FieldError {
+				fe := apis.ErrInvalidValue("COUCHDB_FEED", "spec.template.env[1].name")
+				fe.Details = "reserved for the configuration of the receive adapter"
+				return fe
+			}(),
+		},
+		"invalid template label": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink: &validSink,
+					Template: &AdapterTemplateSpec{
+						Metadata: &AdapterTemplateMetadata{Labels: map[string]string{"team": "pay ments"}},
+					},
+				},
+			},
+			want: func() *apis.FieldError {
+				fe := apis.ErrInvalidValue("pay ments", "spec.template.metadata.labels.team")
+				fe.Details = "a valid label must be an empty string or consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character (e.g. 'MyValue',  or 'my_value',  or '12345', regex used for validation is '(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])?')"
+				return fe
+			}(),
+		},
+		"invalid keda lag threshold": {
+			cr: &CouchDbSource{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						AutoscalingClassAnnotationKey: KedaAutoscalingClass,
+						KedaLagThresholdAnnotationKey: "0",
+					},
+				},
+				Spec: CouchDbSourceSpec{
+					Sink: &validSink,
+				},
+			},
+			want: func() *apis.FieldError {
+				fe := apis.ErrInvalidValue("0", "metadata.annotations."+KedaLagThresholdAnnotationKey)
+				fe.Details = "must be an integer of at least 1"
+				return fe
+			}(),
+		},
+		"invalid ceOverrides extension": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink: &validSink,
+					CloudEventOverrides: &duckv1.CloudEventOverrides{
+						Extensions: map[string]string{"team": "payments", "Bad-Name": "x"},
+					},
+				},
+			},
+			want: apis.ErrInvalidKeyName("Bad-Name", "spec.ceOverrides.extensions",
+				"extension names must only contain lowercase letters and digits"),
+		},
+		"proxy without url": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:  &validSink,
+					Proxy: &ProxySpec{},
+				},
+			},
+			want: apis.ErrMissingField("spec.proxy.url"),
+		},
+		"proxy with invalid url": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:  &validSink,
+					Proxy: &ProxySpec{URL: "socks5://proxy:1080"},
+				},
+			},
+			want: apis.ErrInvalidValue("socks5://proxy:1080", "spec.proxy.url"),
+		},
+		"unparsable event type template": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:              &validSink,
+					EventTypeTemplate: "com.acme.{{.ChangeType",
+				},
+			},
+			want: func() *apis.FieldError {
+				fe := apis.ErrInvalidValue("com.acme.{{.ChangeType", "spec.eventTypeTemplate")
+				fe.Details = "template: eventType:1: unclosed action"
+				return fe
+			}(),
+		},
+		"invalid partition": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:       &validSink,
+					Partitions: []string{"sensors", "_design"},
+				},
+			},
+			want: apis.ErrInvalidArrayValue("_design", "spec.partitions", 1),
+		},
+		"access without roles": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:   &validSink,
+					Access: &AccessSpec{Field: "tenants"},
+				},
+			},
+			want: apis.ErrMissingField("spec.access.roles"),
+		},
+		"invalid access role": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:   &validSink,
+					Access: &AccessSpec{Roles: []string{"tenant-a", "tenant-b,tenant-c"}},
+				},
+			},
+			want: apis.ErrInvalidArrayValue("tenant-b,tenant-c", "spec.access.roles", 1),
+		},
+		"subject template with unknown field": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:            &validSink,
+					SubjectTemplate: "{{.Table}}",
+				},
+			},
+			want: func() *apis.FieldError {
+				fe := apis.ErrInvalidValue("{{.Table}}", "spec.subjectTemplate")
+				fe.Details = `template: subject:1:2: executing "subject" at <.Table>: can't evaluate field Table in type v1alpha1.SubjectData`
+				return fe
+			}(),
+		},
+		"empty id template": {
+			cr: &CouchDbSource{
+				Spec: CouchDbSourceSpec{
+					Sink:       &validSink,
+					Database:   "orders",
+					IDTemplate: "{{if false}}{{.ID}}{{end}}",
+				},
+			},
+			want: func() *apis.FieldError {
+				fe := apis.ErrInvalidValue("{{if false}}{{.ID}}{{end}}", "spec.idTemplate")
+				fe.Details = `event ID template rendered an empty ID for "id"`
+				return fe
+			}(),
+		},
 	}
 
 	for n, test := range testCases {
@@ -53,3 +980,73 @@ func TestCouchDbSourceValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestCouchDbSourceValidationDefaultSink(t *testing.T) {
+	ctx := config.ToContext(context.Background(), &config.Config{
+		DefaultSinks: &config.DefaultSinks{
+			NamespaceDefaults: map[string]*duckv1.Destination{"team-a": &validSink},
+		},
+	})
+	testCases := map[string]struct {
+		namespace string
+		want      *apis.FieldError
+	}{
+		"namespace with a default sink": {
+			namespace: "team-a",
+		},
+		"namespace without a default sink": {
+			namespace: "team-b",
+			want:      apis.ErrMissingField("spec.sink"),
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			src := &CouchDbSource{ObjectMeta: metav1.ObjectMeta{Name: "source", Namespace: tc.namespace}}
+			got := src.Validate(ctx)
+			if diff := cmp.Diff(tc.want.Error(), got.Error()); diff != "" {
+				t.Errorf("validate (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
+
+func TestCouchDbSourceValidationIdleTimeout(t *testing.T) {
+	ctx := config.ToContext(context.Background(), &config.Config{
+		Feed: &config.Feed{IdleTimeout: 30 * time.Second},
+	})
+	testCases := map[string]struct {
+		heartbeat string
+		want      *apis.FieldError
+	}{
+		"heartbeat below the idle timeout": {
+			heartbeat: "PT20S",
+		},
+		"heartbeat of the idle timeout": {
+			heartbeat: "PT30S",
+			want: &apis.FieldError{
+				Message: "invalid value: PT30S",
+				Paths:   []string{"spec.heartbeat"},
+				Details: "the heartbeat must be below the 30s idle timeout of the load balancers in front of CouchDB",
+			},
+		},
+		"no heartbeat": {},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			src := &CouchDbSource{
+				ObjectMeta: metav1.ObjectMeta{Name: "source", Namespace: "ns"},
+				Spec: CouchDbSourceSpec{
+					CouchDbCredentials: corev1.ObjectReference{Name: "couchdb"},
+					Database:           "db",
+					Feed:               FeedContinuous,
+					Heartbeat:          tc.heartbeat,
+					Sink:               &validSink,
+				},
+			}
+			got := src.Validate(ctx)
+			if diff := cmp.Diff(tc.want.Error(), got.Error()); diff != "" {
+				t.Errorf("validate (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/apis"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+// ConvertTo implements apis.Convertible, converting to v1alpha1. v1beta1 is
+// the hub of the conversions.
+func (source *CouchDbSource) ConvertTo(ctx context.Context, to apis.Convertible) error {
+	switch sink := to.(type) {
+	case *v1alpha1.CouchDbSource:
+		src := source.DeepCopy()
+		sink.ObjectMeta = src.ObjectMeta
+		sink.Spec = v1alpha1.CouchDbSourceSpec{
+			ServiceAccountName:  src.Spec.ServiceAccountName,
+			Feed:                src.Spec.Feed,
+			Heartbeat:           src.Spec.Heartbeat,
+			Timeout:             src.Spec.Timeout,
+			Polling:             src.Spec.Polling,
+			Reconnect:           src.Spec.Reconnect,
+			Database:            src.Spec.Database,
+			DatabasePattern:     src.Spec.DatabasePattern,
+			Shards:              src.Spec.Shards,
+			Auth:                src.Spec.Auth,
+			DevInstance:         src.Spec.DevInstance,
+			Proxy:               src.Spec.Proxy,
+			Sink:                src.Spec.Sink,
+			SinkAudience:        src.Spec.SinkAudience,
+			SinkCACerts:         src.Spec.SinkCACerts,
+			Delivery:            src.Spec.Delivery,
+			Reply:               src.Spec.Reply,
+			CloudEventOverrides: src.Spec.CloudEventOverrides,
+			EventTypeTemplate:   src.Spec.EventTypeTemplate,
+			EventTypeField:      src.Spec.EventTypeField,
+			CloudEventSource:    src.Spec.CloudEventSource,
+			SubjectTemplate:     src.Spec.SubjectTemplate,
+			IDTemplate:          src.Spec.IDTemplate,
+			Partitions:          src.Spec.Partitions,
+			Attachments:         src.Spec.Attachments,
+			Payload:             src.Spec.Payload,
+			Match:               src.Spec.Match,
+			Projection:          src.Spec.Projection,
+			Since:               src.Spec.Since,
+			SeqInterval:         src.Spec.SeqInterval,
+			Window:              src.Spec.Window,
+			DeletedDocs:         src.Spec.DeletedDocs,
+			DesignDocs:          src.Spec.DesignDocs,
+			OnDecodeError:       src.Spec.OnDecodeError,
+			Conflicts:           src.Spec.Conflicts,
+			DatabaseUpdates:     src.Spec.DatabaseUpdates,
+			ContentMode:         src.Spec.ContentMode,
+			ApplyMode:           src.Spec.ApplyMode,
+			Ordering:            src.Spec.Ordering,
+			Batch:               src.Spec.Batch,
+			RateLimit:           src.Spec.RateLimit,
+			Limits:              src.Spec.Limits,
+			Backfill:            src.Spec.Backfill,
+			Stats:               src.Spec.Stats,
+			Grouping:            src.Spec.Grouping,
+			Access:              src.Spec.Access,
+			HealthMetrics:       src.Spec.HealthMetrics,
+			LagMetrics:          src.Spec.LagMetrics,
+			Liveness:            src.Spec.Liveness,
+			HighAvailability:    src.Spec.HighAvailability,
+			Template:            src.Spec.Template,
+			Join:                src.Spec.Join,
+			Buffer:              src.Spec.Buffer,
+		}
+		if c := src.Spec.Credentials; c != nil && c.SecretRef != nil {
+			sink.Spec.CouchDbCredentials = *c.SecretRef
+		}
+		sink.Status = src.Status
+		return nil
+	default:
+		return fmt.Errorf("unknown version, got: %T", sink)
+	}
+}
+
+// ConvertFrom implements apis.Convertible, converting from v1alpha1.
+func (sink *CouchDbSource) ConvertFrom(ctx context.Context, from apis.Convertible) error {
+	switch source := from.(type) {
+	case *v1alpha1.CouchDbSource:
+		src := source.DeepCopy()
+		sink.ObjectMeta = src.ObjectMeta
+		sink.Spec = CouchDbSourceSpec{
+			ServiceAccountName:  src.Spec.ServiceAccountName,
+			Feed:                src.Spec.Feed,
+			Heartbeat:           src.Spec.Heartbeat,
+			Timeout:             src.Spec.Timeout,
+			Polling:             src.Spec.Polling,
+			Reconnect:           src.Spec.Reconnect,
+			Database:            src.Spec.Database,
+			DatabasePattern:     src.Spec.DatabasePattern,
+			Shards:              src.Spec.Shards,
+			Auth:                src.Spec.Auth,
+			DevInstance:         src.Spec.DevInstance,
+			Proxy:               src.Spec.Proxy,
+			Sink:                src.Spec.Sink,
+			SinkAudience:        src.Spec.SinkAudience,
+			SinkCACerts:         src.Spec.SinkCACerts,
+			Delivery:            src.Spec.Delivery,
+			Reply:               src.Spec.Reply,
+			CloudEventOverrides: src.Spec.CloudEventOverrides,
+			EventTypeTemplate:   src.Spec.EventTypeTemplate,
+			EventTypeField:      src.Spec.EventTypeField,
+			CloudEventSource:    src.Spec.CloudEventSource,
+			SubjectTemplate:     src.Spec.SubjectTemplate,
+			IDTemplate:          src.Spec.IDTemplate,
+			Partitions:          src.Spec.Partitions,
+			Attachments:         src.Spec.Attachments,
+			Payload:             src.Spec.Payload,
+			Match:               src.Spec.Match,
+			Projection:          src.Spec.Projection,
+			Since:               src.Spec.Since,
+			SeqInterval:         src.Spec.SeqInterval,
+			Window:              src.Spec.Window,
+			DeletedDocs:         src.Spec.DeletedDocs,
+			DesignDocs:          src.Spec.DesignDocs,
+			OnDecodeError:       src.Spec.OnDecodeError,
+			Conflicts:           src.Spec.Conflicts,
+			DatabaseUpdates:     src.Spec.DatabaseUpdates,
+			ContentMode:         src.Spec.ContentMode,
+			ApplyMode:           src.Spec.ApplyMode,
+			Ordering:            src.Spec.Ordering,
+			Batch:               src.Spec.Batch,
+			RateLimit:           src.Spec.RateLimit,
+			Limits:              src.Spec.Limits,
+			Backfill:            src.Spec.Backfill,
+			Stats:               src.Spec.Stats,
+			Grouping:            src.Spec.Grouping,
+			Access:              src.Spec.Access,
+			HealthMetrics:       src.Spec.HealthMetrics,
+			LagMetrics:          src.Spec.LagMetrics,
+			Liveness:            src.Spec.Liveness,
+			HighAvailability:    src.Spec.HighAvailability,
+			Template:            src.Spec.Template,
+			Join:                src.Spec.Join,
+			Buffer:              src.Spec.Buffer,
+		}
+		if ref := src.Spec.CouchDbCredentials; ref != (corev1.ObjectReference{}) {
+			sink.Spec.Credentials = &Credentials{SecretRef: &ref}
+		}
+		sink.Status = src.Status
+		return nil
+	default:
+		return fmt.Errorf("unknown version, got: %T", source)
+	}
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"knative.dev/pkg/ptr"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+func TestCouchDbSourceConversion(t *testing.T) {
+	testCases := map[string]struct {
+		credentials corev1.ObjectReference
+		want        *Credentials
+	}{
+		"credentials": {
+			credentials: corev1.ObjectReference{Name: "couchdb", Namespace: "shared"},
+			want: &Credentials{
+				SecretRef: &corev1.ObjectReference{Name: "couchdb", Namespace: "shared"},
+			},
+		},
+		"dev instance": {},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			stored := &v1alpha1.CouchDbSource{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "orders", Generation: 3},
+				Spec: v1alpha1.CouchDbSourceSpec{
+					CouchDbCredentials: tc.credentials,
+					Database:           "orders",
+					Feed:               v1alpha1.FeedContinuous,
+					DevInstance:        tc.credentials.Name == "",
+					Heartbeat:          "PT20S",
+					Partitions:         []string{"eu"},
+					Batch:              &v1alpha1.BatchSpec{MaxCount: 10},
+				},
+				Status: v1alpha1.CouchDbSourceStatus{LastSequence: "7-g"},
+			}
+
+			got := &CouchDbSource{}
+			if err := got.ConvertFrom(context.Background(), stored); err != nil {
+				t.Fatalf("ConvertFrom() = %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got.Spec.Credentials); diff != "" {
+				t.Errorf("Credentials (-want, +got): %s", diff)
+			}
+
+			back := &v1alpha1.CouchDbSource{}
+			if err := got.ConvertTo(context.Background(), back); err != nil {
+				t.Fatalf("ConvertTo() = %v", err)
+			}
+			if diff := cmp.Diff(stored, back); diff != "" {
+				t.Errorf("Round trip (-want, +got): %s", diff)
+			}
+		})
+	}
+}
+
+func TestCouchDbSourceConversionUnknownVersion(t *testing.T) {
+	src := &CouchDbSource{}
+	if err := src.ConvertTo(context.Background(), &CouchDbSource{}); err == nil {
+		t.Error("ConvertTo(v1beta1) succeeded, want an error")
+	}
+	if err := src.ConvertFrom(context.Background(), &CouchDbSource{}); err == nil {
+		t.Error("ConvertFrom(v1beta1) succeeded, want an error")
+	}
+}
+
+func TestCredentialsJSON(t *testing.T) {
+	raw := `{"credentials":{"secretRef":{"name":"couchdb"}},"database":"orders","feed":"normal"}`
+	var spec CouchDbSourceSpec
+	if err := json.Unmarshal([]byte(raw), &spec); err != nil {
+		t.Fatalf("Unmarshal() = %v", err)
+	}
+	if spec.Credentials == nil || spec.Credentials.SecretRef == nil || spec.Credentials.SecretRef.Name != "couchdb" {
+		t.Errorf("Credentials = %+v, want the couchdb secret", spec.Credentials)
+	}
+	if spec.Database != "orders" || spec.Feed != v1alpha1.FeedNormal {
+		t.Errorf("Spec = %+v, want the orders database and the normal feed", spec)
+	}
+	b, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatalf("Marshal() = %v", err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"secretRef": map[string]interface{}{"name": "couchdb"}}
+	if diff := cmp.Diff(want, got["credentials"]); diff != "" {
+		t.Errorf("credentials (-want, +got): %s", diff)
+	}
+}
+
+func TestCouchDbSourceRoundTrip(t *testing.T) {
+	policy := eventingduckv1.BackoffPolicyLinear
+	storageClass := "ssd"
+	stored := &v1alpha1.CouchDbSource{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "orders", Generation: 3},
+		Spec: v1alpha1.CouchDbSourceSpec{
+			ServiceAccountName: "adapter",
+			CouchDbCredentials: corev1.ObjectReference{
+				Kind:            "Secret",
+				Namespace:       "shared",
+				Name:            "couchdb",
+				UID:             "7c5a",
+				APIVersion:      "v1",
+				ResourceVersion: "42",
+				FieldPath:       "data.url",
+			},
+			Feed:            v1alpha1.FeedContinuous,
+			Heartbeat:       "PT20S",
+			Timeout:         "PT30S",
+			Polling:         &v1alpha1.PollingSpec{MinInterval: "PT1S", MaxInterval: "PT1M"},
+			Reconnect:       &v1alpha1.ReconnectSpec{InitialDelay: "PT1S", MaxDelay: "PT1M"},
+			Database:        "orders",
+			DatabasePattern: "orders-*",
+			Shards:          3,
+			Auth:            v1alpha1.AuthSession,
+			DevInstance:     true,
+			Proxy:           &v1alpha1.ProxySpec{URL: "http://proxy:3128", NoProxy: []string{"local"}},
+			Sink: &duckv1.Destination{
+				Ref: &duckv1.KReference{APIVersion: "v1", Kind: "Service", Name: "sink"},
+			},
+			SinkAudience: "sink",
+			SinkCACerts:  "certs",
+			Delivery: &eventingduckv1.DeliverySpec{
+				Retry:         ptr.Int32(3),
+				BackoffPolicy: &policy,
+				BackoffDelay:  ptr.String("PT1S"),
+			},
+			Reply:               &duckv1.Destination{URI: apis.HTTP("reply")},
+			CloudEventOverrides: &duckv1.CloudEventOverrides{Extensions: map[string]string{"env": "prod"}},
+			EventTypeTemplate:   "{{ .type }}",
+			EventTypeField:      "type",
+			CloudEventSource:    "couchdb",
+			SubjectTemplate:     "{{ .id }}",
+			IDTemplate:          "{{ .seq }}",
+			Partitions:          []string{"eu"},
+			Attachments:         v1alpha1.AttachmentsPolicy("stub"),
+			Payload:             v1alpha1.PayloadDiff,
+			Match:               "doc.type == 'order'",
+			Projection:          map[string]string{"total": "doc.total"},
+			Since:               "now",
+			SeqInterval:         10,
+			Window:              &v1alpha1.WindowSpec{Since: "0", Until: "now"},
+			DeletedDocs:         v1alpha1.DeletedDocsPolicy("exclude"),
+			DesignDocs:          v1alpha1.DesignDocsPolicy("include"),
+			OnDecodeError:       v1alpha1.DecodeErrorPolicy("skip"),
+			Conflicts:           true,
+			DatabaseUpdates:     true,
+			ContentMode:         v1alpha1.ContentModeStructured,
+			ApplyMode:           v1alpha1.ApplyModeManual,
+			Ordering:            v1alpha1.OrderingGlobal,
+			Batch:               &v1alpha1.BatchSpec{MaxCount: 10, MaxWait: "PT2S"},
+			RateLimit:           &v1alpha1.RateLimitSpec{EventsPerSecond: 10, Burst: 20},
+			Limits:              &v1alpha1.LimitsSpec{MaxLineBytes: 1 << 20, MaxJSONDepth: 32, MaxResults: 100},
+			Backfill:            true,
+			Stats:               &v1alpha1.StatsSpec{Interval: "PT1M"},
+			Grouping:            &v1alpha1.GroupingSpec{Field: "txn_id", Delay: "PT1S", Batch: true},
+			Access:              &v1alpha1.AccessSpec{Roles: []string{"eu"}, Field: "roles"},
+			HealthMetrics:       &v1alpha1.HealthMetricsSpec{Interval: "PT1M"},
+			LagMetrics:          &v1alpha1.LagMetricsSpec{Interval: "PT1M"},
+			Liveness:            &v1alpha1.LivenessSpec{DisconnectedThreshold: "PT5M"},
+			HighAvailability:    &v1alpha1.HighAvailabilitySpec{Replicas: 2},
+			Template: &v1alpha1.AdapterTemplateSpec{
+				NodeSelector: map[string]string{"zone": "a"},
+				Resources: &corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")},
+				},
+			},
+			Join: &v1alpha1.JoinSpec{Database: "customers", Key: "customer_id", As: "customer"},
+			Buffer: &v1alpha1.BufferSpec{
+				Size:             resource.MustParse("1Gi"),
+				StorageClassName: &storageClass,
+			},
+		},
+		Status: v1alpha1.CouchDbSourceStatus{LastSequence: "7-g"},
+	}
+
+	// Every field is set, so that a field the conversions miss, e.g. one
+	// added to v1alpha1 only, fails the round trip.
+	spec := reflect.ValueOf(stored.Spec)
+	for i := 0; i < spec.NumField(); i++ {
+		if spec.Field(i).IsZero() {
+			t.Errorf("spec.%s is not set", spec.Type().Field(i).Name)
+		}
+	}
+
+	converted := &CouchDbSource{}
+	if err := converted.ConvertFrom(context.Background(), stored); err != nil {
+		t.Fatalf("ConvertFrom() = %v", err)
+	}
+	back := &v1alpha1.CouchDbSource{}
+	if err := converted.ConvertTo(context.Background(), back); err != nil {
+		t.Fatalf("ConvertTo() = %v", err)
+	}
+	if diff := cmp.Diff(stored, back); diff != "" {
+		t.Errorf("Round trip (-want, +got): %s", diff)
+	}
+}
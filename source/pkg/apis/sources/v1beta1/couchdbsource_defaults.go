@@ -0,0 +1,55 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+
+	"knative.dev/pkg/apis"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+var _ apis.Defaultable = (*CouchDbSource)(nil)
+
+// SetDefaults defaults the source as v1alpha1 does, through its conversion.
+func (c *CouchDbSource) SetDefaults(ctx context.Context) {
+	src := &v1alpha1.CouchDbSource{}
+	if err := c.ConvertTo(ctx, src); err != nil {
+		return
+	}
+	src.SetDefaults(v1alpha1Context(ctx))
+	_ = c.ConvertFrom(ctx, src)
+}
+
+// v1alpha1Context returns the context in which the v1alpha1 source is
+// defaulted and validated, whose baseline, on updates, is converted to
+// v1alpha1 as well.
+func v1alpha1Context(ctx context.Context) context.Context {
+	old, ok := apis.GetBaseline(ctx).(*CouchDbSource)
+	if !ok {
+		return ctx
+	}
+	base := &v1alpha1.CouchDbSource{}
+	if err := old.ConvertTo(ctx, base); err != nil {
+		return ctx
+	}
+	if apis.IsInStatusUpdate(ctx) {
+		return apis.WithinSubResourceUpdate(ctx, base, "status")
+	}
+	return apis.WithinUpdate(ctx, base)
+}
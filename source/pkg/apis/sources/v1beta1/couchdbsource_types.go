@@ -0,0 +1,394 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CouchDbSource is the v1beta1 version of the CouchDbSource, converted by
+// the webhook from and to v1alpha1, in which the sources are stored.
+type CouchDbSource struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CouchDbSourceSpec            `json:"spec,omitempty"`
+	Status v1alpha1.CouchDbSourceStatus `json:"status,omitempty"`
+}
+
+// Check that CouchDbSource can be converted from and to v1alpha1.
+var _ runtime.Object = (*CouchDbSource)(nil)
+var _ apis.Convertible = (*CouchDbSource)(nil)
+
+// CouchDbSourceSpec defines the desired state of CouchDbSource. It has the
+// fields of v1alpha1, whose credentials, an ObjectReference of which only
+// the name and namespace are used, move to Credentials.
+type CouchDbSourceSpec struct {
+	// ServiceAccountName holds the name of the Kubernetes service account
+	// as which the underlying K8s resources should be run. If unspecified
+	// this will default to the "default" service account for the namespace
+	// in which the CouchDbSource exists.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// Credentials locates the URL of the CouchDB server. The dev instances
+	// set none.
+	// +optional
+	Credentials *Credentials `json:"credentials,omitempty"`
+
+	// Feed changes how CouchDB sends the response.
+	// More information: https://docs.couchdb.org/en/stable/api/database/changes.html#changes-feeds
+	Feed v1alpha1.FeedType `json:"feed"`
+
+	// Heartbeat is the ISO-8601 duration after which CouchDB sends an empty
+	// line on an idle continuous feed, keeping the connection open through
+	// load balancers and proxies. It must stay below their idle timeout.
+	// Defaults to the controller's default heartbeat, else half the idle
+	// timeout of the load balancers of config-couchdb-feed, or PT6S.
+	// +optional
+	Heartbeat string `json:"heartbeat,omitempty"`
+
+	// Timeout is the ISO-8601 duration after which CouchDB closes an idle
+	// continuous feed, which the receive adapter then reopens. It cannot be
+	// combined with Heartbeat, which keeps the feed open indefinitely.
+	// +optional
+	Timeout string `json:"timeout,omitempty"`
+
+	// Polling adapts the interval between the polls of the normal feed to
+	// the rate of changes. Without it, the feed is polled every 2 seconds.
+	// +optional
+	Polling *v1alpha1.PollingSpec `json:"polling,omitempty"`
+
+	// Reconnect spaces out the reopenings of the feed after it failed or was
+	// dropped, with an exponential backoff and jitter. Its fields default to
+	// the config-couchdb-reconnect ConfigMap.
+	// +optional
+	Reconnect *v1alpha1.ReconnectSpec `json:"reconnect,omitempty"`
+
+	// Database is the database to watch for changes
+	Database string `json:"database"`
+
+	// DatabasePattern, in place of Database, watches every database of the
+	// server whose name matches this glob, e.g. "orders-*". The receive
+	// adapter runs as a StatefulSet, whose replicas discover the matching
+	// databases and each tail the ones hashing to their ordinal.
+	// +optional
+	DatabasePattern string `json:"databasePattern,omitempty"`
+
+	// Shards is the number of replicas the databases of DatabasePattern are
+	// partitioned across. Defaults to 1.
+	// +optional
+	Shards int32 `json:"shards,omitempty"`
+
+	// Auth selects how the adapter authenticates against CouchDB. When
+	// unspecified, the credentials embedded in the url are handed to the
+	// CouchDB client as-is.
+	// More information: https://docs.couchdb.org/en/stable/api/server/authn.html
+	// +optional
+	Auth v1alpha1.AuthMethod `json:"auth,omitempty"`
+
+	// DevInstance asks the controller to provision a throwaway single-node
+	// CouchDB, along with its credentials, for this source. Data is not
+	// persisted. This is meant for demos and local development and is
+	// mutually exclusive with Credentials.
+	// +optional
+	DevInstance bool `json:"devInstance,omitempty"`
+
+	// Proxy routes the adapter's connections to CouchDB through an HTTP(S)
+	// egress proxy. Deliveries to the sink are not affected.
+	// +optional
+	Proxy *v1alpha1.ProxySpec `json:"proxy,omitempty"`
+
+	// Sink is a reference to an object that will resolve to a domain name to use as the sink.
+	// +optional
+	Sink *duckv1.Destination `json:"sink,omitempty"`
+
+	// SinkAudience is the OIDC audience of the sink, for the sinks given by
+	// URI: the Addressables the sink references advertise theirs in
+	// status.address.audience. The events are then sent to the sink with a
+	// token of the service account of the receive adapter for the audience.
+	// +optional
+	SinkAudience string `json:"sinkAudience,omitempty"`
+
+	// SinkCACerts are the PEM certificates of the certificate authorities the
+	// HTTPS sink is verified with, on top of the system ones, e.g. the
+	// cluster-internal CA of the Knative Eventing transport encryption. The
+	// Addressables the sink references advertise theirs in
+	// status.address.CACerts.
+	// +optional
+	SinkCACerts string `json:"sinkCACerts,omitempty"`
+
+	// Delivery configures how failed deliveries to the sink are retried and
+	// where they end up once retries are exhausted.
+	// +optional
+	Delivery *eventingduckv1.DeliverySpec `json:"delivery,omitempty"`
+
+	// Reply is where the events the sink responds with are forwarded, as
+	// with the reply of a Subscription. Without it, they are discarded.
+	// +optional
+	Reply *duckv1.Destination `json:"reply,omitempty"`
+
+	// CloudEventOverrides defines overrides to control the output format and
+	// modifications of the event sent to the sink.
+	// +optional
+	CloudEventOverrides *duckv1.CloudEventOverrides `json:"ceOverrides,omitempty"`
+
+	// EventTypeTemplate is a Go template producing the CloudEvent type
+	// attribute, e.g. "com.acme.orders.{{.ChangeType}}". It is evaluated with
+	// .ChangeType ("update" or "delete") and .Database. When unspecified the
+	// org.apache.couchdb.document.* types are used.
+	// +optional
+	EventTypeTemplate string `json:"eventTypeTemplate,omitempty"`
+
+	// EventTypeField is the top-level document field whose value is the
+	// CloudEvent type of the update events, e.g. type. Updates of documents
+	// without a non-empty string in the field, deletions and conflicts keep
+	// the type of eventTypeTemplate, or the default one.
+	// +optional
+	EventTypeField string `json:"eventTypeField,omitempty"`
+
+	// CloudEventSource overrides the CloudEvent source attribute, which
+	// defaults to the CouchDB host and database. It must be a URI-reference.
+	// +optional
+	CloudEventSource string `json:"ceSource,omitempty"`
+
+	// SubjectTemplate is a Go template producing the CloudEvent subject
+	// attribute, e.g. "{{.Database}}/{{.ID}}". It is evaluated with .ID,
+	// .Rev and .Database. When unspecified the subject is the document ID.
+	// +optional
+	SubjectTemplate string `json:"subjectTemplate,omitempty"`
+
+	// IDTemplate is a Go template producing the CloudEvent id attribute,
+	// evaluated with .ID, .Rev and .Database. It must identify the revision
+	// of the document, so that the redelivered events can be deduplicated.
+	// When unspecified the ID is "{{.Database}}/{{.ID}}/{{.Rev}}".
+	// +optional
+	IDTemplate string `json:"idTemplate,omitempty"`
+
+	// Partitions restricts the events of a partitioned database to the
+	// documents of the given partitions.
+	// +optional
+	Partitions []string `json:"partitions,omitempty"`
+
+	// Attachments, when set, makes the events carry the changed documents
+	// and selects how their attachments are represented: none, inline or
+	// reference. When unspecified the events only carry the changed
+	// revisions.
+	// +optional
+	Attachments v1alpha1.AttachmentsPolicy `json:"attachments,omitempty"`
+
+	// Payload selects the data of the update and delete events: the changed
+	// revisions (revisions), a JSON patch turning the previous revision of
+	// the document into the changed one (diff), which keeps the events of
+	// large documents with small edits small, or the metadata of the change
+	// without the document contents (metadata). Defaults to revisions.
+	// +optional
+	Payload v1alpha1.Payload `json:"payload,omitempty"`
+
+	// Match only reports the changes of the documents satisfying the
+	// expression, e.g. doc.type == "order" && doc.total > 100. It is
+	// evaluated by the receive adapter, for the databases where server side
+	// filters or design documents cannot be created.
+	// +optional
+	Match string `json:"match,omitempty"`
+
+	// Projection replaces the data of the update and delete events with the
+	// given fields, each evaluating a JSONPath expression in the kubectl
+	// syntax, e.g. "{.customer.name}", against the changed document. It
+	// renames and trims the documents without a transformer in front of
+	// the sink.
+	// +optional
+	Projection map[string]string `json:"projection,omitempty"`
+
+	// Since is the update sequence the changes feed starts after: "0" to
+	// replay the whole history of the database, "now" to only report the
+	// changes made from now on, or an update sequence. Defaults to "0".
+	// +optional
+	Since string `json:"since,omitempty"`
+
+	// SeqInterval makes CouchDB only compute the update sequence of one
+	// change out of SeqInterval, which lightens the changes feed of busy
+	// clustered databases. The other changes are reported without a sequence,
+	// and the adapter resumes from the last sequence it got.
+	// +optional
+	SeqInterval int32 `json:"seqInterval,omitempty"`
+
+	// Window bounds the changes reported by the source, e.g. to replay a
+	// historical slice of the database into the sink.
+	// +optional
+	Window *v1alpha1.WindowSpec `json:"window,omitempty"`
+
+	// DeletedDocs controls whether the deletions of documents are reported
+	// (include), suppressed (exclude) or the only changes reported (only).
+	// Defaults to include.
+	// +optional
+	DeletedDocs v1alpha1.DeletedDocsPolicy `json:"deletedDocs,omitempty"`
+
+	// DesignDocs controls whether the changes of design documents (_design/*)
+	// are reported (include), suppressed (exclude) or the only changes
+	// reported (only). Defaults to include.
+	// +optional
+	DesignDocs v1alpha1.DesignDocsPolicy `json:"designDocs,omitempty"`
+
+	// OnDecodeError controls what happens to a change whose document cannot
+	// be parsed or transformed into an event: it is dropped (skip), reported
+	// to the dead letter sink of spec.delivery (deadLetter), or the feed
+	// stops at it until the document is fixed (fail). Defaults to skip.
+	// +optional
+	OnDecodeError v1alpha1.DecodeErrorPolicy `json:"onDecodeError,omitempty"`
+
+	// Conflicts makes the feed report the conflicting revisions of documents.
+	// Changes of documents in conflict are then reported as
+	// org.apache.couchdb.document.conflicted events carrying the revisions.
+	// +optional
+	Conflicts bool `json:"conflicts,omitempty"`
+
+	// DatabaseUpdates makes the source also report the databases of the
+	// server created, deleted or updated, from its global _db_updates feed,
+	// as org.apache.couchdb.database.* events whose subject is the name of
+	// the database. Reading the feed requires the credentials of a server
+	// admin.
+	// +optional
+	DatabaseUpdates bool `json:"databaseUpdates,omitempty"`
+
+	// ContentMode selects how events are delivered to the sink: one event
+	// per request with its attributes in HTTP headers (binary) or in a JSON
+	// envelope (structured), or many events per request in the CloudEvents
+	// JSON batch format (batch). Sinks answering batches with 415 Unsupported
+	// Media Type get one event per request instead. Defaults to binary.
+	// +optional
+	ContentMode v1alpha1.ContentMode `json:"contentMode,omitempty"`
+
+	// ApplyMode selects whether the changes of the spec are applied to the
+	// receive adapter right away (automatic), or once the plan written to
+	// the status is approved with the approved-plan annotation (manual).
+	// Defaults to automatic.
+	// +optional
+	ApplyMode v1alpha1.ApplyMode `json:"applyMode,omitempty"`
+
+	// Ordering selects whether the changes are delivered strictly in the
+	// order of the changes feed (ordered), each waiting for the sink to
+	// accept the previous one, or possibly out of order by concurrent
+	// batches (unordered). The global ordering also makes a single receive
+	// adapter deliver at any time, with a standby taking over when it fails.
+	// Defaults to unordered.
+	// +optional
+	Ordering v1alpha1.Ordering `json:"ordering,omitempty"`
+
+	// Batch coalesces changes to cut the requests to the sink. With the
+	// binary content mode, the changes are sent as a single
+	// org.apache.couchdb.document.batch event whose data is a JSON array.
+	// With the batch content mode, it sizes the CloudEvents batches.
+	// +optional
+	Batch *v1alpha1.BatchSpec `json:"batch,omitempty"`
+
+	// RateLimit caps the rate of the events sent to the sink, e.g. so that a
+	// bulk import into the database does not flood the downstream broker.
+	// The receive adapter then reads the changes feed at that pace.
+	// +optional
+	RateLimit *v1alpha1.RateLimitSpec `json:"rateLimit,omitempty"`
+
+	// Limits caps what the receive adapter accepts from the changes feed, to
+	// protect it from pathological or malicious responses.
+	// +optional
+	Limits *v1alpha1.LimitsSpec `json:"limits,omitempty"`
+
+	// Backfill makes the receive adapter first report every existing
+	// document of the database, as update events, before reporting the
+	// changes that follow. The progress is reported in status.backfill.
+	// +optional
+	Backfill bool `json:"backfill,omitempty"`
+
+	// Stats reports the delivery statistics of the source in status.stats.
+	// +optional
+	Stats *v1alpha1.StatsSpec `json:"stats,omitempty"`
+
+	// Grouping gathers the changes of related documents, e.g. written by the
+	// same transaction, so that they can be handled together downstream.
+	// +optional
+	Grouping *v1alpha1.GroupingSpec `json:"grouping,omitempty"`
+
+	// Access restricts the events to the documents granting one of the roles
+	// of the source, so that the tenants of a shared database each get their
+	// own source.
+	// +optional
+	Access *v1alpha1.AccessSpec `json:"access,omitempty"`
+
+	// HealthMetrics makes the receive adapter scrape the health of the
+	// CouchDB server, and export it along with the metrics of the source.
+	// +optional
+	HealthMetrics *v1alpha1.HealthMetricsSpec `json:"healthMetrics,omitempty"`
+
+	// LagMetrics makes the receive adapter poll the update sequence of the
+	// database, and export how far behind it the source is.
+	// +optional
+	LagMetrics *v1alpha1.LagMetricsSpec `json:"lagMetrics,omitempty"`
+
+	// Liveness tunes when the receive adapter reports unhealthy on its
+	// liveness endpoint, so that Kubernetes restarts it.
+	// +optional
+	Liveness *v1alpha1.LivenessSpec `json:"liveness,omitempty"`
+
+	// HighAvailability runs standby replicas of the receive adapter, which
+	// take over within seconds when the replica delivering the changes
+	// fails, rather than waiting for its pod to be rescheduled.
+	// +optional
+	HighAvailability *v1alpha1.HighAvailabilitySpec `json:"highAvailability,omitempty"`
+
+	// Template customizes the pod of the receive adapter, e.g. to pin it to
+	// dedicated nodes or to size its resources.
+	// +optional
+	Template *v1alpha1.AdapterTemplateSpec `json:"template,omitempty"`
+
+	// Join enriches the changed documents with the documents of a secondary
+	// database of the server, e.g. a lookup table, whose IDs they hold.
+	// Experimental.
+	// +optional
+	Join *v1alpha1.JoinSpec `json:"join,omitempty"`
+
+	// Buffer keeps the events the sink does not accept on a persistent
+	// volume, so that the changes feed moves on during sink outages.
+	// +optional
+	Buffer *v1alpha1.BufferSpec `json:"buffer,omitempty"`
+}
+
+// Credentials locates the URL of the CouchDB server, with the credentials
+// to connect with.
+type Credentials struct {
+	// SecretRef names the Secret holding the URL under its url key. The
+	// namespace defaults to the one of the source. The fields other than the
+	// name and namespace are kept for the v1alpha1 sources, and ignored.
+	SecretRef *corev1.ObjectReference `json:"secretRef,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CouchDbSourceList contains a list of CouchDbSource
+type CouchDbSourceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CouchDbSource `json:"items"`
+}
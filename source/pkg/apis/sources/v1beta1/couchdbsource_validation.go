@@ -0,0 +1,36 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+
+	"knative.dev/pkg/apis"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+var _ apis.Validatable = (*CouchDbSource)(nil)
+
+// Validate validates the source as v1alpha1 does, through its conversion.
+func (c *CouchDbSource) Validate(ctx context.Context) *apis.FieldError {
+	src := &v1alpha1.CouchDbSource{}
+	if err := c.ConvertTo(ctx, src); err != nil {
+		return apis.ErrGeneric(err.Error())
+	}
+	return src.Validate(v1alpha1Context(ctx))
+}
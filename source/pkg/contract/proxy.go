@@ -0,0 +1,49 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contract
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ProxyFunc returns the proxy of the requests to CouchDB made by the receive
+// adapter and the controller: the proxy, except for the hosts and domain
+// suffixes listed in noProxy.
+func ProxyFunc(proxyURL *url.URL, noProxy []string) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		host := req.URL.Hostname()
+		for _, np := range noProxy {
+			np = strings.TrimSpace(np)
+			if np == "" {
+				continue
+			}
+			if np == "*" {
+				return nil, nil
+			}
+			if h, _, err := net.SplitHostPort(np); err == nil {
+				np = h
+			}
+			if host == np || strings.HasSuffix(host, "."+strings.TrimPrefix(np, ".")) {
+				return nil, nil
+			}
+		}
+		return proxyURL, nil
+	}
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contract
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestProxyFunc(t *testing.T) {
+	proxyURL, _ := url.Parse("http://proxy:3128")
+	noProxy := []string{"localhost", ".cluster.local", "internal.example.com:5984"}
+
+	testCases := map[string]struct {
+		url       string
+		wantProxy bool
+	}{
+		"external host":                       {url: "https://couchdb.example.com/db", wantProxy: true},
+		"exact match":                         {url: "http://localhost:5984/db"},
+		"domain suffix":                       {url: "http://couchdb.default.svc.cluster.local:5984/db"},
+		"host with port":                      {url: "http://internal.example.com:5984/db"},
+		"subdomain of exact":                  {url: "http://a.internal.example.com/db"},
+		"suffix lookalike":                    {url: "http://evilcluster.local/db", wantProxy: true},
+		"leading dot only matches subdomains": {url: "http://cluster.local/db", wantProxy: true},
+	}
+	proxy := ProxyFunc(proxyURL, noProxy)
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, tc.url, nil)
+			got, err := proxy(req)
+			if err != nil {
+				t.Fatal("proxy() =", err)
+			}
+			if (got != nil) != tc.wantProxy {
+				t.Errorf("proxy(%s) = %v, want proxied %v", tc.url, got, tc.wantProxy)
+			}
+		})
+	}
+}
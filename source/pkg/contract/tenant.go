@@ -0,0 +1,36 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package contract holds what the reconciler and the receive adapters agree
+// on without depending on each other: the ConfigMaps through which the
+// multi-tenant receive adapter serves sources, and how the requests to
+// CouchDB go through a proxy.
+package contract
+
+const (
+	// TenantLabelKey labels the ConfigMaps through which the multi-tenant
+	// receive adapter serves sources. Its value is the installation of the
+	// sources, empty for the default one.
+	TenantLabelKey = "couchdb.sources.knative.dev/tenant"
+
+	// TenantEnvKey holds the environment of the receive adapter of the
+	// source, as a JSON object.
+	TenantEnvKey = "env"
+
+	// TenantCredentialsKey holds the name of the secret of the CouchDB
+	// credentials, in the namespace of the source.
+	TenantCredentialsKey = "credentials"
+)
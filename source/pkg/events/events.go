@@ -0,0 +1,608 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package events describes the CloudEvents a CouchDbSource emits, so that Go
+// consumers can build and read them without duplicating the types, the
+// extension attributes and the shapes of the data.
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/types"
+	"gomodules.xyz/jsonpatch/v2"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+const (
+	// UpdateEventType is the type of the events of created or updated
+	// documents.
+	UpdateEventType = v1alpha1.CouchDbSourceUpdateEventType
+
+	// DeleteEventType is the type of the events of deleted documents.
+	DeleteEventType = v1alpha1.CouchDbSourceDeleteEventType
+
+	// ConflictEventType is the type of the events of documents with
+	// conflicting revisions, sent with spec.conflicts.
+	ConflictEventType = v1alpha1.CouchDbSourceConflictEventType
+
+	// UndecodableEventType is the type of the events sent to the dead letter
+	// sink for the changes that could not be turned into events, with
+	// spec.onDecodeError: deadLetter.
+	UndecodableEventType = v1alpha1.CouchDbSourceUndecodableEventType
+
+	// BatchEventType is the type of the events coalescing a group of changes,
+	// sent with spec.grouping.batch.
+	BatchEventType = v1alpha1.CouchDbSourceBatchEventType
+
+	// DatabaseCreatedEventType, DatabaseDeletedEventType and
+	// DatabaseUpdatedEventType are the types of the events of the databases
+	// of the server, sent with spec.databaseUpdates.
+	DatabaseCreatedEventType = v1alpha1.CouchDbSourceDatabaseCreatedEventType
+	DatabaseDeletedEventType = v1alpha1.CouchDbSourceDatabaseDeletedEventType
+	DatabaseUpdatedEventType = v1alpha1.CouchDbSourceDatabaseUpdatedEventType
+)
+
+// databaseEventTypes are the event types of the types of the updates of the
+// _db_updates feed.
+var databaseEventTypes = map[string]string{
+	"created": DatabaseCreatedEventType,
+	"deleted": DatabaseDeletedEventType,
+	"updated": DatabaseUpdatedEventType,
+}
+
+const (
+	// GroupExtension holds the key of the group of a grouped event.
+	GroupExtension = "couchdbgroup"
+
+	// GroupSizeExtension holds the number of events of the group of a grouped
+	// event. Batch events do not carry it.
+	GroupSizeExtension = "couchdbgroupsize"
+
+	// GroupIndexExtension holds the position of a grouped event within its
+	// group. Batch events do not carry it.
+	GroupIndexExtension = "couchdbgroupindex"
+
+	// AttemptsExtension holds, as a JSON array of DeliveryAttempt, the failed
+	// attempts of a dead lettered event.
+	AttemptsExtension = "couchdbattempts"
+
+	// ErrorCodeExtension holds the status code of the last failed attempt of
+	// a dead lettered event, as in the Knative channels.
+	ErrorCodeExtension = "knativeerrorcode"
+
+	// ErrorDestExtension holds the sink a dead lettered event failed to be
+	// delivered to, as in the Knative channels.
+	ErrorDestExtension = "knativeerrordest"
+
+	// ClusterExtension holds the identity of the cluster running the source,
+	// when configured in config-couchdb-identity.
+	ClusterExtension = "couchdbcluster"
+
+	// DatabaseExtension holds the name of the database the change comes
+	// from.
+	DatabaseExtension = "couchdbdatabase"
+
+	// SequenceExtension holds the update sequence of the change, from which
+	// the changes feed can be read again. Backfilled documents and the
+	// changes CouchDB did not compute the sequence of, as asked by
+	// spec.seqInterval, do not carry it. A batch event carries the sequence
+	// of its last change.
+	SequenceExtension = "couchdbsequence"
+
+	// RevExtension holds the changed revision of the document. Batch events
+	// do not carry it.
+	RevExtension = "couchdbrev"
+
+	// DeletedExtension holds whether the document was deleted. Batch events
+	// do not carry it.
+	DeletedExtension = "couchdbdeleted"
+)
+
+// ChangeExtensions are the extension attributes every event of a change
+// carries, which locate the change in the database and its changes feed.
+var ChangeExtensions = []string{DatabaseExtension, SequenceExtension, RevExtension, DeletedExtension}
+
+// Change is a change of a document, as reported by the update and delete
+// events. The data of these events is the JSON array of the revisions, unless
+// spec.attachments is set, in which case it is the document.
+type Change struct {
+	// Database is the name of the database the change comes from.
+	Database string
+
+	// ID is the ID of the changed document.
+	ID string
+
+	// Revisions are the leaf revisions of the document, the winning one
+	// first.
+	Revisions []string
+
+	// Deleted is whether the document was deleted.
+	Deleted bool
+
+	// Seq is the update sequence of the change. It is empty for backfilled
+	// documents.
+	Seq string
+}
+
+// Position locates a change in the database and its changes feed, as told
+// by the ChangeExtensions of its event.
+type Position struct {
+	// Database is the name of the database the change comes from.
+	Database string
+
+	// Sequence is the update sequence of the change, when known.
+	Sequence string
+
+	// Rev is the changed revision of the document.
+	Rev string
+
+	// Deleted is whether the document was deleted.
+	Deleted bool
+}
+
+// ConflictData is the data of the conflicted events.
+type ConflictData struct {
+	// Rev is the winning revision of the document.
+	Rev string `json:"rev"`
+
+	// Conflicts are the losing revisions in conflict with Rev.
+	Conflicts []string `json:"conflicts"`
+}
+
+// DiffData is the data of the update and delete events sent with
+// spec.payload: diff.
+type DiffData struct {
+	// Rev is the changed revision of the document.
+	Rev string `json:"rev"`
+
+	// Previous is the revision the patch applies to. It is empty when the
+	// document is new or its previous revision was compacted, in which case
+	// the patch adds the whole document.
+	Previous string `json:"previous,omitempty"`
+
+	// Patch is the JSON patch (RFC 6902) turning Previous into Rev, without
+	// the _rev and _revisions fields.
+	Patch []jsonpatch.Operation `json:"patch"`
+}
+
+// DocumentMetadata is the data of the update and delete events sent with
+// spec.payload: metadata, which leaves the contents of the document out.
+type DocumentMetadata struct {
+	// ID is the ID of the changed document.
+	ID string `json:"id"`
+
+	// Rev is the changed revision of the document.
+	Rev string `json:"rev"`
+
+	// Seq is the update sequence of the change. It is empty for the changes
+	// CouchDB did not compute the sequence of, as asked by spec.seqInterval.
+	Seq string `json:"seq,omitempty"`
+
+	// Deleted is whether the document was deleted.
+	Deleted bool `json:"deleted"`
+
+	// Database is the name of the database the change comes from.
+	Database string `json:"db"`
+}
+
+// DecodeErrorData is the data of the undecodable events.
+type DecodeErrorData struct {
+	// ID is the ID of the changed document.
+	ID string `json:"id"`
+
+	// Rev is the changed revision of the document.
+	Rev string `json:"rev,omitempty"`
+
+	// Seq is the update sequence of the change, empty while backfilling.
+	Seq string `json:"seq,omitempty"`
+
+	// Deleted is whether the document was deleted.
+	Deleted bool `json:"deleted"`
+
+	// Database is the name of the database the change comes from.
+	Database string `json:"db"`
+
+	// Error is why the change could not be turned into an event.
+	Error string `json:"error"`
+}
+
+// DatabaseUpdate is an update of a database of the server, as reported by
+// the _db_updates feed and carried by the database events.
+type DatabaseUpdate struct {
+	// Database is the name of the created, deleted or updated database.
+	Database string `json:"db_name"`
+
+	// Type is the type of the update: created, deleted or updated.
+	Type string `json:"type"`
+
+	// Seq is the sequence of the update in the _db_updates feed. CouchDB 1.x
+	// does not report it.
+	Seq string `json:"seq,omitempty"`
+}
+
+// BatchEntry is the representation of an event within the data of a batch
+// event.
+type BatchEntry struct {
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	Subject    string            `json:"subject"`
+	Extensions map[string]string `json:"extensions,omitempty"`
+	Data       json.RawMessage   `json:"data,omitempty"`
+}
+
+// DeliveryAttempt records the outcome of one failed delivery to the sink.
+type DeliveryAttempt struct {
+	Time       time.Time `json:"time"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	ErrorClass string    `json:"errorClass"`
+	Error      string    `json:"error"`
+}
+
+// Group is the position of a grouped event within its group.
+type Group struct {
+	// Key is the key of the group.
+	Key string
+
+	// Size and Index are the number of events of the group and the position
+	// of the event within it. They are zero for batch events.
+	Size  int
+	Index int
+}
+
+// NewChangeEvent returns the event the source sends by default for the
+// change, with the default ID and subject.
+func NewChangeEvent(source string, change Change) (cloudevents.Event, error) {
+	eventType := UpdateEventType
+	if change.Deleted {
+		eventType = DeleteEventType
+	}
+	event := newEvent(source, eventType, change.Database, change.ID, firstRev(change.Revisions))
+	SetPosition(&event, Position{
+		Database: change.Database,
+		Sequence: change.Seq,
+		Rev:      firstRev(change.Revisions),
+		Deleted:  change.Deleted,
+	})
+	revs := change.Revisions
+	if revs == nil {
+		revs = []string{}
+	}
+	return event, event.SetData(cloudevents.ApplicationJSON, revs)
+}
+
+// NewConflictEvent returns the event the source sends for a document of the
+// database with conflicting revisions, with the default ID and subject.
+func NewConflictEvent(source, database, id string, data ConflictData) (cloudevents.Event, error) {
+	event := newEvent(source, ConflictEventType, database, id, data.Rev)
+	SetPosition(&event, Position{Database: database, Rev: data.Rev})
+	return event, event.SetData(cloudevents.ApplicationJSON, data)
+}
+
+// NewUndecodableEvent returns the event the source sends to the dead letter
+// sink for a change it could not turn into an event.
+func NewUndecodableEvent(source string, data DecodeErrorData) (cloudevents.Event, error) {
+	event := newEvent(source, UndecodableEventType, data.Database, data.ID, data.Rev)
+	SetPosition(&event, Position{Database: data.Database, Sequence: data.Seq, Rev: data.Rev, Deleted: data.Deleted})
+	return event, event.SetData(cloudevents.ApplicationJSON, data)
+}
+
+// DatabaseEventType returns the event type of an update of the _db_updates
+// feed, or an empty string for the types of updates without events.
+func DatabaseEventType(updateType string) string {
+	return databaseEventTypes[updateType]
+}
+
+// NewDatabaseEvent returns the event the source sends for an update of the
+// _db_updates feed. Its subject is the name of the database.
+func NewDatabaseEvent(source string, update DatabaseUpdate) (cloudevents.Event, error) {
+	event := cloudevents.NewEvent(cloudevents.VersionV1)
+	eventType := DatabaseEventType(update.Type)
+	if eventType == "" {
+		return event, fmt.Errorf("unknown type %q of the update of the database %s", update.Type, update.Database)
+	}
+	seq := update.Seq
+	if seq == "" {
+		// Without a sequence, the time tells the updates of a database apart.
+		seq = strconv.FormatInt(time.Now().UnixNano(), 10)
+	}
+	event.SetID(update.Database + "/" + update.Type + "/" + seq)
+	event.SetSource(source)
+	event.SetType(eventType)
+	event.SetSubject(update.Database)
+	event.SetExtension(DatabaseExtension, update.Database)
+	return event, event.SetData(cloudevents.ApplicationJSON, update)
+}
+
+// NewBatchEvent coalesces the events into a single event whose data is the
+// JSON array of their entries, each with the ChangeExtensions of its event.
+// It takes the ID and source of the last event, and its database and
+// sequence.
+func NewBatchEvent(events ...cloudevents.Event) (cloudevents.Event, error) {
+	event := cloudevents.NewEvent(cloudevents.VersionV1)
+	if len(events) == 0 {
+		return event, fmt.Errorf("a batch needs at least one event")
+	}
+	entries := make([]BatchEntry, 0, len(events))
+	for _, e := range events {
+		entry := BatchEntry{
+			ID:      e.ID(),
+			Type:    e.Type(),
+			Subject: e.Subject(),
+			Data:    e.Data(),
+		}
+		for _, n := range ChangeExtensions {
+			v, ok := e.Extensions()[n]
+			if !ok {
+				continue
+			}
+			s, err := types.Format(v)
+			if err != nil {
+				return event, fmt.Errorf("invalid %s extension: %w", n, err)
+			}
+			if entry.Extensions == nil {
+				entry.Extensions = make(map[string]string)
+			}
+			entry.Extensions[n] = s
+		}
+		entries = append(entries, entry)
+	}
+
+	last := events[len(events)-1]
+	event.SetID(last.ID())
+	event.SetSource(last.Source())
+	event.SetType(BatchEventType)
+	for _, n := range []string{DatabaseExtension, SequenceExtension} {
+		if v, ok := last.Extensions()[n]; ok {
+			event.SetExtension(n, v)
+		}
+	}
+	return event, event.SetData(cloudevents.ApplicationJSON, entries)
+}
+
+// SetPosition sets the ChangeExtensions of the event. The sequence is left
+// out when unknown.
+func SetPosition(event *cloudevents.Event, p Position) {
+	event.SetExtension(DatabaseExtension, p.Database)
+	if p.Sequence != "" {
+		event.SetExtension(SequenceExtension, p.Sequence)
+	}
+	event.SetExtension(RevExtension, p.Rev)
+	event.SetExtension(DeletedExtension, p.Deleted)
+}
+
+// PositionOf returns the position of the change of an event, and false when
+// the event does not carry it, e.g. a batch event.
+func PositionOf(event cloudevents.Event) (Position, bool, error) {
+	ext := event.Extensions()
+	rev, ok := ext[RevExtension]
+	if !ok {
+		return Position{}, false, nil
+	}
+	var p Position
+	var err error
+	if p.Rev, err = types.ToString(rev); err != nil {
+		return Position{}, false, fmt.Errorf("invalid %s extension: %w", RevExtension, err)
+	}
+	if v, ok := ext[DatabaseExtension]; ok {
+		if p.Database, err = types.ToString(v); err != nil {
+			return Position{}, false, fmt.Errorf("invalid %s extension: %w", DatabaseExtension, err)
+		}
+	}
+	if v, ok := ext[SequenceExtension]; ok {
+		if p.Sequence, err = types.ToString(v); err != nil {
+			return Position{}, false, fmt.Errorf("invalid %s extension: %w", SequenceExtension, err)
+		}
+	}
+	if v, ok := ext[DeletedExtension]; ok {
+		if p.Deleted, err = types.ToBool(v); err != nil {
+			return Position{}, false, fmt.Errorf("invalid %s extension: %w", DeletedExtension, err)
+		}
+	}
+	return p, true, nil
+}
+
+func newEvent(source, eventType, database, id, rev string) cloudevents.Event {
+	event := cloudevents.NewEvent(cloudevents.VersionV1)
+	event.SetID(v1alpha1.DefaultEventID(v1alpha1.SubjectData{ID: id, Rev: rev, Database: database}))
+	event.SetSource(source)
+	event.SetType(eventType)
+	event.SetSubject(id)
+	return event
+}
+
+// firstRev returns the first revision listed by a change, which is the
+// winning revision unless the document is in conflict.
+func firstRev(revs []string) string {
+	if len(revs) == 0 {
+		return ""
+	}
+	return revs[0]
+}
+
+// Revisions returns the revisions carried by an update or delete event sent
+// without spec.attachments.
+func Revisions(event cloudevents.Event) ([]string, error) {
+	var revs []string
+	if err := decode(event, &revs, UpdateEventType, DeleteEventType); err != nil {
+		return nil, err
+	}
+	return revs, nil
+}
+
+// Document returns the document carried by an update or delete event sent
+// with spec.attachments.
+func Document(event cloudevents.Event) (map[string]interface{}, error) {
+	var doc map[string]interface{}
+	if err := decode(event, &doc, UpdateEventType, DeleteEventType); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Diff returns the patch carried by an update or delete event sent with
+// spec.payload: diff.
+func Diff(event cloudevents.Event) (*DiffData, error) {
+	data := &DiffData{}
+	if err := decode(event, data, UpdateEventType, DeleteEventType); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Metadata returns the metadata carried by an update or delete event sent
+// with spec.payload: metadata.
+func Metadata(event cloudevents.Event) (*DocumentMetadata, error) {
+	data := &DocumentMetadata{}
+	if err := decode(event, data, UpdateEventType, DeleteEventType); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Conflict returns the data of a conflicted event.
+func Conflict(event cloudevents.Event) (*ConflictData, error) {
+	data := &ConflictData{}
+	if err := decode(event, data, ConflictEventType); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// DecodeError returns the data of an undecodable event.
+func DecodeError(event cloudevents.Event) (*DecodeErrorData, error) {
+	data := &DecodeErrorData{}
+	if err := decode(event, data, UndecodableEventType); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Database returns the update of a database event.
+func Database(event cloudevents.Event) (*DatabaseUpdate, error) {
+	data := &DatabaseUpdate{}
+	if err := decode(event, data, DatabaseCreatedEventType, DatabaseDeletedEventType, DatabaseUpdatedEventType); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// BatchEntries returns the entries of a batch event.
+func BatchEntries(event cloudevents.Event) ([]BatchEntry, error) {
+	var entries []BatchEntry
+	if err := decode(event, &entries, BatchEventType); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Event returns the event an entry of a batch stands for, with the source
+// and the extensions of the batch, and the ChangeExtensions of the entry.
+func (e BatchEntry) Event(batch cloudevents.Event) (cloudevents.Event, error) {
+	event := cloudevents.NewEvent(cloudevents.VersionV1)
+	event.SetID(e.ID)
+	event.SetSource(batch.Source())
+	event.SetType(e.Type)
+	event.SetSubject(e.Subject)
+	for n, v := range batch.Extensions() {
+		if n == SequenceExtension {
+			// The sequence of the batch is the one of its last entry.
+			continue
+		}
+		event.SetExtension(n, v)
+	}
+	for n, v := range e.Extensions {
+		event.SetExtension(n, v)
+	}
+	if len(e.Data) == 0 {
+		return event, nil
+	}
+	return event, event.SetData(cloudevents.ApplicationJSON, []byte(e.Data))
+}
+
+// GroupOf returns the group of a grouped event, and false when the event is
+// not grouped.
+func GroupOf(event cloudevents.Event) (Group, bool, error) {
+	ext := event.Extensions()
+	key, ok := ext[GroupExtension]
+	if !ok {
+		return Group{}, false, nil
+	}
+	var group Group
+	var err error
+	if group.Key, err = types.ToString(key); err != nil {
+		return Group{}, false, fmt.Errorf("invalid %s extension: %w", GroupExtension, err)
+	}
+	if group.Size, err = intExtension(ext, GroupSizeExtension); err != nil {
+		return Group{}, false, err
+	}
+	if group.Index, err = intExtension(ext, GroupIndexExtension); err != nil {
+		return Group{}, false, err
+	}
+	return group, true, nil
+}
+
+// Attempts returns the failed delivery attempts of a dead lettered event,
+// and nil when the event was not dead lettered.
+func Attempts(event cloudevents.Event) ([]DeliveryAttempt, error) {
+	v, ok := event.Extensions()[AttemptsExtension]
+	if !ok {
+		return nil, nil
+	}
+	history, err := types.ToString(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s extension: %w", AttemptsExtension, err)
+	}
+	var attempts []DeliveryAttempt
+	if err := json.Unmarshal([]byte(history), &attempts); err != nil {
+		return nil, fmt.Errorf("invalid %s extension: %w", AttemptsExtension, err)
+	}
+	return attempts, nil
+}
+
+// decode unmarshals the data of the event, which must be of one of the
+// types.
+func decode(event cloudevents.Event, data interface{}, eventTypes ...string) error {
+	for _, t := range eventTypes {
+		if event.Type() == t {
+			if err := event.DataAs(data); err != nil {
+				return fmt.Errorf("invalid data of %s event %s: %w", t, event.ID(), err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("unexpected type %s of event %s", event.Type(), event.ID())
+}
+
+// intExtension returns the integer extension attribute, or zero when the
+// event does not carry it. Integers are strings once the event went through
+// the binary content mode.
+func intExtension(ext map[string]interface{}, name string) (int, error) {
+	v, ok := ext[name]
+	if !ok {
+		return 0, nil
+	}
+	i, err := types.ToInteger(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s extension: %w", name, err)
+	}
+	return int(i), nil
+}
@@ -0,0 +1,366 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/go-cmp/cmp"
+	"gomodules.xyz/jsonpatch/v2"
+)
+
+func TestChangeEvent(t *testing.T) {
+	testCases := map[string]struct {
+		change   Change
+		wantType string
+		wantID   string
+	}{
+		"update": {
+			change:   Change{Database: "db", ID: "doc", Revisions: []string{"2-a"}},
+			wantType: UpdateEventType,
+			wantID:   "db/doc/2-a",
+		},
+		"delete": {
+			change:   Change{Database: "db", ID: "doc", Revisions: []string{"3-b"}, Deleted: true},
+			wantType: DeleteEventType,
+			wantID:   "db/doc/3-b",
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			event, err := NewChangeEvent("http://couchdb/db", tc.change)
+			if err != nil {
+				t.Fatalf("NewChangeEvent() = %v", err)
+			}
+			if err := event.Validate(); err != nil {
+				t.Errorf("invalid event: %v", err)
+			}
+			if event.Type() != tc.wantType || event.ID() != tc.wantID || event.Subject() != "doc" {
+				t.Errorf("event = %s %s %s, want %s %s doc", event.Type(), event.ID(), event.Subject(), tc.wantType, tc.wantID)
+			}
+			revs, err := Revisions(event)
+			if err != nil {
+				t.Fatalf("Revisions() = %v", err)
+			}
+			if diff := cmp.Diff(tc.change.Revisions, revs); diff != "" {
+				t.Errorf("unexpected revisions (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
+
+func TestDocument(t *testing.T) {
+	event := cloudevents.NewEvent()
+	event.SetType(UpdateEventType)
+	if err := event.SetData(cloudevents.ApplicationJSON, map[string]interface{}{"_id": "doc", "_rev": "1-a"}); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := Document(event)
+	if err != nil {
+		t.Fatalf("Document() = %v", err)
+	}
+	if doc["_id"] != "doc" {
+		t.Errorf("Document() = %v, want doc", doc)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	event := cloudevents.NewEvent()
+	event.SetType(UpdateEventType)
+	if err := event.SetData(cloudevents.ApplicationJSON, map[string]interface{}{
+		"rev":      "2-b",
+		"previous": "1-a",
+		"patch":    []interface{}{map[string]interface{}{"op": "replace", "path": "/status", "value": "shipped"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Diff(event)
+	if err != nil {
+		t.Fatalf("Diff() = %v", err)
+	}
+	want := &DiffData{
+		Rev:      "2-b",
+		Previous: "1-a",
+		Patch:    []jsonpatch.Operation{{Operation: "replace", Path: "/status", Value: "shipped"}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected diff data (-want, +got) = %v", diff)
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	event := cloudevents.NewEvent()
+	event.SetType(DeleteEventType)
+	if err := event.SetData(cloudevents.ApplicationJSON, []byte(`{"id":"doc","rev":"3-c","seq":"42-x","deleted":true,"db":"orders"}`)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Metadata(event)
+	if err != nil {
+		t.Fatalf("Metadata() = %v", err)
+	}
+	want := &DocumentMetadata{ID: "doc", Rev: "3-c", Seq: "42-x", Deleted: true, Database: "orders"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected metadata (-want, +got) = %v", diff)
+	}
+}
+
+func TestConflictEvent(t *testing.T) {
+	want := ConflictData{Rev: "2-x", Conflicts: []string{"2-y", "2-z"}}
+	event, err := NewConflictEvent("http://couchdb/db", "db", "doc", want)
+	if err != nil {
+		t.Fatalf("NewConflictEvent() = %v", err)
+	}
+	if event.Type() != ConflictEventType || event.ID() != "db/doc/2-x" {
+		t.Errorf("event = %s %s, want %s db/doc/2-x", event.Type(), event.ID(), ConflictEventType)
+	}
+	got, err := Conflict(event)
+	if err != nil {
+		t.Fatalf("Conflict() = %v", err)
+	}
+	if diff := cmp.Diff(want, *got); diff != "" {
+		t.Errorf("unexpected conflict data (-want, +got) = %v", diff)
+	}
+
+	if _, err := Revisions(event); err == nil {
+		t.Error("Revisions() of a conflicted event succeeded, want an error")
+	}
+}
+
+func TestUndecodableEvent(t *testing.T) {
+	want := DecodeErrorData{ID: "doc", Rev: "2-x", Seq: "7-g1A", Database: "db", Error: "unexpected end of JSON input"}
+	event, err := NewUndecodableEvent("http://couchdb/db", want)
+	if err != nil {
+		t.Fatalf("NewUndecodableEvent() = %v", err)
+	}
+	if event.Type() != UndecodableEventType || event.ID() != "db/doc/2-x" {
+		t.Errorf("event = %s %s, want %s db/doc/2-x", event.Type(), event.ID(), UndecodableEventType)
+	}
+	got, err := DecodeError(event)
+	if err != nil {
+		t.Fatalf("DecodeError() = %v", err)
+	}
+	if diff := cmp.Diff(want, *got); diff != "" {
+		t.Errorf("unexpected decode error data (-want, +got) = %v", diff)
+	}
+	position, ok, err := PositionOf(event)
+	if err != nil || !ok {
+		t.Fatalf("PositionOf() = %v, %v", ok, err)
+	}
+	if position.Sequence != want.Seq {
+		t.Errorf("sequence = %q, want %q", position.Sequence, want.Seq)
+	}
+}
+
+func TestDatabaseEvent(t *testing.T) {
+	want := DatabaseUpdate{Database: "tenant-42", Type: "created", Seq: "3-g1A"}
+	event, err := NewDatabaseEvent("http://couchdb/db", want)
+	if err != nil {
+		t.Fatalf("NewDatabaseEvent() = %v", err)
+	}
+	if event.Type() != DatabaseCreatedEventType || event.ID() != "tenant-42/created/3-g1A" || event.Subject() != "tenant-42" {
+		t.Errorf("event = %s %s %s, want %s tenant-42/created/3-g1A tenant-42", event.Type(), event.ID(), event.Subject(), DatabaseCreatedEventType)
+	}
+	got, err := Database(event)
+	if err != nil {
+		t.Fatalf("Database() = %v", err)
+	}
+	if diff := cmp.Diff(want, *got); diff != "" {
+		t.Errorf("unexpected database update (-want, +got) = %v", diff)
+	}
+	if _, ok, _ := PositionOf(event); ok {
+		t.Error("PositionOf() of a database event = true, want false")
+	}
+
+	if _, err := NewDatabaseEvent("http://couchdb/db", DatabaseUpdate{Database: "tenant-42", Type: "ddoc_updated"}); err == nil {
+		t.Error("NewDatabaseEvent() of an unknown update type succeeded, want an error")
+	}
+}
+
+func TestBatchEvent(t *testing.T) {
+	if _, err := NewBatchEvent(); err == nil {
+		t.Error("NewBatchEvent() of no event succeeded, want an error")
+	}
+
+	first, err := NewChangeEvent("http://couchdb/db", Change{Database: "db", ID: "order", Revisions: []string{"1-a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewChangeEvent("http://couchdb/db", Change{Database: "db", ID: "line", Revisions: []string{"1-b"}, Deleted: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch, err := NewBatchEvent(first, second)
+	if err != nil {
+		t.Fatalf("NewBatchEvent() = %v", err)
+	}
+	if batch.Type() != BatchEventType || batch.ID() != second.ID() {
+		t.Errorf("batch = %s %s, want %s %s", batch.Type(), batch.ID(), BatchEventType, second.ID())
+	}
+	batch.SetExtension(GroupExtension, "order")
+
+	entries, err := BatchEntries(batch)
+	if err != nil {
+		t.Fatalf("BatchEntries() = %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("BatchEntries() = %+v, want 2 entries", entries)
+	}
+	for i, want := range []cloudevents.Event{first, second} {
+		got, err := entries[i].Event(batch)
+		if err != nil {
+			t.Fatalf("Event() = %v", err)
+		}
+		if got.ID() != want.ID() || got.Type() != want.Type() || got.Subject() != want.Subject() || got.Source() != want.Source() {
+			t.Errorf("Event() = %v, want %v", got, want)
+		}
+		if diff := cmp.Diff(string(want.Data()), string(got.Data())); diff != "" {
+			t.Errorf("unexpected data (-want, +got) = %v", diff)
+		}
+		if got.Extensions()[GroupExtension] != "order" {
+			t.Errorf("Event() extensions = %v, want the group of the batch", got.Extensions())
+		}
+	}
+}
+
+func TestPositionOf(t *testing.T) {
+	first, err := NewChangeEvent("http://couchdb/db", Change{Database: "db", ID: "order", Revisions: []string{"2-a"}, Seq: "12-g"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Backfilled documents have no sequence.
+	second, err := NewChangeEvent("http://couchdb/db", Change{Database: "db", ID: "line", Revisions: []string{"3-b"}, Deleted: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Position{
+		{Database: "db", Sequence: "12-g", Rev: "2-a"},
+		{Database: "db", Rev: "3-b", Deleted: true},
+	}
+	for i, event := range []cloudevents.Event{first, second} {
+		got, ok, err := PositionOf(event)
+		if err != nil || !ok {
+			t.Fatalf("PositionOf() = %v, %v", ok, err)
+		}
+		if diff := cmp.Diff(want[i], got); diff != "" {
+			t.Errorf("unexpected position (-want, +got) = %v", diff)
+		}
+	}
+
+	batch, err := NewBatchEvent(second, first)
+	if err != nil {
+		t.Fatalf("NewBatchEvent() = %v", err)
+	}
+	if _, ok, err := PositionOf(batch); ok || err != nil {
+		t.Errorf("PositionOf(batch) = %v, %v, want no position", ok, err)
+	}
+	if seq := batch.Extensions()[SequenceExtension]; seq != "12-g" {
+		t.Errorf("batch sequence = %v, want the one of its last event", seq)
+	}
+
+	// The entries keep their own positions through the batch.
+	entries, err := BatchEntries(batch)
+	if err != nil {
+		t.Fatalf("BatchEntries() = %v", err)
+	}
+	for i, entry := range entries {
+		event, err := entry.Event(batch)
+		if err != nil {
+			t.Fatalf("Event() = %v", err)
+		}
+		got, ok, err := PositionOf(event)
+		if err != nil || !ok {
+			t.Fatalf("PositionOf() = %v, %v", ok, err)
+		}
+		if diff := cmp.Diff(want[1-i], got); diff != "" {
+			t.Errorf("unexpected position of entry %d (-want, +got) = %v", i, diff)
+		}
+	}
+}
+
+func TestGroupOf(t *testing.T) {
+	testCases := map[string]struct {
+		extensions map[string]interface{}
+		want       Group
+		wantOK     bool
+		wantErr    bool
+	}{
+		"not grouped": {},
+		"grouped": {
+			extensions: map[string]interface{}{GroupExtension: "t1", GroupSizeExtension: 2, GroupIndexExtension: 1},
+			want:       Group{Key: "t1", Size: 2, Index: 1},
+			wantOK:     true,
+		},
+		"binary content mode": {
+			extensions: map[string]interface{}{GroupExtension: "t1", GroupSizeExtension: "2", GroupIndexExtension: "0"},
+			want:       Group{Key: "t1", Size: 2},
+			wantOK:     true,
+		},
+		"batch": {
+			extensions: map[string]interface{}{GroupExtension: "t1"},
+			want:       Group{Key: "t1"},
+			wantOK:     true,
+		},
+		"invalid size": {
+			extensions: map[string]interface{}{GroupExtension: "t1", GroupSizeExtension: "two"},
+			wantErr:    true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			event := cloudevents.NewEvent()
+			for k, v := range tc.extensions {
+				event.SetExtension(k, v)
+			}
+			got, ok, err := GroupOf(event)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("GroupOf() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want || ok != tc.wantOK {
+				t.Errorf("GroupOf() = %+v, %v, want %+v, %v", got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestAttempts(t *testing.T) {
+	event := cloudevents.NewEvent()
+	if got, err := Attempts(event); got != nil || err != nil {
+		t.Errorf("Attempts() = %v, %v, want nil", got, err)
+	}
+
+	event.SetExtension(AttemptsExtension, `[{"time":"2020-01-02T03:04:05Z","statusCode":503,"errorClass":"server","error":"unavailable"}]`)
+	got, err := Attempts(event)
+	if err != nil {
+		t.Fatalf("Attempts() = %v", err)
+	}
+	want := []DeliveryAttempt{{
+		Time:       time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		StatusCode: 503,
+		ErrorClass: "server",
+		Error:      "unavailable",
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected attempts (-want, +got) = %v", diff)
+	}
+
+	event.SetExtension(AttemptsExtension, "not json")
+	if _, err := Attempts(event); err == nil {
+		t.Error("Attempts() of an invalid extension succeeded, want an error")
+	}
+}
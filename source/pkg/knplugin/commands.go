@@ -0,0 +1,230 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package knplugin
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"text/tabwriter"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+// specFlags are the flags setting the spec of a source.
+type specFlags struct {
+	database    string
+	credentials string
+	sink        string
+	filter      string
+}
+
+func (f *specFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.database, "database", "", "database whose changes the source sends")
+	fs.StringVar(&f.credentials, "credentials", "", "secret holding the url of CouchDB")
+	fs.StringVar(&f.sink, "sink", "", "sink of the events: ksvc:NAME, broker:NAME, channel:NAME, svc:NAME or an http(s) URL")
+	fs.StringVar(&f.filter, "filter", "", "expression the changed documents must satisfy, e.g. doc.type == \"order\"")
+}
+
+// apply sets the fields of the spec whose flags are set.
+func (f *specFlags) apply(fs *flag.FlagSet, spec *v1alpha1.CouchDbSourceSpec) error {
+	var err error
+	fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "database":
+			spec.Database = f.database
+		case "credentials":
+			spec.CouchDbCredentials.Name = f.credentials
+		case "filter":
+			spec.Match = f.filter
+		case "sink":
+			if f.sink == "" {
+				spec.Sink = nil
+				return
+			}
+			sink, serr := parseSink(f.sink)
+			if serr != nil {
+				err = serr
+				return
+			}
+			spec.Sink = sink
+		}
+	})
+	return err
+}
+
+func (p *Plugin) create(ctx context.Context, args []string) error {
+	var common commonFlags
+	var spec specFlags
+	fs := p.newFlagSet("create", &common)
+	spec.register(fs)
+	name, err := parseName(fs, args)
+	if err != nil {
+		return err
+	}
+	if spec.database == "" {
+		return errors.New("create requires --database")
+	}
+	if spec.credentials == "" {
+		return errors.New("create requires --credentials")
+	}
+
+	src := &v1alpha1.CouchDbSource{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if err := spec.apply(fs, &src.Spec); err != nil {
+		return err
+	}
+	sources, namespace, err := p.sources(&common)
+	if err != nil {
+		return err
+	}
+	if _, err := sources.Create(ctx, src, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("unable to create the source %q: %v", name, err)
+	}
+	fmt.Fprintf(p.Out, "CouchDB source '%s' created in namespace '%s'.\n", name, namespace)
+	return nil
+}
+
+func (p *Plugin) update(ctx context.Context, args []string) error {
+	var common commonFlags
+	var spec specFlags
+	fs := p.newFlagSet("update", &common)
+	spec.register(fs)
+	name, err := parseName(fs, args)
+	if err != nil {
+		return err
+	}
+
+	sources, namespace, err := p.sources(&common)
+	if err != nil {
+		return err
+	}
+	src, err := sources.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("unable to get the source %q: %v", name, err)
+	}
+	if err := spec.apply(fs, &src.Spec); err != nil {
+		return err
+	}
+	if _, err := sources.Update(ctx, src, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("unable to update the source %q: %v", name, err)
+	}
+	fmt.Fprintf(p.Out, "CouchDB source '%s' updated in namespace '%s'.\n", name, namespace)
+	return nil
+}
+
+func (p *Plugin) describe(ctx context.Context, args []string) error {
+	var common commonFlags
+	fs := p.newFlagSet("describe", &common)
+	name, err := parseName(fs, args)
+	if err != nil {
+		return err
+	}
+
+	sources, _, err := p.sources(&common)
+	if err != nil {
+		return err
+	}
+	src, err := sources.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("unable to get the source %q: %v", name, err)
+	}
+
+	w := tabwriter.NewWriter(p.Out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", src.Name)
+	fmt.Fprintf(w, "Namespace:\t%s\n", src.Namespace)
+	fmt.Fprintf(w, "Database:\t%s\n", src.Spec.Database)
+	fmt.Fprintf(w, "Credentials:\t%s\n", src.Spec.CouchDbCredentials.Name)
+	if src.Spec.Match != "" {
+		fmt.Fprintf(w, "Filter:\t%s\n", src.Spec.Match)
+	}
+	if src.Spec.Sink != nil {
+		fmt.Fprintf(w, "Sink:\t%s\n", formatSink(src.Spec.Sink))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(src.Status.Conditions) == 0 {
+		return nil
+	}
+	fmt.Fprintln(p.Out, "\nConditions:")
+	w = tabwriter.NewWriter(p.Out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "  TYPE\tSTATUS\tREASON\tMESSAGE")
+	for _, c := range src.Status.Conditions {
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", c.Type, c.Status, c.Reason, c.Message)
+	}
+	return w.Flush()
+}
+
+func (p *Plugin) delete(ctx context.Context, args []string) error {
+	var common commonFlags
+	fs := p.newFlagSet("delete", &common)
+	name, err := parseName(fs, args)
+	if err != nil {
+		return err
+	}
+
+	sources, namespace, err := p.sources(&common)
+	if err != nil {
+		return err
+	}
+	if err := sources.Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+		return fmt.Errorf("unable to delete the source %q: %v", name, err)
+	}
+	fmt.Fprintf(p.Out, "CouchDB source '%s' deleted in namespace '%s'.\n", name, namespace)
+	return nil
+}
+
+func (p *Plugin) list(ctx context.Context, args []string) error {
+	var common commonFlags
+	fs := p.newFlagSet("list", &common)
+	positional, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return errors.New("list takes no argument")
+	}
+
+	sources, namespace, err := p.sources(&common)
+	if err != nil {
+		return err
+	}
+	list, err := sources.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("unable to list the sources: %v", err)
+	}
+	if len(list.Items) == 0 {
+		fmt.Fprintf(p.Out, "No CouchDB sources found in namespace '%s'.\n", namespace)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(p.Out, 0, 4, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tDATABASE\tSINK\tREADY\tREASON")
+	for _, src := range list.Items {
+		ready, reason := "Unknown", ""
+		if c := src.Status.GetCondition(apis.ConditionReady); c != nil {
+			ready, reason = string(c.Status), c.Reason
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", src.Name, src.Spec.Database, formatSink(src.Spec.Sink), ready, reason)
+	}
+	return w.Flush()
+}
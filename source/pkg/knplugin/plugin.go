@@ -0,0 +1,137 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package knplugin implements kn-source-couchdb, the plugin of the kn CLI
+// managing the CouchDbSources of a namespace, e.g.
+//
+//	kn source couchdb create orders --database orders --credentials couchdb-binding --sink ksvc:invoicing
+package knplugin
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	clientv1alpha1 "knative.dev/eventing-couchdb/source/pkg/client/clientset/versioned/typed/sources/v1alpha1"
+)
+
+// ClientFunc returns the client of the sources of the cluster of the
+// kubeconfig, and the namespace of its current context.
+type ClientFunc func(kubeconfig string) (clientv1alpha1.SourcesV1alpha1Interface, string, error)
+
+// Plugin runs the commands of the plugin.
+type Plugin struct {
+	// Out receives the output of the commands.
+	Out io.Writer
+	// Client connects to the cluster.
+	Client ClientFunc
+}
+
+const usage = `Manage the CouchDB sources.
+
+Usage:
+  kn source couchdb create NAME --database DATABASE --credentials SECRET [--sink SINK] [--filter EXPRESSION]
+  kn source couchdb update NAME [--database DATABASE] [--credentials SECRET] [--sink SINK] [--filter EXPRESSION]
+  kn source couchdb describe NAME
+  kn source couchdb delete NAME
+  kn source couchdb list
+
+The commands accept -n/--namespace and --kubeconfig. The sink is given as
+ksvc:NAME, broker:NAME, channel:NAME, svc:NAME or as an http(s) URL.
+`
+
+// Run runs the command of the arguments, without the name of the plugin.
+func (p *Plugin) Run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(p.Out, usage)
+		return flag.ErrHelp
+	}
+
+	switch args[0] {
+	case "create":
+		return p.create(ctx, args[1:])
+	case "update":
+		return p.update(ctx, args[1:])
+	case "describe":
+		return p.describe(ctx, args[1:])
+	case "delete":
+		return p.delete(ctx, args[1:])
+	case "list":
+		return p.list(ctx, args[1:])
+	case "help", "-h", "--help":
+		fmt.Fprint(p.Out, usage)
+		return nil
+	}
+	return fmt.Errorf("unknown command %q, expected one of create, update, describe, delete or list", args[0])
+}
+
+// commonFlags are the flags of every command.
+type commonFlags struct {
+	namespace  string
+	kubeconfig string
+}
+
+func (p *Plugin) newFlagSet(command string, common *commonFlags) *flag.FlagSet {
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	fs.SetOutput(p.Out)
+	fs.StringVar(&common.namespace, "namespace", "", "namespace of the sources, defaults to the one of the current context")
+	fs.StringVar(&common.namespace, "n", "", "shorthand for --namespace")
+	fs.StringVar(&common.kubeconfig, "kubeconfig", "", "path to the kubeconfig, defaults to $KUBECONFIG or ~/.kube/config")
+	return fs
+}
+
+// parse parses the flags of the command, which may come before, after or
+// between its positional arguments, and returns the positional arguments.
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// parseName parses the flags of a command taking the name of a source.
+func parseName(fs *flag.FlagSet, args []string) (string, error) {
+	positional, err := parse(fs, args)
+	if err != nil {
+		return "", err
+	}
+	if len(positional) != 1 {
+		return "", fmt.Errorf("%s takes the name of a source, got %q", fs.Name(), strings.Join(positional, " "))
+	}
+	return positional[0], nil
+}
+
+// sources returns the client of the sources of the namespace of the flags.
+func (p *Plugin) sources(common *commonFlags) (clientv1alpha1.CouchDbSourceInterface, string, error) {
+	client, namespace, err := p.Client(common.kubeconfig)
+	if err != nil {
+		return nil, "", err
+	}
+	if common.namespace != "" {
+		namespace = common.namespace
+	}
+	return client.CouchDbSources(namespace), namespace, nil
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package knplugin
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-couchdb/source/pkg/client/clientset/versioned/fake"
+	clientv1alpha1 "knative.dev/eventing-couchdb/source/pkg/client/clientset/versioned/typed/sources/v1alpha1"
+)
+
+func newPlugin(out *bytes.Buffer, clientset *fake.Clientset) *Plugin {
+	return &Plugin{
+		Out: out,
+		Client: func(string) (clientv1alpha1.SourcesV1alpha1Interface, string, error) {
+			return clientset.SourcesV1alpha1(), "default", nil
+		},
+	}
+}
+
+func TestCreateUpdate(t *testing.T) {
+	ctx := context.Background()
+	clientset := fake.NewSimpleClientset()
+	var out bytes.Buffer
+	plugin := newPlugin(&out, clientset)
+
+	err := plugin.Run(ctx, []string{"create", "orders", "-n", "shop",
+		"--database", "orders", "--credentials", "couchdb-binding", "--sink", "broker:default"})
+	if err != nil {
+		t.Fatal("create:", err)
+	}
+	if got, want := out.String(), "CouchDB source 'orders' created in namespace 'shop'.\n"; got != want {
+		t.Errorf("create output = %q, want %q", got, want)
+	}
+
+	err = plugin.Run(ctx, []string{"update", "--namespace", "shop", "orders", "--filter", `doc.type == "order"`})
+	if err != nil {
+		t.Fatal("update:", err)
+	}
+	src, err := clientset.SourcesV1alpha1().CouchDbSources("shop").Get(ctx, "orders", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal("Get:", err)
+	}
+	want := v1alpha1.CouchDbSourceSpec{
+		Database:           "orders",
+		CouchDbCredentials: corev1.ObjectReference{Name: "couchdb-binding"},
+		Match:              `doc.type == "order"`,
+		Sink: &duckv1.Destination{
+			Ref: &duckv1.KReference{APIVersion: "eventing.knative.dev/v1", Kind: "Broker", Name: "default"},
+		},
+	}
+	if diff := cmp.Diff(want, src.Spec); diff != "" {
+		t.Error("Unexpected spec (-want, +got):", diff)
+	}
+}
+
+func TestCreateErrors(t *testing.T) {
+	tests := map[string][]string{
+		"no name":        {"create", "--database", "orders", "--credentials", "couchdb-binding"},
+		"two names":      {"create", "a", "b", "--database", "orders", "--credentials", "couchdb-binding"},
+		"no database":    {"create", "orders", "--credentials", "couchdb-binding"},
+		"no credentials": {"create", "orders", "--database", "orders"},
+		"bad sink":       {"create", "orders", "--database", "orders", "--credentials", "couchdb-binding", "--sink", "pod:a"},
+		"unknown flag":   {"create", "orders", "--table", "orders"},
+	}
+	for n, args := range tests {
+		t.Run(n, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset()
+			if err := newPlugin(&bytes.Buffer{}, clientset).Run(context.Background(), args); err == nil {
+				t.Error("Run() = nil, want an error")
+			}
+			if list, _ := clientset.SourcesV1alpha1().CouchDbSources("default").List(context.Background(), metav1.ListOptions{}); len(list.Items) != 0 {
+				t.Errorf("Created %d sources, want none", len(list.Items))
+			}
+		})
+	}
+}
+
+func TestDescribeListDelete(t *testing.T) {
+	ctx := context.Background()
+	src := &v1alpha1.CouchDbSource{
+		ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "default"},
+		Spec: v1alpha1.CouchDbSourceSpec{
+			Database:           "orders",
+			CouchDbCredentials: corev1.ObjectReference{Name: "couchdb-binding"},
+			Sink:               &duckv1.Destination{URI: apis.HTTP("example.com")},
+		},
+	}
+	src.Status.Conditions = duckv1.Conditions{{
+		Type:   apis.ConditionReady,
+		Status: corev1.ConditionFalse,
+		Reason: "NotFound",
+	}}
+	clientset := fake.NewSimpleClientset(src)
+	var out bytes.Buffer
+	plugin := newPlugin(&out, clientset)
+
+	if err := plugin.Run(ctx, []string{"describe", "orders"}); err != nil {
+		t.Fatal("describe:", err)
+	}
+	for _, want := range []string{"Database:     orders", "Credentials:  couchdb-binding", "Sink:         http://example.com", "Ready  False   NotFound"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("describe output %q does not contain %q", out.String(), want)
+		}
+	}
+
+	out.Reset()
+	if err := plugin.Run(ctx, []string{"list"}); err != nil {
+		t.Fatal("list:", err)
+	}
+	want := "NAME     DATABASE   SINK                 READY   REASON\n" +
+		"orders   orders     http://example.com   False   NotFound\n"
+	if got := out.String(); got != want {
+		t.Errorf("list output = %q, want %q", got, want)
+	}
+
+	out.Reset()
+	if err := plugin.Run(ctx, []string{"delete", "orders"}); err != nil {
+		t.Fatal("delete:", err)
+	}
+	out.Reset()
+	if err := plugin.Run(ctx, []string{"list"}); err != nil {
+		t.Fatal("list:", err)
+	}
+	if got, want := out.String(), "No CouchDB sources found in namespace 'default'.\n"; got != want {
+		t.Errorf("list output = %q, want %q", got, want)
+	}
+}
+
+func TestSink(t *testing.T) {
+	tests := map[string]string{
+		"ksvc:invoicing":           "ksvc:invoicing",
+		"invoicing":                "ksvc:invoicing",
+		"broker:default":           "broker:default",
+		"channel:orders":           "channel:orders",
+		"svc:invoicing":            "svc:invoicing",
+		"https://example.com/hook": "https://example.com/hook",
+	}
+	for sink, want := range tests {
+		dest, err := parseSink(sink)
+		if err != nil {
+			t.Errorf("parseSink(%q) = %v", sink, err)
+			continue
+		}
+		if got := formatSink(dest); got != want {
+			t.Errorf("formatSink(parseSink(%q)) = %q, want %q", sink, got, want)
+		}
+	}
+
+	for _, sink := range []string{"ksvc:", "pod:invoicing"} {
+		if _, err := parseSink(sink); err == nil {
+			t.Errorf("parseSink(%q) = nil, want an error", sink)
+		}
+	}
+}
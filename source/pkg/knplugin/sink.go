@@ -0,0 +1,88 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package knplugin
+
+import (
+	"fmt"
+	"strings"
+
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+)
+
+// sinkPrefixes are the kinds of the sinks given by reference, as in the
+// --sink flag of kn.
+var sinkPrefixes = []struct {
+	prefix     string
+	apiVersion string
+	kind       string
+}{
+	{"ksvc", "serving.knative.dev/v1", "Service"},
+	{"broker", "eventing.knative.dev/v1", "Broker"},
+	{"channel", "messaging.knative.dev/v1", "Channel"},
+	{"svc", "v1", "Service"},
+}
+
+// parseSink parses a sink given as PREFIX:NAME, with one of the sinkPrefixes,
+// or as an http(s) URL.
+func parseSink(sink string) (*duckv1.Destination, error) {
+	if strings.HasPrefix(sink, "http://") || strings.HasPrefix(sink, "https://") {
+		uri, err := apis.ParseURL(sink)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sink URL %q: %v", sink, err)
+		}
+		return &duckv1.Destination{URI: uri}, nil
+	}
+
+	prefix, name := "ksvc", sink
+	if i := strings.Index(sink, ":"); i >= 0 {
+		prefix, name = sink[:i], sink[i+1:]
+	}
+	if name == "" {
+		return nil, fmt.Errorf("invalid sink %q: missing the name", sink)
+	}
+	for _, p := range sinkPrefixes {
+		if p.prefix == prefix {
+			return &duckv1.Destination{
+				Ref: &duckv1.KReference{
+					APIVersion: p.apiVersion,
+					Kind:       p.kind,
+					Name:       name,
+				},
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid sink %q: the prefix must be one of ksvc, broker, channel or svc", sink)
+}
+
+// formatSink is the inverse of parseSink, falling back to the kind and API
+// version of the references without a prefix.
+func formatSink(sink *duckv1.Destination) string {
+	if sink == nil {
+		return ""
+	}
+	if sink.Ref == nil {
+		return sink.URI.String()
+	}
+	ref := sink.Ref
+	for _, p := range sinkPrefixes {
+		if p.apiVersion == ref.APIVersion && p.kind == ref.Kind {
+			return p.prefix + ":" + ref.Name
+		}
+	}
+	return fmt.Sprintf("%s:%s:%s", ref.Kind, ref.APIVersion, ref.Name)
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"knative.dev/pkg/logging"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-couchdb/source/pkg/reconciler/resources"
+)
+
+// adapterStatusClient fetches the status of the receive adapters.
+var adapterStatusClient = &http.Client{Timeout: 5 * time.Second}
+
+// fetchAdapterStatus returns the status served by a receive adapter.
+func fetchAdapterStatus(ctx context.Context, client *http.Client, url string) (*v1alpha1.AdapterStatus, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	var status v1alpha1.AdapterStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// adapterStatuses returns the status of the running receive adapter pods of
+// the source, by pod name. The status is nil for the pods it could not be
+// fetched from.
+func (r *Reconciler) adapterStatuses(ctx context.Context, src *v1alpha1.CouchDbSource) (map[string]*v1alpha1.AdapterStatus, error) {
+	pods, err := r.kubeClientSet.CoreV1().Pods(src.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(resources.Labels(src.Name)).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	statuses := make(map[string]*v1alpha1.AdapterStatus, len(pods.Items))
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" {
+			continue
+		}
+		url := fmt.Sprintf("http://%s:%d%s", pod.Status.PodIP, resources.AdapterStatusPort, v1alpha1.AdapterStatusPath)
+		status, err := fetchAdapterStatus(ctx, adapterStatusClient, url)
+		if err != nil {
+			logging.FromContext(ctx).Warnw("Unable to fetch the receive adapter status", zap.String("pod", pod.Name), zap.Error(err))
+		}
+		statuses[pod.Name] = status
+	}
+	return statuses, nil
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+func TestFetchAdapterStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != v1alpha1.AdapterStatusPath {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"backfill":{"state":"InProgress","documents":120,"total":300,"sequence":"42-g"}}`))
+	}))
+	defer server.Close()
+
+	got, err := fetchAdapterStatus(context.Background(), server.Client(), server.URL+v1alpha1.AdapterStatusPath)
+	if err != nil {
+		t.Fatalf("fetchAdapterStatus() = %v", err)
+	}
+	want := &v1alpha1.AdapterStatus{
+		Backfill: &v1alpha1.BackfillStatus{
+			State:     v1alpha1.BackfillInProgress,
+			Documents: 120,
+			Total:     300,
+			Sequence:  "42-g",
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected status (-want, +got) = %v", diff)
+	}
+
+	if _, err := fetchAdapterStatus(context.Background(), server.Client(), server.URL+"/missing"); err == nil {
+		t.Error("fetchAdapterStatus() = nil, want an error for a missing endpoint")
+	}
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	"knative.dev/pkg/logging"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+// backfillPollInterval is how often the progress of a backfill is checked.
+const backfillPollInterval = 30 * time.Second
+
+// reconcileBackfill reflects the progress of the backfill of the receive
+// adapter in the status of the source. The status is only fetched until the
+// backfill completes.
+func (r *Reconciler) reconcileBackfill(ctx context.Context, src *v1alpha1.CouchDbSource) {
+	if !src.Spec.Backfill {
+		src.Status.Backfill = nil
+		return
+	}
+	if src.Status.Backfill != nil && src.Status.Backfill.State == v1alpha1.BackfillCompleted {
+		return
+	}
+
+	statuses, err := r.adapterStatuses(ctx, src)
+	if err != nil {
+		logging.FromContext(ctx).Warnw("Unable to list the receive adapter pods", zap.Error(err))
+		return
+	}
+	for _, status := range statuses {
+		if status != nil && status.Backfill != nil {
+			src.Status.Backfill = status.Backfill
+			return
+		}
+	}
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/controller"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-couchdb/source/pkg/reconciler/resources"
+)
+
+const (
+	couchdbsourceBufferClaimCreated = "CouchDbSourceBufferClaimCreated"
+	couchdbsourceBufferClaimUpdated = "CouchDbSourceBufferClaimUpdated"
+)
+
+// reconcileBufferClaim creates the PersistentVolumeClaim holding the buffer
+// of the source, or deletes it once spec.buffer is removed, along with the
+// events it still holds. A claim only grows, where its storage class allows
+// volume expansion.
+func (r *Reconciler) reconcileBufferClaim(ctx context.Context, src *v1alpha1.CouchDbSource) error {
+	if src.Spec.Buffer == nil {
+		return r.deleteBufferClaim(ctx, src)
+	}
+	expected := resources.MakeBufferClaim(src)
+
+	claims := r.kubeClientSet.CoreV1().PersistentVolumeClaims(src.Namespace)
+	claim, err := claims.Get(ctx, expected.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = claims.Create(ctx, expected, metav1.CreateOptions{})
+		controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, couchdbsourceBufferClaimCreated, "PersistentVolumeClaim created, error: %v", err)
+		return err
+	} else if err != nil {
+		return fmt.Errorf("error getting buffer claim: %v", err)
+	} else if !metav1.IsControlledBy(claim, src) {
+		return fmt.Errorf("persistentvolumeclaim %q is not owned by CouchDbSource %q", claim.Name, src.Name)
+	}
+	size := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+	if src.Spec.Buffer.Size.Cmp(size) > 0 {
+		claim.Spec.Resources.Requests[corev1.ResourceStorage] = src.Spec.Buffer.Size
+		if _, err := claims.Update(ctx, claim, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+		controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, couchdbsourceBufferClaimUpdated, "PersistentVolumeClaim resized to %s", src.Spec.Buffer.Size.String())
+	}
+	return nil
+}
+
+// deleteBufferClaim deletes the PersistentVolumeClaim holding the buffer of
+// the source, if any.
+func (r *Reconciler) deleteBufferClaim(ctx context.Context, src *v1alpha1.CouchDbSource) error {
+	claims := r.kubeClientSet.CoreV1().PersistentVolumeClaims(src.Namespace)
+	name := resources.BufferClaimName(src)
+	claim, err := claims.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) || (err == nil && !metav1.IsControlledBy(claim, src)) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error getting buffer claim: %v", err)
+	}
+	if err := claims.Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("error deleting buffer claim: %v", err)
+	}
+	return nil
+}
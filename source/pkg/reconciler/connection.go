@@ -0,0 +1,158 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"knative.dev/pkg/logging"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-couchdb/source/pkg/contract"
+)
+
+const (
+	// connectionCheckEnvVar is the name of the environment variable turning
+	// off the check of the connection to CouchDB when set to false, e.g. when
+	// the controller cannot reach the CouchDB servers.
+	connectionCheckEnvVar = "COUCHDB_CHECK_CONNECTION"
+
+	// connectionTimeout bounds the request checking the connection to
+	// CouchDB.
+	connectionTimeout = 10 * time.Second
+)
+
+// The reasons of the ConnectionEstablished condition.
+const (
+	connectionDNSFailed         = "DNSFailed"
+	connectionTLSFailed         = "TLSFailed"
+	connectionFailed            = "ConnectionFailed"
+	connectionUnauthorized      = "Unauthorized"
+	connectionDatabaseNotFound  = "DatabaseNotFound"
+	connectionUnexpectedStatus  = "UnexpectedStatus"
+	connectionProxyInvalid      = "ProxyInvalid"
+	connectionCredentialsFailed = "CredentialsUnreadable"
+)
+
+// connectionChecker checks that the database of a source can be reached with
+// its credentials, through its egress proxy if any, like the receive adapter
+// would.
+type connectionChecker struct {
+	transport *http.Transport
+	timeout   time.Duration
+}
+
+func newConnectionChecker() *connectionChecker {
+	return &connectionChecker{
+		transport: http.DefaultTransport.(*http.Transport).Clone(),
+		timeout:   connectionTimeout,
+	}
+}
+
+// check makes an authenticated HEAD request on the database, and returns the
+// reason of its failure.
+func (c *connectionChecker) check(ctx context.Context, src *v1alpha1.CouchDbSource, couchDbURL *url.URL) (string, error) {
+	client := &http.Client{Transport: c.transport, Timeout: c.timeout}
+	if p := src.Spec.Proxy; p != nil {
+		proxyURL, err := url.Parse(p.URL)
+		if err != nil {
+			return connectionProxyInvalid, fmt.Errorf("invalid proxy url: %w", err)
+		}
+		transport := c.transport.Clone()
+		transport.Proxy = contract.ProxyFunc(proxyURL, p.NoProxy)
+		client.Transport = transport
+	}
+
+	db := strings.TrimSuffix(couchDbURL.String(), "/") + "/" + url.PathEscape(src.Spec.Database)
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, db, nil)
+	if err != nil {
+		return connectionFailed, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// The client leaves the password out of the error.
+		return connectionFailureReason(err), err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return connectionUnauthorized, fmt.Errorf("CouchDB rejected the credentials of the database %q: %s", src.Spec.Database, resp.Status)
+	case resp.StatusCode == http.StatusNotFound:
+		if src.Spec.DevInstance {
+			// The receive adapter creates the database of a dev instance.
+			return "", nil
+		}
+		return connectionDatabaseNotFound, fmt.Errorf("the database %q does not exist", src.Spec.Database)
+	case resp.StatusCode >= http.StatusMultipleChoices:
+		return connectionUnexpectedStatus, fmt.Errorf("unexpected response to HEAD of the database %q: %s", src.Spec.Database, resp.Status)
+	}
+	return "", nil
+}
+
+// connectionFailureReason returns the reason of a request that got no
+// response.
+func connectionFailureReason(err error) string {
+	var (
+		dnsErr       *net.DNSError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+		recordErr    tls.RecordHeaderError
+	)
+	switch {
+	case errors.As(err, &dnsErr):
+		return connectionDNSFailed
+	case errors.As(err, &authorityErr), errors.As(err, &hostnameErr),
+		errors.As(err, &invalidErr), errors.As(err, &recordErr):
+		return connectionTLSFailed
+	default:
+		return connectionFailed
+	}
+}
+
+// reconcileConnection checks the connection to the database of the source
+// before deploying its receive adapter, which would otherwise only report the
+// failure in its logs.
+func (r *Reconciler) reconcileConnection(ctx context.Context, src *v1alpha1.CouchDbSource, status *v1alpha1.CouchDbSourceStatus, failures *reconcileFailures) error {
+	if r.connection == nil {
+		status.MarkConnectionNotChecked()
+		return nil
+	}
+	u, err := r.couchDbURL(ctx, src)
+	if err != nil {
+		failures.add(v1alpha1.CouchDbConditionConnectionEstablished, connectionCredentialsFailed, err)
+		return err
+	}
+	if reason, err := r.connection.check(ctx, src, u); err != nil {
+		logging.FromContext(ctx).Warnw("Unable to connect to the database", zap.String("reason", reason), zap.Error(err))
+		failures.add(v1alpha1.CouchDbConditionConnectionEstablished, reason, err)
+		return err
+	}
+	status.MarkConnectionEstablished()
+	return nil
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+func TestConnectionCheck(t *testing.T) {
+	var user, password, method, path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ = r.BasicAuth()
+		method, path = r.Method, r.URL.EscapedPath()
+		switch r.URL.Path {
+		case "/mydb", "/a/b":
+			w.WriteHeader(http.StatusOK)
+		case "/locked":
+			w.WriteHeader(http.StatusUnauthorized)
+		case "/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	tlsServer := httptest.NewTLSServer(http.NotFoundHandler())
+	defer tlsServer.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	withCredentials := func(rawurl string) *url.URL {
+		u, _ := url.Parse(rawurl)
+		u.User = url.UserPassword("admin", "secret")
+		return u
+	}
+
+	testCases := map[string]struct {
+		url        *url.URL
+		spec       v1alpha1.CouchDbSourceSpec
+		wantReason string
+		wantPath   string
+	}{
+		"reachable": {
+			url:      withCredentials(server.URL),
+			spec:     v1alpha1.CouchDbSourceSpec{Database: "mydb"},
+			wantPath: "/mydb",
+		},
+		"escaped database": {
+			url:      withCredentials(server.URL + "/"),
+			spec:     v1alpha1.CouchDbSourceSpec{Database: "a/b"},
+			wantPath: "/a%2Fb",
+		},
+		"unauthorized": {
+			url:        withCredentials(server.URL),
+			spec:       v1alpha1.CouchDbSourceSpec{Database: "locked"},
+			wantReason: connectionUnauthorized,
+			wantPath:   "/locked",
+		},
+		"missing database": {
+			url:        withCredentials(server.URL),
+			spec:       v1alpha1.CouchDbSourceSpec{Database: "missing"},
+			wantReason: connectionDatabaseNotFound,
+			wantPath:   "/missing",
+		},
+		"missing database of a dev instance": {
+			url:      withCredentials(server.URL),
+			spec:     v1alpha1.CouchDbSourceSpec{Database: "missing", DevInstance: true},
+			wantPath: "/missing",
+		},
+		"server error": {
+			url:        withCredentials(server.URL),
+			spec:       v1alpha1.CouchDbSourceSpec{Database: "broken"},
+			wantReason: connectionUnexpectedStatus,
+			wantPath:   "/broken",
+		},
+		"untrusted certificate": {
+			url:        withCredentials(tlsServer.URL),
+			spec:       v1alpha1.CouchDbSourceSpec{Database: "mydb"},
+			wantReason: connectionTLSFailed,
+		},
+		"refused": {
+			url:        withCredentials(closed.URL),
+			spec:       v1alpha1.CouchDbSourceSpec{Database: "mydb"},
+			wantReason: connectionFailed,
+		},
+		"unknown host": {
+			url:        withCredentials("http://couchdb.invalid"),
+			spec:       v1alpha1.CouchDbSourceSpec{Database: "mydb"},
+			wantReason: connectionDNSFailed,
+		},
+		"invalid proxy": {
+			url: withCredentials(server.URL),
+			spec: v1alpha1.CouchDbSourceSpec{
+				Database: "mydb",
+				Proxy:    &v1alpha1.ProxySpec{URL: "http://proxy:3128/%zz"},
+			},
+			wantReason: connectionProxyInvalid,
+		},
+		"proxy bypassed": {
+			url: withCredentials(server.URL),
+			spec: v1alpha1.CouchDbSourceSpec{
+				Database: "mydb",
+				Proxy:    &v1alpha1.ProxySpec{URL: "http://proxy.invalid:3128", NoProxy: []string{"127.0.0.1"}},
+			},
+			wantPath: "/mydb",
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			user, password, method, path = "", "", "", ""
+			c := newConnectionChecker()
+			reason, err := c.check(context.Background(), &v1alpha1.CouchDbSource{Spec: tc.spec}, tc.url)
+			if reason != tc.wantReason || (err != nil) != (tc.wantReason != "") {
+				t.Errorf("check() = %q, %v, want reason %q", reason, err, tc.wantReason)
+			}
+			if path != tc.wantPath {
+				t.Errorf("requested %q, want %q", path, tc.wantPath)
+			}
+			if tc.wantPath != "" && (method != http.MethodHead || user != "admin" || password != "secret") {
+				t.Errorf("request = %s as %s:%s, want an authenticated HEAD", method, user, password)
+			}
+		})
+	}
+}
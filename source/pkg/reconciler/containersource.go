@@ -0,0 +1,158 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	sourcesv1 "knative.dev/eventing/pkg/apis/sources/v1"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"knative.dev/pkg/controller"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-couchdb/source/pkg/reconciler/resources"
+)
+
+const (
+	// adapterModeContainerSource, as the adapter mode, makes a Knative
+	// Eventing ContainerSource run the receive adapter of the sources, its
+	// SinkBinding resolving and injecting their sink.
+	adapterModeContainerSource = "containersource"
+
+	// containerSourcePollInterval is how often the ContainerSource of a
+	// source is checked until it is ready.
+	containerSourcePollInterval = 10 * time.Second
+
+	couchdbsourceContainerSourceCreated = "CouchDbSourceContainerSourceCreated"
+	couchdbsourceContainerSourceUpdated = "CouchDbSourceContainerSourceUpdated"
+)
+
+// servedByContainerSource returns whether a ContainerSource runs the receive
+// adapter of the source. The sources whose Deployment the controller tunes
+// keep theirs: those delivering under a lease, scaled by KEDA, buffering the
+// events the sink does not accept, and those whose changes wait for approval.
+func (r *Reconciler) servedByContainerSource(src *v1alpha1.CouchDbSource) bool {
+	if !r.containerSource {
+		return false
+	}
+	spec := &src.Spec
+	return !spec.Leased() &&
+		!src.KedaAutoscaled() &&
+		spec.Buffer == nil &&
+		spec.ApplyMode != v1alpha1.ApplyModeManual
+}
+
+// reconcileContainerSource creates or updates the ContainerSource running the
+// receive adapter of the source, and returns it. The sink is handed to its
+// SinkBinding as a destination, for it to follow the changes of its address.
+func (r *Reconciler) reconcileContainerSource(ctx context.Context, src *v1alpha1.CouchDbSource, status *v1alpha1.CouchDbSourceStatus, image string, sink duckv1.Destination, sinkURI, deadLetterSinkURI *apis.URL) (*sourcesv1.ContainerSource, error) {
+	adapterArgs, err := r.makeReceiveAdapterArgs(ctx, src, image, sinkURI, deadLetterSinkURI)
+	if err != nil {
+		return nil, err
+	}
+	expected := resources.MakeContainerSource(adapterArgs, sink)
+
+	// The source may have been served otherwise before.
+	if err := r.deleteReceiveAdapter(ctx, src, expected.Name); err != nil {
+		return nil, err
+	}
+	if err := r.deleteReceiveAdapterJob(ctx, src, expected.Name); err != nil {
+		return nil, err
+	}
+	if err := r.deleteTenant(ctx, src, expected.Name); err != nil {
+		return nil, err
+	}
+	if err := r.deleteReceiveAdapterStatefulSet(ctx, src, expected.Name); err != nil {
+		return nil, err
+	}
+
+	containerSources := r.dynamicClientSet.Resource(resources.ContainerSourceGVR).Namespace(src.Namespace)
+	u, err := containerSources.Get(ctx, expected.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(expected)
+		if err != nil {
+			return nil, err
+		}
+		u, err = containerSources.Create(ctx, &unstructured.Unstructured{Object: obj}, metav1.CreateOptions{})
+		controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, couchdbsourceContainerSourceCreated, "ContainerSource created, error: %v", err)
+		if err != nil {
+			return nil, err
+		}
+		status.MarkChangesApplied()
+		return toContainerSource(u)
+	} else if err != nil {
+		return nil, fmt.Errorf("error getting container source: %v", err)
+	} else if !metav1.IsControlledBy(u, src) {
+		return nil, fmt.Errorf("containersource %q is not owned by CouchDbSource %q", u.GetName(), src.Name)
+	}
+
+	cs, err := toContainerSource(u)
+	if err != nil {
+		return nil, err
+	}
+	if r.podSpecChanged(cs.Spec.Template.Spec, expected.Spec.Template.Spec) ||
+		!equality.Semantic.DeepEqual(cs.Spec.Template.Labels, expected.Spec.Template.Labels) ||
+		!equality.Semantic.DeepEqual(cs.Spec.SourceSpec, expected.Spec.SourceSpec) {
+		cs.Spec = expected.Spec
+		obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(cs)
+		if err != nil {
+			return nil, err
+		}
+		if u, err = containerSources.Update(ctx, &unstructured.Unstructured{Object: obj}, metav1.UpdateOptions{}); err != nil {
+			return nil, err
+		}
+		controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, couchdbsourceContainerSourceUpdated, "ContainerSource updated")
+		if cs, err = toContainerSource(u); err != nil {
+			return nil, err
+		}
+	}
+	status.MarkChangesApplied()
+	return cs, nil
+}
+
+// deleteContainerSource deletes the ContainerSource of the source, if any.
+// Without Knative Eventing installed, there is none.
+func (r *Reconciler) deleteContainerSource(ctx context.Context, src *v1alpha1.CouchDbSource, name string) error {
+	containerSources := r.dynamicClientSet.Resource(resources.ContainerSourceGVR).Namespace(src.Namespace)
+	cs, err := containerSources.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) || (err == nil && !metav1.IsControlledBy(cs, src)) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error getting container source: %v", err)
+	}
+	if err := containerSources.Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("error deleting container source: %v", err)
+	}
+	return nil
+}
+
+func toContainerSource(u *unstructured.Unstructured) (*sourcesv1.ContainerSource, error) {
+	cs := &sourcesv1.ContainerSource{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, cs); err != nil {
+		return nil, fmt.Errorf("error reading container source: %v", err)
+	}
+	return cs, nil
+}
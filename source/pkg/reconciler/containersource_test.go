@@ -0,0 +1,74 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+func TestServedByContainerSource(t *testing.T) {
+	testCases := map[string]struct {
+		containerSource bool
+		annotations     map[string]string
+		spec            v1alpha1.CouchDbSourceSpec
+		want            bool
+	}{
+		"deployment mode": {},
+		"served": {
+			containerSource: true,
+			want:            true,
+		},
+		"service account": {
+			containerSource: true,
+			spec:            v1alpha1.CouchDbSourceSpec{ServiceAccountName: "couchdb"},
+			want:            true,
+		},
+		"high availability": {
+			containerSource: true,
+			spec:            v1alpha1.CouchDbSourceSpec{HighAvailability: &v1alpha1.HighAvailabilitySpec{Replicas: 2}},
+		},
+		"keda": {
+			containerSource: true,
+			annotations:     map[string]string{v1alpha1.AutoscalingClassAnnotationKey: v1alpha1.KedaAutoscalingClass},
+		},
+		"buffer": {
+			containerSource: true,
+			spec:            v1alpha1.CouchDbSourceSpec{Buffer: &v1alpha1.BufferSpec{Size: resource.MustParse("1Gi")}},
+		},
+		"manual apply": {
+			containerSource: true,
+			spec:            v1alpha1.CouchDbSourceSpec{ApplyMode: v1alpha1.ApplyModeManual},
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			r := &Reconciler{containerSource: tc.containerSource}
+			src := &v1alpha1.CouchDbSource{
+				ObjectMeta: metav1.ObjectMeta{Annotations: tc.annotations},
+				Spec:       tc.spec,
+			}
+			if got := r.servedByContainerSource(src); got != tc.want {
+				t.Errorf("servedByContainerSource() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -19,20 +19,33 @@ package reconciler
 import (
 	"context"
 	"os"
+	"strings"
 
+	"github.com/rickb777/date/period"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/cache"
 	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	eventingclient "knative.dev/eventing/pkg/client/injection/client"
+	eventtypeinformer "knative.dev/eventing/pkg/client/injection/informers/eventing/v1beta1/eventtype"
 	kubeclient "knative.dev/pkg/client/injection/kube/client"
 	deploymentinformer "knative.dev/pkg/client/injection/kube/informers/apps/v1/deployment"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
+	"knative.dev/pkg/injection/clients/dynamicclient"
 	"knative.dev/pkg/logging"
 	"knative.dev/pkg/resolver"
+	"knative.dev/pkg/system"
+	tracingconfig "knative.dev/pkg/tracing/config"
 
+	"knative.dev/eventing-couchdb/source/pkg/apis/config"
 	sourcesv1alpha1 "knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	cdbclient "knative.dev/eventing-couchdb/source/pkg/client/injection/client"
 	couchdbinformer "knative.dev/eventing-couchdb/source/pkg/client/injection/informers/sources/v1alpha1/couchdbsource"
 	cdbreconciler "knative.dev/eventing-couchdb/source/pkg/client/injection/reconciler/sources/v1alpha1/couchdbsource"
+	"knative.dev/eventing-couchdb/source/pkg/reconciler/identity"
+	"knative.dev/eventing-couchdb/source/pkg/reconciler/resources"
 )
 
 const (
@@ -52,6 +65,7 @@ func NewController(
 ) *controller.Impl {
 	deploymentInformer := deploymentinformer.Get(ctx)
 	couchdbSourceInformer := couchdbinformer.Get(ctx)
+	eventTypeInformer := eventtypeinformer.Get(ctx)
 
 	raImage, defined := os.LookupEnv(raImageEnvVar)
 	if !defined {
@@ -59,20 +73,158 @@ func NewController(
 		return nil
 	}
 
+	devImage, defined := os.LookupEnv(devInstanceImageEnvVar)
+	if !defined {
+		devImage = defaultDevInstanceImage
+	}
+
+	var raImageAllowlist []string
+	for _, repo := range strings.Split(os.Getenv(raImageAllowlistEnvVar), ",") {
+		if repo = strings.TrimSpace(repo); repo != "" {
+			raImageAllowlist = append(raImageAllowlist, repo)
+		}
+	}
+
+	raRuntime := map[string]string{
+		resources.GoMaxProcsEnv: os.Getenv(raRuntimeEnvPrefix + resources.GoMaxProcsEnv),
+		resources.GoMemLimitEnv: os.Getenv(raRuntimeEnvPrefix + resources.GoMemLimitEnv),
+	}
+
+	defaultHeartbeat := os.Getenv(defaultHeartbeatEnvVar)
+	if defaultHeartbeat != "" {
+		if _, err := period.Parse(defaultHeartbeat); err != nil {
+			logging.FromContext(ctx).Errorw("Ignoring the invalid default heartbeat", zap.String(defaultHeartbeatEnvVar, defaultHeartbeat), zap.Error(err))
+			defaultHeartbeat = ""
+		}
+	}
+
+	// The heartbeats of the feeds are tuned to the load balancers of the
+	// infrastructure, unless config-couchdb-feed names it.
+	infrastructure, err := config.DetectInfrastructure(ctx, kubeclient.Get(ctx))
+	if err != nil {
+		logging.FromContext(ctx).Warnw("Unable to detect the infrastructure", zap.Error(err))
+	} else if infrastructure != "" {
+		logging.FromContext(ctx).Infow("Detected the infrastructure", zap.String("infrastructure", infrastructure))
+	}
+
+	installation := os.Getenv(installationEnvVar)
+	owns := installationFilter(installation)
+	if installation != "" {
+		logging.FromContext(ctx).Infow("Only reconciling the sources of the installation", zap.String("installation", installation))
+	}
+
+	var connection *connectionChecker
+	if os.Getenv(connectionCheckEnvVar) != "false" {
+		connection = newConnectionChecker()
+	} else {
+		logging.FromContext(ctx).Info("Not checking the connection to CouchDB")
+	}
+
+	multiTenant := os.Getenv(adapterModeEnvVar) == adapterModeMultiTenant
+	if multiTenant {
+		logging.FromContext(ctx).Info("Serving the sources with the multi-tenant receive adapter")
+	}
+	containerSource := os.Getenv(adapterModeEnvVar) == adapterModeContainerSource
+	if containerSource {
+		logging.FromContext(ctx).Info("Running the receive adapters with ContainerSources")
+	}
+
 	r := &Reconciler{
-		receiveAdapterImage: raImage,
-		kubeClientSet:       kubeclient.Get(ctx),
-		deploymentLister:    deploymentInformer.Lister(),
+		receiveAdapterImage:          raImage,
+		receiveAdapterImageAllowlist: raImageAllowlist,
+		receiveAdapterRuntime:        raRuntime,
+		defaultHeartbeat:             defaultHeartbeat,
+		devInstanceImage:             devImage,
+		installation:                 installation,
+		multiTenant:                  multiTenant,
+		containerSource:              containerSource,
+		connection:                   connection,
+		kubeClientSet:                kubeclient.Get(ctx),
+		dynamicClientSet:             dynamicclient.Get(ctx),
+		webhook:                      newWebhookProber(cdbclient.Get(ctx), system.Namespace(), installation),
+		deploymentLister:             deploymentInformer.Lister(),
+		eventingClientSet:            eventingclient.Get(ctx),
+		eventTypeLister:              eventTypeInformer.Lister(),
 	}
-	impl := cdbreconciler.NewImpl(ctx, r)
+	impl := cdbreconciler.NewImpl(ctx, r, func(impl *controller.Impl) controller.Options {
+		// The sources follow the changes of the default sinks, of the
+		// default reconnect backoff and of the load balancers in front of
+		// CouchDB.
+		configStore := config.NewStore(logging.FromContext(ctx).Named("config-store"), func(string, interface{}) {
+			impl.FilteredGlobalResync(owns, couchdbSourceInformer.Informer())
+		})
+		configStore.Infrastructure = infrastructure
+		configStore.WatchConfigs(cmw)
+		return controller.Options{PromoteFilterFunc: owns, ConfigStore: configStore}
+	})
 	r.sinkResolver = resolver.NewURIResolver(ctx, impl.EnqueueKey)
 
+	cmw.Watch(identity.ConfigName, func(cm *corev1.ConfigMap) {
+		cfg, err := identity.NewConfigFromConfigMap(cm)
+		if err != nil {
+			logging.FromContext(ctx).Errorw("Ignoring the invalid cluster identity configuration", zap.Error(err))
+			return
+		}
+		r.setIdentity(cfg)
+		impl.FilteredGlobalResync(owns, couchdbSourceInformer.Informer())
+	})
+	cmw.Watch(logging.ConfigMapName(), func(cm *corev1.ConfigMap) {
+		r.setLoggingConfig(cm.Data)
+		impl.FilteredGlobalResync(owns, couchdbSourceInformer.Informer())
+	})
+	cmw.Watch(tracingconfig.ConfigName, func(cm *corev1.ConfigMap) {
+		cfg, err := tracingconfig.NewTracingConfigFromConfigMap(cm)
+		if err != nil {
+			logging.FromContext(ctx).Errorw("Ignoring the invalid tracing configuration", zap.Error(err))
+			return
+		}
+		if err := r.setTracingConfig(cfg); err != nil {
+			logging.FromContext(ctx).Errorw("Ignoring the unserializable tracing configuration", zap.Error(err))
+			return
+		}
+		impl.FilteredGlobalResync(owns, couchdbSourceInformer.Informer())
+	})
+
 	logging.FromContext(ctx).Info("Setting up event handlers")
-	couchdbSourceInformer.Informer().AddEventHandler(controller.HandleAll(impl.Enqueue))
+	couchdbSourceInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: owns,
+		Handler:    controller.HandleAll(impl.Enqueue),
+	})
 
+	ownsSource := controller.FilterControllerGK(v1alpha1.Kind("CouchDbSource"))
+	ownsAdapter := installationOwnerFilter(couchdbSourceInformer.Lister(), owns)
 	deploymentInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
-		FilterFunc: controller.FilterControllerGK(v1alpha1.Kind("CouchDbSource")),
-		Handler:    controller.HandleAll(impl.EnqueueControllerOf),
+		FilterFunc: func(obj interface{}) bool {
+			return ownsSource(obj) && ownsAdapter(obj)
+		},
+		Handler: controller.HandleAll(impl.EnqueueControllerOf),
+	})
+
+	// The EventTypes are registered again when deleted or changed.
+	eventTypeInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: func(obj interface{}) bool {
+			return ownsSource(obj) && ownsAdapter(obj)
+		},
+		Handler: controller.HandleAll(impl.EnqueueControllerOf),
+	})
+
+	if multiTenant {
+		// The availability of the multi-tenant adapter is the one of its
+		// sources.
+		deploymentInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+			FilterFunc: controller.FilterWithNameAndNamespace(system.Namespace(), mtAdapterName),
+			Handler: controller.HandleAll(func(interface{}) {
+				impl.FilteredGlobalResync(owns, couchdbSourceInformer.Informer())
+			}),
+		})
+	}
+
+	// KEDA reads the lag of the receive adapters it scales from the
+	// controller.
+	serveLag(ctx, newLagHandler(r.kubeClientSet, r.deploymentLister))
+
+	go r.webhook.run(ctx, func() {
+		impl.FilteredGlobalResync(owns, couchdbSourceInformer.Informer())
 	})
 
 	return impl
@@ -18,140 +18,693 @@ package reconciler
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"net/url"
+	"strings"
+	"sync"
+	"time"
 
 	"knative.dev/pkg/controller"
 
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 
 	"go.uber.org/zap"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	appsv1listers "k8s.io/client-go/listers/apps/v1"
 	cdbreconciler "knative.dev/eventing-couchdb/source/pkg/client/injection/reconciler/sources/v1alpha1/couchdbsource"
+	eventingclientset "knative.dev/eventing/pkg/client/clientset/versioned"
+	eventinglisters "knative.dev/eventing/pkg/client/listers/eventing/v1beta1"
 	"knative.dev/pkg/apis"
 	duckv1 "knative.dev/pkg/apis/duck/v1"
 	"knative.dev/pkg/logging"
 	pkgreconciler "knative.dev/pkg/reconciler"
 	"knative.dev/pkg/resolver"
+	tracingconfig "knative.dev/pkg/tracing/config"
 
+	"knative.dev/eventing-couchdb/source/pkg/apis/config"
 	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	cdbevents "knative.dev/eventing-couchdb/source/pkg/events"
+	"knative.dev/eventing-couchdb/source/pkg/reconciler/identity"
 	"knative.dev/eventing-couchdb/source/pkg/reconciler/resources"
 )
 
 const (
 	// Name of the corev1.Events emitted from the reconciliation process
-	couchdbsourceDeploymentCreated = "CouchDbSourceDeploymentCreated"
-	couchdbsourceDeploymentUpdated = "CouchDbSourceDeploymentUpdated"
+	couchdbsourceDeploymentCreated  = "CouchDbSourceDeploymentCreated"
+	couchdbsourceDeploymentUpdated  = "CouchDbSourceDeploymentUpdated"
+	couchdbsourceDevInstanceCreated = "CouchDbSourceDevInstanceCreated"
+	couchdbsourceJobCreated         = "CouchDbSourceJobCreated"
+	couchdbsourcePlanPending        = "CouchDbSourcePlanPending"
+
+	// jobPollInterval is how often the receive adapter Job of a source bounded by a window is
+	// checked for completion.
+	jobPollInterval = 30 * time.Second
 
 	// raImageEnvVar is the name of the environment variable that contains the receive adapter's
 	// image. It must be defined.
 	raImageEnvVar = "COUCHDB_RA_IMAGE"
+
+	// devInstanceImageEnvVar is the name of the environment variable that contains the CouchDB
+	// image used for dev instances. It defaults to defaultDevInstanceImage.
+	devInstanceImageEnvVar  = "COUCHDB_DEV_INSTANCE_IMAGE"
+	defaultDevInstanceImage = "couchdb:3.1"
+
+	// raImageAllowlistEnvVar is the name of the environment variable listing, comma separated,
+	// the image repositories sources may select with the adapter image annotation. Overrides are
+	// rejected when it is empty.
+	raImageAllowlistEnvVar = "COUCHDB_RA_IMAGE_ALLOWLIST"
+
+	// raRuntimeEnvPrefix prefixes the environment variables overriding the Go runtime
+	// settings of the receive adapters, e.g. COUCHDB_RA_GOMEMLIMIT. By default they are
+	// derived from the adapter's resource limits.
+	raRuntimeEnvPrefix = "COUCHDB_RA_"
+
+	// defaultHeartbeatEnvVar is the name of the environment variable holding the
+	// ISO-8601 heartbeat of continuous feeds that configure neither a heartbeat
+	// nor a timeout. It should stay below the idle timeout of the load
+	// balancers in front of CouchDB.
+	defaultHeartbeatEnvVar = "COUCHDB_DEFAULT_HEARTBEAT"
 )
 
 // Reconciler reconciles a CouchDbSource object
 type Reconciler struct {
-	receiveAdapterImage string
+	receiveAdapterImage          string
+	receiveAdapterImageAllowlist []string
+	receiveAdapterRuntime        map[string]string
+	defaultHeartbeat             string
+	devInstanceImage             string
+
+	// installation is the name of the installation of the controller, empty
+	// for the default one.
+	installation string
+
+	// multiTenant makes the multi-tenant receive adapter serve the sources
+	// that do not need a pod of their own.
+	multiTenant bool
+
+	// containerSource makes ContainerSources run the receive adapters of the
+	// sources whose Deployment the controller does not need to tune.
+	containerSource bool
+
+	// connection checks the connection to CouchDB before deploying the
+	// receive adapters, when set.
+	connection *connectionChecker
 
 	// Clients
 	kubeClientSet kubernetes.Interface
 
+	// dynamicClientSet manages the KEDA ScaledObjects, whose types are not
+	// vendored.
+	dynamicClientSet dynamic.Interface
+
 	// listers index properties about resources
 
 	deploymentLister appsv1listers.DeploymentLister
 
+	// eventingClientSet and eventTypeLister register the types of the
+	// events the sources send to a Broker.
+	eventingClientSet eventingclientset.Interface
+	eventTypeLister   eventinglisters.EventTypeLister
+
 	sinkResolver *resolver.URIResolver
+
+	// webhook reflects the health of the admission webhooks in the status.
+	webhook *webhookProber
+
+	// identityMu guards identity, the cluster identity configuration.
+	identityMu sync.RWMutex
+	identity   *identity.Config
+
+	// tracingMu guards tracingConfig, the JSON of the tracing configuration
+	// passed to the receive adapters, empty when tracing is disabled.
+	tracingMu     sync.RWMutex
+	tracingConfig string
+
+	// loggingMu guards loggingConfig, the keys of config-logging copied to
+	// the logging ConfigMaps of the receive adapters.
+	loggingMu     sync.RWMutex
+	loggingConfig map[string]string
 }
 
 var _ cdbreconciler.Interface = (*Reconciler)(nil)
 
 func (r *Reconciler) ReconcileKind(ctx context.Context, source *v1alpha1.CouchDbSource) pkgreconciler.Event {
 	source.Status.InitializeConditions()
+	r.webhook.markHealth(&source.Status)
+
+	// The sub-resources are reconciled as far as their dependencies allow,
+	// so that the status reports every failure rather than the first one.
+	var failures reconcileFailures
+
+	sinkURI, deadLetterSinkURI, sinkErr := r.resolveSinks(ctx, source, &failures)
+
+	// The dev instance only stands in for the credentials of the adapter, so
+	// the status is still written to source.
+	adapterSource := source
+	var devInstanceErr error
+	if source.Spec.DevInstance {
+		var devSource *v1alpha1.CouchDbSource
+		if devSource, devInstanceErr = r.reconcileDevInstance(ctx, source); devInstanceErr != nil {
+			logging.FromContext(ctx).Errorw("Unable to provision the dev instance", zap.Error(devInstanceErr))
+			failures.add(v1alpha1.CouchDbConditionDeployed, "DevInstanceFailed", devInstanceErr)
+		} else {
+			adapterSource = devSource
+		}
+	}
 
-	if source.Spec.Sink == nil {
-		source.Status.MarkNoSink("SinkMissing", "")
-		return fmt.Errorf("spec.sink missing")
+	image, imageErr := r.adapterImage(source)
+	if imageErr != nil {
+		failures.add(v1alpha1.CouchDbConditionDeployed, "AdapterImageNotAllowed", controller.NewPermanentError(imageErr))
 	}
 
-	dest := source.Spec.Sink.DeepCopy()
-	if dest.Ref != nil {
-		// To call URIFromDestination(), dest.Ref must have a Namespace. If there is
-		// no Namespace defined in dest.Ref, we will use the Namespace of the source
-		// as the Namespace of dest.Ref.
-		if dest.Ref.Namespace == "" {
-			dest.Ref.Namespace = source.GetNamespace()
+	var ceSource string
+	ceSourceErr := devInstanceErr
+	if devInstanceErr == nil {
+		if ceSource, ceSourceErr = r.makeEventSource(ctx, adapterSource); ceSourceErr != nil {
+			logging.FromContext(ctx).Errorw("Unable to create the CloudEvents source", zap.Error(ceSourceErr))
+			failures.add(v1alpha1.CouchDbConditionDeployed, "EventSourceUnresolved", ceSourceErr)
 		}
 	}
 
-	sinkURI, err := r.sinkResolver.URIFromDestinationV1(ctx, *dest, source)
-	if err != nil {
-		source.Status.MarkNoSink("NotFound", "")
-		return fmt.Errorf("getting sink URI: %v", err)
+	connectionErr := ceSourceErr
+	if ceSourceErr == nil {
+		connectionErr = r.reconcileConnection(ctx, adapterSource, &source.Status, &failures)
 	}
 
-	source.Status.MarkSink(sinkURI)
+	if sinkErr == nil && imageErr == nil && connectionErr == nil {
+		// The adapters run by the source mount its logging configuration,
+		// unlike the multi-tenant one.
+		mtAdapter := !source.Spec.IsBounded() && !source.Spec.Sharded() && r.servedByMTAdapter(source)
+		if !mtAdapter {
+			if err := r.reconcileLoggingConfig(ctx, adapterSource); err != nil {
+				logging.FromContext(ctx).Errorw("Unable to reconcile the logging configuration", zap.Error(err))
+				failures.add(v1alpha1.CouchDbConditionDeployed, "LoggingConfigFailed", err)
+			}
+		}
+		if source.Spec.IsBounded() {
+			job, err := r.createReceiveAdapterJob(ctx, adapterSource, image, sinkURI, deadLetterSinkURI)
+			if err != nil {
+				logging.FromContext(ctx).Errorw("Unable to create the receive adapter job", zap.Error(err))
+				failures.add(v1alpha1.CouchDbConditionDeployed, "ReceiveAdapterFailed", err)
+			} else {
+				source.Status.PropagateJobStatus(job)
+			}
+		} else if source.Spec.Sharded() {
+			ss, err := r.createReceiveAdapterStatefulSet(ctx, adapterSource, &source.Status, image, sinkURI, deadLetterSinkURI)
+			if err != nil {
+				logging.FromContext(ctx).Errorw("Unable to create the receive adapter statefulset", zap.Error(err))
+				failures.add(v1alpha1.CouchDbConditionDeployed, "ReceiveAdapterFailed", err)
+			} else {
+				source.Status.PropagateStatefulSetAvailability(ss)
+			}
+		} else if mtAdapter {
+			ra, err := r.reconcileTenant(ctx, adapterSource, &source.Status, sinkURI, deadLetterSinkURI)
+			if err != nil {
+				logging.FromContext(ctx).Errorw("Unable to configure the multi-tenant receive adapter", zap.Error(err))
+				failures.add(v1alpha1.CouchDbConditionDeployed, "ReceiveAdapterFailed", err)
+			} else {
+				source.Status.PropagateDeploymentAvailability(ra)
+			}
+		} else if r.servedByContainerSource(source) {
+			cs, err := r.reconcileContainerSource(ctx, adapterSource, &source.Status, image, *sinkDestination(ctx, source), sinkURI, deadLetterSinkURI)
+			if err != nil {
+				logging.FromContext(ctx).Errorw("Unable to reconcile the container source", zap.Error(err))
+				failures.add(v1alpha1.CouchDbConditionDeployed, "ReceiveAdapterFailed", err)
+			} else {
+				source.Status.PropagateContainerSourceStatus(cs)
+			}
+		} else {
+			if err := r.reconcileBufferClaim(ctx, adapterSource); err != nil {
+				logging.FromContext(ctx).Errorw("Unable to reconcile the buffer claim", zap.Error(err))
+				failures.add(v1alpha1.CouchDbConditionDeployed, "BufferClaimFailed", err)
+			}
+			ra, err := r.createReceiveAdapter(ctx, adapterSource, &source.Status, image, sinkURI, deadLetterSinkURI)
+			if err != nil {
+				logging.FromContext(ctx).Errorw("Unable to create the receive adapter", zap.Error(err))
+				failures.add(v1alpha1.CouchDbConditionDeployed, "ReceiveAdapterFailed", err)
+			} else {
+				source.Status.PropagateDeploymentAvailability(ra)
+				if err := r.reconcileDisruptionBudget(ctx, adapterSource, ra); err != nil {
+					logging.FromContext(ctx).Errorw("Unable to reconcile the pod disruption budget", zap.Error(err))
+					failures.add(v1alpha1.CouchDbConditionDeployed, "DisruptionBudgetFailed", err)
+				}
+				if err := r.reconcileScaledObject(ctx, adapterSource, ra); err != nil {
+					logging.FromContext(ctx).Errorw("Unable to reconcile the KEDA scaled object", zap.Error(err))
+					failures.add(v1alpha1.CouchDbConditionDeployed, "ScaledObjectFailed", err)
+				}
+			}
+		}
+	}
 
-	ra, err := r.createReceiveAdapter(ctx, source, sinkURI)
-	if err != nil {
-		logging.FromContext(ctx).Errorw("Unable to create the receive adapter", zap.Error(err))
+	// Like the sink, the attributes are only reported when known, for the
+	// event discovery not to register stale types.
+	source.Status.CloudEventAttributes = nil
+	if ceSourceErr == nil {
+		ceAttributes, err := r.createCloudEventAttributes(source, ceSource)
+		if err != nil {
+			logging.FromContext(ctx).Errorw("Unable to compute the CloudEvent types", zap.Error(err))
+			failures.add(v1alpha1.CouchDbConditionDeployed, "EventTypesInvalid", controller.NewPermanentError(err))
+		} else {
+			source.Status.CloudEventAttributes = ceAttributes
+		}
+	}
+	if devInstanceErr == nil {
+		r.reconcileFeedConfig(ctx, adapterSource, &source.Status)
+	}
+	if extensions, err := r.cloudEventExtensions(source); err != nil {
+		logging.FromContext(ctx).Warnw("Unable to list the CloudEvent extensions", zap.Error(err))
+	} else {
+		source.Status.CloudEventExtensions = extensions
+	}
+
+	source.Status.MarkFailures(failures.conditions)
+	if err := failures.err(); err != nil {
 		return err
 	}
-	// Update source status// Update source status
-	source.Status.PropagateDeploymentAvailability(ra)
 
-	ceSource, err := r.makeEventSource(ctx, source)
-	if err != nil {
-		logging.FromContext(ctx).Errorw("Unable to create the CloudEvents source", zap.Error(err))
+	// The discovery of the event types does not hold back the delivery of
+	// the events.
+	if err := r.reconcileEventTypes(ctx, source, sinkDestination(ctx, source)); err != nil {
+		logging.FromContext(ctx).Errorw("Unable to reconcile the event types", zap.Error(err))
 		return err
 	}
 
-	source.Status.CloudEventAttributes = r.createCloudEventAttributes(ceSource)
+	r.reconcileBackfill(ctx, source)
+	statsWait := r.reconcileStats(ctx, source)
+	jobsActive := r.reconcileJobSink(ctx, source, ceSource)
+
+	if source.Spec.IsBounded() && !source.Status.IsCompleted() &&
+		source.Status.GetCondition(v1alpha1.CouchDbConditionDeployed).IsTrue() {
+		// Jobs are not watched, so poll the running Job until it completes.
+		return controller.NewRequeueAfter(jobPollInterval)
+	}
+	if r.servedByContainerSource(source) &&
+		!source.Status.GetCondition(v1alpha1.CouchDbConditionDeployed).IsTrue() {
+		// ContainerSources are not watched either, so poll the
+		// ContainerSource until it is ready.
+		return controller.NewRequeueAfter(containerSourcePollInterval)
+	}
+	if source.Spec.Sharded() &&
+		!source.Status.GetCondition(v1alpha1.CouchDbConditionDeployed).IsTrue() {
+		// Nor are StatefulSets, so poll the StatefulSet until all its shards
+		// are ready.
+		return controller.NewRequeueAfter(statefulSetPollInterval)
+	}
+	if jobsActive {
+		// Nor are the Jobs of the JobSink, so poll them until they finish.
+		if statsWait > 0 && statsWait < jobSinkPollInterval {
+			return controller.NewRequeueAfter(statsWait)
+		}
+		return controller.NewRequeueAfter(jobSinkPollInterval)
+	}
+	if source.Spec.Backfill &&
+		(source.Status.Backfill == nil || source.Status.Backfill.State != v1alpha1.BackfillCompleted) {
+		// The progress is pulled from the receive adapter, so poll it until the backfill completes.
+		if statsWait > 0 && statsWait < backfillPollInterval {
+			return controller.NewRequeueAfter(statsWait)
+		}
+		return controller.NewRequeueAfter(backfillPollInterval)
+	}
+	if statsWait > 0 {
+		// The statistics are pulled from the receive adapters as well.
+		return controller.NewRequeueAfter(statsWait)
+	}
 	return nil
 }
 
-func (r *Reconciler) createReceiveAdapter(ctx context.Context, src *v1alpha1.CouchDbSource, sinkURI *apis.URL) (*appsv1.Deployment, error) {
+// resolveSinks resolves the sink and the dead letter sink of the source.
+func (r *Reconciler) resolveSinks(ctx context.Context, source *v1alpha1.CouchDbSource, failures *reconcileFailures) (sinkURI, deadLetterSinkURI *apis.URL, err error) {
+	// The status only shows the sink the events are delivered to, rather
+	// than the last one resolved.
+	source.Status.SinkURI = nil
+	source.Status.MarkSinkAudience("", "")
+	source.Status.MarkSinkCACerts("")
+	dest := sinkDestination(ctx, source)
+	if dest == nil {
+		err = fmt.Errorf("spec.sink missing")
+		failures.add(v1alpha1.CouchDbConditionSinkProvided, "SinkMissing", err)
+		return nil, nil, err
+	}
+
+	sinkURI, err = r.sinkResolver.URIFromDestinationV1(ctx, *dest, source)
+	if err != nil {
+		err = fmt.Errorf("getting sink URI: %v", err)
+		failures.add(v1alpha1.CouchDbConditionSinkProvided, "NotFound", err)
+		return nil, nil, err
+	}
+
+	source.Status.MarkSink(sinkURI)
+
+	// The sinks with an OIDC audience authenticate the events with a token
+	// of the service account of the receive adapter, and the HTTPS sinks may
+	// be signed by a cluster-internal CA.
+	audience, caCerts, err := r.sinkAddress(ctx, source, dest)
+	if err != nil {
+		err = fmt.Errorf("getting sink address: %v", err)
+		failures.add(v1alpha1.CouchDbConditionSinkProvided, "SinkAddressUnresolved", err)
+		return nil, nil, err
+	}
+	source.Status.MarkSinkAudience(audience, adapterServiceAccountName(source))
+	source.Status.MarkSinkCACerts(caCerts)
+
+	if source.Spec.Delivery != nil && source.Spec.Delivery.DeadLetterSink != nil {
+		dls := source.Spec.Delivery.DeadLetterSink.DeepCopy()
+		if dls.Ref != nil && dls.Ref.Namespace == "" {
+			dls.Ref.Namespace = source.GetNamespace()
+		}
+		deadLetterSinkURI, err = r.sinkResolver.URIFromDestinationV1(ctx, *dls, source)
+		if err != nil {
+			err = fmt.Errorf("getting dead letter sink URI: %v", err)
+			failures.add(v1alpha1.CouchDbConditionSinkProvided, "DeadLetterSinkNotFound", err)
+			return sinkURI, nil, err
+		}
+	}
+	source.Status.DeadLetterSinkURI = deadLetterSinkURI
+
+	// The reply is handed to the adapter through the status, which the
+	// source of a dev instance copies.
+	source.Status.ReplyURI = nil
+	if source.Spec.Reply != nil {
+		reply := source.Spec.Reply.DeepCopy()
+		if reply.Ref != nil && reply.Ref.Namespace == "" {
+			reply.Ref.Namespace = source.GetNamespace()
+		}
+		replyURI, err := r.sinkResolver.URIFromDestinationV1(ctx, *reply, source)
+		if err != nil {
+			err = fmt.Errorf("getting reply URI: %v", err)
+			failures.add(v1alpha1.CouchDbConditionSinkProvided, "ReplyNotFound", err)
+			return sinkURI, deadLetterSinkURI, err
+		}
+		source.Status.ReplyURI = replyURI
+	}
+	return sinkURI, deadLetterSinkURI, nil
+}
+
+// sinkDestination returns the destination of the events of the source: its
+// sink or, without one, the default sink of its namespace, e.g. its default
+// Broker. It returns nil when there is neither.
+func sinkDestination(ctx context.Context, source *v1alpha1.CouchDbSource) *duckv1.Destination {
+	dest := source.Spec.Sink.DeepCopy()
+	if dest == nil {
+		if dest = config.FromContextOrDefaults(ctx).DefaultSinks.Sink(source.Namespace); dest == nil {
+			return nil
+		}
+	}
+	if dest.Ref != nil {
+		// To call URIFromDestination(), dest.Ref must have a Namespace. If there is
+		// no Namespace defined in dest.Ref, we will use the Namespace of the source
+		// as the Namespace of dest.Ref.
+		if dest.Ref.Namespace == "" {
+			dest.Ref.Namespace = source.GetNamespace()
+		}
+	}
+	return dest
+}
+
+func (r *Reconciler) makeReceiveAdapterArgs(ctx context.Context, src *v1alpha1.CouchDbSource, image string, sinkURI, deadLetterSinkURI *apis.URL) (*resources.ReceiveAdapterArgs, error) {
 	eventSource, err := r.makeEventSource(ctx, src)
 	if err != nil {
 		return nil, err
 	}
 	logging.FromContext(ctx).Debugw("event source", zap.Any("source", eventSource))
 
-	adapterArgs := resources.ReceiveAdapterArgs{
+	adapterArgs := &resources.ReceiveAdapterArgs{
 		EventSource: eventSource,
-		Image:       r.receiveAdapterImage,
+		Image:       image,
 		Source:      src,
 		Labels:      resources.Labels(src.Name),
 		SinkURI:     sinkURI.String(),
+
+		DefaultHeartbeat: r.heartbeat(ctx),
+		RuntimeOverrides: r.receiveAdapterRuntime,
+	}
+	if reconnect := config.FromContextOrDefaults(ctx).Reconnect; reconnect != nil {
+		adapterArgs.DefaultReconnect = v1alpha1.ReconnectSpec{
+			InitialDelay: reconnect.InitialDelay,
+			MaxDelay:     reconnect.MaxDelay,
+		}
+	}
+	if clusterID, attribute, err := r.clusterIdentity(); err != nil {
+		return nil, err
+	} else if attribute == identity.AttributeExtension {
+		adapterArgs.ClusterID = clusterID
+	}
+	r.tracingMu.RLock()
+	adapterArgs.TracingConfig = r.tracingConfig
+	r.tracingMu.RUnlock()
+	if deadLetterSinkURI != nil {
+		adapterArgs.DeadLetterSinkURI = deadLetterSinkURI.String()
+	}
+	if src.Status.ReplyURI != nil {
+		adapterArgs.ReplyURI = src.Status.ReplyURI.String()
+	}
+	if src.Status.SinkAudience != nil {
+		adapterArgs.SinkAudience = *src.Status.SinkAudience
+	}
+	if src.Status.SinkCACerts != nil {
+		adapterArgs.SinkCACerts = *src.Status.SinkCACerts
+	}
+	return adapterArgs, nil
+}
+
+// createReceiveAdapter creates or updates the receive adapter Deployment of
+// the source. With spec.applyMode manual, the changes are only applied once
+// their plan, written to the status, is approved.
+func (r *Reconciler) createReceiveAdapter(ctx context.Context, src *v1alpha1.CouchDbSource, status *v1alpha1.CouchDbSourceStatus, image string, sinkURI, deadLetterSinkURI *apis.URL) (*appsv1.Deployment, error) {
+	adapterArgs, err := r.makeReceiveAdapterArgs(ctx, src, image, sinkURI, deadLetterSinkURI)
+	if err != nil {
+		return nil, err
+	}
+	expected := resources.MakeReceiveAdapter(adapterArgs)
+
+	// The source may have been bounded by a window, or served by the
+	// multi-tenant adapter or a ContainerSource, before.
+	if err := r.deleteReceiveAdapterJob(ctx, src, expected.Name); err != nil {
+		return nil, err
+	}
+	if err := r.deleteTenant(ctx, src, expected.Name); err != nil {
+		return nil, err
+	}
+	if err := r.deleteContainerSource(ctx, src, expected.Name); err != nil {
+		return nil, err
+	}
+	if err := r.deleteReceiveAdapterStatefulSet(ctx, src, expected.Name); err != nil {
+		return nil, err
 	}
-	expected := resources.MakeReceiveAdapter(&adapterArgs)
 
 	ra, err := r.kubeClientSet.AppsV1().Deployments(src.Namespace).Get(ctx, expected.Name, metav1.GetOptions{})
 	if apierrors.IsNotFound(err) {
 
 		ra, err = r.kubeClientSet.AppsV1().Deployments(src.Namespace).Create(ctx, expected, metav1.CreateOptions{})
 		controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, couchdbsourceDeploymentCreated, "Deployment created, error: %v", err)
+		if err == nil {
+			status.MarkChangesApplied()
+		}
 		return ra, err
 	} else if err != nil {
 		return nil, fmt.Errorf("error getting receive adapter: %v", err)
 	} else if !metav1.IsControlledBy(ra, src) {
 		return nil, fmt.Errorf("deployment %q is not owned by CouchDbSource %q", ra.Name, src.Name)
-	} else if r.podSpecChanged(ra.Spec.Template.Spec, expected.Spec.Template.Spec) {
+	}
+	if src.KedaAutoscaled() {
+		// KEDA owns the replicas.
+		expected.Spec.Replicas = ra.Spec.Replicas
+	}
+	if r.podSpecChanged(ra.Spec.Template.Spec, expected.Spec.Template.Spec) ||
+		resources.TemplateMetadataChanged(ra, expected) ||
+		resources.StrategyChanged(ra, expected) ||
+		!equality.Semantic.DeepEqual(ra.Spec.Replicas, expected.Spec.Replicas) {
+		if src.Spec.ApplyMode == v1alpha1.ApplyModeManual {
+			plan := resources.MakePlan(ra, expected)
+			id := resources.PlanID(ra.Generation, plan)
+			if src.Annotations[v1alpha1.ApprovedPlanAnnotationKey] != id {
+				if status.Annotations[v1alpha1.PlanIDAnnotationKey] != id {
+					controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, couchdbsourcePlanPending, "Plan %s waits for approval:\n%s", id, plan)
+				}
+				status.MarkPlanPending(id, plan)
+				return ra, nil
+			}
+		}
 		ra.Spec.Template.Spec = expected.Spec.Template.Spec
+		resources.UpdateTemplateMetadata(ra, expected)
+		ra.Spec.Replicas = expected.Spec.Replicas
+		if resources.StrategyChanged(ra, expected) {
+			ra.Spec.Strategy = expected.Spec.Strategy
+		}
 		if ra, err = r.kubeClientSet.AppsV1().Deployments(src.Namespace).Update(ctx, ra, metav1.UpdateOptions{}); err != nil {
 			return ra, err
 		}
 		controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, couchdbsourceDeploymentUpdated, "Deployment updated")
-		return ra, nil
 	} else {
 		logging.FromContext(ctx).Debugw("Reusing existing receive adapter", zap.Any("receiveAdapter", ra))
 	}
+	status.MarkChangesApplied()
 	return ra, nil
 }
 
+// createReceiveAdapterJob runs the receive adapter of a source bounded by a window as a Job,
+// which completes once the adapter has reported the changes of the window.
+func (r *Reconciler) createReceiveAdapterJob(ctx context.Context, src *v1alpha1.CouchDbSource, image string, sinkURI, deadLetterSinkURI *apis.URL) (*batchv1.Job, error) {
+	adapterArgs, err := r.makeReceiveAdapterArgs(ctx, src, image, sinkURI, deadLetterSinkURI)
+	if err != nil {
+		return nil, err
+	}
+	expected := resources.MakeReceiveAdapterJob(adapterArgs)
+
+	// The source may not have been bounded by a window before.
+	if err := r.deleteReceiveAdapter(ctx, src, expected.Name); err != nil {
+		return nil, err
+	}
+	if err := r.deleteTenant(ctx, src, expected.Name); err != nil {
+		return nil, err
+	}
+	if err := r.deleteContainerSource(ctx, src, expected.Name); err != nil {
+		return nil, err
+	}
+	if err := r.deleteReceiveAdapterStatefulSet(ctx, src, expected.Name); err != nil {
+		return nil, err
+	}
+
+	jobs := r.kubeClientSet.BatchV1().Jobs(src.Namespace)
+	job, err := jobs.Get(ctx, expected.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		job, err = jobs.Create(ctx, expected, metav1.CreateOptions{})
+		controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, couchdbsourceJobCreated, "Job created, error: %v", err)
+		return job, err
+	} else if err != nil {
+		return nil, fmt.Errorf("error getting receive adapter job: %v", err)
+	} else if !metav1.IsControlledBy(job, src) {
+		return nil, fmt.Errorf("job %q is not owned by CouchDbSource %q", job.Name, src.Name)
+	} else if r.podSpecChanged(job.Spec.Template.Spec, expected.Spec.Template.Spec) {
+		// The pod template of a Job is immutable: replay the window with a new Job.
+		propagation := metav1.DeletePropagationBackground
+		if err := jobs.Delete(ctx, job.Name, metav1.DeleteOptions{PropagationPolicy: &propagation}); err != nil {
+			return nil, fmt.Errorf("error deleting outdated receive adapter job: %v", err)
+		}
+		job, err = jobs.Create(ctx, expected, metav1.CreateOptions{})
+		controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, couchdbsourceJobCreated, "Job recreated, error: %v", err)
+		return job, err
+	}
+	return job, nil
+}
+
+// deleteReceiveAdapter deletes the receive adapter Deployment of the source,
+// and its PodDisruptionBudget and ScaledObject, if any.
+func (r *Reconciler) deleteReceiveAdapter(ctx context.Context, src *v1alpha1.CouchDbSource, name string) error {
+	if err := r.deleteScaledObject(ctx, src, name); err != nil {
+		return err
+	}
+	if err := r.deleteDisruptionBudget(ctx, src, name); err != nil {
+		return err
+	}
+	ra, err := r.kubeClientSet.AppsV1().Deployments(src.Namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) || (err == nil && !metav1.IsControlledBy(ra, src)) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error getting receive adapter: %v", err)
+	}
+	if err := r.kubeClientSet.AppsV1().Deployments(src.Namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("error deleting receive adapter: %v", err)
+	}
+	return nil
+}
+
+// deleteReceiveAdapterJob deletes the receive adapter Job of the source, if any.
+func (r *Reconciler) deleteReceiveAdapterJob(ctx context.Context, src *v1alpha1.CouchDbSource, name string) error {
+	job, err := r.kubeClientSet.BatchV1().Jobs(src.Namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) || (err == nil && !metav1.IsControlledBy(job, src)) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error getting receive adapter job: %v", err)
+	}
+	propagation := metav1.DeletePropagationBackground
+	if err := r.kubeClientSet.BatchV1().Jobs(src.Namespace).Delete(ctx, name, metav1.DeleteOptions{PropagationPolicy: &propagation}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("error deleting receive adapter job: %v", err)
+	}
+	return nil
+}
+
+// adapterImage returns the receive adapter image for the source, honoring the
+// adapter image annotation when the image is allowed.
+func (r *Reconciler) adapterImage(src *v1alpha1.CouchDbSource) (string, error) {
+	image, ok := src.Annotations[v1alpha1.AdapterImageAnnotationKey]
+	if !ok {
+		return r.receiveAdapterImage, nil
+	}
+	for _, repo := range r.receiveAdapterImageAllowlist {
+		if image == repo || strings.HasPrefix(image, repo+":") || strings.HasPrefix(image, repo+"@") {
+			return image, nil
+		}
+	}
+	return "", fmt.Errorf("adapter image %q is not in the allowlist", image)
+}
+
+// reconcileDevInstance provisions the throwaway CouchDB backing a source with
+// spec.devInstance set. It returns a copy of the source whose credentials point
+// at the generated secret.
+func (r *Reconciler) reconcileDevInstance(ctx context.Context, src *v1alpha1.CouchDbSource) (*v1alpha1.CouchDbSource, error) {
+	name := resources.DevInstanceName(src)
+
+	if _, err := r.kubeClientSet.CoreV1().Secrets(src.Namespace).Get(ctx, name, metav1.GetOptions{}); apierrors.IsNotFound(err) {
+		password, err := randomPassword()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := r.kubeClientSet.CoreV1().Secrets(src.Namespace).Create(ctx, resources.MakeDevInstanceSecret(src, password), metav1.CreateOptions{}); err != nil {
+			return nil, fmt.Errorf("error creating dev instance secret: %v", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("error getting dev instance secret: %v", err)
+	}
+
+	if _, err := r.kubeClientSet.CoreV1().Services(src.Namespace).Get(ctx, name, metav1.GetOptions{}); apierrors.IsNotFound(err) {
+		if _, err := r.kubeClientSet.CoreV1().Services(src.Namespace).Create(ctx, resources.MakeDevInstanceService(src), metav1.CreateOptions{}); err != nil {
+			return nil, fmt.Errorf("error creating dev instance service: %v", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("error getting dev instance service: %v", err)
+	}
+
+	expected := resources.MakeDevInstance(src, r.devInstanceImage)
+	ss, err := r.kubeClientSet.AppsV1().StatefulSets(src.Namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		if _, err := r.kubeClientSet.AppsV1().StatefulSets(src.Namespace).Create(ctx, expected, metav1.CreateOptions{}); err != nil {
+			return nil, fmt.Errorf("error creating dev instance: %v", err)
+		}
+		controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, couchdbsourceDevInstanceCreated, "Dev instance created")
+	} else if err != nil {
+		return nil, fmt.Errorf("error getting dev instance: %v", err)
+	} else if !metav1.IsControlledBy(ss, src) {
+		return nil, fmt.Errorf("statefulset %q is not owned by CouchDbSource %q", ss.Name, src.Name)
+	} else if r.podSpecChanged(ss.Spec.Template.Spec, expected.Spec.Template.Spec) {
+		ss.Spec.Template.Spec = expected.Spec.Template.Spec
+		if _, err := r.kubeClientSet.AppsV1().StatefulSets(src.Namespace).Update(ctx, ss, metav1.UpdateOptions{}); err != nil {
+			return nil, err
+		}
+	}
+
+	src = src.DeepCopy()
+	src.Spec.CouchDbCredentials = corev1.ObjectReference{Name: name}
+	return src, nil
+}
+
+func randomPassword() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
 func (r *Reconciler) podSpecChanged(oldPodSpec corev1.PodSpec, newPodSpec corev1.PodSpec) bool {
 	if !equality.Semantic.DeepDerivative(newPodSpec, oldPodSpec) {
 		return true
@@ -169,6 +722,34 @@ func (r *Reconciler) podSpecChanged(oldPodSpec corev1.PodSpec, newPodSpec corev1
 
 // MakeEventSource computes the Cloud Event source attribute for the given source
 func (r *Reconciler) makeEventSource(ctx context.Context, src *v1alpha1.CouchDbSource) (string, error) {
+	if src.Spec.CloudEventSource != "" {
+		return src.Spec.CloudEventSource, nil
+	}
+
+	url, err := r.couchDbURL(ctx, src)
+	if err != nil {
+		return "", err
+	}
+
+	database := src.Spec.Database
+	if src.Spec.Sharded() {
+		// The receive adapter puts the database of each change in place of
+		// the pattern.
+		database = src.Spec.DatabasePattern
+	}
+
+	clusterID, attribute, err := r.clusterIdentity()
+	if err != nil {
+		return "", err
+	}
+	if attribute == identity.AttributeSource {
+		return fmt.Sprintf("%s/%s/%s", clusterID, url.Hostname(), database), nil
+	}
+	return fmt.Sprintf("%s/%s", url.Hostname(), database), nil
+}
+
+// couchDbURL returns the url of the credentials secret of the source.
+func (r *Reconciler) couchDbURL(ctx context.Context, src *v1alpha1.CouchDbSource) (*url.URL, error) {
 	namespace := src.Spec.CouchDbCredentials.Namespace
 	if namespace == "" {
 		namespace = src.Namespace
@@ -177,29 +758,144 @@ func (r *Reconciler) makeEventSource(ctx context.Context, src *v1alpha1.CouchDbS
 	secret, err := r.kubeClientSet.CoreV1().Secrets(namespace).Get(ctx, src.Spec.CouchDbCredentials.Name, metav1.GetOptions{})
 	if err != nil {
 		logging.FromContext(ctx).Errorw("Unable to read CouchDB credentials secret", zap.Error(err))
-		return "", err
+		return nil, err
 	}
 	rawurl, ok := secret.Data["url"]
 	if !ok {
 		logging.FromContext(ctx).Errorw("Unable to get CouchDB url field", zap.Any("secretName", secret.Name), zap.Any("secretNamespace", secret.Namespace))
-		return "", err
+		return nil, fmt.Errorf("the url of the secret %s/%s is missing", secret.Namespace, secret.Name)
 	}
+	return url.Parse(string(rawurl))
+}
 
-	url, err := url.Parse(string(rawurl))
+// reconcileFeedConfig reflects the changes feed request of the receive
+// adapter in status.feedConfig. The URL is left out when the credentials
+// cannot be read.
+func (r *Reconciler) reconcileFeedConfig(ctx context.Context, src *v1alpha1.CouchDbSource, status *v1alpha1.CouchDbSourceStatus) {
+	u, err := r.couchDbURL(ctx, src)
 	if err != nil {
-		return "", err
+		u = nil
+	}
+	status.FeedConfig = resources.MakeFeedConfig(&resources.ReceiveAdapterArgs{
+		Source:           src,
+		DefaultHeartbeat: r.heartbeat(ctx),
+	}, u)
+}
+
+// heartbeat returns the heartbeat of the continuous feeds setting neither a
+// heartbeat nor a timeout: the default one of the controller, else the one
+// suiting the load balancers in front of CouchDB, else none, which leaves it
+// to the receive adapter.
+func (r *Reconciler) heartbeat(ctx context.Context) string {
+	if r.defaultHeartbeat != "" || config.FromContextOrDefaults(ctx).Feed.LoadBalancerIdleTimeout() == 0 {
+		return r.defaultHeartbeat
+	}
+	return v1alpha1.DefaultHeartbeatFor(ctx)
+}
+
+func (r *Reconciler) setIdentity(cfg *identity.Config) {
+	r.identityMu.Lock()
+	defer r.identityMu.Unlock()
+	r.identity = cfg
+}
+
+// setTracingConfig sets the tracing configuration of the receive adapters.
+// Disabled tracing is left out of their environment, so that the adapters of
+// the installations without tracing are not rolled out when it changes.
+func (r *Reconciler) setTracingConfig(cfg *tracingconfig.Config) error {
+	var tracing string
+	if cfg.Backend != tracingconfig.None {
+		var err error
+		if tracing, err = tracingconfig.TracingConfigToJSON(cfg); err != nil {
+			return err
+		}
 	}
+	r.tracingMu.Lock()
+	defer r.tracingMu.Unlock()
+	r.tracingConfig = tracing
+	return nil
+}
 
-	return fmt.Sprintf("%s/%s", url.Hostname(), src.Spec.Database), nil
+// clusterIdentity returns the identity of the cluster and where to stamp it
+// on events, or an empty identity when events are not stamped.
+func (r *Reconciler) clusterIdentity() (string, identity.Attribute, error) {
+	r.identityMu.RLock()
+	cfg := r.identity
+	r.identityMu.RUnlock()
+	if cfg == nil {
+		return "", "", nil
+	}
+	provider, err := cfg.NewProvider()
+	if err != nil || provider == nil {
+		return "", "", err
+	}
+	clusterID, err := provider.ClusterID()
+	if err != nil {
+		return "", "", fmt.Errorf("getting the cluster identity: %v", err)
+	}
+	return clusterID, cfg.Attribute, nil
 }
 
-func (r *Reconciler) createCloudEventAttributes(ceSource string) []duckv1.CloudEventAttributes {
-	ceAttributes := make([]duckv1.CloudEventAttributes, 0, len(v1alpha1.CouchDbSourceEventTypes))
-	for _, couchDbSourceEventType := range v1alpha1.CouchDbSourceEventTypes {
+// cloudEventExtensions returns the sorted names of the extension attributes
+// of the events of the source.
+func (r *Reconciler) cloudEventExtensions(src *v1alpha1.CouchDbSource) ([]string, error) {
+	names := sets.NewString(cdbevents.ChangeExtensions...)
+	if src.Spec.Grouping != nil {
+		names.Insert(cdbevents.GroupExtension, cdbevents.GroupSizeExtension, cdbevents.GroupIndexExtension)
+	}
+	if overrides := src.Spec.CloudEventOverrides; overrides != nil {
+		for n := range overrides.Extensions {
+			names.Insert(n)
+		}
+	}
+	clusterID, attribute, err := r.clusterIdentity()
+	if err != nil {
+		return nil, err
+	}
+	if clusterID != "" && attribute == identity.AttributeExtension {
+		names.Insert(identity.Extension)
+	}
+	return names.List(), nil
+}
+
+func (r *Reconciler) createCloudEventAttributes(src *v1alpha1.CouchDbSource, ceSource string) ([]duckv1.CloudEventAttributes, error) {
+	eventType, err := v1alpha1.ParseEventTypeTemplate(src.Spec.EventTypeTemplate)
+	if err != nil {
+		return nil, err
+	}
+	changeTypes := src.Spec.ChangeTypes()
+	ceAttributes := make([]duckv1.CloudEventAttributes, 0, len(changeTypes))
+	for _, changeType := range changeTypes {
+		couchDbSourceEventType, err := eventType.Render(v1alpha1.EventTypeData{
+			ChangeType: changeType,
+			Database:   src.Spec.Database,
+		})
+		if err != nil {
+			return nil, err
+		}
 		ceAttributes = append(ceAttributes, duckv1.CloudEventAttributes{
 			Type:   couchDbSourceEventType,
 			Source: ceSource,
 		})
 	}
-	return ceAttributes
+	if (src.Spec.Grouping != nil && src.Spec.Grouping.Batch) ||
+		(src.Spec.Batch != nil && src.Spec.ContentMode != v1alpha1.ContentModeBatch) {
+		ceAttributes = append(ceAttributes, duckv1.CloudEventAttributes{
+			Type:   v1alpha1.CouchDbSourceBatchEventType,
+			Source: ceSource,
+		})
+	}
+	if src.Spec.DatabaseUpdates {
+		for _, t := range []string{
+			v1alpha1.CouchDbSourceDatabaseCreatedEventType,
+			v1alpha1.CouchDbSourceDatabaseDeletedEventType,
+			v1alpha1.CouchDbSourceDatabaseUpdatedEventType,
+		} {
+			ceAttributes = append(ceAttributes, duckv1.CloudEventAttributes{
+				Type:   t,
+				Source: ceSource,
+			})
+		}
+	}
+	return ceAttributes, nil
 }
@@ -0,0 +1,83 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/controller"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-couchdb/source/pkg/reconciler/resources"
+)
+
+const (
+	couchdbsourceDisruptionBudgetCreated = "CouchDbSourceDisruptionBudgetCreated"
+	couchdbsourceDisruptionBudgetUpdated = "CouchDbSourceDisruptionBudgetUpdated"
+)
+
+// reconcileDisruptionBudget creates or updates the PodDisruptionBudget of the
+// receive adapter Deployment when it runs several replicas, and deletes it
+// otherwise. It follows the current Deployment, so that a budget never
+// outruns a change of the replicas waiting for approval.
+func (r *Reconciler) reconcileDisruptionBudget(ctx context.Context, src *v1alpha1.CouchDbSource, ra *appsv1.Deployment) error {
+	expected := resources.MakePodDisruptionBudget(ra)
+	if expected == nil {
+		return r.deleteDisruptionBudget(ctx, src, ra.Name)
+	}
+
+	pdbs := r.kubeClientSet.PolicyV1beta1().PodDisruptionBudgets(src.Namespace)
+	pdb, err := pdbs.Get(ctx, expected.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = pdbs.Create(ctx, expected, metav1.CreateOptions{})
+		controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, couchdbsourceDisruptionBudgetCreated, "PodDisruptionBudget created, error: %v", err)
+		return err
+	} else if err != nil {
+		return fmt.Errorf("error getting pod disruption budget: %v", err)
+	} else if !metav1.IsControlledBy(pdb, src) {
+		return fmt.Errorf("poddisruptionbudget %q is not owned by CouchDbSource %q", pdb.Name, src.Name)
+	} else if !equality.Semantic.DeepEqual(pdb.Spec, expected.Spec) {
+		pdb.Spec = expected.Spec
+		if _, err := pdbs.Update(ctx, pdb, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+		controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, couchdbsourceDisruptionBudgetUpdated, "PodDisruptionBudget updated")
+	}
+	return nil
+}
+
+// deleteDisruptionBudget deletes the PodDisruptionBudget of the receive
+// adapter of the source, if any.
+func (r *Reconciler) deleteDisruptionBudget(ctx context.Context, src *v1alpha1.CouchDbSource, name string) error {
+	pdbs := r.kubeClientSet.PolicyV1beta1().PodDisruptionBudgets(src.Namespace)
+	pdb, err := pdbs.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) || (err == nil && !metav1.IsControlledBy(pdb, src)) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error getting pod disruption budget: %v", err)
+	}
+	if err := pdbs.Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("error deleting pod disruption budget: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	eventingv1 "knative.dev/eventing/pkg/apis/eventing/v1"
+	eventingv1beta1 "knative.dev/eventing/pkg/apis/eventing/v1beta1"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"knative.dev/pkg/controller"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-couchdb/source/pkg/reconciler/resources"
+)
+
+const (
+	couchdbsourceEventTypeCreated = "CouchDbSourceEventTypeCreated"
+	couchdbsourceEventTypeDeleted = "CouchDbSourceEventTypeDeleted"
+)
+
+// sinkBroker returns the name of the Broker the destination references in
+// the namespace of the source, if any. The EventTypes are only registered
+// with those, since they are owned by the source.
+func sinkBroker(src *v1alpha1.CouchDbSource, dest *duckv1.Destination) string {
+	if dest == nil || dest.Ref == nil || dest.Ref.Kind != "Broker" || dest.Ref.Namespace != src.Namespace {
+		return ""
+	}
+	if gv, err := schema.ParseGroupVersion(dest.Ref.APIVersion); err != nil || gv.Group != eventingv1.SchemeGroupVersion.Group {
+		return ""
+	}
+	return dest.Ref.Name
+}
+
+// reconcileEventTypes registers the CloudEvent attributes of the status of
+// the source as EventTypes of the broker it sends its events to, so that
+// the trigger authors can discover them, and deletes the ones it no longer
+// emits, or all of them when its sink is not a broker.
+func (r *Reconciler) reconcileEventTypes(ctx context.Context, src *v1alpha1.CouchDbSource, dest *duckv1.Destination) error {
+	var expected []*eventingv1beta1.EventType
+	if broker := sinkBroker(src, dest); broker != "" {
+		var err error
+		if expected, err = resources.MakeEventTypes(src, broker, src.Status.CloudEventAttributes); err != nil {
+			return err
+		}
+	}
+
+	existing, err := r.eventTypeLister.EventTypes(src.Namespace).List(labels.SelectorFromSet(resources.Labels(src.Name)))
+	if err != nil {
+		return fmt.Errorf("error listing the event types: %v", err)
+	}
+	current := make(map[string]*eventingv1beta1.EventType, len(existing))
+	for _, et := range existing {
+		if metav1.IsControlledBy(et, src) {
+			current[et.Name] = et
+		}
+	}
+
+	eventTypes := r.eventingClientSet.EventingV1beta1().EventTypes(src.Namespace)
+	for _, et := range expected {
+		cur, ok := current[et.Name]
+		delete(current, et.Name)
+		switch {
+		case !ok:
+			if _, err := eventTypes.Create(ctx, et, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+				return fmt.Errorf("error creating the event type %q: %v", et.Spec.Type, err)
+			}
+			controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, couchdbsourceEventTypeCreated, "EventType %q created", et.Spec.Type)
+		case !equality.Semantic.DeepEqual(cur.Spec, et.Spec):
+			cur = cur.DeepCopy()
+			cur.Spec = et.Spec
+			if _, err := eventTypes.Update(ctx, cur, metav1.UpdateOptions{}); err != nil {
+				return fmt.Errorf("error updating the event type %q: %v", et.Spec.Type, err)
+			}
+		}
+	}
+	for _, et := range current {
+		if err := eventTypes.Delete(ctx, et.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("error deleting the event type %q: %v", et.Spec.Type, err)
+		}
+		controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, couchdbsourceEventTypeDeleted, "EventType %q deleted", et.Spec.Type)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+func TestSinkBroker(t *testing.T) {
+	src := &v1alpha1.CouchDbSource{ObjectMeta: metav1.ObjectMeta{Name: "source-name", Namespace: "ns"}}
+	testCases := map[string]struct {
+		dest *duckv1.Destination
+		want string
+	}{
+		"no sink": {},
+		"broker": {
+			dest: &duckv1.Destination{Ref: &duckv1.KReference{APIVersion: "eventing.knative.dev/v1", Kind: "Broker", Namespace: "ns", Name: "default"}},
+			want: "default",
+		},
+		"broker of another namespace": {
+			dest: &duckv1.Destination{Ref: &duckv1.KReference{APIVersion: "eventing.knative.dev/v1", Kind: "Broker", Namespace: "other", Name: "default"}},
+		},
+		"broker of another group": {
+			dest: &duckv1.Destination{Ref: &duckv1.KReference{APIVersion: "example.com/v1", Kind: "Broker", Namespace: "ns", Name: "default"}},
+		},
+		"service": {
+			dest: &duckv1.Destination{Ref: &duckv1.KReference{APIVersion: "serving.knative.dev/v1", Kind: "Service", Namespace: "ns", Name: "default"}},
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			if got := sinkBroker(src, tc.dest); got != tc.want {
+				t.Errorf("sinkBroker() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
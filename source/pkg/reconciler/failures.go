@@ -0,0 +1,70 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"errors"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/controller"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+// reconcileFailures collects the sub-resources of a source that failed to
+// reconcile, and the conditions reflecting them.
+type reconcileFailures struct {
+	conditions []v1alpha1.ConditionFailure
+	errs       []error
+}
+
+// add records that err made the condition fail for the given reason.
+func (f *reconcileFailures) add(condition apis.ConditionType, reason string, err error) {
+	f.conditions = append(f.conditions, v1alpha1.ConditionFailure{
+		Condition: condition,
+		Reason:    reason,
+		Message:   err.Error(),
+	})
+	f.errs = append(f.errs, err)
+}
+
+// err aggregates the errors of the failures. The aggregate is permanent only
+// when all of them are, so that a transient failure is still retried.
+func (f *reconcileFailures) err() error {
+	switch len(f.errs) {
+	case 0:
+		return nil
+	case 1:
+		return f.errs[0]
+	}
+	permanent := true
+	errs := make([]error, 0, len(f.errs))
+	for _, err := range f.errs {
+		if !controller.IsPermanentError(err) {
+			permanent = false
+		}
+		// Drop the permanence of the individual errors, which the
+		// aggregate would report otherwise.
+		errs = append(errs, errors.New(err.Error()))
+	}
+	agg := utilerrors.NewAggregate(errs)
+	if permanent {
+		return controller.NewPermanentError(agg)
+	}
+	return agg
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"errors"
+	"testing"
+
+	"knative.dev/pkg/controller"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+func TestReconcileFailures(t *testing.T) {
+	transient := errors.New("deployment conflict")
+	permanent := controller.NewPermanentError(errors.New("image not allowed"))
+	invalid := controller.NewPermanentError(errors.New("invalid event type template"))
+
+	testCases := map[string]struct {
+		errs          []error
+		wantErr       string
+		wantPermanent bool
+	}{
+		"none": {},
+		"one": {
+			errs:          []error{permanent},
+			wantErr:       "image not allowed",
+			wantPermanent: true,
+		},
+		"mixed": {
+			errs:    []error{transient, permanent},
+			wantErr: "[deployment conflict, image not allowed]",
+		},
+		"all permanent": {
+			errs:          []error{permanent, invalid},
+			wantErr:       "[image not allowed, invalid event type template]",
+			wantPermanent: true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			var f reconcileFailures
+			for _, err := range tc.errs {
+				f.add(v1alpha1.CouchDbConditionDeployed, "Failed", err)
+			}
+			err := f.err()
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("err() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.wantErr {
+				t.Fatalf("err() = %v, want %s", err, tc.wantErr)
+			}
+			if got := controller.IsPermanentError(err); got != tc.wantPermanent {
+				t.Errorf("IsPermanentError() = %v, want %v", got, tc.wantPermanent)
+			}
+			if len(f.conditions) != len(tc.errs) {
+				t.Errorf("recorded %d condition failures, want %d", len(f.conditions), len(tc.errs))
+			}
+		})
+	}
+}
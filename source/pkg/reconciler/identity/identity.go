@@ -0,0 +1,156 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package identity provides the identity of the cluster running the sources,
+// which is stamped on their events so that events aggregated from many
+// clusters remain attributable to their origin.
+package identity
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/configmap"
+
+	"knative.dev/eventing-couchdb/source/pkg/events"
+)
+
+const (
+	// ConfigName is the name of the ConfigMap configuring the cluster
+	// identity.
+	ConfigName = "config-couchdb-identity"
+
+	// Extension is the CloudEvents extension attribute holding the cluster
+	// identity when Attribute is AttributeExtension.
+	Extension = events.ClusterExtension
+)
+
+// Attribute is where the cluster identity is stamped on events.
+type Attribute string
+
+const (
+	// AttributeExtension stamps the identity in the couchdbcluster extension.
+	AttributeExtension = Attribute("extension")
+
+	// AttributeSource prefixes the default source of the events with the
+	// identity.
+	AttributeSource = Attribute("source")
+)
+
+// Provider provides the identity of the cluster.
+type Provider interface {
+	ClusterID() (string, error)
+}
+
+// Config is the configuration of the cluster identity.
+type Config struct {
+	// Provider is "configmap", "spiffe", or empty when events are not
+	// stamped.
+	Provider string
+
+	// ClusterID is the identity provided by the configmap provider.
+	ClusterID string
+
+	// SVIDPath is the PEM X.509 SVID the spiffe provider reads the trust
+	// domain of, e.g. written by the SPIFFE CSI driver or spiffe-helper.
+	SVIDPath string
+
+	// Attribute is where the identity is stamped. Defaults to extension.
+	Attribute Attribute
+}
+
+// NewConfigFromConfigMap parses the cluster identity ConfigMap.
+func NewConfigFromConfigMap(cm *corev1.ConfigMap) (*Config, error) {
+	c := &Config{Attribute: AttributeExtension}
+	var attribute string
+	if err := configmap.Parse(cm.Data,
+		configmap.AsString("provider", &c.Provider),
+		configmap.AsString("cluster-id", &c.ClusterID),
+		configmap.AsString("spiffe-svid-path", &c.SVIDPath),
+		configmap.AsString("attribute", &attribute),
+	); err != nil {
+		return nil, err
+	}
+	if attribute != "" {
+		c.Attribute = Attribute(attribute)
+	}
+	switch c.Attribute {
+	case AttributeExtension, AttributeSource:
+	default:
+		return nil, fmt.Errorf("invalid attribute %q", attribute)
+	}
+	if _, err := c.NewProvider(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewProvider returns the configured provider, or nil when events are not
+// stamped.
+func (c *Config) NewProvider() (Provider, error) {
+	switch c.Provider {
+	case "":
+		return nil, nil
+	case "configmap":
+		if c.ClusterID == "" {
+			return nil, errors.New("the configmap provider requires a cluster-id")
+		}
+		return staticProvider(c.ClusterID), nil
+	case "spiffe":
+		if c.SVIDPath == "" {
+			return nil, errors.New("the spiffe provider requires a spiffe-svid-path")
+		}
+		return spiffeProvider(c.SVIDPath), nil
+	default:
+		return nil, fmt.Errorf("unknown provider %q", c.Provider)
+	}
+}
+
+// staticProvider provides a configured identity.
+type staticProvider string
+
+func (p staticProvider) ClusterID() (string, error) {
+	return string(p), nil
+}
+
+// spiffeProvider provides the SPIFFE trust domain of the X.509 SVID at the
+// given path. The SVID is read every time, since it rotates.
+type spiffeProvider string
+
+func (p spiffeProvider) ClusterID() (string, error) {
+	data, err := ioutil.ReadFile(string(p))
+	if err != nil {
+		return "", fmt.Errorf("unable to read the SVID: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return "", fmt.Errorf("no PEM certificate in %s", string(p))
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", fmt.Errorf("invalid SVID: %v", err)
+	}
+	for _, uri := range cert.URIs {
+		if uri.Scheme == "spiffe" && uri.Host != "" {
+			return uri.Host, nil
+		}
+	}
+	return "", fmt.Errorf("the certificate in %s has no SPIFFE ID", string(p))
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package identity
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestNewConfigFromConfigMap(t *testing.T) {
+	testCases := map[string]struct {
+		data    map[string]string
+		want    Config
+		wantErr bool
+	}{
+		"disabled": {
+			want: Config{Attribute: AttributeExtension},
+		},
+		"configmap": {
+			data: map[string]string{"provider": "configmap", "cluster-id": "eu-west-1", "attribute": "source"},
+			want: Config{Provider: "configmap", ClusterID: "eu-west-1", Attribute: AttributeSource},
+		},
+		"configmap without cluster id": {
+			data:    map[string]string{"provider": "configmap"},
+			wantErr: true,
+		},
+		"spiffe without svid": {
+			data:    map[string]string{"provider": "spiffe"},
+			wantErr: true,
+		},
+		"unknown provider": {
+			data:    map[string]string{"provider": "aws"},
+			wantErr: true,
+		},
+		"invalid attribute": {
+			data:    map[string]string{"provider": "configmap", "cluster-id": "eu-west-1", "attribute": "subject"},
+			wantErr: true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			got, err := NewConfigFromConfigMap(&corev1.ConfigMap{Data: tc.data})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("NewConfigFromConfigMap() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err == nil && *got != tc.want {
+				t.Errorf("NewConfigFromConfigMap() = %+v, want %+v", *got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSPIFFEProvider(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := url.Parse("spiffe://prod.example.org/ns/knative-sources/sa/couchdb-controller-manager")
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		URIs:         []*url.URL{id},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "svid.pem")
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := (&Config{Provider: "spiffe", SVIDPath: path}).NewProvider()
+	if err != nil {
+		t.Fatalf("NewProvider() = %v", err)
+	}
+	got, err := provider.ClusterID()
+	if err != nil {
+		t.Fatalf("ClusterID() = %v", err)
+	}
+	if want := "prod.example.org"; got != want {
+		t.Errorf("ClusterID() = %q, want %q", got, want)
+	}
+
+	if _, err := spiffeProvider(filepath.Join(t.TempDir(), "missing.pem")).ClusterID(); err == nil {
+		t.Error("ClusterID() = nil, want an error for a missing SVID")
+	}
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	listers "knative.dev/eventing-couchdb/source/pkg/client/listers/sources/v1alpha1"
+)
+
+// installationEnvVar is the name of the environment variable holding the name
+// of the installation of the controller, when several are installed in the
+// cluster, e.g. a canary next to the production one. Each installation only
+// reconciles the sources labeled with its name, and the default unnamed one
+// the unlabeled sources.
+const installationEnvVar = "COUCHDB_INSTALLATION"
+
+// installationFilter returns whether a source belongs to the installation.
+func installationFilter(installation string) func(obj interface{}) bool {
+	return func(obj interface{}) bool {
+		o, ok := obj.(metav1.Object)
+		if !ok {
+			return false
+		}
+		name, labeled := o.GetLabels()[v1alpha1.InstallationLabelKey]
+		if installation == "" {
+			return !labeled
+		}
+		return name == installation
+	}
+}
+
+// installationOwnerFilter returns whether the controller of an object, e.g.
+// a receive adapter, is a source of the installation.
+func installationOwnerFilter(lister listers.CouchDbSourceLister, owns func(obj interface{}) bool) func(obj interface{}) bool {
+	return func(obj interface{}) bool {
+		o, ok := obj.(metav1.Object)
+		if !ok {
+			return false
+		}
+		ref := metav1.GetControllerOf(o)
+		if ref == nil {
+			return false
+		}
+		src, err := lister.CouchDbSources(o.GetNamespace()).Get(ref.Name)
+		if err != nil {
+			return false
+		}
+		return owns(src)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	listers "knative.dev/eventing-couchdb/source/pkg/client/listers/sources/v1alpha1"
+)
+
+func installationSource(name string, labels map[string]string) *v1alpha1.CouchDbSource {
+	return &v1alpha1.CouchDbSource{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      name,
+			Labels:    labels,
+		},
+	}
+}
+
+func TestInstallationFilter(t *testing.T) {
+	unlabeled := installationSource("unlabeled", nil)
+	canary := installationSource("canary", map[string]string{v1alpha1.InstallationLabelKey: "canary"})
+	other := installationSource("other", map[string]string{v1alpha1.InstallationLabelKey: "other"})
+
+	testCases := map[string]struct {
+		installation string
+		obj          interface{}
+		want         bool
+	}{
+		"default owns unlabeled": {
+			obj:  unlabeled,
+			want: true,
+		},
+		"default skips labeled": {
+			obj: canary,
+		},
+		"named owns labeled": {
+			installation: "canary",
+			obj:          canary,
+			want:         true,
+		},
+		"named skips unlabeled": {
+			installation: "canary",
+			obj:          unlabeled,
+		},
+		"named skips other": {
+			installation: "canary",
+			obj:          other,
+		},
+		"not an object": {
+			obj: "default/unlabeled",
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			if got := installationFilter(tc.installation)(tc.obj); got != tc.want {
+				t.Errorf("installationFilter(%q) = %v, want %v", tc.installation, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestInstallationOwnerFilter(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	canary := installationSource("canary", map[string]string{v1alpha1.InstallationLabelKey: "canary"})
+	unlabeled := installationSource("unlabeled", nil)
+	for _, src := range []*v1alpha1.CouchDbSource{canary, unlabeled} {
+		if err := indexer.Add(src); err != nil {
+			t.Fatal(err)
+		}
+	}
+	owns := installationOwnerFilter(listers.NewCouchDbSourceLister(indexer), installationFilter("canary"))
+
+	adapter := func(owner string) *appsv1.Deployment {
+		d := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "adapter"}}
+		if owner != "" {
+			src := installationSource(owner, nil)
+			d.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(src, v1alpha1.SchemeGroupVersion.WithKind("CouchDbSource"))}
+		}
+		return d
+	}
+
+	testCases := map[string]struct {
+		obj  interface{}
+		want bool
+	}{
+		"owned": {
+			obj:  adapter("canary"),
+			want: true,
+		},
+		"other installation": {
+			obj: adapter("unlabeled"),
+		},
+		"source not found": {
+			obj: adapter("deleted"),
+		},
+		"no controller": {
+			obj: adapter(""),
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			if got := owns(tc.obj); got != tc.want {
+				t.Errorf("installationOwnerFilter() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"time"
+
+	"go.uber.org/zap"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"knative.dev/pkg/logging"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+const (
+	// jobSinkPollInterval is how often the Jobs of the JobSink the events
+	// are sent to are counted while some are running.
+	jobSinkPollInterval = 30 * time.Second
+
+	// jobSinkNameLabel is the label the JobSinks put on their Jobs, and on
+	// the Secrets holding the events of the Jobs, set to the name of the
+	// JobSink.
+	jobSinkNameLabel = "sinks.knative.dev/job-sink-name"
+)
+
+// reconcileJobSink reflects the Jobs the JobSink the events are sent to
+// started for the events of the source in its status, and returns whether
+// some of them are running. The Jobs of the other clients of the JobSink
+// are told apart by the source of their event.
+func (r *Reconciler) reconcileJobSink(ctx context.Context, src *v1alpha1.CouchDbSource, ceSource string) bool {
+	dest := sinkDestination(ctx, src)
+	if !v1alpha1.IsJobSink(dest) {
+		src.Status.MarkNoJobSink()
+		return false
+	}
+	ref := dest.Ref
+	running := src.Status.Jobs != nil && src.Status.Jobs.Active > 0
+
+	opts := metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{jobSinkNameLabel: ref.Name}).String(),
+	}
+	jobs, err := r.kubeClientSet.BatchV1().Jobs(ref.Namespace).List(ctx, opts)
+	if err != nil {
+		logging.FromContext(ctx).Warnw("Unable to list the Jobs of the JobSink", zap.String("jobSink", ref.Name), zap.Error(err))
+		return running
+	}
+	secrets, err := r.kubeClientSet.CoreV1().Secrets(ref.Namespace).List(ctx, opts)
+	if err != nil {
+		logging.FromContext(ctx).Warnw("Unable to list the events of the JobSink", zap.String("jobSink", ref.Name), zap.Error(err))
+		return running
+	}
+	src.Status.PropagateJobSinkJobs(ref.Name, jobsOfSource(jobs.Items, secrets.Items, src, ceSource))
+	return src.Status.Jobs.Active > 0
+}
+
+// jobsOfSource returns the Jobs processing an event of the source. The
+// JobSink stores the event of each Job in a Secret named after it.
+func jobsOfSource(jobs []batchv1.Job, secrets []corev1.Secret, src *v1alpha1.CouchDbSource, ceSource string) []batchv1.Job {
+	sources := make(map[string]string, len(secrets))
+	for i := range secrets {
+		sources[secrets[i].Name] = eventSource(&secrets[i])
+	}
+	var owned []batchv1.Job
+	for _, job := range jobs {
+		if s, ok := sources[job.Name]; ok && matchesSource(src, ceSource, s) {
+			owned = append(owned, job)
+		}
+	}
+	return owned
+}
+
+// eventSource returns the source attribute of the event the Secret holds,
+// or "" when it holds none.
+func eventSource(secret *corev1.Secret) string {
+	for _, data := range secret.Data {
+		var event struct {
+			SpecVersion string `json:"specversion"`
+			Source      string `json:"source"`
+		}
+		if json.Unmarshal(data, &event) == nil && event.SpecVersion != "" {
+			return event.Source
+		}
+	}
+	return ""
+}
+
+// matchesSource returns whether the event source is the one of the events
+// of the source. The sources watching the databases of a pattern put the
+// database of each change in place of the pattern.
+func matchesSource(src *v1alpha1.CouchDbSource, ceSource, eventSource string) bool {
+	if eventSource == "" {
+		return false
+	}
+	if eventSource == ceSource {
+		return true
+	}
+	if src.Spec.Sharded() {
+		matched, err := path.Match(ceSource, eventSource)
+		return err == nil && matched
+	}
+	return false
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	batchv1client "k8s.io/client-go/kubernetes/typed/batch/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+// fakeJobSink lists the Jobs of a JobSink and the Secrets holding their
+// events.
+type fakeJobSink struct {
+	kubernetes.Interface
+
+	jobs       []batchv1.Job
+	secrets    []corev1.Secret
+	namespaces []string
+	selectors  []string
+}
+
+func (f *fakeJobSink) BatchV1() batchv1client.BatchV1Interface {
+	return &fakeJobList{sink: f}
+}
+
+func (f *fakeJobSink) CoreV1() corev1client.CoreV1Interface {
+	return &fakeSecretList{sink: f}
+}
+
+func (f *fakeJobSink) listed(namespace string, opts metav1.ListOptions) {
+	f.namespaces = append(f.namespaces, namespace)
+	f.selectors = append(f.selectors, opts.LabelSelector)
+}
+
+type fakeJobList struct {
+	batchv1client.BatchV1Interface
+	batchv1client.JobInterface
+
+	sink      *fakeJobSink
+	namespace string
+}
+
+func (f *fakeJobList) Jobs(namespace string) batchv1client.JobInterface {
+	f.namespace = namespace
+	return f
+}
+
+func (f *fakeJobList) List(_ context.Context, opts metav1.ListOptions) (*batchv1.JobList, error) {
+	f.sink.listed(f.namespace, opts)
+	return &batchv1.JobList{Items: f.sink.jobs}, nil
+}
+
+type fakeSecretList struct {
+	corev1client.CoreV1Interface
+	corev1client.SecretInterface
+
+	sink      *fakeJobSink
+	namespace string
+}
+
+func (f *fakeSecretList) Secrets(namespace string) corev1client.SecretInterface {
+	f.namespace = namespace
+	return f
+}
+
+func (f *fakeSecretList) List(_ context.Context, opts metav1.ListOptions) (*corev1.SecretList, error) {
+	f.sink.listed(f.namespace, opts)
+	return &corev1.SecretList{Items: f.sink.secrets}, nil
+}
+
+// jobSinkJob returns a Job of the JobSink, and the Secret holding its event.
+func jobSinkJob(name, source string, finished batchv1.JobConditionType) (batchv1.Job, corev1.Secret) {
+	job := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if finished != "" {
+		job.Status.Conditions = []batchv1.JobCondition{{Type: finished, Status: corev1.ConditionTrue}}
+	}
+	secret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Data: map[string][]byte{
+			"event": []byte(`{"specversion":"1.0","id":"1","type":"org.apache.couchdb.document.batch","source":"` + source + `"}`),
+		},
+	}
+	return job, secret
+}
+
+func TestReconcileJobSink(t *testing.T) {
+	jobSink := &duckv1.Destination{Ref: &duckv1.KReference{APIVersion: "sinks.knative.dev/v1alpha1", Kind: "JobSink", Name: "batches"}}
+	done, doneEvent := jobSinkJob("batches-1", "couchdb.example.com/orders", batchv1.JobComplete)
+	running, runningEvent := jobSinkJob("batches-2", "couchdb.example.com/orders", "")
+	failed, failedEvent := jobSinkJob("batches-3", "couchdb.example.com/orders-eu", batchv1.JobFailed)
+	other, otherEvent := jobSinkJob("batches-4", "couchdb.example.com/customers", batchv1.JobFailed)
+	// A Job whose event was already deleted.
+	unknown, _ := jobSinkJob("batches-5", "couchdb.example.com/orders", batchv1.JobFailed)
+
+	for n, tc := range map[string]struct {
+		sink           *duckv1.Destination
+		pattern        string
+		wantJobs       *v1alpha1.JobsStatus
+		wantActive     bool
+		wantNamespaces []string
+	}{
+		"not a JobSink": {
+			sink: &duckv1.Destination{Ref: &duckv1.KReference{APIVersion: "v1", Kind: "Service", Name: "batches"}},
+		},
+		"database": {
+			sink:           jobSink,
+			wantJobs:       &v1alpha1.JobsStatus{Active: 1, Succeeded: 1},
+			wantActive:     true,
+			wantNamespaces: []string{"source", "source"},
+		},
+		"pattern": {
+			sink:           jobSink,
+			pattern:        "orders*",
+			wantJobs:       &v1alpha1.JobsStatus{Active: 1, Succeeded: 1, Failed: 1},
+			wantActive:     true,
+			wantNamespaces: []string{"source", "source"},
+		},
+		"in another namespace": {
+			sink: &duckv1.Destination{Ref: &duckv1.KReference{
+				APIVersion: "sinks.knative.dev/v1alpha1", Kind: "JobSink", Namespace: "jobs", Name: "batches",
+			}},
+			wantJobs:       &v1alpha1.JobsStatus{Active: 1, Succeeded: 1},
+			wantActive:     true,
+			wantNamespaces: []string{"jobs", "jobs"},
+		},
+	} {
+		t.Run(n, func(t *testing.T) {
+			kube := &fakeJobSink{
+				jobs:    []batchv1.Job{done, running, failed, other, unknown},
+				secrets: []corev1.Secret{doneEvent, runningEvent, failedEvent, otherEvent},
+			}
+			r := &Reconciler{kubeClientSet: kube}
+			src := &v1alpha1.CouchDbSource{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "source", Name: "orders"},
+				Spec:       v1alpha1.CouchDbSourceSpec{Sink: tc.sink, Database: "orders"},
+			}
+			ceSource := "couchdb.example.com/orders"
+			if tc.pattern != "" {
+				src.Spec.Database, src.Spec.DatabasePattern = "", tc.pattern
+				ceSource = "couchdb.example.com/" + tc.pattern
+			}
+			src.Status.InitializeConditions()
+
+			if got := r.reconcileJobSink(context.Background(), src, ceSource); got != tc.wantActive {
+				t.Errorf("reconcileJobSink() = %v, want %v", got, tc.wantActive)
+			}
+			if diff := cmp.Diff(tc.wantJobs, src.Status.Jobs); diff != "" {
+				t.Errorf("Unexpected jobs (-want, +got) = %v", diff)
+			}
+			if diff := cmp.Diff(tc.wantNamespaces, kube.namespaces); diff != "" {
+				t.Errorf("Unexpected namespaces (-want, +got) = %v", diff)
+			}
+			for _, selector := range kube.selectors {
+				if want := "sinks.knative.dev/job-sink-name=batches"; selector != want {
+					t.Errorf("selector = %q, want %q", selector, want)
+				}
+			}
+			if tc.wantJobs == nil && src.Status.GetCondition(v1alpha1.CouchDbConditionJobsSucceeded) != nil {
+				t.Error("JobsSucceeded is set for a sink that is not a JobSink")
+			}
+		})
+	}
+}
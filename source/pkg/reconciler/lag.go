@@ -0,0 +1,224 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	appsv1listers "k8s.io/client-go/listers/apps/v1"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/network"
+	"knative.dev/pkg/system"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-couchdb/source/pkg/reconciler/resources"
+)
+
+const (
+	// controllerServiceName is the Service in front of the controller.
+	controllerServiceName = "couchdb-controller-manager"
+
+	// lagPort and lagPath are where the controller serves the lag of the
+	// receive adapters scaled by KEDA.
+	lagPort = 8085
+	lagPath = "/lag/"
+)
+
+// lagURL returns the URL the KEDA metrics-api scaler reads the lag of the
+// receive adapter Deployment from.
+func lagURL(ra *appsv1.Deployment) string {
+	return fmt.Sprintf("http://%s:%d%s%s/%s", network.GetServiceHostname(controllerServiceName, system.Namespace()),
+		lagPort, lagPath, ra.Namespace, ra.Name)
+}
+
+// adapterLag is the body served on the lag endpoint.
+type adapterLag struct {
+	// Pending is the number of changes made after the checkpoint of the
+	// receive adapter.
+	Pending int64 `json:"pending"`
+}
+
+// lagHandler serves the lag of the receive adapters scaled by KEDA, which
+// cannot compute it from CouchDB alone: it reads the checkpoint the adapter
+// saves in the database, and asks CouchDB how many changes follow it.
+type lagHandler struct {
+	deployments appsv1listers.DeploymentLister
+
+	// credentials returns the url of the credentials secret of the given
+	// namespace and name.
+	credentials func(ctx context.Context, namespace, name string) (string, error)
+
+	client *http.Client
+}
+
+func newLagHandler(kube kubernetes.Interface, deployments appsv1listers.DeploymentLister) *lagHandler {
+	return &lagHandler{
+		deployments: deployments,
+		credentials: func(ctx context.Context, namespace, name string) (string, error) {
+			secret, err := kube.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return "", err
+			}
+			rawurl, ok := secret.Data["url"]
+			if !ok {
+				return "", fmt.Errorf("secret %s/%s has no url", namespace, name)
+			}
+			return string(rawurl), nil
+		},
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// ServeHTTP serves the lag of the receive adapter Deployment at
+// /lag/<namespace>/<name>.
+func (h *lagHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(req.URL.Path, lagPath), "/")
+	if len(parts) != 2 {
+		http.NotFound(w, req)
+		return
+	}
+	ra, err := h.deployments.Deployments(parts[0]).Get(parts[1])
+	if apierrors.IsNotFound(err) {
+		http.NotFound(w, req)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if owner := metav1.GetControllerOf(ra); owner == nil || owner.Kind != "CouchDbSource" ||
+		owner.APIVersion != v1alpha1.SchemeGroupVersion.String() {
+		// Only the receive adapters are served, not any Deployment.
+		http.NotFound(w, req)
+		return
+	}
+
+	pending, err := h.pending(req.Context(), ra)
+	if err != nil {
+		logging.FromContext(req.Context()).Warnw("Unable to compute the lag of the receive adapter",
+			zap.String("namespace", ra.Namespace), zap.String("name", ra.Name), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&adapterLag{Pending: pending})
+}
+
+// pending returns the number of changes made to the database of the receive
+// adapter after its checkpoint. An adapter that has not saved a checkpoint yet
+// lags by one change, so that it is scaled up to save one.
+func (h *lagHandler) pending(ctx context.Context, ra *appsv1.Deployment) (int64, error) {
+	var secret, database, checkpointDoc string
+	for _, v := range ra.Spec.Template.Spec.Volumes {
+		if v.Name == resources.CredentialsVolumeName && v.Secret != nil {
+			secret = v.Secret.SecretName
+		}
+	}
+	for _, c := range ra.Spec.Template.Spec.Containers {
+		for _, e := range c.Env {
+			switch e.Name {
+			case resources.DatabaseEnv:
+				database = e.Value
+			case resources.CheckpointDocEnv:
+				checkpointDoc = e.Value
+			}
+		}
+	}
+	if secret == "" || database == "" || checkpointDoc == "" {
+		return 0, fmt.Errorf("the receive adapter does not save its checkpoint")
+	}
+
+	rawurl, err := h.credentials(ctx, ra.Namespace, secret)
+	if err != nil {
+		return 0, fmt.Errorf("unable to read the credentials: %w", err)
+	}
+	db := strings.TrimSuffix(rawurl, "/") + "/" + url.PathEscape(database)
+
+	var saved struct {
+		Sequence v1alpha1.SequenceID `json:"sequence"`
+	}
+	if found, err := h.get(ctx, db+"/"+checkpointDoc, &saved); err != nil {
+		return 0, fmt.Errorf("unable to read the checkpoint: %w", err)
+	} else if !found || saved.Sequence == "" {
+		return 1, nil
+	}
+
+	// The changes feed counts the changes pending after the ones returned.
+	var changes struct {
+		Results []json.RawMessage `json:"results"`
+		Pending int64             `json:"pending"`
+	}
+	if _, err := h.get(ctx, db+"/_changes?limit=1&since="+url.QueryEscape(string(saved.Sequence)), &changes); err != nil {
+		return 0, fmt.Errorf("unable to read the changes: %w", err)
+	}
+	return int64(len(changes.Results)) + changes.Pending, nil
+}
+
+// get decodes the JSON response of CouchDB to a GET of the URL, and returns
+// whether it was found.
+func (h *lagHandler) get(ctx context.Context, u string, v interface{}) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, json.NewDecoder(resp.Body).Decode(v)
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+}
+
+// serveLag serves the lag of the receive adapters until ctx is done.
+func serveLag(ctx context.Context, h http.Handler) {
+	mux := http.NewServeMux()
+	mux.Handle(lagPath, h)
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", lagPort),
+		Handler: mux,
+		// The requests log with the logger of the controller.
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logging.FromContext(ctx).Errorw("The lag server failed", zap.Error(err))
+		}
+	}()
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	appsv1listers "k8s.io/client-go/listers/apps/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-couchdb/source/pkg/reconciler/resources"
+)
+
+func TestLagHandler(t *testing.T) {
+	keda := func(uid string) *v1alpha1.CouchDbSource {
+		return &v1alpha1.CouchDbSource{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "source-" + uid,
+				Namespace:   "default",
+				UID:         types.UID("uid-" + uid),
+				Annotations: map[string]string{v1alpha1.AutoscalingClassAnnotationKey: v1alpha1.KedaAutoscalingClass},
+			},
+			Spec: v1alpha1.CouchDbSourceSpec{Database: "orders"},
+		}
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, src := range []*v1alpha1.CouchDbSource{keda("saved"), keda("new"), {
+		ObjectMeta: metav1.ObjectMeta{Name: "source-plain", Namespace: "default", UID: "uid-plain"},
+	}} {
+		src.Spec.CouchDbCredentials.Name = "couchdb-binding"
+		ra := resources.MakeReceiveAdapter(&resources.ReceiveAdapterArgs{
+			Image:  "test-image",
+			Source: src,
+			Labels: resources.Labels(src.Name),
+		})
+		ra.Name = src.Name
+		indexer.Add(ra)
+	}
+	// Not a receive adapter.
+	indexer.Add(&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"}})
+
+	couchdb := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/orders/_local/knative-checkpoint-uid-saved":
+			w.Write([]byte(`{"_id":"_local/knative-checkpoint-uid-saved","sequence":"7-g"}`))
+		case "/orders/_changes":
+			if r.URL.Query().Get("since") != "7-g" {
+				t.Errorf("since = %q, want 7-g", r.URL.Query().Get("since"))
+			}
+			w.Write([]byte(`{"results":[{"seq":"8-h","id":"a","changes":[{"rev":"1-a"}]}],"last_seq":"8-h","pending":41}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer couchdb.Close()
+
+	h := &lagHandler{
+		deployments: appsv1listers.NewDeploymentLister(indexer),
+		credentials: func(_ context.Context, namespace, name string) (string, error) {
+			if namespace != "default" || name != "couchdb-binding" {
+				t.Errorf("credentials %s/%s, want default/couchdb-binding", namespace, name)
+			}
+			return couchdb.URL + "/", nil
+		},
+		client: couchdb.Client(),
+	}
+
+	testCases := map[string]struct {
+		path        string
+		wantCode    int
+		wantPending int64
+	}{
+		"saved checkpoint": {
+			path:        "/lag/default/source-saved",
+			wantCode:    http.StatusOK,
+			wantPending: 42,
+		},
+		"no checkpoint yet": {
+			path:        "/lag/default/source-new",
+			wantCode:    http.StatusOK,
+			wantPending: 1,
+		},
+		"not scaled by keda": {
+			path:     "/lag/default/source-plain",
+			wantCode: http.StatusBadGateway,
+		},
+		"not a receive adapter": {
+			path:     "/lag/default/other",
+			wantCode: http.StatusNotFound,
+		},
+		"unknown deployment": {
+			path:     "/lag/default/unknown",
+			wantCode: http.StatusNotFound,
+		},
+		"invalid path": {
+			path:     "/lag/default",
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			if rec.Code != tc.wantCode {
+				t.Fatalf("status code = %d, want %d: %s", rec.Code, tc.wantCode, rec.Body)
+			}
+			if tc.wantCode != http.StatusOK {
+				return
+			}
+			var got adapterLag
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("invalid lag: %v", err)
+			}
+			if got.Pending != tc.wantPending {
+				t.Errorf("pending = %d, want %d", got.Pending, tc.wantPending)
+			}
+		})
+	}
+}
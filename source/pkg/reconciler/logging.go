@@ -0,0 +1,64 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-couchdb/source/pkg/reconciler/resources"
+)
+
+// setLoggingConfig sets the logging configuration of the receive adapters,
+// the keys of config-logging applying to them.
+func (r *Reconciler) setLoggingConfig(data map[string]string) {
+	r.loggingMu.Lock()
+	defer r.loggingMu.Unlock()
+	r.loggingConfig = resources.AdapterLoggingConfig(data)
+}
+
+// reconcileLoggingConfig copies the logging configuration of the receive
+// adapters to the ConfigMap the adapter of the source mounts, which cannot
+// mount config-logging from the namespace of the controller. The adapter
+// follows the changes of its level without restarting.
+func (r *Reconciler) reconcileLoggingConfig(ctx context.Context, src *v1alpha1.CouchDbSource) error {
+	r.loggingMu.RLock()
+	expected := resources.MakeLoggingConfigMap(src, r.loggingConfig)
+	r.loggingMu.RUnlock()
+
+	configMaps := r.kubeClientSet.CoreV1().ConfigMaps(src.Namespace)
+	cm, err := configMaps.Get(ctx, expected.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = configMaps.Create(ctx, expected, metav1.CreateOptions{})
+		return err
+	} else if err != nil {
+		return fmt.Errorf("error getting logging configmap: %v", err)
+	} else if !metav1.IsControlledBy(cm, src) {
+		return fmt.Errorf("configmap %q is not owned by CouchDbSource %q", cm.Name, src.Name)
+	}
+	if equality.Semantic.DeepEqual(cm.Data, expected.Data) {
+		return nil
+	}
+	cm.Data = expected.Data
+	_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
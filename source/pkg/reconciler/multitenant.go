@@ -0,0 +1,155 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/system"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-couchdb/source/pkg/reconciler/resources"
+)
+
+const (
+	// adapterModeEnvVar is the name of the environment variable selecting
+	// how the sources are served: by a receive adapter Deployment each, by
+	// default, or, when set to adapterModeMultiTenant, by the multi-tenant
+	// receive adapter shared by the sources of the installation, or, when set
+	// to adapterModeContainerSource, by ContainerSources.
+	adapterModeEnvVar      = "COUCHDB_ADAPTER_MODE"
+	adapterModeMultiTenant = "multitenant"
+
+	// mtAdapterName is the name of the Deployment of the multi-tenant
+	// receive adapter, in the system namespace.
+	mtAdapterName = "couchdb-mtadapter"
+
+	couchdbsourceTenantCreated = "CouchDbSourceTenantCreated"
+	couchdbsourceTenantUpdated = "CouchDbSourceTenantUpdated"
+)
+
+// servedByMTAdapter returns whether the multi-tenant receive adapter serves
+// the source. The sources needing a pod of their own keep their Deployment:
+// those selecting an adapter image, a service account or a pod template,
+// serving their status, delivering under a lease, scaled by KEDA, buffering
+// the events the sink does not accept, going through a proxy, sending their
+// events with a token for the OIDC audience of their sink, verifying it with
+// CAs of their own or tightening the parsing limits, which apply to the whole
+// process, and those whose changes wait for approval.
+func (r *Reconciler) servedByMTAdapter(src *v1alpha1.CouchDbSource) bool {
+	if !r.multiTenant {
+		return false
+	}
+	spec := &src.Spec
+	if _, ok := src.Annotations[v1alpha1.AdapterImageAnnotationKey]; ok {
+		return false
+	}
+	limited := spec.Limits != nil && (spec.Limits.MaxLineBytes > 0 || spec.Limits.MaxJSONDepth > 0)
+	return spec.ServiceAccountName == "" &&
+		spec.Template == nil &&
+		!spec.ServesStatus() &&
+		!spec.Leased() &&
+		!src.KedaAutoscaled() &&
+		spec.Buffer == nil &&
+		spec.Proxy == nil &&
+		src.Status.SinkAudience == nil &&
+		src.Status.SinkCACerts == nil &&
+		!limited &&
+		spec.ApplyMode != v1alpha1.ApplyModeManual
+}
+
+// reconcileTenant writes the configuration of the source to the ConfigMap the
+// multi-tenant receive adapter serves it from, and returns the Deployment of
+// the multi-tenant adapter, whose availability stands for the one of the
+// adapter of the source.
+func (r *Reconciler) reconcileTenant(ctx context.Context, src *v1alpha1.CouchDbSource, status *v1alpha1.CouchDbSourceStatus, sinkURI, deadLetterSinkURI *apis.URL) (*appsv1.Deployment, error) {
+	adapterArgs, err := r.makeReceiveAdapterArgs(ctx, src, r.receiveAdapterImage, sinkURI, deadLetterSinkURI)
+	if err != nil {
+		return nil, err
+	}
+	expected, err := resources.MakeTenantConfig(adapterArgs, r.installation)
+	if err != nil {
+		return nil, err
+	}
+
+	// The source may have had a receive adapter of its own, or a
+	// ContainerSource, before.
+	if err := r.deleteReceiveAdapter(ctx, src, expected.Name); err != nil {
+		return nil, err
+	}
+	if err := r.deleteReceiveAdapterJob(ctx, src, expected.Name); err != nil {
+		return nil, err
+	}
+	if err := r.deleteContainerSource(ctx, src, expected.Name); err != nil {
+		return nil, err
+	}
+	if err := r.deleteReceiveAdapterStatefulSet(ctx, src, expected.Name); err != nil {
+		return nil, err
+	}
+
+	configMaps := r.kubeClientSet.CoreV1().ConfigMaps(src.Namespace)
+	cm, err := configMaps.Get(ctx, expected.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = configMaps.Create(ctx, expected, metav1.CreateOptions{})
+		controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, couchdbsourceTenantCreated, "Tenant ConfigMap created, error: %v", err)
+		if err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("error getting tenant configmap: %v", err)
+	} else if !metav1.IsControlledBy(cm, src) {
+		return nil, fmt.Errorf("configmap %q is not owned by CouchDbSource %q", cm.Name, src.Name)
+	} else if !equality.Semantic.DeepEqual(cm.Data, expected.Data) || !equality.Semantic.DeepEqual(cm.Labels, expected.Labels) {
+		cm.Data = expected.Data
+		cm.Labels = expected.Labels
+		if _, err := configMaps.Update(ctx, cm, metav1.UpdateOptions{}); err != nil {
+			return nil, err
+		}
+		controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, couchdbsourceTenantUpdated, "Tenant ConfigMap updated")
+	}
+	status.MarkChangesApplied()
+
+	ra, err := r.deploymentLister.Deployments(system.Namespace()).Get(mtAdapterName)
+	if err != nil {
+		return nil, fmt.Errorf("error getting the multi-tenant receive adapter: %v", err)
+	}
+	return ra, nil
+}
+
+// deleteTenant deletes the ConfigMap the multi-tenant receive adapter served
+// the source from, if any.
+func (r *Reconciler) deleteTenant(ctx context.Context, src *v1alpha1.CouchDbSource, name string) error {
+	configMaps := r.kubeClientSet.CoreV1().ConfigMaps(src.Namespace)
+	cm, err := configMaps.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) || (err == nil && !metav1.IsControlledBy(cm, src)) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error getting tenant configmap: %v", err)
+	}
+	if err := configMaps.Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("error deleting tenant configmap: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+func TestServedByMTAdapter(t *testing.T) {
+	testCases := map[string]struct {
+		multiTenant bool
+		annotations map[string]string
+		spec        v1alpha1.CouchDbSourceSpec
+		audience    string
+		want        bool
+	}{
+		"single tenant mode": {},
+		"served": {
+			multiTenant: true,
+			want:        true,
+		},
+		"adapter image": {
+			multiTenant: true,
+			annotations: map[string]string{v1alpha1.AdapterImageAnnotationKey: "image"},
+		},
+		"service account": {
+			multiTenant: true,
+			spec:        v1alpha1.CouchDbSourceSpec{ServiceAccountName: "couchdb"},
+		},
+		"backfill": {
+			multiTenant: true,
+			spec:        v1alpha1.CouchDbSourceSpec{Backfill: true},
+		},
+		"global ordering": {
+			multiTenant: true,
+			spec:        v1alpha1.CouchDbSourceSpec{Ordering: v1alpha1.OrderingGlobal},
+		},
+		"high availability": {
+			multiTenant: true,
+			spec:        v1alpha1.CouchDbSourceSpec{HighAvailability: &v1alpha1.HighAvailabilitySpec{Replicas: 2}},
+		},
+		"keda": {
+			multiTenant: true,
+			annotations: map[string]string{v1alpha1.AutoscalingClassAnnotationKey: v1alpha1.KedaAutoscalingClass},
+		},
+		"buffer": {
+			multiTenant: true,
+			spec:        v1alpha1.CouchDbSourceSpec{Buffer: &v1alpha1.BufferSpec{Size: resource.MustParse("1Gi")}},
+		},
+		"proxy": {
+			multiTenant: true,
+			spec:        v1alpha1.CouchDbSourceSpec{Proxy: &v1alpha1.ProxySpec{URL: "http://proxy:3128"}},
+		},
+		"parsing limits": {
+			multiTenant: true,
+			spec:        v1alpha1.CouchDbSourceSpec{Limits: &v1alpha1.LimitsSpec{MaxLineBytes: 1024}},
+		},
+		"manual apply": {
+			multiTenant: true,
+			spec:        v1alpha1.CouchDbSourceSpec{ApplyMode: v1alpha1.ApplyModeManual},
+		},
+		"sink audience": {
+			multiTenant: true,
+			audience:    "eventing.knative.dev/broker/default/default",
+		},
+		"pod template": {
+			multiTenant: true,
+			spec:        v1alpha1.CouchDbSourceSpec{Template: &v1alpha1.AdapterTemplateSpec{NodeSelector: map[string]string{"pool": "couchdb"}}},
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			r := &Reconciler{multiTenant: tc.multiTenant}
+			src := &v1alpha1.CouchDbSource{
+				ObjectMeta: metav1.ObjectMeta{Annotations: tc.annotations},
+				Spec:       tc.spec,
+			}
+			src.Status.MarkSinkAudience(tc.audience, "default")
+			if got := r.servedByMTAdapter(src); got != tc.want {
+				t.Errorf("servedByMTAdapter() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/kmeta"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+const (
+	// BufferVolumeName and BufferMountPath are the volume of the receive
+	// adapter holding spec.buffer, and where it is mounted.
+	BufferVolumeName = "buffer"
+	BufferMountPath  = "/var/run/couchdb-buffer"
+)
+
+// BufferClaimName is the name of the PersistentVolumeClaim holding the
+// buffer of a CouchDbSource with spec.buffer set.
+func BufferClaimName(src *v1alpha1.CouchDbSource) string {
+	return kmeta.ChildName(fmt.Sprintf("couchdbsource-%s-buffer-", src.Name), string(src.UID))
+}
+
+// MakeBufferClaim generates (but does not insert into K8s) the
+// PersistentVolumeClaim holding the buffer of the source, mounted by its
+// single receive adapter.
+func MakeBufferClaim(src *v1alpha1.CouchDbSource) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: src.Namespace,
+			Name:      BufferClaimName(src),
+			Labels:    Labels(src.Name),
+			OwnerReferences: []metav1.OwnerReference{
+				*kmeta.NewControllerRef(src),
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			StorageClassName: src.Spec.Buffer.StorageClassName,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: src.Spec.Buffer.Size,
+				},
+			},
+		},
+	}
+}
+
+// addBuffer mounts the buffer of the source in the pod of the receive
+// adapter.
+func addBuffer(template *corev1.PodTemplateSpec, src *v1alpha1.CouchDbSource) {
+	if src.Spec.Buffer == nil {
+		return
+	}
+	spec := &template.Spec
+	spec.Volumes = append(spec.Volumes, corev1.Volume{
+		Name: BufferVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: BufferClaimName(src),
+			},
+		},
+	})
+	c := &spec.Containers[0]
+	c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{
+		Name:      BufferVolumeName,
+		MountPath: BufferMountPath,
+	})
+}
+
+// deploymentStrategy returns the strategy of the receive adapter Deployment:
+// a pod holding the buffer is replaced once stopped, since its volume can
+// only be mounted by one node at a time.
+func deploymentStrategy(src *v1alpha1.CouchDbSource) v1.DeploymentStrategy {
+	if src.Spec.Buffer != nil {
+		return v1.DeploymentStrategy{Type: v1.RecreateDeploymentStrategyType}
+	}
+	return v1.DeploymentStrategy{Type: v1.RollingUpdateDeploymentStrategyType}
+}
+
+// StrategyChanged is whether the type of the strategy of the current receive
+// adapter Deployment differs from the expected one. The API server defaults
+// the parameters of rolling updates, which are not changes.
+func StrategyChanged(current, expected *v1.Deployment) bool {
+	t1 := current.Spec.Strategy.Type
+	if t1 == "" {
+		t1 = v1.RollingUpdateDeploymentStrategyType
+	}
+	return t1 != expected.Spec.Strategy.Type
+}
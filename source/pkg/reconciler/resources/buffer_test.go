@@ -0,0 +1,114 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+func TestMakeReceiveAdapterBuffer(t *testing.T) {
+	storageClass := "ssd"
+	src := &v1alpha1.CouchDbSource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "source-name",
+			Namespace: "source-namespace",
+			UID:       "1234",
+		},
+		Spec: v1alpha1.CouchDbSourceSpec{
+			Buffer: &v1alpha1.BufferSpec{
+				Size:             resource.MustParse("1Gi"),
+				StorageClassName: &storageClass,
+			},
+		},
+	}
+
+	claim := MakeBufferClaim(src)
+	if claim.Name != BufferClaimName(src) || claim.Namespace != "source-namespace" {
+		t.Errorf("claim %s/%s, want source-namespace/%s", claim.Namespace, claim.Name, BufferClaimName(src))
+	}
+	if len(claim.OwnerReferences) != 1 || claim.OwnerReferences[0].UID != "1234" {
+		t.Errorf("OwnerReferences = %v, want the source", claim.OwnerReferences)
+	}
+	if got := claim.Spec.Resources.Requests[corev1.ResourceStorage]; got.Cmp(src.Spec.Buffer.Size) != 0 {
+		t.Errorf("storage request = %v, want 1Gi", got.String())
+	}
+	if got := claim.Spec.StorageClassName; got == nil || *got != storageClass {
+		t.Errorf("storage class = %v, want %s", got, storageClass)
+	}
+
+	ra := MakeReceiveAdapter(&ReceiveAdapterArgs{
+		Image:  "test-image",
+		Source: src,
+		Labels: Labels("source-name"),
+	})
+	if got := ra.Spec.Strategy.Type; got != v1.RecreateDeploymentStrategyType {
+		t.Errorf("strategy = %s, want Recreate", got)
+	}
+	var mounted bool
+	for _, m := range ra.Spec.Template.Spec.Containers[0].VolumeMounts {
+		mounted = mounted || (m.Name == BufferVolumeName && m.MountPath == BufferMountPath)
+	}
+	if !mounted {
+		t.Errorf("the buffer is not mounted at %s", BufferMountPath)
+	}
+	var claimed string
+	for _, v := range ra.Spec.Template.Spec.Volumes {
+		if v.Name == BufferVolumeName && v.PersistentVolumeClaim != nil {
+			claimed = v.PersistentVolumeClaim.ClaimName
+		}
+	}
+	if claimed != claim.Name {
+		t.Errorf("buffer volume claims %q, want %q", claimed, claim.Name)
+	}
+}
+
+func TestStrategyChanged(t *testing.T) {
+	deployment := func(strategy v1.DeploymentStrategyType) *v1.Deployment {
+		return &v1.Deployment{Spec: v1.DeploymentSpec{Strategy: v1.DeploymentStrategy{Type: strategy}}}
+	}
+	testCases := map[string]struct {
+		current, expected v1.DeploymentStrategyType
+		want              bool
+	}{
+		"defaulted": {
+			expected: v1.RollingUpdateDeploymentStrategyType,
+		},
+		"unchanged": {
+			current:  v1.RecreateDeploymentStrategyType,
+			expected: v1.RecreateDeploymentStrategyType,
+		},
+		"changed": {
+			current:  v1.RollingUpdateDeploymentStrategyType,
+			expected: v1.RecreateDeploymentStrategyType,
+			want:     true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			if got := StrategyChanged(deployment(tc.current), deployment(tc.expected)); got != tc.want {
+				t.Errorf("StrategyChanged() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
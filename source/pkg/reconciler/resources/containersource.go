@@ -0,0 +1,67 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	sourcesv1 "knative.dev/eventing/pkg/apis/sources/v1"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+)
+
+// ContainerSourceGVR is the resource of the Knative Eventing ContainerSources,
+// whose clients are not vendored.
+var ContainerSourceGVR = sourcesv1.SchemeGroupVersion.WithResource("containersources")
+
+// sinkBindingEnv are the environment variables the SinkBinding of a
+// ContainerSource injects into its pods.
+var sinkBindingEnv = map[string]bool{
+	"K_SINK":         true,
+	"K_CE_OVERRIDES": true,
+}
+
+// MakeContainerSource generates (but does not insert into K8s) the
+// ContainerSource running the receive adapter of the source. Its SinkBinding
+// resolves the sink and injects it, along with the CloudEvent overrides, into
+// the pod of the adapter, in place of the ones the controller sets on the
+// Deployments it manages.
+func MakeContainerSource(args *ReceiveAdapterArgs, sink duckv1.Destination) *sourcesv1.ContainerSource {
+	template := makePodTemplate(args)
+	for i, c := range template.Spec.Containers {
+		env := make([]corev1.EnvVar, 0, len(c.Env))
+		for _, e := range c.Env {
+			if !sinkBindingEnv[e.Name] {
+				env = append(env, e)
+			}
+		}
+		template.Spec.Containers[i].Env = env
+	}
+	return &sourcesv1.ContainerSource{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: sourcesv1.SchemeGroupVersion.String(),
+			Kind:       "ContainerSource",
+		},
+		ObjectMeta: makeObjectMeta(args),
+		Spec: sourcesv1.ContainerSourceSpec{
+			Template: template,
+			SourceSpec: duckv1.SourceSpec{
+				Sink:                sink,
+				CloudEventOverrides: makeCloudEventOverrides(args),
+			},
+		},
+	}
+}
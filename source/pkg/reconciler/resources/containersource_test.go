@@ -0,0 +1,83 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+func TestMakeContainerSource(t *testing.T) {
+	args := &ReceiveAdapterArgs{
+		Image: "test-image",
+		Source: &v1alpha1.CouchDbSource{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "source-name",
+				Namespace: "source-namespace",
+				UID:       "1234",
+			},
+			Spec: v1alpha1.CouchDbSourceSpec{
+				CloudEventOverrides: &duckv1.CloudEventOverrides{
+					Extensions: map[string]string{"team": "storage"},
+				},
+			},
+		},
+		Labels:  Labels("source-name"),
+		SinkURI: "sink-uri",
+	}
+	sink := duckv1.Destination{
+		Ref: &duckv1.KReference{
+			APIVersion: "eventing.knative.dev/v1",
+			Kind:       "Broker",
+			Name:       "default",
+			Namespace:  "source-namespace",
+		},
+	}
+	got := MakeContainerSource(args, sink)
+	ra := MakeReceiveAdapter(args)
+
+	if got.Name != ra.Name || got.Namespace != ra.Namespace {
+		t.Errorf("ContainerSource %s/%s, want %s/%s", got.Namespace, got.Name, ra.Namespace, ra.Name)
+	}
+	if refs := got.OwnerReferences; len(refs) != 1 || refs[0].UID != "1234" {
+		t.Errorf("OwnerReferences = %v, want the source", refs)
+	}
+	if diff := cmp.Diff(sink, got.Spec.Sink); diff != "" {
+		t.Errorf("unexpected sink (-want, +got) = %v", diff)
+	}
+	if diff := cmp.Diff(args.Source.Spec.CloudEventOverrides, got.Spec.CloudEventOverrides); diff != "" {
+		t.Errorf("unexpected CloudEvent overrides (-want, +got) = %v", diff)
+	}
+	if diff := cmp.Diff(ra.Spec.Template.Labels, got.Spec.Template.Labels); diff != "" {
+		t.Errorf("unexpected pod labels (-want, +got) = %v", diff)
+	}
+
+	// The SinkBinding injects the sink and the overrides.
+	for _, e := range got.Spec.Template.Spec.Containers[0].Env {
+		if e.Name == "K_SINK" || e.Name == "K_CE_OVERRIDES" {
+			t.Errorf("Env has %s, want it left to the SinkBinding", e.Name)
+		}
+	}
+	if len(got.Spec.Template.Spec.Containers[0].Env) == 0 {
+		t.Error("Env is empty, want the configuration of the adapter")
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"knative.dev/pkg/kmeta"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+const (
+	// DevInstanceUser is the admin user of a provisioned dev instance.
+	DevInstanceUser = "admin"
+
+	devInstancePort = 5984
+)
+
+// DevInstanceName is the name shared by the Secret, Service and StatefulSet
+// provisioned for a CouchDbSource with spec.devInstance set.
+func DevInstanceName(src *v1alpha1.CouchDbSource) string {
+	return kmeta.ChildName(fmt.Sprintf("couchdb-%s-", src.Name), string(src.UID))
+}
+
+// DevInstanceLabels are the labels selecting the pods of a dev instance.
+func DevInstanceLabels(name string) map[string]string {
+	return map[string]string{
+		"knative-eventing-source":             controllerAgentName,
+		"knative-eventing-source-devinstance": name,
+	}
+}
+
+// MakeDevInstanceSecret generates (but does not insert into K8s) the Secret
+// holding the admin password and url of a dev instance.
+func MakeDevInstanceSecret(src *v1alpha1.CouchDbSource, password string) *corev1.Secret {
+	name := DevInstanceName(src)
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: src.Namespace,
+			Name:      name,
+			Labels:    DevInstanceLabels(src.Name),
+			OwnerReferences: []metav1.OwnerReference{
+				*kmeta.NewControllerRef(src),
+			},
+		},
+		Data: map[string][]byte{
+			"password": []byte(password),
+			"url": []byte(fmt.Sprintf("http://%s:%s@%s.%s.svc:%d",
+				DevInstanceUser, password, name, src.Namespace, devInstancePort)),
+		},
+	}
+}
+
+// MakeDevInstanceService generates (but does not insert into K8s) the Service
+// fronting a dev instance.
+func MakeDevInstanceService(src *v1alpha1.CouchDbSource) *corev1.Service {
+	labels := DevInstanceLabels(src.Name)
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: src.Namespace,
+			Name:      DevInstanceName(src),
+			Labels:    labels,
+			OwnerReferences: []metav1.OwnerReference{
+				*kmeta.NewControllerRef(src),
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: labels,
+			Ports: []corev1.ServicePort{{
+				Name:       "http",
+				Port:       devInstancePort,
+				TargetPort: intstr.FromInt(devInstancePort),
+			}},
+		},
+	}
+}
+
+// MakeDevInstance generates (but does not insert into K8s) a throwaway
+// single-node CouchDB StatefulSet. Data lives in an emptyDir and is lost
+// whenever the pod is rescheduled.
+func MakeDevInstance(src *v1alpha1.CouchDbSource, image string) *v1.StatefulSet {
+	name := DevInstanceName(src)
+	labels := DevInstanceLabels(src.Name)
+	replicas := int32(1)
+	return &v1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: src.Namespace,
+			Name:      name,
+			Labels:    labels,
+			OwnerReferences: []metav1.OwnerReference{
+				*kmeta.NewControllerRef(src),
+			},
+		},
+		Spec: v1.StatefulSetSpec{
+			ServiceName: name,
+			Replicas:    &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:  "couchdb",
+						Image: image,
+						Ports: []corev1.ContainerPort{{
+							Name:          "http",
+							ContainerPort: devInstancePort,
+						}},
+						Env: []corev1.EnvVar{{
+							Name:  "COUCHDB_USER",
+							Value: DevInstanceUser,
+						}, {
+							Name: "COUCHDB_PASSWORD",
+							ValueFrom: &corev1.EnvVarSource{
+								SecretKeyRef: &corev1.SecretKeySelector{
+									LocalObjectReference: corev1.LocalObjectReference{Name: name},
+									Key:                  "password",
+								},
+							},
+						}},
+						ReadinessProbe: &corev1.Probe{
+							Handler: corev1.Handler{
+								HTTPGet: &corev1.HTTPGetAction{
+									Path: "/_up",
+									Port: intstr.FromInt(devInstancePort),
+								},
+							},
+						},
+						VolumeMounts: []corev1.VolumeMount{{
+							Name:      "data",
+							MountPath: "/opt/couchdb/data",
+						}},
+					}},
+					Volumes: []corev1.Volume{{
+						Name: "data",
+						VolumeSource: corev1.VolumeSource{
+							EmptyDir: &corev1.EmptyDirVolumeSource{},
+						},
+					}},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	v1 "k8s.io/api/apps/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// MakePodDisruptionBudget generates (but does not insert into K8s) the
+// PodDisruptionBudget of a receive adapter Deployment running several
+// replicas, which lets voluntary disruptions, such as node drains, evict one
+// replica at a time so that a standby remains to take over. A single replica
+// gets no budget, which would block the drains of its node. It returns nil
+// then.
+func MakePodDisruptionBudget(ra *v1.Deployment) *policyv1beta1.PodDisruptionBudget {
+	if ra.Spec.Replicas == nil || *ra.Spec.Replicas < 2 {
+		return nil
+	}
+	maxUnavailable := intstr.FromInt(1)
+	return &policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       ra.Namespace,
+			Name:            ra.Name,
+			Labels:          ra.Labels,
+			OwnerReferences: ra.OwnerReferences,
+		},
+		Spec: policyv1beta1.PodDisruptionBudgetSpec{
+			Selector:       ra.Spec.Selector,
+			MaxUnavailable: &maxUnavailable,
+		},
+	}
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+func TestMakePodDisruptionBudget(t *testing.T) {
+	source := func(spec v1alpha1.CouchDbSourceSpec) *v1alpha1.CouchDbSource {
+		return &v1alpha1.CouchDbSource{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "source-name",
+				Namespace: "source-namespace",
+				UID:       "1234",
+			},
+			Spec: spec,
+		}
+	}
+	testCases := map[string]struct {
+		spec v1alpha1.CouchDbSourceSpec
+		want bool
+	}{
+		"single replica": {},
+		"global ordering": {
+			spec: v1alpha1.CouchDbSourceSpec{Ordering: v1alpha1.OrderingGlobal},
+			want: true,
+		},
+		"high availability": {
+			spec: v1alpha1.CouchDbSourceSpec{HighAvailability: &v1alpha1.HighAvailabilitySpec{Replicas: 3}},
+			want: true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			ra := MakeReceiveAdapter(&ReceiveAdapterArgs{
+				Image:  "test-image",
+				Source: source(tc.spec),
+				Labels: Labels("source-name"),
+			})
+			got := MakePodDisruptionBudget(ra)
+			if (got != nil) != tc.want {
+				t.Fatalf("MakePodDisruptionBudget() = %v, want a budget: %v", got, tc.want)
+			}
+			if got == nil {
+				return
+			}
+			if got.Name != ra.Name || got.Namespace != ra.Namespace {
+				t.Errorf("budget %s/%s, want %s/%s", got.Namespace, got.Name, ra.Namespace, ra.Name)
+			}
+			if len(got.OwnerReferences) != 1 || got.OwnerReferences[0].UID != "1234" {
+				t.Errorf("OwnerReferences = %v, want the source", got.OwnerReferences)
+			}
+			if got.Spec.Selector != ra.Spec.Selector {
+				t.Errorf("selector = %v, want the one of the Deployment", got.Spec.Selector)
+			}
+			if want := intstr.FromInt(1); *got.Spec.MaxUnavailable != want {
+				t.Errorf("maxUnavailable = %v, want %v", got.Spec.MaxUnavailable, want)
+			}
+		})
+	}
+}
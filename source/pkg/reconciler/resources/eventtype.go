@@ -0,0 +1,68 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	eventingv1beta1 "knative.dev/eventing/pkg/apis/eventing/v1beta1"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"knative.dev/pkg/kmeta"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+// EventTypeName returns the name of the EventType of the source for the
+// CloudEvent attributes, stable across reconciliations.
+func EventTypeName(src *v1alpha1.CouchDbSource, attributes duckv1.CloudEventAttributes) string {
+	sum := sha256.Sum256([]byte(attributes.Type + "\x00" + attributes.Source))
+	return kmeta.ChildName(src.Name+"-", hex.EncodeToString(sum[:])[:10])
+}
+
+// MakeEventTypes generates (but does not insert into K8s) the EventTypes
+// registering the CloudEvent attributes of the source with the broker it
+// sends its events to, for the trigger authors to discover them.
+func MakeEventTypes(src *v1alpha1.CouchDbSource, broker string, attributes []duckv1.CloudEventAttributes) ([]*eventingv1beta1.EventType, error) {
+	eventTypes := make([]*eventingv1beta1.EventType, 0, len(attributes))
+	for _, a := range attributes {
+		source, err := apis.ParseURL(a.Source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source %q of the event type %q: %w", a.Source, a.Type, err)
+		}
+		eventTypes = append(eventTypes, &eventingv1beta1.EventType{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: src.Namespace,
+				Name:      EventTypeName(src, a),
+				Labels:    Labels(src.Name),
+				OwnerReferences: []metav1.OwnerReference{
+					*kmeta.NewControllerRef(src),
+				},
+			},
+			Spec: eventingv1beta1.EventTypeSpec{
+				Type:        a.Type,
+				Source:      source,
+				Broker:      broker,
+				Description: fmt.Sprintf("Changes of the CouchDB database %s", src.Spec.Database),
+			},
+		})
+	}
+	return eventTypes, nil
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	eventingv1beta1 "knative.dev/eventing/pkg/apis/eventing/v1beta1"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+func TestMakeEventTypes(t *testing.T) {
+	src := &v1alpha1.CouchDbSource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "source-name",
+			Namespace: "source-namespace",
+			UID:       "1234",
+		},
+		Spec: v1alpha1.CouchDbSourceSpec{Database: "orders"},
+	}
+	attributes := []duckv1.CloudEventAttributes{{
+		Type:   v1alpha1.CouchDbSourceUpdateEventType,
+		Source: "http://couchdb:5984/orders",
+	}, {
+		Type:   v1alpha1.CouchDbSourceDeleteEventType,
+		Source: "http://couchdb:5984/orders",
+	}}
+
+	eventTypes, err := MakeEventTypes(src, "default", attributes)
+	if err != nil {
+		t.Fatal("MakeEventTypes() =", err)
+	}
+	if len(eventTypes) != 2 {
+		t.Fatalf("MakeEventTypes() returned %d event types, want 2", len(eventTypes))
+	}
+	if eventTypes[0].Name == eventTypes[1].Name {
+		t.Errorf("the event types share the name %q", eventTypes[0].Name)
+	}
+	if got := EventTypeName(src, attributes[0]); got != eventTypes[0].Name {
+		t.Errorf("EventTypeName() = %q, want %q", got, eventTypes[0].Name)
+	}
+	for _, et := range eventTypes {
+		if !metav1.IsControlledBy(et, src) {
+			t.Errorf("the event type %s is not controlled by the source", et.Name)
+		}
+	}
+	want := eventingv1beta1.EventTypeSpec{
+		Type:        v1alpha1.CouchDbSourceUpdateEventType,
+		Source:      apis.HTTP("couchdb:5984"),
+		Broker:      "default",
+		Description: "Changes of the CouchDB database orders",
+	}
+	want.Source.Path = "/orders"
+	if diff := cmp.Diff(want, eventTypes[0].Spec); diff != "" {
+		t.Error("Unexpected spec (-want, +got) =", diff)
+	}
+
+	if _, err := MakeEventTypes(src, "default", []duckv1.CloudEventAttributes{{Type: "t", Source: ":invalid"}}); err == nil {
+		t.Error("MakeEventTypes() = nil, want an error for an invalid source")
+	}
+}
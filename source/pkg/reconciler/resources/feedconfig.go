@@ -0,0 +1,112 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"net/url"
+	"path"
+	"strconv"
+
+	"github.com/rickb777/date/period"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+// MakeFeedConfig returns the changes feed request the receive adapter starts
+// with, as reflected in status.feedConfig. The credentials of couchDbURL are left out, and the URL
+// is omitted when couchDbURL is nil. The parameters the server dialects add,
+// e.g. the seq_interval of Cloudant, depend on the banner of the server and
+// are not reported.
+func MakeFeedConfig(args *ReceiveAdapterArgs, couchDbURL *url.URL) *v1alpha1.FeedConfig {
+	spec := &args.Source.Spec
+	params := map[string]string{
+		"feed":  string(spec.Feed),
+		"since": feedSince(args.Source),
+	}
+	if spec.Attachments != "" || len(spec.Projection) > 0 || spec.Match != "" || spec.EventTypeField != "" ||
+		spec.Grouping != nil || spec.Conflicts || spec.Access != nil || spec.Join != nil {
+		params["include_docs"] = "true"
+	}
+	if spec.Conflicts {
+		params["conflicts"] = "true"
+	}
+	if spec.Feed == v1alpha1.FeedContinuous {
+		if spec.Timeout != "" {
+			params["timeout"] = milliseconds(spec.Timeout)
+		} else {
+			heartbeat := strconv.FormatInt(v1alpha1.DefaultHeartbeat.Milliseconds(), 10)
+			if spec.Heartbeat != "" {
+				heartbeat = milliseconds(spec.Heartbeat)
+			} else if args.DefaultHeartbeat != "" {
+				heartbeat = milliseconds(args.DefaultHeartbeat)
+			}
+			params["heartbeat"] = heartbeat
+		}
+	}
+	if spec.SeqInterval > 0 {
+		params["seq_interval"] = strconv.Itoa(int(spec.SeqInterval))
+	}
+	if spec.Limits != nil && spec.Limits.MaxResults > 0 {
+		params["limit"] = strconv.Itoa(int(spec.Limits.MaxResults))
+	}
+	if spec.Attachments == v1alpha1.AttachmentsInline {
+		params["attachments"] = "true"
+	}
+
+	config := &v1alpha1.FeedConfig{Parameters: params}
+	if couchDbURL != nil {
+		u := *couchDbURL
+		u.User = nil
+		u.Path = path.Join("/", u.Path, spec.Database, "_changes")
+		u.RawPath = ""
+		query := url.Values{}
+		for k, v := range params {
+			query.Set(k, v)
+		}
+		u.RawQuery = query.Encode()
+		u.Fragment = ""
+		config.URL = u.String()
+	}
+	return config
+}
+
+// feedSince is the update sequence the feed starts after, as the receive
+// adapter picks it.
+func feedSince(source *v1alpha1.CouchDbSource) string {
+	since := "0"
+	if source.Spec.Since != "" {
+		since = source.Spec.Since
+	}
+	if source.Spec.Window != nil && source.Spec.Window.Since != "" {
+		since = source.Spec.Window.Since
+	}
+	if seq, ok := source.Annotations[v1alpha1.ReplayFromAnnotationKey]; ok {
+		since = seq
+	}
+	return since
+}
+
+// milliseconds converts an ISO-8601 duration to milliseconds, as CouchDB
+// expects them. Invalid durations, rejected by the validation, are returned
+// as is.
+func milliseconds(iso8601 string) string {
+	p, err := period.Parse(iso8601)
+	if err != nil {
+		return iso8601
+	}
+	return strconv.FormatInt(p.DurationApprox().Milliseconds(), 10)
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/kmeta"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+const (
+	// LoggingVolumeName and LoggingMountPath are the volume of the receive
+	// adapter holding its logging configuration, and where it is mounted.
+	LoggingVolumeName = "couchdb-logging"
+	LoggingMountPath  = "/etc/couchdb-logging"
+
+	// AdapterComponent is the component of the receive adapters, whose level
+	// is set by the loglevel.couchdbsource key of config-logging.
+	AdapterComponent = "couchdbsource"
+)
+
+// LoggingConfigMapName is the name of the ConfigMap holding the logging
+// configuration of the receive adapter of a CouchDbSource.
+func LoggingConfigMapName(src *v1alpha1.CouchDbSource) string {
+	return kmeta.ChildName(fmt.Sprintf("couchdbsource-%s-logging-", src.Name), string(src.UID))
+}
+
+// AdapterLoggingConfig returns the keys of config-logging applying to the
+// receive adapters: the zap configuration and the levels of the adapter
+// components, e.g. loglevel.couchdbsource.
+func AdapterLoggingConfig(data map[string]string) map[string]string {
+	config := map[string]string{}
+	for k, v := range data {
+		if k == "zap-logger-config" || strings.HasPrefix(k, "loglevel."+AdapterComponent) {
+			config[k] = v
+		}
+	}
+	return config
+}
+
+// MakeLoggingConfigMap generates (but does not insert into K8s) the ConfigMap
+// holding the logging configuration of the receive adapter of the source,
+// copied from config-logging, which lives in the namespace of the controller.
+func MakeLoggingConfigMap(src *v1alpha1.CouchDbSource, data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: src.Namespace,
+			Name:      LoggingConfigMapName(src),
+			Labels:    Labels(src.Name),
+			OwnerReferences: []metav1.OwnerReference{
+				*kmeta.NewControllerRef(src),
+			},
+		},
+		Data: data,
+	}
+}
+
+// addLogging mounts the logging configuration of the source in the pod of
+// the receive adapter. The kubelet refreshes it as the ConfigMap changes,
+// without restarting the pod.
+func addLogging(template *corev1.PodTemplateSpec, src *v1alpha1.CouchDbSource) {
+	optional := true
+	spec := &template.Spec
+	spec.Volumes = append(spec.Volumes, corev1.Volume{
+		Name: LoggingVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: LoggingConfigMapName(src)},
+				Optional:             &optional,
+			},
+		},
+	})
+	c := &spec.Containers[0]
+	c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{
+		Name:      LoggingVolumeName,
+		MountPath: LoggingMountPath,
+		ReadOnly:  true,
+	})
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+// MakePlan returns the human-readable plan of the changes turning the current
+// receive adapter Deployment into the expected one, one change per line:
+// "+" for an added setting, "-" for a removed one and "~" for a changed one.
+// It is empty when there is no change.
+func MakePlan(current, expected *v1.Deployment) string {
+	var plan []string
+	if r1, r2 := replicas(current), replicas(expected); r1 != r2 {
+		plan = append(plan, fmt.Sprintf("~ replicas: %d -> %d", r1, r2))
+	}
+	if StrategyChanged(current, expected) {
+		plan = append(plan, fmt.Sprintf("~ strategy: %s -> %s", current.Spec.Strategy.Type, expected.Spec.Strategy.Type))
+	}
+	p1, p2 := current.Spec.Template.Spec, expected.Spec.Template.Spec
+	if p1.ServiceAccountName != p2.ServiceAccountName {
+		plan = append(plan, fmt.Sprintf("~ serviceAccountName: %q -> %q", p1.ServiceAccountName, p2.ServiceAccountName))
+	}
+
+	var c1, c2 corev1.Container
+	if len(p1.Containers) > 0 {
+		c1 = p1.Containers[0]
+	}
+	if len(p2.Containers) > 0 {
+		c2 = p2.Containers[0]
+	}
+	if c1.Image != c2.Image {
+		plan = append(plan, fmt.Sprintf("~ image: %q -> %q", c1.Image, c2.Image))
+	}
+	plan = append(plan, envPlan(c1.Env, c2.Env)...)
+	if TemplateMetadataChanged(current, expected) {
+		plan = append(plan, "~ pod labels and annotations")
+	}
+
+	if len(plan) == 0 && !equality.Semantic.DeepDerivative(p2, p1) {
+		plan = append(plan, "~ pod template")
+	}
+	return strings.Join(plan, "\n")
+}
+
+// PlanID identifies the plan of the changes made to the Deployment of the
+// given generation, so that an approval does not carry over to the same
+// changes made again later.
+func PlanID(generation int64, plan string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d\n%s", generation, plan)))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+func replicas(d *v1.Deployment) int32 {
+	if d.Spec.Replicas == nil {
+		return 1
+	}
+	return *d.Spec.Replicas
+}
+
+// envPlan returns the changes of the environment, in the order of the
+// expected variables followed by the removed ones.
+func envPlan(current, expected []corev1.EnvVar) []string {
+	byName := make(map[string]corev1.EnvVar, len(current))
+	for _, e := range current {
+		byName[e.Name] = e
+	}
+	var plan []string
+	for _, e := range expected {
+		old, ok := byName[e.Name]
+		delete(byName, e.Name)
+		switch {
+		case !ok:
+			plan = append(plan, fmt.Sprintf("+ env %s = %s", e.Name, envValue(e)))
+		case !equality.Semantic.DeepEqual(old, e):
+			plan = append(plan, fmt.Sprintf("~ env %s: %s -> %s", e.Name, envValue(old), envValue(e)))
+		}
+	}
+	for _, e := range current {
+		if _, ok := byName[e.Name]; ok {
+			plan = append(plan, fmt.Sprintf("- env %s = %s", e.Name, envValue(e)))
+		}
+	}
+	return plan
+}
+
+func envValue(e corev1.EnvVar) string {
+	if e.ValueFrom != nil {
+		return "(from reference)"
+	}
+	return fmt.Sprintf("%q", e.Value)
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+func TestMakePlan(t *testing.T) {
+	source := func(spec v1alpha1.CouchDbSourceSpec) *v1.Deployment {
+		return MakeReceiveAdapter(&ReceiveAdapterArgs{
+			Image: "test-image",
+			Source: &v1alpha1.CouchDbSource{
+				ObjectMeta: metav1.ObjectMeta{Name: "source-name", Namespace: "source-namespace", UID: "1234"},
+				Spec:       spec,
+			},
+			Labels:  Labels("source-name"),
+			SinkURI: "sink-uri",
+		})
+	}
+	current := source(v1alpha1.CouchDbSourceSpec{Ordering: v1alpha1.OrderingOrdered})
+
+	testCases := map[string]struct {
+		expected *v1.Deployment
+		want     string
+	}{
+		"unchanged": {
+			expected: source(v1alpha1.CouchDbSourceSpec{Ordering: v1alpha1.OrderingOrdered}),
+		},
+		"env": {
+			expected: source(v1alpha1.CouchDbSourceSpec{Payload: v1alpha1.PayloadDiff}),
+			want: `+ env COUCHDB_PAYLOAD = "diff"
+- env COUCHDB_ORDERING = "ordered"`,
+		},
+		"global ordering": {
+			expected: source(v1alpha1.CouchDbSourceSpec{Ordering: v1alpha1.OrderingGlobal}),
+			want: `~ replicas: 1 -> 2
+~ env COUCHDB_ORDERING: "ordered" -> "global"
++ env COUCHDB_LEASE_NAME = "couchdbsource-source-name-1234"`,
+		},
+		"image and service account": {
+			expected: func() *v1.Deployment {
+				d := source(v1alpha1.CouchDbSourceSpec{Ordering: v1alpha1.OrderingOrdered, ServiceAccountName: "adapter"})
+				d.Spec.Template.Spec.Containers[0].Image = "patched-image"
+				return d
+			}(),
+			want: `~ serviceAccountName: "" -> "adapter"
+~ image: "test-image" -> "patched-image"`,
+		},
+		"other changes": {
+			expected: func() *v1.Deployment {
+				d := source(v1alpha1.CouchDbSourceSpec{Ordering: v1alpha1.OrderingOrdered})
+				d.Spec.Template.Spec.Containers[0].Resources.Limits = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}
+				return d
+			}(),
+			want: "~ pod template",
+		},
+		"pod annotations": {
+			expected: source(v1alpha1.CouchDbSourceSpec{
+				Ordering: v1alpha1.OrderingOrdered,
+				Template: &v1alpha1.AdapterTemplateSpec{
+					Metadata: &v1alpha1.AdapterTemplateMetadata{Annotations: map[string]string{"sidecar.istio.io/inject": "false"}},
+				},
+			}),
+			want: "~ pod labels and annotations",
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, MakePlan(current, tc.expected)); diff != "" {
+				t.Errorf("unexpected plan (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
+
+func TestPlanID(t *testing.T) {
+	id := PlanID(3, "~ replicas: 1 -> 2")
+	if len(id) != 12 {
+		t.Errorf("PlanID() = %q, want 12 characters", id)
+	}
+	if PlanID(3, "~ replicas: 1 -> 2") != id {
+		t.Error("PlanID() is not stable")
+	}
+	if PlanID(4, "~ replicas: 1 -> 2") == id {
+		t.Error("PlanID() is the same for another generation of the Deployment")
+	}
+	if PlanID(3, "~ replicas: 2 -> 1") == id {
+		t.Error("PlanID() is the same for another plan")
+	}
+}
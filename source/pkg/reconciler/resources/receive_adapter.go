@@ -17,14 +17,41 @@ limitations under the License.
 package resources
 
 import (
+	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 
 	v1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
 	"knative.dev/pkg/kmeta"
 
 	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-couchdb/source/pkg/reconciler/identity"
+)
+
+// AdapterStatusPort is the port the receive adapter serves its status on,
+// when the controller needs it.
+const AdapterStatusPort = 8080
+
+// AdapterHealthPort is the port the receive adapter serves its liveness and
+// readiness endpoints on.
+const AdapterHealthPort = 8081
+
+// The parts of the receive adapter Deployment the controller reads back to
+// report the lag of a source scaled by KEDA: the volume of the credentials,
+// and the environment variables of the database and of the local document
+// holding the checkpoint.
+const (
+	CredentialsVolumeName = "couchdb-credentials"
+	DatabaseEnv           = "COUCHDB_DATABASE"
+	CheckpointDocEnv      = "COUCHDB_CHECKPOINT_DOC"
 )
 
 // ReceiveAdapterArgs are the arguments needed to create a CouchDB Receive Adapter.
@@ -35,79 +62,325 @@ type ReceiveAdapterArgs struct {
 	Source      *v1alpha1.CouchDbSource
 	Labels      map[string]string
 	SinkURI     string
+
+	// DeadLetterSinkURI is the resolved spec.delivery.deadLetterSink, if any.
+	// +optional
+	DeadLetterSinkURI string
+
+	// ReplyURI is the resolved spec.reply, if any.
+	// +optional
+	ReplyURI string
+
+	// SinkAudience is the OIDC audience of the sink, if any.
+	// +optional
+	SinkAudience string
+
+	// SinkCACerts are the PEM certificates the sink is verified with, if any.
+	// +optional
+	SinkCACerts string
+
+	// DefaultHeartbeat is the heartbeat of continuous feeds that configure
+	// neither a heartbeat nor a timeout, tuned to the idle timeout of the
+	// load balancers in front of CouchDB.
+	// +optional
+	DefaultHeartbeat string
+
+	// DefaultReconnect holds the reconnect backoff bounds of the sources
+	// whose spec.reconnect leaves them unset.
+	// +optional
+	DefaultReconnect v1alpha1.ReconnectSpec
+
+	// ClusterID is stamped on every event in the couchdbcluster extension,
+	// when set.
+	// +optional
+	ClusterID string
+
+	// TracingConfig is the JSON of the tracing configuration of the receive
+	// adapter, when tracing is enabled.
+	// +optional
+	TracingConfig string
+
+	// RuntimeOverrides replace the GOMAXPROCS and GOMEMLIMIT values derived
+	// from the adapter's resource limits.
+	// +optional
+	RuntimeOverrides map[string]string
 }
 
 // MakeReceiveAdapter generates (but does not insert into K8s) the Receive Adapter Deployment for
 // CouchDB sources.
 func MakeReceiveAdapter(args *ReceiveAdapterArgs) *v1.Deployment {
-	replicas := int32(1)
+	// The standbys take over the lease when the active replica fails.
+	replicas := args.Source.Spec.AdapterReplicas()
+	template := makePodTemplate(args)
+	if replicas > 1 && (template.Spec.Affinity == nil || template.Spec.Affinity.PodAntiAffinity == nil) {
+		if template.Spec.Affinity == nil {
+			template.Spec.Affinity = &corev1.Affinity{}
+		}
+		template.Spec.Affinity.PodAntiAffinity = makeAntiAffinity(args)
+	}
 	return &v1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: args.Source.Namespace,
-			Name:      kmeta.ChildName(fmt.Sprintf("couchdbsource-%s-", args.Source.Name), string(args.Source.UID)),
-			Labels:    args.Labels,
-			OwnerReferences: []metav1.OwnerReference{
-				*kmeta.NewControllerRef(args.Source),
-			},
-		},
+		ObjectMeta: makeObjectMeta(args),
 		Spec: v1.DeploymentSpec{
 			Selector: &metav1.LabelSelector{
 				MatchLabels: args.Labels,
 			},
 			Replicas: &replicas,
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
+			Strategy: deploymentStrategy(args.Source),
+			Template: template,
+		},
+	}
+}
 
-					Labels: args.Labels,
+// makeAntiAffinity spreads the replicas of the receive adapter across nodes
+// where possible, so that a node failure leaves a standby to take over.
+func makeAntiAffinity(args *ReceiveAdapterArgs) *corev1.PodAntiAffinity {
+	return &corev1.PodAntiAffinity{
+		PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{{
+			Weight: 100,
+			PodAffinityTerm: corev1.PodAffinityTerm{
+				LabelSelector: &metav1.LabelSelector{
+					MatchLabels: args.Labels,
 				},
-				Spec: corev1.PodSpec{
-					ServiceAccountName: args.Source.Spec.ServiceAccountName,
-					Containers: []corev1.Container{
+				TopologyKey: corev1.LabelHostname,
+			},
+		}},
+	}
+}
+
+// MakeReceiveAdapterJob generates (but does not insert into K8s) the Receive Adapter Job for
+// CouchDB sources bounded by a window. The adapter exits once the window is exhausted.
+func MakeReceiveAdapterJob(args *ReceiveAdapterArgs) *batchv1.Job {
+	template := makePodTemplate(args)
+	template.Spec.RestartPolicy = corev1.RestartPolicyOnFailure
+	return &batchv1.Job{
+		ObjectMeta: makeObjectMeta(args),
+		Spec: batchv1.JobSpec{
+			Template: template,
+		},
+	}
+}
+
+func makeObjectMeta(args *ReceiveAdapterArgs) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: args.Source.Namespace,
+		Name:      kmeta.ChildName(fmt.Sprintf("couchdbsource-%s-", args.Source.Name), string(args.Source.UID)),
+		Labels:    args.Labels,
+		OwnerReferences: []metav1.OwnerReference{
+			*kmeta.NewControllerRef(args.Source),
+		},
+	}
+}
+
+func makePodTemplate(args *ReceiveAdapterArgs) corev1.PodTemplateSpec {
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+
+			Labels: args.Labels,
+		},
+		Spec: corev1.PodSpec{
+			ServiceAccountName: args.Source.Spec.ServiceAccountName,
+			Containers: []corev1.Container{
+				{
+					Name:           "receive-adapter",
+					Image:          args.Image,
+					Env:            makeEnv(args),
+					Ports:          makePorts(args),
+					LivenessProbe:  makeProbe("/healthz"),
+					ReadinessProbe: makeProbe("/readyz"),
+					VolumeMounts: []corev1.VolumeMount{
 						{
-							Name:  "receive-adapter",
-							Image: args.Image,
-							Env:   makeEnv(args.EventSource, args.SinkURI, &args.Source.Spec),
-							VolumeMounts: []corev1.VolumeMount{
-								{
-									Name:      "couchdb-credentials",
-									MountPath: "/etc/couchdb-credentials",
-									ReadOnly:  true,
-								},
-							},
+							Name:      CredentialsVolumeName,
+							MountPath: "/etc/couchdb-credentials",
+							ReadOnly:  true,
 						},
 					},
-					Volumes: []corev1.Volume{
-						{
-							Name: "couchdb-credentials",
-							VolumeSource: corev1.VolumeSource{
-								Secret: &corev1.SecretVolumeSource{
-									SecretName: args.Source.Spec.CouchDbCredentials.Name,
-								},
-							},
+				},
+			},
+			Volumes: []corev1.Volume{
+				{
+					Name: CredentialsVolumeName,
+					VolumeSource: corev1.VolumeSource{
+						Secret: &corev1.SecretVolumeSource{
+							SecretName: args.Source.Spec.CouchDbCredentials.Name,
 						},
 					},
 				},
 			},
 		},
 	}
+	addLogging(&template, args.Source)
+	addBuffer(&template, args.Source)
+	addSinkToken(&template, args.SinkAudience)
+	applyTemplate(&template, args.Source.Spec.Template)
+	return template
+}
+
+// applyTemplate applies spec.template to the pod of the receive adapter. The
+// labels selecting the pods take precedence over the ones of the template, and
+// its environment variables come after the ones configuring the adapter, which
+// the webhook keeps from being overridden, or replace the Go runtime settings.
+func applyTemplate(template *corev1.PodTemplateSpec, ts *v1alpha1.AdapterTemplateSpec) {
+	if ts == nil {
+		return
+	}
+	if ts.Metadata != nil {
+		labels := make(map[string]string, len(ts.Metadata.Labels)+len(template.Labels))
+		for k, v := range ts.Metadata.Labels {
+			labels[k] = v
+		}
+		for k, v := range template.Labels {
+			labels[k] = v
+		}
+		template.Labels = labels
+		if len(ts.Metadata.Annotations) > 0 {
+			template.Annotations = make(map[string]string, len(ts.Metadata.Annotations))
+			for k, v := range ts.Metadata.Annotations {
+				template.Annotations[k] = v
+			}
+		}
+	}
+
+	spec := &template.Spec
+	spec.NodeSelector = ts.NodeSelector
+	spec.Tolerations = ts.Tolerations
+	spec.Affinity = ts.Affinity.DeepCopy()
+
+	c := &spec.Containers[0]
+	if ts.Resources != nil {
+		c.Resources = *ts.Resources.DeepCopy()
+	}
+	for _, e := range ts.Env {
+		replaced := false
+		for i := range c.Env {
+			if c.Env[i].Name == e.Name {
+				c.Env[i] = e
+				replaced = true
+			}
+		}
+		if !replaced {
+			c.Env = append(c.Env, e)
+		}
+	}
+}
+
+// TemplateMetadataChanged is whether the labels or annotations of the pod
+// template of the receive adapter changed. The annotations added by others,
+// e.g. by kubectl rollout restart, are not changes.
+func TemplateMetadataChanged(current, expected *v1.Deployment) bool {
+	t1, t2 := current.Spec.Template.ObjectMeta, expected.Spec.Template.ObjectMeta
+	return !equality.Semantic.DeepEqual(t1.Labels, t2.Labels) ||
+		!equality.Semantic.DeepDerivative(t2.Annotations, t1.Annotations)
+}
+
+// UpdateTemplateMetadata sets the labels and annotations of the pod template
+// of the current receive adapter to the expected ones, keeping the
+// annotations added by others.
+func UpdateTemplateMetadata(current, expected *v1.Deployment) {
+	current.Spec.Template.Labels = expected.Spec.Template.Labels
+	if len(expected.Spec.Template.Annotations) == 0 {
+		return
+	}
+	if current.Spec.Template.Annotations == nil {
+		current.Spec.Template.Annotations = make(map[string]string, len(expected.Spec.Template.Annotations))
+	}
+	for k, v := range expected.Spec.Template.Annotations {
+		current.Spec.Template.Annotations[k] = v
+	}
+}
+
+func makePorts(args *ReceiveAdapterArgs) []corev1.ContainerPort {
+	ports := []corev1.ContainerPort{{
+		Name:          "health",
+		ContainerPort: AdapterHealthPort,
+	}}
+	if args.Source.Spec.ServesStatus() {
+		ports = append(ports, corev1.ContainerPort{
+			Name:          "status",
+			ContainerPort: AdapterStatusPort,
+		})
+	}
+	return ports
+}
+
+// makeProbe returns a probe of the given health endpoint of the receive
+// adapter. The adapter turns unhealthy once its changes feed has failed for
+// spec.liveness.disconnectedThreshold, so the probe itself is lenient.
+func makeProbe(path string) *corev1.Probe {
+	return &corev1.Probe{
+		Handler: corev1.Handler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path: path,
+				Port: intstr.FromInt(AdapterHealthPort),
+			},
+		},
+		PeriodSeconds:    10,
+		FailureThreshold: 3,
+	}
+}
+
+// makeCloudEventOverrides returns spec.ceOverrides, along with the cluster
+// identity extension.
+func makeCloudEventOverrides(args *ReceiveAdapterArgs) *duckv1.CloudEventOverrides {
+	overrides := args.Source.Spec.CloudEventOverrides
+	if args.ClusterID == "" {
+		return overrides
+	}
+	extensions := map[string]string{}
+	if overrides != nil {
+		for k, v := range overrides.Extensions {
+			extensions[k] = v
+		}
+	}
+	// The cluster identity wins over the one of the source, so that events
+	// cannot claim another origin.
+	extensions[identity.Extension] = args.ClusterID
+	return &duckv1.CloudEventOverrides{Extensions: extensions}
+}
+
+// backfillBookmarkID is the local document of the database in which the
+// receive adapter saves the progress of the backfill. It is named after the
+// UID of the source, so that a source created again backfills from scratch.
+func backfillBookmarkID(source *v1alpha1.CouchDbSource) string {
+	return "_local/knative-backfill-" + string(source.UID)
+}
+
+// checkpointDocID is the local document the receive adapter of a source scaled
+// by KEDA saves its checkpoint in.
+func checkpointDocID(source *v1alpha1.CouchDbSource) string {
+	return "_local/knative-checkpoint-" + string(source.UID)
 }
 
-func makeEnv(eventSource, sinkURI string, spec *v1alpha1.CouchDbSourceSpec) []corev1.EnvVar {
-	return []corev1.EnvVar{{
+func makeEnv(args *ReceiveAdapterArgs) []corev1.EnvVar {
+	spec := &args.Source.Spec
+	env := []corev1.EnvVar{{
 		Name:  "K_SINK",
-		Value: sinkURI,
+		Value: args.SinkURI,
 	}, {
 		Name:  "EVENT_SOURCE",
-		Value: eventSource,
+		Value: args.EventSource,
 	}, {
 		Name:  "COUCHDB_CREDENTIALS",
 		Value: "/etc/couchdb-credentials",
 	}, {
-		Name:  "COUCHDB_DATABASE",
+		Name:  "COUCHDB_LOGGING_DIR",
+		Value: LoggingMountPath,
+	}, {
+		Name:  "COUCHDB_HEALTH_PORT",
+		Value: strconv.Itoa(AdapterHealthPort),
+	}, {
+		Name:  DatabaseEnv,
 		Value: spec.Database,
 	}, {
 		Name:  "COUCHDB_FEED",
 		Value: string(spec.Feed),
+	}, {
+		Name:  "NAME",
+		Value: args.Source.Name,
+	}, {
+		// With NAME and NAMESPACE, identifies the source the Kubernetes
+		// Events are recorded on.
+		Name:  "COUCHDB_SOURCE_UID",
+		Value: string(args.Source.UID),
 	}, {
 		Name: "NAMESPACE",
 		ValueFrom: &corev1.EnvVarSource{
@@ -125,4 +398,426 @@ func makeEnv(eventSource, sinkURI string, spec *v1alpha1.CouchDbSourceSpec) []co
 		Name:  "K_LOGGING_CONFIG",
 		Value: "",
 	}}
+	var resources *corev1.ResourceRequirements
+	if spec.Template != nil {
+		resources = spec.Template.Resources
+	}
+	env = append(env, MakeRuntimeEnv("receive-adapter", resources, args.RuntimeOverrides)...)
+
+	if spec.Sharded() {
+		env = append(env, makeShardEnv(spec)...)
+	}
+	if spec.Auth != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_AUTH",
+			Value: string(spec.Auth),
+		})
+	}
+	if spec.DevInstance {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_CREATE_DATABASE",
+			Value: "true",
+		})
+	}
+	if spec.Proxy != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_PROXY_URL",
+			Value: spec.Proxy.URL,
+		}, corev1.EnvVar{
+			Name:  "COUCHDB_NO_PROXY",
+			Value: strings.Join(spec.Proxy.NoProxy, ","),
+		})
+	}
+	if ceOverrides := makeCloudEventOverrides(args); ceOverrides != nil {
+		// Applied to every outbound event by the adapter framework.
+		ceOverrides, _ := json.Marshal(ceOverrides)
+		env = append(env, corev1.EnvVar{
+			Name:  "K_CE_OVERRIDES",
+			Value: string(ceOverrides),
+		})
+	}
+	if spec.EventTypeTemplate != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_EVENT_TYPE_TEMPLATE",
+			Value: spec.EventTypeTemplate,
+		})
+	}
+	if spec.EventTypeField != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_EVENT_TYPE_FIELD",
+			Value: spec.EventTypeField,
+		})
+	}
+	if spec.SubjectTemplate != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_SUBJECT_TEMPLATE",
+			Value: spec.SubjectTemplate,
+		})
+	}
+	if spec.IDTemplate != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_ID_TEMPLATE",
+			Value: spec.IDTemplate,
+		})
+	}
+	if spec.DeletedDocs != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_DELETED_DOCS",
+			Value: string(spec.DeletedDocs),
+		})
+	}
+	if spec.DesignDocs != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_DESIGN_DOCS",
+			Value: string(spec.DesignDocs),
+		})
+	}
+	if spec.OnDecodeError != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_ON_DECODE_ERROR",
+			Value: string(spec.OnDecodeError),
+		})
+	}
+	if spec.Conflicts {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_CONFLICTS",
+			Value: "true",
+		})
+	}
+	if spec.DatabaseUpdates {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_DATABASE_UPDATES",
+			Value: "true",
+		})
+	}
+	if spec.ContentMode != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_CONTENT_MODE",
+			Value: string(spec.ContentMode),
+		})
+	}
+	if spec.Ordering != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_ORDERING",
+			Value: string(spec.Ordering),
+		})
+	}
+	if spec.Leased() {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_LEASE_NAME",
+			Value: makeObjectMeta(args).Name,
+		})
+	}
+	if spec.Batch != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_BATCH",
+			Value: "true",
+		})
+		if spec.Batch.MaxCount > 0 {
+			env = append(env, corev1.EnvVar{
+				Name:  "COUCHDB_BATCH_MAX_COUNT",
+				Value: strconv.Itoa(int(spec.Batch.MaxCount)),
+			})
+		}
+		if spec.Batch.MaxWait != "" {
+			env = append(env, corev1.EnvVar{
+				Name:  "COUCHDB_BATCH_MAX_WAIT",
+				Value: spec.Batch.MaxWait,
+			})
+		}
+	}
+	if spec.Limits != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_MAX_LINE_BYTES",
+			Value: strconv.FormatInt(spec.Limits.MaxLineBytes, 10),
+		}, corev1.EnvVar{
+			Name:  "COUCHDB_MAX_JSON_DEPTH",
+			Value: strconv.Itoa(int(spec.Limits.MaxJSONDepth)),
+		}, corev1.EnvVar{
+			Name:  "COUCHDB_MAX_RESULTS",
+			Value: strconv.Itoa(int(spec.Limits.MaxResults)),
+		})
+	}
+	if spec.Backfill {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_BACKFILL",
+			Value: "true",
+		}, corev1.EnvVar{
+			Name:  "COUCHDB_BACKFILL_BOOKMARK",
+			Value: backfillBookmarkID(args.Source),
+		})
+	}
+	if args.Source.KedaAutoscaled() {
+		env = append(env, corev1.EnvVar{
+			Name:  CheckpointDocEnv,
+			Value: checkpointDocID(args.Source),
+		})
+	}
+	if spec.HealthMetrics != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_HEALTH_METRICS_INTERVAL",
+			Value: spec.HealthMetrics.ScrapeInterval().String(),
+		})
+	}
+	if spec.LagMetrics != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_LAG_METRICS_INTERVAL",
+			Value: spec.LagMetrics.PollInterval().String(),
+		})
+	}
+	if spec.Liveness != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_DISCONNECTED_THRESHOLD",
+			Value: spec.Liveness.Threshold().String(),
+		})
+	}
+	if spec.Stats != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_STATS",
+			Value: "true",
+		})
+	}
+	if spec.ServesStatus() {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_STATUS_PORT",
+			Value: strconv.Itoa(AdapterStatusPort),
+		})
+	}
+	if spec.Attachments != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_ATTACHMENTS",
+			Value: string(spec.Attachments),
+		})
+	}
+	if spec.Payload != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_PAYLOAD",
+			Value: string(spec.Payload),
+		})
+	}
+	if spec.Match != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_MATCH",
+			Value: spec.Match,
+		})
+	}
+	if len(spec.Projection) > 0 {
+		// JSONPath expressions hold the separators of envconfig maps.
+		projection, _ := json.Marshal(spec.Projection)
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_PROJECTION",
+			Value: string(projection),
+		})
+	}
+	heartbeat := spec.Heartbeat
+	if heartbeat == "" && spec.Timeout == "" && spec.Feed == v1alpha1.FeedContinuous {
+		heartbeat = args.DefaultHeartbeat
+	}
+	if heartbeat != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_HEARTBEAT",
+			Value: heartbeat,
+		})
+	}
+	if spec.RateLimit != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_RATE_LIMIT",
+			Value: strconv.Itoa(int(spec.RateLimit.EventsPerSecond)),
+		})
+		if spec.RateLimit.Burst > 0 {
+			env = append(env, corev1.EnvVar{
+				Name:  "COUCHDB_RATE_LIMIT_BURST",
+				Value: strconv.Itoa(int(spec.RateLimit.Burst)),
+			})
+		}
+	}
+	if spec.Polling != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_POLLING",
+			Value: "true",
+		})
+		if spec.Polling.MinInterval != "" {
+			env = append(env, corev1.EnvVar{
+				Name:  "COUCHDB_POLL_MIN_INTERVAL",
+				Value: spec.Polling.MinInterval,
+			})
+		}
+		if spec.Polling.MaxInterval != "" {
+			env = append(env, corev1.EnvVar{
+				Name:  "COUCHDB_POLL_MAX_INTERVAL",
+				Value: spec.Polling.MaxInterval,
+			})
+		}
+	}
+	reconnect := args.DefaultReconnect
+	if spec.Reconnect != nil {
+		if spec.Reconnect.InitialDelay != "" {
+			reconnect.InitialDelay = spec.Reconnect.InitialDelay
+		}
+		if spec.Reconnect.MaxDelay != "" {
+			reconnect.MaxDelay = spec.Reconnect.MaxDelay
+		}
+	}
+	if reconnect.InitialDelay != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_RECONNECT_INITIAL_DELAY",
+			Value: reconnect.InitialDelay,
+		})
+	}
+	if reconnect.MaxDelay != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_RECONNECT_MAX_DELAY",
+			Value: reconnect.MaxDelay,
+		})
+	}
+	if spec.Timeout != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_TIMEOUT",
+			Value: spec.Timeout,
+		})
+	}
+	if spec.Since != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_SINCE",
+			Value: spec.Since,
+		})
+	}
+	if spec.SeqInterval > 0 {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_SEQ_INTERVAL",
+			Value: strconv.Itoa(int(spec.SeqInterval)),
+		})
+	}
+	if seq, ok := args.Source.Annotations[v1alpha1.ReplayFromAnnotationKey]; ok {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_REPLAY_FROM",
+			Value: seq,
+		})
+	}
+	if port, ok := args.Source.Annotations[v1alpha1.PprofPortAnnotationKey]; ok {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_PPROF_PORT",
+			Value: port,
+		})
+	}
+	if spec.Window != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_WINDOW_SINCE",
+			Value: spec.Window.Since,
+		}, corev1.EnvVar{
+			Name:  "COUCHDB_WINDOW_UNTIL",
+			Value: spec.Window.Until,
+		})
+	}
+	if len(spec.Partitions) > 0 {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_PARTITIONS",
+			Value: strings.Join(spec.Partitions, ","),
+		})
+	}
+	if spec.Grouping != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_GROUP_FIELD",
+			Value: spec.Grouping.Field,
+		}, corev1.EnvVar{
+			Name:  "COUCHDB_GROUP_DELAY",
+			Value: spec.Grouping.Delay,
+		}, corev1.EnvVar{
+			Name:  "COUCHDB_GROUP_BATCH",
+			Value: strconv.FormatBool(spec.Grouping.Batch),
+		})
+	}
+	if spec.Access != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_ACCESS_ROLES",
+			Value: strings.Join(spec.Access.Roles, ","),
+		}, corev1.EnvVar{
+			Name:  "COUCHDB_ACCESS_FIELD",
+			Value: spec.Access.Field,
+		})
+	}
+	if spec.Join != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_JOIN_DATABASE",
+			Value: spec.Join.Database,
+		}, corev1.EnvVar{
+			Name:  "COUCHDB_JOIN_KEY",
+			Value: spec.Join.Key,
+		}, corev1.EnvVar{
+			Name:  "COUCHDB_JOIN_AS",
+			Value: spec.Join.Field(),
+		}, corev1.EnvVar{
+			Name:  "COUCHDB_JOIN_CACHE_SIZE",
+			Value: strconv.Itoa(int(spec.Join.CacheSize)),
+		}, corev1.EnvVar{
+			Name:  "COUCHDB_JOIN_MAX_AGE",
+			Value: spec.Join.MaxAge,
+		}, corev1.EnvVar{
+			Name:  "COUCHDB_JOIN_ON_MISSING",
+			Value: string(spec.Join.OnMissing),
+		})
+	}
+	if spec.Buffer != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_BUFFER_DIR",
+			Value: BufferMountPath,
+		}, corev1.EnvVar{
+			Name:  "COUCHDB_BUFFER_MAX_BYTES",
+			Value: strconv.FormatInt(spec.Buffer.MaxBytes(), 10),
+		}, corev1.EnvVar{
+			Name:  "COUCHDB_BUFFER_ON_FULL",
+			Value: string(spec.Buffer.OnFull),
+		})
+	}
+	if args.ReplyURI != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_REPLY_SINK",
+			Value: args.ReplyURI,
+		})
+	}
+	if args.SinkCACerts != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "COUCHDB_SINK_CA_CERTS",
+			Value: args.SinkCACerts,
+		})
+	}
+	if args.TracingConfig != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "K_TRACING_CONFIG",
+			Value: args.TracingConfig,
+		})
+	}
+	if spec.Delivery != nil {
+		env = append(env, makeDeliveryEnv(spec.Delivery, args.DeadLetterSinkURI)...)
+	}
+	return env
+}
+
+func makeDeliveryEnv(delivery *eventingduckv1.DeliverySpec, deadLetterSinkURI string) []corev1.EnvVar {
+	var env []corev1.EnvVar
+	if delivery.Retry != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  "DELIVERY_RETRY",
+			Value: strconv.Itoa(int(*delivery.Retry)),
+		})
+	}
+	if delivery.BackoffPolicy != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  "DELIVERY_BACKOFF_POLICY",
+			Value: string(*delivery.BackoffPolicy),
+		})
+	}
+	if delivery.BackoffDelay != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  "DELIVERY_BACKOFF_DELAY",
+			Value: *delivery.BackoffDelay,
+		})
+	}
+	if deadLetterSinkURI != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "DELIVERY_DEAD_LETTER_SINK",
+			Value: deadLetterSinkURI,
+		})
+	}
+	return env
 }
@@ -23,8 +23,13 @@ import (
 	"github.com/google/go-cmp/cmp"
 	v1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
 	_ "knative.dev/pkg/metrics/testing"
 )
 
@@ -83,6 +88,7 @@ func TestMakeReceiveAdapter(t *testing.T) {
 				},
 			},
 			Replicas: &one,
+			Strategy: v1.DeploymentStrategy{Type: v1.RollingUpdateDeploymentStrategyType},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: map[string]string{
@@ -96,6 +102,30 @@ func TestMakeReceiveAdapter(t *testing.T) {
 						{
 							Name:  "receive-adapter",
 							Image: "test-image",
+							Ports: []corev1.ContainerPort{{
+								Name:          "health",
+								ContainerPort: 8081,
+							}},
+							LivenessProbe: &corev1.Probe{
+								Handler: corev1.Handler{
+									HTTPGet: &corev1.HTTPGetAction{
+										Path: "/healthz",
+										Port: intstr.FromInt(8081),
+									},
+								},
+								PeriodSeconds:    10,
+								FailureThreshold: 3,
+							},
+							ReadinessProbe: &corev1.Probe{
+								Handler: corev1.Handler{
+									HTTPGet: &corev1.HTTPGetAction{
+										Path: "/readyz",
+										Port: intstr.FromInt(8081),
+									},
+								},
+								PeriodSeconds:    10,
+								FailureThreshold: 3,
+							},
 							Env: []corev1.EnvVar{
 								{
 									Name:  "K_SINK",
@@ -106,12 +136,24 @@ func TestMakeReceiveAdapter(t *testing.T) {
 								}, {
 									Name:  "COUCHDB_CREDENTIALS",
 									Value: "/etc/couchdb-credentials",
+								}, {
+									Name:  "COUCHDB_LOGGING_DIR",
+									Value: "/etc/couchdb-logging",
+								}, {
+									Name:  "COUCHDB_HEALTH_PORT",
+									Value: "8081",
 								}, {
 									Name:  "COUCHDB_DATABASE",
 									Value: "mydb",
 								}, {
 									Name:  "COUCHDB_FEED",
 									Value: "continuous",
+								}, {
+									Name:  "NAME",
+									Value: name,
+								}, {
+									Name:  "COUCHDB_SOURCE_UID",
+									Value: "1234",
 								}, {
 									Name: "NAMESPACE",
 									ValueFrom: &corev1.EnvVarSource{
@@ -129,6 +171,15 @@ func TestMakeReceiveAdapter(t *testing.T) {
 									Name:  "K_LOGGING_CONFIG",
 									Value: "",
 								},
+								{
+									Name: "GOMAXPROCS",
+									ValueFrom: &corev1.EnvVarSource{
+										ResourceFieldRef: &corev1.ResourceFieldSelector{
+											ContainerName: "receive-adapter",
+											Resource:      "limits.cpu",
+										},
+									},
+								},
 							},
 							VolumeMounts: []corev1.VolumeMount{
 								{
@@ -136,13 +187,24 @@ func TestMakeReceiveAdapter(t *testing.T) {
 									MountPath: "/etc/couchdb-credentials",
 									ReadOnly:  true,
 								},
+								{
+									Name:      "couchdb-logging",
+									MountPath: "/etc/couchdb-logging",
+									ReadOnly:  true,
+								},
 							},
 						},
 					},
 					Volumes: []corev1.Volume{{
 						Name: "couchdb-credentials",
 						VolumeSource: corev1.VolumeSource{
-							Secret: &corev1.SecretVolumeSource{}}}},
+							Secret: &corev1.SecretVolumeSource{}}}, {
+						Name: "couchdb-logging",
+						VolumeSource: corev1.VolumeSource{
+							ConfigMap: &corev1.ConfigMapVolumeSource{
+								LocalObjectReference: corev1.LocalObjectReference{Name: fmt.Sprintf("couchdbsource-%s-logging-1234", name)},
+								Optional:             &trueValue,
+							}}}},
 				},
 			},
 		},
@@ -152,3 +214,776 @@ func TestMakeReceiveAdapter(t *testing.T) {
 		t.Errorf("unexpected deploy (-want, +got) = %v", diff)
 	}
 }
+
+func TestMakeEnvOptional(t *testing.T) {
+	retry := int32(3)
+	policy := eventingduckv1.BackoffPolicyExponential
+	delay := "PT1S"
+
+	testCases := map[string]struct {
+		uid               types.UID
+		annotations       map[string]string
+		spec              v1alpha1.CouchDbSourceSpec
+		deadLetterSinkURI string
+		replyURI          string
+		sinkCACerts       string
+		defaultHeartbeat  string
+		defaultReconnect  v1alpha1.ReconnectSpec
+		clusterID         string
+		tracingConfig     string
+		want              []corev1.EnvVar
+	}{
+		"nothing set": {
+			spec: v1alpha1.CouchDbSourceSpec{},
+			want: []corev1.EnvVar{},
+		},
+		"session auth": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				Auth: v1alpha1.AuthSession,
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_AUTH",
+				Value: "session",
+			}},
+		},
+		"dev instance": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				DevInstance: true,
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_CREATE_DATABASE",
+				Value: "true",
+			}},
+		},
+		"proxy": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				Proxy: &v1alpha1.ProxySpec{
+					URL:     "http://proxy:3128",
+					NoProxy: []string{"localhost", ".svc.cluster.local"},
+				},
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_PROXY_URL",
+				Value: "http://proxy:3128",
+			}, {
+				Name:  "COUCHDB_NO_PROXY",
+				Value: "localhost,.svc.cluster.local",
+			}},
+		},
+		"ceOverrides": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				CloudEventOverrides: &duckv1.CloudEventOverrides{
+					Extensions: map[string]string{"env": "prod"},
+				},
+			},
+			want: []corev1.EnvVar{{
+				Name:  "K_CE_OVERRIDES",
+				Value: `{"extensions":{"env":"prod"}}`,
+			}},
+		},
+		"cluster identity": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				CloudEventOverrides: &duckv1.CloudEventOverrides{
+					Extensions: map[string]string{"env": "prod", "couchdbcluster": "spoofed"},
+				},
+			},
+			clusterID: "eu-west-1",
+			want: []corev1.EnvVar{{
+				Name:  "K_CE_OVERRIDES",
+				Value: `{"extensions":{"couchdbcluster":"eu-west-1","env":"prod"}}`,
+			}},
+		},
+		"liveness": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				Liveness: &v1alpha1.LivenessSpec{DisconnectedThreshold: "PT2M"},
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_DISCONNECTED_THRESHOLD",
+				Value: "2m0s",
+			}},
+		},
+		"tracing": {
+			tracingConfig: `{"backend":"zipkin","debug":"false","sample-rate":"0.1","zipkin-endpoint":"http://zipkin.istio-system.svc.cluster.local:9411/api/v2/spans"}`,
+			want: []corev1.EnvVar{{
+				Name:  "K_TRACING_CONFIG",
+				Value: `{"backend":"zipkin","debug":"false","sample-rate":"0.1","zipkin-endpoint":"http://zipkin.istio-system.svc.cluster.local:9411/api/v2/spans"}`,
+			}},
+		},
+		"eventTypeTemplate": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				EventTypeTemplate: "com.acme.orders.{{.ChangeType}}",
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_EVENT_TYPE_TEMPLATE",
+				Value: "com.acme.orders.{{.ChangeType}}",
+			}},
+		},
+		"eventTypeField": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				EventTypeField: "type",
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_EVENT_TYPE_FIELD",
+				Value: "type",
+			}},
+		},
+		"subjectTemplate": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				SubjectTemplate: "{{.Database}}/{{.ID}}",
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_SUBJECT_TEMPLATE",
+				Value: "{{.Database}}/{{.ID}}",
+			}},
+		},
+		"idTemplate": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				IDTemplate: "{{.ID}}@{{.Rev}}",
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_ID_TEMPLATE",
+				Value: "{{.ID}}@{{.Rev}}",
+			}},
+		},
+		"deletedDocs": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				DeletedDocs: v1alpha1.DeletedDocsOnly,
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_DELETED_DOCS",
+				Value: "only",
+			}},
+		},
+		"designDocs": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				DesignDocs: v1alpha1.DesignDocsExclude,
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_DESIGN_DOCS",
+				Value: "exclude",
+			}},
+		},
+		"onDecodeError": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				OnDecodeError: v1alpha1.DecodeErrorFail,
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_ON_DECODE_ERROR",
+				Value: "fail",
+			}},
+		},
+		"conflicts": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				Conflicts: true,
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_CONFLICTS",
+				Value: "true",
+			}},
+		},
+		"databaseUpdates": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				DatabaseUpdates: true,
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_DATABASE_UPDATES",
+				Value: "true",
+			}},
+		},
+		"contentMode": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				ContentMode: v1alpha1.ContentModeBatch,
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_CONTENT_MODE",
+				Value: "batch",
+			}},
+		},
+		"ordering": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				Ordering: v1alpha1.OrderingOrdered,
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_ORDERING",
+				Value: "ordered",
+			}},
+		},
+		"limits": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				Limits: &v1alpha1.LimitsSpec{MaxLineBytes: 1 << 20, MaxResults: 500},
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_MAX_LINE_BYTES",
+				Value: "1048576",
+			}, {
+				Name:  "COUCHDB_MAX_JSON_DEPTH",
+				Value: "0",
+			}, {
+				Name:  "COUCHDB_MAX_RESULTS",
+				Value: "500",
+			}},
+		},
+		"backfill": {
+			uid: "1234",
+			spec: v1alpha1.CouchDbSourceSpec{
+				Backfill: true,
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_BACKFILL",
+				Value: "true",
+			}, {
+				Name:  "COUCHDB_BACKFILL_BOOKMARK",
+				Value: "_local/knative-backfill-1234",
+			}, {
+				Name:  "COUCHDB_STATUS_PORT",
+				Value: "8080",
+			}},
+		},
+		"health metrics": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				HealthMetrics: &v1alpha1.HealthMetricsSpec{Interval: "PT30S"},
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_HEALTH_METRICS_INTERVAL",
+				Value: "30s",
+			}},
+		},
+		"lag metrics": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				LagMetrics: &v1alpha1.LagMetricsSpec{},
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_LAG_METRICS_INTERVAL",
+				Value: "30s",
+			}},
+		},
+		"stats": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				Stats: &v1alpha1.StatsSpec{},
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_STATS",
+				Value: "true",
+			}, {
+				Name:  "COUCHDB_STATUS_PORT",
+				Value: "8080",
+			}},
+		},
+		"attachments": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				Attachments: v1alpha1.AttachmentsReference,
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_ATTACHMENTS",
+				Value: "reference",
+			}},
+		},
+		"payload": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				Payload: v1alpha1.PayloadDiff,
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_PAYLOAD",
+				Value: "diff",
+			}},
+		},
+		"match": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				Match: `doc.type == "order"`,
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_MATCH",
+				Value: `doc.type == "order"`,
+			}},
+		},
+		"projection": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				Projection: map[string]string{"customer": "{.customer.name}"},
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_PROJECTION",
+				Value: `{"customer":"{.customer.name}"}`,
+			}},
+		},
+		"heartbeat": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				Feed:      v1alpha1.FeedContinuous,
+				Heartbeat: "PT20S",
+			},
+			defaultHeartbeat: "PT10S",
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_HEARTBEAT",
+				Value: "PT20S",
+			}},
+		},
+		"default heartbeat": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				Feed: v1alpha1.FeedContinuous,
+			},
+			defaultHeartbeat: "PT10S",
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_HEARTBEAT",
+				Value: "PT10S",
+			}},
+		},
+		"timeout": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				Feed:    v1alpha1.FeedContinuous,
+				Timeout: "PT40S",
+			},
+			defaultHeartbeat: "PT10S",
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_TIMEOUT",
+				Value: "PT40S",
+			}},
+		},
+		"since": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				Since: "now",
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_SINCE",
+				Value: "now",
+			}},
+		},
+		"batch": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				Batch: &v1alpha1.BatchSpec{MaxCount: 500, MaxWait: "PT5S"},
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_BATCH",
+				Value: "true",
+			}, {
+				Name:  "COUCHDB_BATCH_MAX_COUNT",
+				Value: "500",
+			}, {
+				Name:  "COUCHDB_BATCH_MAX_WAIT",
+				Value: "PT5S",
+			}},
+		},
+		"polling": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				Feed:    v1alpha1.FeedNormal,
+				Polling: &v1alpha1.PollingSpec{MinInterval: "PT0.5S", MaxInterval: "PT30S"},
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_POLLING",
+				Value: "true",
+			}, {
+				Name:  "COUCHDB_POLL_MIN_INTERVAL",
+				Value: "PT0.5S",
+			}, {
+				Name:  "COUCHDB_POLL_MAX_INTERVAL",
+				Value: "PT30S",
+			}},
+		},
+		"reconnect": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				Reconnect: &v1alpha1.ReconnectSpec{InitialDelay: "PT1S", MaxDelay: "PT5M"},
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_RECONNECT_INITIAL_DELAY",
+				Value: "PT1S",
+			}, {
+				Name:  "COUCHDB_RECONNECT_MAX_DELAY",
+				Value: "PT5M",
+			}},
+		},
+		"default reconnect": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				Reconnect: &v1alpha1.ReconnectSpec{MaxDelay: "PT5M"},
+			},
+			defaultReconnect: v1alpha1.ReconnectSpec{InitialDelay: "PT4S", MaxDelay: "PT2M"},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_RECONNECT_INITIAL_DELAY",
+				Value: "PT4S",
+			}, {
+				Name:  "COUCHDB_RECONNECT_MAX_DELAY",
+				Value: "PT5M",
+			}},
+		},
+		"rateLimit": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				RateLimit: &v1alpha1.RateLimitSpec{EventsPerSecond: 50, Burst: 200},
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_RATE_LIMIT",
+				Value: "50",
+			}, {
+				Name:  "COUCHDB_RATE_LIMIT_BURST",
+				Value: "200",
+			}},
+		},
+		"seqInterval": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				SeqInterval: 100,
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_SEQ_INTERVAL",
+				Value: "100",
+			}},
+		},
+		"replay": {
+			annotations: map[string]string{v1alpha1.ReplayFromAnnotationKey: "42-g1AAAA"},
+			spec: v1alpha1.CouchDbSourceSpec{
+				Since: "now",
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_SINCE",
+				Value: "now",
+			}, {
+				Name:  "COUCHDB_REPLAY_FROM",
+				Value: "42-g1AAAA",
+			}},
+		},
+		"pprof": {
+			annotations: map[string]string{v1alpha1.PprofPortAnnotationKey: "6060"},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_PPROF_PORT",
+				Value: "6060",
+			}},
+		},
+		"window": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				Window: &v1alpha1.WindowSpec{Since: "1200", Until: "now"},
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_WINDOW_SINCE",
+				Value: "1200",
+			}, {
+				Name:  "COUCHDB_WINDOW_UNTIL",
+				Value: "now",
+			}},
+		},
+		"partitions": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				Partitions: []string{"sensors", "gateways"},
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_PARTITIONS",
+				Value: "sensors,gateways",
+			}},
+		},
+		"access": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				Access: &v1alpha1.AccessSpec{Roles: []string{"tenant-a", "auditors"}},
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_ACCESS_ROLES",
+				Value: "tenant-a,auditors",
+			}, {
+				Name: "COUCHDB_ACCESS_FIELD",
+			}},
+		},
+		"keda": {
+			uid:         "1234",
+			annotations: map[string]string{v1alpha1.AutoscalingClassAnnotationKey: v1alpha1.KedaAutoscalingClass},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_CHECKPOINT_DOC",
+				Value: "_local/knative-checkpoint-1234",
+			}},
+		},
+		"grouping": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				Grouping: &v1alpha1.GroupingSpec{Field: "txn_id", Delay: "PT2S", Batch: true},
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_GROUP_FIELD",
+				Value: "txn_id",
+			}, {
+				Name:  "COUCHDB_GROUP_DELAY",
+				Value: "PT2S",
+			}, {
+				Name:  "COUCHDB_GROUP_BATCH",
+				Value: "true",
+			}},
+		},
+		"join": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				Join: &v1alpha1.JoinSpec{Database: "customers", Key: "customer_id", MaxAge: "PT5M", OnMissing: v1alpha1.JoinMissingSkip},
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_JOIN_DATABASE",
+				Value: "customers",
+			}, {
+				Name:  "COUCHDB_JOIN_KEY",
+				Value: "customer_id",
+			}, {
+				Name:  "COUCHDB_JOIN_AS",
+				Value: "customers",
+			}, {
+				Name:  "COUCHDB_JOIN_CACHE_SIZE",
+				Value: "0",
+			}, {
+				Name:  "COUCHDB_JOIN_MAX_AGE",
+				Value: "PT5M",
+			}, {
+				Name:  "COUCHDB_JOIN_ON_MISSING",
+				Value: "skip",
+			}},
+		},
+		"buffer": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				Buffer: &v1alpha1.BufferSpec{Size: resource.MustParse("1000Mi"), OnFull: v1alpha1.BufferFullDropOldest},
+			},
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_BUFFER_DIR",
+				Value: "/var/run/couchdb-buffer",
+			}, {
+				Name:  "COUCHDB_BUFFER_MAX_BYTES",
+				Value: "943718400",
+			}, {
+				Name:  "COUCHDB_BUFFER_ON_FULL",
+				Value: "dropOldest",
+			}},
+		},
+		"reply": {
+			replyURI: "http://replies.example.com",
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_REPLY_SINK",
+				Value: "http://replies.example.com",
+			}},
+		},
+		"sink CA certificates": {
+			sinkCACerts: "-----BEGIN CERTIFICATE-----",
+			want: []corev1.EnvVar{{
+				Name:  "COUCHDB_SINK_CA_CERTS",
+				Value: "-----BEGIN CERTIFICATE-----",
+			}},
+		},
+		"delivery": {
+			spec: v1alpha1.CouchDbSourceSpec{
+				Delivery: &eventingduckv1.DeliverySpec{
+					Retry:         &retry,
+					BackoffPolicy: &policy,
+					BackoffDelay:  &delay,
+				},
+			},
+			deadLetterSinkURI: "http://dls.example.com",
+			want: []corev1.EnvVar{{
+				Name:  "DELIVERY_RETRY",
+				Value: "3",
+			}, {
+				Name:  "DELIVERY_BACKOFF_POLICY",
+				Value: "exponential",
+			}, {
+				Name:  "DELIVERY_BACKOFF_DELAY",
+				Value: "PT1S",
+			}, {
+				Name:  "DELIVERY_DEAD_LETTER_SINK",
+				Value: "http://dls.example.com",
+			}},
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			base := makeEnv(&ReceiveAdapterArgs{Source: &v1alpha1.CouchDbSource{}})
+			got := makeEnv(&ReceiveAdapterArgs{
+				Source: &v1alpha1.CouchDbSource{
+					ObjectMeta: metav1.ObjectMeta{UID: tc.uid, Annotations: tc.annotations},
+					Spec:       tc.spec,
+				},
+				DeadLetterSinkURI: tc.deadLetterSinkURI,
+				ReplyURI:          tc.replyURI,
+				SinkCACerts:       tc.sinkCACerts,
+				DefaultHeartbeat:  tc.defaultHeartbeat,
+				DefaultReconnect:  tc.defaultReconnect,
+				ClusterID:         tc.clusterID,
+				TracingConfig:     tc.tracingConfig,
+			})[len(base):]
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("unexpected env (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
+
+func TestMakeReceiveAdapterGlobalOrdering(t *testing.T) {
+	got := MakeReceiveAdapter(&ReceiveAdapterArgs{
+		Image: "test-image",
+		Source: &v1alpha1.CouchDbSource{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "source-name",
+				Namespace: "source-namespace",
+				UID:       "1234",
+			},
+			Spec: v1alpha1.CouchDbSourceSpec{
+				Ordering: v1alpha1.OrderingGlobal,
+			},
+		},
+		Labels:  Labels("source-name"),
+		SinkURI: "sink-uri",
+	})
+	if got := *got.Spec.Replicas; got != 2 {
+		t.Errorf("replicas = %d, want an active replica and a standby", got)
+	}
+	var lease string
+	for _, e := range got.Spec.Template.Spec.Containers[0].Env {
+		if e.Name == "COUCHDB_LEASE_NAME" {
+			lease = e.Value
+		}
+	}
+	if lease != got.Name {
+		t.Errorf("COUCHDB_LEASE_NAME = %q, want %q", lease, got.Name)
+	}
+}
+
+func TestMakeReceiveAdapterHighAvailability(t *testing.T) {
+	labels := Labels("source-name")
+	got := MakeReceiveAdapter(&ReceiveAdapterArgs{
+		Image: "test-image",
+		Source: &v1alpha1.CouchDbSource{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "source-name",
+				Namespace: "source-namespace",
+				UID:       "1234",
+			},
+			Spec: v1alpha1.CouchDbSourceSpec{
+				HighAvailability: &v1alpha1.HighAvailabilitySpec{Replicas: 3},
+			},
+		},
+		Labels:  labels,
+		SinkURI: "sink-uri",
+	})
+	if got := *got.Spec.Replicas; got != 3 {
+		t.Errorf("replicas = %d, want 3", got)
+	}
+	var lease string
+	for _, e := range got.Spec.Template.Spec.Containers[0].Env {
+		if e.Name == "COUCHDB_LEASE_NAME" {
+			lease = e.Value
+		}
+	}
+	if lease != got.Name {
+		t.Errorf("COUCHDB_LEASE_NAME = %q, want %q", lease, got.Name)
+	}
+	affinity := got.Spec.Template.Spec.Affinity
+	if affinity == nil || affinity.PodAntiAffinity == nil {
+		t.Fatal("the replicas are not spread across nodes")
+	}
+	term := affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution[0].PodAffinityTerm
+	if term.TopologyKey != corev1.LabelHostname || !cmp.Equal(term.LabelSelector.MatchLabels, labels) {
+		t.Errorf("anti affinity term = %+v, want the adapter pods by hostname", term)
+	}
+}
+
+func TestMakeReceiveAdapterTemplate(t *testing.T) {
+	labels := Labels("source-name")
+	nodeAffinity := &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+			NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+				MatchExpressions: []corev1.NodeSelectorRequirement{{
+					Key:      "topology.kubernetes.io/zone",
+					Operator: corev1.NodeSelectorOpIn,
+					Values:   []string{"zone-a", "zone-b"},
+				}},
+			}},
+		},
+	}
+	limits := corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")}
+	got := MakeReceiveAdapter(&ReceiveAdapterArgs{
+		Image: "test-image",
+		Source: &v1alpha1.CouchDbSource{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "source-name",
+				Namespace: "source-namespace",
+				UID:       "1234",
+			},
+			Spec: v1alpha1.CouchDbSourceSpec{
+				HighAvailability: &v1alpha1.HighAvailabilitySpec{Replicas: 2},
+				Template: &v1alpha1.AdapterTemplateSpec{
+					Metadata: &v1alpha1.AdapterTemplateMetadata{
+						Labels: map[string]string{
+							"team":                         "payments",
+							"knative-eventing-source-name": "other",
+						},
+						Annotations: map[string]string{"sidecar.istio.io/inject": "false"},
+					},
+					Resources:    &corev1.ResourceRequirements{Limits: limits},
+					NodeSelector: map[string]string{"pool": "couchdb"},
+					Tolerations: []corev1.Toleration{{
+						Key:      "dedicated",
+						Operator: corev1.TolerationOpEqual,
+						Value:    "couchdb",
+						Effect:   corev1.TaintEffectNoSchedule,
+					}},
+					Affinity: &corev1.Affinity{NodeAffinity: nodeAffinity},
+					Env: []corev1.EnvVar{
+						{Name: "GOMAXPROCS", Value: "2"},
+						{Name: "HTTP_PROXY", Value: "http://proxy:3128"},
+					},
+				},
+			},
+		},
+		Labels:  labels,
+		SinkURI: "sink-uri",
+	})
+
+	template := got.Spec.Template
+	if template.Labels["team"] != "payments" || template.Labels["knative-eventing-source-name"] != "source-name" {
+		t.Errorf("labels = %v, want the ones of the template without overriding the selector", template.Labels)
+	}
+	if !cmp.Equal(got.Spec.Selector.MatchLabels, labels) {
+		t.Errorf("selector = %v, want %v", got.Spec.Selector.MatchLabels, labels)
+	}
+	if template.Annotations["sidecar.istio.io/inject"] != "false" {
+		t.Errorf("annotations = %v, want the ones of the template", template.Annotations)
+	}
+	if template.Spec.NodeSelector["pool"] != "couchdb" || len(template.Spec.Tolerations) != 1 {
+		t.Errorf("node selector %v and tolerations %v, want the ones of the template", template.Spec.NodeSelector, template.Spec.Tolerations)
+	}
+	affinity := template.Spec.Affinity
+	if !cmp.Equal(affinity.NodeAffinity, nodeAffinity) || affinity.PodAntiAffinity == nil {
+		t.Errorf("affinity = %+v, want the node affinity of the template and the replicas spread across nodes", affinity)
+	}
+
+	container := template.Spec.Containers[0]
+	if !cmp.Equal(container.Resources.Limits, limits) {
+		t.Errorf("limits = %v, want %v", container.Resources.Limits, limits)
+	}
+	env := map[string]corev1.EnvVar{}
+	for _, e := range container.Env {
+		if _, ok := env[e.Name]; ok {
+			t.Errorf("duplicate env %s", e.Name)
+		}
+		env[e.Name] = e
+	}
+	if env["GOMAXPROCS"].Value != "2" || env["HTTP_PROXY"].Value != "http://proxy:3128" {
+		t.Errorf("env = %v, want the variables of the template", container.Env)
+	}
+	// 90% of the memory limit.
+	if got := env["GOMEMLIMIT"].Value; got != "120795930" {
+		t.Errorf("GOMEMLIMIT = %q, want 120795930", got)
+	}
+}
+
+func TestMakeReceiveAdapterJob(t *testing.T) {
+	args := &ReceiveAdapterArgs{
+		Image: "test-image",
+		Source: &v1alpha1.CouchDbSource{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "source-name",
+				Namespace: "source-namespace",
+				UID:       "1234",
+			},
+			Spec: v1alpha1.CouchDbSourceSpec{
+				Window: &v1alpha1.WindowSpec{Until: "now"},
+			},
+		},
+		Labels:  Labels("source-name"),
+		SinkURI: "sink-uri",
+	}
+
+	job := MakeReceiveAdapterJob(args)
+	deployment := MakeReceiveAdapter(args)
+	if diff := cmp.Diff(deployment.ObjectMeta, job.ObjectMeta); diff != "" {
+		t.Errorf("unexpected job metadata (-deployment, +job) = %v", diff)
+	}
+	if got, want := job.Spec.Template.Spec.RestartPolicy, corev1.RestartPolicyOnFailure; got != want {
+		t.Errorf("restart policy = %q, want %q", got, want)
+	}
+	job.Spec.Template.Spec.RestartPolicy = ""
+	if diff := cmp.Diff(deployment.Spec.Template, job.Spec.Template); diff != "" {
+		t.Errorf("unexpected job pod template (-deployment, +job) = %v", diff)
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// GoMaxProcsEnv and GoMemLimitEnv are the Go runtime settings derived
+	// from the container limits.
+	GoMaxProcsEnv = "GOMAXPROCS"
+	GoMemLimitEnv = "GOMEMLIMIT"
+
+	// goMemLimitPercent is the share of the memory limit left to the Go
+	// heap, the rest covering the memory the runtime does not account for,
+	// such as the stacks and the buffers of the HTTP connections.
+	goMemLimitPercent = 90
+)
+
+// MakeRuntimeEnv returns the environment tuning the Go runtime of the named
+// container to its resource limits: GOMAXPROCS follows the CPU limit (rounded
+// up to a whole core) and GOMEMLIMIT is 90% of the memory limit, if any, so
+// that the garbage collector works harder before the container gets OOM
+// killed. Non-empty overrides are used verbatim instead.
+func MakeRuntimeEnv(container string, resources *corev1.ResourceRequirements, overrides map[string]string) []corev1.EnvVar {
+	env := []corev1.EnvVar{{
+		Name: GoMaxProcsEnv,
+		ValueFrom: &corev1.EnvVarSource{
+			ResourceFieldRef: &corev1.ResourceFieldSelector{
+				ContainerName: container,
+				Resource:      "limits.cpu",
+			},
+		},
+	}}
+	if v := overrides[GoMaxProcsEnv]; v != "" {
+		env[0] = corev1.EnvVar{Name: GoMaxProcsEnv, Value: v}
+	}
+	if v := overrides[GoMemLimitEnv]; v != "" {
+		return append(env, corev1.EnvVar{Name: GoMemLimitEnv, Value: v})
+	}
+	if resources == nil {
+		return env
+	}
+	// Without a limit, the runtime would otherwise follow the memory of the
+	// node, which the container is not guaranteed.
+	if limit, ok := resources.Limits[corev1.ResourceMemory]; ok && !limit.IsZero() {
+		env = append(env, corev1.EnvVar{
+			Name:  GoMemLimitEnv,
+			Value: strconv.FormatInt(limit.Value()/100*goMemLimitPercent, 10),
+		})
+	}
+	return env
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestMakeRuntimeEnv(t *testing.T) {
+	maxProcs := corev1.EnvVar{
+		Name: "GOMAXPROCS",
+		ValueFrom: &corev1.EnvVarSource{
+			ResourceFieldRef: &corev1.ResourceFieldSelector{
+				ContainerName: "receive-adapter",
+				Resource:      "limits.cpu",
+			},
+		},
+	}
+	testCases := map[string]struct {
+		resources *corev1.ResourceRequirements
+		overrides map[string]string
+		want      []corev1.EnvVar
+	}{
+		"no resources": {
+			want: []corev1.EnvVar{maxProcs},
+		},
+		"no memory limit": {
+			resources: &corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("100Mi")},
+			},
+			want: []corev1.EnvVar{maxProcs},
+		},
+		"memory limit": {
+			resources: &corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1000M")},
+			},
+			want: []corev1.EnvVar{maxProcs, {
+				Name:  "GOMEMLIMIT",
+				Value: "900000000",
+			}},
+		},
+		"overrides": {
+			resources: &corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1000M")},
+			},
+			overrides: map[string]string{
+				GoMemLimitEnv: "200MiB",
+				GoMaxProcsEnv: "",
+			},
+			want: []corev1.EnvVar{maxProcs, {
+				Name:  "GOMEMLIMIT",
+				Value: "200MiB",
+			}},
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			got := MakeRuntimeEnv("receive-adapter", tc.resources, tc.overrides)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("unexpected env (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"strconv"
+
+	v1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+// ScaledObjectGVR is the resource of the KEDA ScaledObjects, whose types are
+// not vendored.
+var ScaledObjectGVR = schema.GroupVersionResource{
+	Group:    "keda.sh",
+	Version:  "v1alpha1",
+	Resource: "scaledobjects",
+}
+
+// MakeScaledObject generates (but does not insert into K8s) the KEDA
+// ScaledObject scaling the receive adapter Deployment on the number of
+// changes waiting for it, which the metrics-api scaler reads from lagURL.
+func MakeScaledObject(ra *v1.Deployment, keda *v1alpha1.KedaAutoscaling, lagURL string) *unstructured.Unstructured {
+	so := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"scaleTargetRef": map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"name":       ra.Name,
+			},
+			"minReplicaCount": int64(keda.MinReplicas),
+			"maxReplicaCount": int64(keda.MaxReplicas),
+			"pollingInterval": int64(keda.PollingInterval),
+			"cooldownPeriod":  int64(keda.CooldownPeriod),
+			"triggers": []interface{}{
+				map[string]interface{}{
+					"type": "metrics-api",
+					"metadata": map[string]interface{}{
+						"url":           lagURL,
+						"valueLocation": "pending",
+						"targetValue":   strconv.Itoa(int(keda.LagThreshold)),
+					},
+				},
+			},
+		},
+	}}
+	so.SetAPIVersion(ScaledObjectGVR.GroupVersion().String())
+	so.SetKind("ScaledObject")
+	so.SetNamespace(ra.Namespace)
+	so.SetName(ra.Name)
+	so.SetLabels(ra.Labels)
+	so.SetOwnerReferences(ra.OwnerReferences)
+	return so
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+func TestMakeScaledObject(t *testing.T) {
+	ra := MakeReceiveAdapter(&ReceiveAdapterArgs{
+		Image: "test-image",
+		Source: &v1alpha1.CouchDbSource{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "source-name",
+				Namespace: "source-namespace",
+				UID:       "1234",
+			},
+		},
+		Labels: Labels("source-name"),
+	})
+	got := MakeScaledObject(ra, &v1alpha1.KedaAutoscaling{
+		MaxReplicas:     1,
+		PollingInterval: 30,
+		CooldownPeriod:  300,
+		LagThreshold:    10,
+	}, "http://controller/lag/source-namespace/"+ra.Name)
+
+	if got.GetName() != ra.Name || got.GetNamespace() != ra.Namespace {
+		t.Errorf("ScaledObject %s/%s, want %s/%s", got.GetNamespace(), got.GetName(), ra.Namespace, ra.Name)
+	}
+	if refs := got.GetOwnerReferences(); len(refs) != 1 || refs[0].UID != "1234" {
+		t.Errorf("OwnerReferences = %v, want the source", refs)
+	}
+	want := map[string]interface{}{
+		"scaleTargetRef": map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"name":       ra.Name,
+		},
+		"minReplicaCount": int64(0),
+		"maxReplicaCount": int64(1),
+		"pollingInterval": int64(30),
+		"cooldownPeriod":  int64(300),
+		"triggers": []interface{}{
+			map[string]interface{}{
+				"type": "metrics-api",
+				"metadata": map[string]interface{}{
+					"url":           "http://controller/lag/source-namespace/" + ra.Name,
+					"valueLocation": "pending",
+					"targetValue":   "10",
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got.Object["spec"]); diff != "" {
+		t.Errorf("unexpected spec (-want, +got) = %v", diff)
+	}
+	// The object must survive the deep copies of the dynamic client.
+	got.DeepCopy()
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"strconv"
+
+	v1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+// MakeReceiveAdapterStatefulSet generates (but does not insert into K8s) the
+// Receive Adapter StatefulSet of CouchDB sources watching the databases of a
+// pattern. Each replica tails the databases hashing to its ordinal, which it
+// reads from the name of its pod.
+func MakeReceiveAdapterStatefulSet(args *ReceiveAdapterArgs) *v1.StatefulSet {
+	replicas := args.Source.Spec.AdapterShards()
+	template := makePodTemplate(args)
+	if replicas > 1 && (template.Spec.Affinity == nil || template.Spec.Affinity.PodAntiAffinity == nil) {
+		if template.Spec.Affinity == nil {
+			template.Spec.Affinity = &corev1.Affinity{}
+		}
+		template.Spec.Affinity.PodAntiAffinity = makeAntiAffinity(args)
+	}
+	meta := makeObjectMeta(args)
+	return &v1.StatefulSet{
+		ObjectMeta: meta,
+		Spec: v1.StatefulSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: args.Labels,
+			},
+			Replicas: &replicas,
+			// No headless Service is created: the replicas are only told
+			// apart by their ordinal.
+			ServiceName: meta.Name,
+			// The shards are independent, so they start and stop at once.
+			PodManagementPolicy: v1.ParallelPodManagement,
+			Template:            template,
+		},
+	}
+}
+
+// makeShardEnv returns the environment variables selecting the databases of
+// the pattern the replica tails.
+func makeShardEnv(spec *v1alpha1.CouchDbSourceSpec) []corev1.EnvVar {
+	return []corev1.EnvVar{{
+		Name:  "COUCHDB_DATABASE_PATTERN",
+		Value: spec.DatabasePattern,
+	}, {
+		Name:  "COUCHDB_SHARDS",
+		Value: strconv.Itoa(int(spec.AdapterShards())),
+	}, {
+		Name: "COUCHDB_POD_NAME",
+		ValueFrom: &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{
+				FieldPath: "metadata.name",
+			},
+		},
+	}}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+func TestMakeReceiveAdapterStatefulSet(t *testing.T) {
+	labels := Labels("source-name")
+	args := &ReceiveAdapterArgs{
+		Image: "test-image",
+		Source: &v1alpha1.CouchDbSource{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "source-name",
+				Namespace: "source-namespace",
+				UID:       "1234",
+			},
+			Spec: v1alpha1.CouchDbSourceSpec{
+				DatabasePattern: "orders-*",
+				Shards:          3,
+			},
+		},
+		Labels:  labels,
+		SinkURI: "sink-uri",
+	}
+
+	got := MakeReceiveAdapterStatefulSet(args)
+	if diff := cmp.Diff(MakeReceiveAdapter(args).ObjectMeta, got.ObjectMeta); diff != "" {
+		t.Errorf("unexpected statefulset metadata (-deployment, +statefulset) = %v", diff)
+	}
+	if got := *got.Spec.Replicas; got != 3 {
+		t.Errorf("replicas = %d, want 3", got)
+	}
+	if got.Spec.PodManagementPolicy != v1.ParallelPodManagement {
+		t.Errorf("pod management policy = %q, want %q", got.Spec.PodManagementPolicy, v1.ParallelPodManagement)
+	}
+	if got.Spec.Template.Spec.Affinity == nil || got.Spec.Template.Spec.Affinity.PodAntiAffinity == nil {
+		t.Error("the shards are not spread across nodes")
+	}
+
+	env := map[string]corev1.EnvVar{}
+	for _, e := range got.Spec.Template.Spec.Containers[0].Env {
+		env[e.Name] = e
+	}
+	if got := env["COUCHDB_DATABASE_PATTERN"].Value; got != "orders-*" {
+		t.Errorf("COUCHDB_DATABASE_PATTERN = %q, want orders-*", got)
+	}
+	if got := env["COUCHDB_SHARDS"].Value; got != "3" {
+		t.Errorf("COUCHDB_SHARDS = %q, want 3", got)
+	}
+	if from := env["COUCHDB_POD_NAME"].ValueFrom; from == nil || from.FieldRef == nil || from.FieldRef.FieldPath != "metadata.name" {
+		t.Errorf("COUCHDB_POD_NAME = %+v, want the name of the pod", env["COUCHDB_POD_NAME"])
+	}
+}
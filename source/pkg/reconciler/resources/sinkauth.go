@@ -0,0 +1,68 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// SinkTokenVolumeName and SinkTokenMountPath are the volume of the
+	// receive adapter projecting the OIDC token of the audience of the sink,
+	// and where it is mounted.
+	SinkTokenVolumeName = "sink-token"
+	SinkTokenMountPath  = "/var/run/secrets/couchdb-sink"
+
+	// sinkTokenExpiration is the lifetime of the projected tokens, which the
+	// kubelet rotates at 80% of it.
+	sinkTokenExpiration = int64(3600)
+)
+
+// addSinkToken projects a token of the service account of the receive
+// adapter for the audience of the sink, which the adapter sends the events
+// with, when the sink has one.
+func addSinkToken(template *corev1.PodTemplateSpec, audience string) {
+	if audience == "" {
+		return
+	}
+	expiration := sinkTokenExpiration
+	spec := &template.Spec
+	spec.Volumes = append(spec.Volumes, corev1.Volume{
+		Name: SinkTokenVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Projected: &corev1.ProjectedVolumeSource{
+				Sources: []corev1.VolumeProjection{{
+					ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
+						Audience:          audience,
+						ExpirationSeconds: &expiration,
+						Path:              "token",
+					},
+				}},
+			},
+		},
+	})
+	c := &spec.Containers[0]
+	c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{
+		Name:      SinkTokenVolumeName,
+		MountPath: SinkTokenMountPath,
+		ReadOnly:  true,
+	})
+	c.Env = append(c.Env, corev1.EnvVar{
+		Name:  "COUCHDB_SINK_TOKEN",
+		Value: SinkTokenMountPath + "/token",
+	})
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+func TestMakeReceiveAdapterSinkToken(t *testing.T) {
+	args := &ReceiveAdapterArgs{
+		Image: "test-image",
+		Source: &v1alpha1.CouchDbSource{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "source-name",
+				Namespace: "source-namespace",
+				UID:       "1234",
+			},
+		},
+		Labels:  Labels("source-name"),
+		SinkURI: "https://broker-ingress.knative-eventing.svc.cluster.local/ns/default",
+	}
+
+	ra := MakeReceiveAdapter(args)
+	for _, v := range ra.Spec.Template.Spec.Volumes {
+		if v.Name == SinkTokenVolumeName {
+			t.Errorf("Volumes has %s without an audience", SinkTokenVolumeName)
+		}
+	}
+
+	args.SinkAudience = "eventing.knative.dev/broker/default/default"
+	ra = MakeReceiveAdapter(args)
+	var projected *corev1.ServiceAccountTokenProjection
+	for _, v := range ra.Spec.Template.Spec.Volumes {
+		if v.Name == SinkTokenVolumeName && v.Projected != nil && len(v.Projected.Sources) == 1 {
+			projected = v.Projected.Sources[0].ServiceAccountToken
+		}
+	}
+	if projected == nil || projected.Audience != args.SinkAudience || projected.Path != "token" {
+		t.Fatalf("token projection = %+v, want one for %s", projected, args.SinkAudience)
+	}
+
+	c := ra.Spec.Template.Spec.Containers[0]
+	wantMount := corev1.VolumeMount{Name: SinkTokenVolumeName, MountPath: SinkTokenMountPath, ReadOnly: true}
+	found := false
+	for _, m := range c.VolumeMounts {
+		if m.Name == SinkTokenVolumeName {
+			found = true
+			if diff := cmp.Diff(wantMount, m); diff != "" {
+				t.Errorf("unexpected mount (-want, +got) = %v", diff)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("VolumeMounts = %v, want the sink token", c.VolumeMounts)
+	}
+	var token string
+	for _, e := range c.Env {
+		if e.Name == "COUCHDB_SINK_TOKEN" {
+			token = e.Value
+		}
+	}
+	if want := SinkTokenMountPath + "/token"; token != want {
+		t.Errorf("COUCHDB_SINK_TOKEN = %q, want %q", token, want)
+	}
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"knative.dev/eventing-couchdb/source/pkg/contract"
+)
+
+// MakeTenantConfig generates (but does not insert into K8s) the ConfigMap
+// through which the multi-tenant receive adapter serves the source, in place
+// of its receive adapter Deployment.
+func MakeTenantConfig(args *ReceiveAdapterArgs, installation string) (*corev1.ConfigMap, error) {
+	env, err := json.Marshal(makeTenantEnv(args))
+	if err != nil {
+		return nil, err
+	}
+	meta := makeObjectMeta(args)
+	meta.Labels = make(map[string]string, len(args.Labels)+1)
+	for k, v := range args.Labels {
+		meta.Labels[k] = v
+	}
+	meta.Labels[contract.TenantLabelKey] = installation
+	return &corev1.ConfigMap{
+		ObjectMeta: meta,
+		Data: map[string]string{
+			contract.TenantEnvKey:         string(env),
+			contract.TenantCredentialsKey: args.Source.Spec.CouchDbCredentials.Name,
+		},
+	}, nil
+}
+
+// makeTenantEnv returns the environment of the receive adapter, less the
+// settings of the process shared by the sources.
+func makeTenantEnv(args *ReceiveAdapterArgs) map[string]string {
+	env := map[string]string{
+		"NAMESPACE": args.Source.Namespace,
+	}
+	for _, e := range makeEnv(args) {
+		switch e.Name {
+		case "METRICS_DOMAIN", "K_METRICS_CONFIG", "K_LOGGING_CONFIG", "COUCHDB_LOGGING_DIR", "COUCHDB_HEALTH_PORT", "COUCHDB_PPROF_PORT", GoMaxProcsEnv, GoMemLimitEnv:
+			continue
+		}
+		if e.ValueFrom == nil {
+			env[e.Name] = e.Value
+		}
+	}
+	return env
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"encoding/json"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-couchdb/source/pkg/contract"
+)
+
+func TestMakeTenantConfig(t *testing.T) {
+	src := &v1alpha1.CouchDbSource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "source-name",
+			Namespace: "source-namespace",
+			UID:       "1234",
+			// Profiling is set on the shared adapter.
+			Annotations: map[string]string{v1alpha1.PprofPortAnnotationKey: "6060"},
+		},
+		Spec: v1alpha1.CouchDbSourceSpec{
+			CouchDbCredentials: corev1.ObjectReference{Name: "couchdb-binding"},
+			Database:           "mydb",
+			Feed:               v1alpha1.FeedContinuous,
+		},
+	}
+
+	got, err := MakeTenantConfig(&ReceiveAdapterArgs{
+		Image:   "test-image",
+		Source:  src,
+		Labels:  map[string]string{"test-key": "test-value"},
+		SinkURI: "sink-uri",
+	}, "blue")
+	if err != nil {
+		t.Fatalf("MakeTenantConfig() = %v", err)
+	}
+
+	if want := "couchdbsource-source-name-1234"; got.Name != want || got.Namespace != "source-namespace" {
+		t.Errorf("ConfigMap = %s/%s, want source-namespace/%s", got.Namespace, got.Name, want)
+	}
+	if len(got.OwnerReferences) != 1 || got.OwnerReferences[0].UID != "1234" {
+		t.Errorf("OwnerReferences = %v, want the source", got.OwnerReferences)
+	}
+	if got.Labels[contract.TenantLabelKey] != "blue" || got.Labels["test-key"] != "test-value" {
+		t.Errorf("Labels = %v, want the adapter labels and %s=blue", got.Labels, contract.TenantLabelKey)
+	}
+	if got.Data[contract.TenantCredentialsKey] != "couchdb-binding" {
+		t.Errorf("credentials = %q, want couchdb-binding", got.Data[contract.TenantCredentialsKey])
+	}
+
+	var env map[string]string
+	if err := json.Unmarshal([]byte(got.Data[contract.TenantEnvKey]), &env); err != nil {
+		t.Fatalf("Unmarshal(env) = %v", err)
+	}
+	for k, want := range map[string]string{
+		"NAMESPACE":        "source-namespace",
+		"NAME":             "source-name",
+		"K_SINK":           "sink-uri",
+		"COUCHDB_DATABASE": "mydb",
+		"COUCHDB_FEED":     "continuous",
+	} {
+		if env[k] != want {
+			t.Errorf("env[%s] = %q, want %q", k, env[k], want)
+		}
+	}
+	for _, k := range []string{"METRICS_DOMAIN", "K_METRICS_CONFIG", "K_LOGGING_CONFIG", "COUCHDB_LOGGING_DIR", "COUCHDB_HEALTH_PORT", "COUCHDB_PPROF_PORT", GoMaxProcsEnv, GoMemLimitEnv} {
+		if _, ok := env[k]; ok {
+			t.Errorf("env[%s] is set, want the setting of the shared adapter", k)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/controller"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-couchdb/source/pkg/reconciler/resources"
+)
+
+const (
+	couchdbsourceScaledObjectCreated = "CouchDbSourceScaledObjectCreated"
+	couchdbsourceScaledObjectUpdated = "CouchDbSourceScaledObjectUpdated"
+)
+
+// reconcileScaledObject creates or updates the KEDA ScaledObject of the
+// receive adapter Deployment when the source is scaled by KEDA, and deletes
+// it otherwise.
+func (r *Reconciler) reconcileScaledObject(ctx context.Context, src *v1alpha1.CouchDbSource, ra *appsv1.Deployment) error {
+	keda, fe := src.KedaAutoscaling()
+	if fe != nil {
+		return controller.NewPermanentError(fe.ViaField("metadata", "annotations"))
+	}
+	if keda == nil {
+		return r.deleteScaledObject(ctx, src, ra.Name)
+	}
+	expected := resources.MakeScaledObject(ra, keda, lagURL(ra))
+
+	scaledObjects := r.dynamicClientSet.Resource(resources.ScaledObjectGVR).Namespace(src.Namespace)
+	so, err := scaledObjects.Get(ctx, expected.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = scaledObjects.Create(ctx, expected, metav1.CreateOptions{})
+		controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, couchdbsourceScaledObjectCreated, "ScaledObject created, error: %v", err)
+		return err
+	} else if err != nil {
+		return fmt.Errorf("error getting scaled object: %v", err)
+	} else if !metav1.IsControlledBy(so, src) {
+		return fmt.Errorf("scaledobject %q is not owned by CouchDbSource %q", so.GetName(), src.Name)
+	} else if !equality.Semantic.DeepEqual(so.Object["spec"], expected.Object["spec"]) {
+		so.Object["spec"] = expected.Object["spec"]
+		if _, err := scaledObjects.Update(ctx, so, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+		controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, couchdbsourceScaledObjectUpdated, "ScaledObject updated")
+	}
+	return nil
+}
+
+// deleteScaledObject deletes the KEDA ScaledObject of the receive adapter of
+// the source, if any. Without KEDA installed, there is none.
+func (r *Reconciler) deleteScaledObject(ctx context.Context, src *v1alpha1.CouchDbSource, name string) error {
+	scaledObjects := r.dynamicClientSet.Resource(resources.ScaledObjectGVR).Namespace(src.Namespace)
+	so, err := scaledObjects.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) || (err == nil && !metav1.IsControlledBy(so, src)) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error getting scaled object: %v", err)
+	}
+	if err := scaledObjects.Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("error deleting scaled object: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-couchdb/source/pkg/reconciler/resources"
+)
+
+const (
+	// statefulSetPollInterval is how often the receive adapter StatefulSet
+	// of a source watching the databases of a pattern is checked until all
+	// its shards are ready.
+	statefulSetPollInterval = 10 * time.Second
+
+	couchdbsourceStatefulSetCreated = "CouchDbSourceStatefulSetCreated"
+	couchdbsourceStatefulSetUpdated = "CouchDbSourceStatefulSetUpdated"
+)
+
+// createReceiveAdapterStatefulSet creates or updates the receive adapter
+// StatefulSet of a source watching the databases of a pattern, whose
+// replicas each tail the databases of their shard.
+func (r *Reconciler) createReceiveAdapterStatefulSet(ctx context.Context, src *v1alpha1.CouchDbSource, status *v1alpha1.CouchDbSourceStatus, image string, sinkURI, deadLetterSinkURI *apis.URL) (*appsv1.StatefulSet, error) {
+	adapterArgs, err := r.makeReceiveAdapterArgs(ctx, src, image, sinkURI, deadLetterSinkURI)
+	if err != nil {
+		return nil, err
+	}
+	expected := resources.MakeReceiveAdapterStatefulSet(adapterArgs)
+
+	// The source may have watched a single database before.
+	if err := r.deleteReceiveAdapter(ctx, src, expected.Name); err != nil {
+		return nil, err
+	}
+	if err := r.deleteReceiveAdapterJob(ctx, src, expected.Name); err != nil {
+		return nil, err
+	}
+	if err := r.deleteTenant(ctx, src, expected.Name); err != nil {
+		return nil, err
+	}
+	if err := r.deleteContainerSource(ctx, src, expected.Name); err != nil {
+		return nil, err
+	}
+
+	statefulSets := r.kubeClientSet.AppsV1().StatefulSets(src.Namespace)
+	ss, err := statefulSets.Get(ctx, expected.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		ss, err = statefulSets.Create(ctx, expected, metav1.CreateOptions{})
+		controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, couchdbsourceStatefulSetCreated, "StatefulSet created, error: %v", err)
+		if err == nil {
+			status.MarkChangesApplied()
+		}
+		return ss, err
+	} else if err != nil {
+		return nil, fmt.Errorf("error getting receive adapter statefulset: %v", err)
+	} else if !metav1.IsControlledBy(ss, src) {
+		return nil, fmt.Errorf("statefulset %q is not owned by CouchDbSource %q", ss.Name, src.Name)
+	}
+	if r.podSpecChanged(ss.Spec.Template.Spec, expected.Spec.Template.Spec) ||
+		!equality.Semantic.DeepEqual(ss.Spec.Template.Labels, expected.Spec.Template.Labels) ||
+		!equality.Semantic.DeepDerivative(expected.Spec.Template.Annotations, ss.Spec.Template.Annotations) ||
+		!equality.Semantic.DeepEqual(ss.Spec.Replicas, expected.Spec.Replicas) {
+		// Changing the number of shards rolls every replica, which then
+		// tails the databases of its new shard.
+		ss.Spec.Template.Spec = expected.Spec.Template.Spec
+		ss.Spec.Template.Labels = expected.Spec.Template.Labels
+		if len(expected.Spec.Template.Annotations) > 0 && ss.Spec.Template.Annotations == nil {
+			ss.Spec.Template.Annotations = make(map[string]string, len(expected.Spec.Template.Annotations))
+		}
+		for k, v := range expected.Spec.Template.Annotations {
+			ss.Spec.Template.Annotations[k] = v
+		}
+		ss.Spec.Replicas = expected.Spec.Replicas
+		if ss, err = statefulSets.Update(ctx, ss, metav1.UpdateOptions{}); err != nil {
+			return ss, err
+		}
+		controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, couchdbsourceStatefulSetUpdated, "StatefulSet updated")
+	} else {
+		logging.FromContext(ctx).Debugw("Reusing existing receive adapter statefulset")
+	}
+	status.MarkChangesApplied()
+	return ss, nil
+}
+
+// deleteReceiveAdapterStatefulSet deletes the receive adapter StatefulSet of
+// the source, if any.
+func (r *Reconciler) deleteReceiveAdapterStatefulSet(ctx context.Context, src *v1alpha1.CouchDbSource, name string) error {
+	ss, err := r.kubeClientSet.AppsV1().StatefulSets(src.Namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) || (err == nil && !metav1.IsControlledBy(ss, src)) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error getting receive adapter statefulset: %v", err)
+	}
+	if err := r.kubeClientSet.AppsV1().StatefulSets(src.Namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("error deleting receive adapter statefulset: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+// defaultServiceAccountName is the service account the receive adapters run
+// as without spec.serviceAccountName.
+const defaultServiceAccountName = "default"
+
+// sinkAddress returns the OIDC audience of the sink of the source and the
+// certificates of the CAs it is verified with, empty when it has none: the
+// ones of the spec, or else the ones the Addressable referenced by the sink
+// advertises in status.address.
+func (r *Reconciler) sinkAddress(ctx context.Context, source *v1alpha1.CouchDbSource, dest *duckv1.Destination) (audience, caCerts string, err error) {
+	audience, caCerts = source.Spec.SinkAudience, source.Spec.SinkCACerts
+	if dest.Ref == nil || (audience != "" && caCerts != "") {
+		return audience, caCerts, nil
+	}
+	gv, err := schema.ParseGroupVersion(dest.Ref.APIVersion)
+	if err != nil {
+		return "", "", err
+	}
+	gvr, _ := meta.UnsafeGuessKindToResource(gv.WithKind(dest.Ref.Kind))
+	addressable, err := r.dynamicClientSet.Resource(gvr).Namespace(dest.Ref.Namespace).Get(ctx, dest.Ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", "", fmt.Errorf("error getting the sink %s %q: %v", dest.Ref.Kind, dest.Ref.Name, err)
+	}
+	if audience == "" {
+		if audience, _, err = unstructured.NestedString(addressable.Object, "status", "address", "audience"); err != nil {
+			return "", "", err
+		}
+	}
+	if caCerts == "" {
+		if caCerts, _, err = unstructured.NestedString(addressable.Object, "status", "address", "CACerts"); err != nil {
+			return "", "", err
+		}
+	}
+	return audience, caCerts, nil
+}
+
+// adapterServiceAccountName returns the service account the receive adapter
+// of the source runs as.
+func adapterServiceAccountName(source *v1alpha1.CouchDbSource) string {
+	if source.Spec.ServiceAccountName != "" {
+		return source.Spec.ServiceAccountName
+	}
+	return defaultServiceAccountName
+}
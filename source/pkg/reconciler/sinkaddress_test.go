@@ -0,0 +1,84 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+func TestSinkAddress(t *testing.T) {
+	testCases := map[string]struct {
+		spec        v1alpha1.CouchDbSourceSpec
+		dest        duckv1.Destination
+		wantAud     string
+		wantCACerts string
+	}{
+		"uri": {
+			dest: duckv1.Destination{URI: apis.HTTP("sink.example.com")},
+		},
+		"uri with an audience": {
+			spec:    v1alpha1.CouchDbSourceSpec{SinkAudience: "sink"},
+			dest:    duckv1.Destination{URI: apis.HTTP("sink.example.com")},
+			wantAud: "sink",
+		},
+		"uri with CA certificates": {
+			spec:        v1alpha1.CouchDbSourceSpec{SinkCACerts: "certs"},
+			dest:        duckv1.Destination{URI: apis.HTTPS("sink.example.com")},
+			wantCACerts: "certs",
+		},
+		"address of the spec over the one of the reference": {
+			spec: v1alpha1.CouchDbSourceSpec{SinkAudience: "sink", SinkCACerts: "certs"},
+			dest: duckv1.Destination{Ref: &duckv1.KReference{
+				APIVersion: "eventing.knative.dev/v1",
+				Kind:       "Broker",
+				Name:       "default",
+				Namespace:  "default",
+			}},
+			wantAud:     "sink",
+			wantCACerts: "certs",
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			r := &Reconciler{}
+			aud, caCerts, err := r.sinkAddress(context.Background(), &v1alpha1.CouchDbSource{Spec: tc.spec}, &tc.dest)
+			if err != nil {
+				t.Fatalf("sinkAddress() = %v", err)
+			}
+			if aud != tc.wantAud || caCerts != tc.wantCACerts {
+				t.Errorf("sinkAddress() = %q, %q, want %q, %q", aud, caCerts, tc.wantAud, tc.wantCACerts)
+			}
+		})
+	}
+}
+
+func TestAdapterServiceAccountName(t *testing.T) {
+	src := &v1alpha1.CouchDbSource{}
+	if got := adapterServiceAccountName(src); got != "default" {
+		t.Errorf("adapterServiceAccountName() = %q, want default", got)
+	}
+	src.Spec.ServiceAccountName = "couchdb"
+	if got := adapterServiceAccountName(src); got != "couchdb" {
+		t.Errorf("adapterServiceAccountName() = %q, want couchdb", got)
+	}
+}
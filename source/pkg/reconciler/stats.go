@@ -0,0 +1,132 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/logging"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+// reconcileStats reflects the delivery statistics of the receive adapters in
+// status.stats, at most once per spec.stats.interval. It returns how long to
+// wait before the next update.
+func (r *Reconciler) reconcileStats(ctx context.Context, src *v1alpha1.CouchDbSource) time.Duration {
+	if src.Spec.Stats == nil {
+		src.Status.Stats = nil
+		src.Status.LastSequence = ""
+		src.Status.LastEventTime = nil
+		return 0
+	}
+	interval := src.Spec.Stats.UpdateInterval()
+	now := time.Now()
+	if src.Status.Stats != nil {
+		if wait := src.Status.Stats.UpdateTime.Add(interval).Sub(now); wait > 0 {
+			return wait
+		}
+	}
+
+	statuses, err := r.adapterStatuses(ctx, src)
+	if err != nil {
+		logging.FromContext(ctx).Warnw("Unable to list the receive adapter pods", zap.Error(err))
+		return interval
+	}
+	src.Status.Stats = mergeStats(src.Status.Stats, statuses, now)
+	src.Status.LastEventTime = src.Status.Stats.LastDeliveryTime
+	if seq := lastSequence(statuses); seq != "" {
+		src.Status.LastSequence = seq
+	}
+	return interval
+}
+
+// lastSequence returns the sequence reported by the receive adapter which
+// delivered an event last, the one reading the feed when the others stand
+// by, or empty when none reported one.
+func lastSequence(statuses map[string]*v1alpha1.AdapterStatus) string {
+	var seq string
+	var last *metav1.Time
+	for _, status := range statuses {
+		if status == nil || status.Sequence == "" {
+			continue
+		}
+		var delivered *metav1.Time
+		if status.Stats != nil {
+			delivered = status.Stats.LastDeliveryTime
+		}
+		if seq == "" || (delivered != nil && (last == nil || last.Before(delivered))) {
+			seq, last = status.Sequence, delivered
+		}
+	}
+	return seq
+}
+
+// mergeStats adds to the previous statistics what the receive adapters
+// delivered since they were last observed. Adapters that could not be reached
+// keep their last observation, and the adapters that are gone are forgotten.
+func mergeStats(prev *v1alpha1.DeliveryStats, statuses map[string]*v1alpha1.AdapterStatus, now time.Time) *v1alpha1.DeliveryStats {
+	stats := &v1alpha1.DeliveryStats{UpdateTime: metav1.NewTime(now)}
+	observed := map[string]v1alpha1.AdapterStatsObservation{}
+	if prev != nil {
+		stats.EventsDelivered = prev.EventsDelivered
+		stats.EventsDeadLettered = prev.EventsDeadLettered
+		stats.LastDeliveryTime = prev.LastDeliveryTime
+		for _, o := range prev.Adapters {
+			observed[o.Pod] = o
+		}
+	}
+
+	for pod, status := range statuses {
+		if status == nil || status.Stats == nil {
+			if o, ok := observed[pod]; ok {
+				stats.Adapters = append(stats.Adapters, o)
+			}
+			continue
+		}
+		s := status.Stats
+		delivered, deadLettered := s.EventsDelivered, s.EventsDeadLettered
+		// The counters of the same run of the adapter only grow, otherwise the
+		// adapter restarted and counts from zero again.
+		if o, ok := observed[pod]; ok && o.StartTime.Equal(&s.StartTime) &&
+			o.EventsDelivered <= delivered && o.EventsDeadLettered <= deadLettered {
+			delivered -= o.EventsDelivered
+			deadLettered -= o.EventsDeadLettered
+		}
+		stats.EventsDelivered += delivered
+		stats.EventsDeadLettered += deadLettered
+		stats.EventsPerMinute += s.EventsPerMinute
+		if s.LastDeliveryTime != nil && (stats.LastDeliveryTime == nil || stats.LastDeliveryTime.Before(s.LastDeliveryTime)) {
+			stats.LastDeliveryTime = s.LastDeliveryTime
+		}
+		stats.Adapters = append(stats.Adapters, v1alpha1.AdapterStatsObservation{
+			Pod:                pod,
+			StartTime:          s.StartTime,
+			EventsDelivered:    s.EventsDelivered,
+			EventsDeadLettered: s.EventsDeadLettered,
+		})
+	}
+	// Keep the status stable across updates.
+	sort.Slice(stats.Adapters, func(i, j int) bool {
+		return stats.Adapters[i].Pod < stats.Adapters[j].Pod
+	})
+	return stats
+}
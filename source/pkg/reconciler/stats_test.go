@@ -0,0 +1,120 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+func TestMergeStats(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	started := metav1.NewTime(now.Add(-time.Hour))
+	restarted := metav1.NewTime(now.Add(-time.Minute))
+	last := metav1.NewTime(now.Add(-time.Second))
+
+	prev := &v1alpha1.DeliveryStats{
+		EventsDelivered:    100,
+		EventsDeadLettered: 2,
+		Adapters: []v1alpha1.AdapterStatsObservation{
+			{Pod: "a", StartTime: started, EventsDelivered: 60, EventsDeadLettered: 1},
+			{Pod: "b", StartTime: started, EventsDelivered: 40, EventsDeadLettered: 1},
+			{Pod: "c", StartTime: started},
+			{Pod: "gone", StartTime: started},
+		},
+	}
+	statuses := map[string]*v1alpha1.AdapterStatus{
+		// Delivered 10 more events since the last update.
+		"a": {Stats: &v1alpha1.AdapterStats{
+			StartTime:          started,
+			EventsDelivered:    70,
+			EventsDeadLettered: 1,
+			LastDeliveryTime:   &last,
+			EventsPerMinute:    10,
+		}},
+		// Restarted and delivered 5 events since.
+		"b": {Stats: &v1alpha1.AdapterStats{
+			StartTime:          restarted,
+			EventsDelivered:    5,
+			EventsDeadLettered: 1,
+			EventsPerMinute:    5,
+		}},
+		// Unreachable.
+		"c": nil,
+	}
+
+	want := &v1alpha1.DeliveryStats{
+		EventsDelivered:    115,
+		EventsDeadLettered: 3,
+		LastDeliveryTime:   &last,
+		EventsPerMinute:    15,
+		UpdateTime:         metav1.NewTime(now),
+		Adapters: []v1alpha1.AdapterStatsObservation{
+			{Pod: "a", StartTime: started, EventsDelivered: 70, EventsDeadLettered: 1},
+			{Pod: "b", StartTime: restarted, EventsDelivered: 5, EventsDeadLettered: 1},
+			{Pod: "c", StartTime: started},
+		},
+	}
+	if diff := cmp.Diff(want, mergeStats(prev, statuses, now)); diff != "" {
+		t.Errorf("unexpected stats (-want, +got) = %v", diff)
+	}
+}
+
+func TestLastSequence(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	earlier := metav1.NewTime(now.Add(-time.Minute))
+	later := metav1.NewTime(now.Add(-time.Second))
+
+	testCases := map[string]struct {
+		statuses map[string]*v1alpha1.AdapterStatus
+		want     string
+	}{
+		"none reported": {
+			statuses: map[string]*v1alpha1.AdapterStatus{
+				"a": nil,
+				"b": {Stats: &v1alpha1.AdapterStats{}},
+			},
+		},
+		"standby": {
+			statuses: map[string]*v1alpha1.AdapterStatus{
+				"active":  {Sequence: "42-g1AAAA", Stats: &v1alpha1.AdapterStats{}},
+				"standby": {Stats: &v1alpha1.AdapterStats{}},
+			},
+			want: "42-g1AAAA",
+		},
+		"latest delivery": {
+			statuses: map[string]*v1alpha1.AdapterStatus{
+				"old":  {Sequence: "40-g1AAAA", Stats: &v1alpha1.AdapterStats{LastDeliveryTime: &earlier}},
+				"new":  {Sequence: "42-g1AAAA", Stats: &v1alpha1.AdapterStats{LastDeliveryTime: &later}},
+				"idle": {Sequence: "41-g1AAAA", Stats: &v1alpha1.AdapterStats{}},
+			},
+			want: "42-g1AAAA",
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			if got := lastSequence(tc.statuses); got != tc.want {
+				t.Errorf("lastSequence() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/metrics"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-couchdb/source/pkg/client/clientset/versioned"
+)
+
+const (
+	// webhookProbeInterval is the period between two probes of the webhooks.
+	webhookProbeInterval = time.Minute
+
+	// webhookProbeTimeout bounds a probe. The API server gives up on an
+	// admission webhook after 10 seconds by default.
+	webhookProbeTimeout = 30 * time.Second
+
+	// webhookSlowThreshold is the probe latency above which the webhooks are
+	// reported slow.
+	webhookSlowThreshold = 5 * time.Second
+)
+
+var (
+	// webhookProbeLatencyM is the time the API server took to admit the
+	// probing CouchDbSource, webhooks included.
+	webhookProbeLatencyM = stats.Float64(
+		"couchdb_webhook_probe_latencies",
+		"The time in milliseconds the API server took to admit a dry run CouchDbSource",
+		stats.UnitMilliseconds,
+	)
+
+	probeResultKey = tag.MustNewKey("result")
+)
+
+func init() {
+	if err := view.Register(
+		&view.View{
+			Description: webhookProbeLatencyM.Description(),
+			Measure:     webhookProbeLatencyM,
+			Aggregation: view.Distribution(metrics.Buckets125(1, 100000)...),
+			TagKeys:     []tag.Key{probeResultKey},
+		},
+	); err != nil {
+		panic(err)
+	}
+}
+
+// webhookProber periodically creates a CouchDbSource in dry run mode, which
+// goes through the admission webhooks like the sources of the users, and
+// reflects their health in the WebhookHealthy condition of the sources.
+// Slow or failing webhooks otherwise only show up as API server timeouts.
+type webhookProber struct {
+	client       versioned.Interface
+	namespace    string
+	installation string
+	slow         time.Duration
+
+	mu     sync.RWMutex
+	probed bool
+	reason string
+	msg    string
+}
+
+func newWebhookProber(client versioned.Interface, namespace, installation string) *webhookProber {
+	return &webhookProber{
+		client:       client,
+		namespace:    namespace,
+		installation: installation,
+		slow:         webhookSlowThreshold,
+	}
+}
+
+// run probes the webhooks until the context is done, and calls changed when
+// their health changes.
+func (p *webhookProber) run(ctx context.Context, changed func()) {
+	wait.Until(func() {
+		if p.probe(ctx) {
+			changed()
+		}
+	}, webhookProbeInterval, ctx.Done())
+}
+
+// probe creates a dry run CouchDbSource, and returns whether the health of
+// the webhooks changed.
+func (p *webhookProber) probe(ctx context.Context) bool {
+	src := &v1alpha1.CouchDbSource{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "couchdb-webhook-probe-",
+			Namespace:    p.namespace,
+		},
+		Spec: v1alpha1.CouchDbSourceSpec{
+			Feed:     v1alpha1.FeedContinuous,
+			Database: "probe",
+			Sink:     &duckv1.Destination{URI: apis.HTTP("probe.invalid")},
+		},
+	}
+	if p.installation != "" {
+		// The webhooks of the installation only admit its sources.
+		src.Labels = map[string]string{v1alpha1.InstallationLabelKey: p.installation}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookProbeTimeout)
+	defer cancel()
+	start := time.Now()
+	_, err := p.client.SourcesV1alpha1().CouchDbSources(p.namespace).Create(ctx, src, metav1.CreateOptions{
+		DryRun: []string{metav1.DryRunAll},
+	})
+	latency := time.Since(start)
+
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	if tagged, terr := tag.New(context.Background(), tag.Insert(probeResultKey, result)); terr == nil {
+		metrics.Record(tagged, webhookProbeLatencyM.M(float64(latency.Milliseconds())))
+	}
+
+	reason, msg := "", ""
+	switch {
+	case err != nil:
+		reason = "WebhookUnavailable"
+		msg = fmt.Sprintf("The API server failed to admit a dry run CouchDbSource: %v", err)
+	case latency > p.slow:
+		reason = "WebhookSlow"
+		msg = fmt.Sprintf("The API server took %v to admit a dry run CouchDbSource", latency.Round(time.Millisecond))
+	}
+	return p.update(ctx, reason, msg)
+}
+
+// update records the health of the webhooks, and returns whether it changed.
+// Only the reason counts, so that a varying latency does not update every
+// source after each probe.
+func (p *webhookProber) update(ctx context.Context, reason, msg string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	changed := !p.probed || reason != p.reason
+	p.probed, p.reason, p.msg = true, reason, msg
+	if changed {
+		if reason == "" {
+			logging.FromContext(ctx).Info("The admission webhooks are healthy")
+		} else {
+			logging.FromContext(ctx).Warnf("The admission webhooks are unhealthy: %s", msg)
+		}
+	}
+	return changed
+}
+
+// markHealth reflects the health of the webhooks in the status of a source.
+func (p *webhookProber) markHealth(status *v1alpha1.CouchDbSourceStatus) {
+	if p == nil {
+		return
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	switch {
+	case !p.probed:
+	case p.reason == "":
+		status.MarkWebhookHealthy()
+	default:
+		status.MarkWebhookUnhealthy(p.reason, p.msg)
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgotesting "k8s.io/client-go/testing"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-couchdb/source/pkg/client/clientset/versioned/fake"
+)
+
+func TestWebhookProber(t *testing.T) {
+	testCases := map[string]struct {
+		err        error
+		delay      time.Duration
+		wantReason string
+	}{
+		"healthy": {},
+		"unavailable": {
+			err:        errors.New(`failed calling webhook "validation.webhook.couchdb.messaging.knative.dev": context deadline exceeded`),
+			wantReason: "WebhookUnavailable",
+		},
+		"slow": {
+			delay:      20 * time.Millisecond,
+			wantReason: "WebhookSlow",
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			var labels map[string]string
+			client.PrependReactor("create", "couchdbsources", func(action clientgotesting.Action) (bool, runtime.Object, error) {
+				labels = action.(clientgotesting.CreateAction).GetObject().(*v1alpha1.CouchDbSource).Labels
+				time.Sleep(tc.delay)
+				return true, nil, tc.err
+			})
+			p := newWebhookProber(client, "knative-sources", "canary")
+			p.slow = 10 * time.Millisecond
+
+			if !p.probe(context.Background()) {
+				t.Error("probe() = false for the first probe, want true")
+			}
+			if got := labels[v1alpha1.InstallationLabelKey]; got != "canary" {
+				t.Errorf("installation label = %q, want canary", got)
+			}
+			if p.probe(context.Background()) {
+				t.Error("probe() = true without any change, want false")
+			}
+
+			status := &v1alpha1.CouchDbSourceStatus{}
+			p.markHealth(status)
+			cond := status.GetCondition(v1alpha1.CouchDbConditionWebhookHealthy)
+			if tc.wantReason == "" {
+				if !cond.IsTrue() {
+					t.Errorf("WebhookHealthy = %+v, want true", cond)
+				}
+				return
+			}
+			if !cond.IsFalse() || cond.Reason != tc.wantReason {
+				t.Errorf("WebhookHealthy = %+v, want false with reason %s", cond, tc.wantReason)
+			}
+		})
+	}
+}
+
+func TestWebhookProberNotProbed(t *testing.T) {
+	status := &v1alpha1.CouchDbSourceStatus{}
+	newWebhookProber(fake.NewSimpleClientset(), "knative-sources", "").markHealth(status)
+	if cond := status.GetCondition(v1alpha1.CouchDbConditionWebhookHealthy); cond != nil {
+		t.Errorf("WebhookHealthy = %+v before the first probe, want none", cond)
+	}
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sandboxmigration carries the CouchDbSources of the deprecated
+// knative-sandbox installation over to this project. Their objects already
+// match this project's API, but the old receive adapter kept no checkpoint:
+// the migration pins every source to the current update sequence of its
+// database, so that the adopted adapters resume from there rather than
+// replaying the whole changes feed.
+package sandboxmigration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"knative.dev/pkg/logging"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-couchdb/source/pkg/client/clientset/versioned"
+	"knative.dev/eventing-couchdb/source/pkg/contract"
+)
+
+const (
+	// MigratedFromAnnotationKey marks the sources already migrated, so that
+	// running the migration again leaves them alone.
+	MigratedFromAnnotationKey = "couchdb.sources.knative.dev/migrated-from"
+
+	// sandbox is the value of MigratedFromAnnotationKey.
+	sandbox = "knative-sandbox"
+
+	// requestTimeout bounds the request reading the update sequence of a
+	// database.
+	requestTimeout = 30 * time.Second
+)
+
+// Migrator migrates the CouchDbSources left by the knative-sandbox
+// installation.
+type Migrator struct {
+	secrets corev1client.SecretsGetter
+	sources versioned.Interface
+	client  *http.Client
+}
+
+// NewMigrator returns a Migrator using the given clients.
+func NewMigrator(secrets corev1client.SecretsGetter, sources versioned.Interface) *Migrator {
+	return &Migrator{
+		secrets: secrets,
+		sources: sources,
+		client:  &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// Migrate annotates every CouchDbSource not migrated yet with the current
+// update sequence of its database, as the sequence to replay from. Sources
+// that already carry a sequence to replay from, or that start from
+// spec.since, keep it. The sources whose database cannot be read are left
+// for the next run, and reported in the returned error once all others are
+// migrated.
+func (m *Migrator) Migrate(ctx context.Context) error {
+	logger := logging.FromContext(ctx)
+
+	var (
+		cont   string
+		failed []string
+	)
+	for {
+		list, err := m.sources.SourcesV1alpha1().CouchDbSources(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+			Limit:    500,
+			Continue: cont,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to list couchdbsources: %w", err)
+		}
+		migrated := 0
+		for i := range list.Items {
+			src := &list.Items[i]
+			if _, ok := src.Annotations[MigratedFromAnnotationKey]; ok {
+				continue
+			}
+			annotations := map[string]string{MigratedFromAnnotationKey: sandbox}
+			if _, ok := src.Annotations[v1alpha1.ReplayFromAnnotationKey]; !ok && src.Spec.Since == "" {
+				seq, err := m.updateSeq(ctx, src)
+				if err != nil {
+					logger.Warnw("Unable to read the update sequence of the database",
+						zap.String("source", src.Namespace+"/"+src.Name), zap.Error(err))
+					failed = append(failed, src.Namespace+"/"+src.Name)
+					continue
+				}
+				annotations[v1alpha1.ReplayFromAnnotationKey] = seq
+			}
+			patch, err := json.Marshal(map[string]interface{}{
+				"metadata": map[string]interface{}{"annotations": annotations},
+			})
+			if err != nil {
+				return err
+			}
+			if _, err := m.sources.SourcesV1alpha1().CouchDbSources(src.Namespace).Patch(ctx, src.Name,
+				types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+				return fmt.Errorf("unable to migrate %s/%s: %w", src.Namespace, src.Name, err)
+			}
+			migrated++
+		}
+		logger.Infof("Migrated %d couchdbsources", migrated)
+		if cont = list.Continue; cont == "" {
+			break
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("unable to read the update sequence of the databases of %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// updateSeq reads the current update sequence of the database of the
+// source, with its credentials and through its egress proxy if any, like the
+// receive adapter would.
+func (m *Migrator) updateSeq(ctx context.Context, src *v1alpha1.CouchDbSource) (string, error) {
+	namespace := src.Spec.CouchDbCredentials.Namespace
+	if namespace == "" {
+		namespace = src.Namespace
+	}
+	secret, err := m.secrets.Secrets(namespace).Get(ctx, src.Spec.CouchDbCredentials.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	rawurl, ok := secret.Data["url"]
+	if !ok {
+		return "", fmt.Errorf("the url of the secret %s/%s is missing", secret.Namespace, secret.Name)
+	}
+	couchDbURL, err := url.Parse(string(rawurl))
+	if err != nil {
+		// The error would carry the password of the url.
+		return "", fmt.Errorf("the url of the secret %s/%s is invalid", secret.Namespace, secret.Name)
+	}
+
+	client := m.client
+	if p := src.Spec.Proxy; p != nil {
+		proxyURL, err := url.Parse(p.URL)
+		if err != nil {
+			return "", fmt.Errorf("invalid proxy url: %w", err)
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = contract.ProxyFunc(proxyURL, p.NoProxy)
+		client = &http.Client{Transport: transport, Timeout: m.client.Timeout}
+	}
+
+	db := strings.TrimSuffix(couchDbURL.String(), "/") + "/" + url.PathEscape(src.Spec.Database)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, db, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected response to GET of the database %q: %s", src.Spec.Database, resp.Status)
+	}
+
+	var info struct {
+		UpdateSeq v1alpha1.SequenceID `json:"update_seq"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return "", fmt.Errorf("invalid information of the database %q: %w", src.Spec.Database, err)
+	}
+	if info.UpdateSeq == "" {
+		return "", fmt.Errorf("the database %q has no update sequence", src.Spec.Database)
+	}
+	return string(info.UpdateSeq), nil
+}
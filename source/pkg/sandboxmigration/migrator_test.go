@@ -0,0 +1,153 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sandboxmigration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	clientgotesting "k8s.io/client-go/testing"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-couchdb/source/pkg/client/clientset/versioned/fake"
+)
+
+// fakeSecrets implements the few SecretsGetter methods used by the Migrator.
+type fakeSecrets struct {
+	corev1client.SecretInterface
+	namespace string
+	secrets   map[string]*corev1.Secret
+}
+
+func (f *fakeSecrets) Secrets(namespace string) corev1client.SecretInterface {
+	return &fakeSecrets{namespace: namespace, secrets: f.secrets}
+}
+
+func (f *fakeSecrets) Get(ctx context.Context, name string, opts metav1.GetOptions) (*corev1.Secret, error) {
+	if s, ok := f.secrets[f.namespace+"/"+name]; ok {
+		return s, nil
+	}
+	return nil, apierrors.NewNotFound(corev1.Resource("secrets"), name)
+}
+
+func source(namespace, name, database string, annotations map[string]string) *v1alpha1.CouchDbSource {
+	return &v1alpha1.CouchDbSource{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Annotations: annotations},
+		Spec: v1alpha1.CouchDbSourceSpec{
+			CouchDbCredentials: corev1.ObjectReference{Name: "couchdb"},
+			Database:           database,
+		},
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	couchdb := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, p, _ := r.BasicAuth(); u != "admin" || p != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/photos":
+			json.NewEncoder(w).Encode(map[string]interface{}{"db_name": "photos", "update_seq": "42-g1AAAA"})
+		case "/legacy":
+			// CouchDB 1.x numbers the update sequences.
+			json.NewEncoder(w).Encode(map[string]interface{}{"db_name": "legacy", "update_seq": 7})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer couchdb.Close()
+
+	secrets := &fakeSecrets{secrets: map[string]*corev1.Secret{}}
+	for _, ns := range []string{"ns1", "ns2"} {
+		secrets.secrets[ns+"/couchdb"] = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: "couchdb"},
+			Data:       map[string][]byte{"url": []byte("http://admin:secret@" + couchdb.Listener.Addr().String())},
+		}
+	}
+	since := source("ns2", "since", "photos", nil)
+	since.Spec.Since = "now"
+	sources := fake.NewSimpleClientset(
+		source("ns1", "photos", "photos", nil),
+		source("ns2", "legacy", "legacy", nil),
+		source("ns2", "rewound", "photos", map[string]string{v1alpha1.ReplayFromAnnotationKey: "0"}),
+		source("ns2", "migrated", "photos", map[string]string{MigratedFromAnnotationKey: sandbox}),
+		since,
+	)
+
+	m := NewMigrator(secrets, sources)
+	if err := m.Migrate(context.Background()); err != nil {
+		t.Fatal("Migrate() =", err)
+	}
+
+	patched := map[string]string{}
+	for _, action := range sources.Actions() {
+		if p, ok := action.(clientgotesting.PatchAction); ok {
+			patched[p.GetNamespace()+"/"+p.GetName()] = string(p.GetPatch())
+		}
+	}
+	want := map[string]string{
+		"ns1/photos":  `{"metadata":{"annotations":{"couchdb.sources.knative.dev/migrated-from":"knative-sandbox","couchdb.sources.knative.dev/replay-from":"42-g1AAAA"}}}`,
+		"ns2/legacy":  `{"metadata":{"annotations":{"couchdb.sources.knative.dev/migrated-from":"knative-sandbox","couchdb.sources.knative.dev/replay-from":"7"}}}`,
+		"ns2/rewound": `{"metadata":{"annotations":{"couchdb.sources.knative.dev/migrated-from":"knative-sandbox"}}}`,
+		"ns2/since":   `{"metadata":{"annotations":{"couchdb.sources.knative.dev/migrated-from":"knative-sandbox"}}}`,
+	}
+	if diff := cmp.Diff(want, patched); diff != "" {
+		t.Errorf("unexpected patches (-want, +got) = %v", diff)
+	}
+}
+
+func TestMigrateUnreachableDatabase(t *testing.T) {
+	couchdb := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer couchdb.Close()
+
+	secrets := &fakeSecrets{secrets: map[string]*corev1.Secret{
+		"ns1/couchdb": {
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "couchdb"},
+			Data:       map[string][]byte{"url": []byte(couchdb.URL)},
+		},
+	}}
+	sources := fake.NewSimpleClientset(
+		source("ns1", "rejected", "photos", nil),
+		source("ns2", "no-secret", "photos", nil),
+		source("ns2", "rewound", "photos", map[string]string{v1alpha1.ReplayFromAnnotationKey: "0"}),
+	)
+
+	if err := NewMigrator(secrets, sources).Migrate(context.Background()); err == nil {
+		t.Error("Migrate() = nil, wanted an error")
+	}
+	var patched []string
+	for _, action := range sources.Actions() {
+		if p, ok := action.(clientgotesting.PatchAction); ok {
+			patched = append(patched, p.GetNamespace()+"/"+p.GetName())
+		}
+	}
+	// The other sources are still migrated.
+	if diff := cmp.Diff([]string{"ns2/rewound"}, patched); diff != "" {
+		t.Errorf("unexpected patches (-want, +got) = %v", diff)
+	}
+}
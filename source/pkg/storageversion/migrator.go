@@ -0,0 +1,111 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package storageversion rewrites stored CouchDbSources in the current
+// storage version of their CRD, so that older versions can safely be dropped
+// from the CRD.
+package storageversion
+
+import (
+	"context"
+	"fmt"
+
+	apix "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apixclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/logging"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-couchdb/source/pkg/client/clientset/versioned"
+)
+
+// CRDName is the name of the CouchDbSource CustomResourceDefinition.
+var CRDName = v1alpha1.Resource("couchdbsources").String()
+
+// Migrator migrates the stored CouchDbSources to the storage version of the
+// CRD and then prunes all other versions from the CRD status.
+type Migrator struct {
+	crds    apixclient.CustomResourceDefinitionInterface
+	sources versioned.Interface
+}
+
+// NewMigrator returns a Migrator using the given clients.
+func NewMigrator(crds apixclient.CustomResourceDefinitionInterface, sources versioned.Interface) *Migrator {
+	return &Migrator{
+		crds:    crds,
+		sources: sources,
+	}
+}
+
+// Migrate rewrites every CouchDbSource so that the API server re-encodes it
+// in the storage version, and then sets the CRD's storedVersions to that
+// version alone.
+func (m *Migrator) Migrate(ctx context.Context) error {
+	logger := logging.FromContext(ctx)
+
+	crd, err := m.crds.Get(ctx, CRDName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("unable to fetch crd %s: %w", CRDName, err)
+	}
+	version := storageVersion(crd)
+	if version == "" {
+		return fmt.Errorf("unable to determine storage version for %s", CRDName)
+	}
+	if version != v1alpha1.SchemeGroupVersion.Version {
+		// The generated clientset only speaks the versions this binary knows.
+		return fmt.Errorf("storage version %q of %s is not known to this migrator", version, CRDName)
+	}
+
+	var cont string
+	for {
+		list, err := m.sources.SourcesV1alpha1().CouchDbSources(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+			Limit:    500,
+			Continue: cont,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to list %s: %w", CRDName, err)
+		}
+		for _, src := range list.Items {
+			// An empty merge patch is enough for the API server to read the
+			// object in its stored version and write it back in the storage
+			// version.
+			if _, err := m.sources.SourcesV1alpha1().CouchDbSources(src.Namespace).Patch(ctx, src.Name,
+				types.MergePatchType, []byte("{}"), metav1.PatchOptions{}); err != nil {
+				return fmt.Errorf("unable to migrate %s/%s: %w", src.Namespace, src.Name, err)
+			}
+		}
+		logger.Infof("Migrated %d %s", len(list.Items), CRDName)
+		if cont = list.Continue; cont == "" {
+			break
+		}
+	}
+
+	crd.Status.StoredVersions = []string{version}
+	if _, err := m.crds.UpdateStatus(ctx, crd, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("unable to prune stored versions of %s: %w", CRDName, err)
+	}
+	return nil
+}
+
+func storageVersion(crd *apix.CustomResourceDefinition) string {
+	for _, v := range crd.Spec.Versions {
+		if v.Storage {
+			return v.Name
+		}
+	}
+	return ""
+}
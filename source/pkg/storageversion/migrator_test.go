@@ -0,0 +1,110 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storageversion
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	apix "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apixclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgotesting "k8s.io/client-go/testing"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-couchdb/source/pkg/client/clientset/versioned/fake"
+)
+
+// fakeCRDs implements the few CustomResourceDefinitionInterface methods used
+// by the Migrator.
+type fakeCRDs struct {
+	apixclient.CustomResourceDefinitionInterface
+	crd     *apix.CustomResourceDefinition
+	updated *apix.CustomResourceDefinition
+}
+
+func (f *fakeCRDs) Get(ctx context.Context, name string, opts metav1.GetOptions) (*apix.CustomResourceDefinition, error) {
+	return f.crd.DeepCopy(), nil
+}
+
+func (f *fakeCRDs) UpdateStatus(ctx context.Context, crd *apix.CustomResourceDefinition, opts metav1.UpdateOptions) (*apix.CustomResourceDefinition, error) {
+	f.updated = crd
+	return crd, nil
+}
+
+func TestMigrate(t *testing.T) {
+	crds := &fakeCRDs{
+		crd: &apix.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: CRDName},
+			Spec: apix.CustomResourceDefinitionSpec{
+				Versions: []apix.CustomResourceDefinitionVersion{{
+					Name: "v1alpha0",
+				}, {
+					Name:    "v1alpha1",
+					Storage: true,
+				}},
+			},
+			Status: apix.CustomResourceDefinitionStatus{
+				StoredVersions: []string{"v1alpha0", "v1alpha1"},
+			},
+		},
+	}
+	sources := fake.NewSimpleClientset(
+		&v1alpha1.CouchDbSource{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "a"}},
+		&v1alpha1.CouchDbSource{ObjectMeta: metav1.ObjectMeta{Namespace: "ns2", Name: "b"}},
+	)
+
+	if err := NewMigrator(crds, sources).Migrate(context.Background()); err != nil {
+		t.Fatal("Migrate() =", err)
+	}
+
+	var patched []string
+	for _, action := range sources.Actions() {
+		if p, ok := action.(clientgotesting.PatchAction); ok {
+			patched = append(patched, p.GetNamespace()+"/"+p.GetName())
+		}
+	}
+	if diff := cmp.Diff([]string{"ns1/a", "ns2/b"}, patched); diff != "" {
+		t.Errorf("unexpected patches (-want, +got) = %v", diff)
+	}
+	if crds.updated == nil {
+		t.Fatal("CRD status was not updated")
+	}
+	if diff := cmp.Diff([]string{"v1alpha1"}, crds.updated.Status.StoredVersions); diff != "" {
+		t.Errorf("unexpected stored versions (-want, +got) = %v", diff)
+	}
+}
+
+func TestMigrateUnknownStorageVersion(t *testing.T) {
+	crds := &fakeCRDs{
+		crd: &apix.CustomResourceDefinition{
+			Spec: apix.CustomResourceDefinitionSpec{
+				Versions: []apix.CustomResourceDefinitionVersion{{
+					Name:    "v2",
+					Storage: true,
+				}},
+			},
+		},
+	}
+	if err := NewMigrator(crds, fake.NewSimpleClientset()).Migrate(context.Background()); err == nil {
+		t.Error("Migrate() = nil, wanted an error")
+	}
+	if crds.updated != nil {
+		t.Error("CRD status must not be updated when migration fails")
+	}
+}
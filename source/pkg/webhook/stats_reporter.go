@@ -0,0 +1,121 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhook holds the instrumentation and the validation callbacks of
+// the admission webhooks of the CouchDB source.
+package webhook
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	admissionv1 "k8s.io/api/admission/v1"
+	"knative.dev/pkg/metrics"
+	"knative.dev/pkg/webhook"
+)
+
+// maxReasonLength bounds the reason tag of the rejections.
+const maxReasonLength = 64
+
+var (
+	// rejectionCountM counts the admission requests denied by the webhooks.
+	rejectionCountM = stats.Int64(
+		"couchdb_webhook_rejection_count",
+		"Number of admission requests rejected by the webhook",
+		stats.UnitDimensionless,
+	)
+
+	operationKey = tag.MustNewKey("request_operation")
+	kindKey      = tag.MustNewKey("kind_kind")
+	namespaceKey = tag.MustNewKey("resource_namespace")
+	reasonKey    = tag.MustNewKey("reason")
+)
+
+func init() {
+	if err := view.Register(
+		&view.View{
+			Description: rejectionCountM.Description(),
+			Measure:     rejectionCountM,
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{operationKey, kindKey, namespaceKey, reasonKey},
+		},
+	); err != nil {
+		panic(err)
+	}
+}
+
+// statsReporter records the reason of the rejected requests on top of the
+// request count and latencies recorded by the knative reporter.
+type statsReporter struct {
+	webhook.StatsReporter
+}
+
+// NewStatsReporter returns the webhook.StatsReporter of the CouchDB source
+// webhooks.
+func NewStatsReporter() (webhook.StatsReporter, error) {
+	r, err := webhook.NewStatsReporter()
+	if err != nil {
+		return nil, err
+	}
+	return &statsReporter{StatsReporter: r}, nil
+}
+
+// ReportRequest implements webhook.StatsReporter.
+func (r *statsReporter) ReportRequest(req *admissionv1.AdmissionRequest, resp *admissionv1.AdmissionResponse, d time.Duration) error {
+	if err := r.StatsReporter.ReportRequest(req, resp, d); err != nil {
+		return err
+	}
+	if resp.Allowed {
+		return nil
+	}
+	ctx, err := tag.New(context.Background(),
+		tag.Insert(operationKey, string(req.Operation)),
+		tag.Insert(kindKey, req.Kind.Kind),
+		tag.Insert(namespaceKey, req.Namespace),
+		tag.Insert(reasonKey, rejectionReason(resp)))
+	if err != nil {
+		return err
+	}
+	metrics.Record(ctx, rejectionCountM.M(1))
+	return nil
+}
+
+// rejectionReason returns a reason of bounded cardinality for a rejection,
+// e.g. "validation failed: missing field(s)". The webhooks report what failed
+// followed by the message of the first field error, whose values and paths
+// are left out.
+func rejectionReason(resp *admissionv1.AdmissionResponse) string {
+	if resp.Result == nil || resp.Result.Message == "" {
+		return "unknown"
+	}
+	msg := resp.Result.Message
+	if i := strings.IndexByte(msg, '\n'); i >= 0 {
+		msg = msg[:i]
+	}
+	parts := strings.SplitN(msg, ": ", 3)
+	reason := parts[0]
+	if len(parts) > 2 {
+		reason += ": " + parts[1]
+	}
+	if len(reason) > maxReasonLength {
+		reason = reason[:maxReasonLength]
+	}
+	return reason
+}
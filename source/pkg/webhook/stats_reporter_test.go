@@ -0,0 +1,59 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRejectionReason(t *testing.T) {
+	testCases := map[string]struct {
+		result *metav1.Status
+		want   string
+	}{
+		"no result": {
+			want: "unknown",
+		},
+		"missing field": {
+			result: &metav1.Status{Message: "validation failed: missing field(s): spec.sink"},
+			want:   "validation failed: missing field(s)",
+		},
+		"invalid value": {
+			result: &metav1.Status{Message: "validation failed: invalid value: sometimes: spec.feed"},
+			want:   "validation failed: invalid value",
+		},
+		"several errors": {
+			result: &metav1.Status{Message: "validation failed: expected exactly one, got both: spec.backfill, spec.since\nmissing field(s): spec.sink"},
+			want:   "validation failed: expected exactly one, got both",
+		},
+		"mutation": {
+			result: &metav1.Status{Message: "mutation failed: cannot decode incoming new object"},
+			want:   "mutation failed",
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			got := rejectionReason(&admissionv1.AdmissionResponse{Result: tc.result})
+			if got != tc.want {
+				t.Errorf("rejectionReason() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+//go:build e2e
+// +build e2e
+
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cetest "github.com/cloudevents/sdk-go/v2/test"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"knative.dev/eventing/pkg/apis/eventing"
+	testlib "knative.dev/eventing/test/lib"
+	"knative.dev/eventing/test/lib/recordevents"
+	"knative.dev/eventing/test/lib/resources"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	pkgtest "knative.dev/pkg/test"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-couchdb/source/pkg/events"
+	"knative.dev/eventing-couchdb/test/lib"
+)
+
+const (
+	brokerName = "default"
+
+	// teamExtension is the extension attribute the sources set with
+	// spec.ceOverrides, for the Triggers to filter on.
+	teamExtension = "team"
+
+	writeTimeout = 5 * time.Minute
+)
+
+var orders = map[string]string{
+	"order-1": `{"type": "order", "total": 120}`,
+	"order-2": `{"type": "order", "total": 80}`,
+}
+
+// TestBrokerTriggerFilters sends the events of a source to a Broker, and
+// checks that Triggers filtering on the event type, the subject and the
+// extension attributes of the source route them to their subscribers.
+func TestBrokerTriggerFilters(t *testing.T) {
+	ctx := context.Background()
+	client := testlib.Setup(t, true)
+	defer testlib.TearDown(client)
+
+	createBrokerOrFail(client)
+
+	updates, _ := recordevents.StartEventRecordOrFail(ctx, client, "order-updates")
+	first, _ := recordevents.StartEventRecordOrFail(ctx, client, "first-order")
+	billing, _ := recordevents.StartEventRecordOrFail(ctx, client, "billing")
+
+	client.CreateTriggerOrFail("order-updates",
+		resources.WithBroker(brokerName),
+		resources.WithAttributesTriggerFilter("", events.UpdateEventType, map[string]interface{}{teamExtension: "orders"}),
+		resources.WithSubscriberServiceRefForTrigger("order-updates"),
+	)
+	client.CreateTriggerOrFail("first-order",
+		resources.WithBroker(brokerName),
+		resources.WithAttributesTriggerFilter("", "", map[string]interface{}{"subject": "order-1"}),
+		resources.WithSubscriberServiceRefForTrigger("first-order"),
+	)
+	client.CreateTriggerOrFail("billing",
+		resources.WithBroker(brokerName),
+		resources.WithAttributesTriggerFilter("", "", map[string]interface{}{teamExtension: "billing"}),
+		resources.WithSubscriberServiceRefForTrigger("billing"),
+	)
+
+	source := createSourceOrFail(ctx, client, "orders")
+	client.WaitForAllTestResourcesReadyOrFail(ctx)
+
+	lib.WriteDocumentsOrFail(ctx, client, source, orders, writeTimeout)
+
+	// Delivery is at least once, so the events may be duplicated.
+	for id := range orders {
+		updates.AssertAtLeast(1, recordevents.MatchEvent(
+			cetest.HasType(events.UpdateEventType),
+			cetest.HasSubject(id),
+			cetest.HasExtension(teamExtension, "orders"),
+		))
+	}
+	first.AssertAtLeast(1, recordevents.MatchEvent(cetest.HasSubject("order-1")))
+	first.AssertNot(recordevents.MatchEvent(cetest.HasSubject("order-2")))
+	billing.AssertNot(recordevents.Any())
+}
+
+// TestBrokerTriggerKService routes the events of a source through a Knative
+// Service replying with a processed event, which another Trigger delivers to
+// its subscriber.
+func TestBrokerTriggerKService(t *testing.T) {
+	ctx := context.Background()
+	client := testlib.Setup(t, true)
+	defer testlib.TearDown(client)
+
+	createBrokerOrFail(client)
+
+	const processedType = "com.example.order.processed"
+	processed, _ := recordevents.StartEventRecordOrFail(ctx, client, "processed")
+
+	createProcessorOrFail(client, "order-processor", processedType)
+	client.CreateTriggerOrFail("order-processor",
+		resources.WithBroker(brokerName),
+		resources.WithAttributesTriggerFilter("", events.UpdateEventType, map[string]interface{}{teamExtension: "orders"}),
+		resources.WithSubscriberKServiceRefForTrigger("order-processor"),
+	)
+	client.CreateTriggerOrFail("processed",
+		resources.WithBroker(brokerName),
+		resources.WithAttributesTriggerFilter("", processedType, nil),
+		resources.WithSubscriberServiceRefForTrigger("processed"),
+	)
+
+	source := createSourceOrFail(ctx, client, "orders")
+	client.WaitForAllTestResourcesReadyOrFail(ctx)
+
+	lib.WriteDocumentsOrFail(ctx, client, source, orders, writeTimeout)
+
+	processed.AssertAtLeast(len(orders), recordevents.MatchEvent(cetest.HasType(processedType)))
+}
+
+// createBrokerOrFail creates the Broker the sources send their events to.
+func createBrokerOrFail(client *testlib.Client) {
+	client.CreateBrokerOrFail(brokerName, resources.WithBrokerClassForBroker(eventing.MTChannelBrokerClassValue))
+	client.WaitForResourceReadyOrFail(brokerName, testlib.BrokerTypeMeta)
+}
+
+// createSourceOrFail creates a source of the orders team on a dev instance,
+// sending its events to the Broker.
+func createSourceOrFail(ctx context.Context, client *testlib.Client, name string) *v1alpha1.CouchDbSource {
+	return lib.CreateCouchDbSourceOrFail(ctx, client, &v1alpha1.CouchDbSource{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1alpha1.CouchDbSourceSpec{
+			DevInstance: true,
+			Database:    "orders",
+			Feed:        v1alpha1.FeedContinuous,
+			CloudEventOverrides: &duckv1.CloudEventOverrides{
+				Extensions: map[string]string{teamExtension: "orders"},
+			},
+			Sink: &duckv1.Destination{
+				Ref: resources.KnativeRefForBroker(brokerName, client.Namespace),
+			},
+		},
+	})
+}
+
+var kserviceTypeMeta = &metav1.TypeMeta{APIVersion: "serving.knative.dev/v1", Kind: "Service"}
+
+// createProcessorOrFail creates a Knative Service replying to every event
+// with an event of the given type, and waits for it to become ready.
+func createProcessorOrFail(client *testlib.Client, name, replyType string) {
+	container := corev1.Container{
+		Image: pkgtest.ImagePath("recordevents"),
+		Env: []corev1.EnvVar{
+			{Name: "EVENT_GENERATORS", Value: "receiver"},
+			// The recorder reports to its own Pod, whose name the Service
+			// does not know, so the processor only logs the events.
+			{Name: "EVENT_LOGS", Value: "logger"},
+			{Name: "POD_NAME", Value: name},
+			{Name: "REPLY", Value: "true"},
+			{Name: "REPLY_EVENT_TYPE", Value: replyType},
+		},
+	}
+	c, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&container)
+	if err != nil {
+		client.T.Fatalf("Failed to convert the container of %s: %v", name, err)
+	}
+	ksvc := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": kserviceTypeMeta.APIVersion,
+		"kind":       kserviceTypeMeta.Kind,
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": client.Namespace,
+		},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{c},
+				},
+			},
+		},
+	}}
+
+	gvr := schema.GroupVersionResource{Group: "serving.knative.dev", Version: "v1", Resource: "services"}
+	if _, err := client.Dynamic.Resource(gvr).Namespace(client.Namespace).Create(context.Background(), ksvc, metav1.CreateOptions{}); err != nil {
+		client.T.Fatalf("Failed to create the Knative Service %s: %v", name, err)
+	}
+	client.Tracker.Add(gvr.Group, gvr.Version, gvr.Resource, client.Namespace, name)
+	client.WaitForResourceReadyOrFail(name, kserviceTypeMeta)
+	client.T.Logf("Knative Service %s is ready", name)
+}
@@ -0,0 +1,45 @@
+//go:build e2e
+// +build e2e
+
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"flag"
+	"testing"
+
+	"knative.dev/eventing-couchdb/test/lib"
+)
+
+var scenarios = flag.String("scenarios", "scenarios", "The directory of the YAML scenarios run by TestScenarios.")
+
+// TestScenarios runs the scenarios of the YAML files of the scenarios
+// directory, see lib.Scenario.
+func TestScenarios(t *testing.T) {
+	all, err := lib.LoadScenarios(*scenarios)
+	if err != nil {
+		t.Fatalf("Failed to load the scenarios: %v", err)
+	}
+	for _, s := range all {
+		s := s
+		t.Run(s.Name, func(t *testing.T) {
+			t.Parallel()
+			lib.RunScenario(t, s)
+		})
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	testlib "knative.dev/eventing/test/lib"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-couchdb/source/pkg/client/clientset/versioned"
+	"knative.dev/eventing-couchdb/source/pkg/reconciler/resources"
+)
+
+// CouchDbSourceTypeMeta is the TypeMeta of CouchDbSource, e.g. to wait for a
+// source to become ready with WaitForResourceReadyOrFail.
+var CouchDbSourceTypeMeta = &metav1.TypeMeta{
+	APIVersion: v1alpha1.SchemeGroupVersion.String(),
+	Kind:       "CouchDbSource",
+}
+
+// DocumentWriterImage is the image of the Jobs writing documents. The CouchDB
+// image of the dev instances comes with curl.
+const DocumentWriterImage = "couchdb:3.1"
+
+// CreateCouchDbSourceOrFail creates the source in the namespace of the client
+// and tracks it, so that WaitForAllTestResourcesReadyOrFail waits for it and
+// TearDown deletes it.
+func CreateCouchDbSourceOrFail(ctx context.Context, client *testlib.Client, source *v1alpha1.CouchDbSource) *v1alpha1.CouchDbSource {
+	cs, err := versioned.NewForConfig(client.Config)
+	if err != nil {
+		client.T.Fatalf("Failed to create the CouchDbSource client: %v", err)
+	}
+	source.Namespace = client.Namespace
+	created, err := cs.SourcesV1alpha1().CouchDbSources(client.Namespace).Create(ctx, source, metav1.CreateOptions{})
+	if err != nil {
+		client.T.Fatalf("Failed to create the CouchDbSource %q: %v", source.Name, err)
+	}
+	client.Tracker.AddObj(created)
+	return created
+}
+
+// WriteDocumentsOrFail writes the documents, JSON objects by ID, to the
+// database of a source with spec.devInstance, creating the database when
+// missing. It runs a Job in the cluster, since the dev instance is only
+// reachable from there, and waits for it to complete.
+func WriteDocumentsOrFail(ctx context.Context, client *testlib.Client, source *v1alpha1.CouchDbSource, docs map[string]string, timeout time.Duration) {
+	WriteDatabaseDocumentsOrFail(ctx, client, source, source.Spec.Database, docs, timeout)
+}
+
+// WriteDatabaseDocumentsOrFail is WriteDocumentsOrFail for another database
+// of the dev instance of the source. Without documents, it only creates the
+// database.
+func WriteDatabaseDocumentsOrFail(ctx context.Context, client *testlib.Client, source *v1alpha1.CouchDbSource, database string, docs map[string]string, timeout time.Duration) {
+	job := DocumentWriterJob("", resources.DevInstanceName(source), database, docs)
+	// Successive writes may happen within the same second.
+	job.GenerateName = source.Name + "-writer-"
+	job, err := client.Kube.BatchV1().Jobs(client.Namespace).Create(ctx, job, metav1.CreateOptions{})
+	if err != nil {
+		client.T.Fatalf("Failed to create the document writer: %v", err)
+	}
+	err = wait.PollImmediate(time.Second, timeout, func() (bool, error) {
+		j, err := client.Kube.BatchV1().Jobs(client.Namespace).Get(ctx, job.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, c := range j.Status.Conditions {
+			if c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue {
+				return false, fmt.Errorf("the document writer failed: %s", c.Message)
+			}
+		}
+		return j.Status.Succeeded > 0, nil
+	})
+	if err != nil {
+		client.T.Fatalf("Failed to write the documents: %v", err)
+	}
+}
+
+// DocumentWriterJob returns a Job writing the documents to the database of
+// the CouchDB server whose URL, with the credentials, is the url key of the
+// secret. The Job retries until the server is up.
+func DocumentWriterJob(name, secret, database string, docs map[string]string) *batchv1.Job {
+	ids := make([]string, 0, len(docs))
+	for id := range docs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var script strings.Builder
+	script.WriteString("set -e\n")
+	// The database may already exist, e.g. created by the receive adapter.
+	script.WriteString(`curl -sS -o /dev/null -X PUT "$COUCHDB_URL/$COUCHDB_DATABASE"` + "\n")
+	for _, id := range ids {
+		fmt.Fprintf(&script, `curl -fsS -X PUT "$COUCHDB_URL/$COUCHDB_DATABASE/"%s -H 'Content-Type: application/json' -d %s`+"\n",
+			shellQuote(id), shellQuote(docs[id]))
+	}
+
+	backoffLimit := int32(6)
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{{
+						Name:    "writer",
+						Image:   DocumentWriterImage,
+						Command: []string{"sh", "-c", script.String()},
+						Env: []corev1.EnvVar{{
+							Name: "COUCHDB_URL",
+							ValueFrom: &corev1.EnvVarSource{
+								SecretKeyRef: &corev1.SecretKeySelector{
+									LocalObjectReference: corev1.LocalObjectReference{Name: secret},
+									Key:                  "url",
+								},
+							},
+						}, {
+							Name:  "COUCHDB_DATABASE",
+							Value: database,
+						}},
+					}},
+				},
+			},
+		},
+	}
+}
+
+// shellQuote quotes s for sh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestDocumentWriterJob(t *testing.T) {
+	job := DocumentWriterJob("writer", "source-couchdb", "orders", map[string]string{
+		"order-2": `{"type": "order", "note": "it's late"}`,
+		"order-1": `{"type": "order"}`,
+	})
+
+	spec := job.Spec.Template.Spec
+	if len(spec.Containers) != 1 {
+		t.Fatalf("got %d containers, want 1", len(spec.Containers))
+	}
+	c := spec.Containers[0]
+	if c.Image != DocumentWriterImage {
+		t.Errorf("image = %q, want %q", c.Image, DocumentWriterImage)
+	}
+	if ref := c.Env[0].ValueFrom.SecretKeyRef; ref.Name != "source-couchdb" || ref.Key != "url" {
+		t.Errorf("COUCHDB_URL from %s/%s, want source-couchdb/url", ref.Name, ref.Key)
+	}
+
+	want := `set -e
+curl -sS -o /dev/null -X PUT "$COUCHDB_URL/$COUCHDB_DATABASE"
+curl -fsS -X PUT "$COUCHDB_URL/$COUCHDB_DATABASE/"'order-1' -H 'Content-Type: application/json' -d '{"type": "order"}'
+curl -fsS -X PUT "$COUCHDB_URL/$COUCHDB_DATABASE/"'order-2' -H 'Content-Type: application/json' -d '{"type": "order", "note": "it'\''s late"}'
+`
+	if got := c.Command[2]; got != want {
+		t.Errorf("script =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh is not available")
+	}
+	for _, s := range []string{`plain`, `it's`, `{"a": "$HOME"}`, `''`} {
+		out, err := exec.Command(sh, "-c", "printf %s "+shellQuote(s)).Output()
+		if err != nil {
+			t.Fatalf("sh -c printf %s: %v", shellQuote(s), err)
+		}
+		if string(out) != s {
+			t.Errorf("shellQuote(%q) printed %q", s, out)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lib holds the helpers shared by the CouchDbSource e2e tests on top
+// of the Knative eventing test library.
+package lib
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	apixclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+)
+
+// clusterResource lists and deletes one kind of cluster-scoped resource.
+type clusterResource struct {
+	kind   string
+	list   func(context.Context) ([]string, error)
+	delete func(context.Context, string) error
+}
+
+// LeakDetector finds the cluster-scoped resources created during a test and
+// still around after its TearDown. The Knative test Tracker only cleans what
+// it was told about, so ClusterRoleBindings, CRDs or webhooks created by the
+// code under test are otherwise left behind and break the next CI runs.
+type LeakDetector struct {
+	resources []clusterResource
+	before    map[string]sets.String
+}
+
+// SnapshotClusterResources records the cluster-scoped resources existing
+// before a test. Call it right after Setup and call Check after TearDown.
+func SnapshotClusterResources(ctx context.Context, kube kubernetes.Interface, crds apixclient.CustomResourceDefinitionInterface) (*LeakDetector, error) {
+	d := &LeakDetector{
+		resources: []clusterResource{{
+			kind: "ClusterRoleBinding",
+			list: func(ctx context.Context) ([]string, error) {
+				l, err := kube.RbacV1().ClusterRoleBindings().List(ctx, metav1.ListOptions{})
+				if err != nil {
+					return nil, err
+				}
+				names := make([]string, 0, len(l.Items))
+				for _, i := range l.Items {
+					names = append(names, i.Name)
+				}
+				return names, nil
+			},
+			delete: func(ctx context.Context, name string) error {
+				return kube.RbacV1().ClusterRoleBindings().Delete(ctx, name, metav1.DeleteOptions{})
+			},
+		}, {
+			kind: "CustomResourceDefinition",
+			list: func(ctx context.Context) ([]string, error) {
+				l, err := crds.List(ctx, metav1.ListOptions{})
+				if err != nil {
+					return nil, err
+				}
+				names := make([]string, 0, len(l.Items))
+				for _, i := range l.Items {
+					names = append(names, i.Name)
+				}
+				return names, nil
+			},
+			delete: func(ctx context.Context, name string) error {
+				return crds.Delete(ctx, name, metav1.DeleteOptions{})
+			},
+		}, {
+			kind: "ValidatingWebhookConfiguration",
+			list: func(ctx context.Context) ([]string, error) {
+				l, err := kube.AdmissionregistrationV1().ValidatingWebhookConfigurations().List(ctx, metav1.ListOptions{})
+				if err != nil {
+					return nil, err
+				}
+				names := make([]string, 0, len(l.Items))
+				for _, i := range l.Items {
+					names = append(names, i.Name)
+				}
+				return names, nil
+			},
+			delete: func(ctx context.Context, name string) error {
+				return kube.AdmissionregistrationV1().ValidatingWebhookConfigurations().Delete(ctx, name, metav1.DeleteOptions{})
+			},
+		}, {
+			kind: "MutatingWebhookConfiguration",
+			list: func(ctx context.Context) ([]string, error) {
+				l, err := kube.AdmissionregistrationV1().MutatingWebhookConfigurations().List(ctx, metav1.ListOptions{})
+				if err != nil {
+					return nil, err
+				}
+				names := make([]string, 0, len(l.Items))
+				for _, i := range l.Items {
+					names = append(names, i.Name)
+				}
+				return names, nil
+			},
+			delete: func(ctx context.Context, name string) error {
+				return kube.AdmissionregistrationV1().MutatingWebhookConfigurations().Delete(ctx, name, metav1.DeleteOptions{})
+			},
+		}},
+		before: make(map[string]sets.String),
+	}
+
+	for _, r := range d.resources {
+		names, err := r.list(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list %ss: %v", r.kind, err)
+		}
+		d.before[r.kind] = sets.NewString(names...)
+	}
+	return d, nil
+}
+
+// Leaks returns, by kind, the names of the cluster-scoped resources that did
+// not exist when the snapshot was taken.
+func (d *LeakDetector) Leaks(ctx context.Context) (map[string][]string, error) {
+	leaks := make(map[string][]string)
+	for _, r := range d.resources {
+		names, err := r.list(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list %ss: %v", r.kind, err)
+		}
+		if leaked := sets.NewString(names...).Difference(d.before[r.kind]); leaked.Len() > 0 {
+			leaks[r.kind] = leaked.List()
+		}
+	}
+	return leaks, nil
+}
+
+// Check fails the test for every leaked cluster-scoped resource and, when
+// clean is set, deletes it so that it cannot break the following runs.
+func (d *LeakDetector) Check(ctx context.Context, t *testing.T, clean bool) {
+	leaks, err := d.Leaks(ctx)
+	if err != nil {
+		t.Errorf("Unable to check for leaked cluster-scoped resources: %v", err)
+		return
+	}
+
+	kinds := make([]string, 0, len(leaks))
+	for kind := range leaks {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		t.Errorf("Leaked %s: %v", kind, leaks[kind])
+	}
+	if !clean {
+		return
+	}
+	for _, r := range d.resources {
+		for _, name := range leaks[r.kind] {
+			if err := r.delete(ctx, name); err != nil {
+				t.Logf("Failed to delete leaked %s %q: %v", r.kind, name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,257 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/binding"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+	"github.com/go-kivik/kivik/v3"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+
+	"knative.dev/eventing-couchdb/source/pkg/events"
+	"knative.dev/eventing-couchdb/source/pkg/reconciler/resources"
+)
+
+// Prober writes a stream of documents with monotonically increasing IDs to a
+// CouchDB database, to check that the sink received an event for every one of
+// them, e.g. across a rolling restart of the receive adapter. It relies on
+// the default subject of the events, the document ID.
+type Prober struct {
+	db       *kivik.DB
+	prefix   string
+	interval time.Duration
+
+	mu      sync.Mutex
+	written int
+	errs    []error
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// NewProber returns a Prober writing a document every interval, with IDs
+// made of the prefix and a sequence number.
+func NewProber(db *kivik.DB, prefix string, interval time.Duration) *Prober {
+	return &Prober{
+		db:       db,
+		prefix:   prefix,
+		interval: interval,
+	}
+}
+
+// DocID returns the ID of the i-th document written by the prober.
+func (p *Prober) DocID(i int) string {
+	return fmt.Sprintf("%s-%08d", p.prefix, i)
+}
+
+// Start writes documents until Stop is called or ctx is done.
+func (p *Prober) Start(ctx context.Context) {
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.done = make(chan struct{})
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			p.write(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (p *Prober) write(ctx context.Context) {
+	p.mu.Lock()
+	id := p.DocID(p.written)
+	p.mu.Unlock()
+
+	_, err := p.db.Put(ctx, id, map[string]interface{}{"probe": true})
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		if ctx.Err() == nil {
+			p.errs = append(p.errs, fmt.Errorf("unable to write %s: %v", id, err))
+		}
+		return
+	}
+	p.written++
+}
+
+// Stop stops writing documents and returns the IDs of the documents written.
+func (p *Prober) Stop() []string {
+	p.cancel()
+	<-p.done
+	return p.Written()
+}
+
+// Written returns the IDs of the documents written so far.
+func (p *Prober) Written() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ids := make([]string, 0, p.written)
+	for i := 0; i < p.written; i++ {
+		ids = append(ids, p.DocID(i))
+	}
+	return ids
+}
+
+// Missing returns the written documents absent from the received ones.
+func (p *Prober) Missing(received sets.String) []string {
+	var missing []string
+	for _, id := range p.Written() {
+		if !received.Has(id) {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
+
+// Check fails the test when the prober could not write its documents, or when
+// the received subjects miss any of the written documents. Duplicates are
+// fine, since delivery is at least once.
+func (p *Prober) Check(t *testing.T, received sets.String) {
+	p.mu.Lock()
+	for _, err := range p.errs {
+		t.Error(err)
+	}
+	p.mu.Unlock()
+
+	written := p.Written()
+	if len(written) == 0 {
+		t.Error("The prober did not write any document")
+		return
+	}
+	if missing := p.Missing(received); len(missing) > 0 {
+		t.Errorf("Lost %d of %d events: %s", len(missing), len(written), strings.Join(missing, ", "))
+	}
+}
+
+// Receiver is an HTTP sink recording the subjects of the events it receives,
+// in the binary, structured and batch content modes, and the entries of the
+// org.apache.couchdb.document.batch events.
+type Receiver struct {
+	mu       sync.Mutex
+	subjects sets.String
+}
+
+// NewReceiver returns an empty Receiver.
+func NewReceiver() *Receiver {
+	return &Receiver{subjects: sets.NewString()}
+}
+
+// Received returns the subjects received so far.
+func (r *Receiver) Received() sets.String {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return sets.NewString(r.subjects.UnsortedList()...)
+}
+
+// ServeHTTP implements http.Handler.
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var received []cloudevents.Event
+	if strings.HasPrefix(req.Header.Get("Content-Type"), cloudevents.ApplicationCloudEventsBatchJSON) {
+		if err := json.NewDecoder(req.Body).Decode(&received); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		event, err := binding.ToEvent(req.Context(), cehttp.NewMessageFromHttpRequest(req))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		received = append(received, *event)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, event := range received {
+		if event.Type() != events.BatchEventType {
+			r.subjects.Insert(event.Subject())
+			continue
+		}
+		entries, err := events.BatchEntries(event)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, e := range entries {
+			r.subjects.Insert(e.Subject)
+		}
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// RestartReceiveAdapter rolls the receive adapter Deployment of the source,
+// like kubectl rollout restart, and waits for the rollout to complete.
+func RestartReceiveAdapter(ctx context.Context, kube kubernetes.Interface, namespace, source string, timeout time.Duration) error {
+	deployments, err := kube.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(resources.Labels(source)).String(),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to list the receive adapters: %v", err)
+	}
+	if len(deployments.Items) != 1 {
+		return fmt.Errorf("found %d receive adapters for %s/%s, want 1", len(deployments.Items), namespace, source)
+	}
+	name := deployments.Items[0].Name
+
+	patch := fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{"kubectl.kubernetes.io/restartedAt":%q}}}}}`,
+		time.Now().Format(time.RFC3339))
+	if _, err := kube.AppsV1().Deployments(namespace).Patch(ctx, name, types.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("unable to restart %s/%s: %v", namespace, name, err)
+	}
+
+	return wait.PollImmediate(time.Second, timeout, func() (bool, error) {
+		d, err := kube.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return rolledOut(d), nil
+	})
+}
+
+// rolledOut is whether all the replicas of the Deployment run its latest
+// template, as kubectl rollout status checks.
+func rolledOut(d *appsv1.Deployment) bool {
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	return d.Status.ObservedGeneration >= d.Generation &&
+		d.Status.UpdatedReplicas == replicas &&
+		d.Status.Replicas == replicas &&
+		d.Status.AvailableReplicas == replicas
+}
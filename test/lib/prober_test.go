@@ -0,0 +1,93 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+func newEvent(id, eventType, subject string) cloudevents.Event {
+	event := cloudevents.NewEvent()
+	event.SetID(id)
+	event.SetType(eventType)
+	event.SetSource("test")
+	event.SetSubject(subject)
+	return event
+}
+
+func TestReceiver(t *testing.T) {
+	r := NewReceiver()
+	sink := httptest.NewServer(r)
+	defer sink.Close()
+
+	// A binary event.
+	c, err := cloudevents.NewClientHTTP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := cloudevents.ContextWithTarget(context.Background(), sink.URL)
+	if result := c.Send(ctx, newEvent("1", v1alpha1.CouchDbSourceUpdateEventType, "probe-00000000")); !cloudevents.IsACK(result) {
+		t.Fatalf("Send() = %v", result)
+	}
+
+	// A CloudEvents batch holding a batch event.
+	batchEvent := newEvent("3", v1alpha1.CouchDbSourceBatchEventType, "")
+	if err := batchEvent.SetData(cloudevents.ApplicationJSON, []map[string]string{
+		{"id": "2", "subject": "probe-00000001"},
+		{"id": "3", "subject": "probe-00000002"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal([]cloudevents.Event{batchEvent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(sink.URL, cloudevents.ApplicationCloudEventsBatchJSON, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("batch status = %d, want %d", resp.StatusCode, http.StatusAccepted)
+	}
+
+	want := sets.NewString("probe-00000000", "probe-00000001", "probe-00000002")
+	if diff := cmp.Diff(want.List(), r.Received().List()); diff != "" {
+		t.Errorf("unexpected subjects (-want, +got) = %v", diff)
+	}
+}
+
+func TestProberMissing(t *testing.T) {
+	p := NewProber(nil, "probe", 0)
+	p.written = 4
+
+	got := p.Missing(sets.NewString(p.DocID(0), p.DocID(1), p.DocID(1), p.DocID(3)))
+	if diff := cmp.Diff([]string{"probe-00000002"}, got); diff != "" {
+		t.Errorf("unexpected missing documents (-want, +got) = %v", diff)
+	}
+}
@@ -0,0 +1,358 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	cetest "github.com/cloudevents/sdk-go/v2/test"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	testlib "knative.dev/eventing/test/lib"
+	"knative.dev/eventing/test/lib/recordevents"
+	"knative.dev/eventing/test/lib/resources"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	"knative.dev/eventing-couchdb/source/pkg/apis/sources/v1alpha1"
+)
+
+// DefaultScenarioWriteTimeout bounds each write step of a scenario, unless it
+// sets writeTimeout.
+const DefaultScenarioWriteTimeout = 5 * time.Minute
+
+// Scenario is an end to end test described in YAML: a source on a dev
+// instance sending its events to a recorder, the documents written to its
+// databases, and the events the recorder must, or must not, receive. E.g.
+//
+//	name: large-orders
+//	source:
+//	  database: orders
+//	  match: doc.total > 100
+//	steps:
+//	- write:
+//	    docs:
+//	      order-1: {"total": 120}
+//	      order-2: {"total": 80}
+//	expect:
+//	- subject: order-1
+//	- subject: order-2
+//	  none: true
+type Scenario struct {
+	// Name names the source and the recorder. It must be a DNS label.
+	Name string `json:"name"`
+
+	// Description says what the scenario checks.
+	Description string `json:"description,omitempty"`
+
+	// Databases are created on the dev instance before the steps run, in
+	// addition to the one of the source.
+	Databases []string `json:"databases,omitempty"`
+
+	// Source is the spec of the source. The runner sets devInstance and the
+	// sink, and defaults the feed to continuous.
+	Source v1alpha1.CouchDbSourceSpec `json:"source"`
+
+	// Steps run in order once the source is ready.
+	Steps []ScenarioStep `json:"steps"`
+
+	// Expect lists the events the recorder must, or must not, receive.
+	Expect []ExpectedEvent `json:"expect"`
+
+	// Settle is how long to wait after the steps before checking that the
+	// events expected with none were not received. Defaults to no wait.
+	Settle *metav1.Duration `json:"settle,omitempty"`
+
+	// WriteTimeout bounds each write step. Defaults to
+	// DefaultScenarioWriteTimeout.
+	WriteTimeout *metav1.Duration `json:"writeTimeout,omitempty"`
+
+	// path is the file the scenario was loaded from, if any.
+	path string
+}
+
+// ScenarioStep is a step of a scenario: either writing documents or waiting.
+type ScenarioStep struct {
+	// Write writes documents to a database.
+	Write *WriteStep `json:"write,omitempty"`
+
+	// Wait pauses the scenario, e.g. to let the source catch up.
+	Wait *metav1.Duration `json:"wait,omitempty"`
+}
+
+// WriteStep writes documents to a database of the dev instance.
+type WriteStep struct {
+	// Database defaults to the one of the source.
+	Database string `json:"database,omitempty"`
+
+	// Docs are the JSON objects to write, by ID. The IDs must be new.
+	Docs map[string]json.RawMessage `json:"docs"`
+}
+
+// ExpectedEvent matches the events received by the recorder. Every attribute
+// set must match.
+type ExpectedEvent struct {
+	Type    string `json:"type,omitempty"`
+	Source  string `json:"source,omitempty"`
+	Subject string `json:"subject,omitempty"`
+
+	// Extensions are the values of extension attributes.
+	Extensions map[string]string `json:"extensions,omitempty"`
+
+	// Data are the values of top level fields of the JSON event data.
+	Data map[string]json.RawMessage `json:"data,omitempty"`
+
+	// AtLeast is the minimum number of matching events, 1 by default.
+	AtLeast *int `json:"atLeast,omitempty"`
+
+	// AtMost is the maximum number of matching events, unbounded by
+	// default. Delivery is at least once, so mind duplicates.
+	AtMost *int `json:"atMost,omitempty"`
+
+	// None expects no matching event at all.
+	None bool `json:"none,omitempty"`
+}
+
+// ParseScenario parses and validates a scenario in YAML or JSON. Unknown
+// fields are rejected, to catch misspelled ones.
+func ParseScenario(data []byte) (*Scenario, error) {
+	j, err := yaml.ToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(j))
+	dec.DisallowUnknownFields()
+	s := &Scenario{}
+	if err := dec.Decode(s); err != nil {
+		return nil, err
+	}
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// LoadScenarios parses the scenarios of the .yaml files of the directory,
+// sorted by file name.
+func LoadScenarios(dir string) ([]*Scenario, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	scenarios := make([]*Scenario, 0, len(paths))
+	names := make(map[string]string, len(paths))
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		s, err := ParseScenario(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		if other, ok := names[s.Name]; ok {
+			return nil, fmt.Errorf("%s: scenario %q is already defined in %s", path, s.Name, other)
+		}
+		names[s.Name] = path
+		s.path = path
+		scenarios = append(scenarios, s)
+	}
+	return scenarios, nil
+}
+
+func (s *Scenario) validate() error {
+	if errs := validation.IsDNS1123Label(s.Name); len(errs) > 0 {
+		return fmt.Errorf("invalid name %q: %v", s.Name, errs)
+	}
+	if s.Source.Database == "" {
+		return fmt.Errorf("missing source.database")
+	}
+	for i, step := range s.Steps {
+		if (step.Write == nil) == (step.Wait == nil) {
+			return fmt.Errorf("steps[%d]: expected exactly one of write or wait", i)
+		}
+		if step.Write == nil {
+			continue
+		}
+		if len(step.Write.Docs) == 0 {
+			return fmt.Errorf("steps[%d].write: missing docs", i)
+		}
+		for id, doc := range step.Write.Docs {
+			var obj map[string]interface{}
+			if err := json.Unmarshal(doc, &obj); err != nil || obj == nil {
+				return fmt.Errorf("steps[%d].write.docs[%s]: expected a JSON object", i, id)
+			}
+		}
+	}
+	if len(s.Expect) == 0 {
+		return fmt.Errorf("missing expect")
+	}
+	for i, e := range s.Expect {
+		if e.None && (e.AtLeast != nil || e.AtMost != nil) {
+			return fmt.Errorf("expect[%d]: none cannot be combined with atLeast or atMost", i)
+		}
+		if e.AtLeast != nil && *e.AtLeast < 1 {
+			return fmt.Errorf("expect[%d]: atLeast must be positive, use none to expect no event", i)
+		}
+		if e.AtMost != nil && *e.AtMost < e.atLeast() {
+			return fmt.Errorf("expect[%d]: atMost must be at least atLeast", i)
+		}
+	}
+	return nil
+}
+
+func (e *ExpectedEvent) atLeast() int {
+	if e.AtLeast != nil {
+		return *e.AtLeast
+	}
+	return 1
+}
+
+// Matchers returns the matchers of the attributes and data of the expected
+// events.
+func (e *ExpectedEvent) Matchers() []cetest.EventMatcher {
+	var matchers []cetest.EventMatcher
+	if e.Type != "" {
+		matchers = append(matchers, cetest.HasType(e.Type))
+	}
+	if e.Source != "" {
+		matchers = append(matchers, cetest.HasSource(e.Source))
+	}
+	if e.Subject != "" {
+		matchers = append(matchers, cetest.HasSubject(e.Subject))
+	}
+	for k, v := range e.Extensions {
+		matchers = append(matchers, cetest.HasExtension(k, v))
+	}
+	if len(e.Data) > 0 {
+		matchers = append(matchers, hasDataFields(e.Data))
+	}
+	return matchers
+}
+
+// hasDataFields matches the events whose JSON data has the fields, whatever
+// their formatting.
+func hasDataFields(fields map[string]json.RawMessage) cetest.EventMatcher {
+	return func(have cloudevents.Event) error {
+		var data map[string]json.RawMessage
+		if err := json.Unmarshal(have.Data(), &data); err != nil {
+			return fmt.Errorf("the data is not a JSON object: %v", err)
+		}
+		for k, want := range fields {
+			got, ok := data[k]
+			if !ok {
+				return fmt.Errorf("the data has no field %q", k)
+			}
+			var w, g interface{}
+			if err := json.Unmarshal(want, &w); err != nil {
+				return fmt.Errorf("invalid expected value of %q: %v", k, err)
+			}
+			if err := json.Unmarshal(got, &g); err != nil {
+				return err
+			}
+			if !reflect.DeepEqual(w, g) {
+				return fmt.Errorf("the data field %q is %s, want %s", k, got, want)
+			}
+		}
+		return nil
+	}
+}
+
+// RunScenario runs the scenario in a new test namespace: it creates the
+// source and its recorder, runs the steps, then checks the expected events.
+func RunScenario(t *testing.T, s *Scenario) {
+	ctx := context.Background()
+	client := testlib.Setup(t, true)
+	defer testlib.TearDown(client)
+	if s.path != "" {
+		t.Logf("Running scenario %s from %s", s.Name, s.path)
+	}
+
+	recorderName := s.Name + "-recorder"
+	recorder, _ := recordevents.StartEventRecordOrFail(ctx, client, recorderName)
+
+	spec := s.Source.DeepCopy()
+	spec.DevInstance = true
+	if spec.Feed == "" {
+		spec.Feed = v1alpha1.FeedContinuous
+	}
+	spec.Sink = &duckv1.Destination{
+		Ref: resources.KnativeRefForService(recorderName, client.Namespace),
+	}
+	source := CreateCouchDbSourceOrFail(ctx, client, &v1alpha1.CouchDbSource{
+		ObjectMeta: metav1.ObjectMeta{Name: s.Name},
+		Spec:       *spec,
+	})
+
+	writeTimeout := DefaultScenarioWriteTimeout
+	if s.WriteTimeout != nil {
+		writeTimeout = s.WriteTimeout.Duration
+	}
+	for _, db := range s.Databases {
+		WriteDatabaseDocumentsOrFail(ctx, client, source, db, nil, writeTimeout)
+	}
+	client.WaitForAllTestResourcesReadyOrFail(ctx)
+
+	for _, step := range s.Steps {
+		switch {
+		case step.Write != nil:
+			db := step.Write.Database
+			if db == "" {
+				db = spec.Database
+			}
+			docs := make(map[string]string, len(step.Write.Docs))
+			for id, doc := range step.Write.Docs {
+				docs[id] = string(doc)
+			}
+			WriteDatabaseDocumentsOrFail(ctx, client, source, db, docs, writeTimeout)
+		case step.Wait != nil:
+			time.Sleep(step.Wait.Duration)
+		}
+	}
+
+	// Wait for the expected events first, so that the settling time only
+	// adds to the time the source took to send them.
+	for _, e := range s.Expect {
+		if !e.None {
+			matcher := recordevents.MatchEvent(e.Matchers()...)
+			if e.AtMost != nil {
+				recorder.AssertInRange(e.atLeast(), *e.AtMost, matcher)
+			} else {
+				recorder.AssertAtLeast(e.atLeast(), matcher)
+			}
+		}
+	}
+	if s.Settle != nil {
+		time.Sleep(s.Settle.Duration)
+	}
+	for _, e := range s.Expect {
+		if e.None {
+			recorder.AssertNot(recordevents.MatchEvent(e.Matchers()...))
+		}
+	}
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"strings"
+	"testing"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+const validScenario = `
+name: large-orders
+source:
+  database: orders
+  match: doc.total > 100
+steps:
+- write:
+    docs:
+      order-1: {"total": 120}
+- wait: 5s
+expect:
+- type: org.apache.couchdb.document.update
+  subject: order-1
+  atMost: 2
+- subject: order-2
+  none: true
+settle: 10s
+`
+
+func TestParseScenario(t *testing.T) {
+	s, err := ParseScenario([]byte(validScenario))
+	if err != nil {
+		t.Fatalf("ParseScenario() = %v", err)
+	}
+	if s.Name != "large-orders" || s.Source.Match != "doc.total > 100" {
+		t.Errorf("parsed %+v", s)
+	}
+	if len(s.Steps) != 2 || string(s.Steps[0].Write.Docs["order-1"]) != `{"total":120}` || s.Steps[1].Wait.Seconds() != 5 {
+		t.Errorf("parsed steps %+v", s.Steps)
+	}
+	if len(s.Expect) != 2 || s.Expect[0].atLeast() != 1 || *s.Expect[0].AtMost != 2 || !s.Expect[1].None {
+		t.Errorf("parsed expectations %+v", s.Expect)
+	}
+}
+
+func TestParseScenarioErrors(t *testing.T) {
+	testCases := map[string]struct {
+		old, new string
+		wantErr  string
+	}{
+		"unknown field": {
+			old:     "settle:",
+			new:     "setle:",
+			wantErr: "unknown field",
+		},
+		"invalid name": {
+			old:     "name: large-orders",
+			new:     "name: Large_Orders",
+			wantErr: "invalid name",
+		},
+		"missing database": {
+			old:     "database: orders",
+			new:     "",
+			wantErr: "missing source.database",
+		},
+		"step with write and wait": {
+			old:     "- wait: 5s",
+			new:     "  wait: 5s",
+			wantErr: "exactly one of write or wait",
+		},
+		"document not an object": {
+			old:     `{"total": 120}`,
+			new:     `[120]`,
+			wantErr: "expected a JSON object",
+		},
+		"none with atLeast": {
+			old:     "none: true",
+			new:     "none: true\n  atLeast: 1",
+			wantErr: "none cannot be combined",
+		},
+		"atMost below atLeast": {
+			old:     "atMost: 2",
+			new:     "atMost: 2\n  atLeast: 3",
+			wantErr: "atMost must be at least atLeast",
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			_, err := ParseScenario([]byte(strings.Replace(validScenario, tc.old, tc.new, 1)))
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("ParseScenario() = %v, want an error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestExpectedEventMatchers(t *testing.T) {
+	s, err := ParseScenario([]byte(`
+name: matchers
+source:
+  database: orders
+expect:
+- type: org.apache.couchdb.document.update
+  subject: order-1
+  extensions:
+    team: orders
+  data:
+    order: order-1
+    total: 120.0
+`))
+	if err != nil {
+		t.Fatalf("ParseScenario() = %v", err)
+	}
+	expected := s.Expect[0]
+
+	event := func(subject, data string) cloudevents.Event {
+		e := cloudevents.NewEvent()
+		e.SetID("1")
+		e.SetSource("couchdb")
+		e.SetType("org.apache.couchdb.document.update")
+		e.SetSubject(subject)
+		e.SetExtension("team", "orders")
+		if err := e.SetData(cloudevents.ApplicationJSON, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+	match := func(e cloudevents.Event) error {
+		for _, m := range expected.Matchers() {
+			if err := m(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := match(event("order-1", `{"order": "order-1", "total": 120, "other": true}`)); err != nil {
+		t.Errorf("matching event: %v", err)
+	}
+	for _, e := range []cloudevents.Event{
+		event("order-2", `{"order": "order-1", "total": 120}`),
+		event("order-1", `{"order": "order-1", "total": 80}`),
+		event("order-1", `{"order": "order-1"}`),
+		event("order-1", `[]`),
+	} {
+		if err := match(e); err == nil {
+			t.Errorf("event %s with data %s matched", e.Subject(), e.Data())
+		}
+	}
+}
+
+// TestE2EScenarios checks that the scenarios run by the e2e and smoke tests
+// are valid.
+func TestE2EScenarios(t *testing.T) {
+	for _, dir := range []string{"../e2e/scenarios", "../e2e/smoke"} {
+		scenarios, err := LoadScenarios(dir)
+		if err != nil {
+			t.Fatalf("LoadScenarios(%s) = %v", dir, err)
+		}
+		if len(scenarios) == 0 {
+			t.Errorf("found no scenario in %s", dir)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+#!/usr/bin/env bash
+
+# Copyright 2021 The Knative Authors
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# This script runs the smoke tests, a fast local signal before the full e2e
+# suite: it creates a kind cluster, installs Knative Eventing and the CouchDB
+# source built with ko, runs the scenarios of test/e2e/smoke, and deletes the
+# cluster. It needs docker, kind, kubectl and ko, and is started by:
+#
+#   make test-smoke
+#
+# Set KEEP_CLUSTER=1 to keep the cluster, e.g. to investigate a failure; the
+# next run reuses it.
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+readonly ROOT_DIR="$(cd "$(dirname "$0")/.." && pwd)"
+readonly CLUSTER_NAME="${KIND_CLUSTER_NAME:-couchdb-smoke}"
+readonly EVENTING_VERSION="${EVENTING_VERSION:-v0.25.0}"
+readonly EVENTING_RELEASE="https://github.com/knative/eventing/releases/download/${EVENTING_VERSION}"
+# Images loaded into kind must not be tagged latest, which Kubernetes always
+# pulls.
+readonly IMAGE_TAG="smoke"
+
+export KO_DOCKER_REPO="kind.local"
+export KIND_CLUSTER_NAME="${CLUSTER_NAME}"
+export GOFLAGS="-mod=vendor"
+
+function log() {
+  echo "=== $(date +%T) $*"
+}
+
+function teardown() {
+  if [[ -n "${KEEP_CLUSTER:-}" ]]; then
+    log "Keeping the kind cluster ${CLUSTER_NAME}"
+    return
+  fi
+  log "Deleting the kind cluster ${CLUSTER_NAME}"
+  kind delete cluster --name "${CLUSTER_NAME}"
+}
+
+for tool in docker kind kubectl ko; do
+  if ! command -v "${tool}" > /dev/null; then
+    echo "The smoke tests need ${tool}" >&2
+    exit 1
+  fi
+done
+
+cd "${ROOT_DIR}"
+
+if ! kind get clusters | grep -qx "${CLUSTER_NAME}"; then
+  log "Creating the kind cluster ${CLUSTER_NAME}"
+  kind create cluster --name "${CLUSTER_NAME}" --wait 2m
+fi
+trap teardown EXIT
+kubectl config use-context "kind-${CLUSTER_NAME}"
+
+log "Installing Knative Eventing ${EVENTING_VERSION}"
+kubectl apply -f "${EVENTING_RELEASE}/eventing-crds.yaml"
+kubectl wait --for=condition=Established --all crd --timeout=1m
+kubectl apply -f "${EVENTING_RELEASE}/eventing-core.yaml"
+
+# The images build while Eventing starts.
+log "Building the CouchDB source and the test images"
+ko apply -f source/config/ --tags "${IMAGE_TAG}"
+ko build --base-import-paths --tags "${IMAGE_TAG}" knative.dev/eventing/test/test_images/recordevents
+
+log "Waiting for Knative Eventing and the CouchDB source"
+kubectl wait --for=condition=Available deployment --all -n knative-eventing --timeout=2m
+kubectl wait --for=condition=Available deployment/couchdb-controller-manager deployment/couchdb-webhook \
+  -n knative-sources --timeout=2m
+
+log "Running the smoke tests"
+go test -v -count=1 -timeout 4m -tags=e2e ./test/e2e \
+  -run '^TestScenarios$' -scenarios smoke \
+  -dockerrepo "${KO_DOCKER_REPO}" -tag "${IMAGE_TAG}"